@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+	"go2exe/internal/launch"
+	"go2exe/internal/ui"
+)
+
+// 按 uv.lock 检查用户手动创建的虚拟环境，通过后记入状态文件，之后启动时用它代替 python\.venv
+func runAdopt(exeDir, dir string) error {
+	venv, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	log.Printf("正在检查虚拟环境 %s", venv)
+	problems := install.CheckVenv(venv, filepath.Join(exeDir, "python", "uv.lock"), installConfig.PythonVersion)
+	if len(problems) > 0 {
+		for _, p := range problems {
+			log.Printf("虚拟环境检查未通过: %s", p)
+		}
+		ui.ErrorBox(i18n.T("无法使用该虚拟环境"), i18n.T("%s 未通过检查：\n\n%s\n\n可以先在该环境中按 uv.lock 安装依赖（uv sync）后再试。", venv, strings.Join(problems, "\n")))
+		return fmt.Errorf("虚拟环境 %s 未通过检查: %s", venv, strings.Join(problems, "; "))
+	}
+
+	state, _ := readCheckState()
+	state.Venv = venv
+	if err := writeCheckState(state); err != nil {
+		return fmt.Errorf("保存启动检查状态失败: %v", err)
+	}
+	log.Printf("已改用虚拟环境 %s", venv)
+	ui.MessageBox(i18n.T("已使用现有的虚拟环境"), i18n.T("SpeakMyBook 以后将使用 %s 运行。\n\n运行 --repair 可以恢复使用程序目录中的虚拟环境。", venv))
+	return nil
+}
+
+// 改用 --adopt 记录的虚拟环境：uv sync 同步到该环境，应用用其中的解释器启动。
+// 记录的环境已被删除时继续使用 python\.venv
+func useAdoptedVenv() {
+	state, _ := readCheckState()
+	if state.Venv == "" {
+		return
+	}
+	if _, err := os.Stat(install.VenvPythonW(state.Venv)); err != nil {
+		log.Printf("记录的虚拟环境 %s 不可用，改用 python\\.venv: %v", state.Venv, err)
+		return
+	}
+	log.Printf("使用虚拟环境 %s", state.Venv)
+	childEnv.Set("UV_PROJECT_ENVIRONMENT", state.Venv)
+	app.Python = install.VenvPythonW(state.Venv)
+}
+
+// 不再使用 --adopt 记录的虚拟环境（环境本身不删除）
+func forgetAdoptedVenv() {
+	state, err := readCheckState()
+	if err != nil || state.Venv == "" {
+		return
+	}
+	log.Printf("不再使用虚拟环境 %s", state.Venv)
+	state.Venv = ""
+	if err := writeCheckState(state); err != nil {
+		log.Printf("保存启动检查状态失败: %v", err)
+	}
+	childEnv.Unset("UV_PROJECT_ENVIRONMENT")
+	app.Python = ""
+}
+
+// 应用使用的解释器的完整路径
+func appPython(exeDir string) string {
+	if app.Python != "" {
+		return app.Python
+	}
+	return filepath.Join(exeDir, "python", filepath.FromSlash(launch.PythonW))
+}
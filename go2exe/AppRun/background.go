@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+)
+
+var (
+	setThreadPriority = kernel32.NewProc("SetThreadPriority")
+	getCurrentThread  = kernel32.NewProc("GetCurrentThread")
+	// 降低线程的 CPU、磁盘和内存优先级
+	THREAD_MODE_BACKGROUND_BEGIN = 0x00010000
+	THREAD_MODE_BACKGROUND_END   = 0x00020000
+)
+
+// 应用启动后等待多久再开始后台检查，避免与应用自身的启动争抢磁盘
+const backgroundCheckDelay = 30 * time.Second
+
+// 后台检查发现的一个问题
+type backgroundFinding struct {
+	text    string
+	warning bool
+	action  func() // 用户点击通知时执行
+}
+
+// 应用启动后在后台补做本次启动跳过的检查：按校验清单校验安装文件、检查虚拟环境是否与 uv.lock 一致，并查询应用更新。
+// 发现问题时在托盘通知，安装文件或依赖有问题时下次启动执行全部检查
+func startBackgroundChecks(exeDir string) {
+	if tray == nil {
+		return
+	}
+	go func() {
+		time.Sleep(backgroundCheckDelay)
+		findings := runBackgroundChecks(exeDir)
+		if len(findings) == 0 || tray == nil {
+			return
+		}
+		var texts []string
+		warning := false
+		for _, f := range findings {
+			texts = append(texts, f.text)
+			warning = warning || f.warning
+		}
+		// 通知只有一个点击动作，执行最重要的（第一个）问题的处理
+		tray.Notify(i18n.T("SpeakMyBook 后台检查"), strings.Join(texts, "\n"), warning, findings[0].action)
+	}()
+}
+
+// 在后台优先级的线程上执行检查，返回按重要程度排列的问题
+func runBackgroundChecks(exeDir string) []backgroundFinding {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	thread, _, _ := getCurrentThread.Call()
+	setThreadPriority.Call(thread, uintptr(THREAD_MODE_BACKGROUND_BEGIN))
+	defer setThreadPriority.Call(thread, uintptr(THREAD_MODE_BACKGROUND_END))
+
+	log.Printf("开始后台检查")
+	var findings []backgroundFinding
+	if !checks.verify() {
+		if err := verifyInstallFiles(exeDir); err != nil {
+			log.Printf("后台检查: 安装文件校验失败: %v", err)
+			resetLaunchChecks()
+			findings = append(findings, backgroundFinding{
+				text:    i18n.T("安装文件已损坏，请重新下载完整的安装包。"),
+				warning: true,
+				action:  func() { openLog(exeDir) },
+			})
+		}
+	}
+	if !checks.sync() {
+		venv := filepath.Dir(filepath.Dir(appPython(exeDir)))
+		if problems := install.CheckVenv(venv, filepath.Join(exeDir, "python", "uv.lock"), installConfig.PythonVersion); len(problems) > 0 {
+			log.Printf("后台检查: 虚拟环境与 uv.lock 不一致: %s", strings.Join(problems, "; "))
+			resetLaunchChecks()
+			findings = append(findings, backgroundFinding{
+				text:    i18n.T("已安装的依赖与 uv.lock 不一致，点击这里更新。"),
+				warning: true,
+				action:  trayAction(exeDir, checkForUpdates),
+			})
+		}
+	}
+	rel, found, err := latestRelease(exeDir)
+	if err != nil {
+		log.Printf("后台检查: 检查应用更新失败: %v", err)
+	}
+	if found {
+		findings = append(findings, backgroundFinding{
+			text: i18n.T("有新版本 %s，点击这里更新。", rel.Version),
+			action: trayAction(exeDir, func(exeDir string) error {
+				return updateFromTray(exeDir, rel)
+			}),
+		})
+	}
+	log.Printf("后台检查完成，发现 %d 项", len(findings))
+	return findings
+}
+
+// 启动时是否把检查更新留给后台检查
+func deferUpdateCheck(cfg Config) bool {
+	return cfg.Tray.Icon && checks.background()
+}
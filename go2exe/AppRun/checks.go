@@ -0,0 +1,287 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go2exe/internal/install"
+)
+
+// 记录上次成功启动时安装包指纹的文件
+func checkStateFile() string {
+	return filepath.Join(dataDir(), "checks.json")
+}
+
+// 上次成功启动时的状态
+type checkState struct {
+	Fingerprint string `json:"fingerprint"`
+	Venv        string `json:"venv,omitempty"`  // 通过 --adopt 使用的虚拟环境，为空时使用 python\.venv
+	Index       string `json:"index,omitempty"` // 测速选出的 PyPI 镜像名称（[network] index = "auto" 时使用）
+	// 已提示过太旧的 NVIDIA 驱动版本，同一版本不再弹出提示
+	DriverNotice string `json:"driver_notice,omitempty"`
+	// 应用连续启动失败的次数，成功启动后清零
+	StartFailures int `json:"start_failures,omitempty"`
+	// 回退前的应用版本，检查更新时跳过这个版本，直到发布了更新的版本
+	RolledBack string `json:"rolled_back,omitempty"`
+	// 上次检测到的 uv 和满足版本要求（PythonRequest）的 Python 解释器，文件仍在时启动不再运行检查命令
+	UVPath        string `json:"uv_path,omitempty"`
+	PythonPath    string `json:"python_path,omitempty"`
+	PythonRequest string `json:"python_request,omitempty"`
+	// 上次成功启动时的环境戳：uv、Python、uv.lock 和虚拟环境都没有变化时跳过全部检查和同步
+	Stamp string `json:"stamp,omitempty"`
+	// 上次成功同步依赖时 uv.lock 和 pyproject.toml 的哈希，没有变化时启动不再运行 uv sync
+	SyncHash string `json:"sync_hash,omitempty"`
+}
+
+// 读取状态文件，文件不存在或无法解析时返回零值
+func readCheckState() (checkState, error) {
+	var state checkState
+	data, err := os.ReadFile(checkStateFile())
+	if err != nil {
+		return state, err
+	}
+	json.Unmarshal(data, &state)
+	return state, nil
+}
+
+// 写入状态文件，没有需要保存的内容时删除它
+func writeCheckState(state checkState) error {
+	if state == (checkState{}) {
+		if err := os.Remove(checkStateFile()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(dataDir(), 0755); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(state)
+	return os.WriteFile(checkStateFile(), data, 0644)
+}
+
+// 本次启动要执行哪些检查，零值表示全部执行
+type launchChecks struct {
+	cfg         ChecksConfig
+	exeDir      string
+	firstRun    bool   // 还没有成功启动过
+	updated     bool   // 安装包与上次成功启动时不同
+	warm        bool   // 环境戳与上次成功启动时相同，跳过全部检查
+	restored    bool   // 磁盘被还原，记录的 uv、Python 或虚拟环境已不存在，不论配置执行全部检查
+	fingerprint string // 当前安装包的指纹
+}
+
+// 本次启动的检查计划
+var checks launchChecks
+
+var (
+	checksMu          sync.Mutex
+	checksInvalidated bool // 本次运行期间收到了 invalidate，不再保存指纹
+)
+
+// 读取上次成功启动的记录，确定本次是首次运行、更新后运行还是普通启动
+func loadLaunchChecks(exeDir string, cfg ChecksConfig) launchChecks {
+	c := launchChecks{cfg: cfg, exeDir: exeDir, fingerprint: packageFingerprint(exeDir)}
+	state, _ := readCheckState()
+	if state.Fingerprint == "" {
+		c.firstRun = true
+	} else {
+		c.updated = state.Fingerprint != c.fingerprint
+		// 黄金镜像由管理员准备，缺少文件时由 checkGoldenImage 提示，不在这里重新安装
+		if missing := restoredArtifacts(exeDir, state); len(missing) > 0 && !goldenMode {
+			c.restored = true
+			forgetRestoredEnvironment(missing)
+		}
+	}
+	if cfg.Stamp && !c.firstRun && !c.updated && !c.restored && state.Stamp != "" {
+		c.warm = environmentStamp(exeDir, state) == state.Stamp
+	}
+	log.Printf("启动检查: 首次运行 %v，安装包已更新 %v，磁盘已还原 %v，环境未变化 %v", c.firstRun, c.updated, c.restored, c.warm)
+	return c
+}
+
+// 按配置的时机判断这次是否执行检查：always、after_update、first_run 或 never
+func (c launchChecks) need(name, when string) bool {
+	if c.warm {
+		return false
+	}
+	if c.restored {
+		return true
+	}
+	switch when {
+	case "", "always":
+		return true
+	case "after_update":
+		return c.firstRun || c.updated
+	case "first_run":
+		return c.firstRun
+	case "never":
+		return false
+	}
+	log.Printf("配置项 checks.%s 的值 %q 无效，按 always 处理", name, when)
+	return true
+}
+
+func (c launchChecks) uv() bool     { return c.need("uv", c.cfg.UV) }
+func (c launchChecks) python() bool { return c.need("python", c.cfg.Python) }
+func (c launchChecks) sync() bool   { return c.need("sync", c.cfg.Sync) }
+func (c launchChecks) verify() bool { return c.need("verify", c.cfg.Verify) }
+
+// 应用启动后是否在后台补做检查：按配置开启，且本次启动跳过了安装文件校验或依赖同步
+func (c launchChecks) background() bool {
+	return c.cfg.Background && (!c.verify() || !c.sync())
+}
+
+// 应用成功启动后记下安装包指纹，之后的启动按配置跳过检查
+func (c launchChecks) save() {
+	checksMu.Lock()
+	defer checksMu.Unlock()
+	if c.fingerprint == "" || checksInvalidated {
+		return
+	}
+	state, _ := readCheckState()
+	state.Fingerprint = c.fingerprint
+	state.Stamp = ""
+	if c.cfg.Stamp {
+		state.Stamp = environmentStamp(c.exeDir, state)
+	}
+	if err := writeCheckState(state); err != nil {
+		log.Printf("保存启动检查状态失败: %v", err)
+	}
+}
+
+// 清除记录的指纹和检测结果，下次启动视为首次运行，执行全部检查。使用中的虚拟环境仍然保留
+func clearFingerprint() error {
+	state, err := readCheckState()
+	if err != nil {
+		return nil
+	}
+	state.Fingerprint, state.Stamp, state.SyncHash = "", "", ""
+	// 检测到的 uv 和 Python 也可能已被替换，一起重新检查
+	state.UVPath, state.PythonPath, state.PythonRequest = "", "", ""
+	return writeCheckState(state)
+}
+
+// 启动失败时清除记录，下次启动执行全部检查，避免被跳过的检查掩盖问题
+func resetLaunchChecks() {
+	if err := clearFingerprint(); err != nil {
+		log.Printf("清除启动检查状态失败: %v", err)
+	}
+}
+
+// 把记录的指纹标记为失效，下次启动执行全部检查。用于手动修改环境或由管理员推送 Python 更新之后
+func invalidateLaunchChecks() error {
+	checksMu.Lock()
+	defer checksMu.Unlock()
+	checksInvalidated = true
+	if err := clearFingerprint(); err != nil {
+		return fmt.Errorf("清除启动检查状态失败: %v", err)
+	}
+	log.Printf("已将环境指纹标记为失效，下次启动执行全部检查")
+	return nil
+}
+
+// 只标记环境指纹失效；正在运行的启动器也要知道，否则它会在应用启动后重新写入指纹
+func runInvalidate() error {
+	sendToLauncher("invalidate")
+	err := invalidateLaunchChecks()
+	if err != nil {
+		log.Printf("%v", err)
+	}
+	return err
+}
+
+// 安装包的指纹：启动器本身、校验清单和应用依赖声明任一变化即视为更新
+func packageFingerprint(exeDir string) string {
+	h := sha256.New()
+	if exePath, err := os.Executable(); err == nil {
+		if info, err := os.Stat(exePath); err == nil {
+			fmt.Fprintf(h, "exe %d %d\n", info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	for _, name := range []string{"checksums.txt", filepath.Join("python", "pyproject.toml"), filepath.Join("python", "uv.lock")} {
+		fmt.Fprintf(h, "%s\n", name)
+		f, err := os.Open(filepath.Join(exeDir, name))
+		if err != nil {
+			continue
+		}
+		io.Copy(h, f)
+		f.Close()
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// 环境戳：检测到的 uv 和 Python 解释器（以文件大小和修改时间代表版本，不必运行它们）、Python 版本要求、
+// uv.lock 的内容、虚拟环境的解释器和要安装的可选依赖。还没有检测到 uv 或 Python，或有文件不存在时返回空字符串
+func environmentStamp(exeDir string, state checkState) string {
+	if state.UVPath == "" || state.PythonPath == "" {
+		return ""
+	}
+	h := sha256.New()
+	venvPython := appPython(exeDir)
+	pyvenv := filepath.Join(filepath.Dir(filepath.Dir(venvPython)), "pyvenv.cfg")
+	for _, path := range []string{state.UVPath, state.PythonPath, venvPython, pyvenv} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return ""
+		}
+		fmt.Fprintf(h, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	fmt.Fprintf(h, "python %s\nextras %s\n", pythonRequest(), strings.Join(syncExtras, ","))
+	lock, err := os.ReadFile(filepath.Join(exeDir, "python", "uv.lock"))
+	if err != nil {
+		return ""
+	}
+	h.Write(lock)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// 依赖声明的哈希：uv.lock、pyproject.toml、要安装的可选依赖和使用的虚拟环境。没有 uv.lock 时返回空字符串
+func dependencyHash(exeDir string) string {
+	lock, err := os.ReadFile(filepath.Join(exeDir, "python", "uv.lock"))
+	if err != nil {
+		return ""
+	}
+	h := sha256.New()
+	h.Write(lock)
+	pyproject, _ := os.ReadFile(filepath.Join(exeDir, "python", "pyproject.toml"))
+	fmt.Fprintf(h, "\npyproject\n%s\nextras %s\nvenv %s\n", pyproject, strings.Join(syncExtras, ","), appPython(exeDir))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// 依赖声明是否与上次成功同步时相同
+func dependenciesSynced(exeDir string) bool {
+	state, _ := readCheckState()
+	return state.SyncHash != "" && state.SyncHash == dependencyHash(exeDir)
+}
+
+// 记录（synced 为 false 时清除）同步依赖时的依赖声明哈希。同步失败时虚拟环境可能不完整，下次启动需要重新同步
+func recordDependencySync(exeDir string, synced bool) {
+	checksMu.Lock()
+	defer checksMu.Unlock()
+	state, _ := readCheckState()
+	state.SyncHash = ""
+	if synced {
+		state.SyncHash = dependencyHash(exeDir)
+	}
+	if err := writeCheckState(state); err != nil {
+		log.Printf("保存依赖同步状态失败: %v", err)
+	}
+}
+
+// 按校验清单检查随程序分发的安装文件，没有 uv 目录（使用内置安装文件）时只检查 Python
+func verifyInstallFiles(exeDir string) error {
+	if install.HasExternalUV(exeDir) {
+		if err := install.VerifyArtifacts(exeDir, "uv"); err != nil {
+			return err
+		}
+	}
+	return install.VerifyArtifacts(exeDir, pythonArtifacts())
+}
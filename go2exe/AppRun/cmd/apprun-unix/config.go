@@ -0,0 +1,40 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"path/filepath"
+
+	"go2exe/internal/appconfig"
+	"go2exe/internal/envcheck"
+)
+
+// 这个启动器使用的配置项，节和键与 Windows 版的 apprun.toml 相同
+type config struct {
+	UI struct {
+		Language string `toml:"language" check:"auto,zh-CN,en-US"`
+	} `toml:"ui"`
+	Network struct {
+		Index string `toml:"index" check:"mirror"`
+	} `toml:"network"`
+	Install struct {
+		PythonVersion string `toml:"python_version"`
+	} `toml:"install"`
+}
+
+// 读取程序目录下的 apprun.toml，再按与 Windows 版相同的环境变量（SPEAKMYBOOK_UI_LANGUAGE、
+// SPEAKMYBOOK_MIRROR 等）覆盖。配置文件中这个启动器不使用的配置项不报告，
+// 有问题的设置使用默认值，返回列出所有问题的 *appconfig.Error
+func loadConfig(exeDir string) (config, error) {
+	var cfg config
+	cfg.UI.Language = "auto"
+	cfg.Install.PythonVersion = envcheck.DefaultPython
+	schema := appconfig.Schema(cfg)
+	e := appconfig.Effective{Partial: true}
+	e.DecodeFile(&cfg, filepath.Join(exeDir, "apprun.toml"), schema)
+	e.Decode(&cfg, appconfig.EnvValues(schema), schema)
+	if len(e.Problems) > 0 {
+		return cfg, &appconfig.Error{Problems: e.Problems}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,214 @@
+//go:build linux || darwin
+
+// Linux 和 macOS 版启动器：与 Windows 版相同的启动流程（检查并安装 uv 和 Python、同步依赖、启动应用），
+// 在终端中显示进度，按 Ctrl+C 取消。uv 使用 uv 目录中的 uv-installer.sh 安装，没有时下载官方安装脚本。
+// 配置与 Windows 版共用 apprun.toml 和覆盖它的环境变量，只使用其中的 [ui] language、[network] index 和
+// [install] python_version（见 config.go），退出码也与 Windows 版相同。与系统有关的部分在 platform_linux.go 和 platform_darwin.go 中
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/exitcode"
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+	"go2exe/internal/launch"
+	"go2exe/internal/mirror"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+var (
+	// 传给 uv、安装脚本和应用的环境变量，安装 uv 后它的目录只加到这里的 PATH 中
+	childEnv                           = &runner.Environment{}
+	cmdRunner     runner.CommandRunner = runner.Exec{Env: childEnv}
+	console                            = ui.NewConsole("安装进度")
+	installCtx    context.Context
+	cancelInstall context.CancelFunc
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "无法确定程序所在目录: %v\n", err)
+		return exitcode.Failed
+	}
+	exeDir := appDir(filepath.Dir(exePath))
+	if logFile, err := openLog(exeDir); err == nil {
+		defer logFile.Close()
+		log.SetOutput(logFile)
+	}
+	envcheck.Current = platform
+	cfg, err := loadConfig(exeDir)
+	i18n.SetLanguage(cfg.UI.Language)
+	setupUI()
+	if err != nil {
+		log.Printf("配置有问题，有问题的设置使用默认值:\n%v", err)
+		console.Line(i18n.T("配置有问题，有问题的设置使用默认值:\n%v", err))
+	}
+
+	index := mirror.Default
+	if m, ok, _ := mirror.Parse(cfg.Network.Index); ok {
+		index = m
+	}
+	python := cfg.Install.PythonVersion
+	request, err := envcheck.InstallRequest(python, envcheck.ArchAuto)
+	if err != nil {
+		console.Line(i18n.T("配置错误: %v", err))
+		return exitcode.Failed
+	}
+
+	installCtx, cancelInstall = context.WithCancel(context.Background())
+	defer cancelInstall()
+	console.OnCancel = func() {
+		if !ui.ConfirmBox(i18n.T("取消"), i18n.T("确定要取消吗？\n\n正在执行的步骤会被终止，下次启动时会重新执行。")) {
+			return
+		}
+		log.Printf("用户取消了操作")
+		console.Line(i18n.T("正在取消..."))
+		cancelInstall()
+	}
+	console.Open()
+	defer console.Close()
+
+	inst := &install.Installer{
+		ExeDir:  exeDir,
+		Runner:  cmdRunner,
+		Out:     console,
+		Context: installCtx,
+		Python:  request,
+		Index:   index.URL,
+		Arch:    envcheck.ArchAuto,
+	}
+	check := envcheck.Checker{Runner: cmdRunner, Python: python, Arch: envcheck.ArchAuto}
+	if code, err := ensureEnvironment(inst, check, python); err != nil {
+		return failed(code, err)
+	}
+
+	projectDir := filepath.Join(exeDir, "python")
+	if _, err := os.Stat(projectDir); err != nil {
+		return failed(exitcode.AppStart, fmt.Errorf("无法进入python目录: %v", err))
+	}
+	if err := inst.RunStep("同步依赖", inst.Sync); err != nil {
+		if _, statErr := os.Stat(install.VenvPythonW(filepath.Join(projectDir, ".venv"))); statErr != nil || errors.Is(err, runner.ErrCanceled) {
+			return failed(exitcode.Sync, err)
+		}
+		// 与 Windows 版一样，同步失败时仍尝试用现有的虚拟环境启动
+		console.Line(i18n.T("依赖检查未通过，继续使用现有的虚拟环境: %v", err))
+	}
+	console.Close()
+	// 启动器没有自己的参数，都传给应用；-- 分隔符与 Windows 版一样去掉
+	own, forwarded := launch.SplitArgs(os.Args[1:])
+	return runApp(projectDir, index.URL, append(own, forwarded...))
+}
+
+// 检查 uv 和所需的 Python，缺少时安装，失败时返回退出码
+func ensureEnvironment(inst *install.Installer, check envcheck.Checker, python string) (int, error) {
+	uvInstalled, _ := check.UVInstalled()
+	if !uvInstalled {
+		if path, ok := envcheck.FindUV(childEnv); ok {
+			envcheck.AddToPath(childEnv, filepath.Dir(path))
+			uvInstalled, _ = check.UVInstalled()
+		}
+	}
+	if !uvInstalled {
+		// 与 Windows 版一样，首次安装前告知用户（从 Finder 打开时看不到终端中的进度）
+		ui.MessageBox(i18n.T("环境安装"), i18n.T("即将开始安装必要的环境组件，请稍候...\n\n这个过程只在首次运行时执行，可能需要几分钟。"))
+		console.Line(i18n.T("正在安装uv..."))
+		if err := inst.RunStep("安装 uv", inst.InstallUV); err != nil {
+			return exitcode.UVInstall, err
+		}
+		// 安装脚本修改的 PATH 只对新的登录会话生效
+		path, ok := envcheck.FindUV(childEnv, inst.UVDir)
+		if !ok {
+			return exitcode.UVInstall, fmt.Errorf("安装后仍无法检测到uv")
+		}
+		envcheck.AddToPath(childEnv, filepath.Dir(path))
+		console.Line(i18n.T("uv安装完成"))
+	}
+
+	pythonInstalled, err := check.PythonInstalled()
+	if err != nil {
+		return exitcode.Check, err
+	}
+	if !pythonInstalled {
+		console.Line(i18n.T("正在安装Python %s...", python))
+		if err := inst.RunStep("安装 Python", inst.InstallPython); err != nil {
+			return exitcode.PythonInstall, err
+		}
+		console.Line(i18n.T("Python %s安装完成", python))
+	}
+	return exitcode.OK, nil
+}
+
+// 启动应用。detachApp 时应用与终端分离，启动后启动器即退出；
+// 否则应用在启动器所在的终端中运行，Ctrl+C 交给应用处理，启动器等它退出后再退出
+func runApp(projectDir, index string, appArgs []string) int {
+	python := install.VenvPythonW(filepath.Join(projectDir, ".venv"))
+	if detachApp {
+		app := &launch.Launcher{
+			Python: python,
+			Dir:    projectDir,
+			Index:  index,
+			Runner: cmdRunner,
+			Out:    console,
+			Detach: true,
+		}
+		if err := app.Start(appArgs); err != nil {
+			return failed(exitcode.AppStart, err)
+		}
+		return exitcode.OK
+	}
+
+	// 不用 signal.Ignore：忽略的信号会被应用继承
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	go func() {
+		for range interrupts {
+		}
+	}()
+
+	exited := make(chan error, 1)
+	app := &launch.Launcher{
+		Python: python,
+		Dir:    projectDir,
+		Index:  index,
+		Runner: cmdRunner,
+		Out:    console,
+		OnExit: func(err error) { exited <- err },
+	}
+	if err := app.Start(appArgs); err != nil {
+		return failed(exitcode.AppStart, err)
+	}
+	if err := <-exited; err != nil {
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return exitcode.Failed
+	}
+	return exitcode.OK
+}
+
+// 记录失败原因并返回退出码，取消时返回 exitcode.Cancelled
+func failed(code int, err error) int {
+	if errors.Is(err, runner.ErrCanceled) {
+		console.Line(i18n.T("已取消"))
+		return exitcode.Cancelled
+	}
+	log.Printf("启动失败: %v", err)
+	console.Line(i18n.T("启动失败: %v", err))
+	return code
+}
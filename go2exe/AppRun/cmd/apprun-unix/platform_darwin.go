@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/ui"
+)
+
+var platform = envcheck.Darwin
+
+// 与 Windows 版一样，应用启动后启动器即退出：从 Finder 打开 .app 时没有终端，从终端运行时关闭终端也不影响应用
+const detachApp = true
+
+// 打包为 SpeakMyBook.app 时启动器在 Contents/MacOS 中，安装文件放在 Contents/Resources 中；
+// 直接运行时安装文件与启动器在同一目录
+func appDir(exeDir string) string {
+	if filepath.Base(exeDir) == "MacOS" && filepath.Base(filepath.Dir(exeDir)) == "Contents" {
+		return filepath.Join(filepath.Dir(exeDir), "Resources")
+	}
+	return exeDir
+}
+
+// 日志写到 ~/Library/Logs/SpeakMyBook/app.log，“控制台”应用中可以直接查看
+func openLog(string) (*os.File, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(home, "Library", "Logs", "SpeakMyBook")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filepath.Join(dir, "app.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// 消息框和确认使用系统对话框，进度仍输出到终端（如果有）
+func setupUI() {
+	ui.UseNativeDialogs()
+}
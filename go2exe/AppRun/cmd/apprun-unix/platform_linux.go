@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/ui"
+)
+
+var platform = envcheck.Linux
+
+// 从终端运行，应用在同一个终端中运行，启动器等它退出
+const detachApp = false
+
+// 安装文件（uv 和 python 目录）与启动器在同一目录
+func appDir(exeDir string) string {
+	return exeDir
+}
+
+// 日志写到程序目录下的 app.log，与 Windows 版相同
+func openLog(dir string) (*os.File, error) {
+	return os.OpenFile(filepath.Join(dir, "app.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// 提示和进度都显示在终端中
+func setupUI() {
+	ui.UseTextMode("Linux")
+}
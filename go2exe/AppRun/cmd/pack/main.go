@@ -0,0 +1,40 @@
+// pack 把编译好的启动器、Python 项目、uv 安装文件和离线安装包打包成带版本信息的 Windows 安装程序，
+// 代替直接压缩程序目录分发。在 go2exe/AppRun 中运行，默认从仓库根目录收集文件：
+//
+//	go run ./cmd/pack -format nsis
+//	go run ./cmd/pack -format msi -publisher "SpeakMyBook" -sign-cert cert.pfx
+//
+// NSIS 需要 PATH 中有 makensis，MSI 需要 WiX Toolset 4 或更高版本的 wix；签名需要 Windows SDK 的 signtool，
+// 证书密码从环境变量 SPEAKMYBOOK_SIGN_PASSWORD 读取，不出现在命令行中
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"go2exe/internal/pack"
+)
+
+func main() {
+	opts := pack.Options{Out: os.Stdout}
+	flag.StringVar(&opts.Root, "root", "../..", "程序目录的源文件所在目录（仓库根目录）")
+	flag.StringVar(&opts.Launcher, "launcher", "", "编译好的启动器，默认为 <root>/SpeakMyBook.exe")
+	flag.StringVar(&opts.Format, "format", pack.NSIS, "安装程序的格式：nsis 或 msi")
+	flag.StringVar(&opts.Output, "o", "", "生成的安装程序，默认为 <root>/dist/SpeakMyBook-<版本>-setup.exe 或 .msi")
+	flag.StringVar(&opts.Version, "version", "", "版本号，默认为 python/pyproject.toml 中的版本")
+	flag.StringVar(&opts.Publisher, "publisher", "SpeakMyBook", "发布者")
+	flag.StringVar(&opts.Sign.Cert, "sign-cert", "", "签名使用的 .pfx 证书，留空时不签名")
+	flag.StringVar(&opts.Sign.Tool, "signtool", "", "signtool 的路径，默认在 PATH 中查找")
+	flag.StringVar(&opts.Sign.TimestampURL, "timestamp-url", "", "签名使用的时间戳服务器")
+	flag.StringVar(&opts.WorkDir, "work-dir", "", "保留生成的安装脚本的目录，用于排查打包问题")
+	flag.Parse()
+	opts.Sign.Password = os.Getenv("SPEAKMYBOOK_SIGN_PASSWORD")
+
+	output, err := pack.Build(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打包失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已生成 %s\n", output)
+}
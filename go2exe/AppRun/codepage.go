@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+var (
+	getACP   = kernel32.NewProc("GetACP")
+	getOEMCP = kernel32.NewProc("GetOEMCP")
+	CP_UTF8  = 65001
+)
+
+// 系统的 ANSI 和 OEM 代码页
+func systemCodePages() (acp, oemcp int) {
+	a, _, _ := getACP.Call()
+	o, _, _ := getOEMCP.Call()
+	return int(a), int(o)
+}
+
+// 是否启用了系统级的 UTF-8 Beta 选项
+func utf8BetaEnabled() bool {
+	acp, oemcp := systemCodePages()
+	return acp == CP_UTF8 || oemcp == CP_UTF8
+}
+
+// 代码页说明，写入日志和诊断包
+func codePageInfo() string {
+	acp, oemcp := systemCodePages()
+	info := fmt.Sprintf("ANSI %d, OEM %d", acp, oemcp)
+	if acp == CP_UTF8 || oemcp == CP_UTF8 {
+		info += "（已启用 UTF-8 Beta）"
+	}
+	return info
+}
+
+// 按配置得到 uv 和应用的 Python 编码环境变量。
+// 启用了 UTF-8 Beta 时 ANSI 和 OEM 代码页都是 65001，按 GBK 处理控制台或文件名的库会乱码或抛出异常，
+// 这时不论配置如何都使用 UTF-8
+func pythonEncodingEnv(cfg EncodingConfig, utf8Beta bool) map[string]string {
+	env := map[string]string{}
+	if cfg.UTF8Mode || utf8Beta {
+		env["PYTHONUTF8"] = "1"
+	}
+	io := strings.TrimSpace(cfg.IOEncoding)
+	if utf8Beta && io != "" && !strings.HasPrefix(strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(io)), "utf8") {
+		log.Printf("io_encoding = %q 与 UTF-8 代码页冲突，改用 utf-8", io)
+		io = ""
+	}
+	if io == "" && utf8Beta {
+		io = "utf-8"
+	}
+	if io != "" {
+		env["PYTHONIOENCODING"] = io
+	}
+	return env
+}
+
+// 检查系统代码页，按配置设置 uv 和应用（都继承本进程的环境变量）的 Python 编码，
+// 覆盖用户环境中与之冲突的设置
+func applyCodePage(cfg EncodingConfig) {
+	log.Printf("系统代码页: %s", codePageInfo())
+	beta := utf8BetaEnabled()
+	if beta {
+		log.Printf("系统启用了“使用 Unicode UTF-8 提供全球语言支持”（Beta），子进程统一使用 UTF-8")
+	}
+	env := pythonEncodingEnv(cfg, beta)
+	for k, v := range env {
+		if old, ok := childEnv.Lookup(k); ok && old != v {
+			log.Printf("环境变量 %s=%s 改为 %s", k, old, v)
+		}
+		childEnv.Set(k, v)
+	}
+	// 旧版控制台 I/O 不使用 UTF-8 读写控制台，与 UTF-8 模式一起使用时中文会出错
+	if _, ok := childEnv.Lookup("PYTHONLEGACYWINDOWSSTDIO"); ok && env["PYTHONUTF8"] == "1" {
+		log.Printf("UTF-8 模式下不使用 PYTHONLEGACYWINDOWSSTDIO")
+		childEnv.Unset("PYTHONLEGACYWINDOWSSTDIO")
+	}
+	log.Printf("Python 编码: PYTHONUTF8=%s, PYTHONIOENCODING=%s", childEnv.Get("PYTHONUTF8"), childEnv.Get("PYTHONIOENCODING"))
+}
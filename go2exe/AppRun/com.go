@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// 最小化的 COM 调用辅助，只覆盖启动器用到的接口
+
+var (
+	ole32                    = syscall.NewLazyDLL("ole32.dll")
+	coInitializeEx           = ole32.NewProc("CoInitializeEx")
+	coUninitialize           = ole32.NewProc("CoUninitialize")
+	coCreateInstance         = ole32.NewProc("CoCreateInstance")
+	COINIT_APARTMENTTHREADED = 0x2
+	CLSCTX_INPROC_SERVER     = 0x1
+	VT_LPWSTR                = 31
+)
+
+// COM GUID
+type GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+// 解析 "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx" 形式的 GUID
+func mustGUID(s string) GUID {
+	var g GUID
+	var d4a uint16
+	var d4b uint64
+	if _, err := fmt.Sscanf(s, "%08x-%04x-%04x-%04x-%012x", &g.Data1, &g.Data2, &g.Data3, &d4a, &d4b); err != nil {
+		panic("无效的 GUID: " + s)
+	}
+	g.Data4[0] = byte(d4a >> 8)
+	g.Data4[1] = byte(d4a)
+	for i := 0; i < 6; i++ {
+		g.Data4[2+i] = byte(d4b >> (8 * (5 - i)))
+	}
+	return g
+}
+
+// COM 接口，内存布局的第一个字段是虚函数表指针
+type comObject struct {
+	vtbl *[64]uintptr
+}
+
+// 按虚函数表序号调用方法，返回 HRESULT 错误
+func (o *comObject) call(index int, args ...uintptr) error {
+	r, _, _ := syscall.SyscallN(o.vtbl[index], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	if int32(r) < 0 {
+		return fmt.Errorf("HRESULT 0x%08X", uint32(r))
+	}
+	return nil
+}
+
+// 接口指针的数值形式，用于作为参数传递
+func (o *comObject) ptr() uintptr {
+	return uintptr(unsafe.Pointer(o))
+}
+
+// IUnknown::QueryInterface
+func (o *comObject) queryInterface(iid *GUID) (*comObject, error) {
+	var out *comObject
+	err := o.call(0, uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(&out)))
+	return out, err
+}
+
+// IUnknown::Release
+func (o *comObject) release() {
+	if o != nil {
+		o.call(2)
+	}
+}
+
+// 创建 COM 对象
+func createInstance(clsid, iid *GUID) (*comObject, error) {
+	var out *comObject
+	r, _, _ := coCreateInstance.Call(
+		uintptr(unsafe.Pointer(clsid)),
+		0,
+		uintptr(CLSCTX_INPROC_SERVER),
+		uintptr(unsafe.Pointer(iid)),
+		uintptr(unsafe.Pointer(&out)),
+	)
+	if int32(r) < 0 {
+		return nil, fmt.Errorf("CoCreateInstance 失败: HRESULT 0x%08X", uint32(r))
+	}
+	return out, nil
+}
+
+// 属性键
+type propertyKey struct {
+	fmtid GUID
+	pid   uint32
+}
+
+// 只用于传递字符串的 PROPVARIANT
+type propVariant struct {
+	vt       uint16
+	reserved [3]uint16
+	val      uintptr
+	pad      uintptr
+}
+
+// 构造 VT_LPWSTR 类型的 PROPVARIANT，返回的 UTF-16 切片需在使用期间保持存活
+func stringPropVariant(s string) (propVariant, []uint16) {
+	buf, _ := syscall.UTF16FromString(s)
+	return propVariant{vt: uint16(VT_LPWSTR), val: uintptr(unsafe.Pointer(&buf[0]))}, buf
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"go2exe/internal/appconfig"
+	"go2exe/internal/envcheck"
+)
+
+// 配置文件名，放在可执行文件同目录
+const configFileName = "apprun.toml"
+
+// 数据目录所在的位置，为空时使用当前用户的 LocalAppData。模拟场景中改为临时目录，提权的安装进程中为普通用户的 LocalAppData
+var dataRoot string
+
+// 启动器的数据目录（状态文件等），位于当前用户的 LocalAppData 下
+func dataDir() string {
+	if dataRoot != "" {
+		return filepath.Join(dataRoot, "SpeakMyBook")
+	}
+	return filepath.Join(os.Getenv("LOCALAPPDATA"), "SpeakMyBook")
+}
+
+// 启动器配置
+type Config struct {
+	UI          UIConfig          `toml:"ui"`
+	Tray        TrayConfig        `toml:"tray"`
+	Shell       ShellConfig       `toml:"shell"`
+	Network     NetworkConfig     `toml:"network"`
+	Install     InstallConfig     `toml:"install"`
+	Encoding    EncodingConfig    `toml:"encoding"`
+	Diagnostics DiagnosticsConfig `toml:"diagnostics"`
+	Control     ControlConfig     `toml:"control"`
+	Logging     LoggingConfig     `toml:"logging"`
+	Checks      ChecksConfig      `toml:"checks"`
+	Update      UpdateConfig      `toml:"update"`
+	Health      HealthConfig      `toml:"health"`
+}
+
+// 界面设置
+type UIConfig struct {
+	Language       string `toml:"language" check:"auto,zh-CN,en-US"` // 界面语言：auto（跟随系统）、zh-CN 或 en-US
+	SpeechLanguage string `toml:"speech_language"`                   // 默认朗读语言，例如 zh-CN；auto 表示使用系统默认语音的语言
+}
+
+// 托盘与快捷键设置
+type TrayConfig struct {
+	Hotkey       string `toml:"hotkey"`                                        // 全局快捷键，例如 "Ctrl+Alt+R"，留空表示不注册
+	HotkeyAction string `toml:"hotkey_action" check:"activate,read_selection"` // 按下快捷键后的动作：activate 或 read_selection
+	Icon         bool   `toml:"icon"`                                          // 应用启动后在通知区域显示图标，提供查看日志、修复环境等操作
+}
+
+// 资源管理器集成设置
+type ShellConfig struct {
+	ContextMenu bool `toml:"context_menu"` // 为支持的文件类型添加“用 SpeakMyBook 朗读”右键菜单
+	SendTo      bool `toml:"send_to"`      // 首次安装时在“发送到”菜单中添加 SpeakMyBook
+	JumpList    bool `toml:"jump_list"`    // 在任务栏跳转列表中显示最近打开的书
+	// 首次安装后在开始菜单和桌面上添加启动 SpeakMyBook 的快捷方式
+	StartMenu       bool `toml:"start_menu"`
+	DesktopShortcut bool `toml:"desktop_shortcut"`
+	// 用 SpeakMyBook 打开 .epub、.txt、.pdf：ask（首次安装后询问，回答保存到配置文件）、yes 或 no
+	FileAssociations string `toml:"file_associations" check:"ask,yes,no"`
+}
+
+// 网络设置
+type NetworkConfig struct {
+	Proxy   string `toml:"proxy"`    // 代理地址，例如 "http://proxy.corp:8080"；"none" 表示不使用任何代理；留空则使用环境变量或系统代理
+	NoProxy string `toml:"no_proxy"` // 不走代理的主机，逗号分隔
+	// 同步依赖使用的 PyPI 镜像：auto（首次运行时测速选择最快的）、tsinghua、aliyun、pypi 或完整的索引地址
+	Index string `toml:"index" check:"mirror"`
+}
+
+// 安装设置
+type InstallConfig struct {
+	MinFreeSpaceMB int64  `toml:"min_free_space_mb"` // 安装时磁盘剩余空间低于该值（MB）会暂停并提示释放空间
+	TempDir        string `toml:"temp_dir"`          // 安装时解压等临时文件的存放目录，留空使用系统临时目录
+	// 安装 uv、Python 和同步依赖时每条命令的最长执行时间（分钟），超时后结束命令，0 表示不限制
+	StepTimeoutMinutes int `toml:"step_timeout_minutes"`
+	// 检查 uv、Python 和 PowerShell 的命令的最长执行时间（秒），0 表示不限制
+	CheckTimeoutSeconds int `toml:"check_timeout_seconds"`
+	// 启动应用后等待它报告就绪的最长时间（秒），超时视为已启动，0 表示不等待
+	ReadyTimeoutSeconds int `toml:"ready_timeout_seconds"`
+	// 安装 uv、Python 和同步依赖因网络错误失败时最多尝试的次数，1 表示不重试
+	RetryAttempts int `toml:"retry_attempts"`
+	// 第一次重试前等待的时间（秒），之后每次翻倍，最长 1 分钟
+	RetryBackoffSeconds int `toml:"retry_backoff_seconds"`
+	// 首次运行向导中选择的安装位置，留空使用 uv 的默认位置
+	UVDir     string `toml:"uv_dir"`
+	PythonDir string `toml:"python_dir"`
+	// 便携模式：yes 时 uv、Python 和启动器数据都放在程序目录下的 runtime 目录中；auto 时在 Windows 沙盒或
+	// 临时用户配置文件中询问，回答保存到配置文件
+	Portable string `toml:"portable" check:"auto,yes,no"`
+	// 黄金镜像模式（机房部署）：管理员用 --provision-golden 把 uv、Python、虚拟环境和模型文件一次性准备在程序目录中，
+	// 之后程序目录可以只读（或由还原卡保护），每个用户启动时跳过全部安装和同步，只在用户目录中写日志和数据
+	GoldenImage bool `toml:"golden_image"`
+	// 已在首次运行向导中接受许可协议，之后不再显示许可协议页
+	LicenseAccepted bool `toml:"license_accepted"`
+	// 应用需要的 Python 版本约束，例如 "3.11.9"、"3.11.*" 或 ">=3.10,<3.13"
+	PythonVersion string `toml:"python_version"`
+	// Python 的架构：x86_64、x86、aarch64，或 auto（按本机架构选择，Windows on ARM 上为 aarch64）
+	PythonArch string `toml:"python_arch" check:"auto,x86_64,x86,aarch64"`
+	// 随程序分发的 Python 安装包所在目录（相对于程序目录），安装前按 checksums.txt 校验
+	PythonArtifacts string `toml:"python_artifacts"`
+	// 依赖中有 GPU 加速的包（例如 PyTorch）时使用的版本：cpu、cuda 或 auto（有 NVIDIA 显卡时使用 CUDA 版本）
+	GPU string `toml:"gpu" check:"cpu,cuda,auto"`
+	// 语音和模型文件的存放目录，多个安装共用，留空使用 %LOCALAPPDATA%\SpeakMyBook\models
+	ModelsDir string `toml:"models_dir"`
+	// 下载 python/models.json 中有 cid 的模型文件时使用的 IPFS 网关，例如 ["https://ipfs.io", "https://dweb.link"]。
+	// 从下载地址下载失败时依次改用网关（prefer_ipfs 时先用网关），下载的内容同样校验 SHA-256；留空不使用
+	IPFSGateways []string `toml:"ipfs_gateways"`
+	PreferIPFS   bool     `toml:"prefer_ipfs"`
+	// 局域网共享的预热目录（例如 \\server\share\speakmybook），其中可以有 uv-cache、wheels 和 models 子目录。
+	// 同步依赖和下载模型文件前先从这里复制，多台电脑批量部署时不必各自从网络下载；无法访问时照常下载
+	SeedDir string `toml:"seed_dir"`
+	// 执行下载的 uv 安装脚本等文件前要求有效的 Authenticode 签名；allow_unsigned 为 true 时只记录，仍然执行。
+	// trusted_publishers 为签名证书的主题名称，留空时接受任何有效签名
+	AllowUnsigned     bool     `toml:"allow_unsigned"`
+	TrustedPublishers []string `toml:"trusted_publishers"`
+	// 局域网中的启动器互相共享模型文件：peer_share 时常驻并在 peer_listen 上提供本机的模型文件，
+	// 通过 mDNS 让其他电脑发现；peer_fetch 时下载模型文件前先从发现的电脑复制并校验 SHA-256
+	PeerShare  bool   `toml:"peer_share"`
+	PeerFetch  bool   `toml:"peer_fetch"`
+	PeerListen string `toml:"peer_listen" check:"hostport"`
+	// 最多同时下载的语音、模型和应用更新文件数，1 表示逐个下载
+	MaxParallelDownloads int `toml:"max_parallel_downloads"`
+	// 最多同时执行的相互独立的启动步骤（同步依赖、下载模型文件等），0 表示不限制
+	MaxParallelSteps int `toml:"max_parallel_steps"`
+	// uv 同时执行的下载、构建和安装数量（UV_CONCURRENT_DOWNLOADS 等），0 表示使用 uv 的默认值
+	UVConcurrentDownloads int `toml:"uv_concurrent_downloads"`
+	UVConcurrentBuilds    int `toml:"uv_concurrent_builds"`
+	UVConcurrentInstalls  int `toml:"uv_concurrent_installs"`
+}
+
+// 应用更新设置
+type UpdateConfig struct {
+	// 发布清单的地址，启动时和托盘菜单“检查更新”时查询，有新版本时询问是否更新 python 目录；留空不检查
+	ManifestURL string `toml:"manifest_url" check:"url"`
+}
+
+// uv 和应用的 Python 编码设置
+type EncodingConfig struct {
+	UTF8Mode   bool   `toml:"utf8_mode"`   // 以 UTF-8 模式（PYTHONUTF8=1）运行，文件名和文件内容默认按 UTF-8 处理
+	IOEncoding string `toml:"io_encoding"` // 标准输入输出的编码（PYTHONIOENCODING），例如 "utf-8" 或 "utf-8:replace"；留空不设置
+}
+
+// 诊断包设置
+type DiagnosticsConfig struct {
+	ScrubUser    bool `toml:"scrub_user"`    // 把用户名替换为 <user>
+	ScrubMachine bool `toml:"scrub_machine"` // 把计算机名和域名替换为 <machine>
+	ScrubBooks   bool `toml:"scrub_books"`   // 把书籍路径和文件名替换为 <book-N>
+	AutoCollect  bool `toml:"auto_collect"`  // 启动失败时自动在桌面生成诊断包
+}
+
+// 集中管理控制接口设置，证书路径可以是相对于程序目录的路径
+type ControlConfig struct {
+	Enabled        bool   `toml:"enabled"`                 // 启用控制接口，启动器会在应用退出后继续常驻
+	Listen         string `toml:"listen" check:"hostport"` // 监听地址，默认只监听本机
+	CertFile       string `toml:"cert_file"`               // 服务端证书（PEM）
+	KeyFile        string `toml:"key_file"`                // 服务端私钥（PEM）
+	ClientCAFile   string `toml:"client_ca_file"`          // 签发管理控制台客户端证书的 CA（PEM）
+	AllowedClients string `toml:"allowed_clients"`         // 允许的客户端证书 CN，逗号分隔，留空表示接受 CA 签发的所有证书
+}
+
+// 健康检查接口设置
+type HealthConfig struct {
+	Enabled bool `toml:"enabled"` // 在本机提供健康检查接口，应用运行期间启动器常驻
+	Port    int  `toml:"port"`    // 监听的端口，只监听 127.0.0.1
+}
+
+// 启动检查设置，每项的执行时机：always（每次启动）、after_update（首次运行和安装包更新后）、
+// first_run（只在首次运行）或 never。启动失败后下次启动会执行全部检查
+type ChecksConfig struct {
+	UV     string `toml:"uv" check:"always,after_update,first_run,never"`     // 检查 uv 是否可用
+	Python string `toml:"python" check:"always,after_update,first_run,never"` // 检查所需的 Python 是否已安装
+	Sync   string `toml:"sync" check:"always,after_update,first_run,never"`   // 启动应用前同步依赖（uv sync）
+	Verify string `toml:"verify" check:"always,after_update,first_run,never"` // 启动时按 checksums.txt 校验安装文件（安装前总是校验）
+	// 显示托盘图标时，应用启动后在后台以低优先级补做本次跳过的检查并检查更新，发现问题时在托盘通知
+	Background bool `toml:"background"`
+	// 环境与上次成功启动时相同（uv、Python、uv.lock 和虚拟环境都没有变化）时跳过以上全部检查，直接启动应用
+	Stamp bool `toml:"stamp"`
+}
+
+// 远程日志设置
+type LoggingConfig struct {
+	RemoteURL    string `toml:"remote_url" check:"url=http|https|udp|tcp"` // 日志收集器地址：http(s)://...，或 udp://host:514、tcp://host:514 发送 syslog；留空不发送
+	BatchSize    int    `toml:"batch_size"`                                // 每批最多发送的日志条数
+	FlushSeconds int    `toml:"flush_seconds"`                             // 最长多久发送一次（秒）
+}
+
+// 默认配置
+func defaultConfig() Config {
+	return Config{
+		UI: UIConfig{
+			Language:       "auto",
+			SpeechLanguage: "auto",
+		},
+		Tray: TrayConfig{
+			HotkeyAction: "activate",
+		},
+		Shell: ShellConfig{
+			SendTo:           true,
+			JumpList:         true,
+			StartMenu:        true,
+			FileAssociations: "ask",
+		},
+		Install: InstallConfig{
+			MinFreeSpaceMB:       500,
+			Portable:             "auto",
+			StepTimeoutMinutes:   30,
+			CheckTimeoutSeconds:  60,
+			ReadyTimeoutSeconds:  60,
+			RetryAttempts:        3,
+			RetryBackoffSeconds:  5,
+			PythonVersion:        "3.11.9",
+			PythonArch:           envcheck.ArchAuto,
+			PythonArtifacts:      "python/20240814",
+			GPU:                  "cpu",
+			MaxParallelDownloads: 4,
+			PeerListen:           ":7444",
+		},
+		Encoding: EncodingConfig{
+			UTF8Mode:   true,
+			IOEncoding: "utf-8",
+		},
+		Diagnostics: DiagnosticsConfig{
+			ScrubUser:    true,
+			ScrubMachine: true,
+			ScrubBooks:   true,
+			AutoCollect:  true,
+		},
+		Control: ControlConfig{
+			Listen: "127.0.0.1:7443",
+		},
+		Health: HealthConfig{
+			Port: 7445,
+		},
+		Logging: LoggingConfig{
+			BatchSize:    100,
+			FlushSeconds: 5,
+		},
+		Checks: ChecksConfig{
+			UV:         "always",
+			Python:     "always",
+			Sync:       "always",
+			Verify:     "never",
+			Background: true,
+			Stamp:      true,
+		},
+	}
+}
+
+// 读取配置文件，再按环境变量和策略覆盖（见 readEffectiveConfig），文件不存在时使用默认配置。
+// 有问题的设置使用默认值，返回列出所有问题的 *appconfig.Error
+func loadConfig(exeDir string) (Config, error) {
+	e := readEffectiveConfig(exeDir)
+	if len(e.Problems) > 0 {
+		return e.cfg, &appconfig.Error{Problems: e.Problems}
+	}
+	return e.cfg, nil
+}
+
+// 修改配置文件中 section 节的值，保留其他内容和注释；文件、节或键不存在时添加
+func saveConfigValues(exeDir, section string, values map[string]interface{}) error {
+	return appconfig.SaveValues(filepath.Join(exeDir, configFileName), section, values)
+}
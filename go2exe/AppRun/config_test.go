@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"go2exe/internal/appconfig"
+)
+
+func TestIsConfigEnvVar(t *testing.T) {
+	schema := configSchema()
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"SPEAKMYBOOK_MIRROR", true},
+		{"speakmybook_python_version", true},
+		{"SPEAKMYBOOK_INSTALL_GPU", true},
+		{"SPEAKMYBOOK_UI_LANGUAGE", true},
+		{"APPRUN_NETWORK_INDEX", true},
+		// 启动器传给应用的变量不是配置项
+		{"SPEAKMYBOOK_LANG", false},
+		{"SPEAKMYBOOK_IPC_PIPE", false},
+		{"PATH", false},
+	}
+	for _, tt := range tests {
+		if got := appconfig.IsEnvVar(tt.name, schema); got != tt.want {
+			t.Errorf("IsEnvVar(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"go2exe/internal/appconfig"
+	"go2exe/internal/exitcode"
+	"go2exe/internal/i18n"
+	"go2exe/internal/ui"
+)
+
+// 配置项列表，按 Config 中的顺序排列
+func configSchema() []appconfig.Field {
+	return appconfig.Schema(Config{})
+}
+
+// 覆盖配置文件和环境变量的组策略注册表项（HKCU 和 HKLM 下），值名为 "节.键"，例如 install.gpu。
+// HKLM 的策略优先于 HKCU
+const configPolicyKey = `SOFTWARE\Policies\SpeakMyBook`
+
+// 组策略中的配置，测试时替换
+var policyConfigValues = func(schema []appconfig.Field) map[string]appconfig.Value {
+	values := map[string]appconfig.Value{}
+	for _, root := range []struct {
+		key  uintptr
+		name string
+	}{{HKEY_CURRENT_USER, "HKCU"}, {HKEY_LOCAL_MACHINE, "HKLM"}} {
+		for _, f := range schema {
+			raw, err := regGetValue(root.key, configPolicyKey, f.Key)
+			if err != nil {
+				continue
+			}
+			values[f.Key] = appconfig.Value{Value: appconfig.ParseString(f.Kind, raw), Source: i18n.T("策略 %s\\%s\\%s", root.name, configPolicyKey, f.Key)}
+		}
+	}
+	return values
+}
+
+// 合并后的配置：配置文件、环境变量和策略依次覆盖默认值
+type effectiveConfig struct {
+	cfg Config
+	appconfig.Effective
+}
+
+// 读取并检查配置文件、环境变量和策略，按顺序合并。配置文件无法解析时忽略整个文件，有问题的设置使用默认值
+func readEffectiveConfig(exeDir string) effectiveConfig {
+	schema := configSchema()
+	e := effectiveConfig{cfg: defaultConfig()}
+	e.DecodeFile(&e.cfg, filepath.Join(exeDir, configFileName), schema)
+	e.Decode(&e.cfg, appconfig.EnvValues(schema), schema)
+	e.Decode(&e.cfg, policyConfigValues(schema), schema)
+	return e
+}
+
+// 按 TOML 格式列出合并后的全部配置，每项注明来源
+func (e effectiveConfig) format() []string {
+	var lines []string
+	section := ""
+	root := reflect.ValueOf(e.cfg)
+	for _, f := range configSchema() {
+		sec, name, _ := strings.Cut(f.Key, ".")
+		if sec != section {
+			if section != "" {
+				lines = append(lines, "")
+			}
+			lines = append(lines, "["+sec+"]")
+			section = sec
+		}
+		field, _ := appconfig.LookupField(root, strings.Split(f.Key, "."))
+		source, ok := e.Sources[f.Key]
+		if !ok {
+			source = i18n.T("默认值")
+		}
+		lines = append(lines, fmt.Sprintf("%s = %s  # %s", name, appconfig.FormatValue(field.Interface()), source))
+	}
+	return lines
+}
+
+// config 子命令的输出，写入结果文件
+var configReport []string
+
+// config validate：检查配置文件、环境变量和策略，列出所有问题；
+// config print-effective：列出合并后的全部配置及每项的来源。重定向标准输出时也写到标准输出
+func runConfigCommand(exeDir string, args []string) error {
+	if len(args) == 0 || (args[0] != "validate" && args[0] != "print-effective") {
+		return fmt.Errorf("用法: config validate 或 config print-effective")
+	}
+	e := readEffectiveConfig(exeDir)
+	for _, p := range e.Problems {
+		log.Printf("配置问题: %s", p)
+	}
+	if args[0] == "print-effective" {
+		configReport = e.format()
+		fmt.Fprintln(os.Stdout, strings.Join(configReport, "\n"))
+		ui.MessageBox(i18n.T("生效的配置"), strings.Join(configReport, "\n"))
+		return nil
+	}
+
+	for _, p := range e.Problems {
+		configReport = append(configReport, p.String())
+	}
+	if len(configReport) == 0 {
+		fmt.Fprintln(os.Stdout, i18n.T("配置检查通过"))
+		ui.MessageBox(i18n.T("配置检查"), i18n.T("配置检查通过"))
+		return nil
+	}
+	fmt.Fprintln(os.Stdout, strings.Join(configReport, "\n"))
+	ui.ErrorBox(i18n.T("配置检查"), i18n.T("发现 %d 个问题，有问题的设置使用默认值：\n\n%s", len(configReport), strings.Join(configReport, "\n")))
+	return withExitCode(exitcode.Config, &appconfig.Error{Problems: e.Problems})
+}
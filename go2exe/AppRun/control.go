@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go2exe/internal/control"
+	"go2exe/internal/diagnostics"
+	"go2exe/internal/ui"
+)
+
+// 控制接口的实际操作
+type controlService struct {
+	exeDir string
+	cfg    Config
+}
+
+// 无人值守安装，不显示任何窗口
+func (s controlService) Install() error {
+	inst := newInstaller(s.exeDir)
+	inst.Out = ui.Discard
+	_, err := ensureEnvironment(s.exeDir, inst, setupOptions{unattended: true})
+	return err
+}
+
+// 重新同步依赖。应用运行时虚拟环境中的文件被占用，需要先关闭应用
+func (s controlService) Update() error {
+	if isAppRunning() || findAppWindow() != 0 {
+		return fmt.Errorf("应用正在运行，请先关闭应用")
+	}
+	inst := newInstaller(s.exeDir)
+	inst.Out = ui.Discard
+	return syncVenv(s.exeDir, inst)
+}
+
+func (s controlService) Status() control.Status {
+	check := newChecker()
+	status := control.Status{AppRunning: isAppRunning() || findAppWindow() != 0}
+	status.Hostname, _ = os.Hostname()
+	status.UVInstalled, _ = check.UVInstalled()
+	if status.UVInstalled {
+		status.PythonInstalled, _ = check.PythonInstalled()
+	}
+	if _, err := os.Stat(appPython(s.exeDir)); err == nil {
+		status.VenvReady = true
+	}
+	return status
+}
+
+// 诊断包先写到临时文件，再传给管理控制台
+func (s controlService) CollectDiagnostics(w io.Writer) error {
+	f, err := os.CreateTemp("", "SpeakMyBook-diagnostics-*.zip")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	if err := diagnostics.WriteBundle(f.Name(), diagnosticEntries(s.exeDir, s.cfg), newScrubber(s.cfg.Diagnostics)); err != nil {
+		return err
+	}
+	f, err = os.Open(f.Name())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// 在后台启动控制接口
+func startControlServer(exeDir string, cfg Config) error {
+	abs := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(exeDir, path)
+	}
+	server, err := control.NewServer(control.Config{
+		Listen:         cfg.Control.Listen,
+		CertFile:       abs(cfg.Control.CertFile),
+		KeyFile:        abs(cfg.Control.KeyFile),
+		ClientCAFile:   abs(cfg.Control.ClientCAFile),
+		AllowedClients: strings.Split(cfg.Control.AllowedClients, ","),
+	}, controlService{exeDir: exeDir, cfg: cfg})
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			log.Printf("控制接口停止: %v", err)
+		}
+	}()
+	return nil
+}
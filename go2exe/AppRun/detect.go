@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/i18n"
+)
+
+// 检查 uv 和所需的 Python 是否已安装。上次检测到的文件仍在时直接使用检测结果，不启动任何进程；
+// 否则同时运行两项检查（各要启动一个 uv 进程）
+func detectEnvironment(check envcheck.Checker, opts setupOptions) (uvInstalled, pythonInstalled bool, err error) {
+	if !opts.skipUV || !opts.skipPython {
+		if cachedEnvironment() {
+			log.Printf("uv 和 Python %s 与上次检测到的相同，跳过检查", pythonVersion())
+			return true, true, nil
+		}
+	}
+
+	uvInstalled = true
+	var uvOutput string
+	var python *envcheck.Installation
+	var wg sync.WaitGroup
+	if opts.skipUV {
+		log.Printf("按配置跳过 uv 检查")
+	} else {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uvInstalled, uvOutput = check.UVInstalled()
+		}()
+	}
+	if !opts.skipPython {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			python, err = check.FindPython()
+		}()
+	}
+	wg.Wait()
+
+	if !uvInstalled {
+		// 可能是 uv 已安装但本进程的 PATH 是在安装之前继承的，找到后再检查一次 Python
+		uvInstalled = locateUV(check)
+		if uvInstalled && !opts.skipPython {
+			python, err = check.FindPython()
+		}
+	}
+	if !opts.skipUV {
+		log.Printf("uv安装状态: %v, 输出: %s", uvInstalled, uvOutput)
+		addOutputText(i18n.T("uv安装状态: %v", uvInstalled))
+	}
+
+	// 没有 uv 时 Python 必然未安装，检查命令的结果不可信
+	switch {
+	case !uvInstalled:
+		return false, false, nil
+	case opts.skipPython:
+		log.Printf("按配置跳过 Python 检查")
+		return true, true, nil
+	case err != nil:
+		log.Printf("检查Python安装状态失败: %v", err)
+		addOutputText(i18n.T("检查Python安装状态失败: %v", err))
+		return true, false, err
+	}
+	if python != nil {
+		saveDetectedEnvironment(python.Path)
+	}
+	return true, python != nil, nil
+}
+
+// 上次检测到的 uv 和 Python 解释器是否仍在，且 Python 的版本要求没有改变。仍在时确保 uv 在 PATH 中
+func cachedEnvironment() bool {
+	state, _ := readCheckState()
+	if state.UVPath == "" || state.PythonPath == "" || state.PythonRequest != pythonRequest() {
+		return false
+	}
+	for _, path := range []string{state.UVPath, state.PythonPath} {
+		if _, err := os.Stat(path); err != nil {
+			log.Printf("上次检测到的 %s 已不存在，重新检查", path)
+			return false
+		}
+	}
+	addUVToPath(state.UVPath)
+	return true
+}
+
+// uv 不在 PATH 中时（PATH 是在安装 uv 之前继承的）把 uvPath 所在的目录加入传给外部命令的 PATH，之后的 uv 命令才能找到它
+func addUVToPath(uvPath string) {
+	if _, err := childEnv.LookPath("uv"); err != nil {
+		envcheck.AddToPath(childEnv, filepath.Dir(uvPath))
+	}
+}
+
+// 记下检测到的 uv 和 Python 解释器，之后的启动不再运行检查命令
+func saveDetectedEnvironment(pythonPath string) {
+	uvPath, ok := envcheck.FindUV(childEnv, installConfig.UVDir)
+	if !ok || pythonPath == "" {
+		return
+	}
+	state, _ := readCheckState()
+	state.UVPath, state.PythonPath, state.PythonRequest = uvPath, pythonPath, pythonRequest()
+	if err := writeCheckState(state); err != nil {
+		log.Printf("保存检测结果失败: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+var (
+	enumDisplayDevices              = user32.NewProc("EnumDisplayDevicesW")
+	CLSID_MMDeviceEnumerator        = mustGUID("BCDE0395-E52F-467C-8E3D-C4579291692E")
+	IID_IMMDeviceEnumerator         = mustGUID("A95664D2-9614-4F35-A746-DE8DB63617E6")
+	eRender                         = 0
+	DEVICE_STATE_ACTIVE             = 0x1
+	DISPLAY_DEVICE_MIRRORING_DRIVER = 0x8
+	COINIT_MULTITHREADED            = 0x0
+	RPC_E_CHANGED_MODE              = 0x80010106
+)
+
+// 本机的音频输出设备和显卡
+type deviceInfo struct {
+	AudioOutputs int      // 可用的音频输出设备数量，无法检测时为 -1
+	GPUs         []string // 显卡名称
+	CUDA         bool     // 是否安装了 NVIDIA 驱动提供的 CUDA（nvcuda.dll）
+}
+
+// DISPLAY_DEVICEW
+type displayDevice struct {
+	cb           uint32
+	deviceName   [32]uint16
+	deviceString [128]uint16
+	stateFlags   uint32
+	deviceID     [128]uint16
+	deviceKey    [128]uint16
+}
+
+// 检测音频输出设备和显卡
+func detectDevices() deviceInfo {
+	d := deviceInfo{AudioOutputs: -1, GPUs: displayAdapters(), CUDA: cudaAvailable()}
+	if n, err := audioOutputCount(); err != nil {
+		log.Printf("检测音频输出设备失败: %v", err)
+	} else {
+		d.AudioOutputs = n
+	}
+	return d
+}
+
+// 通过 Core Audio（IMMDeviceEnumerator）统计已启用的音频输出设备
+func audioOutputCount() (int, error) {
+	// COM 对象必须在初始化了 COM 的同一个线程上使用
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if r, _, _ := coInitializeEx.Call(0, uintptr(COINIT_MULTITHREADED)); uint32(r) != uint32(RPC_E_CHANGED_MODE) {
+		defer coUninitialize.Call()
+	}
+
+	enumerator, err := createInstance(&CLSID_MMDeviceEnumerator, &IID_IMMDeviceEnumerator)
+	if err != nil {
+		return 0, err
+	}
+	defer enumerator.release()
+	// IMMDeviceEnumerator::EnumAudioEndpoints
+	var devices *comObject
+	if err := enumerator.call(3, uintptr(eRender), uintptr(DEVICE_STATE_ACTIVE), uintptr(unsafe.Pointer(&devices))); err != nil {
+		return 0, fmt.Errorf("EnumAudioEndpoints 失败: %v", err)
+	}
+	defer devices.release()
+	// IMMDeviceCollection::GetCount
+	var count uint32
+	if err := devices.call(3, uintptr(unsafe.Pointer(&count))); err != nil {
+		return 0, fmt.Errorf("GetCount 失败: %v", err)
+	}
+	return int(count), nil
+}
+
+// 显卡名称，多个显示器连接同一块显卡时只列一次
+func displayAdapters() []string {
+	var names []string
+	seen := map[string]bool{}
+	for i := 0; ; i++ {
+		var dd displayDevice
+		dd.cb = uint32(unsafe.Sizeof(dd))
+		if r, _, _ := enumDisplayDevices.Call(0, uintptr(i), uintptr(unsafe.Pointer(&dd)), 0); r == 0 {
+			break
+		}
+		name := syscall.UTF16ToString(dd.deviceString[:])
+		if name == "" || dd.stateFlags&uint32(DISPLAY_DEVICE_MIRRORING_DRIVER) != 0 || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// NVIDIA 驱动会在系统目录中安装 nvcuda.dll；只检查文件，不加载
+func cudaAvailable() bool {
+	_, err := os.Stat(filepath.Join(os.Getenv("SystemRoot"), "System32", "nvcuda.dll"))
+	return err == nil
+}
+
+// 设备说明，写入日志和诊断包
+func (d deviceInfo) String() string {
+	audio := "未知"
+	if d.AudioOutputs >= 0 {
+		audio = fmt.Sprint(d.AudioOutputs)
+	}
+	gpus := strings.Join(d.GPUs, "; ")
+	if gpus == "" {
+		gpus = "无"
+	}
+	return fmt.Sprintf("音频输出设备 %s 个，显卡: %s，CUDA: %v", audio, gpus, d.CUDA)
+}
+
+// 告诉应用本机的设备情况：没有音频输出设备时应用可以提示用户，而不是播放时静默失败；
+// 有 CUDA 时可以选择使用 GPU 的语音模型
+func (d deviceInfo) env() []string {
+	cuda := "0"
+	if d.CUDA {
+		cuda = "1"
+	}
+	return []string{
+		fmt.Sprintf("SPEAKMYBOOK_AUDIO_OUTPUTS=%d", d.AudioOutputs),
+		"SPEAKMYBOOK_CUDA=" + cuda,
+		"SPEAKMYBOOK_GPU=" + strings.Join(d.GPUs, "; "),
+	}
+}
+
+// 启动应用前检测设备并传给应用。没有音频输出设备时提示用户，首次运行时弹出提示，之后只写在进度窗口中
+func checkDevices(firstRun bool) {
+	d := detectDevices()
+	log.Printf("设备: %s", d)
+	for _, kv := range d.env() {
+		app.Env = runner.Overlay(app.Env, kv)
+	}
+	if d.AudioOutputs != 0 {
+		return
+	}
+	msg := i18n.T("没有检测到可用的音频输出设备，朗读和试听将没有声音（导出音频文件不受影响）。\n请连接扬声器或耳机，或在设备管理器中检查声卡驱动。")
+	addOutputText(msg)
+	if firstRun {
+		ui.ErrorBox(i18n.T("没有音频输出设备"), msg)
+	}
+}
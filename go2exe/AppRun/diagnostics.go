@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"go2exe/internal/diagnostics"
+	"go2exe/internal/envcheck"
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+)
+
+// 按配置创建诊断包使用的 Scrubber，全部关闭时返回 nil
+func newScrubber(cfg DiagnosticsConfig) *diagnostics.Scrubber {
+	if !cfg.ScrubUser && !cfg.ScrubMachine && !cfg.ScrubBooks {
+		return nil
+	}
+	var o diagnostics.ScrubOptions
+	if cfg.ScrubUser {
+		o.Users = []string{os.Getenv("USERNAME")}
+	}
+	if cfg.ScrubMachine {
+		o.Machines = []string{os.Getenv("COMPUTERNAME"), os.Getenv("USERDOMAIN")}
+	}
+	if cfg.ScrubBooks {
+		o.Books = loadRecentBooks()
+		o.AllBooks = true
+	}
+	return diagnostics.NewScrubber(o)
+}
+
+// 把日志、配置和环境信息打包到桌面，返回诊断包路径。failure 为自动生成时的失败原因
+func collectDiagnostics(exeDir string, cfg Config, failure error) (string, error) {
+	desktop, err := knownFolderPath(&FOLDERID_Desktop)
+	if err != nil {
+		return "", fmt.Errorf("无法获取桌面路径: %v", err)
+	}
+	dest := filepath.Join(desktop, "SpeakMyBook-诊断-"+time.Now().Format("20060102-150405")+".zip")
+	entries := diagnosticEntries(exeDir, cfg)
+	if failure != nil {
+		entries = append(entries, diagnostics.Entry{Name: "failure.txt", Data: fmt.Sprintf("退出码: %d\r\n错误: %v", exitCode(failure), failure)})
+	}
+	return dest, diagnostics.WriteBundle(dest, entries, newScrubber(cfg.Diagnostics))
+}
+
+// 启动失败时自动生成诊断包，用户反馈问题时直接发送这个文件，不用再截图
+func collectOnFailure(exeDir string, cfg Config, err error) {
+	if err == nil || !cfg.Diagnostics.AutoCollect || errors.Is(err, runner.ErrCanceled) {
+		return
+	}
+	path, cerr := collectDiagnostics(exeDir, cfg, err)
+	if cerr != nil {
+		log.Printf("自动生成诊断包失败: %v", cerr)
+		return
+	}
+	log.Printf("已自动生成诊断包: %s", path)
+	addOutputText(i18n.T("已在桌面生成诊断包，反馈问题时请附上这个文件：%s", path))
+}
+
+// 诊断包包含的文件
+func diagnosticEntries(exeDir string, cfg Config) []diagnostics.Entry {
+	scrubbed := "否"
+	if cfg.Diagnostics.ScrubUser || cfg.Diagnostics.ScrubMachine || cfg.Diagnostics.ScrubBooks {
+		scrubbed = "是"
+	}
+	info := strings.Join([]string{
+		"生成时间: " + time.Now().Format(time.RFC3339),
+		"程序目录: " + exeDir,
+		"已去除个人信息: " + scrubbed,
+	}, "\r\n")
+
+	return []diagnostics.Entry{
+		{Name: "info.txt", Data: info},
+		{Name: "launcher.log", Path: launcherLogPath(exeDir)},
+		{Name: "app.log", Path: filepath.Join(exeDir, "python", "app.log")},
+		{Name: configFileName, Path: filepath.Join(exeDir, configFileName)},
+		{Name: "provenance.jsonl", Path: provenanceRecords().Path()},
+		{Name: "os.txt", Data: osInfo()},
+		{Name: "environment.txt", Data: diagnostics.Environment(os.Environ())},
+		{Name: "uv-version.txt", Data: commandOutput("uv", "--version")},
+		{Name: "uv-python-list.txt", Data: commandOutput("uv", "python", "list", "--only-installed")},
+		{Name: "pip-freeze.txt", Data: commandOutput("uv", "pip", "freeze", "--python", appPython(exeDir))},
+	}
+}
+
+// 执行命令，返回输出；失败时在输出后附上错误，命令本身的失败也是有用的诊断信息
+func commandOutput(name string, args ...string) string {
+	output, err := cmdRunner.Output(runner.Command{Name: name, Args: args, HideWindow: true, Timeout: checkTimeout()})
+	if err != nil {
+		output += fmt.Sprintf("\r\n[执行 %s %s 失败: %v]", name, strings.Join(args, " "), err)
+	}
+	return output
+}
+
+// 操作系统版本、架构和处理器数量
+func osInfo() string {
+	const key = `SOFTWARE\Microsoft\Windows NT\CurrentVersion`
+	lines := []string{}
+	for _, name := range []string{"ProductName", "DisplayVersion", "CurrentBuild", "EditionID", "InstallationType"} {
+		value, err := regGetString(HKEY_LOCAL_MACHINE, key, name)
+		if err != nil {
+			value = "(" + err.Error() + ")"
+		}
+		lines = append(lines, name+": "+value)
+	}
+	lines = append(lines,
+		"PROCESSOR_ARCHITECTURE: "+os.Getenv("PROCESSOR_ARCHITECTURE"),
+		fmt.Sprintf("启动器架构: %s/%s", runtime.GOOS, runtime.GOARCH),
+		"本机架构: "+envcheck.MachineArch(),
+		fmt.Sprintf("处理器数量: %d", runtime.NumCPU()),
+		fmt.Sprintf("管理员权限: %v", isElevated()),
+		"代码页: "+codePageInfo(),
+		"设备: "+detectDevices().String(),
+	)
+	return strings.Join(lines, "\r\n")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/timeline"
+	"go2exe/internal/ui"
+)
+
+// doctor 子命令。doctor --timeline 把最近一次安装的转录文件生成 HTML 时间线（步骤、用时、重试和下载）并打开，
+// 用于查看首次运行慢在哪里；doctor --network 检查各镜像和语音合成服务能否访问，用于看出是哪个镜像有问题；
+// doctor --encoding 显示系统代码页（是否启用了 UTF-8 Beta）和传给 uv、应用的 Python 编码设置
+func runDoctorCommand(cfg Config, args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	showTimeline := fs.Bool("timeline", false, "生成安装时间线")
+	checkNetwork := fs.Bool("network", false, "检查镜像和语音合成服务能否访问")
+	checkEncoding := fs.Bool("encoding", false, "显示系统代码页和 Python 编码设置")
+	input := fs.String("input", "", "转录文件，默认为数据目录中最近一次有安装活动的转录文件")
+	output := fs.String("output", "", "生成的 HTML 文件，默认与转录文件同名")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *checkEncoding {
+		// 启动时已由 applyCodePage 按代码页和配置设置了编码环境变量
+		showDoctorReport(i18n.T("编码检查"), []string{
+			i18n.T("系统代码页：%s", codePageInfo()),
+			"PYTHONUTF8=" + childEnv.Get("PYTHONUTF8"),
+			"PYTHONIOENCODING=" + childEnv.Get("PYTHONIOENCODING"),
+		})
+	}
+	if *checkNetwork {
+		// 与正常启动相同的代理
+		applyProxy(cfg)
+		showDoctorReport(i18n.T("网络检查"), networkHealth(cfg.Network))
+	}
+	if !*showTimeline {
+		if *checkEncoding || *checkNetwork {
+			return nil
+		}
+		return fmt.Errorf("用法: doctor --timeline [--input 转录文件] [--output HTML 文件] | doctor --network | doctor --encoding")
+	}
+
+	path := *input
+	if path == "" {
+		if path = timeline.Latest(timelineDir()); path == "" {
+			err := fmt.Errorf("%s 中没有安装过程的记录", timelineDir())
+			ui.ErrorBox(i18n.T("安装时间线"), i18n.T("%s 中没有安装过程的记录", timelineDir()))
+			return err
+		}
+	}
+	events, err := timeline.Load(path)
+	if err != nil {
+		return err
+	}
+	out := *output
+	if out == "" {
+		out = strings.TrimSuffix(path, ".jsonl") + ".html"
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := timeline.Render(f, events); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("已根据 %s 生成安装时间线: %s", path, out)
+	fmt.Fprintln(os.Stdout, out)
+	if !ui.SilentMode() && !ui.TextMode() {
+		if err := shellOpen(out); err != nil {
+			log.Printf("无法打开 %s: %v", out, err)
+		}
+	}
+	return nil
+}
+
+// 把检查结果逐行输出到标准输出，供部署脚本读取，有图形界面时再用消息框显示
+func showDoctorReport(title string, lines []string) {
+	for _, line := range lines {
+		fmt.Fprintln(os.Stdout, line)
+	}
+	if !ui.SilentMode() && !ui.TextMode() {
+		ui.MessageBox(title, strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/exitcode"
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+	"go2exe/internal/preflight"
+	"go2exe/internal/ui"
+)
+
+// 演练（--dry-run）得到的操作计划，指定了 --result-file 时一并写入结果文件
+var dryRunPlan []string
+
+// 执行所有检测（uv、Python、虚拟环境、安装文件、磁盘空间、权限和网络），列出正常启动时会执行的操作，
+// 不运行任何安装程序，也不同步依赖或启动应用
+func runDryRun(exeDir string, cfg Config) error {
+	console.Open()
+	defer console.Close()
+	plan := func(format string, args ...interface{}) {
+		log.Printf("演练: "+format, args...)
+		line := i18n.T(format, args...)
+		addOutputText("- " + line)
+		dryRunPlan = append(dryRunPlan, line)
+	}
+	addOutputText(i18n.T("演练模式：只检测环境，不执行任何安装"))
+
+	launchChecks := loadLaunchChecks(exeDir, cfg.Checks)
+	check := newChecker()
+
+	if utf8BetaEnabled() {
+		plan("系统启用了 UTF-8 Beta，uv 和应用将使用 PYTHONUTF8=1 和 PYTHONIOENCODING=utf-8")
+	}
+
+	// uv：本进程的 PATH 可能是在安装 uv 之前继承的，按绝对路径再找一次，但不修改 PATH
+	uvInstalled, _ := check.UVInstalled()
+	if !uvInstalled {
+		if path, ok := envcheck.FindUV(childEnv, installConfig.UVDir); ok {
+			plan("uv 已安装在 %s，但不在 PATH 中，启动时会把它加入 PATH", path)
+			uvInstalled = true
+		}
+	}
+	targets := installTargets(exeDir, !uvInstalled)
+	switch {
+	case !uvInstalled && install.HasExternalUV(exeDir):
+		plan("安装 uv：使用 uv 目录中的安装文件，安装到 %s", targets[len(targets)-1])
+	case !uvInstalled:
+		plan("安装 uv：使用内置的安装脚本从网上下载，安装到 %s", targets[len(targets)-1])
+	case !launchChecks.uv():
+		plan("uv 已安装（按配置启动时不检查）")
+	default:
+		plan("uv 已安装，不需要安装")
+	}
+
+	// Python：没有 uv 时必然未安装
+	pythonInstalled := false
+	if uvInstalled {
+		python, err := check.FindPython()
+		if err != nil {
+			log.Printf("检查Python安装状态失败: %v", err)
+			return withExitCode(exitcode.Check, err)
+		}
+		if python != nil {
+			pythonInstalled = true
+			plan("Python %s 已安装（%s），不需要安装", python.Version, python.Key)
+		}
+	}
+	if !pythonInstalled {
+		plan("安装 Python %s：使用 %s 中的安装包，安装到 %s", pythonRequest(), pythonArtifacts(), targets[1])
+	}
+
+	// 安装文件：需要安装或按配置启动时校验
+	if !uvInstalled || !pythonInstalled || launchChecks.verify() {
+		if err := verifyInstallFiles(exeDir); err != nil {
+			plan("安装文件校验失败，启动将中止：%v", err)
+		} else {
+			plan("安装文件校验通过")
+		}
+	}
+
+	// 磁盘空间、写入权限、PowerShell（需要安装 uv 时）和长路径支持，只在需要安装时检查
+	if !uvInstalled || !pythonInstalled {
+		inst := newInstaller(exeDir)
+		problems := preflight.Run(preflight.Options{
+			Runner:     cmdRunner,
+			Dirs:       append([]string{inst.TempBase()}, targets...),
+			CheckWrite: isElevated(),
+			MinFreeMB:  installSpaceMB + inst.MinFreeSpaceMB,
+			PathRoots:  targets[:2],
+			PowerShell: !uvInstalled,
+			Timeout:    checkTimeout(),
+		})
+		for _, p := range problems {
+			plan("安装前检查未通过，安装将中止：%s", p.String())
+		}
+		if len(problems) == 0 {
+			plan("安装前检查（磁盘空间、PowerShell、长路径）通过")
+		}
+		if !isElevated() {
+			if dir, ok := writableDirs(targets); !ok {
+				plan("当前用户无权写入 %s，安装时会请求管理员权限", dir)
+			}
+		}
+	}
+
+	// 离线安装包：依赖只从 wheels 目录安装，缺包时同步前就会中止
+	if dir := offlineWheelsDir(exeDir); dir != "" {
+		plan("使用离线安装包目录 %s，同步依赖时不访问网络", dir)
+		missing, err := install.MissingWheels(filepath.Join(exeDir, "python", "uv.lock"), dir, pythonArch())
+		switch {
+		case err != nil:
+			plan("无法检查离线安装包：%v", err)
+		case len(missing) > 0:
+			plan("离线安装包目录缺少 %d 个包，同步将中止：%s", len(missing), strings.Join(missing, ", "))
+		}
+	}
+
+	// 网络：各镜像、镜像上的关键依赖和语音合成服务能否访问，便于看出是哪个镜像有问题
+	applyProxy(cfg)
+	for _, line := range networkHealth(cfg.Network) {
+		plan("网络检查 %s", line)
+	}
+
+	// 虚拟环境
+	switch _, err := os.Stat(appPython(exeDir)); {
+	case err != nil:
+		plan("创建虚拟环境并同步依赖（uv sync）")
+	case launchChecks.sync():
+		plan("虚拟环境已存在，同步依赖（uv sync）")
+	default:
+		plan("虚拟环境已存在，按配置跳过依赖同步")
+	}
+	plan("启动 Python 应用")
+
+	// 部署脚本通过结果文件读取计划，不弹出消息框
+	if resultFile == "" {
+		ui.MessageBox(i18n.T("演练结果"), strings.Join(dryRunPlan, "\n"))
+	}
+	return nil
+}
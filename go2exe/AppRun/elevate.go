@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"go2exe/internal/appconfig"
+	"go2exe/internal/exitcode"
+	"go2exe/internal/i18n"
+	"go2exe/internal/preflight"
+)
+
+var (
+	shellExecuteEx          = shell32.NewProc("ShellExecuteExW")
+	getExitCodeProcess      = kernel32.NewProc("GetExitCodeProcess")
+	TokenElevation          = 20
+	SEE_MASK_NOCLOSEPROCESS = 0x00000040
+	SW_SHOWNORMAL           = 1
+	ERROR_CANCELLED         = 1223
+)
+
+// SHELLEXECUTEINFOW
+type shellExecuteInfo struct {
+	cbSize       uint32
+	fMask        uint32
+	hwnd         uintptr
+	lpVerb       *uint16
+	lpFile       *uint16
+	lpParameters *uint16
+	lpDirectory  *uint16
+	nShow        int32
+	hInstApp     uintptr
+	lpIDList     uintptr
+	lpClass      *uint16
+	hkeyClass    uintptr
+	dwHotKey     uint32
+	hIcon        uintptr
+	hProcess     syscall.Handle
+}
+
+// 提权后的进程需要沿用的普通用户环境变量。管理员可能是另一个账户，
+// 不传递这些变量时 uv 和 Python 会被装到管理员的用户目录下
+var elevatedEnvKeys = []string{
+	"USERPROFILE", "APPDATA", "LOCALAPPDATA", "XDG_BIN_HOME",
+	"UV_INSTALL_DIR", "UV_PYTHON_INSTALL_DIR", "UV_CACHE_DIR",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"PYTHONUTF8", "PYTHONIOENCODING",
+}
+
+// 当前进程是否以管理员身份（已提权）运行
+func isElevated() bool {
+	p, _ := syscall.GetCurrentProcess()
+	var token syscall.Token
+	if err := syscall.OpenProcessToken(p, syscall.TOKEN_QUERY, &token); err != nil {
+		return false
+	}
+	defer token.Close()
+	var elevated uint32
+	var n uint32
+	err := syscall.GetTokenInformation(token, uint32(TokenElevation), (*byte)(unsafe.Pointer(&elevated)), uint32(unsafe.Sizeof(elevated)), &n)
+	return err == nil && elevated != 0
+}
+
+// 安装会写入的目录：.venv 所在的 python 目录、uv 的 Python 目录，以及需要安装 uv 时 uv 的可执行文件目录
+func installTargets(exeDir string, needUV bool) []string {
+	dirs := []string{filepath.Join(exeDir, "python")}
+	if d := childEnv.Get("UV_PYTHON_INSTALL_DIR"); d != "" {
+		dirs = append(dirs, d)
+	} else {
+		dirs = append(dirs, filepath.Join(childEnv.Get("APPDATA"), "uv", "python"))
+	}
+	if needUV {
+		switch {
+		case childEnv.Get("UV_INSTALL_DIR") != "":
+			dirs = append(dirs, childEnv.Get("UV_INSTALL_DIR"))
+		case childEnv.Get("XDG_BIN_HOME") != "":
+			dirs = append(dirs, childEnv.Get("XDG_BIN_HOME"))
+		default:
+			dirs = append(dirs, filepath.Join(childEnv.Get("USERPROFILE"), ".local", "bin"))
+		}
+	}
+	return dirs
+}
+
+// 检查是否能写入所有目录，返回第一个无权写入的目录
+func writableDirs(dirs []string) (string, bool) {
+	for _, dir := range dirs {
+		if err := preflight.CheckWritable(dir); err != nil && os.IsPermission(err) {
+			return dir, false
+		}
+	}
+	return "", true
+}
+
+// 传给提权进程的普通用户环境变量：elevatedEnvKeys，以及覆盖配置的所有环境变量（与 appconfig.EnvValues 接受的相同），
+// 否则部署脚本设置的镜像、Python 版本等在提权的安装步骤中不生效
+func elevatedUserEnv() []string {
+	var env []string
+	for _, key := range elevatedEnvKeys {
+		if v := childEnv.Get(key); v != "" {
+			env = append(env, key+"="+v)
+		}
+	}
+	// 管理员账户的 $HOME 与当前用户不同，明确指定 uv 的安装位置
+	if childEnv.Get("UV_INSTALL_DIR") == "" && childEnv.Get("XDG_BIN_HOME") == "" {
+		env = append(env, "XDG_BIN_HOME="+filepath.Join(childEnv.Get("USERPROFILE"), ".local", "bin"))
+	}
+	schema := configSchema()
+	for _, kv := range os.Environ() {
+		if name, _, _ := strings.Cut(kv, "="); appconfig.IsEnvVar(name, schema) {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// 以管理员身份重新运行自身，只执行安装步骤，等待结束后返回
+func runElevatedInstall(exeDir string) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	env := elevatedUserEnv()
+	// 提权进程的安装步骤记录到同一条时间线中
+	if runTimeline != nil {
+		env = append(env, timelineEnv+"="+runTimeline.Path())
+	}
+	params := "--install-only --user-env " + syscall.EscapeArg(strings.Join(env, "|"))
+
+	verb, _ := syscall.UTF16PtrFromString("runas")
+	file, _ := syscall.UTF16PtrFromString(exePath)
+	args, _ := syscall.UTF16PtrFromString(params)
+	dir, _ := syscall.UTF16PtrFromString(exeDir)
+	info := shellExecuteInfo{
+		fMask:        uint32(SEE_MASK_NOCLOSEPROCESS),
+		lpVerb:       verb,
+		lpFile:       file,
+		lpParameters: args,
+		lpDirectory:  dir,
+		nShow:        int32(SW_SHOWNORMAL),
+	}
+	info.cbSize = uint32(unsafe.Sizeof(info))
+	if r, _, err := shellExecuteEx.Call(uintptr(unsafe.Pointer(&info))); r == 0 {
+		if errno, ok := err.(syscall.Errno); ok && int(errno) == ERROR_CANCELLED {
+			return withExitCode(exitcode.Cancelled, fmt.Errorf("用户拒绝了管理员权限请求"))
+		}
+		return withExitCode(exitcode.Elevation, fmt.Errorf("无法以管理员身份启动: %v", err))
+	}
+	defer syscall.CloseHandle(info.hProcess)
+
+	log.Printf("已启动提权的安装进程，等待完成")
+	syscall.WaitForSingleObject(info.hProcess, syscall.INFINITE)
+	var code uint32
+	getExitCodeProcess.Call(uintptr(info.hProcess), uintptr(unsafe.Pointer(&code)))
+	if code != 0 {
+		// 沿用提权进程的退出码，部署工具看到的是实际失败的步骤
+		return withExitCode(int(code), fmt.Errorf("安装进程退出码 %d，详细信息请查看 app.log", code))
+	}
+	return nil
+}
+
+// 提权进程的入口：安装 uv、Python 并创建虚拟环境后退出，返回进程退出码。
+// 普通用户的环境变量放进 childEnv 传给 uv 和安装脚本；本进程的环境变量仍是管理员的，
+// 启动器自己用到的用户目录（数据目录、uv 的安装位置）按这些值明确指定
+func runInstallOnly(userEnv string) int {
+	schema := configSchema()
+	for _, kv := range strings.Split(userEnv, "|") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			childEnv.Set(k, v)
+			// 覆盖配置的变量由 loadConfig 从本进程的环境变量中读取
+			if appconfig.IsEnvVar(k, schema) {
+				os.Setenv(k, v)
+			}
+		}
+	}
+	if dir := childEnv.Get("LOCALAPPDATA"); dir != "" {
+		dataRoot = dir
+	}
+	log.Printf("以管理员身份执行安装步骤")
+
+	// 提权的进程从 ShellExecute 指定的目录启动，路径都从程序目录计算，不依赖当前目录
+	exeDir, err := executableDir()
+	if err != nil {
+		log.Printf("无法获取可执行文件路径: %v", err)
+		return exitcode.Failed
+	}
+	cfg, err := loadConfig(exeDir)
+	i18n.SetLanguage(cfg.UI.Language)
+	if err != nil {
+		log.Printf("配置有问题，有问题的设置使用默认值:\n%v", err)
+	}
+	applyPortable(exeDir, &cfg)
+	startLogShipping(exeDir, cfg)
+	applyProxy(cfg)
+	applyIndex(exeDir, cfg.Network)
+	installConfig = cfg.Install
+	applyInstallDirs(cfg.Install)
+	// 不经过安装脚本安装 uv 时也装到普通用户的目录中（runElevatedInstall 总会传递其中之一）
+	if installConfig.UVDir == "" {
+		installConfig.UVDir = childEnv.Get("UV_INSTALL_DIR")
+	}
+	if installConfig.UVDir == "" {
+		installConfig.UVDir = childEnv.Get("XDG_BIN_HOME")
+	}
+	applyGPU(exeDir, cfg.Install.GPU)
+	if path := childEnv.Get(timelineEnv); path != "" {
+		startTimeline(path, "以管理员身份安装")
+		defer stopTimeline(nil)
+	}
+	inst := newInstaller(exeDir)
+
+	defer console.Close()
+	if _, err := ensureEnvironment(exeDir, inst, setupOptions{}); err != nil {
+		return exitCode(err)
+	}
+	// .venv 也在可能无权写入的程序目录中，一并创建
+	if err := syncVenv(exeDir, inst); err != nil {
+		return exitcode.Sync
+	}
+	return exitcode.OK
+}
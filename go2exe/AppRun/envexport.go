@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go2exe/internal/envbundle"
+	"go2exe/internal/i18n"
+	"go2exe/internal/ui"
+)
+
+// 把当前可以运行的环境（uv 管理的解释器、虚拟环境和应用）打包到 zipPath，
+// 供离线的电脑用 --import-env 导入
+func runExportEnv(exeDir, zipPath string) error {
+	zipPath, _ = filepath.Abs(zipPath)
+	if err := exportEnv(exeDir, zipPath, nil); err != nil {
+		return err
+	}
+	ui.MessageBox(i18n.T("导出完成"), i18n.T("环境已导出到：\n%s\n\n在另一台电脑上运行 AppRun.exe --import-env <文件> 即可导入。", zipPath))
+	return nil
+}
+
+// 导出环境和 data 中的用户文件，失败时提示用户
+func exportEnv(exeDir, zipPath string, data map[string]string) error {
+	console.Open()
+	defer console.Close()
+	fail := func(err error) error {
+		log.Printf("导出环境失败: %v", err)
+		addOutputText(i18n.T("导出环境失败: %v", err))
+		ui.ErrorBox(i18n.T("导出失败"), i18n.T("导出环境失败: %v", err))
+		return err
+	}
+
+	python, err := newChecker().FindPython()
+	if err != nil {
+		return fail(err)
+	}
+	if python == nil || python.Path == "" {
+		return fail(fmt.Errorf("未安装 Python %s，请先正常启动一次 SpeakMyBook", pythonVersion()))
+	}
+	if _, err := os.Stat(appPython(exeDir)); err != nil {
+		return fail(fmt.Errorf("还没有虚拟环境，请先正常启动一次 SpeakMyBook"))
+	}
+	venv := filepath.Dir(filepath.Dir(appPython(exeDir)))
+	appDir := filepath.Join(exeDir, "python")
+	// 程序目录中的虚拟环境已单独打包，Python 安装包在目标电脑上也有
+	exclude := []string{".venv"}
+	if rel, err := filepath.Rel(appDir, filepath.Join(exeDir, filepath.FromSlash(pythonArtifacts()))); err == nil {
+		exclude = append(exclude, rel)
+	}
+	log.Printf("正在导出环境到 %s", zipPath)
+	addOutputText(i18n.T("正在导出环境到 %s，可能需要几分钟...", zipPath))
+	err = envbundle.Export(zipPath, envbundle.Source{
+		PythonKey:     python.Key,
+		PythonVersion: python.Version.String(),
+		PythonHome:    filepath.Dir(python.Path),
+		Venv:          venv,
+		AppDir:        appDir,
+		Exclude:       exclude,
+		Data:          data,
+	})
+	if err != nil {
+		return fail(err)
+	}
+	log.Printf("环境已导出到 %s", zipPath)
+	return nil
+}
+
+// 导入 --export-env 或迁移向导生成的环境包：解释器放到 uv 的 Python 目录，虚拟环境和应用放到程序目录，
+// 包中的用户数据放回原来的位置
+func runImportEnv(exeDir, zipPath string) error {
+	console.Open()
+	defer console.Close()
+	fail := func(err error) error {
+		log.Printf("导入环境失败: %v", err)
+		addOutputText(i18n.T("导入环境失败: %v", err))
+		ui.ErrorBox(i18n.T("导入失败"), i18n.T("导入环境失败: %v", err))
+		return err
+	}
+
+	zipPath, _ = filepath.Abs(zipPath)
+	m, err := envbundle.ReadManifest(zipPath)
+	if err != nil {
+		return fail(err)
+	}
+	msg := i18n.T("将导入 %s 上导出的 Python %s 和虚拟环境，并替换程序目录中现有的虚拟环境。", m.CreatedAt.Format("2006-01-02 15:04"), m.PythonVersion)
+	if names := m.DataNames(); len(names) > 0 {
+		msg += "\n\n" + i18n.T("包中的用户数据（%s）也会替换这台电脑上现有的。", strings.Join(names, ", "))
+	}
+	if !ui.ConfirmBox(i18n.T("导入环境"), msg+"\n\n"+i18n.T("是否继续？")) {
+		log.Printf("用户取消了导入")
+		return nil
+	}
+	log.Printf("正在导入环境 %s", zipPath)
+	addOutputText(i18n.T("正在导入环境，可能需要几分钟..."))
+	// 导入的虚拟环境放在程序目录中，不再使用 --adopt 记录的环境
+	forgetAdoptedVenv()
+	target := envbundle.Target{
+		PythonRoot: installTargets(exeDir, false)[1],
+		Venv:       filepath.Join(exeDir, "python", ".venv"),
+		AppDir:     filepath.Join(exeDir, "python"),
+		Data:       transferData(exeDir),
+	}
+	if _, err := envbundle.Import(zipPath, target); err != nil {
+		return fail(err)
+	}
+	// 下次启动执行全部检查，确认导入的环境可用
+	if err := invalidateLaunchChecks(); err != nil {
+		log.Printf("%v", err)
+	}
+	log.Printf("环境导入完成")
+	ui.MessageBox(i18n.T("导入完成"), i18n.T("环境已导入，可以正常启动 SpeakMyBook 了。"))
+	return nil
+}
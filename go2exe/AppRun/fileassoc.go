@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/ui"
+)
+
+// 可以用 SpeakMyBook 打开的文件类型（与卸载程序中的列表保持一致）
+var fileAssocTypes = []string{".epub", ".txt", ".pdf"}
+
+// 文件关联使用的 ProgID，以及“打开方式”列表中显示的程序
+const (
+	fileAssocProgID = "SpeakMyBook.Book"
+	fileAssocApp    = `Software\Classes\Applications\SpeakMyBook.exe`
+)
+
+func fileAssocProgIDKey() string {
+	return `Software\Classes\` + fileAssocProgID
+}
+
+func fileAssocExtKey(ext string) string {
+	return `Software\Classes\` + ext
+}
+
+// 打开文件的命令：双击或“打开方式”时每个文件启动一次启动器，文件路径在命令行中。
+// 不使用 DDE：已有常驻的启动器时由新启动的进程通过命名管道把路径转发给它，应用已运行时再转发给应用
+func fileAssocCommand(exePath string) string {
+	return fmt.Sprintf(`"%s" "%%1"`, exePath)
+}
+
+// 注册文件关联（当前用户，无需管理员权限），重复调用会更新程序路径。
+// 所有类型都加入“打开方式”列表；只有还没有默认程序的类型（通常是 .epub）设为双击打开，
+// 已有默认程序的 .txt、.pdf 不抢占，由用户在“打开方式”或系统设置中选择
+func registerFileAssociations(exePath string) error {
+	command := fileAssocCommand(exePath)
+	for _, key := range []string{fileAssocProgIDKey(), fileAssocApp} {
+		if err := regSetString(HKEY_CURRENT_USER, key+`\shell\open\command`, "", command); err != nil {
+			return err
+		}
+		if err := regSetString(HKEY_CURRENT_USER, key+`\DefaultIcon`, "", exePath+",0"); err != nil {
+			return err
+		}
+	}
+	if err := regSetString(HKEY_CURRENT_USER, fileAssocProgIDKey(), "", i18n.T("SpeakMyBook 电子书")); err != nil {
+		return err
+	}
+	if err := regSetString(HKEY_CURRENT_USER, fileAssocApp, "FriendlyAppName", "SpeakMyBook"); err != nil {
+		return err
+	}
+	var defaults []string
+	for _, ext := range fileAssocTypes {
+		if err := regSetString(HKEY_CURRENT_USER, fileAssocApp+`\SupportedTypes`, ext, ""); err != nil {
+			return err
+		}
+		if err := regSetString(HKEY_CURRENT_USER, fileAssocExtKey(ext)+`\OpenWithProgids`, fileAssocProgID, ""); err != nil {
+			return err
+		}
+		if current, _ := regGetString(HKEY_CLASSES_ROOT, ext, ""); current == "" || current == fileAssocProgID {
+			if err := regSetString(HKEY_CURRENT_USER, fileAssocExtKey(ext), "", fileAssocProgID); err != nil {
+				return err
+			}
+			defaults = append(defaults, ext)
+		}
+	}
+	notifyAssocChanged()
+	log.Printf("已注册文件关联: %v，双击打开: %v", fileAssocTypes, defaults)
+	return nil
+}
+
+// 删除文件关联，只删除 SpeakMyBook 自己写入的项；某一项删除失败时仍删除其他项
+func unregisterFileAssociations() error {
+	var errs []error
+	for _, ext := range fileAssocTypes {
+		if err := regDeleteValue(HKEY_CURRENT_USER, fileAssocExtKey(ext)+`\OpenWithProgids`, fileAssocProgID); err != nil {
+			errs = append(errs, err)
+		}
+		if current, _ := regGetString(HKEY_CURRENT_USER, fileAssocExtKey(ext), ""); current == fileAssocProgID {
+			if err := regDeleteValue(HKEY_CURRENT_USER, fileAssocExtKey(ext), ""); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, key := range []string{fileAssocProgIDKey(), fileAssocApp} {
+		if err := regDeleteKeyTree(HKEY_CURRENT_USER, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	notifyAssocChanged()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	log.Printf("已删除文件关联")
+	return nil
+}
+
+// 文件关联是否已注册
+func fileAssociationsRegistered() bool {
+	cmd, err := regGetString(HKEY_CURRENT_USER, fileAssocProgIDKey()+`\shell\open\command`, "")
+	return err == nil && cmd != ""
+}
+
+// 按配置同步文件关联：yes 时注册（更新程序路径），no 时删除已注册的，ask 时还没有询问过，不做改动
+func syncFileAssociations(cfg Config, exePath string) {
+	var err error
+	switch cfg.Shell.FileAssociations {
+	case "yes":
+		err = registerFileAssociations(exePath)
+	case "no":
+		if fileAssociationsRegistered() {
+			err = unregisterFileAssociations()
+		}
+	}
+	if err != nil {
+		log.Printf("更新文件关联失败: %v", err)
+	}
+}
+
+// 首次安装后询问是否用 SpeakMyBook 打开电子书，回答保存到配置文件，之后不再询问
+func askFileAssociations(exeDir, exePath string, cfg *Config) {
+	if cfg.Shell.FileAssociations != "ask" || ui.SilentMode() {
+		return
+	}
+	answer := "no"
+	if ui.ConfirmBox("SpeakMyBook", i18n.T("是否用 SpeakMyBook 打开 %s 文件？\n\n可以随时在 apprun.toml 的 [shell] file_associations 中修改，卸载时会一并删除。", strings.Join(fileAssocTypes, i18n.T("、")))) {
+		answer = "yes"
+	}
+	log.Printf("文件关联: %s", answer)
+	cfg.Shell.FileAssociations = answer
+	if err := saveConfigValues(exeDir, "shell", map[string]interface{}{"file_associations": answer}); err != nil {
+		log.Printf("保存文件关联设置失败: %v", err)
+	}
+	syncFileAssociations(*cfg, exePath)
+}
+
+// --file-associations yes|no：部署工具注册或删除文件关联，保存到配置文件后退出
+func setFileAssociations(exeDir, exePath, value string) error {
+	var err error
+	switch value {
+	case "yes":
+		err = registerFileAssociations(exePath)
+	case "no":
+		err = unregisterFileAssociations()
+	default:
+		return fmt.Errorf("--file-associations 只能是 yes 或 no，不是 %q", value)
+	}
+	if err != nil {
+		return err
+	}
+	return saveConfigValues(exeDir, "shell", map[string]interface{}{"file_associations": value})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go2exe/internal/exitcode"
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+// 管理员准备好黄金镜像后写在 runtime 目录中的记录
+const goldenStampName = "golden.json"
+
+// 黄金镜像模式下的用户启动：程序目录只读，跳过全部安装和同步，日志和数据只写用户目录。
+// 管理员执行 --provision-golden 时为 false，照常安装
+var goldenMode bool
+
+// 黄金镜像的准备记录
+type goldenStamp struct {
+	ProvisionedAt time.Time `json:"provisioned_at"`
+	Dependencies  string    `json:"dependencies"` // 准备时的依赖声明哈希（见 dependencyHash）
+	Python        string    `json:"python"`
+	Launcher      string    `json:"launcher,omitempty"`
+}
+
+func goldenStampPath(exeDir string) string {
+	return filepath.Join(exeDir, portableDirName, goldenStampName)
+}
+
+// 黄金镜像模式：uv、Python 和模型文件放在程序目录下的 runtime 目录中（配置中明确指定的位置不变），
+// 启动器数据仍在每个用户自己的目录中。不添加右键菜单、文件关联和快捷方式，也不检查更新，这些都要写入共享的程序目录
+// 或者在还原卡保护下重启后丢失。provision 为 true 时是管理员在准备镜像，照常安装
+func applyGolden(exeDir string, cfg *Config, provision bool) {
+	if !cfg.Install.GoldenImage && !provision {
+		return
+	}
+	root := filepath.Join(exeDir, portableDirName)
+	if cfg.Install.UVDir == "" {
+		cfg.Install.UVDir = filepath.Join(root, "uv")
+	}
+	if cfg.Install.PythonDir == "" {
+		cfg.Install.PythonDir = filepath.Join(root, "python")
+	}
+	if cfg.Install.ModelsDir == "" {
+		cfg.Install.ModelsDir = filepath.Join(root, "models")
+	}
+	if cfg.Install.Portable != "no" {
+		// 便携模式会把启动器数据也放进程序目录，与只读的程序目录冲突
+		log.Printf("黄金镜像模式不使用便携模式（portable = %q）", cfg.Install.Portable)
+		cfg.Install.Portable = "no"
+	}
+	cfg.Shell.SendTo = false
+	cfg.Shell.StartMenu = false
+	cfg.Shell.DesktopShortcut = false
+	cfg.Shell.ContextMenu = false
+	cfg.Shell.FileAssociations = "no"
+	cfg.Update.ManifestURL = ""
+	cfg.Checks.Background = false
+	if provision {
+		log.Printf("正在准备黄金镜像：uv、Python 和模型文件放在 %s", root)
+		return
+	}
+	goldenMode = true
+	useGoldenOverlay()
+	log.Printf("黄金镜像模式：使用 %s 中准备好的环境，日志和数据写到 %s", root, dataDir())
+}
+
+// 每个用户可写的部分：启动器日志写到数据目录，应用在“文档”下的 SpeakMyBook 中运行，不在只读的 python 目录中写入字节码
+func useGoldenOverlay() {
+	path := launcherLogPath("")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		if f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666); err == nil {
+			if logFile != nil {
+				logFile.Close()
+			}
+			logFile = f
+			log.SetOutput(io.MultiWriter(logFile, recentLog))
+		}
+	}
+	work := dataDir()
+	if docs, err := knownFolderPath(&FOLDERID_Documents); err == nil {
+		work = filepath.Join(docs, "SpeakMyBook")
+	}
+	if err := os.MkdirAll(work, 0755); err != nil {
+		log.Printf("无法创建应用的工作目录 %s: %v", work, err)
+	} else {
+		app.WorkDir = work
+	}
+	app.Env = runner.Overlay(app.Env, "PYTHONDONTWRITEBYTECODE=1")
+}
+
+// 启动器日志的位置：黄金镜像模式下在用户的数据目录中，否则在程序目录中
+func launcherLogPath(exeDir string) string {
+	if goldenMode {
+		return filepath.Join(dataDir(), "app.log")
+	}
+	return filepath.Join(exeDir, "app.log")
+}
+
+// 用户启动前检查管理员准备的环境：准备记录、虚拟环境都在，且依赖声明与准备时相同
+func checkGoldenImage(exeDir string) error {
+	data, err := os.ReadFile(goldenStampPath(exeDir))
+	if err != nil {
+		return fmt.Errorf("运行环境尚未准备: %v", err)
+	}
+	var stamp goldenStamp
+	if err := json.Unmarshal(data, &stamp); err != nil {
+		return fmt.Errorf("无法读取 %s: %v", goldenStampPath(exeDir), err)
+	}
+	if _, err := os.Stat(appPython(exeDir)); err != nil {
+		return fmt.Errorf("虚拟环境不存在: %v", err)
+	}
+	if stamp.Dependencies != dependencyHash(exeDir) {
+		return fmt.Errorf("程序在 %s 准备运行环境之后有更新，依赖与准备时不同", stamp.ProvisionedAt.Format(time.DateTime))
+	}
+	log.Printf("使用 %s 准备的运行环境（Python %s）", stamp.ProvisionedAt.Format(time.DateTime), stamp.Python)
+	return nil
+}
+
+// 管理员准备黄金镜像：安装 uv 和 Python、同步依赖（预先编译字节码）、下载模型文件，全部成功后写入准备记录，
+// 并在配置文件中开启 golden_image，之后的用户启动直接使用准备好的环境
+func runProvisionGolden(exeDir string, inst *install.Installer) error {
+	// 用户启动时 python 目录只读，不能再写入 __pycache__
+	childEnv.Set("UV_COMPILE_BYTECODE", "1")
+	if _, err := ensureEnvironment(exeDir, inst, setupOptions{allowElevate: !ui.SilentMode()}); err != nil {
+		return err
+	}
+	if err := syncVenv(exeDir, inst); err != nil {
+		return withExitCode(exitcode.Sync, err)
+	}
+	if err := ensureModels(exeDir, inst); err != nil {
+		return withExitCode(exitcode.Golden, err)
+	}
+
+	stamp := goldenStamp{ProvisionedAt: time.Now(), Dependencies: dependencyHash(exeDir), Python: pythonVersion(), Launcher: launcherVersion()}
+	data, _ := json.MarshalIndent(stamp, "", "  ")
+	path := goldenStampPath(exeDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return withExitCode(exitcode.Golden, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return withExitCode(exitcode.Golden, fmt.Errorf("写入准备记录失败: %v", err))
+	}
+	if err := saveConfigValues(exeDir, "install", map[string]interface{}{"golden_image": true}); err != nil {
+		return withExitCode(exitcode.Golden, fmt.Errorf("保存配置失败: %v", err))
+	}
+	log.Printf("黄金镜像已准备好: %s", path)
+	ui.MessageBox(i18n.T("运行环境已准备好"), i18n.T("uv、Python、依赖和模型文件已准备在 %s 中。\n\n"+
+		"现在可以把程序目录设为只读或开启还原保护，用户启动时将直接使用这个环境。程序更新后请重新执行 --provision-golden。",
+		filepath.Join(exeDir, portableDirName)))
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go2exe/internal/cuda"
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+var (
+	// uv sync 时启用的 extra，由 applyGPU 确定
+	syncExtras []string
+	// 选用的 CUDA 版本，使用 CPU 版本时为零值
+	gpuVariant cuda.Variant
+)
+
+// NVIDIA 驱动下载页面
+const nvidiaDriverURL = "https://www.nvidia.com/Download/index.aspx"
+
+// pyproject.toml 中 [project.optional-dependencies] 定义的 extra 名称
+func projectExtras(projectDir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(projectDir, "pyproject.toml"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var extras []string
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "["):
+			inSection = line == "[project.optional-dependencies]"
+		case inSection:
+			// cu121 = [ -> cu121
+			if name, _, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(line, "#") {
+				extras = append(extras, strings.Trim(strings.TrimSpace(name), `"'`))
+			}
+		}
+	}
+	return extras, scanner.Err()
+}
+
+// 通过 nvidia-smi 读取 NVIDIA 驱动版本，随驱动安装在 System32 中
+func nvidiaDriver() (cuda.Driver, error) {
+	output, err := cmdRunner.Output(runner.Command{
+		Name:       "nvidia-smi",
+		Args:       []string{"--query-gpu=driver_version", "--format=csv,noheader"},
+		HideWindow: true,
+		Timeout:    checkTimeout(),
+	})
+	if err != nil {
+		return cuda.Driver{}, err
+	}
+	return cuda.ParseSMI(output)
+}
+
+// 按配置和显卡驱动选择依赖的 CUDA 版本（pyproject.toml 中的 cu124、cu121、cu118 等 extra），
+// 避免安装驱动不支持的版本，应用运行时才报错。不使用 CUDA 时，项目定义了 cpu extra 就使用它
+func applyGPU(exeDir string, mode string) {
+	syncExtras, gpuVariant = nil, cuda.Variant{}
+	defer func() {
+		app.Env = runner.Overlay(app.Env, "SPEAKMYBOOK_CUDA_VARIANT="+accelerationName())
+	}()
+
+	extras, err := projectExtras(filepath.Join(exeDir, "python"))
+	if err != nil {
+		log.Printf("读取 pyproject.toml 失败: %v", err)
+	}
+	useCPU := func() {
+		for _, extra := range extras {
+			if extra == "cpu" {
+				syncExtras = []string{"cpu"}
+			}
+		}
+	}
+
+	switch mode {
+	case "", "cpu":
+		useCPU()
+		return
+	case "cuda", "auto":
+	default:
+		log.Printf("无效的 gpu 配置 %q，使用 CPU 版本", mode)
+		useCPU()
+		return
+	}
+	known := cuda.Known(extras)
+	if len(known) == 0 {
+		log.Printf("pyproject.toml 没有定义 CUDA 版本的 extra（%s 等），gpu = %q 不影响依赖同步", cuda.Variants[0].Name, mode)
+		useCPU()
+		return
+	}
+
+	driver, err := nvidiaDriver()
+	if err != nil {
+		log.Printf("读取 NVIDIA 驱动版本失败: %v", err)
+		if mode == "cuda" {
+			addOutputText(i18n.T("没有检测到 NVIDIA 显卡驱动，安装 CPU 版本的依赖"))
+		}
+		useCPU()
+		return
+	}
+	v, ok := cuda.Select(driver, extras)
+	if !ok {
+		driverOutdated(driver, known[len(known)-1])
+		useCPU()
+		return
+	}
+	log.Printf("NVIDIA 驱动 %s，使用 CUDA %s（%s）", driver, v.CUDA, v.Name)
+	syncExtras = []string{v.Name}
+	gpuVariant = v
+}
+
+// 选用的版本名称：cu121 等，使用 CPU 版本时为 cpu
+func accelerationName() string {
+	if gpuVariant.Name == "" {
+		return "cpu"
+	}
+	return gpuVariant.Name
+}
+
+// 驱动太旧，不支持项目提供的任何 CUDA 版本：说明需要的驱动版本，询问是否打开驱动下载页面。
+// 同一驱动版本只弹出一次提示，之后只写在进度窗口中
+func driverOutdated(driver cuda.Driver, oldest cuda.Variant) {
+	log.Printf("NVIDIA 驱动 %s 不支持项目提供的 CUDA 版本，至少需要 %s（CUDA %s）", driver, oldest.MinDriver, oldest.CUDA)
+	addOutputText(i18n.T("NVIDIA 驱动版本 %s 太旧，至少需要 %s 才能使用 CUDA %s，安装 CPU 版本的依赖。更新显卡驱动后可以修复环境改用 GPU", driver, oldest.MinDriver, oldest.CUDA))
+
+	state, _ := readCheckState()
+	if state.DriverNotice == driver.String() {
+		return
+	}
+	state.DriverNotice = driver.String()
+	if err := writeCheckState(state); err != nil {
+		log.Printf("保存启动检查状态失败: %v", err)
+	}
+	if resultFile != "" {
+		// 部署工具运行时不弹出对话框
+		return
+	}
+	if !ui.ConfirmBox(i18n.T("显卡驱动需要更新"), i18n.T("NVIDIA 显卡驱动的版本是 %s，无法使用 GPU 加速。CUDA %s 至少需要 %s 版本的驱动。\n\n"+
+		"这次将安装 CPU 版本的依赖，SpeakMyBook 可以正常使用，只是速度较慢。更新驱动后，在托盘菜单中选择“修复环境”或使用 --repair 即可改用 GPU。\n\n"+
+		"是否现在打开 NVIDIA 驱动下载页面？", driver, oldest.CUDA, oldest.MinDriver)) {
+		return
+	}
+	if err := shellOpen(nvidiaDriverURL); err != nil {
+		log.Printf("打开 %s 失败: %v", nvidiaDriverURL, err)
+		ui.ErrorBox(i18n.T("打开网页失败"), i18n.T("无法打开 %s: %v", nvidiaDriverURL, err))
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go2exe/internal/appupdate"
+	"go2exe/internal/health"
+	"go2exe/internal/runner"
+)
+
+// 健康检查报告中保留的日志行数
+const healthLogLines = 100
+
+// 查询 uv 和 Python 版本要执行命令，结果缓存这么久
+const healthVersionsTTL = time.Minute
+
+var (
+	// 最近的启动器日志，加在日志文件之后
+	recentLog = health.NewRing(healthLogLines)
+	// 启动器的状态，没有启用健康检查接口时也记录，开销很小
+	appHealth = &health.Tracker{Log: recentLog}
+)
+
+// 按配置在本机启动健康检查接口，并把地址通过 SPEAKMYBOOK_HEALTH_URL 告诉应用。报告中的日志按 [diagnostics] 的设置去除个人信息
+func startHealthServer(exeDir string, cfg Config) {
+	if !cfg.Health.Enabled {
+		return
+	}
+	appHealth.Versions = cachedVersions(exeDir)
+	appHealth.Scrub = newScrubber(cfg.Diagnostics).Scrub
+	app.Env = runner.Overlay(app.Env, fmt.Sprintf("SPEAKMYBOOK_HEALTH_URL=http://127.0.0.1:%d/v1/health", cfg.Health.Port))
+	go func() {
+		if err := health.Serve(context.Background(), cfg.Health.Port, appHealth); err != nil {
+			log.Printf("健康检查接口停止: %v", err)
+		}
+	}()
+	log.Printf("健康检查接口: http://127.0.0.1:%d/v1/health", cfg.Health.Port)
+}
+
+// 查询各组件版本，结果缓存 healthVersionsTTL
+func cachedVersions(exeDir string) func() health.Versions {
+	var (
+		mu      sync.Mutex
+		cached  health.Versions
+		checked time.Time
+	)
+	return func() health.Versions {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Since(checked) < healthVersionsTTL {
+			return cached
+		}
+		cached, checked = health.Versions{Launcher: launcherVersion()}, time.Now()
+		// 不经过时间线，查询不记入安装时间线
+		check := newChecker()
+		check.Runner = runner.Exec{Env: childEnv}
+		if ok, output := check.UVInstalled(); ok {
+			cached.UV = output
+		}
+		if python, err := check.FindPython(); err == nil && python != nil {
+			cached.Python = python.Version.String()
+		}
+		cached.App, _ = appupdate.ProjectVersion(filepath.Join(exeDir, "python"))
+		return cached
+	}
+}
+
+// 启动器的版本：编译时的模块版本和提交
+func launcherVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	version := info.Main.Version
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && len(s.Value) >= 12 {
+			version += " " + s.Value[:12]
+		}
+	}
+	return version
+}
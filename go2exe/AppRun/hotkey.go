@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+var (
+	registerHotKey   = user32.NewProc("RegisterHotKey")
+	unregisterHotKey = user32.NewProc("UnregisterHotKey")
+	WM_HOTKEY        = 0x0312
+	MOD_ALT          = 0x0001
+	MOD_CONTROL      = 0x0002
+	MOD_SHIFT        = 0x0004
+	MOD_WIN          = 0x0008
+	MOD_NOREPEAT     = 0x4000
+)
+
+// 本程序使用的快捷键 ID
+const hotkeyID = 1
+
+// 解析 "Ctrl+Alt+R" 形式的快捷键，返回修饰键和虚拟键码
+func parseHotkey(s string) (uint32, uint32, error) {
+	var mods, vk uint32
+	for _, part := range strings.Split(s, "+") {
+		key := strings.ToUpper(strings.TrimSpace(part))
+		switch {
+		case key == "CTRL" || key == "CONTROL":
+			mods |= uint32(MOD_CONTROL)
+		case key == "ALT":
+			mods |= uint32(MOD_ALT)
+		case key == "SHIFT":
+			mods |= uint32(MOD_SHIFT)
+		case key == "WIN":
+			mods |= uint32(MOD_WIN)
+		case vk != 0:
+			return 0, 0, fmt.Errorf("快捷键只能包含一个普通按键: %s", s)
+		case len(key) == 1 && (key[0] >= 'A' && key[0] <= 'Z' || key[0] >= '0' && key[0] <= '9'):
+			vk = uint32(key[0])
+		case key == "SPACE":
+			vk = 0x20
+		case strings.HasPrefix(key, "F"):
+			var n uint32
+			if _, err := fmt.Sscanf(key, "F%d", &n); err != nil || n < 1 || n > 24 {
+				return 0, 0, fmt.Errorf("无法识别的按键: %s", part)
+			}
+			vk = 0x70 + n - 1
+		default:
+			return 0, 0, fmt.Errorf("无法识别的按键: %s", part)
+		}
+	}
+	if vk == 0 {
+		return 0, 0, fmt.Errorf("快捷键缺少普通按键: %s", s)
+	}
+	if mods == 0 {
+		return 0, 0, fmt.Errorf("快捷键至少需要一个修饰键: %s", s)
+	}
+	return mods, vk, nil
+}
+
+// 注册全局快捷键，WM_HOTKEY 会投递到当前线程的消息队列
+func registerGlobalHotkey(s string) error {
+	mods, vk, err := parseHotkey(s)
+	if err != nil {
+		return err
+	}
+	r, _, callErr := registerHotKey.Call(0, hotkeyID, uintptr(mods|uint32(MOD_NOREPEAT)), uintptr(vk))
+	if r == 0 {
+		return fmt.Errorf("快捷键可能已被其他程序占用: %v", callErr)
+	}
+	log.Printf("已注册全局快捷键: %s", s)
+	return nil
+}
+
+// 注销全局快捷键
+func unregisterGlobalHotkey() {
+	unregisterHotKey.Call(0, hotkeyID)
+}
+
+// 响应快捷键：应用未运行时先启动，切换到应用窗口，再通过 IPC 转发动作
+func onHotkey(action string) {
+	if !isAppRunning() {
+		log.Printf("快捷键触发，应用未运行，正在启动")
+		if err := startPythonApp(nil); err != nil {
+			log.Printf("快捷键启动应用失败: %v", err)
+			return
+		}
+	}
+	// 收到快捷键的启动器有权切换前台窗口，由它切换；应用刚启动、窗口还没有显示时由应用连接 IPC 后自己切换
+	if !bringAppToFront() {
+		sendToApp("activate")
+	}
+	if action == "read_selection" {
+		sendToApp("read_selection")
+	}
+}
@@ -0,0 +1,230 @@
+// Package appconfig 读取启动器的配置：apprun.toml（TOML 的子集）和覆盖它的环境变量，
+// 按配置结构体的 toml 和 check 标签检查每一项，有问题的设置保留默认值并报告。
+// Windows 版和 Linux、macOS 版启动器共用同一个配置文件格式和同一组环境变量
+package appconfig
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/mirror"
+)
+
+// 配置中的一个问题
+type Problem struct {
+	Source  string // 配置文件的行、环境变量或策略
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Key == "" {
+		return p.Source + ": " + p.Message
+	}
+	return fmt.Sprintf("%s %s: %s", p.Source, p.Key, p.Message)
+}
+
+// 配置中有问题的设置，这些设置使用默认值
+type Error struct {
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	var lines []string
+	for _, p := range e.Problems {
+		lines = append(lines, p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 合并配置的结果：依次解码的各个来源覆盖配置结构体中的默认值
+type Effective struct {
+	Sources  map[string]string // 每个配置项的来源，使用默认值的项不在其中
+	Problems []Problem
+	// 只读取 schema 中的配置项，不报告其他配置项：配置文件和环境变量与 Windows 版启动器共用，
+	// 其中有这个程序不使用的配置项
+	Partial bool
+}
+
+// 读取配置文件 path 并解码到 cfg（配置结构体的指针），文件不存在时不做任何事，无法解析时忽略整个文件
+func (e *Effective) DecodeFile(cfg interface{}, path string, schema []Field) {
+	values, err := ParseFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		e.Problems = append(e.Problems, Problem{Source: filepath.Base(path), Message: i18n.T("无法解析，忽略整个文件: %v", err)})
+	default:
+		for key, v := range values {
+			v.Source = i18n.T("%s 第 %d 行", filepath.Base(path), v.Line)
+			values[key] = v
+		}
+		e.Decode(cfg, values, schema)
+	}
+}
+
+// 检查 values 中的每一项，把通过检查的写入 cfg（配置结构体的指针）
+func (e *Effective) Decode(cfg interface{}, values map[string]Value, schema []Field) {
+	if e.Sources == nil {
+		e.Sources = map[string]string{}
+	}
+	fields := map[string]Field{}
+	var known []string
+	for _, f := range schema {
+		fields[f.Key] = f
+		known = append(known, f.Key)
+	}
+	// 按行号（其他来源按名称）排列，问题按文件中的顺序报告
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := values[keys[i]], values[keys[j]]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		v := values[key]
+		problem := func(format string, args ...interface{}) {
+			e.Problems = append(e.Problems, Problem{Source: v.Source, Key: key, Message: i18n.T(format, args...)})
+		}
+		f, ok := fields[key]
+		if !ok {
+			if e.Partial {
+				continue
+			}
+			if IsEnvName(key, schema) {
+				// 来源已经是变量名
+				e.Problems = append(e.Problems, Problem{Source: v.Source, Message: i18n.T("没有对应的配置项")})
+			} else if similar := similarKey(key, known); similar != "" {
+				problem("未知的配置项，是否为 %s？", similar)
+			} else {
+				problem("未知的配置项")
+			}
+			continue
+		}
+		field, _ := LookupField(reflect.ValueOf(cfg).Elem(), strings.Split(key, "."))
+		scratch := reflect.New(field.Type()).Elem()
+		if err := assign(scratch, v.Value); err != nil {
+			problem("%v", err)
+			continue
+		}
+		if err := checkValue(f, scratch); err != nil {
+			problem("%v", err)
+			continue
+		}
+		field.Set(scratch)
+		e.Sources[key] = v.Source
+	}
+}
+
+// 按 check 标签检查配置值。标签为逗号分隔的允许值和规则：url（http/https 地址）、
+// url=方案|方案（指定方案的地址）、hostport（主机:端口）、mirror（PyPI 镜像名称或地址）。
+// 空字符串表示使用默认行为，总是允许；整数不能为负数
+func checkValue(f Field, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int64:
+		if v.Int() < 0 {
+			return fmt.Errorf("%s", i18n.T("不能为负数"))
+		}
+		return nil
+	case reflect.String:
+	default:
+		return nil
+	}
+	value := v.String()
+	if f.Check == "" || value == "" {
+		return nil
+	}
+	var allowed []string
+	var ruleErr error
+	for _, rule := range strings.Split(f.Check, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		var err error
+		switch name {
+		case "url":
+			err = checkURL(value, arg)
+		case "hostport":
+			if _, _, e := net.SplitHostPort(value); e != nil {
+				err = fmt.Errorf("%s", i18n.T("应为 主机:端口，例如 127.0.0.1:7443"))
+			}
+		case "mirror":
+			_, _, err = mirror.Parse(value)
+		default:
+			if strings.EqualFold(value, rule) {
+				return nil
+			}
+			allowed = append(allowed, rule)
+			continue
+		}
+		if err == nil {
+			return nil
+		}
+		ruleErr = err
+	}
+	if len(allowed) == 0 {
+		return ruleErr
+	}
+	if ruleErr != nil {
+		return fmt.Errorf("%s", i18n.T("值 %q 无效，可用 %s，或 %v", value, strings.Join(allowed, "、"), ruleErr))
+	}
+	return fmt.Errorf("%s", i18n.T("值 %q 无效，可用 %s", value, strings.Join(allowed, "、")))
+}
+
+// 检查地址的格式，schemes 为 | 分隔的允许的方案，为空时允许 http 和 https
+func checkURL(value, schemes string) error {
+	if schemes == "" {
+		schemes = "http|https"
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" || !slices.ContainsFunc(strings.Split(schemes, "|"), func(s string) bool { return strings.EqualFold(s, u.Scheme) }) {
+		return fmt.Errorf("%s", i18n.T("%q 不是有效的地址，应以 %s:// 开头并包含主机名", value, strings.ReplaceAll(schemes, "|", "://、")))
+	}
+	return nil
+}
+
+// 与未知的配置项最接近的已知配置项：键相同只是节不同，或拼写相差不超过 2 个字符
+func similarKey(key string, known []string) string {
+	_, name, _ := strings.Cut(key, ".")
+	best, bestDist := "", 3
+	for _, k := range known {
+		if _, n, _ := strings.Cut(k, "."); n == name {
+			return k
+		}
+		if d := editDistance(key, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// 两个字符串的编辑距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,43 @@
+package appconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 只使用部分配置项的程序：读取共用的配置文件和环境变量，不报告其他配置项
+func TestDecodePartial(t *testing.T) {
+	var cfg struct {
+		UI struct {
+			Language string `toml:"language" check:"auto,zh-CN,en-US"`
+		} `toml:"ui"`
+		Network struct {
+			Index string `toml:"index" check:"mirror"`
+		} `toml:"network"`
+	}
+	cfg.UI.Language = "auto"
+	path := filepath.Join(t.TempDir(), "apprun.toml")
+	os.WriteFile(path, []byte("[ui]\nlanguage = \"zh-CN\"\n\n[tray]\nicon = true\n\n[network]\nindex = \"ftp://example.com\"\n"), 0644)
+	t.Setenv("SPEAKMYBOOK_UI_LANGUAGE", "en-US")
+	t.Setenv("SPEAKMYBOOK_INSTALL_GPU", "cuda")
+
+	schema := Schema(cfg)
+	e := Effective{Partial: true}
+	e.DecodeFile(&cfg, path, schema)
+	e.Decode(&cfg, EnvValues(schema), schema)
+	if cfg.UI.Language != "en-US" || e.Sources["ui.language"] != "环境变量 SPEAKMYBOOK_UI_LANGUAGE" {
+		t.Errorf("ui.language = %q（%s）", cfg.UI.Language, e.Sources["ui.language"])
+	}
+	// 使用的配置项仍然检查，不使用的 [tray] 和 SPEAKMYBOOK_INSTALL_GPU 不报告
+	if len(e.Problems) != 1 || e.Problems[0].Key != "network.index" || cfg.Network.Index != "" {
+		t.Errorf("Problems = %v, index = %q", e.Problems, cfg.Network.Index)
+	}
+
+	// 不是 Partial 时报告未知的配置项
+	e = Effective{}
+	e.DecodeFile(&cfg, path, schema)
+	if len(e.Problems) != 2 {
+		t.Errorf("Problems = %v", e.Problems)
+	}
+}
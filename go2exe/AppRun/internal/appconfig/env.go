@@ -0,0 +1,136 @@
+package appconfig
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go2exe/internal/i18n"
+)
+
+// 覆盖配置文件的环境变量前缀，例如 SPEAKMYBOOK_INSTALL_GPU 对应 [install] gpu。
+// 早期版本使用的 APPRUN_ 前缀仍然有效，同一项两种变量都设置时 SPEAKMYBOOK_ 优先
+const (
+	EnvPrefix       = "SPEAKMYBOOK_"
+	LegacyEnvPrefix = "APPRUN_"
+)
+
+// 部署脚本常用的配置项的简短变量名，同时设置了完整的变量名时以完整的为准
+var EnvAliases = map[string]string{
+	"SPEAKMYBOOK_MIRROR":         "network.index",
+	"SPEAKMYBOOK_PYTHON_VERSION": "install.python_version",
+}
+
+// 配置的一项，由配置结构体的 toml 和 check 标签生成
+type Field struct {
+	Key   string // 节.键
+	Kind  reflect.Kind
+	Check string // 取值规则，见 checkValue
+}
+
+// 配置项列表，按 cfg 中的顺序排列。cfg 为配置结构体，每个字段是一节
+func Schema(cfg interface{}) []Field {
+	var fields []Field
+	t := reflect.TypeOf(cfg)
+	for i := 0; i < t.NumField(); i++ {
+		section := t.Field(i)
+		for j := 0; j < section.Type.NumField(); j++ {
+			f := section.Type.Field(j)
+			fields = append(fields, Field{
+				Key:   section.Tag.Get("toml") + "." + f.Tag.Get("toml"),
+				Kind:  f.Type.Kind(),
+				Check: f.Tag.Get("check"),
+			})
+		}
+	}
+	return fields
+}
+
+// 配置项对应的环境变量
+func EnvName(prefix, key string) string {
+	return prefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// 看起来是配置项的环境变量：APPRUN_ 开头，或 SPEAKMYBOOK_ 加节名开头。
+// 启动器传给应用的 SPEAKMYBOOK_LANG、SPEAKMYBOOK_GPU 等变量不是配置项，不报告
+func IsEnvName(name string, schema []Field) bool {
+	name = strings.ToUpper(name)
+	if strings.HasPrefix(name, LegacyEnvPrefix) {
+		return true
+	}
+	for _, f := range schema {
+		section, _, _ := strings.Cut(f.Key, ".")
+		if strings.HasPrefix(name, EnvPrefix+strings.ToUpper(section)+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvValues 读取的环境变量：简短的变量名，或者看起来是配置项的变量
+func IsEnvVar(name string, schema []Field) bool {
+	_, alias := EnvAliases[strings.ToUpper(name)]
+	return alias || IsEnvName(name, schema)
+}
+
+// 环境变量和策略中的值都是字符串，按配置项的类型转换；无法转换时保留字符串，由 Effective.Decode 报告类型错误
+func ParseString(kind reflect.Kind, raw string) interface{} {
+	switch kind {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(strings.TrimSpace(raw)); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int64:
+		if n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64); err == nil {
+			return n
+		}
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	return raw
+}
+
+// 环境变量中的配置，看起来是配置项但不对应任何配置项的变量也返回，由 Effective.Decode 报告
+func EnvValues(schema []Field) map[string]Value {
+	// 变量名 -> 配置项和优先级：简短的变量名最低，SPEAKMYBOOK_ 的完整变量名最高
+	type envField struct {
+		Field
+		rank int
+	}
+	fields := map[string]envField{}
+	byKey := map[string]Field{}
+	for _, f := range schema {
+		byKey[f.Key] = f
+		fields[EnvName(LegacyEnvPrefix, f.Key)] = envField{f, 1}
+		fields[EnvName(EnvPrefix, f.Key)] = envField{f, 2}
+	}
+	for name, key := range EnvAliases {
+		fields[name] = envField{byKey[key], 0}
+	}
+	values := map[string]Value{}
+	ranks := map[string]int{}
+	for _, kv := range os.Environ() {
+		name, raw, _ := strings.Cut(kv, "=")
+		source := i18n.T("环境变量 %s", name)
+		f, ok := fields[strings.ToUpper(name)]
+		if !ok {
+			if IsEnvName(name, schema) {
+				values[name] = Value{Value: raw, Source: source}
+			}
+			continue
+		}
+		if r, set := ranks[f.Key]; set && r > f.rank {
+			continue
+		}
+		ranks[f.Key] = f.rank
+		values[f.Key] = Value{Value: ParseString(f.Kind, raw), Source: source}
+	}
+	return values
+}
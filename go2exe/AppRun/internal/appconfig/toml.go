@@ -0,0 +1,319 @@
+package appconfig
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TOML 中的一个值，key 为 "节.键" 形式
+type Value struct {
+	Value  interface{}
+	Line   int
+	Source string // 来源：配置文件的行、环境变量或策略，用于报告问题
+}
+
+// 解析 TOML 文件（仅支持启动器用到的子集：节、字符串、整数、布尔值、可以跨行的字符串数组）
+func ParseFile(path string) (map[string]Value, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]Value)
+	section := ""
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("%s 第 %d 行: 节名缺少 ]", filepath.Base(path), lineNo)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("%s 第 %d 行: 缺少 =", filepath.Base(path), lineNo)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if section != "" {
+			key = section + "." + key
+		}
+		raw := strings.TrimSpace(line[eq+1:])
+		start := lineNo
+		// 跨行的数组：一直读到 ] 结尾的行
+		for strings.HasPrefix(raw, "[") && !strings.HasSuffix(raw, "]") {
+			if !scanner.Scan() {
+				return nil, fmt.Errorf("%s 第 %d 行: 数组缺少 ]", filepath.Base(path), start)
+			}
+			lineNo++
+			raw += " " + strings.TrimSpace(stripComment(scanner.Text()))
+			raw = strings.TrimSpace(raw)
+		}
+		value, err := parseValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s 第 %d 行: %v", filepath.Base(path), start, err)
+		}
+		values[key] = Value{Value: value, Line: start}
+	}
+	return values, scanner.Err()
+}
+
+// 去掉不在字符串内的 # 注释
+func stripComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			// 双引号字符串中的转义，\" 不结束字符串
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// 按不在字符串内的逗号拆分数组元素
+func splitArray(s string) []string {
+	var parts []string
+	var quote rune
+	escaped := false
+	begin := 0
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == ',':
+			parts = append(parts, s[begin:i])
+			begin = i + 1
+		}
+	}
+	return append(parts, s[begin:])
+}
+
+// 解析单个值
+func parseValue(raw string) (interface{}, error) {
+	switch {
+	case raw == "":
+		return nil, fmt.Errorf("缺少值")
+	case raw == "true" || raw == "false":
+		return raw == "true", nil
+	case strings.HasPrefix(raw, "\""):
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return nil, fmt.Errorf("字符串格式错误: %s", raw)
+		}
+		return s, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return nil, fmt.Errorf("字符串格式错误: %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case strings.HasPrefix(raw, "["):
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("数组缺少 ]")
+		}
+		var items []string
+		for _, part := range splitArray(raw[1 : len(raw)-1]) {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			v, err := parseValue(part)
+			if err != nil {
+				return nil, err
+			}
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("数组只支持字符串元素")
+			}
+			items = append(items, s)
+		}
+		return items, nil
+	default:
+		n, err := strconv.ParseInt(strings.ReplaceAll(raw, "_", ""), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无法识别的值: %s", raw)
+		}
+		return n, nil
+	}
+}
+
+// 根据 "节.键" 路径查找结构体字段
+func LookupField(v reflect.Value, path []string) (reflect.Value, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("toml") == path[0] {
+			return LookupField(v.Field(i), path[1:])
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// 类型检查后赋值
+func assign(field reflect.Value, value interface{}) error {
+	switch field.Kind() {
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("应为字符串")
+		}
+		field.SetString(s)
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("应为 true 或 false")
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("应为整数")
+		}
+		field.SetInt(n)
+	case reflect.Slice:
+		items, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("应为字符串数组")
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("不支持的配置类型")
+	}
+	return nil
+}
+
+// 修改配置文件 path 中 section 节的值，保留其他内容和注释；文件、节或键不存在时添加
+func SaveValues(path, section string, values map[string]interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	eol := "\n"
+	if strings.Contains(string(data), "\r\n") {
+		eol = "\r\n"
+	}
+	var lines []string
+	if text := strings.TrimRight(string(data), "\r\n"); text != "" {
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, strings.TrimRight(line, "\r"))
+		}
+	}
+
+	// 替换节中已有的键，记下节的最后一行
+	current, sectionEnd := "", -1
+	written := map[string]bool{}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(stripComment(line))
+		if strings.HasPrefix(strings.TrimSpace(line), "[") && strings.HasSuffix(trimmed, "]") {
+			current = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if current == section {
+				sectionEnd = i + 1
+			}
+			continue
+		}
+		if current != section {
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			sectionEnd = i + 1
+		}
+		key, raw, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		// 跨行数组的其余行属于这个键，不当作键读取
+		end := i
+		if raw = strings.TrimSpace(raw); strings.HasPrefix(raw, "[") && !strings.HasSuffix(raw, "]") {
+			for end+1 < len(lines) {
+				end++
+				if strings.HasSuffix(strings.TrimSpace(stripComment(lines[end])), "]") {
+					break
+				}
+			}
+		}
+		if v, ok := values[key]; ok {
+			lines[i] = key + " = " + FormatValue(v)
+			lines = append(lines[:i+1], lines[end+1:]...)
+			written[key] = true
+		} else if end > i {
+			i = end
+			sectionEnd = i + 1
+		}
+	}
+
+	// 其余的键加到节的末尾，没有该节时在文件末尾添加
+	var keys []string
+	for key := range values {
+		if !written[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var added []string
+	for _, key := range keys {
+		added = append(added, key+" = "+FormatValue(values[key]))
+	}
+	if sectionEnd < 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+section+"]")
+		lines = append(lines, added...)
+	} else {
+		lines = append(lines[:sectionEnd], append(added, lines[sectionEnd:]...)...)
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, eol)+eol), 0644)
+}
+
+// 按 TOML 格式输出单个值
+func FormatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case []string:
+		var items []string
+		for _, item := range v {
+			items = append(items, strconv.Quote(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,130 @@
+package appconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStripComment(t *testing.T) {
+	tests := []struct {
+		line, want string
+	}{
+		{`key = 1 # 注释`, `key = 1 `},
+		{`# 整行注释`, ``},
+		{`url = "http://host/#frag" # 注释`, `url = "http://host/#frag" `},
+		{`path = 'C:\#dir' # 注释`, `path = 'C:\#dir' `},
+		{`s = "a\"#b" # 注释`, `s = "a\"#b" `},
+		{`list = ["#a", '#b'] # 注释`, `list = ["#a", '#b'] `},
+	}
+	for _, tt := range tests {
+		if got := stripComment(tt.line); got != tt.want {
+			t.Errorf("stripComment(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want interface{}
+		ok   bool
+	}{
+		{`"text"`, "text", true},
+		{`"C:\\Apps"`, `C:\Apps`, true},
+		{`'C:\Apps'`, `C:\Apps`, true},
+		{`"a, b"`, "a, b", true},
+		{`true`, true, true},
+		{`false`, false, true},
+		{`1_000`, int64(1000), true},
+		{`-5`, int64(-5), true},
+		{`[]`, []string(nil), true},
+		{`["a", 'b']`, []string{"a", "b"}, true},
+		{`["a,b", "c"]`, []string{"a,b", "c"}, true},
+		{`['x,y', "z\",w",]`, []string{"x,y", `z",w`}, true},
+		{``, nil, false},
+		{`"unterminated`, nil, false},
+		{`'unterminated`, nil, false},
+		{`["a"`, nil, false},
+		{`[1, 2]`, nil, false},
+		{`1.5`, nil, false},
+		{`yes`, nil, false},
+	}
+	for _, tt := range tests {
+		got, err := parseValue(tt.raw)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseValue(%q) error = %v, want ok %v", tt.raw, err, tt.ok)
+			continue
+		}
+		if tt.ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseValue(%q) = %#v, want %#v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apprun.toml")
+	os.WriteFile(path, []byte(`# 启动器配置
+[install]
+uv_dir = "D:\\uv # 不是注释"  # 注释
+min_free_space_mb = 2_048
+mirrors = [
+  "https://a.example/simple", # 第一个
+  'https://b.example/a,b',
+]
+
+[ui]
+language = 'en-US'
+`), 0644)
+
+	values, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile() = %v", err)
+	}
+	want := map[string]Value{
+		"install.uv_dir":            {Value: `D:\uv # 不是注释`, Line: 3},
+		"install.min_free_space_mb": {Value: int64(2048), Line: 4},
+		"install.mirrors":           {Value: []string{"https://a.example/simple", "https://b.example/a,b"}, Line: 5},
+		"ui.language":               {Value: "en-US", Line: 11},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("ParseFile() = %#v, want %#v", values, want)
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"[install\nuv_dir = 'a'\n", "第 1 行: 节名缺少 ]"},
+		{"[install]\n\nuv_dir\n", "第 3 行: 缺少 ="},
+		{"[install]\n# 注释\nmin_free_space_mb = 1.5\n", "第 3 行: 无法识别的值"},
+		{"[install]\nmirrors = [\n  'a',\n  1,\n]\n", "第 2 行: 数组只支持字符串元素"},
+		{"[install]\nmirrors = [\n  'a',\n", "第 2 行: 数组缺少 ]"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "apprun.toml")
+		os.WriteFile(path, []byte(tt.content), 0644)
+		_, err := ParseFile(path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseFile(%q) error = %v, want %q", tt.content, err, tt.want)
+		}
+	}
+}
+
+func TestSaveValuesMultilineArray(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apprun.toml")
+	os.WriteFile(path, []byte("[install]\nipfs_gateways = [\n  \"https://ipfs.io\",\n  \"https://gw.example/?a=b\",\n]\nprefer_ipfs = true\n"), 0644)
+
+	if err := SaveValues(path, "install", map[string]interface{}{"ipfs_gateways": []string{"https://dweb.link"}}); err != nil {
+		t.Fatalf("SaveValues() = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	want := "[install]\nipfs_gateways = [\"https://dweb.link\"]\nprefer_ipfs = true\n"
+	if string(data) != want {
+		t.Errorf("配置文件 =\n%s\nwant\n%s", data, want)
+	}
+}
@@ -0,0 +1,280 @@
+// Package appupdate 按发布清单更新程序目录中的 Python 项目：下载新版本的压缩包（或只下载有变化的文件）并校验 SHA-256，
+// 在暂存目录中准备好新版本后整体替换原来的目录，替换失败时保持原样。上一个版本保留在备份目录中，可以回退
+package appupdate
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go2exe/internal/models"
+)
+
+// 暂存目录和替换时旧目录的后缀
+const (
+	stagingSuffix = ".new"
+	oldSuffix     = ".old"
+)
+
+// 发布清单，例如 {"version": "0.2.0", "url": "https://.../python-0.2.0.zip", "sha256": "...", "notes": "..."}
+type Release struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`    // Python 项目的 zip，根目录（或唯一的顶层目录）中有 pyproject.toml
+	SHA256  string `json:"sha256"` // zip 的 SHA-256
+	Size    int64  `json:"size"`   // zip 的大小（字节），用于显示进度，0 表示未知
+	Notes   string `json:"notes"`  // 更新说明，询问用户时显示
+	Files   string `json:"files"`  // 可选，新版本的文件列表地址，提供时只下载有变化的文件（见 FetchFiles）
+}
+
+// 下载并解析发布清单
+func Fetch(ctx context.Context, client *http.Client, url string) (Release, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Release{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Release{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("获取发布清单失败: 服务器返回 %s", resp.Status)
+	}
+	var rel Release
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&rel); err != nil {
+		return Release{}, fmt.Errorf("发布清单格式错误: %v", err)
+	}
+	if rel.Version == "" || rel.URL == "" || len(rel.SHA256) != sha256.Size*2 {
+		return Release{}, fmt.Errorf("发布清单缺少版本、下载地址或 SHA-256")
+	}
+	return rel, nil
+}
+
+// latest 是否比 current 新。按点分隔逐段比较，数字段按数值比较，例如 0.10.0 比 0.9.1 新
+func Newer(latest, current string) bool {
+	a, b := strings.Split(latest, "."), strings.Split(current, ".")
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y string
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x == y {
+			continue
+		}
+		nx, errx := strconv.Atoi(x)
+		ny, erry := strconv.Atoi(y)
+		switch {
+		case x == "":
+			return false
+		case y == "":
+			return true
+		case errx == nil && erry == nil:
+			return nx > ny
+		}
+		return x > y
+	}
+	return false
+}
+
+// pyproject.toml 中 [project] 的 version
+func ProjectVersion(projectDir string) (string, error) {
+	f, err := os.Open(filepath.Join(projectDir, "pyproject.toml"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && section == "[project]" && strings.TrimSpace(key) == "version" {
+			return strings.Trim(strings.TrimSpace(value), `"'`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("pyproject.toml 中没有版本号")
+}
+
+// 下载 rel 的压缩包时使用的文件说明，下载到更新目录中的 python-<版本>.zip
+func (rel Release) File() models.File {
+	return models.File{
+		Name:   "python",
+		Path:   "python-" + rel.Version + ".zip",
+		URL:    rel.URL,
+		SHA256: rel.SHA256,
+		Size:   rel.Size,
+	}
+}
+
+// 用 zipPath 中的项目替换 projectDir，原来的目录移到 backupDir（替换之前的备份），更新后无法启动时可以回退。
+// keep 中列出的文件和目录（例如虚拟环境）新版本中没有时从原目录移过去。
+// 先完整解压到暂存目录，再通过两次重命名替换，任何一步失败都恢复原来的目录
+func Apply(zipPath, projectDir, backupDir string, keep []string) error {
+	staging := projectDir + stagingSuffix
+	os.RemoveAll(staging)
+	if err := extract(zipPath, staging); err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(staging, "pyproject.toml")); err != nil {
+		os.RemoveAll(staging)
+		return fmt.Errorf("更新包中没有 pyproject.toml")
+	}
+	return install(staging, projectDir, backupDir, keep)
+}
+
+// 用准备好的暂存目录 staging 替换 projectDir，原来的目录移到 backupDir。失败时删除暂存目录，projectDir 保持原样
+func install(staging, projectDir, backupDir string, keep []string) error {
+	old, err := swap(staging, projectDir, keep)
+	if err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+
+	// 备份失败（例如被杀毒软件占用）不影响更新，只是无法回退
+	os.RemoveAll(backupDir)
+	if err := os.MkdirAll(filepath.Dir(backupDir), 0755); err == nil {
+		if os.Rename(old, backupDir) == nil {
+			return nil
+		}
+	}
+	os.RemoveAll(old)
+	return nil
+}
+
+// 用 backupDir 中的上一个版本替换 projectDir，keep 中的文件从 projectDir 移过去，被替换的版本删除
+func Rollback(projectDir, backupDir string, keep []string) error {
+	if _, err := os.Stat(filepath.Join(backupDir, "pyproject.toml")); err != nil {
+		return fmt.Errorf("没有可以回退的版本")
+	}
+	old, err := swap(backupDir, projectDir, keep)
+	if err != nil {
+		return err
+	}
+	os.RemoveAll(old)
+	return nil
+}
+
+// 把 newDir 换到 projectDir 的位置，keep 中的文件先从 projectDir 移到 newDir（newDir 中已有的不移）。
+// 返回原来的目录被移到的位置；失败时恢复原样，newDir 保留
+func swap(newDir, projectDir string, keep []string) (old string, err error) {
+	old = projectDir + oldSuffix
+	os.RemoveAll(old)
+
+	// 移过去的文件在失败时移回原目录
+	var moved []string
+	defer func() {
+		if err != nil {
+			for _, name := range moved {
+				os.Rename(filepath.Join(newDir, name), filepath.Join(projectDir, name))
+			}
+		}
+	}()
+	for _, name := range keep {
+		src := filepath.Join(projectDir, filepath.FromSlash(name))
+		dst := filepath.Join(newDir, filepath.FromSlash(name))
+		if _, err := os.Lstat(src); err != nil {
+			continue
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return "", err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return "", fmt.Errorf("无法移动 %s（文件可能正在使用）: %v", name, err)
+		}
+		moved = append(moved, filepath.FromSlash(name))
+	}
+
+	if err := os.Rename(projectDir, old); err != nil {
+		return "", fmt.Errorf("无法替换 %s（文件可能正在使用）: %v", projectDir, err)
+	}
+	if err := os.Rename(newDir, projectDir); err != nil {
+		os.Rename(old, projectDir)
+		return "", fmt.Errorf("无法替换 %s: %v", projectDir, err)
+	}
+	return old, nil
+}
+
+// 解压到 dir。压缩包中所有文件都在同一个顶层目录下时去掉这层目录
+func extract(zipPath, dir string) error {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("无法打开更新包: %v", err)
+	}
+	defer r.Close()
+
+	prefix := commonRoot(r.File)
+	for _, zf := range r.File {
+		name := strings.TrimPrefix(zf.Name, prefix)
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("更新包中的路径无效: %s", zf.Name)
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := extractFile(zf, dest); err != nil {
+			return fmt.Errorf("解压 %s 失败: %v", zf.Name, err)
+		}
+	}
+	return nil
+}
+
+func extractFile(zf *zip.File, dest string) error {
+	src, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// 所有条目共同的顶层目录（带末尾的 /），没有时为空
+func commonRoot(files []*zip.File) string {
+	root := ""
+	for _, zf := range files {
+		first, _, nested := strings.Cut(zf.Name, "/")
+		if !nested || root != "" && first+"/" != root {
+			return ""
+		}
+		root = first + "/"
+	}
+	return root
+}
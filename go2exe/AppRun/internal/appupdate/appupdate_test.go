@@ -0,0 +1,191 @@
+package appupdate
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewer(t *testing.T) {
+	cases := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"0.2.0", "0.1.0", true},
+		{"0.10.0", "0.9.1", true},
+		{"0.1.0", "0.1.0", false},
+		{"0.1.0", "0.2.0", false},
+		{"0.1.1", "0.1", true},
+		{"0.1", "0.1.1", false},
+	}
+	for _, c := range cases {
+		if got := Newer(c.latest, c.current); got != c.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", c.latest, c.current, got, c.want)
+		}
+	}
+}
+
+// 写一个压缩包，files 的键为条目名
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, _ := w.Create(name)
+		fw.Write([]byte(content))
+	}
+	w.Close()
+	f.Close()
+}
+
+func TestApply(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "python")
+	os.MkdirAll(filepath.Join(project, ".venv", "Scripts"), 0755)
+	os.WriteFile(filepath.Join(project, ".venv", "Scripts", "python.exe"), []byte("venv"), 0644)
+	os.WriteFile(filepath.Join(project, "pyproject.toml"), []byte("[project]\nname = \"speakmybook\"\nversion = \"0.1.0\"\n"), 0644)
+	os.WriteFile(filepath.Join(project, "old.py"), []byte("old"), 0644)
+
+	zipPath := filepath.Join(root, "python-0.2.0.zip")
+	writeZip(t, zipPath, map[string]string{
+		"speakmybook-0.2.0/pyproject.toml": "[project]\nname = \"speakmybook\"\nversion = \"0.2.0\"\n",
+		"speakmybook-0.2.0/app.py":         "new",
+	})
+	backup := filepath.Join(root, "backup", "python")
+	if err := Apply(zipPath, project, backup, []string{".venv", "app.log"}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if v, err := ProjectVersion(project); v != "0.2.0" || err != nil {
+		t.Errorf("ProjectVersion() = %q, %v", v, err)
+	}
+	if _, err := os.Stat(filepath.Join(project, "old.py")); !os.IsNotExist(err) {
+		t.Errorf("旧版本的文件应被移除")
+	}
+	if data, _ := os.ReadFile(filepath.Join(project, ".venv", "Scripts", "python.exe")); string(data) != "venv" {
+		t.Errorf("虚拟环境应保留")
+	}
+	for _, dir := range []string{project + stagingSuffix, project + oldSuffix} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("%s 应被删除", dir)
+		}
+	}
+
+	if v, err := ProjectVersion(backup); v != "0.1.0" || err != nil {
+		t.Errorf("备份的版本 = %q, %v", v, err)
+	}
+
+	// 更新包无效时保持原样
+	bad := filepath.Join(root, "bad.zip")
+	writeZip(t, bad, map[string]string{"../evil.py": "x", "pyproject.toml": ""})
+	if err := Apply(bad, project, backup, []string{".venv"}); err == nil {
+		t.Errorf("路径无效的更新包应返回错误")
+	}
+	if _, err := os.Stat(filepath.Join(project, ".venv", "Scripts", "python.exe")); err != nil {
+		t.Errorf("更新失败时虚拟环境应保留: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "evil.py")); !os.IsNotExist(err) {
+		t.Errorf("不应解压到项目目录之外")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "python")
+	backup := filepath.Join(root, "backup", "python")
+	if err := Rollback(project, backup, nil); err == nil {
+		t.Errorf("没有备份时应返回错误")
+	}
+	os.MkdirAll(filepath.Join(project, ".venv"), 0755)
+	os.WriteFile(filepath.Join(project, "pyproject.toml"), []byte("[project]\nversion = \"0.2.0\"\n"), 0644)
+	os.MkdirAll(backup, 0755)
+	os.WriteFile(filepath.Join(backup, "pyproject.toml"), []byte("[project]\nversion = \"0.1.0\"\n"), 0644)
+	os.WriteFile(filepath.Join(backup, "uv.lock"), []byte("old lock"), 0644)
+
+	if err := Rollback(project, backup, []string{".venv"}); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if v, _ := ProjectVersion(project); v != "0.1.0" {
+		t.Errorf("回退后的版本 = %q", v)
+	}
+	if data, _ := os.ReadFile(filepath.Join(project, "uv.lock")); string(data) != "old lock" {
+		t.Errorf("uv.lock 应回退")
+	}
+	if _, err := os.Stat(filepath.Join(project, ".venv")); err != nil {
+		t.Errorf("虚拟环境应保留: %v", err)
+	}
+	for _, dir := range []string{backup, project + oldSuffix} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("%s 应被删除", dir)
+		}
+	}
+}
+
+func TestDelta(t *testing.T) {
+	sum := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+	root := t.TempDir()
+	project := filepath.Join(root, "python")
+	os.MkdirAll(filepath.Join(project, ".venv"), 0755)
+	os.WriteFile(filepath.Join(project, "pyproject.toml"), []byte("version 0.1.0"), 0644)
+	os.WriteFile(filepath.Join(project, "app.py"), []byte("unchanged"), 0644)
+	os.WriteFile(filepath.Join(project, "old.py"), []byte("old"), 0644)
+
+	list := &FileList{
+		Files: []Entry{
+			{Path: "pyproject.toml", SHA256: sum("version 0.2.0"), Size: 13},
+			{Path: "app.py", SHA256: sum("unchanged"), Size: 9},
+			{Path: "lib/new module.py", SHA256: sum("new"), Size: 3},
+		},
+		url: "https://example.com/speakmybook/python-0.2.0/files.json",
+	}
+	d, err := list.Diff(project)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	var urls []string
+	for _, f := range d.Changed {
+		urls = append(urls, f.URL)
+	}
+	want := []string{
+		"https://example.com/speakmybook/python-0.2.0/pyproject.toml",
+		"https://example.com/speakmybook/python-0.2.0/lib/new%20module.py",
+	}
+	if strings.Join(urls, " ") != strings.Join(want, " ") || d.Size != 16 {
+		t.Errorf("Diff() = %v, %d bytes, want %v, 16 bytes", urls, d.Size, want)
+	}
+	if !d.Worthwhile(100) || d.Worthwhile(20) || d.Worthwhile(0) {
+		t.Errorf("Worthwhile() 应在需要下载的大小不超过完整压缩包的一半时为 true")
+	}
+
+	downloads := filepath.Join(root, "updates")
+	os.MkdirAll(filepath.Join(downloads, "lib"), 0755)
+	os.WriteFile(filepath.Join(downloads, "pyproject.toml"), []byte("version 0.2.0"), 0644)
+	os.WriteFile(filepath.Join(downloads, "lib", "new module.py"), []byte("new"), 0644)
+	backup := filepath.Join(root, "backup", "python")
+	if err := list.Apply(downloads, project, backup, []string{".venv"}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	for name, want := range map[string]string{"pyproject.toml": "version 0.2.0", "app.py": "unchanged", "lib/new module.py": "new"} {
+		if data, _ := os.ReadFile(filepath.Join(project, filepath.FromSlash(name))); string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(project, "old.py")); !os.IsNotExist(err) {
+		t.Errorf("新版本中没有的文件应被移除")
+	}
+	if _, err := os.Stat(filepath.Join(project, ".venv")); err != nil {
+		t.Errorf("虚拟环境应保留")
+	}
+	if data, _ := os.ReadFile(filepath.Join(backup, "old.py")); string(data) != "old" {
+		t.Errorf("原来的版本应移到备份目录")
+	}
+}
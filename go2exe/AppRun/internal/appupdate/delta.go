@@ -0,0 +1,172 @@
+package appupdate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go2exe/internal/models"
+)
+
+// 有变化的文件超过完整压缩包大小的这个比例时，直接下载压缩包更划算（请求少，压缩率高）
+const deltaThreshold = 0.5
+
+// 新版本中的一个文件
+type Entry struct {
+	Path   string `json:"path"`   // 在项目目录中的相对路径，使用 /
+	SHA256 string `json:"sha256"` // 文件的 SHA-256
+	Size   int64  `json:"size"`   // 文件大小（字节）
+}
+
+// 新版本的文件列表，例如 {"files": [{"path": "app.py", "sha256": "...", "size": 1234}]}。
+// 每个文件放在列表所在的目录下，按相对路径下载，例如 .../python-0.2.0/files.json 对应 .../python-0.2.0/app.py
+type FileList struct {
+	Files []Entry `json:"files"`
+	url   string
+}
+
+// 需要下载的文件和它们的总大小
+type Delta struct {
+	Changed []models.File
+	Size    int64
+	total   int64 // 新版本所有文件的总大小
+}
+
+// 下载并解析文件列表
+func FetchFiles(ctx context.Context, client *http.Client, listURL string) (*FileList, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, listURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取文件列表失败: 服务器返回 %s", resp.Status)
+	}
+	list := &FileList{url: listURL}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(list); err != nil {
+		return nil, fmt.Errorf("文件列表格式错误: %v", err)
+	}
+	hasProject := false
+	for _, e := range list.Files {
+		if len(e.SHA256) != sha256.Size*2 || !filepath.IsLocal(filepath.FromSlash(e.Path)) {
+			return nil, fmt.Errorf("文件列表中的 %q 缺少 SHA-256 或路径无效", e.Path)
+		}
+		hasProject = hasProject || e.Path == "pyproject.toml"
+	}
+	if !hasProject {
+		return nil, fmt.Errorf("文件列表中没有 pyproject.toml")
+	}
+	return list, nil
+}
+
+// 列表中的文件，下载地址按列表的地址解析
+func (l *FileList) files() ([]models.File, error) {
+	base, err := url.Parse(l.url)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]models.File, 0, len(l.Files))
+	for _, e := range l.Files {
+		var segments []string
+		for _, s := range strings.Split(e.Path, "/") {
+			segments = append(segments, url.PathEscape(s))
+		}
+		ref, err := url.Parse(strings.Join(segments, "/"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, models.File{
+			Name:   e.Path,
+			Path:   e.Path,
+			URL:    base.ResolveReference(ref).String(),
+			SHA256: e.SHA256,
+			Size:   e.Size,
+		})
+	}
+	return files, nil
+}
+
+// 对比 projectDir 中的文件，找出新版本中新增或内容不同的文件
+func (l *FileList) Diff(projectDir string) (Delta, error) {
+	files, err := l.files()
+	if err != nil {
+		return Delta{}, err
+	}
+	d := Delta{Changed: models.Verify(projectDir, files)}
+	for _, f := range files {
+		d.total += f.Size
+	}
+	for _, f := range d.Changed {
+		d.Size += f.Size
+	}
+	return d, nil
+}
+
+// 是否值得增量更新：需要下载的大小不超过完整压缩包（未知时按所有文件的总大小）的一半
+func (d Delta) Worthwhile(fullSize int64) bool {
+	if fullSize <= 0 {
+		fullSize = d.total
+	}
+	return float64(d.Size) <= float64(fullSize)*deltaThreshold
+}
+
+// 用列表中的文件组成新版本并替换 projectDir：有变化的文件取自 downloadDir（已由 Diff 列出并下载），
+// 其余文件从 projectDir 复制。新版本中没有的文件不保留（keep 中列出的除外），原来的目录移到 backupDir
+func (l *FileList) Apply(downloadDir, projectDir, backupDir string, keep []string) error {
+	files, err := l.files()
+	if err != nil {
+		return err
+	}
+	staging := projectDir + stagingSuffix
+	os.RemoveAll(staging)
+	for _, f := range files {
+		src := f.Local(downloadDir)
+		if _, err := os.Stat(src); err != nil {
+			src = f.Local(projectDir)
+		}
+		if err := copyFile(src, f.Local(staging)); err != nil {
+			os.RemoveAll(staging)
+			return fmt.Errorf("无法准备 %s: %v", f.Path, err)
+		}
+	}
+	// 对比之后原来的文件可能被修改过，全部校验一次
+	if bad := models.Verify(staging, files); len(bad) > 0 {
+		os.RemoveAll(staging)
+		return fmt.Errorf("%s 的校验值不匹配", bad[0].Path)
+	}
+	return install(staging, projectDir, backupDir, keep)
+}
+
+func copyFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,274 @@
+// Package control 提供集中管理用的控制接口：管理控制台通过双向 TLS 认证的 gRPC
+// 远程执行安装、更新依赖、查询状态和收集诊断包。接口定义见 controlpb/control.proto：
+//
+//	Install             检查并安装 uv 和 Python
+//	Update              重新同步应用依赖
+//	Status              查询安装和运行状态
+//	CollectDiagnostics  分块返回诊断包（zip）
+package control
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative controlpb/control.proto
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"go2exe/internal/control/controlpb"
+)
+
+// 控制接口背后的实际操作，由启动器实现
+type Service interface {
+	// 检查 uv 和 Python，缺少时安装
+	Install() error
+	// 重新同步应用依赖
+	Update() error
+	// 当前的安装和运行状态
+	Status() Status
+	// 生成诊断包并写入 w
+	CollectDiagnostics(w io.Writer) error
+}
+
+// 安装和运行状态
+type Status struct {
+	Hostname        string
+	UVInstalled     bool
+	PythonInstalled bool
+	VenvReady       bool
+	AppRunning      bool
+	Busy            string     // 正在执行的操作
+	LastOperation   *Operation // 最近一次完成的操作
+}
+
+// 一次安装或更新操作的结果
+type Operation struct {
+	Name       string
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// 服务设置
+type Config struct {
+	Listen         string   // 监听地址，例如 "127.0.0.1:7443"
+	CertFile       string   // 服务端证书
+	KeyFile        string   // 服务端私钥
+	ClientCAFile   string   // 签发客户端证书的 CA，只接受它签发的证书
+	AllowedClients []string // 允许的客户端证书 CN，为空时接受 CA 签发的所有证书
+}
+
+// 控制接口服务
+type Server struct {
+	controlpb.UnimplementedControlServer
+
+	svc     Service
+	allowed map[string]bool
+	listen  string
+	grpc    *grpc.Server
+
+	mu   sync.Mutex
+	busy string
+	last *Operation
+}
+
+// 按设置加载证书并创建服务，ListenAndServe 后开始接受请求
+func NewServer(cfg Config, svc Service) (*Server, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取服务端证书失败: %v", err)
+	}
+	caPEM, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取客户端 CA 失败: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("%s 中没有有效的证书", cfg.ClientCAFile)
+	}
+
+	s := newServer(svc, cfg.AllowedClients, credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}))
+	s.listen = cfg.Listen
+	return s, nil
+}
+
+// 创建服务并注册 RPC，creds 为连接使用的 TLS 设置
+func newServer(svc Service, allowedClients []string, creds credentials.TransportCredentials) *Server {
+	s := &Server{svc: svc, allowed: map[string]bool{}}
+	for _, cn := range allowedClients {
+		if cn = strings.TrimSpace(cn); cn != "" {
+			s.allowed[cn] = true
+		}
+	}
+	s.grpc = grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.ConnectionTimeout(10*time.Second),
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := s.authorize(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.authorize(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	controlpb.RegisterControlServer(s.grpc, s)
+	return s
+}
+
+// 开始接受请求，直到 Close
+func (s *Server) ListenAndServe() error {
+	lis, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+// 在 lis 上接受请求，直到 Close
+func (s *Server) Serve(lis net.Listener) error {
+	log.Printf("控制接口监听 %s", lis.Addr())
+	return s.grpc.Serve(lis)
+}
+
+// 停止服务
+func (s *Server) Close() error {
+	s.grpc.Stop()
+	return nil
+}
+
+// 检查客户端证书的 CN 是否在允许列表中
+func (s *Server) authorize(ctx context.Context, method string) error {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "需要客户端证书")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return status.Error(codes.Unauthenticated, "需要客户端证书")
+	}
+	cn := info.State.PeerCertificates[0].Subject.CommonName
+	if len(s.allowed) > 0 && !s.allowed[cn] {
+		log.Printf("拒绝控制接口请求: 客户端 %s 不在允许列表中", cn)
+		return status.Error(codes.PermissionDenied, "客户端未被授权")
+	}
+	log.Printf("控制接口请求: %s（客户端 %s）", method, cn)
+	return nil
+}
+
+func (s *Server) Install(ctx context.Context, req *controlpb.InstallRequest) (*controlpb.Operation, error) {
+	return s.runOperation("install", s.svc.Install)
+}
+
+func (s *Server) Update(ctx context.Context, req *controlpb.UpdateRequest) (*controlpb.Operation, error) {
+	return s.runOperation("update", s.svc.Update)
+}
+
+func (s *Server) Status(ctx context.Context, req *controlpb.StatusRequest) (*controlpb.StatusReply, error) {
+	st := s.svc.Status()
+	s.mu.Lock()
+	st.Busy, st.LastOperation = s.busy, s.last
+	s.mu.Unlock()
+	return &controlpb.StatusReply{
+		Hostname:        st.Hostname,
+		UvInstalled:     st.UVInstalled,
+		PythonInstalled: st.PythonInstalled,
+		VenvReady:       st.VenvReady,
+		AppRunning:      st.AppRunning,
+		Busy:            st.Busy,
+		LastOperation:   operationProto(st.LastOperation),
+	}, nil
+}
+
+// 诊断包较大，按块发送
+func (s *Server) CollectDiagnostics(req *controlpb.CollectDiagnosticsRequest, stream grpc.ServerStreamingServer[controlpb.DiagnosticsChunk]) error {
+	if err := s.svc.CollectDiagnostics(&chunkWriter{stream: stream}); err != nil {
+		log.Printf("控制接口生成诊断包失败: %v", err)
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// 把写入的内容作为 DiagnosticsChunk 发送，每块不超过 chunkSize
+type chunkWriter struct {
+	stream grpc.ServerStreamingServer[controlpb.DiagnosticsChunk]
+}
+
+const chunkSize = 64 << 10
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		size := min(len(p), chunkSize)
+		if err := w.stream.Send(&controlpb.DiagnosticsChunk{Data: p[:size]}); err != nil {
+			return n, err
+		}
+		n += size
+		p = p[size:]
+	}
+	return n, nil
+}
+
+// 执行安装或更新，同一时间只允许一个操作，完成后返回结果
+func (s *Server) runOperation(name string, fn func() error) (*controlpb.Operation, error) {
+	s.mu.Lock()
+	if s.busy != "" {
+		busy := s.busy
+		s.mu.Unlock()
+		return nil, status.Errorf(codes.Aborted, "正在执行 %s", busy)
+	}
+	s.busy = name
+	s.mu.Unlock()
+
+	op := &Operation{Name: name, StartedAt: time.Now()}
+	err := fn()
+	op.FinishedAt = time.Now()
+	if err != nil {
+		op.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	s.busy, s.last = "", op
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Printf("控制接口操作 %s 失败: %v", name, err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return operationProto(op), nil
+}
+
+func operationProto(op *Operation) *controlpb.Operation {
+	if op == nil {
+		return nil
+	}
+	return &controlpb.Operation{
+		Name:       op.Name,
+		Error:      op.Error,
+		StartedAt:  timestamppb.New(op.StartedAt),
+		FinishedAt: timestamppb.New(op.FinishedAt),
+	}
+}
@@ -0,0 +1,235 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"go2exe/internal/control/controlpb"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// 模拟的启动器操作
+type fakeService struct {
+	installErr error
+	installs   int
+	block      chan struct{} // 不为 nil 时 Install 等待它关闭
+}
+
+func (f *fakeService) Install() error {
+	f.installs++
+	if f.block != nil {
+		<-f.block
+	}
+	return f.installErr
+}
+
+func (f *fakeService) Update() error { return nil }
+
+func (f *fakeService) Status() Status {
+	return Status{Hostname: "pc-01", UVInstalled: true}
+}
+
+func (f *fakeService) CollectDiagnostics(w io.Writer) error {
+	_, err := w.Write(append([]byte("PK"), make([]byte, chunkSize+10)...))
+	return err
+}
+
+// 测试用的 CA 和由它签发的证书
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// 签发 CN 为 cn 的证书，server 为 true 时用于服务端
+func (ca *testCA) issue(t *testing.T, cn string, server bool) tls.Certificate {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.DNSNames = []string{"localhost"}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// 在内存中的连接上启动服务，返回以 clientCN 的证书连接的客户端；clientCN 为空时不带证书
+func startServer(t *testing.T, svc Service, allowed []string, clientCN string) (*Server, controlpb.ControlClient) {
+	ca := newTestCA(t)
+	s := newServer(svc, allowed, credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "localhost", true)},
+		// 实际使用 RequireAndVerifyClientCert，这里允许不带证书的连接，以便测试 authorize
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  ca.pool,
+		MinVersion: tls.VersionTLS12,
+	}))
+	lis := bufconn.Listen(1 << 20)
+	go s.Serve(lis)
+	t.Cleanup(func() { s.Close() })
+
+	clientTLS := &tls.Config{RootCAs: ca.pool, ServerName: "localhost"}
+	if clientCN != "" {
+		clientTLS.Certificates = []tls.Certificate{ca.issue(t, clientCN, false)}
+	}
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return s, controlpb.NewControlClient(conn)
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		cn   string
+		want codes.Code
+	}{
+		{"", codes.Unauthenticated},
+		{"someone-else", codes.PermissionDenied},
+		{"fleet-console", codes.OK},
+	}
+	for _, tt := range tests {
+		_, client := startServer(t, &fakeService{}, []string{"fleet-console"}, tt.cn)
+		_, err := client.Status(context.Background(), &controlpb.StatusRequest{})
+		if got := status.Code(err); got != tt.want {
+			t.Errorf("客户端 %q: 错误码 %v, want %v", tt.cn, got, tt.want)
+		}
+	}
+}
+
+func TestInstallAndStatus(t *testing.T) {
+	svc := &fakeService{installErr: errors.New("安装 uv 失败")}
+	_, client := startServer(t, svc, nil, "console")
+	ctx := context.Background()
+
+	_, err := client.Install(ctx, &controlpb.InstallRequest{})
+	if status.Code(err) != codes.Internal || status.Convert(err).Message() != "安装 uv 失败" {
+		t.Errorf("安装失败时 Install() = %v", err)
+	}
+
+	st, err := client.Status(ctx, &controlpb.StatusRequest{})
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+	op := st.GetLastOperation()
+	if st.GetHostname() != "pc-01" || !st.GetUvInstalled() || op.GetName() != "install" || op.GetError() != "安装 uv 失败" {
+		t.Errorf("状态 = %v", st)
+	}
+
+	svc.installErr = nil
+	op, err = client.Install(ctx, &controlpb.InstallRequest{})
+	if err != nil || op.GetName() != "install" || op.GetError() != "" || op.GetFinishedAt() == nil {
+		t.Errorf("Install() = %v, %v", op, err)
+	}
+}
+
+func TestCollectDiagnostics(t *testing.T) {
+	_, client := startServer(t, &fakeService{}, nil, "console")
+	stream, err := client.CollectDiagnostics(context.Background(), &controlpb.CollectDiagnosticsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	chunks := 0
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() = %v", err)
+		}
+		buf.Write(chunk.GetData())
+		chunks++
+	}
+	if !strings.HasPrefix(buf.String(), "PK") || buf.Len() != chunkSize+12 || chunks != 2 {
+		t.Errorf("诊断包 %d 字节，%d 块", buf.Len(), chunks)
+	}
+}
+
+func TestOperationConflict(t *testing.T) {
+	svc := &fakeService{block: make(chan struct{})}
+	s, client := startServer(t, svc, nil, "console")
+	ctx := context.Background()
+
+	done := make(chan struct{})
+	go func() {
+		client.Install(ctx, &controlpb.InstallRequest{})
+		close(done)
+	}()
+	// 等第一个安装开始执行
+	for {
+		s.mu.Lock()
+		busy := s.busy
+		s.mu.Unlock()
+		if busy != "" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err := client.Update(ctx, &controlpb.UpdateRequest{})
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("已有操作时 Update() = %v, want %v", err, codes.Aborted)
+	}
+	close(svc.block)
+	<-done
+}
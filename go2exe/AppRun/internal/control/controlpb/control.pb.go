@@ -0,0 +1,479 @@
+// 集中管理控制接口。管理控制台用客户端证书（双向 TLS）连接启动器，远程执行安装、
+// 更新依赖、查询状态和收集诊断包。修改后在 internal/control 目录中执行 go generate 重新生成代码。
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: control.proto
+
+package controlpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type InstallRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InstallRequest) Reset() {
+	*x = InstallRequest{}
+	mi := &file_control_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InstallRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstallRequest) ProtoMessage() {}
+
+func (x *InstallRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstallRequest.ProtoReflect.Descriptor instead.
+func (*InstallRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{0}
+}
+
+type UpdateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateRequest) Reset() {
+	*x = UpdateRequest{}
+	mi := &file_control_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateRequest) ProtoMessage() {}
+
+func (x *UpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateRequest.ProtoReflect.Descriptor instead.
+func (*UpdateRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{1}
+}
+
+type StatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	mi := &file_control_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{2}
+}
+
+type CollectDiagnosticsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CollectDiagnosticsRequest) Reset() {
+	*x = CollectDiagnosticsRequest{}
+	mi := &file_control_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CollectDiagnosticsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectDiagnosticsRequest) ProtoMessage() {}
+
+func (x *CollectDiagnosticsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectDiagnosticsRequest.ProtoReflect.Descriptor instead.
+func (*CollectDiagnosticsRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{3}
+}
+
+// 一次安装或更新操作的结果
+type Operation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Operation) Reset() {
+	*x = Operation{}
+	mi := &file_control_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Operation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Operation) ProtoMessage() {}
+
+func (x *Operation) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Operation.ProtoReflect.Descriptor instead.
+func (*Operation) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Operation) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Operation) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Operation) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Operation) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+// 安装和运行状态
+type StatusReply struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Hostname        string                 `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	UvInstalled     bool                   `protobuf:"varint,2,opt,name=uv_installed,json=uvInstalled,proto3" json:"uv_installed,omitempty"`
+	PythonInstalled bool                   `protobuf:"varint,3,opt,name=python_installed,json=pythonInstalled,proto3" json:"python_installed,omitempty"`
+	VenvReady       bool                   `protobuf:"varint,4,opt,name=venv_ready,json=venvReady,proto3" json:"venv_ready,omitempty"`
+	AppRunning      bool                   `protobuf:"varint,5,opt,name=app_running,json=appRunning,proto3" json:"app_running,omitempty"`
+	// 正在执行的操作
+	Busy string `protobuf:"bytes,6,opt,name=busy,proto3" json:"busy,omitempty"`
+	// 最近一次完成的操作
+	LastOperation *Operation `protobuf:"bytes,7,opt,name=last_operation,json=lastOperation,proto3" json:"last_operation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusReply) Reset() {
+	*x = StatusReply{}
+	mi := &file_control_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusReply) ProtoMessage() {}
+
+func (x *StatusReply) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusReply.ProtoReflect.Descriptor instead.
+func (*StatusReply) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StatusReply) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *StatusReply) GetUvInstalled() bool {
+	if x != nil {
+		return x.UvInstalled
+	}
+	return false
+}
+
+func (x *StatusReply) GetPythonInstalled() bool {
+	if x != nil {
+		return x.PythonInstalled
+	}
+	return false
+}
+
+func (x *StatusReply) GetVenvReady() bool {
+	if x != nil {
+		return x.VenvReady
+	}
+	return false
+}
+
+func (x *StatusReply) GetAppRunning() bool {
+	if x != nil {
+		return x.AppRunning
+	}
+	return false
+}
+
+func (x *StatusReply) GetBusy() string {
+	if x != nil {
+		return x.Busy
+	}
+	return ""
+}
+
+func (x *StatusReply) GetLastOperation() *Operation {
+	if x != nil {
+		return x.LastOperation
+	}
+	return nil
+}
+
+// 诊断包的一段内容
+type DiagnosticsChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiagnosticsChunk) Reset() {
+	*x = DiagnosticsChunk{}
+	mi := &file_control_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiagnosticsChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiagnosticsChunk) ProtoMessage() {}
+
+func (x *DiagnosticsChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiagnosticsChunk.ProtoReflect.Descriptor instead.
+func (*DiagnosticsChunk) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DiagnosticsChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+var File_control_proto protoreflect.FileDescriptor
+
+const file_control_proto_rawDesc = "" +
+	"\n" +
+	"\rcontrol.proto\x12\x16speakmybook.control.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x10\n" +
+	"\x0eInstallRequest\"\x0f\n" +
+	"\rUpdateRequest\"\x0f\n" +
+	"\rStatusRequest\"\x1b\n" +
+	"\x19CollectDiagnosticsRequest\"\xad\x01\n" +
+	"\tOperation\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"started_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12;\n" +
+	"\vfinished_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\"\x95\x02\n" +
+	"\vStatusReply\x12\x1a\n" +
+	"\bhostname\x18\x01 \x01(\tR\bhostname\x12!\n" +
+	"\fuv_installed\x18\x02 \x01(\bR\vuvInstalled\x12)\n" +
+	"\x10python_installed\x18\x03 \x01(\bR\x0fpythonInstalled\x12\x1d\n" +
+	"\n" +
+	"venv_ready\x18\x04 \x01(\bR\tvenvReady\x12\x1f\n" +
+	"\vapp_running\x18\x05 \x01(\bR\n" +
+	"appRunning\x12\x12\n" +
+	"\x04busy\x18\x06 \x01(\tR\x04busy\x12H\n" +
+	"\x0elast_operation\x18\a \x01(\v2!.speakmybook.control.v1.OperationR\rlastOperation\"&\n" +
+	"\x10DiagnosticsChunk\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data2\xfe\x02\n" +
+	"\aControl\x12T\n" +
+	"\aInstall\x12&.speakmybook.control.v1.InstallRequest\x1a!.speakmybook.control.v1.Operation\x12R\n" +
+	"\x06Update\x12%.speakmybook.control.v1.UpdateRequest\x1a!.speakmybook.control.v1.Operation\x12T\n" +
+	"\x06Status\x12%.speakmybook.control.v1.StatusRequest\x1a#.speakmybook.control.v1.StatusReply\x12s\n" +
+	"\x12CollectDiagnostics\x121.speakmybook.control.v1.CollectDiagnosticsRequest\x1a(.speakmybook.control.v1.DiagnosticsChunk0\x01B#Z!go2exe/internal/control/controlpbb\x06proto3"
+
+var (
+	file_control_proto_rawDescOnce sync.Once
+	file_control_proto_rawDescData []byte
+)
+
+func file_control_proto_rawDescGZIP() []byte {
+	file_control_proto_rawDescOnce.Do(func() {
+		file_control_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_control_proto_rawDesc), len(file_control_proto_rawDesc)))
+	})
+	return file_control_proto_rawDescData
+}
+
+var file_control_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_control_proto_goTypes = []any{
+	(*InstallRequest)(nil),            // 0: speakmybook.control.v1.InstallRequest
+	(*UpdateRequest)(nil),             // 1: speakmybook.control.v1.UpdateRequest
+	(*StatusRequest)(nil),             // 2: speakmybook.control.v1.StatusRequest
+	(*CollectDiagnosticsRequest)(nil), // 3: speakmybook.control.v1.CollectDiagnosticsRequest
+	(*Operation)(nil),                 // 4: speakmybook.control.v1.Operation
+	(*StatusReply)(nil),               // 5: speakmybook.control.v1.StatusReply
+	(*DiagnosticsChunk)(nil),          // 6: speakmybook.control.v1.DiagnosticsChunk
+	(*timestamppb.Timestamp)(nil),     // 7: google.protobuf.Timestamp
+}
+var file_control_proto_depIdxs = []int32{
+	7, // 0: speakmybook.control.v1.Operation.started_at:type_name -> google.protobuf.Timestamp
+	7, // 1: speakmybook.control.v1.Operation.finished_at:type_name -> google.protobuf.Timestamp
+	4, // 2: speakmybook.control.v1.StatusReply.last_operation:type_name -> speakmybook.control.v1.Operation
+	0, // 3: speakmybook.control.v1.Control.Install:input_type -> speakmybook.control.v1.InstallRequest
+	1, // 4: speakmybook.control.v1.Control.Update:input_type -> speakmybook.control.v1.UpdateRequest
+	2, // 5: speakmybook.control.v1.Control.Status:input_type -> speakmybook.control.v1.StatusRequest
+	3, // 6: speakmybook.control.v1.Control.CollectDiagnostics:input_type -> speakmybook.control.v1.CollectDiagnosticsRequest
+	4, // 7: speakmybook.control.v1.Control.Install:output_type -> speakmybook.control.v1.Operation
+	4, // 8: speakmybook.control.v1.Control.Update:output_type -> speakmybook.control.v1.Operation
+	5, // 9: speakmybook.control.v1.Control.Status:output_type -> speakmybook.control.v1.StatusReply
+	6, // 10: speakmybook.control.v1.Control.CollectDiagnostics:output_type -> speakmybook.control.v1.DiagnosticsChunk
+	7, // [7:11] is the sub-list for method output_type
+	3, // [3:7] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_control_proto_init() }
+func file_control_proto_init() {
+	if File_control_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_control_proto_rawDesc), len(file_control_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_control_proto_goTypes,
+		DependencyIndexes: file_control_proto_depIdxs,
+		MessageInfos:      file_control_proto_msgTypes,
+	}.Build()
+	File_control_proto = out.File
+	file_control_proto_goTypes = nil
+	file_control_proto_depIdxs = nil
+}
@@ -0,0 +1,90 @@
+// Package cuda 按 NVIDIA 驱动版本选择兼容的 CUDA 安装包变体（例如 PyTorch 的 cu118、cu121、cu124），
+// 避免安装了驱动不支持的版本，运行时才报 “torch not compiled for your driver” 之类的错误
+package cuda
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NVIDIA 驱动版本，例如 551.61
+type Driver struct {
+	Major, Minor int
+}
+
+func (d Driver) String() string {
+	return fmt.Sprintf("%d.%02d", d.Major, d.Minor)
+}
+
+// 是否不低于 o
+func (d Driver) AtLeast(o Driver) bool {
+	return d.Major > o.Major || d.Major == o.Major && d.Minor >= o.Minor
+}
+
+// 解析 "551.61" 形式的驱动版本
+func ParseDriver(s string) (Driver, error) {
+	s = strings.TrimSpace(s)
+	major, minor, _ := strings.Cut(s, ".")
+	var d Driver
+	var err1, err2 error
+	d.Major, err1 = strconv.Atoi(major)
+	d.Minor, err2 = strconv.Atoi(minor)
+	if err1 != nil || err2 != nil {
+		return Driver{}, fmt.Errorf("无法识别的驱动版本: %q", s)
+	}
+	return d, nil
+}
+
+// 解析 nvidia-smi --query-gpu=driver_version --format=csv,noheader 的输出，多块显卡时取第一块
+func ParseSMI(output string) (Driver, error) {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return ParseDriver(line)
+		}
+	}
+	return Driver{}, fmt.Errorf("nvidia-smi 没有输出驱动版本")
+}
+
+// 一个 CUDA 安装包变体
+type Variant struct {
+	Name      string // 变体名称，也是 pyproject.toml 中对应的 extra，例如 cu121
+	CUDA      string // CUDA 版本
+	MinDriver Driver // Windows 上需要的最低驱动版本
+}
+
+// 已知的变体，从新到旧排列。最低驱动版本取自 NVIDIA 的 CUDA 工具包发行说明（Windows）
+var Variants = []Variant{
+	{"cu124", "12.4", Driver{551, 61}},
+	{"cu121", "12.1", Driver{531, 14}},
+	{"cu118", "11.8", Driver{522, 6}},
+}
+
+// 在 available（项目提供的变体名称）中选出驱动支持的最新变体
+func Select(d Driver, available []string) (Variant, bool) {
+	for _, v := range Variants {
+		if !d.AtLeast(v.MinDriver) {
+			continue
+		}
+		for _, name := range available {
+			if strings.EqualFold(name, v.Name) {
+				return v, true
+			}
+		}
+	}
+	return Variant{}, false
+}
+
+// available 中的已知变体，从新到旧排列
+func Known(available []string) []Variant {
+	var list []Variant
+	for _, v := range Variants {
+		for _, name := range available {
+			if strings.EqualFold(name, v.Name) {
+				list = append(list, v)
+				break
+			}
+		}
+	}
+	return list
+}
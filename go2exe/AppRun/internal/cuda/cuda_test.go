@@ -0,0 +1,46 @@
+package cuda
+
+import "testing"
+
+func TestParseSMI(t *testing.T) {
+	d, err := ParseSMI("\r\n551.61\r\n546.33\r\n")
+	if err != nil || d != (Driver{551, 61}) {
+		t.Errorf("ParseSMI() = %v, %v", d, err)
+	}
+	if d.String() != "551.61" {
+		t.Errorf("String() = %s", d)
+	}
+	if _, err := ParseSMI("NVIDIA-SMI has failed because it couldn't communicate with the NVIDIA driver."); err == nil {
+		t.Errorf("无法识别的输出应返回错误")
+	}
+	if _, err := ParseSMI(""); err == nil {
+		t.Errorf("空输出应返回错误")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	all := []string{"cpu", "cu118", "cu121", "cu124"}
+	cases := []struct {
+		driver    Driver
+		available []string
+		want      string
+	}{
+		{Driver{560, 94}, all, "cu124"},
+		{Driver{551, 61}, all, "cu124"},
+		{Driver{546, 33}, all, "cu121"},
+		{Driver{531, 14}, all, "cu121"},
+		{Driver{527, 41}, all, "cu118"},
+		{Driver{516, 94}, all, ""},
+		{Driver{560, 94}, []string{"cpu", "CU118"}, "cu118"},
+		{Driver{560, 94}, []string{"cpu"}, ""},
+	}
+	for _, c := range cases {
+		v, ok := Select(c.driver, c.available)
+		if v.Name != c.want || ok != (c.want != "") {
+			t.Errorf("Select(%s, %v) = %v, %v, want %s", c.driver, c.available, v, ok, c.want)
+		}
+	}
+	if got := Known(all); len(got) != 3 || got[len(got)-1].Name != "cu118" {
+		t.Errorf("Known() = %v", got)
+	}
+}
@@ -0,0 +1,59 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"fmt"
+	"log"
+	"os"
+)
+
+// 诊断包中的一个文件
+type Entry struct {
+	Name string // 在压缩包中的文件名
+	Path string // 磁盘上的文件，为空时使用 Data
+	Data string
+}
+
+// 把文件打包为 zip，每个文件的内容先经过 scrubber 处理（为 nil 时不处理）。
+// 不存在的文件会被跳过并在包内的 missing.txt 中列出
+func WriteBundle(dest string, entries []Entry, scrubber *Scrubber) error {
+	f, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("无法创建诊断包: %v", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	var missing []string
+	for _, e := range entries {
+		data := e.Data
+		if e.Path != "" {
+			raw, err := os.ReadFile(e.Path)
+			if err != nil {
+				log.Printf("诊断包跳过 %s: %v", e.Path, err)
+				missing = append(missing, fmt.Sprintf("%s: %v", e.Name, err))
+				continue
+			}
+			data = string(raw)
+		}
+		w, err := zw.Create(e.Name)
+		if err != nil {
+			return fmt.Errorf("写入 %s 失败: %v", e.Name, err)
+		}
+		if _, err := w.Write([]byte(scrubber.Scrub(data))); err != nil {
+			return fmt.Errorf("写入 %s 失败: %v", e.Name, err)
+		}
+	}
+	if len(missing) > 0 {
+		w, err := zw.Create("missing.txt")
+		if err == nil {
+			for _, m := range missing {
+				fmt.Fprintln(w, scrubber.Scrub(m))
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("写入诊断包失败: %v", err)
+	}
+	return nil
+}
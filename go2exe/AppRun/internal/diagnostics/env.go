@@ -0,0 +1,59 @@
+package diagnostics
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 值可能是密码或令牌的环境变量名
+var secretName = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|PWD$|CREDENTIAL|API_?KEY|_KEY$|^KEY$|AUTH)`)
+
+// 按名称排序的环境变量，每行一个。可能是密码或令牌的值替换为 <hidden>，
+// 代理地址等 URL 中的用户名和密码替换为 <hidden>
+func Environment(environ []string) string {
+	vars := append([]string(nil), environ...)
+	sort.Slice(vars, func(i, j int) bool { return strings.ToUpper(vars[i]) < strings.ToUpper(vars[j]) })
+	var lines []string
+	for _, kv := range vars {
+		name, value, ok := strings.Cut(kv, "=")
+		// Windows 中以 = 开头的是各驱动器的当前目录（=C:=C:\...）
+		if !ok || name == "" {
+			continue
+		}
+		lines = append(lines, name+"="+maskValue(name, value))
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+func maskValue(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if secretName.MatchString(name) {
+		return "<hidden>"
+	}
+	if strings.Contains(value, "://") {
+		return RedactURL(value)
+	}
+	return value
+}
+
+// 把 URL 中的用户名和密码替换为 <hidden>，用于在日志中记录代理地址等。
+// 没有 scheme 的 user:pass@host:port 同样处理，不是 URL 时原样返回
+func RedactURL(value string) string {
+	if !strings.Contains(value, "@") {
+		return value
+	}
+	raw, prefix := value, ""
+	if !strings.Contains(value, "://") {
+		raw, prefix = "http://"+value, "http://"
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return value
+	}
+	u.User = url.User("<hidden>")
+	return strings.TrimPrefix(strings.Replace(u.String(), "%3Chidden%3E", "<hidden>", 1), prefix)
+}
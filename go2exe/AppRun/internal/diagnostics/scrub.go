@@ -0,0 +1,88 @@
+// Package diagnostics 收集日志等诊断文件并打包，打包前可以去除其中的个人信息
+package diagnostics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 去除哪些个人信息
+type ScrubOptions struct {
+	Users    []string // 用户名，通常为 USERNAME
+	Machines []string // 计算机名、域名，通常为 COMPUTERNAME、USERDOMAIN
+	Books    []string // 已知的书籍完整路径（最近打开的书等），会被替换成稳定的编号
+	AllBooks bool     // 是否同时替换日志中出现的其他书籍路径和文件名
+}
+
+// 电子书和文本文件的扩展名
+const bookExts = `epub|mobi|azw3|pdf|txt|docx`
+
+var (
+	// 以盘符、UNC 或 / 开头，以书籍扩展名结尾的路径
+	bookPathPattern = regexp.MustCompile(`(?i)(?:[a-z]:|\\\\|/)[^"'\r\n<>|*?]*?\.(?:` + bookExts + `)\b`)
+	// 单独出现的书籍文件名
+	bookNamePattern = regexp.MustCompile(`(?i)[^\s"'\\/:*?<>|]+\.(?:` + bookExts + `)\b`)
+)
+
+// 对文本进行替换的规则集合
+type Scrubber struct {
+	replacer *strings.Replacer
+	allBooks bool
+}
+
+// 根据选项创建 Scrubber。同一本书在所有文件中被替换为相同的编号，便于对照
+func NewScrubber(o ScrubOptions) *Scrubber {
+	var pairs [][2]string
+	for i, book := range o.Books {
+		token := fmt.Sprintf("<book-%d>", i+1)
+		pairs = append(pairs, [2]string{book, token})
+		// 日志中的路径可能来自其他系统或使用另一种分隔符，不用 filepath.Base
+		pairs = append(pairs, [2]string{book[strings.LastIndexAny(book, `\/`)+1:], token})
+	}
+	for _, u := range o.Users {
+		pairs = append(pairs, [2]string{u, "<user>"})
+	}
+	for _, m := range o.Machines {
+		pairs = append(pairs, [2]string{m, "<machine>"})
+	}
+	// 先替换较长的字符串，避免书名中的用户名被提前替换而无法整体匹配
+	sort.SliceStable(pairs, func(i, j int) bool { return len(pairs[i][0]) > len(pairs[j][0]) })
+
+	var args []string
+	for _, p := range pairs {
+		if p[0] == "" {
+			continue
+		}
+		// 路径和用户名在 Windows 下不区分大小写，常见的几种写法都替换
+		for _, v := range uniqueCases(p[0]) {
+			args = append(args, v, p[1])
+		}
+	}
+	return &Scrubber{replacer: strings.NewReplacer(args...), allBooks: o.AllBooks}
+}
+
+// 原样、全小写、全大写三种写法，去重
+func uniqueCases(s string) []string {
+	out := []string{s}
+	for _, v := range []string{strings.ToLower(s), strings.ToUpper(s)} {
+		if v != s && v != out[len(out)-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// 去除文本中的个人信息；s 为 nil 时原样返回
+func (s *Scrubber) Scrub(text string) string {
+	if s == nil {
+		return text
+	}
+	text = s.replacer.Replace(text)
+	if s.allBooks {
+		text = bookPathPattern.ReplaceAllString(text, "<book-path>")
+		text = bookNamePattern.ReplaceAllString(text, "<book>")
+	}
+	return text
+}
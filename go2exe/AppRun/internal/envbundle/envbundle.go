@@ -0,0 +1,453 @@
+// Package envbundle 把可以运行的环境（uv 管理的解释器、虚拟环境和应用文件）和用户数据打包成 zip，
+// 在另一台离线的电脑上校验、解压并修正虚拟环境中的绝对路径
+package envbundle
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 包格式的版本，不兼容的修改时增加
+const Format = 2
+
+// 包中各部分所在的目录
+const (
+	manifestName = "manifest.json"
+	pythonPrefix = "python/"
+	venvPrefix   = "venv/"
+	appPrefix    = "app/"
+	dataPrefix   = "data/"
+)
+
+// 包的说明，记录导出时的路径，导入时据此修正虚拟环境
+type Manifest struct {
+	Format        int       `json:"format"`
+	PythonKey     string    `json:"python_key"`     // uv 的解释器名称，例如 cpython-3.11.9-windows-x86_64-none
+	PythonVersion string    `json:"python_version"` // 例如 3.11.9
+	PythonHome    string    `json:"python_home"`    // 导出时解释器所在的目录
+	Venv          string    `json:"venv"`           // 导出时虚拟环境的目录
+	AppDir        string    `json:"app_dir"`        // 导出时应用（python 目录）的位置
+	CreatedAt     time.Time `json:"created_at"`
+	// 包中每个文件的 SHA-256，导入前逐一校验，防止传输中损坏或被改动
+	Files map[string]string `json:"files"`
+}
+
+// 导出的内容
+type Source struct {
+	PythonKey     string
+	PythonVersion string
+	PythonHome    string   // 解释器目录（uv python list 中 python.exe 所在的目录）
+	Venv          string   // 虚拟环境目录
+	AppDir        string   // 应用目录
+	Exclude       []string // 应用目录中不导出的子目录（相对路径，例如 .venv 和 Python 安装包）
+	// 随环境一起迁移的用户文件，键为包中的名称，值为本机上的路径；不存在的文件跳过
+	Data map[string]string
+}
+
+// 导入的位置
+type Target struct {
+	PythonRoot string // uv 存放解释器的目录，解释器解压到其中以 PythonKey 命名的子目录
+	Venv       string // 虚拟环境目录，已存在时先删除
+	AppDir     string // 应用目录，同名文件被覆盖
+	// 用户文件解压到的位置，键为包中的名称；包中有而这里没有的文件不解压
+	Data map[string]string
+}
+
+// 把 src 打包到 zipPath
+func Export(zipPath string, src Source) (err error) {
+	f, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(zipPath)
+		}
+	}()
+	zw := zip.NewWriter(f)
+
+	m := Manifest{
+		Format:        Format,
+		PythonKey:     src.PythonKey,
+		PythonVersion: src.PythonVersion,
+		PythonHome:    src.PythonHome,
+		Venv:          src.Venv,
+		AppDir:        src.AppDir,
+		CreatedAt:     time.Now(),
+		Files:         map[string]string{},
+	}
+	if err := addDir(zw, m.Files, src.PythonHome, pythonPrefix, nil); err != nil {
+		return fmt.Errorf("打包解释器失败: %v", err)
+	}
+	if err := addDir(zw, m.Files, src.Venv, venvPrefix, nil); err != nil {
+		return fmt.Errorf("打包虚拟环境失败: %v", err)
+	}
+	if err := addDir(zw, m.Files, src.AppDir, appPrefix, src.Exclude); err != nil {
+		return fmt.Errorf("打包应用失败: %v", err)
+	}
+	for name, p := range src.Data {
+		info, err := os.Stat(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("打包 %s 失败: %v", name, err)
+		}
+		if err := addFile(zw, m.Files, p, dataPrefix+name, info); err != nil {
+			return fmt.Errorf("打包 %s 失败: %v", name, err)
+		}
+	}
+
+	// 说明最后写入，其中包含前面所有文件的校验值
+	w, err := zw.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(m, "", "  ")
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// 把 dir 中的文件加入 zip 的 prefix 目录，跳过 exclude 中的子目录，校验值记入 sums
+func addDir(zw *zip.Writer, sums map[string]string, dir, prefix string, exclude []string) error {
+	skip := map[string]bool{}
+	for _, e := range exclude {
+		skip[filepath.ToSlash(filepath.Clean(e))] = true
+	}
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if skip[rel] {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return addFile(zw, sums, p, prefix+rel, info)
+	})
+}
+
+// 把文件 p 以 name 加入 zip，并记下它的 SHA-256
+func addFile(zw *zip.Writer, sums map[string]string, p, name string, info fs.FileInfo) error {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	hdr.Method = zip.Deflate
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), f); err != nil {
+		return err
+	}
+	sums[name] = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// 读取 zipPath 中的说明，不解压
+func ReadManifest(zipPath string) (Manifest, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer zr.Close()
+	return readManifest(&zr.Reader)
+}
+
+func readManifest(zr *zip.Reader) (Manifest, error) {
+	var m Manifest
+	f, err := zr.Open(manifestName)
+	if err != nil {
+		return m, fmt.Errorf("不是环境包：缺少 %s", manifestName)
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return m, fmt.Errorf("读取 %s 失败: %v", manifestName, err)
+	}
+	if m.Format != Format {
+		return m, fmt.Errorf("不支持的环境包格式 %d", m.Format)
+	}
+	if m.PythonKey == "" || strings.ContainsAny(m.PythonKey, `/\`) || m.PythonKey == ".." {
+		return m, fmt.Errorf("环境包中的解释器名称 %q 无效", m.PythonKey)
+	}
+	return m, nil
+}
+
+// 包中用户文件的名称
+func (m Manifest) DataNames() []string {
+	var names []string
+	for name := range m.Files {
+		if rel, ok := strings.CutPrefix(name, dataPrefix); ok {
+			names = append(names, rel)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 按说明中的校验值检查 zipPath 中的每个文件，不解压
+func Verify(zipPath string) (Manifest, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer zr.Close()
+	m, err := readManifest(&zr.Reader)
+	if err != nil {
+		return m, err
+	}
+	return m, verify(&zr.Reader, m)
+}
+
+// 包中的文件必须与说明中的列表完全一致，内容的 SHA-256 也必须相同
+func verify(zr *zip.Reader, m Manifest) error {
+	seen := map[string]bool{}
+	for _, f := range zr.File {
+		if f.Name == manifestName || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		want, ok := m.Files[f.Name]
+		if !ok {
+			return fmt.Errorf("环境包中的 %s 不在文件列表中", f.Name)
+		}
+		got, err := fileSum(f)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", f.Name, err)
+		}
+		if got != want {
+			return fmt.Errorf("%s 的校验值不一致，环境包可能已损坏", f.Name)
+		}
+		seen[f.Name] = true
+	}
+	for name := range m.Files {
+		if !seen[name] {
+			return fmt.Errorf("环境包缺少 %s，可能没有复制完整", name)
+		}
+	}
+	return nil
+}
+
+func fileSum(f *zip.File) (string, error) {
+	r, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// 校验 zipPath 后解压到 t，并把虚拟环境中导出时的路径改为新的位置。
+// PythonRoot 中已有同名解释器时沿用已有的
+func Import(zipPath string, t Target) (Manifest, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer zr.Close()
+	m, err := readManifest(&zr.Reader)
+	if err != nil {
+		return m, err
+	}
+	if err := verify(&zr.Reader, m); err != nil {
+		return m, err
+	}
+
+	home := filepath.Join(t.PythonRoot, m.PythonKey)
+	if _, err := os.Stat(home); err == nil {
+		log.Printf("解释器 %s 已存在，不再解压", home)
+	} else {
+		// 先解压到临时目录，避免中断后留下不完整的解释器被 uv 当作已安装
+		tmp := home + ".importing"
+		os.RemoveAll(tmp)
+		if err := extract(&zr.Reader, pythonPrefix, tmp); err != nil {
+			os.RemoveAll(tmp)
+			return m, fmt.Errorf("解压解释器失败: %v", err)
+		}
+		if err := os.Rename(tmp, home); err != nil {
+			os.RemoveAll(tmp)
+			return m, fmt.Errorf("解压解释器失败: %v", err)
+		}
+	}
+
+	// 虚拟环境同样先解压到临时目录并修正路径，全部成功后才替换原有的，失败时原有的虚拟环境仍然可用
+	tmp := t.Venv + ".importing"
+	os.RemoveAll(tmp)
+	if err := extract(&zr.Reader, venvPrefix, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return m, fmt.Errorf("解压虚拟环境失败: %v", err)
+	}
+	if err := relocate(tmp, []string{m.Venv, m.PythonHome, m.AppDir}, []string{t.Venv, home, t.AppDir}); err != nil {
+		os.RemoveAll(tmp)
+		return m, fmt.Errorf("修正虚拟环境中的路径失败: %v", err)
+	}
+	if err := extract(&zr.Reader, appPrefix, t.AppDir); err != nil {
+		os.RemoveAll(tmp)
+		return m, fmt.Errorf("解压应用失败: %v", err)
+	}
+	if err := replaceDir(tmp, t.Venv); err != nil {
+		os.RemoveAll(tmp)
+		return m, fmt.Errorf("替换原有的虚拟环境失败: %v", err)
+	}
+	for _, f := range zr.File {
+		name, ok := strings.CutPrefix(f.Name, dataPrefix)
+		dest := t.Data[name]
+		if !ok || dest == "" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return m, fmt.Errorf("解压 %s 失败: %v", name, err)
+		}
+		if err := extractFile(f, dest); err != nil {
+			return m, fmt.Errorf("解压 %s 失败: %v", name, err)
+		}
+	}
+	return m, nil
+}
+
+// 用 src 目录替换 dest：原有的先改名为 dest.old，src 改名失败时改回去，成功后再删除
+func replaceDir(src, dest string) error {
+	old := dest + ".old"
+	os.RemoveAll(old)
+	if err := os.Rename(dest, old); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(src, dest); err != nil {
+		os.Rename(old, dest)
+		return err
+	}
+	if err := os.RemoveAll(old); err != nil {
+		log.Printf("删除原有的虚拟环境 %s 失败: %v", old, err)
+	}
+	return nil
+}
+
+// 把 zip 中 prefix 目录下的文件解压到 dir，拒绝指向 dir 之外的路径
+func extract(zr *zip.Reader, prefix, dir string) error {
+	for _, f := range zr.File {
+		rel, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok || rel == "" || strings.HasSuffix(rel, "/") {
+			continue
+		}
+		clean := path.Clean(rel)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, ":") {
+			return fmt.Errorf("环境包中的路径 %q 无效", f.Name)
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(clean))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := extractFile(f, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(f *zip.File, dest string) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dest, f.Modified, f.Modified)
+}
+
+// 虚拟环境中记录了绝对路径的文本文件：pyvenv.cfg、Scripts 中的激活脚本和 site-packages 中的 .pth
+func relocatable(venv, p string) bool {
+	rel, err := filepath.Rel(venv, p)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	switch {
+	case rel == "pyvenv.cfg":
+		return true
+	case strings.HasPrefix(rel, "Scripts/"):
+		ext := strings.ToLower(path.Ext(rel))
+		return ext == "" || ext == ".bat" || ext == ".ps1" || ext == ".fish" || ext == ".csh" || ext == ".nu"
+	case strings.HasPrefix(strings.ToLower(rel), "lib/site-packages/") && strings.ToLower(path.Ext(rel)) == ".pth":
+		return true
+	}
+	return false
+}
+
+// 把虚拟环境中记录的旧路径替换为新路径，olds 和 news 一一对应
+func relocate(venv string, olds, news []string) error {
+	var pairs []string
+	for i, old := range olds {
+		if old != "" && old != news[i] {
+			pairs = append(pairs, old, news[i])
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	replacer := strings.NewReplacer(pairs...)
+	return filepath.WalkDir(venv, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !relocatable(venv, p) {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		fixed := replacer.Replace(string(data))
+		if fixed == string(data) {
+			return nil
+		}
+		return os.WriteFile(p, []byte(fixed), 0644)
+	})
+}
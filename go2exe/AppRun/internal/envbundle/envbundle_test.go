@@ -0,0 +1,234 @@
+package envbundle
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 在 dir 下按相对路径创建文件
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// 按说明和文件内容手工生成环境包，files 为空时使用文件内容的实际校验值
+func writeBundle(t *testing.T, bundle string, m Manifest, files map[string]string) {
+	t.Helper()
+	if m.Files == nil {
+		m.Files = map[string]string{}
+		for name, content := range files {
+			sum := sha256.Sum256([]byte(content))
+			m.Files[name] = hex.EncodeToString(sum[:])
+		}
+	}
+	f, err := os.Create(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create(manifestName)
+	json.NewEncoder(w).Encode(m)
+	for name, content := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	from := t.TempDir()
+	home := filepath.Join(from, "uv", "cpython-3.11.9-windows-x86_64-none")
+	app := filepath.Join(from, "app", "python")
+	venv := filepath.Join(app, ".venv")
+	writeFiles(t, home, map[string]string{"python.exe": "interpreter", "Lib/os.py": "# os"})
+	writeFiles(t, app, map[string]string{
+		"app.pyw":                             "print('hi')",
+		"uv.lock":                             "version = 1",
+		"20240814/cpython.tar.gz":             "archive",
+		".venv/pyvenv.cfg":                    "home = " + home + "\nversion_info = 3.11.9\n",
+		".venv/Scripts/activate.bat":          "set VIRTUAL_ENV=" + venv + "\n",
+		".venv/Scripts/pythonw.exe":           "launcher " + venv,
+		".venv/Lib/site-packages/app.pth":     app + "\n",
+		".venv/Lib/site-packages/edge_tts.py": "# edge_tts",
+	})
+
+	writeFiles(t, from, map[string]string{"data/recent.json": `["a.epub"]`})
+
+	bundle := filepath.Join(from, "env.zip")
+	err := Export(bundle, Source{
+		PythonKey:     "cpython-3.11.9-windows-x86_64-none",
+		PythonVersion: "3.11.9",
+		PythonHome:    home,
+		Venv:          venv,
+		AppDir:        app,
+		Exclude:       []string{".venv", "20240814"},
+		Data: map[string]string{
+			"recent.json": filepath.Join(from, "data", "recent.json"),
+			"apprun.toml": filepath.Join(from, "missing.toml"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Export() = %v", err)
+	}
+
+	to := t.TempDir()
+	target := Target{
+		PythonRoot: filepath.Join(to, "uv"),
+		Venv:       filepath.Join(to, "SpeakMyBook", "python", ".venv"),
+		AppDir:     filepath.Join(to, "SpeakMyBook", "python"),
+		Data:       map[string]string{"recent.json": filepath.Join(to, "data", "recent.json")},
+	}
+	m, err := Import(bundle, target)
+	if err != nil {
+		t.Fatalf("Import() = %v", err)
+	}
+	if m.PythonVersion != "3.11.9" || m.Venv != venv {
+		t.Errorf("Manifest = %+v", m)
+	}
+	newHome := filepath.Join(target.PythonRoot, m.PythonKey)
+	read := func(p string) string {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Errorf("读取 %s 失败: %v", p, err)
+		}
+		return string(data)
+	}
+	if got := read(filepath.Join(newHome, "python.exe")); got != "interpreter" {
+		t.Errorf("解释器内容 = %q", got)
+	}
+	if got := read(filepath.Join(target.Venv, "pyvenv.cfg")); !strings.Contains(got, "home = "+newHome+"\n") {
+		t.Errorf("pyvenv.cfg 未修正: %q", got)
+	}
+	if got := read(filepath.Join(target.Venv, "Scripts", "activate.bat")); got != "set VIRTUAL_ENV="+target.Venv+"\n" {
+		t.Errorf("activate.bat 未修正: %q", got)
+	}
+	if got := read(filepath.Join(target.Venv, "Lib", "site-packages", "app.pth")); got != target.AppDir+"\n" {
+		t.Errorf("app.pth 未修正: %q", got)
+	}
+	// 二进制文件不修改
+	if got := read(filepath.Join(target.Venv, "Scripts", "pythonw.exe")); got != "launcher "+venv {
+		t.Errorf("pythonw.exe 被修改: %q", got)
+	}
+	if read(filepath.Join(target.AppDir, "app.pyw")) != "print('hi')" {
+		t.Errorf("应用文件未解压")
+	}
+	if read(target.Data["recent.json"]) != `["a.epub"]` {
+		t.Errorf("用户数据未解压")
+	}
+	if got := m.DataNames(); len(got) != 1 || got[0] != "recent.json" {
+		t.Errorf("DataNames() = %v，不存在的用户文件不应导出", got)
+	}
+	for _, excluded := range []string{"20240814", filepath.Join(".venv", ".venv")} {
+		if _, err := os.Stat(filepath.Join(target.AppDir, excluded)); err == nil {
+			t.Errorf("%s 不应导出", excluded)
+		}
+	}
+}
+
+func TestImportRejectsUnsafePaths(t *testing.T) {
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "bad.zip")
+	writeBundle(t, bundle, Manifest{Format: Format, PythonKey: "cpython-3.11.9-windows-x86_64-none"},
+		map[string]string{"app/../../evil.txt": "evil"})
+
+	_, err := Import(bundle, Target{
+		PythonRoot: filepath.Join(dir, "uv"),
+		Venv:       filepath.Join(dir, "out", ".venv"),
+		AppDir:     filepath.Join(dir, "out"),
+	})
+	if err == nil {
+		t.Errorf("包含 .. 的路径应被拒绝")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.txt")); err == nil {
+		t.Errorf("文件被解压到了目标目录之外")
+	}
+}
+
+func TestReadManifestFormat(t *testing.T) {
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "new.zip")
+	f, _ := os.Create(bundle)
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create(manifestName)
+	w.Write([]byte(`{"format": 99, "python_key": "cpython-3.13.0-windows-x86_64-none"}`))
+	zw.Close()
+	f.Close()
+	if _, err := ReadManifest(bundle); err == nil {
+		t.Errorf("不支持的格式应返回错误")
+	}
+}
+
+func TestImportVerifiesChecksums(t *testing.T) {
+	key := "cpython-3.11.9-windows-x86_64-none"
+	files := map[string]string{"app/app.pyw": "print('hi')"}
+	sum := sha256.Sum256([]byte("print('hello')"))
+	good := sha256.Sum256([]byte("print('hi')"))
+	cases := map[string]Manifest{
+		"内容被改动": {Files: map[string]string{"app/app.pyw": hex.EncodeToString(sum[:])}},
+		"多出文件":  {Files: map[string]string{}},
+		"缺少文件":  {Files: map[string]string{"app/app.pyw": hex.EncodeToString(good[:]), "app/uv.lock": "00"}},
+	}
+	for name, m := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			bundle := filepath.Join(dir, "bundle.zip")
+			m.Format, m.PythonKey = Format, key
+			writeBundle(t, bundle, m, files)
+			if _, err := Verify(bundle); err == nil {
+				t.Errorf("Verify() 应返回错误")
+			}
+			out := filepath.Join(dir, "out")
+			if _, err := Import(bundle, Target{PythonRoot: filepath.Join(dir, "uv"), Venv: filepath.Join(out, ".venv"), AppDir: out}); err == nil {
+				t.Errorf("Import() 应返回错误")
+			}
+			if _, err := os.Stat(filepath.Join(out, "app.pyw")); err == nil {
+				t.Errorf("校验失败时不应解压任何文件")
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "good.zip")
+	writeBundle(t, bundle, Manifest{Format: Format, PythonKey: key}, files)
+	if _, err := Verify(bundle); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+}
+
+func TestImportKeepsVenvOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	venv := filepath.Join(out, ".venv")
+	writeFiles(t, venv, map[string]string{"pyvenv.cfg": "home = old"})
+	bundle := filepath.Join(dir, "bad.zip")
+	writeBundle(t, bundle, Manifest{Format: Format, PythonKey: "cpython-3.11.9-windows-x86_64-none"},
+		map[string]string{"venv/pyvenv.cfg": "home = new", "venv/../../evil.txt": "evil"})
+
+	if _, err := Import(bundle, Target{PythonRoot: filepath.Join(dir, "uv"), Venv: venv, AppDir: out}); err == nil {
+		t.Fatal("Import() 应返回错误")
+	}
+	// 解压失败时原有的虚拟环境不变，也不留下临时目录
+	if data, err := os.ReadFile(filepath.Join(venv, "pyvenv.cfg")); err != nil || string(data) != "home = old" {
+		t.Errorf("原有的虚拟环境被修改: %q, %v", data, err)
+	}
+	if _, err := os.Stat(venv + ".importing"); !os.IsNotExist(err) {
+		t.Errorf("临时目录没有删除")
+	}
+}
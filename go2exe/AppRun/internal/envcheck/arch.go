@@ -0,0 +1,28 @@
+package envcheck
+
+import (
+	"log"
+	"sync"
+)
+
+// 按本机架构选择 Python 的配置值
+const ArchAuto = "auto"
+
+// 本机的处理器架构，使用 uv 的名称（x86_64、x86 或 aarch64）。
+// Windows on ARM 上以 x64 仿真运行的启动器同样返回 aarch64
+var MachineArch = sync.OnceValue(func() string {
+	arch := machineArch()
+	log.Printf("本机架构: %s", arch)
+	return arch
+})
+
+// 配置的架构对应的 uv 架构名称：auto 为本机架构，为空时为 DefaultArch
+func ResolveArch(arch string) string {
+	switch arch {
+	case "":
+		return DefaultArch
+	case ArchAuto:
+		return MachineArch()
+	}
+	return arch
+}
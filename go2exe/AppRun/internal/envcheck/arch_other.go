@@ -0,0 +1,16 @@
+//go:build !windows
+
+package envcheck
+
+import "runtime"
+
+// 按启动器编译目标的架构选择 Python 安装包，在 Rosetta 下运行的 x86_64 启动器也安装 x86_64 的 Python
+func machineArch() string {
+	switch runtime.GOARCH {
+	case "arm64":
+		return "aarch64"
+	case "386":
+		return "x86"
+	}
+	return DefaultArch
+}
@@ -0,0 +1,53 @@
+package envcheck
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	isWow64Process2     = kernel32.NewProc("IsWow64Process2")
+	getNativeSystemInfo = kernel32.NewProc("GetNativeSystemInfo")
+)
+
+// IMAGE_FILE_MACHINE_* 和 PROCESSOR_ARCHITECTURE_* 的取值
+const (
+	imageFileMachineI386  = 0x014c
+	imageFileMachineAMD64 = 0x8664
+	imageFileMachineARM64 = 0xaa64
+
+	processorArchitectureIntel = 0
+	processorArchitectureAMD64 = 9
+	processorArchitectureARM64 = 12
+)
+
+// 本机架构：IsWow64Process2 报告系统的原生架构，不受 x64 仿真和 WOW64 影响；
+// 没有该函数的旧版 Windows（早于 Windows 10 1709）不支持 ARM64 上的 x64 仿真，使用 GetNativeSystemInfo
+func machineArch() string {
+	if isWow64Process2.Find() == nil {
+		var process, native uint16
+		current, _ := syscall.GetCurrentProcess()
+		if r, _, _ := isWow64Process2.Call(uintptr(current), uintptr(unsafe.Pointer(&process)), uintptr(unsafe.Pointer(&native))); r != 0 {
+			switch native {
+			case imageFileMachineARM64:
+				return "aarch64"
+			case imageFileMachineAMD64:
+				return "x86_64"
+			case imageFileMachineI386:
+				return "x86"
+			}
+		}
+	}
+	// SYSTEM_INFO 的前两个字节为 wProcessorArchitecture
+	var info [48]byte
+	getNativeSystemInfo.Call(uintptr(unsafe.Pointer(&info[0])))
+	switch *(*uint16)(unsafe.Pointer(&info[0])) {
+	case processorArchitectureARM64:
+		return "aarch64"
+	case processorArchitectureAMD64:
+		return "x86_64"
+	case processorArchitectureIntel:
+		return "x86"
+	}
+	return DefaultArch
+}
@@ -0,0 +1,112 @@
+// Package envcheck 检查运行应用所需的 uv 和 Python 是否已经安装
+package envcheck
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go2exe/internal/runner"
+)
+
+// 环境检查器
+type Checker struct {
+	Runner  runner.CommandRunner
+	Timeout time.Duration // 每条检查命令的最长执行时间，0 表示不限制
+	Python  string        // 需要的 Python 版本约束（见 ParseConstraint），为空时为 DefaultPython
+	Arch    string        // 需要的 Python 架构，为空时为 DefaultArch，ArchAuto 为本机架构
+}
+
+// 检查是否安装了uv
+func (c Checker) UVInstalled() (bool, string) {
+	// 执行 uv -V 命令
+	output, err := c.Runner.Output(runner.Command{Name: "uv", Args: []string{"-V"}, HideWindow: true, Timeout: c.Timeout})
+
+	// 将输出转换为字符串
+	outputStr := strings.TrimSpace(output)
+
+	// 记录日志
+	log.Printf("UV 检查结果: %v, 输出: %s", err == nil, outputStr)
+
+	// 如果命令执行出错，或输出包含错误信息，说明未安装
+	if err != nil || strings.Contains(outputStr, "不是内部或外部命令") {
+		return false, outputStr
+	}
+
+	// 如果输出包含版本号，说明已安装
+	if strings.Contains(outputStr, "uv") {
+		if v, ok := ParseUVVersion(outputStr); ok {
+			setPythonListJSON(v.Compare(pythonListJSONSince) >= 0)
+		}
+		return true, outputStr
+	}
+
+	return false, outputStr
+}
+
+// 查找已安装且满足版本约束的 CPython，有多个时返回版本最高的，没有时返回 nil
+func (c Checker) FindPython() (*Installation, error) {
+	constraint := c.Python
+	if constraint == "" {
+		constraint = DefaultPython
+	}
+	arch := ResolveArch(c.Arch)
+	want, err := ParseConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	// 直接执行 uv python list，不经过 PowerShell（执行策略或配置文件被锁定时 PowerShell 可能无法使用）。
+	// uv 的版本支持时使用 JSON 格式的输出，不必依赖文本格式的排版；版本未知时先尝试 JSON，不支持时改用文本格式并记住
+	list := func(args ...string) (string, error) {
+		return c.Runner.Output(runner.Command{
+			Name:       "uv",
+			Args:       append([]string{"python", "list"}, args...),
+			HideWindow: true,
+			Timeout:    c.Timeout,
+		})
+	}
+	var outputStr string
+	if preferPythonListJSON() {
+		outputStr, err = list("--output-format", "json")
+		switch {
+		case err != nil && strings.Contains(outputStr, "unexpected argument '--output-format'"):
+			log.Printf("uv 不支持 --output-format，改用文本格式")
+			setPythonListJSON(false)
+			outputStr, err = list()
+		case err == nil:
+			setPythonListJSON(true)
+		}
+	} else {
+		outputStr, err = list()
+	}
+	if err != nil {
+		log.Printf("Python 检查命令失败: %v", err)
+		return nil, fmt.Errorf("执行命令失败: %v", err)
+	}
+
+	var found *Installation
+	for _, inst := range ParsePythonList(outputStr) {
+		// 只接受已安装的普通 CPython，不使用预发布版本和 freethreaded 等变体
+		if !inst.Installed() || inst.Implementation != "cpython" || inst.Variant != "" || inst.Arch != arch {
+			continue
+		}
+		if want.Match(inst.Version) && (found == nil || inst.Version.Compare(found.Version) > 0) {
+			inst := inst
+			found = &inst
+		}
+	}
+	if found == nil {
+		log.Printf("未找到已安装的 Python %s (%s)", constraint, arch)
+		return nil, nil
+	}
+	log.Printf("找到已安装的 Python %s: %s", constraint, found.Key)
+	return found, nil
+}
+
+// 检查是否安装了满足版本约束的 Python
+func (c Checker) PythonInstalled() (bool, error) {
+	inst, err := c.FindPython()
+	return inst != nil, err
+}
@@ -0,0 +1,338 @@
+package envcheck
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go2exe/internal/runner"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func TestUVInstalled(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		want   bool
+	}{
+		{"已安装", "uv 0.6.14 (a4cec56dc 2025-04-09)\n", nil, true},
+		{"命令不存在", "", errors.New(`exec: "uv": executable file not found in %PATH%`), false},
+		{"cmd 报错", "'uv' 不是内部或外部命令，也不是可运行的程序\n", nil, false},
+		{"输出无版本号", "something else\n", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &runner.Mock{Handler: func(c runner.Command) (string, error) {
+				return tt.output, tt.err
+			}}
+
+			got, _ := Checker{Runner: m}.UVInstalled()
+			if got != tt.want {
+				t.Errorf("UVInstalled() = %v, want %v", got, tt.want)
+			}
+			if lines := m.CommandLines(); len(lines) != 1 || lines[0] != "uv -V" {
+				t.Errorf("执行的命令 = %v", lines)
+			}
+		})
+	}
+}
+
+func TestPythonInstalled(t *testing.T) {
+	installed := "cpython-3.11.9-windows-x86_64-none    C:\\Users\\me\\AppData\\Roaming\\uv\\python\\cpython-3.11.9-windows-x86_64-none\\python.exe\n"
+	downloadable := "cpython-3.11.9-windows-x86_64-none    <download available>\n"
+
+	check := func(output string, err error) Checker {
+		return Checker{Runner: &runner.Mock{Handler: func(c runner.Command) (string, error) { return output, err }}}
+	}
+	if ok, err := check(installed, nil).PythonInstalled(); !ok || err != nil {
+		t.Errorf("已安装时返回 %v, %v", ok, err)
+	}
+	if ok, err := check(downloadable, nil).PythonInstalled(); ok || err != nil {
+		t.Errorf("仅可下载时返回 %v, %v", ok, err)
+	}
+	if _, err := check("", errors.New("exit status 1")).PythonInstalled(); err == nil {
+		t.Errorf("命令失败时应返回错误")
+	}
+
+	// 按版本约束选择最高的已安装版本
+	list := installed + "cpython-3.11.4-windows-x86_64-none    C:\\Python311\\python.exe\n" +
+		"cpython-3.12.1-windows-x86_64-none    C:\\Python312\\python.exe\n"
+	c := check(list, nil)
+	c.Python = "3.11.*"
+	if inst, err := c.FindPython(); err != nil || inst == nil || inst.Key != "cpython-3.11.9-windows-x86_64-none" {
+		t.Errorf("FindPython(3.11.*) = %+v, %v", inst, err)
+	}
+	c.Arch = "x86"
+	if inst, _ := c.FindPython(); inst != nil {
+		t.Errorf("其他架构不应匹配: %+v", inst)
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	got := joinPath([]string{`C:\Windows`, "", `C:\Users\me\.local\bin`, `c:\windows\`, `C:\Users\me\.local\bin`})
+	if want := `C:\Windows` + string(os.PathListSeparator) + `C:\Users\me\.local\bin`; got != want {
+		t.Errorf("joinPath() = %q, want %q", got, want)
+	}
+}
+
+func TestParsePythonList(t *testing.T) {
+	output := "cpython-3.13.0+freethreaded-windows-x86_64-none    <download available>\n" +
+		"cpython-3.12.4-windows-x86_64-none    C:\\Python312\\python.exe -> C:\\Python312\\python3.12.exe\n" +
+		"pypy-3.10.14-windows-x86_64-none      <download available>\n" +
+		"warning: something\n"
+	list := ParsePythonList(output)
+	if len(list) != 3 {
+		t.Fatalf("解析出 %d 项: %+v", len(list), list)
+	}
+	if list[0].Variant != "+freethreaded" || list[0].Path != "" {
+		t.Errorf("第 1 项 = %+v", list[0])
+	}
+	if list[1].Version != (Version{3, 12, 4}) || list[1].Path != `C:\Python312\python.exe` || list[1].Arch != "x86_64" {
+		t.Errorf("第 2 项 = %+v", list[1])
+	}
+	if list[2].Implementation != "pypy" {
+		t.Errorf("第 3 项 = %+v", list[2])
+	}
+}
+
+// 各版本 uv 的 uv python list 输出（testdata/python-list）：已安装的项、可下载的项和变体都要正确识别
+func TestParsePythonListVersions(t *testing.T) {
+	tests := []struct {
+		file         string
+		installed    []string // 已安装的项，按输出顺序
+		downloadable int
+	}{
+		{"uv-0.2.37.txt", []string{"cpython-3.11.9-windows-x86_64-none", "cpython-3.11.4-windows-x86_64-none"}, 3},
+		{"uv-0.4.18.txt", []string{"cpython-3.11.9-windows-x86_64-none", "cpython-3.11.4-windows-x86-none", "cpython-3.10.11-windows-x86_64-none"}, 3},
+		{"uv-0.6.14.json", []string{"cpython-3.11.9-windows-x86_64-none", "cpython-3.11.4-windows-x86_64-none"}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "python-list", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var installed []string
+			downloadable := 0
+			for _, inst := range ParsePythonList(string(data)) {
+				if inst.Implementation == "" || inst.OS != "windows" || inst.Libc != "none" {
+					t.Errorf("解析结果不完整: %+v", inst)
+				}
+				if inst.Installed() {
+					installed = append(installed, inst.Key)
+					if !strings.HasSuffix(inst.Path, ".exe") {
+						t.Errorf("%s 的路径 = %q", inst.Key, inst.Path)
+					}
+				}
+				if inst.Downloadable {
+					downloadable++
+				}
+				if strings.Contains(inst.Key, "freethreaded") && inst.Variant == "" {
+					t.Errorf("%s 没有识别出变体", inst.Key)
+				}
+			}
+			if !slices.Equal(installed, tt.installed) || downloadable != tt.downloadable {
+				t.Errorf("已安装 %v，可下载 %d 项；want %v，%d 项", installed, downloadable, tt.installed, tt.downloadable)
+			}
+		})
+	}
+}
+
+// 清除记住的 uv python list 输出格式，每个测试从版本未知开始
+func resetPythonListFormat(t *testing.T) {
+	setPythonListJSON(true)
+	pythonListFormat.known = false
+	t.Cleanup(func() { pythonListFormat.known = false })
+}
+
+func TestParseUVVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   Version
+		ok     bool
+	}{
+		{"uv 0.6.14 (a4cec56dc 2025-04-09)\n", Version{0, 6, 14}, true},
+		{"uv 0.2.37\n", Version{0, 2, 37}, true},
+		{"uv 0.5.0-rc.1", Version{0, 5, 0}, true},
+		{"'uv' 不是内部或外部命令", Version{}, false},
+		{"", Version{}, false},
+	}
+	for _, tt := range tests {
+		if got, ok := ParseUVVersion(tt.output); got != tt.want || ok != tt.ok {
+			t.Errorf("ParseUVVersion(%q) = %v, %v, want %v, %v", tt.output, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFindPythonOutputFormat(t *testing.T) {
+	resetPythonListFormat(t)
+	data, _ := os.ReadFile(filepath.Join("testdata", "python-list", "uv-0.6.14.json"))
+	m := &runner.Mock{Handler: func(c runner.Command) (string, error) { return string(data), nil }}
+	if inst, err := (Checker{Runner: m, Python: "3.11.*"}).FindPython(); err != nil || inst == nil || inst.Version != (Version{3, 11, 9}) {
+		t.Errorf("FindPython() = %+v, %v", inst, err)
+	}
+	if lines := m.CommandLines(); len(lines) != 1 || lines[0] != "uv python list --output-format json" {
+		t.Errorf("执行的命令 = %v", lines)
+	}
+
+	// 旧版 uv 不支持 --output-format 时改用文本格式
+	text, _ := os.ReadFile(filepath.Join("testdata", "python-list", "uv-0.2.37.txt"))
+	m = &runner.Mock{Handler: func(c runner.Command) (string, error) {
+		if len(c.Args) > 2 {
+			return "error: unexpected argument '--output-format' found\n", errors.New("exit status 2")
+		}
+		return string(text), nil
+	}}
+	if inst, err := (Checker{Runner: m, Python: "3.11.*"}).FindPython(); err != nil || inst == nil || inst.Version != (Version{3, 11, 9}) {
+		t.Errorf("旧版 uv FindPython() = %+v, %v", inst, err)
+	}
+	if n := len(m.Calls); n != 2 {
+		t.Errorf("执行了 %d 条命令，want 2", n)
+	}
+	// 记住不支持 JSON，再次查找时直接使用文本格式
+	m.Calls = nil
+	if inst, err := (Checker{Runner: m, Python: "3.11.*"}).FindPython(); err != nil || inst == nil {
+		t.Errorf("再次 FindPython() = %+v, %v", inst, err)
+	}
+	if lines := m.CommandLines(); len(lines) != 1 || lines[0] != "uv python list" {
+		t.Errorf("再次查找时执行的命令 = %v", lines)
+	}
+}
+
+func TestFindPythonUVVersion(t *testing.T) {
+	tests := []struct {
+		version, want string
+	}{
+		{"uv 0.4.18 (7b55e9790 2024-10-01)", "uv python list"},
+		{"uv 0.6.14 (a4cec56dc 2025-04-09)", "uv python list --output-format json"},
+	}
+	for _, tt := range tests {
+		resetPythonListFormat(t)
+		m := &runner.Mock{Handler: func(c runner.Command) (string, error) {
+			if c.Args[0] == "-V" {
+				return tt.version + "\n", nil
+			}
+			return "cpython-3.11.9-windows-x86_64-none    C:\\Python311\\python.exe\n", nil
+		}}
+		c := Checker{Runner: m, Python: "3.11.*"}
+		if ok, _ := c.UVInstalled(); !ok {
+			t.Fatalf("%s: UVInstalled() = false", tt.version)
+		}
+		if inst, err := c.FindPython(); err != nil || inst == nil {
+			t.Errorf("%s: FindPython() = %+v, %v", tt.version, inst, err)
+		}
+		if lines := m.CommandLines(); len(lines) != 2 || lines[1] != tt.want {
+			t.Errorf("%s: 执行的命令 = %v, want %s", tt.version, lines, tt.want)
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    Version
+		want       bool
+	}{
+		{"3.11.9", Version{3, 11, 9}, true},
+		{"3.11.9", Version{3, 11, 10}, false},
+		{"3.11.*", Version{3, 11, 4}, true},
+		{"3.11", Version{3, 11, 4}, true},
+		{"3.11.*", Version{3, 12, 0}, false},
+		{">=3.10, <3.13", Version{3, 12, 7}, true},
+		{">=3.10,<3.13", Version{3, 13, 0}, false},
+		{"!=3.11.*", Version{3, 11, 2}, false},
+		{">3.11", Version{3, 11, 1}, true},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q) = %v", tt.constraint, err)
+		}
+		if got := c.Match(tt.version); got != tt.want {
+			t.Errorf("%q 匹配 %v = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "3.x", ">=3.11.*", "3.11.9.1"} {
+		if _, err := ParseConstraint(bad); err == nil {
+			t.Errorf("ParseConstraint(%q) 应返回错误", bad)
+		}
+	}
+}
+
+func TestInstallRequest(t *testing.T) {
+	tests := []struct{ constraint, arch, want string }{
+		{"", "", "3.11.9"},
+		{"3.11.*", "x86_64", "3.11"},
+		{"3.12.4", "x86", "cpython-3.12.4-windows-x86-none"},
+		{"3.11.*", "aarch64", "cpython-3.11-windows-aarch64-none"},
+		{">=3.10,<3.13", "", ">=3.10,<3.13"},
+	}
+	for _, tt := range tests {
+		if got, err := InstallRequest(tt.constraint, tt.arch); got != tt.want || err != nil {
+			t.Errorf("InstallRequest(%q, %q) = %q, %v, want %q", tt.constraint, tt.arch, got, err, tt.want)
+		}
+	}
+
+	Current = Linux
+	defer func() { Current = Windows }()
+	if got, err := InstallRequest("3.11.*", "aarch64"); got != "cpython-3.11-linux-aarch64-gnu" || err != nil {
+		t.Errorf("InstallRequest(Linux) = %q, %v", got, err)
+	}
+	Current = Darwin
+	if got, err := InstallRequest("3.11.*", "aarch64"); got != "cpython-3.11-macos-aarch64-none" || err != nil {
+		t.Errorf("InstallRequest(macOS) = %q, %v", got, err)
+	}
+}
+
+func TestResolveArch(t *testing.T) {
+	for arch, want := range map[string]string{"": DefaultArch, "x86": "x86", "aarch64": "aarch64", ArchAuto: MachineArch()} {
+		if got := ResolveArch(arch); got != want {
+			t.Errorf("ResolveArch(%q) = %q, want %q", arch, got, want)
+		}
+	}
+	// Windows on ARM 上只接受 aarch64 的解释器，不使用已安装的 x86_64 版本
+	output := "cpython-3.11.9-windows-x86_64-none    C:\\Python311\\python.exe\n" +
+		"cpython-3.11.9-windows-aarch64-none    C:\\Users\\me\\AppData\\Roaming\\uv\\python\\cpython-3.11.9-windows-aarch64-none\\python.exe\n"
+	m := &runner.Mock{Handler: func(c runner.Command) (string, error) { return output, nil }}
+	inst, err := Checker{Runner: m, Python: "3.11.*", Arch: "aarch64"}.FindPython()
+	if err != nil || inst == nil || inst.Key != "cpython-3.11.9-windows-aarch64-none" {
+		t.Errorf("FindPython(aarch64) = %+v, %v", inst, err)
+	}
+}
+
+// 用录下的真实 uv python list 输出回放
+func TestFindPythonReplay(t *testing.T) {
+	cassette, err := runner.LoadCassette(filepath.Join("testdata", "uv-python-list.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		python, arch, want string
+	}{
+		{"3.11.*", "", "cpython-3.11.9-windows-x86_64-none"},
+		{">=3.11", "", "cpython-3.12.7-windows-x86_64-none"},
+		{"3.11", "x86", "cpython-3.11.4-windows-x86-none"},
+		{"3.13", "", ""},
+	}
+	for _, tt := range tests {
+		c := Checker{Runner: runner.NewReplay(cassette), Python: tt.python, Arch: tt.arch}
+		inst, err := c.FindPython()
+		got := ""
+		if inst != nil {
+			got = inst.Key
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("FindPython(%s, %s) = %q, %v，want %q", tt.python, tt.arch, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package envcheck
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go2exe/internal/runner"
+)
+
+// 从注册表重新读取系统和用户的 PATH，合并到传给外部命令的 PATH（env）中。
+// 安装程序修改 PATH 后只对新登录的会话生效，刷新后启动器启动的子进程才能找到新安装的程序。
+// env 中已有的目录（包括启动器添加的）保留在后面。本进程的 PATH 不变
+func RefreshPath(env *runner.Environment) {
+	dirs := registryPath()
+	dirs = append(dirs, filepath.SplitList(env.Get("PATH"))...)
+	path := joinPath(dirs)
+	if path != env.Get("PATH") {
+		log.Printf("已刷新 PATH: %s", path)
+		env.Set("PATH", path)
+	}
+}
+
+// 把目录加到传给外部命令的 PATH（env）的最前面，已存在时不重复添加
+func AddToPath(env *runner.Environment, dir string) {
+	path := joinPath(append([]string{dir}, filepath.SplitList(env.Get("PATH"))...))
+	if path != env.Get("PATH") {
+		log.Printf("把 %s 加入 PATH", dir)
+		env.Set("PATH", path)
+	}
+}
+
+// 按绝对路径查找 uv 的可执行文件：先查传给外部命令的 PATH（env），再查 uv 安装脚本的默认安装目录和 extraDirs
+func FindUV(env *runner.Environment, extraDirs ...string) (string, bool) {
+	if path, err := env.LookPath("uv"); err == nil {
+		return path, true
+	}
+	for _, dir := range append(extraDirs, uvInstallDirs(env)...) {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, Current.UV)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// uv 安装脚本可能使用的安装目录，按脚本的选择顺序排列。环境变量取传给安装脚本的值（env）
+func uvInstallDirs(env *runner.Environment) []string {
+	var dirs []string
+	if dir := env.Get("UV_INSTALL_DIR"); dir != "" {
+		dirs = append(dirs, dir, filepath.Join(dir, "bin"))
+	}
+	if dir := env.Get("XDG_BIN_HOME"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if dir := env.Get("XDG_DATA_HOME"); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, "..", "bin"))
+	}
+	// Windows 上为 USERPROFILE，Linux 上为 HOME
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".local", "bin"), filepath.Join(home, ".cargo", "bin"))
+	}
+	return dirs
+}
+
+// 去掉空项和重复项（不区分大小写）后拼接成 PATH
+func joinPath(dirs []string) string {
+	seen := map[string]bool{}
+	var list []string
+	for _, dir := range dirs {
+		key := strings.ToLower(strings.TrimRight(dir, `\/`))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		list = append(list, dir)
+	}
+	return strings.Join(list, string(os.PathListSeparator))
+}
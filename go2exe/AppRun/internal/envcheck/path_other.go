@@ -0,0 +1,8 @@
+//go:build !windows
+
+package envcheck
+
+// PATH 不保存在注册表中，没有需要补充的目录，只使用当前进程的 PATH
+func registryPath() []string {
+	return nil
+}
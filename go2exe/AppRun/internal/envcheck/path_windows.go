@@ -0,0 +1,76 @@
+package envcheck
+
+import (
+	"log"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                 = syscall.NewLazyDLL("kernel32.dll")
+	expandEnvironmentStrings = kernel32.NewProc("ExpandEnvironmentStringsW")
+)
+
+// 用户和系统环境变量在注册表中的位置
+const (
+	userEnvironmentKey    = `Environment`
+	machineEnvironmentKey = `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`
+)
+
+// 注册表中系统和用户的 PATH 中的目录，系统的在前
+func registryPath() []string {
+	var dirs []string
+	for _, k := range []struct {
+		root syscall.Handle
+		path string
+	}{{syscall.HKEY_LOCAL_MACHINE, machineEnvironmentKey}, {syscall.HKEY_CURRENT_USER, userEnvironmentKey}} {
+		value, err := regPath(k.root, k.path)
+		if err != nil {
+			log.Printf("读取注册表中的 PATH 失败: %v", err)
+			continue
+		}
+		dirs = append(dirs, filepath.SplitList(value)...)
+	}
+	return dirs
+}
+
+// 读取注册表中的 Path 值，REG_EXPAND_SZ 会展开其中的环境变量
+func regPath(root syscall.Handle, keyPath string) (string, error) {
+	pathPtr, _ := syscall.UTF16PtrFromString(keyPath)
+	var key syscall.Handle
+	if err := syscall.RegOpenKeyEx(root, pathPtr, 0, syscall.KEY_READ, &key); err != nil {
+		return "", err
+	}
+	defer syscall.RegCloseKey(key)
+
+	name, _ := syscall.UTF16PtrFromString("Path")
+	var typ, size uint32
+	if err := syscall.RegQueryValueEx(key, name, nil, &typ, nil, &size); err != nil {
+		return "", err
+	}
+	if size == 0 {
+		return "", nil
+	}
+	buf := make([]uint16, size/2+1)
+	if err := syscall.RegQueryValueEx(key, name, nil, &typ, (*byte)(unsafe.Pointer(&buf[0])), &size); err != nil {
+		return "", err
+	}
+	value := syscall.UTF16ToString(buf)
+	if typ == syscall.REG_EXPAND_SZ {
+		value = expandEnv(value)
+	}
+	return value, nil
+}
+
+// 展开 %VAR% 形式的环境变量
+func expandEnv(s string) string {
+	src, _ := syscall.UTF16PtrFromString(s)
+	n, _, _ := expandEnvironmentStrings.Call(uintptr(unsafe.Pointer(src)), 0, 0)
+	if n == 0 {
+		return s
+	}
+	buf := make([]uint16, n)
+	expandEnvironmentStrings.Call(uintptr(unsafe.Pointer(src)), uintptr(unsafe.Pointer(&buf[0])), n)
+	return syscall.UTF16ToString(buf)
+}
@@ -0,0 +1,54 @@
+package envcheck
+
+// 安装和运行应用的目标平台：uv 和 Python 安装包的名称、uv 的安装脚本和虚拟环境的布局都与系统有关。
+// Windows 版启动器使用 Windows，Linux 和 macOS 版启动器在启动时把 Current 设为 Linux 或 Darwin
+type Platform struct {
+	OS         string   // uv 的 Python 名称中的系统，例如 cpython-3.11.9-windows-x86_64-none 中的 windows
+	Libc       string   // 名称中的 libc，Windows 上为 none
+	Target     string   // uv 和 python-build-standalone 安装包文件名中架构后面的部分，例如 pc-windows-msvc
+	UV         string   // uv 的可执行文件名
+	UVScript   string   // uv 目录中随程序分发的安装脚本
+	Shell      []string // 执行安装脚本的命令，脚本的路径加在最后
+	VenvPython string   // 虚拟环境中运行应用的解释器，相对于虚拟环境目录，使用 / 分隔
+}
+
+var (
+	Windows = Platform{
+		OS:         "windows",
+		Libc:       "none",
+		Target:     "pc-windows-msvc",
+		UV:         "uv.exe",
+		UVScript:   "uv-installer.ps1",
+		Shell:      []string{"powershell", "-ExecutionPolicy", "ByPass", "-File"},
+		VenvPython: "Scripts/pythonw.exe",
+	}
+	Linux = Platform{
+		OS:         "linux",
+		Libc:       "gnu",
+		Target:     "unknown-linux-gnu",
+		UV:         "uv",
+		UVScript:   "uv-installer.sh",
+		Shell:      []string{"sh"},
+		VenvPython: "bin/python",
+	}
+	Darwin = Platform{
+		OS:         "macos",
+		Libc:       "none",
+		Target:     "apple-darwin",
+		UV:         "uv",
+		UVScript:   "uv-installer.sh",
+		Shell:      []string{"sh"},
+		VenvPython: "bin/python",
+	}
+)
+
+// 当前的目标平台
+var Current = Windows
+
+// 安装包文件名中的目标平台，例如 x86_64-pc-windows-msvc、i686-pc-windows-msvc 或 aarch64-unknown-linux-gnu
+func (p Platform) Triple(arch string) string {
+	if arch == "x86" {
+		arch = "i686"
+	}
+	return arch + "-" + p.Target
+}
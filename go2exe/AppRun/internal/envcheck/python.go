@@ -0,0 +1,307 @@
+package envcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 未配置时应用需要的 Python 版本和架构
+const (
+	DefaultPython = "3.11.9"
+	DefaultArch   = "x86_64"
+)
+
+// Python 版本号
+type Version struct {
+	Major, Minor, Patch int
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// 比较两个版本，返回 -1、0 或 1
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// uv python list 输出中的一项
+type Installation struct {
+	Key            string // 例如 cpython-3.11.9-windows-x86_64-none
+	Implementation string // cpython、pypy 等
+	Version        Version
+	Variant        string // 预发布版本（如 a4、rc1）或 +freethreaded 等变体，普通版本为空
+	OS             string
+	Arch           string
+	Libc           string
+	Path           string // 解释器路径，只能下载时为空
+	Downloadable   bool   // 尚未安装，可以由 uv 下载
+}
+
+// 是否为已安装、可以直接使用的解释器
+func (i Installation) Installed() bool {
+	return i.Path != "" && !i.Downloadable
+}
+
+// 解析 uv python list 的输出，支持文本格式和 --output-format json，跳过无法识别的项
+func ParsePythonList(output string) []Installation {
+	if trimmed := strings.TrimSpace(output); strings.HasPrefix(trimmed, "[") {
+		if list, err := parsePythonListJSON(trimmed); err == nil {
+			return list
+		}
+	}
+	var list []Installation
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		inst, ok := parseKey(fields[0])
+		if !ok {
+			continue
+		}
+		// 路径后面可能是符号链接的目标：<路径> -> <目标>
+		rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), fields[0]))
+		path, _, _ := strings.Cut(rest, " -> ")
+		path = strings.TrimSpace(path)
+		switch {
+		case path == "" || strings.HasPrefix(path, "<download"):
+			inst.Downloadable = true
+		default:
+			inst.Path = path
+		}
+		list = append(list, inst)
+	}
+	return list
+}
+
+// 解析 cpython-3.11.9-windows-x86_64-none 这样的键：实现-版本-系统-架构-libc。
+// 从两端取固定的部分，架构中含有 - 时也能识别
+func parseKey(key string) (Installation, bool) {
+	parts := strings.Split(key, "-")
+	if len(parts) < 5 {
+		return Installation{}, false
+	}
+	version, variant, ok := parseVersionPrefix(parts[1])
+	if !ok {
+		return Installation{}, false
+	}
+	n := len(parts)
+	return Installation{
+		Key:            key,
+		Implementation: parts[0],
+		Version:        version,
+		Variant:        variant,
+		OS:             parts[2],
+		Arch:           strings.Join(parts[3:n-1], "-"),
+		Libc:           parts[n-1],
+	}, true
+}
+
+// uv python list --output-format json 中的一项
+type pythonListEntry struct {
+	Key            string  `json:"key"`
+	Version        string  `json:"version"`
+	Path           *string `json:"path"`
+	URL            *string `json:"url"`
+	OS             string  `json:"os"`
+	Variant        string  `json:"variant"`
+	Implementation string  `json:"implementation"`
+	Arch           string  `json:"arch"`
+	Libc           string  `json:"libc"`
+}
+
+func parsePythonListJSON(data string) ([]Installation, error) {
+	var entries []pythonListEntry
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		return nil, err
+	}
+	var list []Installation
+	for _, e := range entries {
+		version, variant, ok := parseVersionPrefix(e.Version)
+		if !ok {
+			continue
+		}
+		// 文本格式中的变体是版本号的一部分（3.13.0+freethreaded），JSON 中单独给出，普通版本为 default
+		if e.Variant != "" && e.Variant != "default" && !strings.Contains(variant, e.Variant) {
+			variant += "+" + e.Variant
+		}
+		inst := Installation{
+			Key:            e.Key,
+			Implementation: strings.ToLower(e.Implementation),
+			Version:        version,
+			Variant:        variant,
+			OS:             e.OS,
+			Arch:           e.Arch,
+			Libc:           e.Libc,
+		}
+		if e.Path != nil && *e.Path != "" {
+			inst.Path = *e.Path
+		} else {
+			inst.Downloadable = true
+		}
+		list = append(list, inst)
+	}
+	return list, nil
+}
+
+// 解析 "3.11.9"、"3.14.0a4"、"3.13.0+freethreaded" 等版本，返回版本号和其余部分
+func parseVersionPrefix(s string) (Version, string, bool) {
+	end := 0
+	for end < len(s) && (s[end] == '.' || s[end] >= '0' && s[end] <= '9') {
+		end++
+	}
+	nums := strings.Split(s[:end], ".")
+	if len(nums) != 3 {
+		return Version{}, "", false
+	}
+	var n [3]int
+	for i, part := range nums {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return Version{}, "", false
+		}
+		n[i] = v
+	}
+	return Version{n[0], n[1], n[2]}, s[end:], true
+}
+
+// 版本约束，多个条件用逗号分隔且都要满足，例如 "3.11.9"、"3.11.*"、">=3.10,<3.13"。
+// 不带比较符或使用 == 时，不完整的版本（"3.11"）和通配符（"3.11.*"）按前缀匹配
+type Constraint struct {
+	raw     string
+	clauses []clause
+}
+
+type clause struct {
+	op     string
+	parts  []int
+	prefix bool // 按前缀匹配（版本不完整或带 .*）
+}
+
+// 解析版本约束
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	if c.raw == "" {
+		return c, fmt.Errorf("Python 版本约束为空")
+	}
+	for _, item := range strings.Split(c.raw, ",") {
+		item = strings.TrimSpace(item)
+		cl := clause{op: "=="}
+		for _, op := range []string{">=", "<=", "==", "!=", ">", "<"} {
+			if rest, ok := strings.CutPrefix(item, op); ok {
+				cl.op, item = op, strings.TrimSpace(rest)
+				break
+			}
+		}
+		if rest, ok := strings.CutSuffix(item, ".*"); ok {
+			if cl.op != "==" && cl.op != "!=" {
+				return c, fmt.Errorf("Python 版本约束 %q 无效: 通配符只能用于 == 或 !=", s)
+			}
+			item, cl.prefix = rest, true
+		}
+		nums := strings.Split(item, ".")
+		if item == "" || len(nums) > 3 {
+			return c, fmt.Errorf("Python 版本约束 %q 无效", s)
+		}
+		for _, part := range nums {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return c, fmt.Errorf("Python 版本约束 %q 无效", s)
+			}
+			cl.parts = append(cl.parts, n)
+		}
+		if len(cl.parts) < 3 {
+			cl.prefix = cl.prefix || cl.op == "==" || cl.op == "!="
+		}
+		c.clauses = append(c.clauses, cl)
+	}
+	return c, nil
+}
+
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// 版本是否满足所有条件
+func (c Constraint) Match(v Version) bool {
+	for _, cl := range c.clauses {
+		if !cl.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (cl clause) match(v Version) bool {
+	have := []int{v.Major, v.Minor, v.Patch}
+	if cl.prefix {
+		equal := true
+		for i, n := range cl.parts {
+			if have[i] != n {
+				equal = false
+			}
+		}
+		return equal == (cl.op == "==")
+	}
+	want := Version{}
+	for i, n := range cl.parts {
+		switch i {
+		case 0:
+			want.Major = n
+		case 1:
+			want.Minor = n
+		case 2:
+			want.Patch = n
+		}
+	}
+	d := v.Compare(want)
+	switch cl.op {
+	case "==":
+		return d == 0
+	case "!=":
+		return d != 0
+	case ">=":
+		return d >= 0
+	case "<=":
+		return d <= 0
+	case ">":
+		return d > 0
+	default:
+		return d < 0
+	}
+}
+
+// 传给 uv python install 的版本请求：精确版本或前缀（"3.11.*" 转为 "3.11"）在指定了非默认架构时
+// 使用 uv 的完整名称形式（cpython-3.11-windows-x86-none，系统和 libc 取自 Current），其他约束原样传给 uv
+func InstallRequest(constraint, arch string) (string, error) {
+	if constraint == "" {
+		constraint = DefaultPython
+	}
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+	if len(c.clauses) != 1 || c.clauses[0].op != "==" {
+		return c.raw, nil
+	}
+	var nums []string
+	for _, n := range c.clauses[0].parts {
+		nums = append(nums, strconv.Itoa(n))
+	}
+	version := strings.Join(nums, ".")
+	if arch = ResolveArch(arch); arch == DefaultArch {
+		return version, nil
+	}
+	return fmt.Sprintf("cpython-%s-%s-%s-%s", version, Current.OS, arch, Current.Libc), nil
+}
@@ -0,0 +1,40 @@
+package envcheck
+
+import (
+	"strings"
+	"sync"
+)
+
+// 支持 uv python list --output-format json 的最低 uv 版本
+var pythonListJSONSince = Version{0, 6, 6}
+
+// 解析 uv -V 的输出，例如 "uv 0.4.18 (7b55e9790 2024-10-01)"
+func ParseUVVersion(output string) (Version, bool) {
+	fields := strings.Fields(strings.TrimSpace(output))
+	if len(fields) < 2 || fields[0] != "uv" {
+		return Version{}, false
+	}
+	v, _, ok := parseVersionPrefix(fields[1])
+	return v, ok
+}
+
+// 本进程使用的 uv 是否支持 JSON 格式的 uv python list：由 uv -V 报告的版本确定，
+// 版本未知时由第一次尝试的结果确定
+var pythonListFormat struct {
+	sync.Mutex
+	known bool
+	json  bool
+}
+
+func setPythonListJSON(json bool) {
+	pythonListFormat.Lock()
+	defer pythonListFormat.Unlock()
+	pythonListFormat.known, pythonListFormat.json = true, json
+}
+
+// 是否先尝试 JSON 格式：已知不支持时直接使用文本格式
+func preferPythonListJSON() bool {
+	pythonListFormat.Lock()
+	defer pythonListFormat.Unlock()
+	return !pythonListFormat.known || pythonListFormat.json
+}
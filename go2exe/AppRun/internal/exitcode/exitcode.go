@@ -0,0 +1,21 @@
+// Package exitcode 定义 Windows、Linux 和 macOS 版启动器共用的进程退出码，
+// 部署工具（SCCM、Intune 等）和脚本据此判断失败原因。已发布的数值不要修改
+package exitcode
+
+const (
+	OK            = 0
+	Failed        = 1  // 其他错误
+	Cancelled     = 2  // 用户取消（拒绝关闭应用、拒绝管理员权限请求等）
+	Check         = 10 // 检查已安装的 uv 和 Python 失败
+	Preflight     = 11 // 安装前检查未通过
+	Verify        = 12 // 安装文件校验失败
+	Elevation     = 13 // 无法以管理员身份执行安装
+	UVInstall     = 14 // 安装 uv 失败
+	PythonInstall = 15 // 安装 Python 失败
+	Sync          = 16 // 同步依赖失败，应用无法启动
+	AppStart      = 17 // 启动应用失败
+	Uninstall     = 20 // 卸载有步骤失败
+	Repair        = 21 // 修复环境失败
+	Config        = 22 // config validate 发现配置有问题
+	Golden        = 23 // 黄金镜像未准备好或与程序不一致，或准备黄金镜像失败
+)
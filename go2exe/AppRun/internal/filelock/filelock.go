@@ -0,0 +1,45 @@
+// Package filelock 通过 Restart Manager 找出占用文件的进程，并在用户同意后关闭它们
+package filelock
+
+import (
+	"io/fs"
+	"path/filepath"
+
+	"go2exe/internal/i18n"
+)
+
+// 一次最多向 Restart Manager 登记的文件数
+const maxFiles = 500
+
+// 占用文件的进程
+type Owner struct {
+	PID     uint32
+	Name    string // 程序名称（窗口标题或文件描述）
+	Service string // 服务名，不是服务时为空
+	Manual  bool   // 资源管理器、系统关键进程或服务，不能自动关闭，需要用户手动处理
+}
+
+func (o Owner) String() string {
+	if o.Service != "" {
+		return i18n.T("%s（服务 %s，PID %d）", o.Name, o.Service, o.PID)
+	}
+	return i18n.T("%s（PID %d）", o.Name, o.PID)
+}
+
+// 返回 path 下仍然存在的文件（最多 maxFiles 个）。删除目录失败后剩下的通常就是被占用的文件
+func RemainingFiles(path string) []string {
+	var files []string
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			files = append(files, p)
+		}
+		if len(files) >= maxFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return files
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package filelock
+
+import (
+	"fmt"
+	"time"
+)
+
+// macOS 和 Linux 上没有 Restart Manager，不查找占用文件的进程，总是返回空列表
+func Owners(files []string) ([]Owner, error) {
+	return nil, nil
+}
+
+// 不支持关闭其他进程，总是返回错误
+func Close(pid uint32, timeout time.Duration) error {
+	return fmt.Errorf("无法关闭进程 %d：当前系统不支持", pid)
+}
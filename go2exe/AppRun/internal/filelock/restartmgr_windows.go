@@ -0,0 +1,136 @@
+package filelock
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	rstrtmgr                          = syscall.NewLazyDLL("rstrtmgr.dll")
+	user32                            = syscall.NewLazyDLL("user32.dll")
+	rmStartSession                    = rstrtmgr.NewProc("RmStartSession")
+	rmRegisterResources               = rstrtmgr.NewProc("RmRegisterResources")
+	rmGetList                         = rstrtmgr.NewProc("RmGetList")
+	rmEndSession                      = rstrtmgr.NewProc("RmEndSession")
+	enumWindows                       = user32.NewProc("EnumWindows")
+	getWindowThreadProcessId          = user32.NewProc("GetWindowThreadProcessId")
+	postMessage                       = user32.NewProc("PostMessageW")
+	CCH_RM_SESSION_KEY                = 32
+	ERROR_MORE_DATA                   = 234
+	WM_CLOSE                          = 0x0010
+	SYNCHRONIZE                       = 0x00100000
+	PROCESS_TERMINATE                 = 0x0001
+	PROCESS_QUERY_LIMITED_INFORMATION = 0x1000
+	RmService                         = 3
+	RmExplorer                        = 4
+	RmCritical                        = 1000
+)
+
+// 向属于进程 lparam 的窗口发送 WM_CLOSE。syscall.NewCallback 创建的回调不会释放且数量有限，只创建一次
+var closeWindowsCallback = syscall.NewCallback(func(hwnd, lparam uintptr) uintptr {
+	var owner uint32
+	getWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&owner)))
+	if owner == uint32(lparam) {
+		postMessage.Call(hwnd, uintptr(WM_CLOSE), 0, 0)
+	}
+	return 1
+})
+
+// RM_PROCESS_INFO
+type rmProcessInfo struct {
+	processID        uint32
+	startTime        syscall.Filetime
+	appName          [256]uint16 // CCH_RM_MAX_APP_NAME + 1
+	serviceShortName [64]uint16  // CCH_RM_MAX_SVC_NAME + 1
+	applicationType  uint32
+	appStatus        uint32
+	tsSessionID      uint32
+	restartable      int32
+}
+
+// 列出占用 files 中任意文件的进程
+func Owners(files []string) ([]Owner, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	if len(files) > maxFiles {
+		files = files[:maxFiles]
+	}
+
+	var session uint32
+	key := make([]uint16, CCH_RM_SESSION_KEY+1)
+	if r, _, _ := rmStartSession.Call(uintptr(unsafe.Pointer(&session)), 0, uintptr(unsafe.Pointer(&key[0]))); r != 0 {
+		return nil, fmt.Errorf("RmStartSession 失败: %d", r)
+	}
+	defer rmEndSession.Call(uintptr(session))
+
+	names := make([]*uint16, len(files))
+	for i, f := range files {
+		names[i], _ = syscall.UTF16PtrFromString(f)
+	}
+	if r, _, _ := rmRegisterResources.Call(uintptr(session), uintptr(len(names)), uintptr(unsafe.Pointer(&names[0])), 0, 0, 0, 0); r != 0 {
+		return nil, fmt.Errorf("RmRegisterResources 失败: %d", r)
+	}
+
+	// 第一次调用获取所需数量，进程列表在两次调用之间可能变化，因此循环直到缓冲区足够
+	var infos []rmProcessInfo
+	for {
+		var needed, reasons uint32
+		count := uint32(len(infos))
+		var buf uintptr
+		if count > 0 {
+			buf = uintptr(unsafe.Pointer(&infos[0]))
+		}
+		r, _, _ := rmGetList.Call(uintptr(session), uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&count)), buf, uintptr(unsafe.Pointer(&reasons)))
+		if r == uintptr(ERROR_MORE_DATA) {
+			infos = make([]rmProcessInfo, needed)
+			continue
+		}
+		if r != 0 {
+			return nil, fmt.Errorf("RmGetList 失败: %d", r)
+		}
+		infos = infos[:count]
+		break
+	}
+
+	owners := make([]Owner, 0, len(infos))
+	for _, info := range infos {
+		owner := Owner{
+			PID:     info.processID,
+			Name:    syscall.UTF16ToString(info.appName[:]),
+			Service: syscall.UTF16ToString(info.serviceShortName[:]),
+		}
+		// 关闭资源管理器、系统关键进程或服务会影响整个系统，只提示用户手动处理
+		switch info.applicationType {
+		case uint32(RmService), uint32(RmExplorer), uint32(RmCritical):
+			owner.Manual = true
+		}
+		if owner.Service != "" {
+			owner.Manual = true
+		}
+		owners = append(owners, owner)
+	}
+	return owners, nil
+}
+
+// 关闭进程：先向它的窗口发送 WM_CLOSE，timeout 内未退出再强制结束。Manual 为 true 的进程不应调用
+func Close(pid uint32, timeout time.Duration) error {
+	h, err := syscall.OpenProcess(uint32(SYNCHRONIZE|PROCESS_TERMINATE|PROCESS_QUERY_LIMITED_INFORMATION), false, pid)
+	if err != nil {
+		return fmt.Errorf("无法打开进程 %d: %v", pid, err)
+	}
+	defer syscall.CloseHandle(h)
+
+	enumWindows.Call(closeWindowsCallback, uintptr(pid))
+
+	if ev, _ := syscall.WaitForSingleObject(h, uint32(timeout/time.Millisecond)); ev == syscall.WAIT_OBJECT_0 {
+		return nil
+	}
+	if err := syscall.TerminateProcess(h, 1); err != nil {
+		return fmt.Errorf("无法结束进程 %d: %v", pid, err)
+	}
+	syscall.WaitForSingleObject(h, 5000)
+	return nil
+}
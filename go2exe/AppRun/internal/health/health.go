@@ -0,0 +1,204 @@
+// Package health 提供启动器的健康检查接口：只监听本机的 HTTP 服务，以 JSON 报告启动器当前的状态
+// （安装、同步依赖、运行、崩溃等）、Python 应用的进程号、各组件的版本和最近的日志，
+// 供技术支持人员和应用自己查询。
+//
+//	GET /v1/health  完整的状态报告
+//	GET /healthz    只返回状态码：启动失败或应用崩溃时为 503，其他为 200
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 启动器的状态
+type State string
+
+const (
+	StateStarting   State = "starting"   // 检查环境
+	StateInstalling State = "installing" // 安装 uv 和 Python
+	StateSyncing    State = "syncing"    // 同步依赖
+	StateRunning    State = "running"    // 应用正在运行
+	StateExited     State = "exited"     // 应用正常退出
+	StateCrashed    State = "crashed"    // 应用异常退出
+	StateFailed     State = "failed"     // 启动失败，应用没有运行
+)
+
+// 各组件的版本，未知的为空
+type Versions struct {
+	Launcher string `json:"launcher,omitempty"`
+	UV       string `json:"uv,omitempty"`
+	Python   string `json:"python,omitempty"`
+	App      string `json:"app,omitempty"`
+}
+
+// 状态报告
+type Report struct {
+	State       State     `json:"state"`
+	Detail      string    `json:"detail,omitempty"` // 正在执行的步骤等补充说明
+	Since       time.Time `json:"since"`            // 进入当前状态的时间
+	Error       string    `json:"error,omitempty"`  // 启动失败或应用崩溃的原因
+	LauncherPID int       `json:"launcher_pid"`
+	AppPID      int       `json:"app_pid,omitempty"` // 正在运行的 Python 应用的进程号
+	Versions    Versions  `json:"versions"`
+	Log         []string  `json:"log"` // 最近的启动器日志
+}
+
+// 记录启动器状态，可以在多个 goroutine 中使用
+type Tracker struct {
+	Log      *Ring               // 最近的日志，为 nil 时报告中没有日志
+	Versions func() Versions     // 查询版本，为 nil 时报告中没有版本
+	Scrub    func(string) string // 去除日志和错误中的个人信息，为 nil 时原样报告
+
+	mu     sync.Mutex
+	state  State
+	detail string
+	since  time.Time
+	err    string
+	appPID int
+}
+
+// 进入 state，清除上一次的错误
+func (t *Tracker) Set(state State, detail string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state, t.detail, t.since, t.err = state, detail, time.Now(), ""
+	if state != StateRunning {
+		t.appPID = 0
+	}
+}
+
+// 应用已启动
+func (t *Tracker) Running(pid int) {
+	t.Set(StateRunning, "")
+	t.mu.Lock()
+	t.appPID = pid
+	t.mu.Unlock()
+}
+
+// 启动失败（StateFailed）或应用崩溃（StateCrashed）
+func (t *Tracker) Fail(state State, err error) {
+	t.Set(state, "")
+	if err != nil {
+		t.mu.Lock()
+		t.err = err.Error()
+		t.mu.Unlock()
+	}
+}
+
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	r := Report{State: t.state, Detail: t.detail, Since: t.since, Error: t.err, AppPID: t.appPID, LauncherPID: os.Getpid()}
+	t.mu.Unlock()
+	if r.State == "" {
+		r.State = StateStarting
+	}
+	if t.Versions != nil {
+		r.Versions = t.Versions()
+	}
+	r.Log = []string{}
+	if t.Log != nil {
+		r.Log = t.Log.Lines()
+	}
+	// 本机的任何程序都能查询，日志中的用户名和书籍路径等与诊断包一样去除
+	if t.Scrub != nil {
+		r.Error = t.Scrub(r.Error)
+		for n, line := range r.Log {
+			r.Log[n] = t.Scrub(line)
+		}
+	}
+	return r
+}
+
+func (t *Tracker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(t.Report())
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		t.mu.Lock()
+		state := t.state
+		t.mu.Unlock()
+		if state == StateFailed || state == StateCrashed {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		fmt.Fprintln(w, state)
+	})
+	return mux
+}
+
+// 在本机的 port 端口上提供健康检查接口，直到 ctx 取消。只监听 127.0.0.1，不对局域网开放
+func Serve(ctx context.Context, port int, t *Tracker) error {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", fmt.Sprint(port)))
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: localHostOnly(port, t.Handler()), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// 只接受 Host 为 127.0.0.1:<port> 或 localhost:<port> 的请求。网页可以通过 DNS 重绑定让浏览器用自己的域名
+// 访问 127.0.0.1，这样的请求 Host 是网页的域名，拒绝后网页读不到报告
+func localHostOnly(port int, h http.Handler) http.Handler {
+	allowed := []string{fmt.Sprintf("127.0.0.1:%d", port), fmt.Sprintf("localhost:%d", port)}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.ContainsFunc(allowed, func(host string) bool { return strings.EqualFold(host, r.Host) }) {
+			http.Error(w, "forbidden host", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// 保留最近若干行日志的 io.Writer，加到 log 的输出中
+type Ring struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial string // 还没有换行的部分
+}
+
+// 最多保留 n 行的 Ring
+func NewRing(n int) *Ring {
+	return &Ring{max: n}
+}
+
+func (r *Ring) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	text := r.partial + string(p)
+	parts := strings.Split(text, "\n")
+	r.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		r.lines = append(r.lines, strings.TrimRight(line, "\r"))
+	}
+	if over := len(r.lines) - r.max; over > 0 {
+		r.lines = append(r.lines[:0], r.lines[over:]...)
+	}
+	return len(p), nil
+}
+
+// 最近的日志，从旧到新
+func (r *Ring) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.lines...)
+}
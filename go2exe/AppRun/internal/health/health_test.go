@@ -0,0 +1,97 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	r := NewRing(3)
+	for n := 1; n <= 5; n++ {
+		fmt.Fprintf(r, "line %d\r\n", n)
+	}
+	r.Write([]byte("partial"))
+	if got, want := r.Lines(), []string{"line 3", "line 4", "line 5"}; !slices.Equal(got, want) {
+		t.Errorf("Lines() = %q, want %q", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tracker := &Tracker{Log: NewRing(10), Versions: func() Versions { return Versions{UV: "uv 0.4.0"} }}
+	fmt.Fprintln(tracker.Log, "正在同步依赖")
+	srv := httptest.NewServer(tracker.Handler())
+	defer srv.Close()
+
+	get := func(path string) (*http.Response, Report) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var r Report
+		if path == "/v1/health" {
+			json.NewDecoder(resp.Body).Decode(&r)
+		}
+		return resp, r
+	}
+
+	if _, r := get("/v1/health"); r.State != StateStarting || r.Versions.UV != "uv 0.4.0" || len(r.Log) != 1 || r.LauncherPID == 0 {
+		t.Errorf("初始报告 = %+v", r)
+	}
+	tracker.Running(1234)
+	if resp, r := get("/v1/health"); resp.StatusCode != 200 || r.State != StateRunning || r.AppPID != 1234 {
+		t.Errorf("运行中的报告 = %+v", r)
+	}
+	tracker.Fail(StateCrashed, errors.New("exit status 1"))
+	if _, r := get("/v1/health"); r.State != StateCrashed || r.AppPID != 0 || r.Error != "exit status 1" {
+		t.Errorf("崩溃后的报告 = %+v", r)
+	}
+	if resp, _ := get("/healthz"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("崩溃后 /healthz = %d", resp.StatusCode)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	tracker := &Tracker{Log: NewRing(10), Scrub: func(s string) string { return strings.ReplaceAll(s, "alice", "<user>") }}
+	fmt.Fprintln(tracker.Log, `打开 C:\Users\alice\书.epub`)
+	tracker.Fail(StateFailed, errors.New(`找不到 C:\Users\alice\AppData`))
+	r := tracker.Report()
+	if want := []string{`打开 C:\Users\<user>\书.epub`}; !slices.Equal(r.Log, want) {
+		t.Errorf("Log = %q, want %q", r.Log, want)
+	}
+	if want := `找不到 C:\Users\<user>\AppData`; r.Error != want {
+		t.Errorf("Error = %q, want %q", r.Error, want)
+	}
+	// 不影响 Ring 中保存的原文
+	if got := tracker.Log.Lines(); !strings.Contains(got[0], "alice") {
+		t.Errorf("Ring 中的日志被修改: %q", got)
+	}
+}
+
+func TestLocalHostOnly(t *testing.T) {
+	h := localHostOnly(8765, (&Tracker{}).Handler())
+	for host, want := range map[string]int{
+		"127.0.0.1:8765":        http.StatusOK,
+		"localhost:8765":        http.StatusOK,
+		"LOCALHOST:8765":        http.StatusOK,
+		"127.0.0.1:9999":        http.StatusForbidden,
+		"evil.example:8765":     http.StatusForbidden,
+		"127.0.0.1.nip.io:8765": http.StatusForbidden,
+		"":                      http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Host %q: 状态码 %d, want %d", host, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Package hostenv 检测启动器是否运行在 Windows 沙盒、虚拟机或临时用户配置文件中：
+// 这些环境中用户目录下的安装和设置在关闭沙盒、注销或重启后可能丢失
+package hostenv
+
+import (
+	"os"
+	"strings"
+)
+
+// Windows 沙盒中登录的用户
+const sandboxUser = "WDAGUtilityAccount"
+
+// 运行环境
+type Host struct {
+	Sandbox          bool   // Windows 沙盒
+	VM               string // 虚拟机的产品名称，例如 VMware、VirtualBox；不是虚拟机或无法判断时为空
+	TemporaryProfile bool   // 使用临时或强制用户配置文件，注销后对用户目录的修改会丢失
+}
+
+// 检测当前的运行环境
+func Detect() Host {
+	manufacturer, product := biosInfo()
+	return Host{
+		Sandbox:          strings.EqualFold(os.Getenv("USERNAME"), sandboxUser),
+		VM:               vmName(manufacturer, product),
+		TemporaryProfile: temporaryProfile(),
+	}
+}
+
+// 用户目录下的内容是否会在关闭沙盒或注销后丢失。普通的虚拟机通常会保留，只记录不算在内
+func (h Host) Ephemeral() bool {
+	return h.Sandbox || h.TemporaryProfile
+}
+
+// 用于提示的环境名称（中文原文，显示前经过 i18n.T），不是 Ephemeral 时为空
+func (h Host) Reason() string {
+	switch {
+	case h.Sandbox:
+		return "Windows 沙盒"
+	case h.TemporaryProfile:
+		return "临时用户配置文件"
+	}
+	return ""
+}
+
+// 按 BIOS 中的制造商和产品名称判断虚拟机
+func vmName(manufacturer, product string) string {
+	s := strings.ToLower(manufacturer + " " + product)
+	for _, vm := range []struct{ key, name string }{
+		{"vmware", "VMware"},
+		{"virtualbox", "VirtualBox"},
+		{"qemu", "QEMU"},
+		{"kvm", "KVM"},
+		{"xen", "Xen"},
+		{"parallels", "Parallels"},
+		{"amazon ec2", "Amazon EC2"},
+		{"google compute engine", "Google Compute Engine"},
+		{"virtual machine", "Hyper-V"}, // Hyper-V 的产品名称为 Virtual Machine
+	} {
+		if strings.Contains(s, vm.key) {
+			return vm.name
+		}
+	}
+	return ""
+}
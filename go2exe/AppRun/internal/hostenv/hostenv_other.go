@@ -0,0 +1,13 @@
+//go:build !windows
+
+package hostenv
+
+// 不读取 BIOS 信息，因此不会识别为虚拟机
+func biosInfo() (manufacturer, product string) {
+	return "", ""
+}
+
+// 没有 Windows 的临时用户配置文件，用户目录总会保留
+func temporaryProfile() bool {
+	return false
+}
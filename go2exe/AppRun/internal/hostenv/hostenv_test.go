@@ -0,0 +1,34 @@
+package hostenv
+
+import "testing"
+
+func TestVMName(t *testing.T) {
+	tests := []struct {
+		manufacturer, product string
+		want                  string
+	}{
+		{"VMware, Inc.", "VMware7,1", "VMware"},
+		{"innotek GmbH", "VirtualBox", "VirtualBox"},
+		{"QEMU", "Standard PC (Q35 + ICH9, 2009)", "QEMU"},
+		{"Microsoft Corporation", "Virtual Machine", "Hyper-V"},
+		{"Microsoft Corporation", "Surface Laptop 5", ""},
+		{"LENOVO", "20XW0042CD", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := vmName(tt.manufacturer, tt.product); got != tt.want {
+			t.Errorf("vmName(%q, %q) = %q, want %q", tt.manufacturer, tt.product, got, tt.want)
+		}
+	}
+}
+
+func TestHost(t *testing.T) {
+	t.Setenv("USERNAME", "wdagutilityaccount")
+	h := Detect()
+	if !h.Sandbox || !h.Ephemeral() || h.Reason() != "Windows 沙盒" {
+		t.Errorf("Detect() = %+v", h)
+	}
+	if h := (Host{VM: "VMware"}); h.Ephemeral() || h.Reason() != "" {
+		t.Errorf("普通虚拟机不应视为会丢失数据: %+v", h)
+	}
+}
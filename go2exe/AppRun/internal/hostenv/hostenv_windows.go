@@ -0,0 +1,48 @@
+package hostenv
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	userenv        = syscall.NewLazyDLL("userenv.dll")
+	getProfileType = userenv.NewProc("GetProfileType")
+)
+
+// GetProfileType 返回的标志
+const (
+	PT_TEMPORARY = 0x00000001
+	PT_MANDATORY = 0x00000004
+)
+
+// 注册表中 BIOS 报告的系统制造商和产品名称
+func biosInfo() (manufacturer, product string) {
+	path, _ := syscall.UTF16PtrFromString(`HARDWARE\DESCRIPTION\System\BIOS`)
+	var key syscall.Handle
+	if err := syscall.RegOpenKeyEx(syscall.HKEY_LOCAL_MACHINE, path, 0, syscall.KEY_READ, &key); err != nil {
+		return "", ""
+	}
+	defer syscall.RegCloseKey(key)
+	return regString(key, "SystemManufacturer"), regString(key, "SystemProductName")
+}
+
+func regString(key syscall.Handle, name string) string {
+	namePtr, _ := syscall.UTF16PtrFromString(name)
+	var typ, size uint32
+	if err := syscall.RegQueryValueEx(key, namePtr, nil, &typ, nil, &size); err != nil || size == 0 {
+		return ""
+	}
+	buf := make([]uint16, size/2+1)
+	if err := syscall.RegQueryValueEx(key, namePtr, nil, &typ, (*byte)(unsafe.Pointer(&buf[0])), &size); err != nil {
+		return ""
+	}
+	return syscall.UTF16ToString(buf)
+}
+
+// 当前用户是否使用临时或强制配置文件
+func temporaryProfile() bool {
+	var flags uint32
+	r, _, _ := getProfileType.Call(uintptr(unsafe.Pointer(&flags)))
+	return r != 0 && flags&(PT_TEMPORARY|PT_MANDATORY) != 0
+}
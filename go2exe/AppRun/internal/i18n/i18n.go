@@ -0,0 +1,75 @@
+// Package i18n 翻译界面文字。源代码中的简体中文原文即为消息键，
+// 其他语言的目录以 JSON 形式嵌入程序，找不到翻译时显示原文
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// 源代码使用的语言
+const Source = "zh-CN"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var (
+	mu       sync.RWMutex
+	language = Source
+	catalog  map[string]string
+)
+
+// 支持的语言
+func Languages() []string {
+	entries, _ := locales.ReadDir("locales")
+	var langs []string
+	for _, e := range entries {
+		langs = append(langs, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return langs
+}
+
+// 设置界面语言。lang 为空或 "auto" 时跟随系统界面语言，不支持的语言使用英文
+func SetLanguage(lang string) {
+	if lang == "" || strings.EqualFold(lang, "auto") {
+		lang = systemLanguage()
+	}
+	data, err := locales.ReadFile("locales/" + lang + ".json")
+	if err != nil {
+		log.Printf("不支持的界面语言 %s，使用 en-US", lang)
+		lang = "en-US"
+		data, _ = locales.ReadFile("locales/en-US.json")
+	}
+	var c map[string]string
+	if err := json.Unmarshal(data, &c); err != nil {
+		log.Printf("读取 %s 语言目录失败: %v", lang, err)
+	}
+	mu.Lock()
+	language, catalog = lang, c
+	mu.Unlock()
+	log.Printf("界面语言: %s", lang)
+}
+
+// 当前界面语言
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return language
+}
+
+// 翻译一条消息，有参数时按 fmt.Sprintf 格式化
+func T(msg string, args ...interface{}) string {
+	mu.RLock()
+	if t, ok := catalog[msg]; ok && t != "" {
+		msg = t
+	}
+	mu.RUnlock()
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
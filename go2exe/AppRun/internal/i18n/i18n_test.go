@@ -0,0 +1,57 @@
+package i18n
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+func TestT(t *testing.T) {
+	defer SetLanguage(Source)
+
+	SetLanguage("en-US")
+	if got := T("安装已取消"); got != "Installation cancelled" {
+		t.Errorf("T() = %q", got)
+	}
+	if got := T("关闭 %s 失败: %v", "a.exe", "拒绝访问"); got != "Failed to close a.exe: 拒绝访问" {
+		t.Errorf("T() 格式化结果 = %q", got)
+	}
+	// 没有翻译的消息显示原文
+	if got := T("没有翻译的消息 %d", 1); got != "没有翻译的消息 1" {
+		t.Errorf("T() 回退结果 = %q", got)
+	}
+
+	SetLanguage("fr-FR")
+	if Language() != "en-US" {
+		t.Errorf("不支持的语言应使用 en-US，实际为 %s", Language())
+	}
+
+	SetLanguage("zh-CN")
+	if got := T("安装已取消"); got != "安装已取消" {
+		t.Errorf("T() = %q", got)
+	}
+}
+
+// 译文的格式化动词必须与原文一致，否则参数会错位
+func TestCatalogVerbs(t *testing.T) {
+	for _, lang := range Languages() {
+		data, err := locales.ReadFile("locales/" + lang + ".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c map[string]string
+		if err := json.Unmarshal(data, &c); err != nil {
+			t.Fatalf("%s: %v", lang, err)
+		}
+		for src, dst := range c {
+			want := verbPattern.FindAllString(src, -1)
+			got := verbPattern.FindAllString(dst, -1)
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("%s: %q 的格式化动词 %v 与原文 %v 不一致", lang, dst, got, want)
+			}
+		}
+	}
+}
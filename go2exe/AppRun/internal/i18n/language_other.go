@@ -0,0 +1,16 @@
+//go:build !windows
+
+package i18n
+
+import (
+	"os"
+	"strings"
+)
+
+// 按 LANG 环境变量确定界面语言：zh 开头时为中文，其他为英文
+func systemLanguage() string {
+	if strings.HasPrefix(os.Getenv("LANG"), "zh") {
+		return "zh-CN"
+	}
+	return "en-US"
+}
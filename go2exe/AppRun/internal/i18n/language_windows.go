@@ -0,0 +1,18 @@
+package i18n
+
+import "syscall"
+
+var (
+	kernel32                 = syscall.NewLazyDLL("kernel32.dll")
+	getUserDefaultUILanguage = kernel32.NewProc("GetUserDefaultUILanguage")
+	LANG_CHINESE             = 0x04
+)
+
+// 系统界面语言：中文系统（含繁体）使用 zh-CN，其他使用 en-US
+func systemLanguage() string {
+	id, _, _ := getUserDefaultUILanguage.Call()
+	if int(id&0x3ff) == LANG_CHINESE {
+		return "zh-CN"
+	}
+	return "en-US"
+}
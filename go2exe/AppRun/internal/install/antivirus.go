@@ -0,0 +1,192 @@
+package install
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/i18n"
+	"go2exe/internal/ui"
+)
+
+// 显示处理建议的“重试/取消”框，测试时替换
+var retryBox = ui.RetryBox
+
+// 杀毒软件或 SmartScreen 干扰安装的方式
+const (
+	InterferenceQuarantine = "quarantine" // 刚写入的文件被隔离或删除
+	InterferenceAccess     = "access"     // 运行或写入刚安装的文件时拒绝访问
+	InterferenceMOTW       = "motw"       // 文件带有来自网络的标记（Mark of the Web），被 SmartScreen 或脚本策略阻止
+)
+
+// 表示各种干扰的输出（uv、PowerShell 和 Windows 的错误信息），按顺序匹配，先匹配到的为准
+var interferenceHints = []struct {
+	kind  string
+	hints []string
+}{
+	{InterferenceQuarantine, []string{
+		"os error 225", // ERROR_VIRUS_INFECTED
+		"os error 226", // ERROR_VIRUS_DELETED
+		"contains a virus",
+		"potentially unwanted software",
+		"quarantined",
+		"包含病毒",
+	}},
+	{InterferenceMOTW, []string{
+		"is not digitally signed",
+		"running scripts is disabled",
+		"smartscreen",
+		"blocked for your protection",
+		"未进行数字签名",
+		"禁止运行脚本",
+	}},
+	{InterferenceAccess, []string{
+		"access is denied",
+		"os error 5)",
+		"unauthorizedaccess",
+		"拒绝访问",
+	}},
+}
+
+// 命令输出表明的干扰方式，不像被杀毒软件干扰时返回空
+func Interference(output string) string {
+	lower := strings.ToLower(output)
+	for _, h := range interferenceHints {
+		for _, hint := range h.hints {
+			if strings.Contains(lower, hint) {
+				return h.kind
+			}
+		}
+	}
+	return ""
+}
+
+// 安装步骤被杀毒软件或 SmartScreen 干扰而失败
+type InterferenceError struct {
+	Kind  string
+	Paths []string // 被隔离、拒绝访问或带有网络标记的文件，可能为空
+	Err   error
+}
+
+func (e *InterferenceError) Error() string { return e.Err.Error() }
+func (e *InterferenceError) Unwrap() error { return e.Err }
+
+// 给用户的处理建议（已翻译），用户按建议处理后可以重试
+func (e *InterferenceError) Remedy() string {
+	files := ""
+	if len(e.Paths) > 0 {
+		files = "\n\n" + strings.Join(e.Paths, "\n")
+	}
+	switch e.Kind {
+	case InterferenceQuarantine:
+		return i18n.T("杀毒软件可能隔离或删除了刚安装的文件。%s\n\n"+
+			"请在杀毒软件中（Windows 安全中心为“病毒和威胁防护”中的“保护历史记录”）还原这些文件，"+
+			"并把程序目录添加到排除项，然后点击“重试”。", files)
+	case InterferenceMOTW:
+		return i18n.T("以下文件带有“来自 Internet”的标记，被 SmartScreen 或脚本策略阻止运行。%s\n\n"+
+			"请右键单击这些文件，选择“属性”，勾选“解除锁定”后点击“确定”，然后点击“重试”。"+
+			"以后可以在解压前先对下载的压缩包解除锁定。", files)
+	}
+	return i18n.T("运行或写入刚安装的文件时被拒绝访问，可能是杀毒软件正在扫描或拦截。%s\n\n"+
+		"请等待杀毒软件扫描完成，或在杀毒软件中允许这些文件后点击“重试”。", files)
+}
+
+// 记下当前步骤会写入或运行的文件，步骤失败时检查它们是否被隔离或带有网络标记
+func (i *Installer) watchFiles(paths ...string) {
+	for _, p := range paths {
+		if p != "" {
+			i.watched = append(i.watched, p)
+		}
+	}
+}
+
+// 运行随程序分发的脚本和程序之前去掉它们的来自网络标记。从浏览器下载的压缩包解压后每个文件都带有这个标记，
+// uv-installer.ps1 会被 PowerShell 的执行策略或 SmartScreen 阻止。这些文件已按 checksums.txt 校验，
+// 去掉失败时只记录，仍然执行，被阻止时由 checkInterference 提示用户
+func (i *Installer) unblockFiles(paths ...string) {
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		removed, err := unblock(p)
+		switch {
+		case err != nil:
+			log.Printf("无法去掉 %s 的来自网络标记: %v", p, err)
+		case removed:
+			log.Printf("已去掉 %s 的来自网络标记（Zone.Identifier）", p)
+		}
+	}
+}
+
+// 去掉来自网络标记的实现，测试时替换
+var unblock = Unblock
+
+// 检查失败的步骤是否被杀毒软件或 SmartScreen 干扰，是时返回 *InterferenceError，否则原样返回 err
+func (i *Installer) checkInterference(err error, output string) error {
+	var ie *InterferenceError
+	if errors.As(err, &ie) {
+		return err
+	}
+	kind := Interference(output + "\n" + err.Error())
+	var missing, marked []string
+	for _, p := range i.watched {
+		if _, statErr := os.Stat(p); os.IsNotExist(statErr) {
+			missing = append(missing, p)
+		} else if MarkedFromInternet(p) {
+			marked = append(marked, p)
+		}
+	}
+	var paths []string
+	switch {
+	case kind == InterferenceMOTW:
+		paths = marked
+	case len(missing) > 0 && (kind == "" || kind == InterferenceAccess):
+		// 刚才还在的文件不见了，多半是被杀毒软件隔离
+		kind, paths = InterferenceQuarantine, missing
+	case kind == InterferenceAccess && len(i.watched) == 0:
+		// 步骤没有写入或运行新的程序时，拒绝访问多半是文件被占用（例如应用还在运行），不是杀毒软件
+		return err
+	case kind == InterferenceAccess:
+		paths = i.watched
+	case kind == "":
+		return err
+	}
+	log.Printf("安装可能被杀毒软件干扰（%s）: %v %v", kind, paths, err)
+	return &InterferenceError{Kind: kind, Paths: paths, Err: err}
+}
+
+// uv 安装成功后检查安装目录中的 uv 是否还在。安装脚本报告了安装位置、目录存在但程序不见了时，
+// 多半是杀毒软件刚把它隔离
+func (i *Installer) checkUVInstalled() error {
+	if i.UVDir == "" {
+		return nil
+	}
+	if _, err := os.Stat(i.UVDir); err != nil {
+		return nil
+	}
+	uv := filepath.Join(i.UVDir, envcheck.Current.UV)
+	if _, err := os.Stat(uv); !os.IsNotExist(err) {
+		return nil
+	}
+	err := &InterferenceError{Kind: InterferenceQuarantine, Paths: []string{uv}, Err: errors.New(i18n.T("安装后找不到 %s", uv))}
+	log.Printf("安装可能被杀毒软件干扰（%s）: %v", err.Kind, err)
+	return err
+}
+
+// 步骤被杀毒软件或 SmartScreen 干扰时提示处理方法，用户处理后选择“重试”时返回 true
+func (i *Installer) askInterferenceRetry(name string, err error) bool {
+	var ie *InterferenceError
+	if !errors.As(err, &ie) || i.Unattended {
+		return false
+	}
+	i.events().OnProgress(name, i18n.T("%s被安全软件拦截: %v", i18n.T(name), err))
+	if !retryBox(i18n.T("安装被安全软件拦截"), ie.Remedy()) {
+		return false
+	}
+	log.Printf("用户处理了安全软件的拦截，重试%s", name)
+	i.events().OnProgress(name, i18n.T("正在重试%s...", i18n.T(name)))
+	return true
+}
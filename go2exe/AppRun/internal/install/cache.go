@@ -0,0 +1,91 @@
+package install
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go2exe/internal/progress"
+)
+
+// uv 的缓存目录：UV_CACHE_DIR，未设置时为 %LOCALAPPDATA%\uv\cache
+func UVCacheDir() string {
+	if dir := os.Getenv("UV_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.Getenv("LOCALAPPDATA"), "uv", "cache")
+}
+
+// pkgs 中已在 uv 缓存里的包（下载过的 wheel 或构建过的源码包），再次同步时不需要重新下载。
+// uv 按 {wheels,sdists}-vN/{pypi,index/<镜像>}/<包名>/ 存放，其中的条目以版本号或 wheel 文件名命名
+func CachedPackages(cacheDir string, pkgs []LockedPackage) []LockedPackage {
+	versions := map[string]map[string]bool{}
+	buckets, _ := filepath.Glob(filepath.Join(cacheDir, "wheels-v*", "pypi"))
+	for _, pattern := range []string{"wheels-v*", "sdists-v*"} {
+		more, _ := filepath.Glob(filepath.Join(cacheDir, pattern, "index", "*"))
+		buckets = append(buckets, more...)
+	}
+	for _, bucket := range buckets {
+		dirs, err := os.ReadDir(bucket)
+		if err != nil {
+			continue
+		}
+		for _, d := range dirs {
+			if !d.IsDir() {
+				continue
+			}
+			name := normalizeName(d.Name())
+			entries, _ := os.ReadDir(filepath.Join(bucket, d.Name()))
+			for _, e := range entries {
+				if versions[name] == nil {
+					versions[name] = map[string]bool{}
+				}
+				versions[name][cacheEntryVersion(e.Name())] = true
+			}
+		}
+	}
+
+	var cached []LockedPackage
+	for _, p := range pkgs {
+		if versions[normalizeName(p.Name)][p.Version] {
+			cached = append(cached, p)
+		}
+	}
+	return cached
+}
+
+// 缓存条目对应的版本号：条目名为 <版本>、<版本>-<标签>.http 或 wheel 文件名
+func cacheEntryVersion(entry string) string {
+	stem := entry
+	if ext := filepath.Ext(entry); ext == ".http" || ext == ".msgpack" || ext == ".rev" || ext == ".whl" {
+		stem = strings.TrimSuffix(entry, ext)
+	}
+	// 版本号以数字开头，wheel 文件名以包名开头
+	if stem != "" && (stem[0] < '0' || stem[0] > '9') {
+		_, version, _, _ := parseWheel(stem + ".whl")
+		return version
+	}
+	version, _, _ := strings.Cut(stem, "-")
+	return version
+}
+
+// 同步前检查 uv 缓存：上次同步中途失败（或重试）时已下载的包不会重新下载，
+// 把进度显示为从已有的包继续，而不是从头开始
+func (i *Installer) resumeSync(tracker *progress.Tracker) {
+	pkgs, err := ReadLock(filepath.Join(i.ExeDir, "python", "uv.lock"))
+	if err != nil || len(pkgs) == 0 {
+		return
+	}
+	cached := CachedPackages(UVCacheDir(), pkgs)
+	if len(cached) == 0 {
+		return
+	}
+	log.Printf("uv 缓存 %s 中已有 %d/%d 个包", UVCacheDir(), len(cached), len(pkgs))
+	if len(cached) < len(pkgs) {
+		i.printf("%d/%d 个包已下载，继续下载其余的包...", len(cached), len(pkgs))
+	} else {
+		i.printf("%d 个包都已下载，无需重新下载", len(pkgs))
+	}
+	i.events().OnPercent(i.step, tracker.Resume(len(cached), len(pkgs)))
+}
@@ -0,0 +1,92 @@
+package install
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 校验清单文件名，与 sha256sum 输出格式一致：<哈希>  <相对路径>
+const checksumManifest = "checksums.txt"
+
+// 读取校验清单，返回 相对路径 -> SHA-256 的映射
+func loadChecksums(exeDir string) (map[string]string, error) {
+	f, err := os.Open(filepath.Join(exeDir, checksumManifest))
+	if err != nil {
+		return nil, fmt.Errorf("无法读取校验清单: %v", err)
+	}
+	defer f.Close()
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 {
+			return nil, fmt.Errorf("校验清单第 %d 行格式错误: %s", lineNo, line)
+		}
+		// sha256sum 的二进制模式会在路径前加 *
+		path := filepath.ToSlash(strings.TrimPrefix(fields[1], "*"))
+		sums[path] = strings.ToLower(fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取校验清单失败: %v", err)
+	}
+	return sums, nil
+}
+
+// 计算文件的 SHA-256
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// 校验 subDir 目录下清单中列出的所有安装文件
+func VerifyArtifacts(exeDir, subDir string) error {
+	sums, err := loadChecksums(exeDir)
+	if err != nil {
+		return err
+	}
+
+	prefix := filepath.ToSlash(subDir) + "/"
+	checked := 0
+	for path, want := range sums {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		got, err := fileSHA256(filepath.Join(exeDir, filepath.FromSlash(path)))
+		if err != nil {
+			return fmt.Errorf("无法读取 %s: %v", path, err)
+		}
+		if got != want {
+			log.Printf("校验失败: %s, 期望 %s, 实际 %s", path, want, got)
+			return fmt.Errorf("%s 的校验值不匹配，文件可能已损坏或被篡改", path)
+		}
+		checked++
+	}
+	if checked == 0 {
+		return fmt.Errorf("校验清单中没有 %s 目录的条目", subDir)
+	}
+	log.Printf("%s 目录下 %d 个文件校验通过", subDir, checked)
+	return nil
+}
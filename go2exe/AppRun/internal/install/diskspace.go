@@ -0,0 +1,90 @@
+package install
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+// 安装会写入的磁盘：程序目录（.venv）、临时目录以及 uv 的 Python 和缓存目录
+func (i *Installer) installVolumes() []string {
+	var dirs []string
+	if exePath, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(exePath))
+	}
+	dirs = append(dirs, i.TempBase(), os.Getenv("APPDATA"), os.Getenv("LOCALAPPDATA"))
+
+	seen := map[string]bool{}
+	var volumes []string
+	for _, dir := range dirs {
+		vol := filepath.VolumeName(dir)
+		if vol == "" || seen[strings.ToUpper(vol)] {
+			continue
+		}
+		seen[strings.ToUpper(vol)] = true
+		volumes = append(volumes, vol+`\`)
+	}
+	return volumes
+}
+
+// 在安装步骤运行期间监控磁盘空间，空间不足时暂停正在运行的子进程并提示用户，返回停止函数
+func (i *Installer) startDiskMonitor() func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				i.checkDiskSpace()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// 检查一次磁盘空间，不足时暂停安装直到用户释放空间或取消
+func (i *Installer) checkDiskSpace() {
+	threshold := uint64(i.MinFreeSpaceMB) << 20
+	for _, vol := range i.installVolumes() {
+		free, err := FreeDiskSpace(vol)
+		if err != nil || free >= threshold {
+			continue
+		}
+
+		pids := runner.ActiveProcessIDs()
+		log.Printf("磁盘 %s 剩余空间不足: %d MB，暂停安装", vol, free>>20)
+		i.events().OnProgress(i.step, i18n.T("磁盘 %s 剩余空间不足（%d MB），安装已暂停", vol, free>>20))
+		for _, pid := range pids {
+			runner.SuspendTree(uint32(pid))
+		}
+
+		for free < threshold {
+			msg := i18n.T("磁盘 %s 剩余空间不足（剩余 %d MB，至少需要 %d MB）。\n\n"+
+				"请释放一些空间后点击“重试”继续安装，或点击“取消”终止安装。", vol, free>>20, i.MinFreeSpaceMB)
+			if !ui.RetryBox(i18n.T("磁盘空间不足"), msg) {
+				log.Printf("用户在磁盘空间不足时取消了安装")
+				i.events().OnProgress(i.step, i18n.T("安装已取消"))
+				for _, pid := range pids {
+					runner.KillTree(uint32(pid))
+				}
+				return
+			}
+			free, _ = FreeDiskSpace(vol)
+		}
+
+		log.Printf("磁盘 %s 剩余空间已恢复到 %d MB，继续安装", vol, free>>20)
+		i.events().OnProgress(i.step, i18n.T("磁盘空间已释放，继续安装..."))
+		for _, pid := range pids {
+			runner.ResumeTree(uint32(pid))
+		}
+	}
+}
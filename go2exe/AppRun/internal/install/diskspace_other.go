@@ -0,0 +1,14 @@
+//go:build !windows
+
+package install
+
+import "syscall"
+
+// 返回 path 所在文件系统对当前用户可用的剩余空间（字节）
+func FreeDiskSpace(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
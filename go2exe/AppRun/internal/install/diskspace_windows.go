@@ -0,0 +1,19 @@
+package install
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = kernel32.NewProc("GetDiskFreeSpaceExW")
+
+// 返回 path 所在磁盘对当前用户可用的剩余空间（字节）
+func FreeDiskSpace(path string) (uint64, error) {
+	pathPtr, _ := syscall.UTF16PtrFromString(path)
+	var free uint64
+	r, _, err := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(&free)), 0, 0)
+	if r == 0 {
+		return 0, err
+	}
+	return free, nil
+}
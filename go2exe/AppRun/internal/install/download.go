@@ -0,0 +1,151 @@
+package install
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go2exe/internal/models"
+	"go2exe/internal/progress"
+	"go2exe/internal/runner"
+	"go2exe/internal/timeline"
+)
+
+// 下载 dir 中缺少的文件（模型文件、应用更新包）并显示进度，最多同时下载 MaxDownloads 个文件。
+// 有 IPFS 内容标识的文件从 URL 下载失败时改从 Gateways 下载；都失败时按 Retry 重试，从断开的位置继续下载
+func (i *Installer) DownloadFiles(dir string, files []models.File) error {
+	missing := models.Missing(dir, files)
+	if len(missing) == 0 {
+		return nil
+	}
+	parent := i.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	// 一个文件下载失败时停止其他文件，已下载的部分下次继续
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	p := &downloadProgress{i: i, start: time.Now(), files: make([]fileProgress, len(missing)), percent: -1}
+	queue := make(chan int)
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		first   error
+		failed  models.File
+	)
+	for range min(max(i.MaxDownloads, 1), len(missing)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range queue {
+				f := missing[n]
+				i.printf("正在下载 %s（%d/%d）...", f.Path, n+1, len(missing))
+				span := i.Timeline.Begin(timeline.KindDownload, f.Path)
+				resumed := int64(-1) // 断点续传时已有的字节数，不计入本次下载的大小
+				var err error
+				var used models.File
+				for k, src := range f.Sources(i.Gateways, i.PreferGateway) {
+					if k > 0 {
+						i.printf("下载 %s 失败（%v），改从 %s 下载", f.Path, err, src.URL)
+					}
+					err = models.Download(ctx, nil, dir, src, func(done, total int64) {
+						if resumed < 0 {
+							resumed = done
+						}
+						span.SetBytes(done - resumed)
+						p.update(n, done, total)
+					})
+					used = src
+					if err == nil || ctx.Err() != nil {
+						break
+					}
+				}
+				span.End(err)
+				if err != nil {
+					errOnce.Do(func() { first, failed = err, f; cancel() })
+					continue
+				}
+				p.finish(n)
+				i.recordModel(f, used)
+			}
+		}()
+	}
+	for n := range missing {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- n
+	}
+	close(queue)
+	wg.Wait()
+
+	if first != nil {
+		if parent.Err() != nil {
+			return runner.ErrCanceled
+		}
+		// 交给 withRetry 判断是否为网络问题
+		i.recordOutput(first.Error())
+		i.printf("下载 %s 失败: %v", failed.Path, first)
+		return first
+	}
+	i.printf("下载完成")
+	return nil
+}
+
+// 一个文件的下载进度
+type fileProgress struct {
+	first, done, total int64 // first 为第一次报告时已有的字节数（断点续传），总大小未知时 total 为 0
+	started, finished  bool
+}
+
+// 同时下载的所有文件的总进度
+type downloadProgress struct {
+	i       *Installer
+	start   time.Time
+	mu      sync.Mutex
+	files   []fileProgress
+	percent int // 上次报告的百分比，没有变化时不再报告
+}
+
+func (p *downloadProgress) update(n int, done, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f := &p.files[n]
+	if !f.started {
+		f.first, f.started = done, true
+	}
+	f.done, f.total = done, total
+	p.report()
+}
+
+func (p *downloadProgress) finish(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.files[n].finished = true
+	p.report()
+}
+
+// 按已知大小的文件计算百分比，按本次下载的速度估算剩余时间，需持有 mu
+func (p *downloadProgress) report() {
+	status := progress.Status{Percent: -1, Total: len(p.files), ETA: -1}
+	var first, done, total int64
+	for _, f := range p.files {
+		if f.finished {
+			status.Done++
+		}
+		if f.total > 0 {
+			first, done, total = first+f.first, done+f.done, total+f.total
+		}
+	}
+	if total > 0 {
+		status.Percent = int(done * 100 / total)
+		if elapsed := time.Since(p.start); done > first && elapsed > time.Second {
+			status.ETA = time.Duration(float64(elapsed) * float64(total-done) / float64(done-first))
+		}
+	}
+	if status.Percent != p.percent {
+		p.percent = status.Percent
+		p.i.events().OnPercent(p.i.step, status)
+	}
+}
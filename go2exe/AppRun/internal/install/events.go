@@ -0,0 +1,91 @@
+package install
+
+import (
+	"log"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/progress"
+	"go2exe/internal/ui"
+)
+
+// 安装过程中的事件。把安装器嵌入其他程序（图形界面、企业部署工具等）时实现这个接口，
+// 用自己的界面展示进度；所有消息都已按当前界面语言翻译
+type Events interface {
+	// 开始执行一个安装步骤，step 为 RunStep 的 name
+	OnStepStart(step string)
+	// 安装器的状态提示，例如“正在安装 Python 3.11.9，使用本地镜像: ...”；不在安装步骤中时 step 为空
+	OnProgress(step, message string)
+	// 外部命令（安装脚本、uv）输出的一行
+	OnOutputLine(step, line string, isError bool)
+	// 从外部命令的输出中解析出的进度（包的数量、下载百分比和剩余时间）有变化
+	OnPercent(step string, status progress.Status)
+	// 安装步骤最终失败（包括网络错误重试和睡眠后重试仍失败）
+	OnError(step string, err error)
+}
+
+// 忽略所有事件。嵌入到自己的类型中，只需实现关心的方法
+type NopEvents struct{}
+
+func (NopEvents) OnStepStart(string)                {}
+func (NopEvents) OnProgress(string, string)         {}
+func (NopEvents) OnOutputLine(string, string, bool) {}
+func (NopEvents) OnPercent(string, progress.Status) {}
+func (NopEvents) OnError(string, error)             {}
+
+// 未设置 Events 时使用：把提示和命令输出写到 Output，与安装进度控制台的显示一致
+type outputEvents struct {
+	NopEvents
+	out ui.Output
+}
+
+func (e outputEvents) OnProgress(step, message string) {
+	e.out.Line(message)
+}
+
+func (e outputEvents) OnOutputLine(step, line string, isError bool) {
+	prefix := "INFO: "
+	if isError {
+		prefix = "ERROR: "
+	}
+	e.out.Line(prefix + line)
+}
+
+func (e outputEvents) OnPercent(step string, status progress.Status) {
+	if p, ok := e.out.(ui.ProgressOutput); ok {
+		p.Progress(status)
+	}
+}
+
+func (i *Installer) events() Events {
+	if i.Events != nil {
+		return i.Events
+	}
+	return outputEvents{out: i.Out}
+}
+
+// 记录日志并发送状态提示
+func (i *Installer) printf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+	i.events().OnProgress(i.step, i18n.T(format, args...))
+}
+
+// 返回处理外部命令输出的回调：每行写入日志并发送 OnOutputLine，进度有变化时发送 OnPercent
+func (i *Installer) commandOutput() func(line string, isError bool) {
+	return i.trackOutput(progress.New())
+}
+
+// 与 commandOutput 相同，但使用已有的进度跟踪器
+func (i *Installer) trackOutput(tracker *progress.Tracker) func(line string, isError bool) {
+	return func(line string, isError bool) {
+		prefix := "INFO: "
+		if isError {
+			prefix = "ERROR: "
+		}
+		log.Println(prefix + line)
+		i.recordOutput(line)
+		i.events().OnOutputLine(i.step, line, isError)
+		if status, changed := tracker.Feed(line); changed {
+			i.events().OnPercent(i.step, status)
+		}
+	}
+}
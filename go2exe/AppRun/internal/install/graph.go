@@ -0,0 +1,176 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"go2exe/internal/progress"
+)
+
+// 前置步骤失败或被取消，步骤没有执行
+var ErrSkipped = errors.New("前置步骤没有成功完成，未执行")
+
+// 依赖关系图中的一个步骤
+type Task struct {
+	Name  string                   // 步骤名，用于 After 和结果
+	After []string                 // 必须先成功完成的步骤
+	Run   func(i *Installer) error // 执行步骤，通常调用 i.RunStep；每个步骤使用自己的安装器
+}
+
+// 按依赖关系执行 tasks：一个步骤的前置步骤都成功后开始，相互没有依赖的分支同时执行。
+// 步骤失败时只跳过依赖它的步骤（结果为包装了 ErrSkipped 的错误），其他分支照常执行。
+// 最多同时执行 limit 个步骤，0 表示不限制。newInstaller 为每个步骤创建安装器，同时执行的步骤共用一个进度条，显示所有步骤的总进度。
+// 返回每个步骤的结果；步骤名重复、依赖不存在的步骤或有循环依赖时不执行任何步骤，返回错误
+func RunGraph(tasks []Task, limit int, newInstaller func() *Installer) (map[string]error, error) {
+	if err := checkGraph(tasks); err != nil {
+		return nil, err
+	}
+	done := map[string]chan struct{}{}
+	for _, t := range tasks {
+		done[t.Name] = make(chan struct{})
+	}
+	var mu sync.Mutex
+	results := map[string]error{}
+	combined := &graphProgress{total: len(tasks), percent: map[string]progress.Status{}}
+	if limit <= 0 {
+		limit = len(tasks)
+	}
+	slots := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for _, t := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[t.Name])
+			var err error
+			for _, dep := range t.After {
+				<-done[dep]
+				mu.Lock()
+				depErr := results[dep]
+				mu.Unlock()
+				if depErr != nil && err == nil {
+					err = fmt.Errorf("%s: %w（%s）", t.Name, ErrSkipped, dep)
+				}
+			}
+			if err == nil {
+				slots <- struct{}{}
+				inst := newInstaller()
+				inst.Events = taskEvents{Events: inst.events(), graph: combined, task: t.Name}
+				err = t.Run(inst)
+				<-slots
+			}
+			if err != nil {
+				log.Printf("步骤 %s 未完成: %v", t.Name, err)
+			}
+			combined.finish(t.Name)
+			mu.Lock()
+			results[t.Name] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// 检查步骤名是否唯一、依赖的步骤是否存在以及是否有循环依赖
+func checkGraph(tasks []Task) error {
+	after := map[string][]string{}
+	for _, t := range tasks {
+		if _, dup := after[t.Name]; dup {
+			return fmt.Errorf("步骤 %s 重复", t.Name)
+		}
+		after[t.Name] = t.After
+	}
+	for _, t := range tasks {
+		for _, dep := range t.After {
+			if _, ok := after[dep]; !ok {
+				return fmt.Errorf("步骤 %s 依赖的 %s 不存在", t.Name, dep)
+			}
+		}
+	}
+	// 深度优先遍历，遇到正在访问的步骤说明有循环
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("步骤之间有循环依赖: %s -> %s", strings.Join(path, " -> "), name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range after[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, t := range tasks {
+		if err := visit(t.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 所有步骤的总进度：每个步骤占相同的份额，已结束的步骤按 100% 计算
+type graphProgress struct {
+	mu      sync.Mutex
+	total   int
+	percent map[string]progress.Status
+}
+
+// 更新一个步骤的进度，返回总进度
+func (g *graphProgress) update(task string, s progress.Status) progress.Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.percent[task] = s
+	return g.status()
+}
+
+// 步骤结束（成功、失败或跳过）
+func (g *graphProgress) finish(task string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s := g.percent[task]
+	s.Percent, s.ETA = 100, 0
+	g.percent[task] = s
+}
+
+func (g *graphProgress) status() progress.Status {
+	combined := progress.Status{ETA: -1}
+	sum := 0
+	for _, s := range g.percent {
+		sum += max(s.Percent, 0)
+		combined.Done += s.Done
+		combined.Total += s.Total
+		// 同时执行的步骤中最慢的一个决定剩余时间
+		combined.ETA = max(combined.ETA, s.ETA)
+	}
+	combined.Percent = sum / g.total
+	return combined
+}
+
+// 发给一个步骤的安装器的事件：进度换成所有步骤的总进度，其他事件原样转发
+type taskEvents struct {
+	Events
+	graph *graphProgress
+	task  string
+}
+
+func (e taskEvents) OnPercent(step string, status progress.Status) {
+	e.Events.OnPercent(step, e.graph.update(e.task, status))
+}
@@ -0,0 +1,318 @@
+// Package install 使用随程序分发的安装文件离线安装 uv、Python，并同步应用依赖
+package install
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/mirror"
+	"go2exe/internal/progress"
+	"go2exe/internal/provenance"
+	"go2exe/internal/runner"
+	"go2exe/internal/signature"
+	"go2exe/internal/timeline"
+	"go2exe/internal/ui"
+)
+
+// 安装器，ExeDir 为程序所在目录（包含 uv 和 python 子目录）
+type Installer struct {
+	ExeDir         string
+	Runner         runner.CommandRunner
+	Out            ui.Output          // 进度输出，设置了 Events 时不使用
+	Events         Events             // 安装事件，为 nil 时把进度写到 Out
+	MinFreeSpaceMB int64              // 安装过程中磁盘剩余空间低于该值（MB）时暂停安装
+	TempDir        string             // 解压等临时文件的存放位置，留空使用系统临时目录
+	UVDir          string             // InstallUV 后为安装脚本报告的 uv 安装目录，未报告时为空
+	UVInstallDir   string             // 配置的 uv 安装目录（传给安装脚本的 UV_INSTALL_DIR），不经过安装脚本安装时使用
+	Context        context.Context    // 取消后结束正在执行的命令，后续步骤不再执行；为 nil 时不可取消
+	StepTimeout    time.Duration      // 每个安装命令的最长执行时间，超时后结束整个进程树；0 表示不限制
+	Python         string             // 传给 uv python install 的版本请求（见 envcheck.InstallRequest），为空时为 envcheck.DefaultPython
+	Index          string             // uv sync 使用的 PyPI 镜像地址，为空时使用 mirror.Default
+	WheelsDir      string             // 离线安装包目录（见 OfflineWheelsDir），不为空时 uv sync 只从其中安装，不访问网络
+	Arch           string             // Python 的架构（x86_64、x86 或 aarch64），用于判断离线安装包是否适用
+	Retry          RetryPolicy        // 安装步骤因网络问题失败时的重试策略，零值表示不重试
+	Extras         []string           // uv sync 时启用的 pyproject.toml 中的 extra，例如按显卡驱动选出的 cu121
+	MaxDownloads   int                // DownloadFiles 最多同时下载的文件数，0 或 1 表示逐个下载
+	Gateways       []string           // DownloadFiles 下载有 CID 的文件时使用的 IPFS 网关，例如 https://ipfs.io
+	PreferGateway  bool               // 先从 IPFS 网关下载，失败时再用文件的 URL
+	UVConcurrency  UVConcurrency      // uv 的并发设置，零值使用 uv 的默认值
+	Timeline       *timeline.Recorder // 记录安装步骤、下载和重试的时间线，为 nil 时不记录
+	Signature      signature.Policy   // 执行下载的安装文件前校验数字签名的策略
+	Provenance     *provenance.Log    // 记录每个安装内容的来源和哈希，为 nil 时不记录
+	Unattended     bool               // 无人值守：被杀毒软件拦截时不提示用户处理，直接返回 *InterferenceError
+
+	// 下载和检查网络时使用的代理，为 nil 时按本进程的环境变量（http.ProxyFromEnvironment）
+	Proxy func(*http.Request) (*url.URL, error)
+
+	staging string   // 当前安装步骤的暂存目录
+	step    string   // 当前安装步骤的名称
+	watched []string // 当前安装步骤写入或运行的文件，失败时检查是否被杀毒软件隔离
+
+	outputMu sync.Mutex
+	recent   []string // 本次尝试中命令输出的最后几行，用于判断失败是否由网络引起
+
+	prefetchMu sync.Mutex
+	prefetches map[string]*prefetchTask // 正在后台执行或已完成、还没有被 Await 取走的预取任务
+}
+
+// uv 同时执行的下载、构建和安装数量，对应 UV_CONCURRENT_DOWNLOADS、UV_CONCURRENT_BUILDS 和 UV_CONCURRENT_INSTALLS，
+// 0 表示使用 uv 的默认值
+type UVConcurrency struct {
+	Downloads int
+	Builds    int
+	Installs  int
+}
+
+// uv 命令的环境变量：暂存目录和并发设置
+func (i *Installer) uvEnv() []string {
+	env := i.stagingEnv()
+	for _, v := range []struct {
+		name  string
+		value int
+	}{
+		{"UV_CONCURRENT_DOWNLOADS", i.UVConcurrency.Downloads},
+		{"UV_CONCURRENT_BUILDS", i.UVConcurrency.Builds},
+		{"UV_CONCURRENT_INSTALLS", i.UVConcurrency.Installs},
+	} {
+		if v.value > 0 {
+			env = append(env, fmt.Sprintf("%s=%d", v.name, v.value))
+		}
+	}
+	return env
+}
+
+// uv sync 启用 Extras 的参数
+func (i *Installer) extraArgs() []string {
+	var args []string
+	for _, extra := range i.Extras {
+		args = append(args, "--extra", extra)
+	}
+	return args
+}
+
+// 安装uv
+func (i *Installer) InstallUV() error {
+	// 不在安装步骤中时没有暂存目录，内置安装文件需要自己的临时目录
+	if i.staging == "" {
+		cleanup, err := i.beginStaging()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+	}
+	uvDir, err := i.uvSourceDir(i.staging)
+	downloaded := false
+	if err != nil {
+		i.printf("UV 安装失败: %v", err)
+		return err
+	}
+	platform := envcheck.Current
+	script := filepath.Join(uvDir, platform.UVScript)
+	env := i.stagingEnv()
+	// 安装脚本是否从网上下载 uv 的程序：下载的官方脚本，或者 Windows 上 uv 目录中只有脚本、没有安装包
+	onlineBinaries := false
+	if _, err := os.Stat(script); err == nil && platform.OS == envcheck.Windows.OS && uvArchive(uvDir) == "" {
+		onlineBinaries = true
+		i.printf("正在安装 UV，没有随程序分发的安装包，由安装脚本下载")
+	} else if err == nil {
+		// 通过安装脚本的环境变量 INSTALLER_DOWNLOAD_URL 使用本地的安装包。Linux 的安装脚本用 curl 下载，本地目录需要写成 file:// 地址
+		downloadURL := uvDir
+		if platform.OS != envcheck.Windows.OS {
+			downloadURL = "file://" + uvDir
+		}
+		env = append(env, "INSTALLER_DOWNLOAD_URL="+downloadURL)
+		i.printf("正在安装 UV，使用本地路径: %s", uvDir)
+	} else {
+		// 没有随程序分发当前平台的安装文件（内置的只有 Windows 版），先下载 uv 的官方安装脚本
+		i.printf("没有随程序分发的 UV 安装文件，从 %s 下载", onlineUVInstaller)
+		if err := i.downloadUVInstaller(script); err != nil {
+			i.printf("UV 安装失败: %v", err)
+			return err
+		}
+		downloaded = true
+		onlineBinaries = true
+	}
+
+	i.watchFiles(script, uvArchive(uvDir))
+	if !downloaded {
+		i.unblockFiles(script, uvArchive(uvDir))
+	}
+
+	// Windows PowerShell 版本太低时改用 PowerShell 7 或直接解压安装包
+	shell, native, err := i.uvInstallerShell(uvDir, script)
+	if err == nil && native {
+		err = i.installUVNative(uvDir)
+	}
+	if err != nil {
+		i.printf("UV 安装失败: %v", err)
+		return err
+	}
+	if native {
+		if err := i.checkUVInstalled(); err != nil {
+			i.printf("UV 安装失败: %v", err)
+			return err
+		}
+		i.printf("UV 安装成功！")
+		i.recordUV(uvDir, script, downloaded)
+		return nil
+	}
+
+	// 执行 uv-installer.ps1（Linux 上为 uv-installer.sh）脚本，实时处理输出，并记下脚本报告的安装目录（"installing to <目录>"）
+	output := i.commandOutput()
+	err = i.Runner.Stream(runner.Command{
+		Name:       shell[0],
+		Args:       append(slices.Clone(shell[1:]), script),
+		Env:        env,
+		HideWindow: true,
+		Context:    i.Context,
+		Timeout:    i.StepTimeout,
+	}, func(line string, isError bool) {
+		if dir, ok := strings.CutPrefix(strings.TrimSpace(line), "installing to "); ok {
+			i.UVDir = dir
+		}
+		output(line, isError)
+	})
+	if err == nil {
+		err = i.checkUVInstalled()
+	}
+	if err == nil && onlineBinaries {
+		err = i.checkUVSignatures()
+	}
+	if err != nil {
+		i.printf("UV 安装失败: %v", err)
+	} else {
+		i.printf("UV 安装成功！")
+		i.recordUV(uvDir, script, downloaded)
+	}
+	return err
+}
+
+// 安装 Python，优先使用 python 目录中随程序分发的安装包
+func (i *Installer) InstallPython() error {
+	version := i.Python
+	if version == "" {
+		version = envcheck.DefaultPython
+	}
+	args := []string{"python", "install", version}
+	localMirror := "file:///" + strings.TrimPrefix(filepath.Join(i.ExeDir, "python"), "/")
+	if arch := envcheck.ResolveArch(i.Arch); i.bundlesOtherArch(arch) {
+		// 随程序分发的只有其他架构的安装包（例如在 ARM64 设备上运行 x86_64 的安装包），从 uv 的默认下载源安装
+		i.printf("正在安装 Python %s，随程序分发的安装包中没有 %s 架构的版本，将从网络下载", version, arch)
+		localMirror = ""
+	} else {
+		args = append(args, "--mirror", localMirror)
+		i.printf("正在安装 Python %s，使用本地镜像: %s", version, localMirror)
+		i.watchFiles(i.bundledPython(version))
+	}
+
+	// 实时处理输出
+	err := i.Runner.Stream(runner.Command{
+		Name:       "uv",
+		Args:       args,
+		Env:        i.uvEnv(),
+		HideWindow: true,
+		Context:    i.Context,
+		Timeout:    i.StepTimeout,
+	}, i.commandOutput())
+	if err != nil {
+		i.printf("Python 安装失败: %v", err)
+	} else {
+		i.printf("Python %s 安装成功！", version)
+		i.recordPython(version, localMirror)
+	}
+	return err
+}
+
+// 本地镜像中 version 对应的本机架构的 Python 安装包，没有时返回空。同一版本有多个发布日期时 uv 使用最新的
+func (i *Installer) bundledPython(version string) string {
+	triple := envcheck.Current.Triple(envcheck.ResolveArch(i.Arch))
+	files, _ := filepath.Glob(filepath.Join(i.ExeDir, "python", "*", "cpython-"+version+"*-"+triple+"-*"))
+	if len(files) == 0 {
+		return ""
+	}
+	sort.Strings(files)
+	return files[len(files)-1]
+}
+
+// 本地镜像（ExeDir 下的 python 目录中按发布日期分的子目录）中有 Python 安装包，但都不是 arch 架构的
+func (i *Installer) bundlesOtherArch(arch string) bool {
+	files, _ := filepath.Glob(filepath.Join(i.ExeDir, "python", "*", "cpython-*"))
+	if len(files) == 0 {
+		return false
+	}
+	for _, f := range files {
+		if strings.Contains(filepath.Base(f), "-"+envcheck.Current.Triple(arch)+"-") {
+			return false
+		}
+	}
+	return true
+}
+
+// 同步依赖使用的 PyPI 镜像地址
+func (i *Installer) index() string {
+	if i.Index == "" {
+		return mirror.Default.URL
+	}
+	return i.Index
+}
+
+// 下载使用的代理
+func (i *Installer) proxy() func(*http.Request) (*url.URL, error) {
+	if i.Proxy == nil {
+		return http.ProxyFromEnvironment
+	}
+	return i.Proxy
+}
+
+// 在 ExeDir 下的 python 目录中执行 uv sync
+func (i *Installer) Sync() error {
+	args := []string{"sync", "--default-index", i.index()}
+	if i.WheelsDir != "" {
+		// 离线安装包按 uv.lock 准备，不再检查锁文件是否需要更新（那需要访问镜像）
+		i.printf("正在执行 uv sync，只使用离线安装包目录 %s...", i.WheelsDir)
+		args = []string{"sync", "--offline", "--frozen", "--find-links", i.WheelsDir}
+	} else {
+		i.printf("正在执行 uv sync，使用 PyPI 镜像 %s...", i.index())
+	}
+	args = append(args, i.extraArgs()...)
+	tracker := progress.New()
+	if i.WheelsDir == "" {
+		i.resumeSync(tracker)
+	}
+	// 同步前已在缓存中的包，用于记录来源
+	pkgs, _ := ReadLock(filepath.Join(i.ExeDir, "python", "uv.lock"))
+	cached := map[string]bool{}
+	if i.Provenance != nil && i.WheelsDir == "" {
+		for _, p := range CachedPackages(UVCacheDir(), pkgs) {
+			cached[normalizeName(p.Name)+"=="+p.Version] = true
+		}
+	}
+
+	// 实时处理输出
+	err := i.Runner.Stream(runner.Command{
+		Name:       "uv",
+		Args:       args,
+		Env:        i.uvEnv(),
+		Dir:        filepath.Join(i.ExeDir, "python"),
+		HideWindow: true,
+		Context:    i.Context,
+		Timeout:    i.StepTimeout,
+	}, i.trackOutput(tracker))
+	if err != nil {
+		i.printf("uv sync 配置失败: %v", err)
+	} else {
+		i.printf("uv sync 配置成功！")
+		i.recordPackages(pkgs, cached)
+	}
+	return err
+}
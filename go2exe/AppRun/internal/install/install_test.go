@@ -0,0 +1,988 @@
+package install
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/models"
+	"go2exe/internal/provenance"
+	"go2exe/internal/runner"
+	"go2exe/internal/signature"
+	"go2exe/internal/ui"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// 使用模拟执行器的安装器
+func newTestInstaller(t *testing.T) (*Installer, *runner.Mock) {
+	m := &runner.Mock{}
+	return &Installer{ExeDir: t.TempDir(), Runner: m, Out: ui.Discard}, m
+}
+
+// 命令的环境变量 key 的值
+func commandEnv(c runner.Command, key string) string {
+	for _, kv := range c.Env {
+		if v, ok := strings.CutPrefix(kv, key+"="); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+func TestInstallUV(t *testing.T) {
+	inst, m := newTestInstaller(t)
+	os.MkdirAll(filepath.Join(inst.ExeDir, "uv"), 0755)
+	os.WriteFile(filepath.Join(inst.ExeDir, "uv", "uv-installer.ps1"), nil, 0644)
+	os.WriteFile(filepath.Join(inst.ExeDir, "uv", "uv-x86_64-pc-windows-msvc.zip"), nil, 0644)
+
+	if err := inst.InstallUV(); err != nil {
+		t.Fatalf("InstallUV() = %v", err)
+	}
+	lines := m.CommandLines()
+	if len(lines) != 1 || !strings.Contains(lines[0], filepath.Join(inst.ExeDir, "uv", "uv-installer.ps1")) {
+		t.Errorf("执行的命令 = %v", lines)
+	}
+	if got := commandEnv(m.Calls[0], "INSTALLER_DOWNLOAD_URL"); got != filepath.Join(inst.ExeDir, "uv") {
+		t.Errorf("INSTALLER_DOWNLOAD_URL = %q", got)
+	}
+	// 只传给安装脚本，不修改启动器自己的环境变量
+	if got, ok := os.LookupEnv("INSTALLER_DOWNLOAD_URL"); ok {
+		t.Errorf("启动器的环境变量 INSTALLER_DOWNLOAD_URL 被设置为 %q", got)
+	}
+
+	m.Handler = func(c runner.Command) (string, error) {
+		return "downloading uv 0.6.14 x86_64-pc-windows-msvc\ninstalling to C:\\Users\\me\\.local\\bin\n  uv.exe\n", nil
+	}
+	if err := inst.InstallUV(); err != nil {
+		t.Fatalf("InstallUV() = %v", err)
+	}
+	if inst.UVDir != `C:\Users\me\.local\bin` {
+		t.Errorf("UVDir = %q", inst.UVDir)
+	}
+
+	m.Handler = func(c runner.Command) (string, error) { return "ERROR: download failed\n", errors.New("exit status 1") }
+	if err := inst.InstallUV(); err == nil {
+		t.Errorf("安装脚本失败时应返回错误")
+	}
+}
+
+func TestInstallUVSignature(t *testing.T) {
+	defer func(f func(signature.Policy, string) error) { checkSignature = f }(checkSignature)
+	var checked []string
+	checkSignature = func(p signature.Policy, path string) error {
+		checked = append(checked, filepath.Base(path))
+		if filepath.Base(path) == "uvx.exe" {
+			return &signature.Error{Path: path, Err: errors.New("文件没有数字签名")}
+		}
+		return nil
+	}
+
+	// uv 目录中只有安装脚本时由脚本从网上下载 uv，安装后校验下载的程序
+	inst, m := newTestInstaller(t)
+	os.MkdirAll(filepath.Join(inst.ExeDir, "uv"), 0755)
+	os.WriteFile(filepath.Join(inst.ExeDir, "uv", "uv-installer.ps1"), nil, 0644)
+	bin := t.TempDir()
+	m.Handler = func(c runner.Command) (string, error) {
+		for _, name := range []string{"uv.exe", "uvx.exe"} {
+			os.WriteFile(filepath.Join(bin, name), []byte(name), 0755)
+		}
+		return "installing to " + bin + "\n", nil
+	}
+	err := inst.InstallUV()
+	var serr *signature.Error
+	if !errors.As(err, &serr) {
+		t.Fatalf("InstallUV() = %v, want *signature.Error", err)
+	}
+	if got := commandEnv(m.Calls[0], "INSTALLER_DOWNLOAD_URL"); got != "" {
+		t.Errorf("没有安装包时不应设置 INSTALLER_DOWNLOAD_URL，实际为 %q", got)
+	}
+	if !slices.Equal(checked, []string{"uv.exe", "uvx.exe"}) {
+		t.Errorf("校验签名的文件 = %q", checked)
+	}
+	// 校验不通过的程序不留给之后执行
+	for _, name := range []string{"uv.exe", "uvx.exe"} {
+		if _, err := os.Stat(filepath.Join(bin, name)); !os.IsNotExist(err) {
+			t.Errorf("%s 没有删除", name)
+		}
+	}
+
+	// 使用随程序分发的安装包时不校验
+	checked = nil
+	os.WriteFile(filepath.Join(inst.ExeDir, "uv", "uv-x86_64-pc-windows-msvc.zip"), nil, 0644)
+	if err := inst.InstallUV(); err != nil {
+		t.Fatalf("InstallUV() = %v", err)
+	}
+	if len(checked) != 0 {
+		t.Errorf("随程序分发的安装包不应校验签名: %q", checked)
+	}
+}
+
+func TestInstallUVUnblock(t *testing.T) {
+	defer func(f func(string) (bool, error)) { unblock = f }(unblock)
+	var unblocked []string
+	unblock = func(path string) (bool, error) {
+		unblocked = append(unblocked, filepath.Base(path))
+		return true, nil
+	}
+
+	// 随程序分发的安装脚本和安装包执行前去掉来自网络标记
+	inst, _ := newTestInstaller(t)
+	uvDir := filepath.Join(inst.ExeDir, "uv")
+	os.MkdirAll(uvDir, 0755)
+	os.WriteFile(filepath.Join(uvDir, "uv-installer.ps1"), nil, 0644)
+	os.WriteFile(filepath.Join(uvDir, "uv-x86_64-pc-windows-msvc.zip"), nil, 0644)
+	if err := inst.InstallUV(); err != nil {
+		t.Fatalf("InstallUV() = %v", err)
+	}
+	if !slices.Equal(unblocked, []string{"uv-installer.ps1", "uv-x86_64-pc-windows-msvc.zip"}) {
+		t.Errorf("去掉标记的文件 = %q", unblocked)
+	}
+
+	// 没有安装包时只处理安装脚本
+	unblocked = nil
+	os.Remove(filepath.Join(uvDir, "uv-x86_64-pc-windows-msvc.zip"))
+	defer func(f func(signature.Policy, string) error) { checkSignature = f }(checkSignature)
+	checkSignature = func(signature.Policy, string) error { return nil }
+	if err := inst.InstallUV(); err != nil {
+		t.Fatalf("InstallUV() = %v", err)
+	}
+	if !slices.Equal(unblocked, []string{"uv-installer.ps1"}) {
+		t.Errorf("去掉标记的文件 = %q", unblocked)
+	}
+}
+
+func TestInstallUVLinux(t *testing.T) {
+	envcheck.Current = envcheck.Linux
+	t.Cleanup(func() { envcheck.Current = envcheck.Windows })
+	inst, m := newTestInstaller(t)
+	os.MkdirAll(filepath.Join(inst.ExeDir, "uv"), 0755)
+	os.WriteFile(filepath.Join(inst.ExeDir, "uv", "uv-installer.sh"), nil, 0644)
+
+	if !HasExternalUV(inst.ExeDir) {
+		t.Errorf("HasExternalUV() = false")
+	}
+	if err := inst.InstallUV(); err != nil {
+		t.Fatalf("InstallUV() = %v", err)
+	}
+	lines := m.CommandLines()
+	if len(lines) != 1 || lines[0] != "sh "+filepath.Join(inst.ExeDir, "uv", "uv-installer.sh") {
+		t.Errorf("执行的命令 = %v", lines)
+	}
+	if got := commandEnv(m.Calls[0], "INSTALLER_DOWNLOAD_URL"); got != "file://"+filepath.Join(inst.ExeDir, "uv") {
+		t.Errorf("INSTALLER_DOWNLOAD_URL = %q", got)
+	}
+	if got := VenvPythonW(".venv"); got != filepath.Join(".venv", "bin", "python") {
+		t.Errorf("VenvPythonW() = %q", got)
+	}
+}
+
+func TestInstallUVEmbedded(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("只有 Windows 版内置安装脚本")
+	}
+	defer func(f func(signature.Policy, string) error) { checkSignature = f }(checkSignature)
+	var checked []string
+	checkSignature = func(p signature.Policy, path string) error {
+		checked = append(checked, filepath.Base(path))
+		return nil
+	}
+	inst, m := newTestInstaller(t)
+	inst.TempDir = t.TempDir()
+
+	// 程序目录下没有 uv 目录时，解压内置的安装脚本，由脚本下载 uv 的安装包
+	var script string
+	bin := t.TempDir()
+	m.Handler = func(c runner.Command) (string, error) {
+		if slices.Contains(c.Args, "-Command") {
+			// 内置的安装脚本要求 PowerShell 5
+			return "5\n", nil
+		}
+		script = c.Args[len(c.Args)-1]
+		for _, name := range []string{"uv.exe", "uvx.exe"} {
+			os.WriteFile(filepath.Join(bin, name), []byte(name), 0755)
+		}
+		return "installing to " + bin + "\n", nil
+	}
+	if err := inst.InstallUV(); err != nil {
+		t.Fatalf("InstallUV() = %v", err)
+	}
+	if !strings.HasPrefix(script, inst.TempDir) || filepath.Base(script) != "uv-installer.ps1" {
+		t.Errorf("安装脚本 = %q", script)
+	}
+	if got := commandEnv(m.Calls[len(m.Calls)-1], "INSTALLER_DOWNLOAD_URL"); got != "" {
+		t.Errorf("内置的安装文件没有安装包，不应设置 INSTALLER_DOWNLOAD_URL，实际为 %q", got)
+	}
+	if !slices.Equal(checked, []string{"uv.exe", "uvx.exe"}) {
+		t.Errorf("校验签名的文件 = %q", checked)
+	}
+	// 安装结束后删除解压的文件
+	if _, err := os.Stat(script); !os.IsNotExist(err) {
+		t.Errorf("解压的安装文件未删除")
+	}
+}
+
+func TestInstallUVOldPowerShell(t *testing.T) {
+	inst, m := newTestInstaller(t)
+	uvDir := filepath.Join(inst.ExeDir, "uv")
+	os.MkdirAll(uvDir, 0755)
+	os.WriteFile(filepath.Join(uvDir, "uv-installer.ps1"), []byte("#Requires -Version 7\n"), 0644)
+	f, _ := os.Create(filepath.Join(uvDir, "uv-x86_64-pc-windows-msvc.zip"))
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"uv.exe", "uvx.exe"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(name))
+	}
+	zw.Close()
+	f.Close()
+
+	// 有 PowerShell 7 时用它执行安装脚本
+	pwsh := "7"
+	m.Handler = func(c runner.Command) (string, error) {
+		switch {
+		case c.Name == "powershell" && slices.Contains(c.Args, "-Command"):
+			return "5\n", nil
+		case c.Name == "pwsh" && slices.Contains(c.Args, "-Command"):
+			if pwsh == "" {
+				return "", &exec.Error{Name: "pwsh", Err: exec.ErrNotFound}
+			}
+			return pwsh + "\n", nil
+		}
+		return "", nil
+	}
+	if err := inst.InstallUV(); err != nil {
+		t.Fatalf("InstallUV() = %v", err)
+	}
+	lines := m.CommandLines()
+	if last := lines[len(lines)-1]; !strings.HasPrefix(last, "pwsh -ExecutionPolicy ByPass -File ") {
+		t.Errorf("执行的命令 = %v", lines)
+	}
+
+	// 没有 PowerShell 7 时直接解压安装包，不执行安装脚本
+	pwsh = ""
+	m.Calls = nil
+	inst.UVInstallDir = filepath.Join(t.TempDir(), "uv")
+	if err := inst.InstallUV(); err != nil {
+		t.Fatalf("InstallUV() = %v", err)
+	}
+	for _, line := range m.CommandLines() {
+		if strings.Contains(line, "uv-installer.ps1") {
+			t.Errorf("不应执行安装脚本: %s", line)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(inst.UVInstallDir, "uvx.exe")); err != nil || string(data) != "uvx.exe" {
+		t.Errorf("uvx.exe = %q, %v", data, err)
+	}
+	if inst.UVDir != inst.UVInstallDir {
+		t.Errorf("UVDir = %q", inst.UVDir)
+	}
+
+	// 也没有安装包时报告需要的版本
+	os.Remove(filepath.Join(uvDir, "uv-x86_64-pc-windows-msvc.zip"))
+	if err := inst.InstallUV(); err == nil || !strings.Contains(err.Error(), "PowerShell 7") {
+		t.Errorf("InstallUV() = %v", err)
+	}
+}
+
+func TestInstallPython(t *testing.T) {
+	inst, m := newTestInstaller(t)
+
+	if err := inst.InstallPython(); err != nil {
+		t.Fatalf("InstallPython() = %v", err)
+	}
+	lines := m.CommandLines()
+	if len(lines) != 1 || !strings.Contains(lines[0], "uv python install 3.11.9 --mirror file:///"+strings.TrimPrefix(filepath.Join(inst.ExeDir, "python"), "/")) {
+		t.Errorf("执行的命令 = %v", lines)
+	}
+
+	m.Handler = func(c runner.Command) (string, error) { return "", errors.New("exit status 2") }
+	if err := inst.InstallPython(); err == nil {
+		t.Errorf("安装失败时应返回错误")
+	}
+}
+
+func TestInstallPythonArch(t *testing.T) {
+	inst, m := newTestInstaller(t)
+	dir := filepath.Join(inst.ExeDir, "python", "20240814")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "cpython-3.11.9+20240814-x86_64-pc-windows-msvc-install_only_stripped.tar.gz"), nil, 0644)
+
+	// 有对应架构的安装包时使用本地镜像，只有其他架构的安装包时从网络下载
+	for arch, local := range map[string]bool{"x86_64": true, "aarch64": false} {
+		m.Calls = nil
+		inst.Arch = arch
+		if err := inst.InstallPython(); err != nil {
+			t.Fatalf("InstallPython(%s) = %v", arch, err)
+		}
+		if lines := m.CommandLines(); len(lines) != 1 || strings.Contains(lines[0], "--mirror") != local {
+			t.Errorf("%s: 执行的命令 = %v", arch, lines)
+		}
+	}
+}
+
+func TestRunStepStaging(t *testing.T) {
+	inst, m := newTestInstaller(t)
+	inst.TempDir = t.TempDir()
+	// 模拟上次崩溃遗留的暂存目录
+	stale := filepath.Join(inst.TempDir, stagingPrefix+"old")
+	os.Mkdir(stale, 0755)
+	inst.CleanStaging()
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("遗留的暂存目录未被清理")
+	}
+
+	var staging string
+	m.Handler = func(c runner.Command) (string, error) {
+		for _, e := range c.Env {
+			if strings.HasPrefix(e, "TEMP=") {
+				staging = strings.TrimPrefix(e, "TEMP=")
+			}
+		}
+		return "", errors.New("exit status 1")
+	}
+	if err := inst.RunStep("安装 Python", inst.InstallPython); err == nil {
+		t.Fatalf("安装失败时应返回错误")
+	}
+	if filepath.Dir(staging) != inst.TempDir {
+		t.Errorf("TEMP = %q，应位于 %s 下", staging, inst.TempDir)
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Errorf("失败后暂存目录 %s 未被删除", staging)
+	}
+}
+
+// 记录收到的事件
+type recordEvents struct {
+	NopEvents
+	events []string
+}
+
+func (r *recordEvents) OnStepStart(step string) {
+	r.events = append(r.events, "start "+step)
+}
+
+func (r *recordEvents) OnOutputLine(step, line string, isError bool) {
+	r.events = append(r.events, step+": "+line)
+}
+
+func (r *recordEvents) OnError(step string, err error) {
+	r.events = append(r.events, "error "+step+": "+err.Error())
+}
+
+func TestEvents(t *testing.T) {
+	inst, m := newTestInstaller(t)
+	inst.TempDir = t.TempDir()
+	rec := &recordEvents{}
+	inst.Events = rec
+
+	m.Handler = func(c runner.Command) (string, error) { return "Resolved 12 packages\n", errors.New("exit status 1") }
+	if err := inst.RunStep("同步依赖", inst.Sync); err == nil {
+		t.Fatalf("RunStep() 应返回错误")
+	}
+	want := []string{"start 同步依赖", "同步依赖: Resolved 12 packages", "error 同步依赖: exit status 1"}
+	if strings.Join(rec.events, "\n") != strings.Join(want, "\n") {
+		t.Errorf("收到的事件 = %q, want %q", rec.events, want)
+	}
+}
+
+func TestRunStepRetry(t *testing.T) {
+	inst, m := newTestInstaller(t)
+	inst.TempDir = t.TempDir()
+	inst.Retry = RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+	// 网络错误重试后成功
+	m.Handler = func(c runner.Command) (string, error) {
+		if len(m.Calls) == 1 {
+			return "error: Failed to fetch: `https://pypi.tuna.tsinghua.edu.cn/simple/numpy/`\n  Caused by: connection reset by peer\n", errors.New("exit status 2")
+		}
+		return "", nil
+	}
+	if err := inst.RunStep("同步依赖", inst.Sync); err != nil {
+		t.Fatalf("RunStep() = %v", err)
+	}
+	if len(m.Calls) != 2 {
+		t.Errorf("执行了 %d 次，want 2", len(m.Calls))
+	}
+
+	// 网络错误一直存在时最多执行 Attempts 次
+	m.Calls = nil
+	m.Handler = func(c runner.Command) (string, error) {
+		return "dns error: failed to lookup address\n", errors.New("exit status 2")
+	}
+	if err := inst.RunStep("同步依赖", inst.Sync); err == nil {
+		t.Fatalf("RunStep() 应返回错误")
+	}
+	if len(m.Calls) != 3 {
+		t.Errorf("执行了 %d 次，want 3", len(m.Calls))
+	}
+
+	// 其他错误不重试
+	m.Calls = nil
+	m.Handler = func(c runner.Command) (string, error) {
+		return "error: No `project` table found\n", errors.New("exit status 2")
+	}
+	if err := inst.RunStep("同步依赖", inst.Sync); err == nil {
+		t.Fatalf("RunStep() 应返回错误")
+	}
+	if len(m.Calls) != 1 {
+		t.Errorf("执行了 %d 次，want 1", len(m.Calls))
+	}
+}
+
+func TestInterference(t *testing.T) {
+	cases := map[string]string{
+		"Access is denied. (os error 5)\nThe file may have been quarantined by antivirus software.":                                                                                 InterferenceQuarantine,
+		"error: failed to spawn: `uv.exe`\n  Caused by: Operation did not complete successfully because the file contains a virus or potentially unwanted software. (os error 225)": InterferenceQuarantine,
+		"File C:\\app\\uv\\uv-installer.ps1 cannot be loaded. The file is not digitally signed.":                                                                                    InterferenceMOTW,
+		"error: failed to remove file `.venv\\Lib\\site-packages\\numpy\\core.pyd`: Access is denied. (os error 5)":                                                                 InterferenceAccess,
+		"error: Failed to fetch: connection reset by peer":                                                                                                                          "",
+	}
+	for output, want := range cases {
+		if got := Interference(output); got != want {
+			t.Errorf("Interference(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+func TestRunStepInterference(t *testing.T) {
+	inst, m := newTestInstaller(t)
+	inst.TempDir = t.TempDir()
+	dir := filepath.Join(inst.ExeDir, "python", "20240814")
+	os.MkdirAll(dir, 0755)
+	archive := filepath.Join(dir, "cpython-3.11.9+20240814-x86_64-pc-windows-msvc-install_only_stripped.tar.gz")
+	os.WriteFile(archive, nil, 0644)
+	inst.Arch = "x86_64"
+
+	// 安装 Python 时拒绝访问：提示用户处理，用户选择“重试”后再执行一次
+	var asked string
+	retryBox = func(title, message string) bool { asked = message; return true }
+	t.Cleanup(func() { retryBox = ui.RetryBox })
+	m.Handler = func(c runner.Command) (string, error) {
+		if len(m.Calls) == 1 {
+			return "error: Access is denied. (os error 5)\n", errors.New("exit status 2")
+		}
+		return "", nil
+	}
+	if err := inst.RunStep("安装 Python", inst.InstallPython); err != nil {
+		t.Fatalf("RunStep() = %v", err)
+	}
+	if len(m.Calls) != 2 || !strings.Contains(asked, archive) {
+		t.Errorf("执行了 %d 次，提示 = %q", len(m.Calls), asked)
+	}
+
+	// 刚才还在的安装包不见了，视为被隔离；用户选择“取消”时返回错误
+	retryBox = func(title, message string) bool { return false }
+	m.Calls = nil
+	m.Handler = func(c runner.Command) (string, error) {
+		os.Remove(archive)
+		return "", errors.New("exit status 2")
+	}
+	var ie *InterferenceError
+	if err := inst.RunStep("安装 Python", inst.InstallPython); !errors.As(err, &ie) || ie.Kind != InterferenceQuarantine || !slices.Equal(ie.Paths, []string{archive}) {
+		t.Errorf("RunStep() = %v", err)
+	}
+
+	// 同步依赖时拒绝访问多半是文件被占用，不提示
+	m.Handler = func(c runner.Command) (string, error) {
+		return "error: failed to remove file: Access is denied. (os error 5)\n", errors.New("exit status 2")
+	}
+	if err := inst.RunStep("同步依赖", inst.Sync); err == nil || errors.As(err, &ie) {
+		t.Errorf("RunStep() = %v", err)
+	}
+}
+
+func TestRunStepCanceled(t *testing.T) {
+	inst, m := newTestInstaller(t)
+	inst.TempDir = t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	inst.Context = ctx
+	cancel()
+
+	if err := inst.RunStep("同步依赖", inst.Sync); !errors.Is(err, runner.ErrCanceled) {
+		t.Errorf("RunStep() = %v, want ErrCanceled", err)
+	}
+	if len(m.Calls) != 0 {
+		t.Errorf("取消后不应执行命令: %v", m.CommandLines())
+	}
+}
+
+// 内置的安装脚本应与根目录 uv/ 中发布的版本一致
+func TestPayloadChecksums(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("只有 Windows 版内置安装脚本")
+	}
+	sums, err := loadChecksums(filepath.Join("..", "..", "..", ".."))
+	if err != nil {
+		t.Skipf("没有校验清单: %v", err)
+	}
+	dir := t.TempDir()
+	if err := extractPayload(dir); err != nil {
+		t.Fatal(err)
+	}
+	got, err := fileSHA256(filepath.Join(dir, "uv-installer.ps1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := sums["uv/uv-installer.ps1"]; got != want {
+		t.Errorf("内置的安装脚本与 checksums.txt 不一致，请从 uv/ 重新复制")
+	}
+}
+
+func TestCheckVenv(t *testing.T) {
+	dir := t.TempDir()
+	lock := filepath.Join(dir, "uv.lock")
+	os.WriteFile(lock, []byte(`version = 1
+requires-python = ">=3.11"
+
+[[package]]
+name = "edge-tts"
+version = "6.1.12"
+source = { registry = "https://pypi.org/simple" }
+dependencies = [
+    { name = "aiohttp" },
+]
+
+[package.optional-dependencies]
+dev = [
+    { name = "pytest" },
+]
+
+[[package]]
+name = "PyYAML"
+version = "6.0.2"
+source = { registry = "https://pypi.org/simple" }
+
+[[package]]
+name = "speakmybook"
+version = "0.1.0"
+source = { virtual = "." }
+`), 0644)
+	pkgs, err := ReadLock(lock)
+	if err != nil || len(pkgs) != 2 || pkgs[0] != (LockedPackage{"edge-tts", "6.1.12"}) || pkgs[1] != (LockedPackage{"PyYAML", "6.0.2"}) {
+		t.Fatalf("ReadLock() = %v, %v", pkgs, err)
+	}
+
+	venv := filepath.Join(dir, "venv")
+	if problems := CheckVenv(venv, lock, "3.11.*"); len(problems) != 1 {
+		t.Errorf("不是虚拟环境时 CheckVenv() = %v", problems)
+	}
+	os.MkdirAll(filepath.Join(venv, "Scripts"), 0755)
+	os.WriteFile(VenvPythonW(venv), nil, 0644)
+	os.WriteFile(filepath.Join(venv, "pyvenv.cfg"), []byte("home = C:\\Python311\nversion_info = 3.11.9\n"), 0644)
+	site := filepath.Join(venv, "Lib", "site-packages")
+	os.MkdirAll(filepath.Join(site, "edge_tts-6.1.12.dist-info"), 0755)
+	if problems := CheckVenv(venv, lock, "3.11.*"); len(problems) != 1 || !strings.Contains(problems[0], "PyYAML") {
+		t.Errorf("缺少包时 CheckVenv() = %v", problems)
+	}
+	os.MkdirAll(filepath.Join(site, "PyYAML-6.0.1.dist-info"), 0755)
+	if problems := CheckVenv(venv, lock, "3.12"); len(problems) != 2 {
+		t.Errorf("Python 和包的版本不一致时 CheckVenv() = %v", problems)
+	}
+	os.Remove(filepath.Join(site, "PyYAML-6.0.1.dist-info"))
+	os.MkdirAll(filepath.Join(site, "pyyaml-6.0.2.dist-info"), 0755)
+	if problems := CheckVenv(venv, lock, "3.11.*"); len(problems) != 0 {
+		t.Errorf("CheckVenv() = %v", problems)
+	}
+}
+
+func TestCheckResolution(t *testing.T) {
+	inst, m := newTestInstaller(t)
+	inst.Index = "https://pypi.example.com/simple"
+	if err := inst.CheckResolution(); err != nil {
+		t.Fatalf("CheckResolution() = %v", err)
+	}
+	if lines := m.CommandLines(); len(lines) != 1 || !strings.Contains(lines[0], "uv sync --locked --dry-run --default-index https://pypi.example.com/simple") {
+		t.Errorf("执行的命令 = %v", lines)
+	}
+
+	cases := map[string]string{
+		"error: The lockfile at `uv.lock` needs to be updated, but `--locked` was provided.":                                                                                          ResolveOutdated,
+		"error: Distribution `pyaudio==0.2.14 @ registry+https://pypi.org/simple` can't be installed because it doesn't have a source distribution or wheel for the current platform": ResolvePlatform,
+		"  × No solution found\n  ╰─▶ Because edge-tts==6.1.9 was yanked (reason: broken) and you require edge-tts==6.1.9, we can conclude...":                                        ResolveYanked,
+		"error: Failed to fetch: `https://pypi.example.com/simple/edge-tts/`\n  Caused by: error sending request":                                                                     ResolveNetwork,
+		"error: something else went wrong": ResolveOther,
+	}
+	for output, kind := range cases {
+		m.Handler = func(c runner.Command) (string, error) { return output, errors.New("exit status 2") }
+		err := inst.CheckResolution()
+		var rerr *ResolveError
+		if !errors.As(err, &rerr) || rerr.Kind != kind {
+			t.Errorf("输出 %q: CheckResolution() = %v，应为 %s", output, err, kind)
+		}
+	}
+	m.Handler = func(c runner.Command) (string, error) {
+		return "error: something else went wrong", errors.New("exit status 2")
+	}
+	if !strings.Contains(inst.CheckResolution().Error(), "something else went wrong") {
+		t.Errorf("未知错误应包含 uv 的输出")
+	}
+
+	m.Handler = func(c runner.Command) (string, error) {
+		return "error: unexpected argument '--dry-run' found", errors.New("exit status 2")
+	}
+	if err := inst.CheckResolution(); err != nil {
+		t.Errorf("uv 不支持 --dry-run 时应跳过检查: %v", err)
+	}
+}
+
+func TestMissingWheels(t *testing.T) {
+	dir := t.TempDir()
+	lock := filepath.Join(dir, "uv.lock")
+	os.WriteFile(lock, []byte(`version = 1
+
+[[package]]
+name = "aiohttp"
+version = "3.11.18"
+source = { registry = "https://pypi.org/simple" }
+sdist = { url = "https://example.com/aiohttp-3.11.18.tar.gz", hash = "sha256:00" }
+wheels = [
+    { url = "https://example.com/aiohttp-3.11.18-cp311-cp311-win_amd64.whl", hash = "sha256:00" },
+]
+
+[[package]]
+name = "edge-tts"
+version = "6.1.12"
+source = { registry = "https://pypi.org/simple" }
+wheels = [
+    { url = "https://example.com/edge_tts-6.1.12-py3-none-any.whl", hash = "sha256:00" },
+]
+
+[[package]]
+name = "uvloop"
+version = "0.21.0"
+source = { registry = "https://pypi.org/simple" }
+wheels = [
+    { url = "https://example.com/uvloop-0.21.0-cp311-cp311-manylinux_2_17_x86_64.manylinux2014_x86_64.whl", hash = "sha256:00" },
+]
+
+[[package]]
+name = "PyYAML"
+version = "6.0.2"
+source = { registry = "https://pypi.org/simple" }
+wheels = [
+    { url = "https://example.com/PyYAML-6.0.2-cp311-cp311-win_amd64.whl", hash = "sha256:00" },
+]
+
+[[package]]
+name = "speakmybook"
+version = "0.1.0"
+source = { virtual = "." }
+`), 0644)
+	wheels := filepath.Join(dir, "wheels")
+	if OfflineWheelsDir(dir) != "" {
+		t.Errorf("没有 wheels 目录时 OfflineWheelsDir() 应为空")
+	}
+	os.MkdirAll(wheels, 0755)
+	for _, name := range []string{"edge_tts-6.1.12-py3-none-any.whl", "PyYAML-6.0.2-cp311-cp311-win32.whl"} {
+		os.WriteFile(filepath.Join(wheels, name), nil, 0644)
+	}
+	if OfflineWheelsDir(dir) != wheels {
+		t.Errorf("OfflineWheelsDir() = %q", OfflineWheelsDir(dir))
+	}
+
+	missing, err := MissingWheels(lock, wheels, "x86_64")
+	if err != nil || strings.Join(missing, ",") != "PyYAML==6.0.2,aiohttp==3.11.18" {
+		t.Errorf("MissingWheels() = %v, %v", missing, err)
+	}
+	missing, _ = MissingWheels(lock, wheels, "x86")
+	if strings.Join(missing, ",") != "aiohttp==3.11.18" {
+		t.Errorf("32 位时 MissingWheels() = %v", missing)
+	}
+	os.WriteFile(filepath.Join(wheels, "aiohttp-3.11.18.tar.gz"), nil, 0644)
+	os.WriteFile(filepath.Join(wheels, "pyyaml-6.0.2-cp311-cp311-win_amd64.whl"), nil, 0644)
+	if missing, _ := MissingWheels(lock, wheels, "x86_64"); len(missing) != 0 {
+		t.Errorf("MissingWheels() = %v", missing)
+	}
+
+	inst, m := newTestInstaller(t)
+	os.MkdirAll(filepath.Join(inst.ExeDir, "python"), 0755)
+	os.Rename(lock, filepath.Join(inst.ExeDir, "python", "uv.lock"))
+	inst.WheelsDir = wheels
+	inst.Provenance = provenance.Open(filepath.Join(dir, "provenance.jsonl"))
+	if err := inst.CheckResolution(); err != nil {
+		t.Errorf("离线安装包齐全时 CheckResolution() = %v", err)
+	}
+	if err := inst.Sync(); err != nil {
+		t.Fatalf("Sync() = %v", err)
+	}
+	if lines := m.CommandLines(); len(lines) != 1 || !strings.Contains(lines[0], "uv sync --offline --frozen --find-links "+wheels) {
+		t.Errorf("执行的命令 = %v", lines)
+	}
+	// 记录每个包来自离线安装包目录中的哪个文件
+	records, _ := provenance.Load(inst.Provenance.Path())
+	if len(records) != 3 {
+		t.Fatalf("来源记录 = %+v", records)
+	}
+	for _, r := range records {
+		if r.Source != provenance.SourceOffline {
+			t.Errorf("%s 的来源 = %s", r.Name, r.Source)
+		}
+		if r.Name == "edge-tts" && (r.URL != filepath.Join(wheels, "edge_tts-6.1.12-py3-none-any.whl") || r.SHA256 != "00") {
+			t.Errorf("edge-tts 的来源记录 = %+v", r)
+		}
+	}
+	os.Remove(filepath.Join(wheels, "aiohttp-3.11.18.tar.gz"))
+	var rerr *ResolveError
+	if err := inst.CheckResolution(); !errors.As(err, &rerr) || rerr.Kind != ResolveMissing || !strings.Contains(rerr.Message, "aiohttp==3.11.18") {
+		t.Errorf("缺包时 CheckResolution() = %v", err)
+	}
+}
+
+func TestCachedPackages(t *testing.T) {
+	cache := t.TempDir()
+	for _, f := range []string{
+		"wheels-v5/pypi/numpy/1.26.4-cp311-cp311-win_amd64.http",
+		"wheels-v5/index/b2a7eb67d4c26b82/pillow/pillow-10.4.0-cp311-cp311-win_amd64.msgpack",
+		"sdists-v9/index/b2a7eb67d4c26b82/jieba/0.42.1/revision.http",
+		"wheels-v5/pypi/requests/2.31.0-py3-none-any.http",
+	} {
+		path := filepath.Join(cache, filepath.FromSlash(f))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, nil, 0644)
+	}
+	pkgs := []LockedPackage{
+		{Name: "numpy", Version: "1.26.4"},
+		{Name: "Pillow", Version: "10.4.0"},
+		{Name: "jieba", Version: "0.42.1"},
+		{Name: "requests", Version: "2.32.3"}, // 缓存中是其他版本
+		{Name: "edge-tts", Version: "6.1.12"},
+	}
+	var got []string
+	for _, p := range CachedPackages(cache, pkgs) {
+		got = append(got, p.Name)
+	}
+	if want := "numpy,Pillow,jieba"; strings.Join(got, ",") != want {
+		t.Errorf("CachedPackages() = %v, want %s", got, want)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	i := &Installer{}
+	release := make(chan struct{})
+	want := errors.New("prefetched")
+	i.Prefetch("verify", func(ctx context.Context) error {
+		<-release
+		return want
+	})
+	close(release)
+	fallback := func(ctx context.Context) error {
+		t.Error("Await() ran fn although the task was prefetched")
+		return nil
+	}
+	if err := i.Await("verify", fallback); err != want {
+		t.Errorf("Await() = %v, want %v", err, want)
+	}
+
+	// 没有预取过（或结果已被取走）时直接执行
+	ran := false
+	i.Await("verify", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	if !ran {
+		t.Error("Await() did not run fn for a task that was not prefetched")
+	}
+}
+
+func TestRunGraph(t *testing.T) {
+	newInst := func() *Installer { return &Installer{Out: ui.Discard} }
+	failed := errors.New("failed")
+	var mu sync.Mutex
+	var order []string
+	record := func(name string, err error) func(*Installer) error {
+		return func(*Installer) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return err
+		}
+	}
+	results, err := RunGraph([]Task{
+		{Name: "sync", After: []string{"python"}, Run: record("sync", nil)},
+		{Name: "python", Run: record("python", nil)},
+		{Name: "models", Run: record("models", failed)},
+		{Name: "voices", After: []string{"models"}, Run: record("voices", nil)},
+	}, 0, newInst)
+	if err != nil {
+		t.Fatalf("RunGraph() error = %v", err)
+	}
+	if results["sync"] != nil || results["python"] != nil || results["models"] != failed {
+		t.Errorf("RunGraph() results = %v", results)
+	}
+	if !errors.Is(results["voices"], ErrSkipped) {
+		t.Errorf("依赖失败步骤的结果 = %v, want ErrSkipped", results["voices"])
+	}
+	joined := strings.Join(order, ",")
+	if strings.Contains(joined, "voices") || strings.Index(joined, "python") > strings.Index(joined, "sync") {
+		t.Errorf("执行顺序 = %s", joined)
+	}
+
+	if _, err := RunGraph([]Task{
+		{Name: "a", After: []string{"b"}, Run: record("a", nil)},
+		{Name: "b", After: []string{"a"}, Run: record("b", nil)},
+	}, 0, newInst); err == nil {
+		t.Errorf("有循环依赖时 RunGraph() 应返回错误")
+	}
+
+	// 限制为 1 时相互独立的步骤也逐个执行
+	var running, peak int
+	serial := func(*Installer) error {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+	if _, err := RunGraph([]Task{{Name: "a", Run: serial}, {Name: "b", Run: serial}, {Name: "c", Run: serial}}, 1, newInst); err != nil {
+		t.Fatalf("RunGraph() error = %v", err)
+	}
+	if peak != 1 {
+		t.Errorf("限制为 1 时最多同时执行了 %d 个步骤", peak)
+	}
+}
+
+func TestDownloadFilesParallel(t *testing.T) {
+	var mu sync.Mutex
+	var running, peak int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		io.WriteString(w, r.URL.Path)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	var files []models.File
+	for _, name := range []string{"a", "b", "c", "d"} {
+		sum := sha256.Sum256([]byte("/" + name))
+		files = append(files, models.File{Name: name, Path: name + ".bin", URL: srv.URL + "/" + name, SHA256: hex.EncodeToString(sum[:])})
+	}
+	dir := t.TempDir()
+	inst := &Installer{Out: ui.Discard, MaxDownloads: 2}
+	if err := inst.DownloadFiles(dir, files); err != nil {
+		t.Fatalf("DownloadFiles() error = %v", err)
+	}
+	if missing := models.Missing(dir, files); len(missing) > 0 {
+		t.Errorf("下载后仍缺少 %d 个文件", len(missing))
+	}
+	if peak != 2 {
+		t.Errorf("最多同时下载了 %d 个文件，want 2", peak)
+	}
+}
+
+func TestDownloadFilesGateway(t *testing.T) {
+	content := "voice"
+	sum := sha256.Sum256([]byte(content))
+	cid := "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/ipfs/"+cid {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, content)
+	}))
+	defer srv.Close()
+
+	f := models.File{Name: "zh-voice", Path: "zh.onnx", URL: srv.URL + "/zh.onnx", SHA256: hex.EncodeToString(sum[:]), CID: cid}
+	dir := t.TempDir()
+	inst := &Installer{Out: ui.Discard, Gateways: []string{srv.URL + "/"}}
+	if err := inst.DownloadFiles(dir, []models.File{f}); err != nil {
+		t.Fatalf("DownloadFiles() error = %v", err)
+	}
+	if want := []string{"/zh.onnx", "/ipfs/" + cid}; !slices.Equal(paths, want) {
+		t.Errorf("请求 = %v, want %v，URL 失败后应改从网关下载", paths, want)
+	}
+
+	// 优先使用网关时不访问 URL
+	paths = nil
+	inst.PreferGateway = true
+	if err := inst.DownloadFiles(t.TempDir(), []models.File{f}); err != nil || len(paths) != 1 {
+		t.Errorf("DownloadFiles() = %v，请求 %v", err, paths)
+	}
+}
+
+// 用录下的真实 uv 输出回放依赖检查，确认错误分类
+func TestCheckResolutionReplay(t *testing.T) {
+	cassette, err := runner.LoadCassette(filepath.Join("testdata", "resolve.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := runner.NewReplay(cassette)
+	inst := &Installer{ExeDir: t.TempDir(), Runner: replay, Out: ui.Discard, Index: "https://pypi.tuna.tsinghua.edu.cn/simple"}
+	for _, kind := range []string{ResolveOutdated, ResolvePlatform, ResolveYanked, ResolveNetwork} {
+		var rerr *ResolveError
+		if err := inst.CheckResolution(); !errors.As(err, &rerr) || rerr.Kind != kind {
+			t.Errorf("CheckResolution() = %v，应为 %s", err, kind)
+		}
+	}
+	if err := inst.CheckResolution(); err != nil {
+		t.Errorf("解析成功时 CheckResolution() = %v", err)
+	}
+	if n := replay.Remaining(); n != 0 {
+		t.Errorf("还有 %d 条命令没有回放", n)
+	}
+}
+
+func TestSeedCache(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	for name, content := range map[string]string{
+		"wheels-v5/pypi/numpy/1.26.4-cp311-cp311-win_amd64.http": "numpy",
+		"archive-v0/abc/numpy/__init__.py":                       "new",
+		"wheels-v5/pypi/numpy/.lock":                             "",
+		".tmpXYZ/partial":                                        "x",
+	} {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	// 本机已有的文件不覆盖
+	local := filepath.Join(dst, "archive-v0", "abc", "numpy", "__init__.py")
+	os.MkdirAll(filepath.Dir(local), 0755)
+	os.WriteFile(local, []byte("old"), 0644)
+
+	n, err := SeedCache(src, dst)
+	if err != nil || n != 1 {
+		t.Fatalf("SeedCache() = %d, %v，应只复制 1 个文件", n, err)
+	}
+	if got := CachedPackages(dst, []LockedPackage{{Name: "numpy", Version: "1.26.4"}}); len(got) != 1 {
+		t.Error("复制后 numpy 应在本机缓存中")
+	}
+	if data, _ := os.ReadFile(local); string(data) != "old" {
+		t.Error("不应覆盖本机缓存中已有的文件")
+	}
+	for _, name := range []string{"wheels-v5/pypi/numpy/.lock", ".tmpXYZ"} {
+		if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("不应复制 %s", name)
+		}
+	}
+}
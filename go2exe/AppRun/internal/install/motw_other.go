@@ -0,0 +1,13 @@
+//go:build !windows
+
+package install
+
+// 其他系统没有来自网络的标记
+func MarkedFromInternet(path string) bool {
+	return false
+}
+
+// 其他系统没有来自网络的标记，不需要去掉
+func Unblock(path string) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,36 @@
+package install
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 文件是否带有来自网络的标记：NTFS 备用数据流 Zone.Identifier 中的 ZoneId 为 3（Internet）或 4（受限站点）
+func MarkedFromInternet(path string) bool {
+	f, err := os.Open(path + ":Zone.Identifier")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "ZoneId="); ok {
+			zone, _ := strconv.Atoi(v)
+			return zone >= 3
+		}
+	}
+	return false
+}
+
+// 删除文件的来自网络标记（与 PowerShell 的 Unblock-File 相同），返回是否有标记被删除
+func Unblock(path string) (bool, error) {
+	if !MarkedFromInternet(path) {
+		return false, nil
+	}
+	if err := os.Remove(path + ":Zone.Identifier"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
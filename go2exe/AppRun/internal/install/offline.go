@@ -0,0 +1,181 @@
+package install
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 离线安装包目录的名称，放在可执行文件同目录。其中有 wheel 时不访问网络，只从这里安装依赖
+const WheelsDirName = "wheels"
+
+// 程序目录中可用的离线安装包目录，没有或其中没有任何安装包时返回空
+func OfflineWheelsDir(exeDir string) string {
+	dir := filepath.Join(exeDir, WheelsDirName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		if !e.IsDir() && isDistFile(e.Name()) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// 是否为 wheel 或源码包
+func isDistFile(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".whl") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".zip")
+}
+
+// Python 架构对应的 wheel 平台标签
+var wheelPlatforms = map[string]string{
+	"x86_64":  "win_amd64",
+	"x86":     "win32",
+	"aarch64": "win_arm64",
+}
+
+// wheel 文件名：{name}-{version}(-{build})?-{python}-{abi}-{platform}.whl
+var wheelName = regexp.MustCompile(`^([^-]+)-([^-]+)(?:-\d[^-]*)?-[^-]+-[^-]+-([^-]+)\.whl$`)
+
+// 源码包文件名：{name}-{version}.tar.gz 或 .zip
+var sdistName = regexp.MustCompile(`^(.+)-([^-]+)\.(?:tar\.gz|zip)$`)
+
+// 解析 wheel 文件名，返回规范化的包名、版本和平台标签
+func parseWheel(file string) (name, version string, platforms []string, ok bool) {
+	m := wheelName.FindStringSubmatch(file)
+	if m == nil {
+		return "", "", nil, false
+	}
+	return normalizeName(m[1]), m[2], strings.Split(m[3], "."), true
+}
+
+// 平台标签是否适用于 Windows 上指定架构的 Python
+func platformMatches(platforms []string, arch string) bool {
+	want := wheelPlatforms[arch]
+	if want == "" {
+		want = wheelPlatforms["x86_64"]
+	}
+	for _, p := range platforms {
+		if p == "any" || p == want {
+			return true
+		}
+	}
+	return false
+}
+
+// uv.lock 中一个包可用的安装文件
+type lockArtifacts struct {
+	wheels []string // wheel 的文件名
+	sdist  bool
+	urls   map[string]string // wheel 和源码包的文件名 -> 下载地址
+	hashes map[string]string // wheel 和源码包的文件名 -> SHA-256
+}
+
+var (
+	lockWheelURL = regexp.MustCompile(`url = "([^"]+\.whl)"`)
+	lockFileHash = regexp.MustCompile(`url = "([^"]+)", hash = "sha256:([0-9a-f]+)"`)
+)
+
+// 读取 uv.lock 中每个包（按 "规范化包名==版本" 索引）的 wheel 文件名、是否有源码包，以及它们的下载地址和哈希
+func readLockArtifacts(lockPath string) (map[string]*lockArtifacts, error) {
+	f, err := os.Open(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	all := map[string]*lockArtifacts{}
+	var name, version string
+	var cur *lockArtifacts
+	flush := func() {
+		if cur != nil && name != "" {
+			all[normalizeName(name)+"=="+version] = cur
+		}
+		name, version, cur = "", "", nil
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "[[package]]":
+			flush()
+			cur = &lockArtifacts{urls: map[string]string{}, hashes: map[string]string{}}
+		case strings.HasPrefix(line, "[") && !strings.HasPrefix(strings.TrimLeft(line, "["), "package."):
+			flush()
+		case cur == nil:
+		case strings.HasPrefix(line, "name = "):
+			name = strings.Trim(strings.TrimPrefix(line, "name = "), `"`)
+		case strings.HasPrefix(line, "version = "):
+			version = strings.Trim(strings.TrimPrefix(line, "version = "), `"`)
+		default:
+			if strings.HasPrefix(line, "sdist = ") {
+				cur.sdist = true
+			} else if m := lockWheelURL.FindStringSubmatch(line); m != nil {
+				cur.wheels = append(cur.wheels, path.Base(m[1]))
+			}
+			if m := lockFileHash.FindStringSubmatch(line); m != nil {
+				cur.urls[path.Base(m[1])] = m[1]
+				cur.hashes[path.Base(m[1])] = m[2]
+			}
+		}
+	}
+	flush()
+	return all, scanner.Err()
+}
+
+// 按 uv.lock 检查 wheelsDir 中是否有在 Windows 上安装所需的全部包（架构为 arch），
+// 返回缺少的包（name==version）。只为其他平台发布 wheel 的包不需要
+func MissingWheels(lockPath, wheelsDir, arch string) ([]string, error) {
+	pkgs, err := ReadLock(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	artifacts, err := readLockArtifacts(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(wheelsDir)
+	if err != nil {
+		return nil, err
+	}
+	have := map[string]bool{}
+	for _, e := range entries {
+		if name, version, platforms, ok := parseWheel(e.Name()); ok {
+			if platformMatches(platforms, arch) {
+				have[name+"=="+version] = true
+			}
+		} else if m := sdistName.FindStringSubmatch(e.Name()); m != nil {
+			have[normalizeName(m[1])+"=="+m[2]] = true
+		}
+	}
+
+	var missing []string
+	for _, p := range pkgs {
+		key := normalizeName(p.Name) + "==" + p.Version
+		if have[key] {
+			continue
+		}
+		if a := artifacts[key]; a != nil && !a.sdist && len(a.wheels) > 0 && !anyWheelMatches(a.wheels, arch) {
+			continue
+		}
+		missing = append(missing, p.Name+"=="+p.Version)
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+func anyWheelMatches(wheels []string, arch string) bool {
+	for _, w := range wheels {
+		if _, _, platforms, ok := parseWheel(w); ok && platformMatches(platforms, arch) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/signature"
+	"go2exe/internal/timeline"
+)
+
+// uv 的官方安装脚本，没有随程序分发当前平台的安装文件时下载
+const onlineUVInstaller = "https://astral.sh/uv/install.sh"
+
+// 程序目录下是否有随程序分发的 uv 安装文件
+func HasExternalUV(exeDir string) bool {
+	_, err := os.Stat(filepath.Join(exeDir, "uv", envcheck.Current.UVScript))
+	return err == nil
+}
+
+// uv 安装文件所在目录：优先使用程序目录下的 uv 目录，没有时把内置的安装文件解压到 dir 下。
+// 只有 Windows 版内置安装脚本（不含安装包，由脚本下载），其他平台返回 dir 下的空目录，由 InstallUV 下载安装脚本
+func (i *Installer) uvSourceDir(dir string) (string, error) {
+	if HasExternalUV(i.ExeDir) {
+		return filepath.Join(i.ExeDir, "uv"), nil
+	}
+	target := filepath.Join(dir, "uv")
+	if envcheck.Current.UVScript != envcheck.Windows.UVScript {
+		return target, os.MkdirAll(target, 0755)
+	}
+	if err := extractPayload(target); err != nil {
+		return "", fmt.Errorf("无法解压内置的 uv 安装文件: %v", err)
+	}
+	i.printf("未找到 uv 目录，使用内置的安装文件")
+	return target, nil
+}
+
+// 把内置的安装文件写到 target 目录
+func extractPayload(target string) error {
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	entries, err := fs.ReadDir(payload, "payload")
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		data, err := payload.ReadFile("payload/" + e.Name())
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(target, e.Name()), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 下载 uv 的官方安装脚本到 path
+func (i *Installer) downloadUVInstaller(path string) (err error) {
+	span := i.Timeline.Begin(timeline.KindDownload, onlineUVInstaller)
+	defer func() { span.End(err) }()
+	ctx := i.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, onlineUVInstaller, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Timeout:   time.Minute,
+		Transport: &http.Transport{Proxy: i.proxy()},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// 交给 withRetry 判断是否为网络问题
+		i.recordOutput(err.Error())
+		return fmt.Errorf("无法下载 uv 安装脚本: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载 %s 失败: 服务器返回 %s", onlineUVInstaller, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		i.recordOutput(err.Error())
+		return fmt.Errorf("下载 %s 中断: %v", onlineUVInstaller, err)
+	}
+	span.SetBytes(int64(len(data)))
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		return err
+	}
+	// 下载的文件执行前校验签名，不通过时删除，不留给下次使用
+	if err := checkSignature(i.Signature, path); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// 校验签名的实现，测试时替换
+var checkSignature = signature.Policy.Check
+
+// 校验安装脚本从网上下载的 uv 程序（uv 和 uvx）的签名，随程序分发的安装包已由校验清单或启动器自身的签名保证。
+// 不通过时删除这两个程序，不留给之后执行
+func (i *Installer) checkUVSignatures() error {
+	dir := i.UVDir
+	if dir == "" {
+		var err error
+		if dir, err = i.uvInstallDir(); err != nil {
+			return err
+		}
+	}
+	uv := filepath.Join(dir, envcheck.Current.UV)
+	uvx := filepath.Join(dir, strings.Replace(envcheck.Current.UV, "uv", "uvx", 1))
+	for _, path := range []string{uv, uvx} {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := checkSignature(i.Signature, path); err != nil {
+			os.Remove(uv)
+			os.Remove(uvx)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package install
+
+import "embed"
+
+// 其他系统没有内置的安装文件，由 InstallUV 下载官方安装脚本
+var payload embed.FS
@@ -0,0 +1,9 @@
+package install
+
+import "embed"
+
+// 内置的 uv 安装脚本，程序目录下没有 uv 目录时使用，uv 的安装包由脚本下载后校验签名。
+// 更新根目录 uv/ 中的安装脚本后需要同步复制到 payload/
+//
+//go:embed payload/uv-installer.ps1
+var payload embed.FS
@@ -0,0 +1,121 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+	"go2exe/internal/timeline"
+)
+
+// 通过比较含睡眠时间的时钟和不含睡眠时间的时钟，检测期间系统是否睡眠过
+type sleepWatch struct {
+	tick     uint64 // 毫秒，包含睡眠时间
+	unbiased uint64 // 100 纳秒，不包含睡眠时间
+}
+
+func startSleepWatch() sleepWatch {
+	var w sleepWatch
+	w.tick, w.unbiased = readClocks()
+	return w
+}
+
+// 返回开始检测以来系统睡眠的总时长
+func (w sleepWatch) slept() time.Duration {
+	tick, unbiased := readClocks()
+	wall := time.Duration(tick-w.tick) * time.Millisecond
+	awake := time.Duration(unbiased-w.unbiased) * 100
+	// 两个时钟精度不同，忽略几秒内的误差
+	if wall-awake < 5*time.Second {
+		return 0
+	}
+	return wall - awake
+}
+
+// 等待 url（同步依赖使用的镜像）可以访问，超时返回错误（经过 proxy 选择的代理访问，与 uv 的网络路径一致）
+func waitForNetwork(url string, timeout time.Duration, proxy func(*http.Request) (*neturl.URL, error)) error {
+	deadline := time.Now().Add(timeout)
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{Proxy: proxy},
+	}
+	for {
+		resp, err := client.Head(url)
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("网络未恢复: %v", err)
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
+// 执行一个安装步骤：期间阻止系统睡眠并监控磁盘空间，临时文件写入单独的暂存目录，结束后无论成败都删除；
+// 因网络错误失败时按 Retry 重试。如果步骤失败且期间系统睡眠过，说明很可能是连接在睡眠中失效，
+// 等网络恢复后重新执行（uv 会复用已下载完成的缓存）
+func (i *Installer) RunStep(name string, fn func() error) error {
+	i.step = name
+	defer func() { i.step = "" }()
+	i.events().OnStepStart(name)
+	span := i.Timeline.Begin(timeline.KindStep, name)
+	var err error
+	if i.Context != nil && i.Context.Err() != nil {
+		err = runner.ErrCanceled
+	} else {
+		// 被杀毒软件或 SmartScreen 拦截时，用户按提示处理后可以重试
+		for {
+			i.watched = nil
+			err = i.runStep(name, func() error { return i.withRetry(name, fn) })
+			if !i.askInterferenceRetry(name, err) {
+				break
+			}
+		}
+	}
+	span.End(err)
+	if err != nil {
+		i.events().OnError(name, err)
+	}
+	return err
+}
+
+func (i *Installer) runStep(name string, fn func() error) error {
+	cleanup, err := i.beginStaging()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	release := preventSleep("SpeakMyBook 正在" + name)
+	defer release()
+	stopMonitor := i.startDiskMonitor()
+	defer stopMonitor()
+
+	watch := startSleepWatch()
+	err = fn()
+	if err == nil {
+		return nil
+	}
+	slept := watch.slept()
+	if slept == 0 || errors.Is(err, runner.ErrCanceled) {
+		return err
+	}
+
+	// 只使用离线安装包时不需要网络，直接重试
+	if i.WheelsDir != "" {
+		log.Printf("%s失败，期间系统睡眠了 %v，重试", name, slept.Round(time.Second))
+		return fn()
+	}
+	log.Printf("%s失败，期间系统睡眠了 %v，等待网络恢复后继续", name, slept.Round(time.Second))
+	i.events().OnProgress(i.step, i18n.T("检测到系统曾进入睡眠，正在等待网络恢复后继续%s...", i18n.T(name)))
+	if netErr := waitForNetwork(i.index()+"/", 2*time.Minute, i.proxy()); netErr != nil {
+		log.Printf("%v", netErr)
+		return err
+	}
+	return fn()
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package install
+
+// 安装期间不阻止系统睡眠，返回的函数什么也不做
+func preventSleep(reason string) func() {
+	return func() {}
+}
+
+// 两个时钟都返回 0，sleepWatch 总是认为没有睡眠过
+func readClocks() (uint64, uint64) {
+	return 0, 0
+}
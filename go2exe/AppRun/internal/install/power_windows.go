@@ -0,0 +1,79 @@
+package install
+
+import (
+	"log"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                            = syscall.NewLazyDLL("kernel32.dll")
+	setThreadExecutionState             = kernel32.NewProc("SetThreadExecutionState")
+	getTickCount64                      = kernel32.NewProc("GetTickCount64")
+	queryUnbiasedInterruptTime          = kernel32.NewProc("QueryUnbiasedInterruptTime")
+	powerCreateRequest                  = kernel32.NewProc("PowerCreateRequest")
+	powerSetRequest                     = kernel32.NewProc("PowerSetRequest")
+	powerClearRequest                   = kernel32.NewProc("PowerClearRequest")
+	ES_CONTINUOUS                       = 0x80000000
+	ES_SYSTEM_REQUIRED                  = 0x00000001
+	POWER_REQUEST_CONTEXT_SIMPLE_STRING = 0x1
+	PowerRequestSystemRequired          = 1
+)
+
+// REASON_CONTEXT，联合体部分按最大成员（Detailed）的大小预留
+type reasonContext struct {
+	version uint32
+	flags   uint32
+	reason  *uint16
+	_       [2]uintptr
+}
+
+// 阻止系统在安装期间自动睡眠，返回解除函数。
+// 优先使用带原因说明的电源请求，使其出现在 powercfg /requests 中；不可用时退回线程执行状态
+func preventSleep(reason string) func() {
+	release, err := createPowerRequest(reason)
+	if err == nil {
+		return release
+	}
+	log.Printf("创建电源请求失败，改用 SetThreadExecutionState: %v", err)
+
+	// 执行状态是按线程记录的，需要一个固定线程持有到安装结束
+	done := make(chan struct{})
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		setThreadExecutionState.Call(uintptr(ES_CONTINUOUS | ES_SYSTEM_REQUIRED))
+		<-done
+		setThreadExecutionState.Call(uintptr(ES_CONTINUOUS))
+	}()
+	return func() { close(done) }
+}
+
+// 创建并激活一个命名电源请求
+func createPowerRequest(reason string) (func(), error) {
+	reasonPtr, _ := syscall.UTF16PtrFromString(reason)
+	ctx := reasonContext{flags: uint32(POWER_REQUEST_CONTEXT_SIMPLE_STRING), reason: reasonPtr}
+	h, _, err := powerCreateRequest.Call(uintptr(unsafe.Pointer(&ctx)))
+	if syscall.Handle(h) == syscall.InvalidHandle || h == 0 {
+		return nil, err
+	}
+	if r, _, err := powerSetRequest.Call(h, uintptr(PowerRequestSystemRequired)); r == 0 {
+		syscall.CloseHandle(syscall.Handle(h))
+		return nil, err
+	}
+	log.Printf("已创建电源请求: %s", reason)
+	return func() {
+		powerClearRequest.Call(h, uintptr(PowerRequestSystemRequired))
+		syscall.CloseHandle(syscall.Handle(h))
+		log.Printf("已释放电源请求: %s", reason)
+	}, nil
+}
+
+// 含睡眠时间的毫秒时钟和不含睡眠时间的 100 纳秒时钟
+func readClocks() (uint64, uint64) {
+	tick, _, _ := getTickCount64.Call()
+	var unbiased uint64
+	queryUnbiasedInterruptTime.Call(uintptr(unsafe.Pointer(&unbiased)))
+	return uint64(tick), unbiased
+}
@@ -0,0 +1,193 @@
+package install
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+)
+
+// 安装脚本对 PowerShell 版本的要求：#Requires -Version，或脚本开头对 $PSVersionTable.PSVersion.Major 的检查
+var (
+	psRequires     = regexp.MustCompile(`(?im)^\s*#requires\s+-version\s+(\d+)`)
+	psVersionCheck = regexp.MustCompile(`PSVersion\.Major\)*\s*-lt\s+(\d+)`)
+)
+
+// 随程序分发的 PowerShell 7（程序目录下的 pwsh 目录），Windows PowerShell 版本太低时使用
+const bundledPwsh = "pwsh/pwsh.exe"
+
+// 安装脚本要求的 PowerShell 主版本号，没有要求或无法读取时为 0
+func requiredPowerShell(script string) int {
+	data, err := os.ReadFile(script)
+	if err != nil {
+		return 0
+	}
+	for _, re := range []*regexp.Regexp{psRequires, psVersionCheck} {
+		if m := re.FindSubmatch(data); m != nil {
+			n, _ := strconv.Atoi(string(m[1]))
+			return n
+		}
+	}
+	return 0
+}
+
+// shell 的 PowerShell 主版本号。找不到 shell 时返回 0 和 nil，能执行但无法得到版本号时返回错误
+func (i *Installer) powerShellVersion(shell string) (int, error) {
+	out, err := i.Runner.Output(runner.Command{
+		Name:       shell,
+		Args:       []string{"-NoProfile", "-NonInteractive", "-Command", "$PSVersionTable.PSVersion.Major"},
+		HideWindow: true,
+		Context:    i.Context,
+		Timeout:    time.Minute,
+	})
+	if errors.Is(err, exec.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	// 配置文件等可能输出其他内容，版本号在最后一行
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("没有输出版本号")
+	}
+	n, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return 0, fmt.Errorf("无法识别的版本号 %q", fields[len(fields)-1])
+	}
+	return n, nil
+}
+
+// 执行 uv 安装脚本的命令（脚本的路径加在最后）。Windows PowerShell 的版本低于脚本的要求时依次改用随程序分发的
+// 或已安装的 PowerShell 7，都没有时返回 native 为 true，由 installUVNative 直接解压 uv 目录中的安装包，不会执行到一半报语法错误
+func (i *Installer) uvInstallerShell(uvDir, script string) (shell []string, native bool, err error) {
+	platform := envcheck.Current
+	required := requiredPowerShell(script)
+	if platform.UVScript != envcheck.Windows.UVScript || required == 0 {
+		return platform.Shell, false, nil
+	}
+	version, err := i.powerShellVersion(platform.Shell[0])
+	if err != nil {
+		// 无法确定版本时照常执行，由脚本自己检查
+		log.Printf("无法获取 PowerShell 的版本（%v），直接执行 uv 安装脚本", err)
+		return platform.Shell, false, nil
+	}
+	if version >= required {
+		log.Printf("PowerShell %d 满足 uv 安装脚本的要求（%d 或更高）", version, required)
+		return platform.Shell, false, nil
+	}
+
+	current := i18n.T("未安装")
+	if version > 0 {
+		current = strconv.Itoa(version)
+	}
+	for _, pwsh := range []string{filepath.Join(i.ExeDir, filepath.FromSlash(bundledPwsh)), "pwsh"} {
+		if filepath.IsAbs(pwsh) {
+			if _, err := os.Stat(pwsh); err != nil {
+				continue
+			}
+			i.unblockFiles(pwsh)
+		}
+		if v, err := i.powerShellVersion(pwsh); err == nil && v >= required {
+			i.printf("系统的 PowerShell（%s）不满足 uv 安装脚本的要求（%d 或更高），改用 %s（PowerShell %d）", current, required, pwsh, v)
+			return append([]string{pwsh}, platform.Shell[1:]...), false, nil
+		}
+	}
+	if uvArchive(uvDir) != "" {
+		i.printf("系统的 PowerShell（%s）不满足 uv 安装脚本的要求（%d 或更高），直接解压 uv 安装包", current, required)
+		return nil, true, nil
+	}
+	return nil, false, fmt.Errorf("安装 uv 需要 PowerShell %d 或更高版本（当前: %s），也没有可以直接解压的 uv 安装包", required, current)
+}
+
+// uv 目录中当前平台的 uv 安装包，优先使用与系统架构相同的，没有时返回空字符串
+func uvArchive(uvDir string) string {
+	platform := envcheck.Current
+	preferred := filepath.Join(uvDir, "uv-"+platform.Triple(envcheck.ResolveArch(envcheck.ArchAuto))+".zip")
+	if _, err := os.Stat(preferred); err == nil {
+		return preferred
+	}
+	files, _ := filepath.Glob(filepath.Join(uvDir, "uv-*-"+platform.Target+".zip"))
+	if len(files) > 0 {
+		return files[0]
+	}
+	return ""
+}
+
+// uv 的安装目录，与安装脚本的选择相同：UVInstallDir（UV_INSTALL_DIR）、XDG_BIN_HOME、XDG_DATA_HOME/../bin、~/.local/bin
+func (i *Installer) uvInstallDir() (string, error) {
+	if i.UVInstallDir != "" {
+		return i.UVInstallDir, nil
+	}
+	if dir := os.Getenv("XDG_BIN_HOME"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "..", "bin"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "bin"), nil
+}
+
+// 不经过安装脚本，把 uv 目录中安装包里的程序（uv.exe、uvx.exe）解压到安装目录，并记在 UVDir 中。
+// 不会像安装脚本那样修改用户的 PATH，启动器按 UVDir 和默认安装目录查找 uv
+func (i *Installer) installUVNative(uvDir string) error {
+	archive := uvArchive(uvDir)
+	dir, err := i.uvInstallDir()
+	if err != nil {
+		return err
+	}
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("无法打开 uv 安装包: %v", err)
+	}
+	defer r.Close()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	i.printf("正在解压 %s 到 %s", filepath.Base(archive), dir)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		// 安装包中只有顶层的程序，不按压缩包中的路径创建目录
+		if err := extractZipFile(f, filepath.Join(dir, filepath.Base(f.Name))); err != nil {
+			return fmt.Errorf("解压 %s 失败: %v", f.Name, err)
+		}
+	}
+	i.UVDir = dir
+	return nil
+}
+
+// 把压缩包中的一个文件写到 path
+func extractZipFile(f *zip.File, path string) error {
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
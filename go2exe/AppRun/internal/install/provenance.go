@@ -0,0 +1,133 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/models"
+	"go2exe/internal/provenance"
+)
+
+// 记录 uv 的来源：程序目录或内置的安装文件（uvDir），或者下载的官方安装脚本（downloaded 为 true）
+func (i *Installer) recordUV(uvDir, script string, downloaded bool) {
+	if i.Provenance == nil {
+		return
+	}
+	r := provenance.Record{Kind: provenance.KindUV, Name: "uv", Source: provenance.SourceBundled, URL: uvDir}
+	if !HasExternalUV(i.ExeDir) {
+		r.Detail = "内置在启动器中"
+	}
+	if downloaded {
+		r.Source, r.URL, r.Detail = provenance.SourceDownload, onlineUVInstaller, ""
+		r.SHA256, _ = fileSHA256(script)
+	} else if archive := uvArchive(uvDir); archive != "" {
+		r.URL = archive
+		r.SHA256, _ = fileSHA256(archive)
+	}
+	i.Provenance.Add(r)
+}
+
+// 记录 Python 的来源：随程序分发的安装包（localMirror 不为空），或者 uv 的默认下载源
+func (i *Installer) recordPython(version, localMirror string) {
+	if i.Provenance == nil {
+		return
+	}
+	r := provenance.Record{Kind: provenance.KindPython, Name: "python", Version: version, Source: provenance.SourceDownload,
+		Detail: "uv 的默认下载源"}
+	if localMirror != "" {
+		r.Source, r.URL, r.Detail = provenance.SourceBundled, localMirror, ""
+		if archive := i.bundledPython(version); archive != "" {
+			r.URL = archive
+			r.SHA256, _ = fileSHA256(archive)
+		}
+	}
+	i.Provenance.Add(r)
+}
+
+var pythonMinor = regexp.MustCompile(`^(\d+)\.(\d+)`)
+
+// 按 uv.lock 记录同步后每个包的来源和哈希。cached 为同步前已在 uv 缓存中的包（规范化包名==版本），
+// 它们由之前的同步、预热目录或局域网中的其他电脑提供，不是这次下载的
+func (i *Installer) recordPackages(pkgs []LockedPackage, cached map[string]bool) {
+	if i.Provenance == nil || len(pkgs) == 0 {
+		return
+	}
+	artifacts, _ := readLockArtifacts(filepath.Join(i.ExeDir, "python", "uv.lock"))
+	version := i.Python
+	if version == "" {
+		version = envcheck.DefaultPython
+	}
+	pyTag := ""
+	if m := pythonMinor.FindStringSubmatch(version); m != nil {
+		pyTag = "cp" + m[1] + m[2]
+	}
+	arch := envcheck.ResolveArch(i.Arch)
+
+	records := make([]provenance.Record, 0, len(pkgs))
+	for _, p := range pkgs {
+		key := normalizeName(p.Name) + "==" + p.Version
+		r := provenance.Record{Kind: provenance.KindPackage, Name: p.Name, Version: p.Version}
+		file := ""
+		if a := artifacts[key]; a != nil {
+			if file = lockedArtifact(a, arch, pyTag); file == "" {
+				// 只为其他平台发布的包，不会安装
+				continue
+			}
+			r.URL, r.SHA256 = a.urls[file], a.hashes[file]
+		}
+		switch {
+		case i.WheelsDir != "":
+			// 离线安装包目录中没有 uv.lock 中的那个文件时（例如用源码包代替），只记录目录
+			r.Source, r.URL = provenance.SourceOffline, i.WheelsDir
+			if _, err := os.Stat(filepath.Join(i.WheelsDir, file)); file != "" && err == nil {
+				r.URL = filepath.Join(i.WheelsDir, file)
+			} else {
+				r.SHA256 = ""
+			}
+		case cached[key]:
+			r.Source = provenance.SourceCache
+		default:
+			r.Source, r.Mirror = provenance.SourceMirror, i.index()
+		}
+		records = append(records, r)
+	}
+	i.Provenance.Add(records...)
+}
+
+// uv.lock 中的文件里在 Windows 上为 arch 架构、pyTag（例如 cp312）的 Python 安装的那个：
+// 优先使用对应 Python 版本的 wheel，其次是通用的 wheel，没有适用的 wheel 时为源码包
+func lockedArtifact(a *lockArtifacts, arch, pyTag string) string {
+	var generic string
+	for _, w := range a.wheels {
+		if _, _, platforms, ok := parseWheel(w); !ok || !platformMatches(platforms, arch) {
+			continue
+		}
+		if pyTag != "" && strings.Contains(w, "-"+pyTag+"-") {
+			return w
+		}
+		if generic == "" {
+			generic = w
+		}
+	}
+	if generic != "" {
+		return generic
+	}
+	for file := range a.urls {
+		if sdistName.MatchString(file) {
+			return file
+		}
+	}
+	return ""
+}
+
+// 记录从 src 下载的模型文件的来源，src 与 f 的地址不同时为 IPFS 网关
+func (i *Installer) recordModel(f, src models.File) {
+	r := provenance.Record{Kind: provenance.KindModel, Name: f.Path, Source: provenance.SourceDownload, URL: src.URL, SHA256: f.SHA256}
+	if src.URL != f.URL {
+		r.Source, r.Detail = provenance.SourceGateway, "CID "+f.CID
+	}
+	i.Provenance.Add(r)
+}
@@ -0,0 +1,136 @@
+package install
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+)
+
+// 依赖预检发现的问题类别
+const (
+	ResolveOutdated = "outdated" // uv.lock 与 pyproject.toml 不一致
+	ResolvePlatform = "platform" // 某个包没有适用于本机的安装包
+	ResolveYanked   = "yanked"   // 锁定的版本已被撤回
+	ResolveNetwork  = "network"  // 无法访问镜像，实际同步时也可能失败，由调用方决定是否继续
+	ResolveMissing  = "missing"  // 离线安装包目录中缺少需要的包
+	ResolveOther    = "other"
+)
+
+// 依赖预检失败
+type ResolveError struct {
+	Kind    string
+	Message string // 给用户看的说明
+	Output  string // uv 的原始输出
+}
+
+func (e *ResolveError) Error() string {
+	return e.Message
+}
+
+var (
+	// Distribution `pkg==1.0 @ registry+...` can't be installed because it doesn't have a source distribution or wheel for the current platform
+	platformDist = regexp.MustCompile("Distribution `([^` @]+)[^`]*` can't be installed")
+	// Because pkg==1.0 was yanked (reason: ...)
+	yankedDist = regexp.MustCompile(`(\S+==\S+) (?:was|is) yanked`)
+)
+
+// 按 uv 的输出判断预检失败的原因
+func classifyResolve(output, index string) *ResolveError {
+	lower := strings.ToLower(output)
+	e := &ResolveError{Kind: ResolveOther, Output: output}
+	switch {
+	case strings.Contains(lower, "needs to be updated") || strings.Contains(lower, "lockfile") && strings.Contains(lower, "not up-to-date"):
+		e.Kind = ResolveOutdated
+		e.Message = i18n.T("uv.lock 与 pyproject.toml 不一致，安装包可能不完整，请重新下载 SpeakMyBook。")
+	case platformDist.MatchString(output) || strings.Contains(lower, "for the current platform") || strings.Contains(lower, "matching platform tag"):
+		e.Kind = ResolvePlatform
+		name := i18n.T("某个依赖")
+		if m := platformDist.FindStringSubmatch(output); m != nil {
+			name = m[1]
+		}
+		e.Message = i18n.T("%s 没有适用于这台电脑的安装包（不支持当前的处理器架构或 Python 版本），无法安装。", name)
+	case strings.Contains(lower, "yanked"):
+		e.Kind = ResolveYanked
+		name := i18n.T("某个依赖")
+		if m := yankedDist.FindStringSubmatch(output); m != nil {
+			name = m[1]
+		}
+		e.Message = i18n.T("%s 已被发布者撤回（yanked），镜像 %s 不再提供，请更新 SpeakMyBook 或在 apprun.toml 中换用其他镜像。", name, index)
+	case NetworkError(output) || strings.Contains(lower, "timed out") || strings.Contains(lower, "connection"):
+		e.Kind = ResolveNetwork
+		e.Message = i18n.T("无法访问 PyPI 镜像 %s，请检查网络或代理设置。", index)
+	default:
+		e.Message = i18n.T("依赖解析失败：%s", lastLines(output, 3))
+	}
+	return e
+}
+
+// 输出的最后 n 个非空行
+func lastLines(output string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 在下载任何包之前按 uv.lock 和镜像做一次不安装的解析（uv sync --locked --dry-run）：
+// 锁文件过期、包被撤回或没有适合本机的安装包时几秒内就能发现，不必等下载了几百 MB 之后才失败。
+// 失败时返回 *ResolveError；uv 版本太旧不支持 --dry-run 时跳过检查。
+// 使用离线安装包时改为按 uv.lock 检查离线安装包目录，列出缺少的所有包
+func (i *Installer) CheckResolution() error {
+	if i.WheelsDir != "" {
+		return i.checkWheels()
+	}
+	i.printf("正在检查依赖能否从 %s 安装...", i.index())
+	output, err := i.Runner.Output(runner.Command{
+		Name:       "uv",
+		Args:       append([]string{"sync", "--locked", "--dry-run", "--default-index", i.index()}, i.extraArgs()...),
+		Env:        i.uvEnv(),
+		Dir:        filepath.Join(i.ExeDir, "python"),
+		HideWindow: true,
+		Context:    i.Context,
+		Timeout:    i.StepTimeout,
+	})
+	if err == nil {
+		i.printf("依赖检查通过")
+		return nil
+	}
+	if errors.Is(err, runner.ErrCanceled) {
+		return err
+	}
+	log.Printf("依赖检查失败: %v\n%s", err, output)
+	if strings.Contains(output, "unexpected argument '--dry-run'") {
+		log.Printf("uv 不支持 --dry-run，跳过依赖检查")
+		return nil
+	}
+	return classifyResolve(output, i.index())
+}
+
+// 检查离线安装包目录中是否有 uv.lock 需要的全部包
+func (i *Installer) checkWheels() error {
+	i.printf("正在检查离线安装包目录 %s...", i.WheelsDir)
+	missing, err := MissingWheels(filepath.Join(i.ExeDir, "python", "uv.lock"), i.WheelsDir, i.Arch)
+	if err != nil {
+		return &ResolveError{Kind: ResolveOther, Message: i18n.T("检查离线安装包失败: %v", err)}
+	}
+	if len(missing) > 0 {
+		log.Printf("离线安装包目录缺少 %d 个包: %s", len(missing), strings.Join(missing, ", "))
+		return &ResolveError{
+			Kind:    ResolveMissing,
+			Message: i18n.T("离线安装包目录 %s 中缺少 %d 个包：\n%s\n\n请把这些包适用于 Windows 的 wheel 文件放入该目录后重试。", i.WheelsDir, len(missing), strings.Join(missing, "\n")),
+		}
+	}
+	i.printf("离线安装包齐全")
+	return nil
+}
@@ -0,0 +1,114 @@
+package install
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+	"go2exe/internal/timeline"
+)
+
+// 安装步骤遇到网络错误时的重试策略
+type RetryPolicy struct {
+	Attempts   int           // 最多执行的次数，0 或 1 表示不重试
+	Backoff    time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff time.Duration // 等待时间的上限，0 表示不限制
+}
+
+// 保留最近多少行命令输出，用于判断失败原因
+const recentOutputLines = 50
+
+// 表示网络问题的输出（uv、PowerShell 和 Windows 的错误信息）
+var networkErrorHints = []string{
+	"failed to fetch",
+	"failed to download",
+	"error sending request",
+	"dns error",
+	"connection reset",
+	"connection refused",
+	"connection closed",
+	"connection aborted",
+	"operation timed out",
+	"network is unreachable",
+	"tls handshake",
+	"unexpected eof",
+	"no such host",
+	"i/o timeout",
+	"server error",
+	"502 bad gateway",
+	"503 service unavailable",
+	"504 gateway timeout",
+	"无法连接",
+	"远程主机强迫关闭",
+	"请求超时",
+}
+
+// 命令输出是否表明失败是网络问题引起的，这类失败重试往往就能成功
+func NetworkError(output string) bool {
+	lower := strings.ToLower(output)
+	for _, hint := range networkErrorHints {
+		if strings.Contains(lower, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// 记下命令输出的一行
+func (i *Installer) recordOutput(line string) {
+	i.outputMu.Lock()
+	defer i.outputMu.Unlock()
+	i.recent = append(i.recent, line)
+	if len(i.recent) > recentOutputLines {
+		i.recent = i.recent[len(i.recent)-recentOutputLines:]
+	}
+}
+
+// 取出并清空记下的输出
+func (i *Installer) takeOutput() string {
+	i.outputMu.Lock()
+	defer i.outputMu.Unlock()
+	output := strings.Join(i.recent, "\n")
+	i.recent = nil
+	return output
+}
+
+// 执行 fn，因网络问题失败时按 Retry 等待后重试；其他失败和取消立即返回，像是被杀毒软件干扰时返回 *InterferenceError
+func (i *Installer) withRetry(name string, fn func() error) error {
+	attempts := max(i.Retry.Attempts, 1)
+	delay := i.Retry.Backoff
+	var done <-chan struct{}
+	if i.Context != nil {
+		done = i.Context.Done()
+	}
+	for n := 1; ; n++ {
+		i.takeOutput()
+		err := fn()
+		if err == nil || errors.Is(err, runner.ErrCanceled) {
+			return err
+		}
+		output := i.takeOutput()
+		if !NetworkError(output) {
+			return i.checkInterference(err, output)
+		}
+		if n >= attempts {
+			return err
+		}
+		log.Printf("%s失败: %v", name, err)
+		i.Timeline.Mark(timeline.KindRetry, name, err.Error())
+		i.printf("%s失败，网络可能不稳定，%d 秒后重试（%d/%d）...", i18n.T(name), int(delay.Seconds()), n+1, attempts)
+		select {
+		case <-time.After(delay):
+		case <-done:
+			return runner.ErrCanceled
+		}
+		i.printf("正在重试（%d/%d）...", n+1, attempts)
+		delay *= 2
+		if i.Retry.MaxBackoff > 0 && delay > i.Retry.MaxBackoff {
+			delay = i.Retry.MaxBackoff
+		}
+	}
+}
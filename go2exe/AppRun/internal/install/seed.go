@@ -0,0 +1,84 @@
+package install
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 局域网共享的预热目录中的子目录：uv 缓存、离线安装包（WheelsDirName）和模型文件
+const (
+	SeedUVCacheDir = "uv-cache"
+	SeedModelsDir  = "models"
+)
+
+// 把共享的 uv 缓存 src 中本地缓存 dst 还没有（或大小不同）的文件复制过去，返回复制的文件数。
+// 跳过 uv 的锁文件和临时目录（见 SkipCacheEntry）；每个文件先写到临时名再改名，复制中途断开不会留下不完整的缓存条目
+func SeedCache(src, dst string) (int, error) {
+	copied := 0
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if SkipCacheEntry(d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, p)
+		target := filepath.Join(dst, rel)
+		if local, err := os.Stat(target); err == nil && local.Size() == info.Size() {
+			return nil
+		}
+		if err := copySeedFile(p, target); err != nil {
+			return err
+		}
+		copied++
+		return nil
+	})
+	return copied, err
+}
+
+// 复制 uv 缓存时跳过的条目：uv 的临时目录、锁文件和链接等非普通文件
+func SkipCacheEntry(d fs.DirEntry) bool {
+	if d.IsDir() {
+		return strings.HasPrefix(d.Name(), ".tmp")
+	}
+	return !d.Type().IsRegular() || d.Name() == ".lock"
+}
+
+func copySeedFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".seed"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
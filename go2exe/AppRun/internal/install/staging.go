@@ -0,0 +1,67 @@
+package install
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 暂存目录名前缀，启动时按此前缀清理上次崩溃遗留的目录
+const stagingPrefix = "SpeakMyBook-staging-"
+
+// 暂存目录所在的上级目录：配置的 TempDir，未配置时使用系统临时目录
+func (i *Installer) TempBase() string {
+	if i.TempDir != "" {
+		return i.TempDir
+	}
+	return os.TempDir()
+}
+
+// 为一个安装步骤创建暂存目录，返回删除函数。安装脚本和 uv 解压的临时文件都会写到这里
+func (i *Installer) beginStaging() (func(), error) {
+	base := i.TempBase()
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return nil, fmt.Errorf("无法创建临时目录 %s: %v", base, err)
+	}
+	dir, err := os.MkdirTemp(base, stagingPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("无法创建暂存目录: %v", err)
+	}
+	log.Printf("安装暂存目录: %s", dir)
+	i.staging = dir
+	return func() {
+		i.staging = ""
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("清理暂存目录 %s 失败: %v", dir, err)
+		}
+	}, nil
+}
+
+// 子进程的临时目录环境变量，未处于安装步骤中时为空
+func (i *Installer) stagingEnv() []string {
+	if i.staging == "" {
+		return nil
+	}
+	return []string{"TEMP=" + i.staging, "TMP=" + i.staging}
+}
+
+// 删除上次运行（例如崩溃或被强制结束）遗留的暂存目录，需在单实例锁保护下调用
+func (i *Installer) CleanStaging() {
+	entries, err := os.ReadDir(i.TempBase())
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), stagingPrefix) {
+			continue
+		}
+		dir := filepath.Join(i.TempBase(), e.Name())
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("清理遗留的暂存目录 %s 失败: %v", dir, err)
+		} else {
+			log.Printf("已清理遗留的暂存目录: %s", dir)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package install
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/i18n"
+)
+
+// 虚拟环境中运行应用的解释器
+func VenvPythonW(venvDir string) string {
+	return filepath.Join(venvDir, filepath.FromSlash(envcheck.Current.VenvPython))
+}
+
+// 锁文件中的一个包
+type LockedPackage struct {
+	Name    string
+	Version string
+}
+
+// 读取 uv.lock 中需要安装的包，跳过项目本身（source 为 editable 或 virtual）
+func ReadLock(path string) ([]LockedPackage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pkgs []LockedPackage
+	var cur *LockedPackage
+	project, inSub := false, false
+	flush := func() {
+		if cur != nil && !project && cur.Name != "" {
+			pkgs = append(pkgs, *cur)
+		}
+		cur, project, inSub = nil, false, false
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "[[package]]":
+			flush()
+			cur = &LockedPackage{}
+		case strings.HasPrefix(line, "["):
+			// package 下的子表（如 [package.optional-dependencies]）仍属于当前包，但其中的键不是包的属性
+			if strings.HasPrefix(strings.TrimLeft(line, "["), "package.") {
+				inSub = true
+			} else {
+				flush()
+			}
+		case cur != nil && !inSub:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			switch strings.TrimSpace(key) {
+			case "name":
+				cur.Name = strings.Trim(value, `"`)
+			case "version":
+				cur.Version = strings.Trim(value, `"`)
+			case "source":
+				project = strings.Contains(value, "editable") || strings.Contains(value, "virtual")
+			}
+		}
+	}
+	flush()
+	return pkgs, scanner.Err()
+}
+
+// 包名按 PEP 503 规范化后比较
+var nameSeparators = regexp.MustCompile(`[-_.]+`)
+
+func normalizeName(name string) string {
+	return strings.ToLower(nameSeparators.ReplaceAllString(name, "-"))
+}
+
+// 虚拟环境中已安装的包（site-packages 下的 *.dist-info），键为规范化的包名
+func installedPackages(venvDir string) (map[string]string, error) {
+	entries, err := os.ReadDir(filepath.Join(venvDir, "Lib", "site-packages"))
+	if err != nil {
+		return nil, err
+	}
+	pkgs := map[string]string{}
+	for _, e := range entries {
+		base, ok := strings.CutSuffix(e.Name(), ".dist-info")
+		if !ok || !e.IsDir() {
+			continue
+		}
+		// 包名中的 - 已替换为 _，第一个 - 之后是版本
+		name, version, ok := strings.Cut(base, "-")
+		if ok {
+			pkgs[normalizeName(name)] = version
+		}
+	}
+	return pkgs, nil
+}
+
+// 虚拟环境的 Python 版本，读取 pyvenv.cfg 中的 version_info（uv）或 version（venv 模块）
+func venvPythonVersion(venvDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(venvDir, "pyvenv.cfg"))
+	if err != nil {
+		return "", err
+	}
+	values := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	for _, key := range []string{"version_info", "version"} {
+		if v := values[key]; v != "" {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("pyvenv.cfg 中没有 Python 版本")
+}
+
+// 检查手动创建的虚拟环境能否代替 .venv 使用：是虚拟环境、Python 版本满足 python 约束，
+// 并且已安装 lockPath（uv.lock）中的所有包且版本一致。返回发现的所有问题
+func CheckVenv(venvDir, lockPath, python string) []string {
+	if _, err := os.Stat(VenvPythonW(venvDir)); err != nil {
+		return []string{i18n.T("%s 不是虚拟环境：找不到 %s", venvDir, VenvPythonW(venvDir))}
+	}
+	var problems []string
+	if python == "" {
+		python = envcheck.DefaultPython
+	}
+	if version, err := venvPythonVersion(venvDir); err != nil {
+		problems = append(problems, i18n.T("无法确定 Python 版本: %v", err))
+	} else if c, err := envcheck.ParseConstraint(python); err != nil {
+		problems = append(problems, err.Error())
+	} else if v, ok := parseVersion(version); !ok || !c.Match(v) {
+		problems = append(problems, i18n.T("Python 版本 %s 不满足 %s", version, c))
+	}
+
+	locked, err := ReadLock(lockPath)
+	if err != nil {
+		return append(problems, i18n.T("读取 uv.lock 失败: %v", err))
+	}
+	installed, err := installedPackages(venvDir)
+	if err != nil {
+		return append(problems, i18n.T("读取已安装的包失败: %v", err))
+	}
+	var missing, mismatched []string
+	for _, p := range locked {
+		have, ok := installed[normalizeName(p.Name)]
+		switch {
+		case !ok:
+			missing = append(missing, p.Name)
+		case have != p.Version:
+			mismatched = append(mismatched, i18n.T("%s %s（需要 %s）", p.Name, have, p.Version))
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(mismatched)
+	if len(missing) > 0 {
+		problems = append(problems, i18n.T("缺少 %d 个包: %s", len(missing), strings.Join(missing, ", ")))
+	}
+	if len(mismatched) > 0 {
+		problems = append(problems, i18n.T("%d 个包的版本与 uv.lock 不一致: %s", len(mismatched), strings.Join(mismatched, ", ")))
+	}
+	return problems
+}
+
+// 解析 pyvenv.cfg 中的版本，例如 3.11.9 或 3.12.0.final.0
+func parseVersion(s string) (envcheck.Version, bool) {
+	parts := strings.SplitN(s, ".", 4)
+	if len(parts) < 3 {
+		return envcheck.Version{}, false
+	}
+	var v envcheck.Version
+	if _, err := fmt.Sscanf(strings.Join(parts[:3], " "), "%d %d %d", &v.Major, &v.Minor, &v.Patch); err != nil {
+		return envcheck.Version{}, false
+	}
+	return v, true
+}
@@ -0,0 +1,129 @@
+// Package launch 启动 Python 应用并跟踪其运行状态
+package launch
+
+import (
+	"log"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+// 应用的解释器和入口脚本，相对于 python 目录（Launcher.Dir）
+const (
+	PythonW   = "./.venv/Scripts/pythonw.exe"
+	AppScript = "app.pyw"
+)
+
+// 应用启动器，同一时间只跟踪一个应用进程
+type Launcher struct {
+	Python string // 运行应用的解释器，为空时使用 PythonW；相对路径相对于 Dir
+	Dir    string // 应用的工作目录（python 目录的绝对路径）。只设置在子进程上，启动器自己的当前目录不变
+	// 应用的当前目录，为空时为 Dir。python 目录只读时（黄金镜像模式）设为用户可写的目录，应用仍从 Dir 加载
+	WorkDir string
+	Index   string // 传给应用的 PyPI 镜像地址
+	Runner  runner.CommandRunner
+	Out     ui.Output
+	Env     []string        // 追加给应用的环境变量
+	Detach  bool            // 应用与启动器所在的终端分离（见 runner.Command.Detach）
+	OnExit  func(err error) // 应用退出后调用，可为 nil
+
+	mu  sync.Mutex
+	app runner.Process
+}
+
+// 在 Dir 中启动 pythonw.exe 运行 app.pyw，appArgs 追加在应用参数末尾
+func (l *Launcher) Start(appArgs []string) error {
+	// 执行Python应用
+	// 这里不要隐藏窗口，因为是启动真正的应用程序
+	python := l.Python
+	if python == "" {
+		python = PythonW
+	}
+	if l.Dir != "" && !filepath.IsAbs(python) {
+		python = filepath.Join(l.Dir, python)
+	}
+	dir, script := l.Dir, AppScript
+	if l.WorkDir != "" {
+		dir, script = l.WorkDir, filepath.Join(l.Dir, AppScript)
+	}
+	cmd, err := l.Runner.Start(runner.Command{
+		Name:   python,
+		Dir:    dir,
+		Args:   append([]string{script, "--default-index", l.Index}, appArgs...),
+		Env:    l.Env,
+		Detach: l.Detach,
+	})
+	if err != nil {
+		log.Printf("Python 应用启动失败: %v", err)
+		l.Out.Line(i18n.T("Python 应用启动失败: %v", err))
+		return err
+	}
+	// 应用成功启动，记录信息
+	log.Printf("Python 应用已启动")
+	l.Out.Line(i18n.T("Python 应用已启动"))
+
+	l.mu.Lock()
+	l.app = cmd
+	l.mu.Unlock()
+	// 在后台等待退出，只用于记录状态，不影响应用独立运行
+	go func() {
+		err := cmd.Wait()
+		log.Printf("Python 应用已退出: %v", err)
+		l.mu.Lock()
+		if l.app == cmd {
+			l.app = nil
+		}
+		l.mu.Unlock()
+		if l.OnExit != nil {
+			l.OnExit(err)
+		}
+	}()
+	return nil
+}
+
+// 应用进程是否仍在运行
+func (l *Launcher) Running() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.app != nil
+}
+
+// 正在运行的应用的进程号，没有运行或无法取得（例如测试中的模拟进程）时为 0
+func (l *Launcher) PID() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cmd, ok := l.app.(*exec.Cmd); ok && cmd.Process != nil {
+		return cmd.Process.Pid
+	}
+	return 0
+}
+
+// 启动器和应用参数的分隔符：命令行中它之后的参数原样传给应用
+const ArgsSeparator = "--"
+
+// 在第一个 ArgsSeparator 处拆分命令行参数（不含程序名），返回启动器自己的参数和原样传给应用的参数，
+// 例如 `SpeakMyBook.exe --voice xiaoyan -- --book a.epub --debug`
+func SplitArgs(args []string) (own, forwarded []string) {
+	for n, arg := range args {
+		if arg == ArgsSeparator {
+			return args[:n], args[n+1:]
+		}
+	}
+	return args, nil
+}
+
+// 把书籍路径和语音转换为应用参数
+func AppArgs(paths []string, voice string) []string {
+	var args []string
+	if voice != "" {
+		args = append(args, "--voice", voice)
+	}
+	for _, p := range paths {
+		args = append(args, "--book", p)
+	}
+	return args
+}
@@ -0,0 +1,42 @@
+package launch
+
+import (
+	"slices"
+	"testing"
+
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		args      []string
+		own       []string
+		forwarded []string
+	}{
+		{nil, nil, nil},
+		{[]string{"--voice", "x", "a.epub"}, []string{"--voice", "x", "a.epub"}, nil},
+		{[]string{"--", "--book", "a.epub"}, []string{}, []string{"--book", "a.epub"}},
+		{[]string{"--text-ui", "b.epub", "--", "--debug", "--", "x"}, []string{"--text-ui", "b.epub"}, []string{"--debug", "--", "x"}},
+		{[]string{"--silent", "--"}, []string{"--silent"}, []string{}},
+	}
+	for _, tt := range tests {
+		own, forwarded := SplitArgs(tt.args)
+		if !slices.Equal(own, tt.own) || !slices.Equal(forwarded, tt.forwarded) {
+			t.Errorf("SplitArgs(%q) = %q, %q, want %q, %q", tt.args, own, forwarded, tt.own, tt.forwarded)
+		}
+	}
+}
+
+func TestStartForwardsArgs(t *testing.T) {
+	_, forwarded := SplitArgs([]string{"--voice", "x", "a.epub", "--", "--book", "b c.epub", "--debug"})
+	m := &runner.Mock{}
+	l := &Launcher{Dir: "app", Index: "https://pypi.example/simple", Runner: m, Out: ui.Discard}
+	if err := l.Start(append(AppArgs([]string{"a.epub"}, "x"), forwarded...)); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{AppScript, "--default-index", "https://pypi.example/simple", "--voice", "x", "--book", "a.epub", "--book", "b c.epub", "--debug"}
+	if len(m.Calls) != 1 || !slices.Equal(m.Calls[0].Args, want) {
+		t.Errorf("应用命令行 = %q, want %q", m.CommandLines(), want)
+	}
+}
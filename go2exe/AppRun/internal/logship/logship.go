@@ -0,0 +1,275 @@
+// Package logship 把启动器日志和应用崩溃日志批量发送到集中的日志收集器（HTTP 或 syslog），
+// 发送失败时按指数退避重试，缓冲区满时丢弃最旧的记录，不会阻塞写日志的调用方
+package logship
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 一条日志记录
+type Record struct {
+	Time    time.Time `json:"time"`
+	Host    string    `json:"host"`
+	Source  string    `json:"source"` // launcher 或 app
+	Message string    `json:"message"`
+}
+
+// 发送设置
+type Options struct {
+	// 收集器地址：http(s)://... 以 JSON 数组 POST；udp://host:port 或 tcp://host:port 按 RFC 5424 发送 syslog
+	URL           string
+	BatchSize     int                 // 每批最多发送的记录数，默认 100
+	FlushInterval time.Duration       // 最长多久发送一次，默认 5 秒
+	MaxBuffered   int                 // 最多缓冲的记录数，超过时丢弃最旧的，默认 5000
+	MaxBackoff    time.Duration       // 发送失败后重试的最长间隔，默认 5 分钟
+	Scrub         func(string) string // 发送前处理每条消息（去除个人信息），可为 nil
+}
+
+// 发送到收集器的方式
+type sender interface {
+	send(records []Record) error
+}
+
+// 日志发送器，可以作为 log 的输出
+type Shipper struct {
+	o      Options
+	sender sender
+	host   string
+
+	mu      sync.Mutex
+	buf     []Record
+	dropped int
+	trimmed int    // 因缓冲区满从头部丢弃的记录总数，发送期间用于确定已发送的记录的位置
+	partial string // Write 收到的不完整的行
+
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// 创建发送器并在后台开始发送
+func New(o Options) (*Shipper, error) {
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, fmt.Errorf("日志收集器地址无效: %v", err)
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = 5 * time.Second
+	}
+	if o.MaxBuffered <= 0 {
+		o.MaxBuffered = 5000
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 5 * time.Minute
+	}
+
+	s := &Shipper{
+		o:    o,
+		kick: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	s.host, _ = os.Hostname()
+	switch u.Scheme {
+	case "http", "https":
+		s.sender = httpSender{url: o.URL, client: &http.Client{Timeout: 10 * time.Second}}
+	case "udp", "tcp":
+		s.sender = syslogSender{network: u.Scheme, addr: u.Host}
+	default:
+		return nil, fmt.Errorf("不支持的日志收集器协议: %s", u.Scheme)
+	}
+	go s.loop()
+	return s, nil
+}
+
+// 按行拆分写入的日志，每行作为一条 launcher 记录
+func (s *Shipper) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	text := s.partial + string(p)
+	lines := strings.Split(text, "\n")
+	s.partial = lines[len(lines)-1]
+	s.mu.Unlock()
+	for _, line := range lines[:len(lines)-1] {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			s.Send("launcher", line)
+		}
+	}
+	return len(p), nil
+}
+
+// 加入一条记录，等待下一批发送
+func (s *Shipper) Send(source, message string) {
+	if s.o.Scrub != nil {
+		message = s.o.Scrub(message)
+	}
+	s.mu.Lock()
+	s.buf = append(s.buf, Record{Time: time.Now(), Host: s.host, Source: source, Message: message})
+	if over := len(s.buf) - s.o.MaxBuffered; over > 0 {
+		s.buf = s.buf[over:]
+		s.dropped += over
+		s.trimmed += over
+	}
+	full := len(s.buf) >= s.o.BatchSize
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// 停止后台发送，最多等待 timeout 把缓冲的记录发完
+func (s *Shipper) Close(timeout time.Duration) {
+	close(s.stop)
+	select {
+	case <-s.done:
+	case <-time.After(timeout):
+	}
+}
+
+func (s *Shipper) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.o.FlushInterval)
+	defer ticker.Stop()
+	var backoff time.Duration
+	var retryAt time.Time
+	for {
+		select {
+		case <-s.stop:
+			// 退出前尽量发完，不再退避
+			for s.pending() > 0 {
+				if s.flush() != nil {
+					break
+				}
+			}
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		}
+		if time.Now().Before(retryAt) {
+			continue
+		}
+		if err := s.flush(); err != nil {
+			if backoff == 0 {
+				backoff = s.o.FlushInterval
+			} else if backoff *= 2; backoff > s.o.MaxBackoff {
+				backoff = s.o.MaxBackoff
+			}
+			retryAt = time.Now().Add(backoff)
+			continue
+		}
+		backoff, retryAt = 0, time.Time{}
+	}
+}
+
+// 缓冲中的记录数
+func (s *Shipper) pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buf)
+}
+
+// 发送一批记录，成功后从缓冲中移除
+func (s *Shipper) flush() error {
+	s.mu.Lock()
+	n := len(s.buf)
+	if n > s.o.BatchSize {
+		n = s.o.BatchSize
+	}
+	batch := append([]Record(nil), s.buf[:n]...)
+	if s.dropped > 0 {
+		batch = append(batch, Record{Time: time.Now(), Host: s.host, Source: "logship",
+			Message: fmt.Sprintf("缓冲区已满，丢弃了 %d 条日志", s.dropped)})
+	}
+	dropped, trimmed := s.dropped, s.trimmed
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	// 这里不能调用 log，否则发送失败的日志又会进入缓冲区
+	if err := s.sender.send(batch); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	// 发送期间缓冲区头部可能已被丢弃一部分
+	if n -= s.trimmed - trimmed; n > 0 {
+		s.buf = s.buf[n:]
+	}
+	s.dropped -= dropped
+	s.mu.Unlock()
+	return nil
+}
+
+// 以 JSON 数组 POST 到 HTTP 收集器
+type httpSender struct {
+	url    string
+	client *http.Client
+}
+
+func (h httpSender) send(records []Record) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("日志收集器返回 %s", resp.Status)
+	}
+	return nil
+}
+
+// 按 RFC 5424 发送 syslog，TCP 使用 RFC 6587 的长度前缀分帧
+type syslogSender struct {
+	network string
+	addr    string
+}
+
+// syslog 优先级：facility user(1)，severity informational(6)
+const syslogPriority = 1*8 + 6
+
+func (s syslogSender) send(records []Record) error {
+	conn, err := net.DialTimeout(s.network, s.addr, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	for _, r := range records {
+		msg := formatSyslog(r)
+		if s.network == "tcp" {
+			msg = fmt.Sprintf("%d %s", len(msg), msg)
+		}
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 格式化为一条 RFC 5424 消息
+func formatSyslog(r Record) string {
+	host := r.Host
+	if host == "" {
+		host = "-"
+	}
+	return fmt.Sprintf("<%d>1 %s %s SpeakMyBook - %s - %s", syslogPriority,
+		r.Time.UTC().Format(time.RFC3339Nano), host, r.Source, r.Message)
+}
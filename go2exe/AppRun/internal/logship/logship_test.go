@@ -0,0 +1,94 @@
+package logship
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 记录收到的日志的 HTTP 收集器，前 failures 次请求返回 503
+type collector struct {
+	mu       sync.Mutex
+	failures int
+	records  []Record
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failures > 0 {
+		c.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var batch []Record
+	json.NewDecoder(r.Body).Decode(&batch)
+	c.records = append(c.records, batch...)
+}
+
+func (c *collector) messages() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var msgs []string
+	for _, r := range c.records {
+		msgs = append(msgs, r.Source+": "+r.Message)
+	}
+	return msgs
+}
+
+func TestShipHTTP(t *testing.T) {
+	c := &collector{failures: 2}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	s, err := New(Options{
+		URL:           srv.URL,
+		FlushInterval: 10 * time.Millisecond,
+		MaxBackoff:    20 * time.Millisecond,
+		Scrub:         func(msg string) string { return strings.ReplaceAll(msg, "alice", "<user>") },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// log 包每次写入一行，也要处理跨两次写入的行
+	s.Write([]byte("2025/05/01 10:00:00 安装 uv 失败: C:\\Users\\alice\\x\n2025/05/01 "))
+	s.Write([]byte("10:00:01 重试\n"))
+	s.Send("app", "Traceback (most recent call last):")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.messages()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Close(time.Second)
+
+	want := []string{
+		`launcher: 2025/05/01 10:00:00 安装 uv 失败: C:\Users\<user>\x`,
+		"launcher: 2025/05/01 10:00:01 重试",
+		"app: Traceback (most recent call last):",
+	}
+	if got := c.messages(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("收到的日志 = %q, want %q", got, want)
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	s := &Shipper{o: Options{BatchSize: 100, MaxBuffered: 2}, kick: make(chan struct{}, 1)}
+	s.Send("launcher", "1")
+	s.Send("launcher", "2")
+	s.Send("launcher", "3")
+	if len(s.buf) != 2 || s.buf[0].Message != "2" || s.dropped != 1 {
+		t.Errorf("缓冲区 = %+v, 丢弃 %d", s.buf, s.dropped)
+	}
+}
+
+func TestFormatSyslog(t *testing.T) {
+	r := Record{Time: time.Date(2025, 5, 1, 2, 0, 0, 0, time.UTC), Host: "pc-01", Source: "launcher", Message: "hello"}
+	want := "<14>1 2025-05-01T02:00:00Z pc-01 SpeakMyBook - launcher - hello"
+	if got := formatSyslog(r); got != want {
+		t.Errorf("formatSyslog() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,196 @@
+// Package mirror 管理同步依赖使用的 PyPI 镜像：内置的镜像列表、按名称或地址解析配置，
+// 并发测量各镜像的响应时间以选出最快的一个，以及检查镜像和其他下载地址的连通性
+package mirror
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 一个 PyPI 简单索引
+type Mirror struct {
+	Name string // 配置中使用的名称，自定义地址为 custom
+	URL  string // 索引地址，不带末尾的 /
+}
+
+// 内置的镜像，按未测速时的优先顺序排列
+var Builtin = []Mirror{
+	{"tsinghua", "https://pypi.tuna.tsinghua.edu.cn/simple"},
+	{"aliyun", "https://mirrors.aliyun.com/pypi/simple"},
+	{"pypi", "https://pypi.org/simple"},
+}
+
+// 无法测速也没有记录时使用的镜像
+var Default = Builtin[0]
+
+// 配置中表示自动选择的值
+const Auto = "auto"
+
+// 按配置解析镜像：内置镜像的名称，或 http(s) 开头的自定义地址。
+// 为空或为 auto 时返回 false，由调用方自动选择
+func Parse(value string) (Mirror, bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, Auto) {
+		return Mirror{}, false, nil
+	}
+	for _, m := range Builtin {
+		if strings.EqualFold(value, m.Name) {
+			return m, true, nil
+		}
+	}
+	if strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://") {
+		return Mirror{Name: "custom", URL: strings.TrimRight(value, "/")}, true, nil
+	}
+	return Mirror{}, false, fmt.Errorf("未知的 PyPI 镜像 %q，可以使用 auto、%s 或完整的索引地址", value, strings.Join(names(), "、"))
+}
+
+func names() []string {
+	var list []string
+	for _, m := range Builtin {
+		list = append(list, m.Name)
+	}
+	return list
+}
+
+// 一个镜像的测速结果
+type Result struct {
+	Mirror  Mirror
+	Latency time.Duration // 请求索引首页的耗时，失败时为 0
+	Err     error
+}
+
+// 并发请求每个镜像的索引首页，按耗时从短到长返回，失败的排在最后。
+// client 为 nil 时使用 http.DefaultClient，每个请求最多等待 timeout
+func Probe(ctx context.Context, client *http.Client, mirrors []Mirror, timeout time.Duration) []Result {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	results := make([]Result, len(mirrors))
+	var wg sync.WaitGroup
+	for i, m := range mirrors {
+		wg.Add(1)
+		go func(i int, m Mirror) {
+			defer wg.Done()
+			results[i] = probe(ctx, client, m, timeout)
+		}(i, m)
+	}
+	wg.Wait()
+	sort.SliceStable(results, func(a, b int) bool {
+		ra, rb := results[a], results[b]
+		if (ra.Err == nil) != (rb.Err == nil) {
+			return ra.Err == nil
+		}
+		return ra.Err == nil && ra.Latency < rb.Latency
+	})
+	return results
+}
+
+func probe(ctx context.Context, client *http.Client, m Mirror, timeout time.Duration) Result {
+	h := check(ctx, client, Target{Name: m.Name, URL: m.URL + "/"}, timeout)
+	return Result{Mirror: m, Latency: h.Latency, Err: h.Err}
+}
+
+// 测速结果中最快的可用镜像，全部失败时返回 false
+func Fastest(results []Result) (Mirror, bool) {
+	if len(results) == 0 || results[0].Err != nil {
+		return Mirror{}, false
+	}
+	return results[0].Mirror, true
+}
+
+// 要检查连通性的地址
+type Target struct {
+	Name      string
+	URL       string
+	AnyStatus bool // 收到任何 HTTP 响应都算可以访问，用于只检查连通性的服务地址
+}
+
+// 一个地址的检查结果
+type Health struct {
+	Target  Target
+	Latency time.Duration // 请求的耗时，失败时为 0
+	TLS     string        // 协商的 TLS 版本，例如 "TLS 1.3"；不是 https 或连接失败时为空
+	Err     error
+}
+
+// 并发请求每个地址，按 targets 的顺序返回结果。client 为 nil 时使用 http.DefaultClient
+func Check(ctx context.Context, client *http.Client, targets []Target, timeout time.Duration) []Health {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	results := make([]Health, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			results[i] = check(ctx, client, t, timeout)
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+func check(ctx context.Context, client *http.Client, t Target, timeout time.Duration) Health {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, t.URL, nil)
+	if err != nil {
+		return Health{Target: t, Err: err}
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Health{Target: t, Err: err}
+	}
+	resp.Body.Close()
+	h := Health{Target: t}
+	if resp.TLS != nil {
+		h.TLS = tls.VersionName(resp.TLS.Version)
+	}
+	if resp.StatusCode >= 400 && !t.AnyStatus {
+		h.Err = fmt.Errorf("HTTP %d", resp.StatusCode)
+		return h
+	}
+	h.Latency = time.Since(start)
+	return h
+}
+
+// 检查失败的原因类别
+const (
+	ProblemNone    = ""
+	ProblemDNS     = "dns"     // 域名无法解析
+	ProblemTimeout = "timeout" // 连接或响应超时
+	ProblemTLS     = "tls"     // 证书无法验证，常见于拦截 HTTPS 的代理或安全软件
+	ProblemHTTP    = "http"    // 服务器返回了错误状态码
+	ProblemOther   = "other"
+)
+
+// 检查失败的原因类别
+func (h Health) Problem() string {
+	if h.Err == nil {
+		return ProblemNone
+	}
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	msg := strings.ToLower(h.Err.Error())
+	switch {
+	case errors.As(h.Err, &dnsErr):
+		return ProblemDNS
+	case errors.As(h.Err, &certErr) || strings.Contains(msg, "x509:") || strings.Contains(msg, "tls:"):
+		return ProblemTLS
+	case errors.Is(h.Err, context.DeadlineExceeded) || strings.Contains(msg, "timeout"):
+		return ProblemTimeout
+	case strings.HasPrefix(h.Err.Error(), "HTTP "):
+		return ProblemHTTP
+	}
+	return ProblemOther
+}
@@ -0,0 +1,99 @@
+package mirror
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		value string
+		want  Mirror
+		ok    bool
+		err   bool
+	}{
+		{"", Mirror{}, false, false},
+		{"Auto", Mirror{}, false, false},
+		{"aliyun", Builtin[1], true, false},
+		{"PyPI", Builtin[2], true, false},
+		{"https://pypi.example.com/simple/", Mirror{"custom", "https://pypi.example.com/simple"}, true, false},
+		{"douban", Mirror{}, false, true},
+	}
+	for _, c := range cases {
+		got, ok, err := Parse(c.value)
+		if got != c.want || ok != c.ok || (err != nil) != c.err {
+			t.Errorf("Parse(%q) = %v, %v, %v", c.value, got, ok, err)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	handler := func(delay time.Duration, status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.WriteHeader(status)
+		}
+	}
+	slow := httptest.NewServer(handler(200*time.Millisecond, http.StatusOK))
+	defer slow.Close()
+	fast := httptest.NewServer(handler(0, http.StatusOK))
+	defer fast.Close()
+	broken := httptest.NewServer(handler(0, http.StatusNotFound))
+	defer broken.Close()
+	hung := httptest.NewServer(handler(800*time.Millisecond, http.StatusOK))
+	defer hung.Close()
+
+	mirrors := []Mirror{{"broken", broken.URL}, {"slow", slow.URL}, {"hung", hung.URL}, {"fast", fast.URL}}
+	results := Probe(context.Background(), nil, mirrors, 500*time.Millisecond)
+	var order []string
+	for _, r := range results {
+		order = append(order, r.Mirror.Name)
+	}
+	if order[0] != "fast" || order[1] != "slow" {
+		t.Errorf("测速顺序 = %v", order)
+	}
+	for _, r := range results[2:] {
+		if r.Err == nil {
+			t.Errorf("%s 应测速失败", r.Mirror.Name)
+		}
+	}
+	if m, ok := Fastest(results); !ok || m.Name != "fast" {
+		t.Errorf("Fastest() = %v, %v", m, ok)
+	}
+	if _, ok := Fastest(Probe(context.Background(), nil, mirrors[:1], time.Second)); ok {
+		t.Errorf("全部失败时 Fastest() 应返回 false")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer secure.Close()
+
+	targets := []Target{
+		{Name: "index", URL: notFound.URL + "/simple/"},
+		{Name: "service", URL: notFound.URL, AnyStatus: true},
+		{Name: "trusted", URL: secure.URL},
+		{Name: "untrusted", URL: secure.URL},
+	}
+	// 信任测试服务器证书的客户端只用于 trusted，其余使用默认客户端
+	results := Check(context.Background(), nil, targets, time.Second)
+	trusted := Check(context.Background(), secure.Client(), targets[2:3], time.Second)[0]
+
+	if got := results[0].Problem(); got != ProblemHTTP {
+		t.Errorf("HTTP 404 的原因 = %q, want %q", got, ProblemHTTP)
+	}
+	if results[1].Err != nil {
+		t.Errorf("AnyStatus 时 404 应算可以访问: %v", results[1].Err)
+	}
+	if trusted.Err != nil || trusted.TLS == "" {
+		t.Errorf("trusted = %+v，应可以访问并报告 TLS 版本", trusted)
+	}
+	if got := results[3].Problem(); got != ProblemTLS {
+		t.Errorf("证书无法验证时的原因 = %q (%v), want %q", got, results[3].Err, ProblemTLS)
+	}
+}
@@ -0,0 +1,260 @@
+// Package models 管理应用需要的语音和模型文件：按清单检查共享模型目录中缺少的文件，
+// 断点续传下载并校验 SHA-256。模型文件很大，不随依赖安装包分发
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 下载中的文件以此为后缀，下次从已下载的位置继续
+const partSuffix = ".part"
+
+// 清单中的一个文件
+type File struct {
+	Name   string `json:"name"`   // 名称，传给应用的环境变量由它生成，例如 zh-voice -> SPEAKMYBOOK_MODEL_ZH_VOICE
+	Path   string `json:"path"`   // 在模型目录中的相对路径，使用 /
+	URL    string `json:"url"`    // 下载地址
+	SHA256 string `json:"sha256"` // 文件的 SHA-256
+	Size   int64  `json:"size"`   // 文件大小（字节），用于显示进度和快速检查，0 表示未知
+	CID    string `json:"cid"`    // 文件在 IPFS 中的内容标识，可以从 IPFS 网关下载，为空时只从 URL 下载
+}
+
+// 模型清单（python/models.json）
+type Manifest struct {
+	Files []File `json:"files"`
+}
+
+// 读取清单。文件不存在时返回 nil，表示应用不需要模型文件
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("模型清单 %s 格式错误: %v", path, err)
+	}
+	for _, f := range m.Files {
+		if f.Name == "" || f.Path == "" || f.URL == "" || len(f.SHA256) != sha256.Size*2 {
+			return nil, fmt.Errorf("模型清单 %s 中的 %q 缺少名称、路径、下载地址或 SHA-256", path, f.Name)
+		}
+		if f.CID != "" && !cidPattern.MatchString(f.CID) {
+			return nil, fmt.Errorf("模型清单 %s 中的 %q 的 IPFS 内容标识无效: %s", path, f.Name, f.CID)
+		}
+		if !filepath.IsLocal(filepath.FromSlash(f.Path)) {
+			return nil, fmt.Errorf("模型清单 %s 中的 %q 路径不在模型目录中: %s", path, f.Name, f.Path)
+		}
+	}
+	return &m, nil
+}
+
+// 文件在模型目录 dir 中的位置
+func (f File) Local(dir string) string {
+	return filepath.Join(dir, filepath.FromSlash(f.Path))
+}
+
+// IPFS 内容标识：CIDv0（Qm 开头的 base58）或 CIDv1（通常为 b 开头的 base32）
+var cidPattern = regexp.MustCompile(`^(Qm[1-9A-HJ-NP-Za-km-z]{44}|b[a-z2-7]{20,})$`)
+
+// 下载 f 的来源，按尝试的顺序排列：清单中的 URL，以及有 CID 时每个 IPFS 网关（例如 https://ipfs.io）上的地址。
+// preferGateway 为 true 时先尝试网关。各来源的内容相同，下载完成后都按 SHA-256 校验，可以从一个来源断开的位置换另一个来源继续
+func (f File) Sources(gateways []string, preferGateway bool) []File {
+	var viaGateway []File
+	if f.CID != "" {
+		for _, gw := range gateways {
+			alt := f
+			alt.URL = strings.TrimSuffix(gw, "/") + "/ipfs/" + f.CID
+			viaGateway = append(viaGateway, alt)
+		}
+	}
+	if preferGateway {
+		return append(viaGateway, f)
+	}
+	return append([]File{f}, viaGateway...)
+}
+
+// 传给应用的环境变量名
+func (f File) EnvName() string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, f.Name)
+	return "SPEAKMYBOOK_MODEL_" + name
+}
+
+// dir 中缺少或大小不对的文件。只比较大小，不计算 SHA-256：下载完成时已经校验过，每次启动都计算太慢
+func Missing(dir string, files []File) []File {
+	var missing []File
+	for _, f := range files {
+		info, err := os.Stat(f.Local(dir))
+		if err != nil || f.Size > 0 && info.Size() != f.Size {
+			missing = append(missing, f)
+		}
+	}
+	return missing
+}
+
+// 校验 dir 中的文件，返回 SHA-256 不匹配或无法读取的文件
+func Verify(dir string, files []File) []File {
+	var bad []File
+	for _, f := range files {
+		if got, err := fileSHA256(f.Local(dir)); err != nil || !strings.EqualFold(got, f.SHA256) {
+			bad = append(bad, f)
+		}
+	}
+	return bad
+}
+
+// 下载 f 到 dir。之前未下载完的部分保留在 <文件>.part 中，从断开的位置继续；
+// 下载完成后校验 SHA-256，不匹配时删除下载的文件。progress 在每写入一块数据后调用，可以为 nil
+func Download(ctx context.Context, client *http.Client, dir string, f File, progress func(done, total int64)) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	dest := f.Local(dir)
+	part := dest + partSuffix
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.URL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// 已下载的部分和文件一样大（或更大），直接校验
+		resp.Body.Close()
+		return finish(part, dest, f)
+	case resp.StatusCode == http.StatusOK:
+		// 服务器不支持断点续传，从头下载
+		flags |= os.O_TRUNC
+		offset = 0
+	default:
+		return fmt.Errorf("下载 %s 失败: 服务器返回 %s", f.URL, resp.Status)
+	}
+
+	total := f.Size
+	if total <= 0 && resp.ContentLength > 0 {
+		total = offset + resp.ContentLength
+	}
+	out, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return err
+	}
+	done := offset
+	buf := make([]byte, 256*1024)
+	for {
+		n, rerr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := out.Write(buf[:n]); err != nil {
+				out.Close()
+				return err
+			}
+			done += int64(n)
+			if progress != nil {
+				progress(done, total)
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			out.Close()
+			return fmt.Errorf("下载 %s 中断: %v", f.URL, rerr)
+		}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return finish(part, dest, f)
+}
+
+// 从另一个模型目录 src（例如局域网共享目录）复制 f 到 dir，同样校验 SHA-256，不匹配时不使用复制的文件
+func CopyFrom(src, dir string, f File) error {
+	in, err := os.Open(f.Local(src))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	dest := f.Local(dir)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	part := dest + partSuffix
+	out, err := os.Create(part)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(part)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return finish(part, dest, f)
+}
+
+// 校验下载完的文件，通过后改为正式的文件名
+func finish(part, dest string, f File) error {
+	got, err := fileSHA256(part)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(got, f.SHA256) {
+		os.Remove(part)
+		return fmt.Errorf("%s 的校验值不匹配（期望 %s，实际 %s），已删除下载的文件", f.Path, f.SHA256, got)
+	}
+	return os.Rename(part, dest)
+}
+
+// 计算文件的 SHA-256
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadResume(t *testing.T) {
+	content := bytes.Repeat([]byte("speakmybook"), 100000)
+	sum := sha256.Sum256(content)
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "voice.onnx", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	f := File{Name: "zh-voice", Path: "voices/zh.onnx", URL: srv.URL, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+	if got := Missing(dir, []File{f}); len(got) != 1 {
+		t.Fatalf("Missing() = %v，文件还没有下载", got)
+	}
+	// 上次下载到一半
+	os.MkdirAll(filepath.Join(dir, "voices"), 0755)
+	os.WriteFile(f.Local(dir)+partSuffix, content[:400000], 0644)
+
+	var last int64
+	if err := Download(context.Background(), nil, dir, f, func(done, total int64) { last = done }); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=400000-" {
+		t.Errorf("Range = %v，应从已下载的位置继续", ranges)
+	}
+	if last != f.Size {
+		t.Errorf("进度 = %d, want %d", last, f.Size)
+	}
+	if got, _ := os.ReadFile(f.Local(dir)); !bytes.Equal(got, content) {
+		t.Errorf("下载的内容不一致")
+	}
+	if len(Missing(dir, []File{f})) != 0 || len(Verify(dir, []File{f})) != 0 {
+		t.Errorf("下载完成后不应缺少文件")
+	}
+	if f.EnvName() != "SPEAKMYBOOK_MODEL_ZH_VOICE" {
+		t.Errorf("EnvName() = %s", f.EnvName())
+	}
+
+	// 校验值不匹配时删除下载的文件
+	bad := f
+	bad.Path = "voices/bad.onnx"
+	bad.SHA256 = hex.EncodeToString(make([]byte, sha256.Size))
+	if err := Download(context.Background(), nil, dir, bad, nil); err == nil {
+		t.Errorf("校验值不匹配时应返回错误")
+	}
+	if _, err := os.Stat(bad.Local(dir) + partSuffix); !os.IsNotExist(err) {
+		t.Errorf("校验失败的文件应被删除")
+	}
+	if _, err := os.Stat(bad.Local(dir)); !os.IsNotExist(err) {
+		t.Errorf("校验失败时不应生成文件")
+	}
+
+	// 从另一个模型目录复制，同样校验
+	other := t.TempDir()
+	if err := CopyFrom(dir, other, f); err != nil || len(Missing(other, []File{f})) != 0 {
+		t.Errorf("CopyFrom() error = %v", err)
+	}
+	os.WriteFile(bad.Local(dir), content, 0644)
+	if err := CopyFrom(dir, other, bad); err == nil {
+		t.Errorf("复制的文件校验值不匹配时应返回错误")
+	}
+	if _, err := os.Stat(bad.Local(other)); !os.IsNotExist(err) {
+		t.Errorf("校验失败时不应生成文件")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if m, err := Load(filepath.Join(dir, "models.json")); m != nil || err != nil {
+		t.Errorf("没有清单时 Load() = %v, %v", m, err)
+	}
+	path := filepath.Join(dir, "models.json")
+	os.WriteFile(path, []byte(`{"files": [{"name": "a", "path": "../a.bin", "url": "https://example.com/a", "sha256": "`+hex.EncodeToString(make([]byte, sha256.Size))+`"}]}`), 0644)
+	if _, err := Load(path); err == nil {
+		t.Errorf("路径在模型目录之外时应返回错误")
+	}
+	os.WriteFile(path, []byte(`{"files": [{"name": "a", "path": "a.bin", "url": "https://example.com/a", "cid": "not a cid", "sha256": "`+hex.EncodeToString(make([]byte, sha256.Size))+`"}]}`), 0644)
+	if _, err := Load(path); err == nil {
+		t.Errorf("IPFS 内容标识无效时应返回错误")
+	}
+}
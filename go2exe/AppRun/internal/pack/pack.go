@@ -0,0 +1,254 @@
+// Package pack 把启动器、Python 项目、uv 安装文件和离线安装包打包成带版本信息的 Windows 安装程序（NSIS 或 MSI），
+// 可选用 signtool 签名，代替直接压缩程序目录分发
+package pack
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go2exe/internal/appupdate"
+	"go2exe/internal/install"
+	"go2exe/internal/runner"
+)
+
+// 安装程序的格式
+const (
+	NSIS = "nsis" // makensis 生成的 setup.exe
+	MSI  = "msi"  // WiX Toolset 4 或更高版本生成的 .msi
+)
+
+// 启动器在程序目录中的文件名
+const LauncherName = "SpeakMyBook.exe"
+
+// 打包设置
+type Options struct {
+	Root      string // 程序目录的源文件所在目录（仓库根目录）：python、uv、wheels、checksums.txt 等
+	Launcher  string // 编译好的启动器，为空时使用 Root 下的 SpeakMyBook.exe
+	Format    string // NSIS 或 MSI
+	Output    string // 生成的安装程序，为空时放在 Root/dist 下，以版本号命名
+	Version   string // 为空时使用 python/pyproject.toml 中的版本
+	Publisher string // 写入版本信息和“应用和功能”列表的发布者
+	Sign      Signer // Cert 为空时不签名
+	WorkDir   string // 生成脚本和暂存启动器的目录，为空时使用临时目录，结束后删除
+	Runner    runner.CommandRunner
+	Out       io.Writer // 打包工具的输出，为 nil 时不输出
+}
+
+// 要放进安装程序的文件
+type File struct {
+	Src  string // 源文件的路径
+	Dest string // 在程序目录中的相对路径（/ 分隔）
+}
+
+// 程序目录中要分发的文件和目录。没有的可选项直接跳过
+var (
+	requiredFiles = []string{"checksums.txt"}
+	optionalFiles = []string{"apprun.toml", "LICENSE", "README.md"}
+	requiredDirs  = []string{"python", "uv"}
+	optionalDirs  = []string{"wheels", "pwsh"}
+)
+
+// 不分发的开发和运行时文件：虚拟环境、缓存、构建输出和日志
+var excluded = regexp.MustCompile(`(^|/)(\.venv|__pycache__|build|dist|[^/]*\.egg-info|\.git)(/|$)|\.py[oc]$|(^|/)app\.log$`)
+
+// 按 Root 收集要分发的文件，按目标路径排序。收集前按 checksums.txt 校验 uv 和 Python 的安装文件，
+// 校验不通过的包装出去启动器也会拒绝安装
+func Collect(opts Options) ([]File, error) {
+	for _, dir := range requiredDirs {
+		if err := install.VerifyArtifacts(opts.Root, dir); err != nil {
+			return nil, err
+		}
+	}
+	launcher := opts.Launcher
+	if launcher == "" {
+		launcher = filepath.Join(opts.Root, LauncherName)
+	}
+	if _, err := os.Stat(launcher); err != nil {
+		return nil, fmt.Errorf("找不到编译好的启动器，请先按 build_notice.md 编译: %v", err)
+	}
+	files := []File{{Src: launcher, Dest: LauncherName}}
+
+	for _, name := range slices.Concat(requiredFiles, optionalFiles) {
+		src := filepath.Join(opts.Root, name)
+		if _, err := os.Stat(src); err != nil {
+			if slices.Contains(requiredFiles, name) {
+				return nil, err
+			}
+			continue
+		}
+		files = append(files, File{Src: src, Dest: name})
+	}
+	for _, dir := range slices.Concat(requiredDirs, optionalDirs) {
+		src := filepath.Join(opts.Root, dir)
+		if _, err := os.Stat(src); err != nil {
+			if slices.Contains(requiredDirs, dir) {
+				return nil, err
+			}
+			continue
+		}
+		err := filepath.WalkDir(src, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(opts.Root, p)
+			rel = filepath.ToSlash(rel)
+			if excluded.MatchString(rel) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				files = append(files, File{Src: p, Dest: rel})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(files, func(a, b int) bool { return files[a].Dest < files[b].Dest })
+	return files, nil
+}
+
+// 版本号开头的数字部分
+var versionPrefix = regexp.MustCompile(`^\d+(\.\d+)*`)
+
+// 安装程序版本信息中的数字版本：去掉预发布等后缀，补足或截断为 n 段，例如 1.2rc1 -> 1.2.0（n 为 3）
+func numericVersion(version string, n int) string {
+	parts := strings.Split(versionPrefix.FindString(version), ".")
+	nums := make([]string, n)
+	for i := range nums {
+		v := 0
+		if i < len(parts) {
+			v, _ = strconv.Atoi(parts[i])
+		}
+		nums[i] = strconv.Itoa(v)
+	}
+	return strings.Join(nums, ".")
+}
+
+// 生成安装程序，返回它的路径
+func Build(opts Options) (string, error) {
+	if opts.Format != NSIS && opts.Format != MSI {
+		return "", fmt.Errorf("不支持的格式 %q，只能是 %s 或 %s", opts.Format, NSIS, MSI)
+	}
+	if opts.Runner == nil {
+		opts.Runner = runner.Exec{}
+	}
+	if opts.Out == nil {
+		opts.Out = io.Discard
+	}
+	if opts.Version == "" {
+		v, err := appupdate.ProjectVersion(filepath.Join(opts.Root, "python"))
+		if err != nil {
+			return "", err
+		}
+		opts.Version = v
+	}
+	if opts.Output == "" {
+		ext := "-setup.exe"
+		if opts.Format == MSI {
+			ext = ".msi"
+		}
+		opts.Output = filepath.Join(opts.Root, "dist", "SpeakMyBook-"+opts.Version+ext)
+	}
+	output, err := filepath.Abs(opts.Output)
+	if err != nil {
+		return "", err
+	}
+	files, err := Collect(opts)
+	if err != nil {
+		return "", err
+	}
+
+	work := opts.WorkDir
+	if work == "" {
+		if work, err = os.MkdirTemp("", "speakmybook-pack-"); err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(work)
+	} else if err := os.MkdirAll(work, 0755); err != nil {
+		return "", err
+	}
+	// 签名会修改启动器，先复制一份，不改动编译输出
+	if opts.Sign.Cert != "" {
+		staged := filepath.Join(work, LauncherName)
+		if err := copyFile(files[launcherIndex(files)].Src, staged); err != nil {
+			return "", err
+		}
+		if err := opts.Sign.sign(opts, staged); err != nil {
+			return "", err
+		}
+		files[launcherIndex(files)].Src = staged
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(opts.Out, "打包 SpeakMyBook %s：%d 个文件 -> %s\n", opts.Version, len(files), output)
+	info := packageInfo{Version: opts.Version, Publisher: opts.Publisher, Output: output, Files: files}
+	var cmd runner.Command
+	switch opts.Format {
+	case NSIS:
+		script := filepath.Join(work, "installer.nsi")
+		if err := writeScript(script, nsisTemplate, info); err != nil {
+			return "", err
+		}
+		cmd = runner.Command{Name: "makensis", Args: []string{"-V2", "-INPUTCHARSET", "UTF8", script}}
+	case MSI:
+		script := filepath.Join(work, "SpeakMyBook.wxs")
+		if err := writeScript(script, wixTemplate, info); err != nil {
+			return "", err
+		}
+		cmd = runner.Command{Name: "wix", Args: []string{"build", "-arch", "x64", "-o", output, script}}
+	}
+	err = opts.Runner.Stream(cmd, func(line string, isError bool) { fmt.Fprintln(opts.Out, line) })
+	if err != nil {
+		return "", fmt.Errorf("%s 失败: %v", cmd.Name, err)
+	}
+	if opts.Sign.Cert != "" {
+		if err := opts.Sign.sign(opts, output); err != nil {
+			return "", err
+		}
+	}
+	return output, nil
+}
+
+// 启动器在文件列表中的位置
+func launcherIndex(files []File) int {
+	return slices.IndexFunc(files, func(f File) bool { return f.Dest == LauncherName })
+}
+
+// 目标路径所在的目录，程序目录本身为空字符串
+func destDir(dest string) string {
+	if dir := path.Dir(dest); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
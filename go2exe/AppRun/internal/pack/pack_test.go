@@ -0,0 +1,144 @@
+package pack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go2exe/internal/runner"
+)
+
+// 在临时目录中准备一个程序目录，返回它的路径
+func writeRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"SpeakMyBook.exe":            "launcher",
+		"apprun.toml":                "",
+		"python/pyproject.toml":      "[project]\nname = \"app\"\nversion = \"1.2rc1\"\n",
+		"python/app.pyw":             "",
+		"python/20240814/cpython.gz": "python",
+		"python/.venv/pyvenv.cfg":    "",
+		"python/__pycache__/a.pyc":   "",
+		"python/app.log":             "",
+		"uv/uv-installer.ps1":        "uv",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var sums strings.Builder
+	for _, name := range []string{"uv/uv-installer.ps1", "python/20240814/cpython.gz"} {
+		sum := sha256.Sum256([]byte(files[name]))
+		sums.WriteString(hex.EncodeToString(sum[:]) + "  " + name + "\n")
+	}
+	if err := os.WriteFile(filepath.Join(root, "checksums.txt"), []byte(sums.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestCollect(t *testing.T) {
+	root := writeRoot(t)
+	files, err := Collect(Options{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dests []string
+	for _, f := range files {
+		dests = append(dests, f.Dest)
+	}
+	want := "SpeakMyBook.exe apprun.toml checksums.txt python/20240814/cpython.gz python/app.pyw python/pyproject.toml uv/uv-installer.ps1"
+	if got := strings.Join(dests, " "); got != want {
+		t.Errorf("Collect() = %s\nwant %s", got, want)
+	}
+
+	// 安装文件被改动后拒绝打包
+	os.WriteFile(filepath.Join(root, "uv", "uv-installer.ps1"), []byte("changed"), 0644)
+	if _, err := Collect(Options{Root: root}); err == nil {
+		t.Error("校验不通过时应返回错误")
+	}
+}
+
+func TestNumericVersion(t *testing.T) {
+	cases := []struct {
+		version string
+		n       int
+		want    string
+	}{
+		{"0.1.0", 3, "0.1.0"},
+		{"1.2rc1", 4, "1.2.0.0"},
+		{"2.03.4.5", 3, "2.3.4"},
+		{"dev", 3, "0.0.0"},
+	}
+	for _, c := range cases {
+		if got := numericVersion(c.version, c.n); got != c.want {
+			t.Errorf("numericVersion(%q, %d) = %q, want %q", c.version, c.n, got, c.want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	root := writeRoot(t)
+	for _, format := range []string{NSIS, MSI} {
+		t.Run(format, func(t *testing.T) {
+			m := &runner.Mock{}
+			work := t.TempDir()
+			output, err := Build(Options{
+				Root:      root,
+				Format:    format,
+				Publisher: `A & "B"`,
+				Sign:      Signer{Cert: "cert.pfx", Password: "secret"},
+				WorkDir:   work,
+				Runner:    m,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{NSIS: "SpeakMyBook-1.2rc1-setup.exe", MSI: "SpeakMyBook-1.2rc1.msi"}[format]
+			if output != filepath.Join(root, "dist", want) {
+				t.Errorf("Build() = %s，应为 dist 下的 %s", output, want)
+			}
+
+			// 先签名暂存的启动器，再打包，最后签名安装程序
+			lines := m.CommandLines()
+			if len(lines) != 3 || !strings.HasPrefix(lines[0], "signtool sign") || !strings.HasSuffix(lines[0], filepath.Join(work, LauncherName)) ||
+				!strings.HasSuffix(lines[2], output) {
+				t.Fatalf("执行的命令 = %q", lines)
+			}
+			if data, _ := os.ReadFile(filepath.Join(root, LauncherName)); string(data) != "launcher" {
+				t.Error("不应修改编译输出的启动器")
+			}
+
+			var script string
+			if format == NSIS {
+				data, _ := os.ReadFile(filepath.Join(work, "installer.nsi"))
+				script = string(data)
+				for _, want := range []string{`VIProductVersion "1.2.0.0"`, `"CompanyName" "A & $\"B$\""`, `SetOutPath "$INSTDIR\python\20240814"`, `RMDir /r "$INSTDIR\uv"`, `Delete "$INSTDIR\apprun.toml"`, filepath.Join(work, LauncherName)} {
+					if !strings.Contains(script, want) {
+						t.Errorf("NSIS 脚本中缺少 %s", want)
+					}
+				}
+			} else {
+				data, _ := os.ReadFile(filepath.Join(work, "SpeakMyBook.wxs"))
+				script = string(data)
+				for _, want := range []string{`Version="1.2.0"`, `Manufacturer="A &amp; &#34;B&#34;"`, `<Directory Id="d2" Name="20240814">`, `<ComponentRef Id="c_d2" />`, `FileRef="f0"`} {
+					if !strings.Contains(script, want) {
+						t.Errorf("WiX 脚本中缺少 %s", want)
+					}
+				}
+			}
+			if strings.Contains(script, ".venv") || strings.Contains(script, "__pycache__") {
+				t.Error("虚拟环境和缓存不应打包")
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package pack
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// MSI 的升级代码，同一产品的所有版本必须相同，已发布后不要修改
+const upgradeCode = "6F1E0C52-3B7A-4D8E-9A41-2C5D7B9E8F13"
+
+// 生成安装脚本用的数据
+type packageInfo struct {
+	Version   string
+	Publisher string
+	Output    string
+	Files     []File
+}
+
+// 一个目录中的文件
+type dirFiles struct {
+	Path  string // 相对于程序目录，\ 分隔，程序目录本身为空字符串
+	Files []File
+}
+
+// 按目录分组的文件，目录按首次出现的顺序排列
+func (p packageInfo) Dirs() []dirFiles {
+	var dirs []dirFiles
+	index := map[string]int{}
+	for _, f := range p.Files {
+		dir := destDir(f.Dest)
+		n, ok := index[dir]
+		if !ok {
+			n = len(dirs)
+			index[dir] = n
+			dirs = append(dirs, dirFiles{Path: strings.ReplaceAll(dir, "/", `\`)})
+		}
+		dirs[n].Files = append(dirs[n].Files, f)
+	}
+	return dirs
+}
+
+// 程序目录下的顶层文件（dirs 为 false）或目录（dirs 为 true），卸载时只删除这些，不删除安装目录中的其他内容
+func (p packageInfo) topLevel(dirs bool) []string {
+	var names []string
+	for _, f := range p.Files {
+		name, _, isDir := strings.Cut(f.Dest, "/")
+		if isDir == dirs && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (p packageInfo) TopFiles() []string { return p.topLevel(false) }
+func (p packageInfo) TopDirs() []string  { return p.topLevel(true) }
+
+func (p packageInfo) FileVersion() string { return numericVersion(p.Version, 4) }
+func (p packageInfo) MSIVersion() string  { return numericVersion(p.Version, 3) }
+func (p packageInfo) UpgradeCode() string { return upgradeCode }
+
+// WiX 中的目录
+type wixDir struct {
+	ID, Name string
+	Root     bool
+	Files    []wixFile
+	Dirs     []*wixDir
+}
+
+type wixFile struct {
+	ID, Name, Src string
+	Launcher      bool
+}
+
+// 按目标路径生成 WiX 的目录树，每个目录和文件都有唯一的 Id
+func (p packageInfo) Tree() *wixDir {
+	root := &wixDir{ID: "INSTALLFOLDER", Name: "SpeakMyBook", Root: true}
+	dirs := map[string]*wixDir{"": root}
+	var dirFor func(dir string) *wixDir
+	dirFor = func(dir string) *wixDir {
+		if d, ok := dirs[dir]; ok {
+			return d
+		}
+		parent := dirFor(destDir(dir))
+		d := &wixDir{ID: fmt.Sprintf("d%d", len(dirs)), Name: path.Base(dir)}
+		parent.Dirs = append(parent.Dirs, d)
+		dirs[dir] = d
+		return d
+	}
+	for n, f := range p.Files {
+		d := dirFor(destDir(f.Dest))
+		d.Files = append(d.Files, wixFile{ID: fmt.Sprintf("f%d", n), Name: path.Base(f.Dest), Src: f.Src, Launcher: f.Dest == LauncherName})
+	}
+	return root
+}
+
+// 目录树中的所有目录，每个目录一个组件
+func (d *wixDir) All() []*wixDir {
+	all := []*wixDir{d}
+	for _, sub := range d.Dirs {
+		all = append(all, sub.All()...)
+	}
+	return all
+}
+
+var scriptFuncs = template.FuncMap{
+	// NSIS 字符串中的 $ 和双引号需要转义
+	"nsis": strings.NewReplacer("$", "$$", `"`, `$\"`).Replace,
+	"xml": func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	},
+	"base": path.Base,
+}
+
+// 按模板写入安装脚本（UTF-8）
+func writeScript(file string, tmpl *template.Template, info packageInfo) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(f, info); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// NSIS 安装脚本。启动器要在程序目录中创建虚拟环境和写日志，默认安装到当前用户可写的
+// %LOCALAPPDATA%\Programs\SpeakMyBook，不需要管理员权限
+var nsisTemplate = template.Must(template.New("nsis").Funcs(scriptFuncs).Parse(`; 由 go run ./cmd/pack 生成，不要手动修改
+Unicode true
+ManifestDPIAware true
+SetCompressor /SOLID lzma
+!include "MUI2.nsh"
+
+!define APP "SpeakMyBook"
+!define UNINSTALL_KEY "Software\Microsoft\Windows\CurrentVersion\Uninstall\SpeakMyBook"
+
+Name "${APP}"
+OutFile "{{nsis .Output}}"
+InstallDir "$LOCALAPPDATA\Programs\${APP}"
+InstallDirRegKey HKCU "${UNINSTALL_KEY}" "InstallLocation"
+RequestExecutionLevel user
+
+!define MUI_FINISHPAGE_RUN "$INSTDIR\SpeakMyBook.exe"
+!insertmacro MUI_PAGE_DIRECTORY
+!insertmacro MUI_PAGE_INSTFILES
+!insertmacro MUI_PAGE_FINISH
+!insertmacro MUI_UNPAGE_CONFIRM
+!insertmacro MUI_UNPAGE_INSTFILES
+!insertmacro MUI_LANGUAGE "SimpChinese"
+!insertmacro MUI_LANGUAGE "English"
+
+VIProductVersion "{{.FileVersion}}"
+VIAddVersionKey /LANG=${LANG_SIMPCHINESE} "ProductName" "${APP}"
+VIAddVersionKey /LANG=${LANG_SIMPCHINESE} "ProductVersion" "{{nsis .Version}}"
+VIAddVersionKey /LANG=${LANG_SIMPCHINESE} "FileVersion" "{{nsis .Version}}"
+VIAddVersionKey /LANG=${LANG_SIMPCHINESE} "CompanyName" "{{nsis .Publisher}}"
+VIAddVersionKey /LANG=${LANG_SIMPCHINESE} "FileDescription" "${APP} 安装程序"
+
+Section "${APP}"
+{{- range .Dirs}}
+  SetOutPath "$INSTDIR{{if .Path}}\{{nsis .Path}}{{end}}"
+{{- range .Files}}
+  File "/oname={{nsis (base .Dest)}}" "{{nsis .Src}}"
+{{- end}}
+{{- end}}
+  SetOutPath "$INSTDIR"
+  WriteUninstaller "$INSTDIR\uninstall.exe"
+  CreateShortcut "$SMPROGRAMS\${APP}.lnk" "$INSTDIR\SpeakMyBook.exe"
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "DisplayName" "${APP}"
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "DisplayVersion" "{{nsis .Version}}"
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "Publisher" "{{nsis .Publisher}}"
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "DisplayIcon" "$INSTDIR\SpeakMyBook.exe"
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "InstallLocation" "$INSTDIR"
+  WriteRegStr HKCU "${UNINSTALL_KEY}" "UninstallString" '"$INSTDIR\uninstall.exe"'
+  WriteRegDWORD HKCU "${UNINSTALL_KEY}" "NoModify" 1
+  WriteRegDWORD HKCU "${UNINSTALL_KEY}" "NoRepair" 1
+SectionEnd
+
+Section "Uninstall"
+  ; 先由启动器卸载它安装的 Python、虚拟环境、右键菜单、文件关联和快捷方式
+  ExecWait '"$INSTDIR\SpeakMyBook.exe" --uninstall --silent'
+  Delete "$SMPROGRAMS\${APP}.lnk"
+{{- range .TopDirs}}
+  RMDir /r "$INSTDIR\{{nsis .}}"
+{{- end}}
+{{- range .TopFiles}}
+  Delete "$INSTDIR\{{nsis .}}"
+{{- end}}
+  RMDir /r "$INSTDIR\runtime"
+  Delete "$INSTDIR\app.log"
+  Delete "$INSTDIR\uninstall.exe"
+  RMDir "$INSTDIR"
+  DeleteRegKey HKCU "${UNINSTALL_KEY}"
+SectionEnd
+`))
+
+// WiX 4 的安装脚本：与 NSIS 版相同，按用户安装到 %LOCALAPPDATA%\Programs\SpeakMyBook。
+// 每个目录一个组件，以 HKCU 下的注册表值为 KeyPath，卸载时先由启动器清理它安装的环境
+var wixTemplate = template.Must(template.New("wix").Funcs(scriptFuncs).Parse(`<?xml version="1.0" encoding="utf-8"?>
+<!-- 由 go run ./cmd/pack 生成，不要手动修改 -->
+<Wix xmlns="http://wixtoolset.org/schemas/v4/wxs">
+  <Package Name="SpeakMyBook" Manufacturer="{{xml .Publisher}}" Version="{{.MSIVersion}}" UpgradeCode="{{.UpgradeCode}}" Scope="perUser">
+    <MajorUpgrade DowngradeErrorMessage="A newer version of SpeakMyBook is already installed." />
+    <MediaTemplate EmbedCab="yes" CompressionLevel="high" />
+{{- $tree := .Tree}}
+{{- range $tree.Files}}{{if .Launcher}}
+    <Icon Id="SpeakMyBook.exe" SourceFile="{{xml .Src}}" />
+    <Property Id="ARPPRODUCTICON" Value="SpeakMyBook.exe" />
+    <CustomAction Id="CleanupEnvironment" FileRef="{{.ID}}" ExeCommand="--uninstall --silent" Execute="immediate" Return="ignore" />
+{{- end}}{{end}}
+    <InstallExecuteSequence>
+      <Custom Action="CleanupEnvironment" Before="RemoveFiles" Condition="REMOVE=&quot;ALL&quot; AND NOT UPGRADINGPRODUCTCODE" />
+    </InstallExecuteSequence>
+    <StandardDirectory Id="ProgramMenuFolder" />
+    <StandardDirectory Id="LocalAppDataFolder">
+      <Directory Id="ProgramsFolder" Name="Programs">
+{{template "dir" $tree}}
+      </Directory>
+    </StandardDirectory>
+    <Feature Id="Main" Title="SpeakMyBook">
+{{- range $tree.All}}
+      <ComponentRef Id="c_{{.ID}}" />
+{{- end}}
+    </Feature>
+  </Package>
+</Wix>
+{{define "dir"}}<Directory Id="{{.ID}}" Name="{{xml .Name}}">
+<Component Id="c_{{.ID}}">
+  <RegistryValue Root="HKCU" Key="Software\SpeakMyBook\Installer" Name="{{.ID}}" Type="integer" Value="1" KeyPath="yes" />
+  <RemoveFolder Id="rm_{{.ID}}" On="uninstall" />
+{{- if .Root}}
+  <RemoveFile Id="rm_log" Name="app.log" On="uninstall" />
+  <Shortcut Id="StartMenuShortcut" Directory="ProgramMenuFolder" Name="SpeakMyBook" Target="[INSTALLFOLDER]SpeakMyBook.exe" WorkingDirectory="INSTALLFOLDER" />
+{{- end}}
+{{- range .Files}}
+  <File Id="{{.ID}}" Name="{{xml .Name}}" Source="{{xml .Src}}" />
+{{- end}}
+</Component>
+{{- range .Dirs}}
+{{template "dir" .}}
+{{- end}}
+</Directory>{{end}}`))
@@ -0,0 +1,45 @@
+package pack
+
+import (
+	"fmt"
+
+	"go2exe/internal/runner"
+)
+
+// 默认的时间戳服务器，证书过期后签名仍然有效
+const defaultTimestampURL = "http://timestamp.digicert.com"
+
+// 用 signtool 对启动器和生成的安装程序做 Authenticode 签名
+type Signer struct {
+	Tool         string // signtool 的路径，为空时在 PATH 中查找
+	Cert         string // .pfx 证书文件
+	Password     string // 证书密码
+	TimestampURL string // 为空时使用 defaultTimestampURL
+}
+
+// signtool 的参数，不包含要签名的文件
+func (s Signer) args() []string {
+	args := []string{"sign", "/fd", "SHA256", "/f", s.Cert}
+	if s.Password != "" {
+		args = append(args, "/p", s.Password)
+	}
+	ts := s.TimestampURL
+	if ts == "" {
+		ts = defaultTimestampURL
+	}
+	return append(args, "/tr", ts, "/td", "SHA256")
+}
+
+func (s Signer) sign(opts Options, file string) error {
+	tool := s.Tool
+	if tool == "" {
+		tool = "signtool"
+	}
+	fmt.Fprintf(opts.Out, "签名 %s\n", file)
+	out, err := opts.Runner.Output(runner.Command{Name: tool, Args: append(s.args(), file)})
+	if err != nil {
+		// 输出中没有证书密码，可以直接显示
+		return fmt.Errorf("签名 %s 失败: %v\n%s", file, err, out)
+	}
+	return nil
+}
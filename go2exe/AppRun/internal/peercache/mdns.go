@@ -0,0 +1,275 @@
+package peercache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 在局域网中发现共享缓存的 DNS-SD 服务类型
+const Service = "_speakmybook-cache._tcp.local."
+
+// mDNS 的组播地址
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// 用到的 DNS 记录类型
+const (
+	typePTR = 12
+	typeSRV = 33
+	typeANY = 255
+	classIN = 1
+)
+
+// 应答中记录的有效期（秒）
+const recordTTL = 120
+
+// 按 DNS 格式编码域名，不压缩
+func appendName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+func appendRecord(b []byte, name string, typ uint16, rdata []byte) []byte {
+	b = appendName(b, name)
+	b = binary.BigEndian.AppendUint16(b, typ)
+	b = binary.BigEndian.AppendUint16(b, classIN)
+	b = binary.BigEndian.AppendUint32(b, recordTTL)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(rdata)))
+	return append(b, rdata...)
+}
+
+// 查询共享缓存服务的 PTR 记录
+func buildQuery() []byte {
+	b := make([]byte, 12)
+	binary.BigEndian.PutUint16(b[4:], 1) // 一个问题
+	b = appendName(b, Service)
+	b = binary.BigEndian.AppendUint16(b, typePTR)
+	return binary.BigEndian.AppendUint16(b, classIN)
+}
+
+// 对查询的应答：PTR 指向以 instance 命名的服务实例，SRV 给出 HTTP 端口。
+// 地址取应答的来源地址，不附带 A 记录
+func buildResponse(id uint16, instance string, port int) []byte {
+	b := make([]byte, 12)
+	binary.BigEndian.PutUint16(b[0:], id)
+	binary.BigEndian.PutUint16(b[2:], 0x8400) // 应答，权威
+	binary.BigEndian.PutUint16(b[6:], 2)
+	full := instance + "." + Service
+	b = appendRecord(b, Service, typePTR, appendName(nil, full))
+	srv := []byte{0, 0, 0, 0} // 优先级和权重
+	srv = binary.BigEndian.AppendUint16(srv, uint16(port))
+	srv = appendName(srv, instance+".local.")
+	return appendRecord(b, full, typeSRV, srv)
+}
+
+// 实例名称：DNS 标签最长 63 字节，只保留字母、数字和 -
+func instanceName(host string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '-'
+	}, host)
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	if name == "" {
+		name = "speakmybook"
+	}
+	return name
+}
+
+// DNS 消息中的一个问题或记录
+type dnsEntry struct {
+	name  string
+	typ   uint16
+	rdata []byte // 记录的数据，问题为空
+}
+
+// 解析后的 DNS 消息
+type dnsMessage struct {
+	id        uint16
+	response  bool
+	questions []dnsEntry
+	answers   []dnsEntry // 包括附加记录
+}
+
+var errMalformed = errors.New("DNS 消息格式错误")
+
+// 读取 off 处的域名，支持压缩指针，返回域名和之后的位置
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errMalformed
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case n&0xC0 == 0xC0:
+			if off+1 >= len(msg) || jumps > 10 {
+				return "", 0, errMalformed
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+			jumps++
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
+
+func parseMessage(msg []byte) (*dnsMessage, error) {
+	if len(msg) < 12 {
+		return nil, errMalformed
+	}
+	m := &dnsMessage{id: binary.BigEndian.Uint16(msg), response: msg[2]&0x80 != 0}
+	counts := [4]int{}
+	for i := range counts {
+		counts[i] = int(binary.BigEndian.Uint16(msg[4+2*i:]))
+	}
+	off := 12
+	for i := 0; i < counts[0]; i++ {
+		name, next, err := readName(msg, off)
+		if err != nil || next+4 > len(msg) {
+			return nil, errMalformed
+		}
+		m.questions = append(m.questions, dnsEntry{name: name, typ: binary.BigEndian.Uint16(msg[next:])})
+		off = next + 4
+	}
+	for i := 0; i < counts[1]+counts[2]+counts[3]; i++ {
+		name, next, err := readName(msg, off)
+		if err != nil || next+10 > len(msg) {
+			return nil, errMalformed
+		}
+		size := int(binary.BigEndian.Uint16(msg[next+8:]))
+		if next+10+size > len(msg) {
+			return nil, errMalformed
+		}
+		m.answers = append(m.answers, dnsEntry{name: name, typ: binary.BigEndian.Uint16(msg[next:]), rdata: msg[next+10 : next+10+size]})
+		off = next + 10 + size
+	}
+	return m, nil
+}
+
+// 是否在查询共享缓存服务
+func (m *dnsMessage) asksService() bool {
+	if m.response {
+		return false
+	}
+	for _, q := range m.questions {
+		if strings.EqualFold(q.name, Service) && (q.typ == typePTR || q.typ == typeANY) {
+			return true
+		}
+	}
+	return false
+}
+
+// 应答中的服务实例和它们的端口
+func (m *dnsMessage) instances() map[string]int {
+	found := map[string]int{}
+	if !m.response {
+		return found
+	}
+	for _, a := range m.answers {
+		instance, ok := strings.CutSuffix(strings.ToLower(a.name), "."+Service)
+		if a.typ != typeSRV || !ok || len(a.rdata) < 6 {
+			continue
+		}
+		found[instance] = int(binary.BigEndian.Uint16(a.rdata[4:]))
+	}
+	return found
+}
+
+// 在局域网中应答共享缓存服务的 mDNS 查询，直到 ctx 取消
+func announce(ctx context.Context, instance string, port int) error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		m, err := parseMessage(buf[:n])
+		if err != nil || !m.asksService() {
+			continue
+		}
+		// 不是从 5353 端口发出的查询（Discover 的一次性查询）直接回复给查询方，否则按 mDNS 组播应答
+		to, id := mdnsGroup, uint16(0)
+		if src.Port != mdnsGroup.Port {
+			to, id = src, m.id
+		}
+		if _, err := conn.WriteToUDP(buildResponse(id, instance, port), to); err != nil {
+			log.Printf("应答 %s 的共享缓存查询失败: %v", src, err)
+		}
+	}
+}
+
+// 发送一次 mDNS 查询，收集 timeout 内应答的其他启动器，按应答的先后排列
+func Discover(ctx context.Context, timeout time.Duration) ([]Peer, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	if _, err := conn.WriteToUDP(buildQuery(), mdnsGroup); err != nil {
+		return nil, err
+	}
+
+	var peers []Peer
+	seen := map[string]bool{}
+	buf := make([]byte, 9000)
+	for ctx.Err() == nil {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// 到时间后结束
+			break
+		}
+		m, err := parseMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for instance, port := range m.instances() {
+			if seen[instance] {
+				continue
+			}
+			seen[instance] = true
+			peers = append(peers, Peer{Name: instance, Addr: net.JoinHostPort(src.IP.String(), strconv.Itoa(port))})
+		}
+	}
+	return peers, ctx.Err()
+}
@@ -0,0 +1,86 @@
+// Package peercache 让局域网中的启动器互相共享下载过的模型文件：开启共享的启动器通过 HTTP 提供本机的模型文件，
+// 并应答 mDNS 查询；其他启动器在下载模型文件前先发现它们，从局域网复制并校验 SHA-256，
+// 没有找到或复制失败的文件再访问互联网。机房等多台电脑同时部署时大幅减少外网流量。
+//
+// 局域网中的任何电脑都可以应答 mDNS，内容只能按下载方已知的哈希提供和校验。uv 缓存中是解压后的安装包，
+// 无法按 uv.lock 中的哈希校验，复制来的文件会被当作已安装的代码执行，因此不共享。
+//
+//	GET /v1/models/<SHA-256> 模型文件，支持 Range
+package peercache
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go2exe/internal/models"
+)
+
+// 共享的内容
+type Server struct {
+	ModelsDir string        // 模型目录
+	Models    []models.File // 按 SHA-256 提供的模型文件（python/models.json 中的文件）
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/models/{sha256}", s.model)
+	return mux
+}
+
+func (s *Server) model(w http.ResponseWriter, r *http.Request) {
+	sum := r.PathValue("sha256")
+	for _, f := range s.Models {
+		if strings.EqualFold(f.SHA256, sum) && len(models.Missing(s.ModelsDir, []models.File{f})) == 0 {
+			http.ServeFile(w, r, f.Local(s.ModelsDir))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// 在 listen 上提供 s 中的内容，并以 name 为实例名称应答局域网中的 mDNS 查询，直到 ctx 取消
+func Share(ctx context.Context, listen, name string, s *Server) error {
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go func() {
+		if err := announce(ctx, instanceName(name), ln.Addr().(*net.TCPAddr).Port); err != nil {
+			log.Printf("mDNS 应答停止，其他电脑无法自动发现本机共享的模型文件: %v", err)
+		}
+	}()
+	log.Printf("在 %s 共享模型文件", ln.Addr())
+	if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// 局域网中共享缓存的另一台电脑
+type Peer struct {
+	Name string // 实例名称（计算机名）
+	Addr string // HTTP 地址，主机:端口
+}
+
+func (p Peer) url(path string) string {
+	return "http://" + p.Addr + path
+}
+
+// 模型文件在 p 上的地址，传给 models.Download 代替清单中的下载地址
+func (p Peer) ModelURL(f models.File) string {
+	return p.url("/v1/models/" + strings.ToLower(f.SHA256))
+}
+
+// 是否为本机（Discover 也会收到本机的应答）
+func (p Peer) IsSelf(host string) bool {
+	return strings.EqualFold(p.Name, instanceName(host))
+}
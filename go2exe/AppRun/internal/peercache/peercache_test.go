@@ -0,0 +1,70 @@
+package peercache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go2exe/internal/models"
+)
+
+func TestDNSMessages(t *testing.T) {
+	q, err := parseMessage(buildQuery())
+	if err != nil || !q.asksService() {
+		t.Fatalf("查询 = %+v, %v，应询问 %s", q, err, Service)
+	}
+	r, err := parseMessage(buildResponse(7, instanceName("LAB-PC 01"), 7444))
+	if err != nil || r.id != 7 || r.asksService() {
+		t.Fatalf("应答 = %+v, %v", r, err)
+	}
+	if got := r.instances(); len(got) != 1 || got["lab-pc-01"] != 7444 {
+		t.Errorf("instances() = %v", got)
+	}
+	// 截断的消息不应导致越界
+	msg := buildResponse(0, "pc", 1)
+	for n := range msg {
+		parseMessage(msg[:n])
+	}
+}
+
+func TestShareAndFetch(t *testing.T) {
+	modelDir := t.TempDir()
+	content := []byte(strings.Repeat("voice", 1000))
+	sum := sha256.Sum256(content)
+	f := models.File{Name: "zh-voice", Path: "voices/zh.onnx", SHA256: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+	os.MkdirAll(filepath.Join(modelDir, "voices"), 0755)
+	os.WriteFile(f.Local(modelDir), content, 0644)
+
+	srv := httptest.NewServer((&Server{ModelsDir: modelDir, Models: []models.File{f}}).Handler())
+	defer srv.Close()
+	peer := Peer{Name: "lab-pc-01", Addr: strings.TrimPrefix(srv.URL, "http://")}
+
+	// 无法校验的 uv 缓存不共享
+	resp, err := srv.Client().Get(srv.URL + "/v1/uv-cache")
+	if err == nil && resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /v1/uv-cache 状态码 = %d, want 404", resp.StatusCode)
+	}
+
+	// 模型文件按 SHA-256 提供，用 models.Download 下载并校验
+	local := f
+	local.URL = peer.ModelURL(f)
+	dir := t.TempDir()
+	if err := models.Download(context.Background(), srv.Client(), dir, local, nil); err != nil {
+		t.Fatalf("从局域网下载模型文件失败: %v", err)
+	}
+	unknown := f
+	unknown.SHA256 = hex.EncodeToString(make([]byte, sha256.Size))
+	unknown.URL = peer.ModelURL(unknown)
+	if err := models.Download(context.Background(), srv.Client(), t.TempDir(), unknown, nil); err == nil {
+		t.Error("没有的模型文件应返回错误")
+	}
+	if !peer.IsSelf("LAB-PC.01") {
+		t.Error("IsSelf() 应按实例名称比较")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package preflight
+
+// macOS 和 Linux 没有 MAX_PATH 那样的 260 字符限制，不需要开启长路径支持
+func longPathsEnabled() bool {
+	return true
+}
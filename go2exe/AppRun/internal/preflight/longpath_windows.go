@@ -0,0 +1,23 @@
+package preflight
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// 系统是否启用了长路径支持（HKLM\SYSTEM\CurrentControlSet\Control\FileSystem\LongPathsEnabled）
+func longPathsEnabled() bool {
+	keyPath, _ := syscall.UTF16PtrFromString(`SYSTEM\CurrentControlSet\Control\FileSystem`)
+	var key syscall.Handle
+	if syscall.RegOpenKeyEx(syscall.HKEY_LOCAL_MACHINE, keyPath, 0, syscall.KEY_READ, &key) != nil {
+		return false
+	}
+	defer syscall.RegCloseKey(key)
+	name, _ := syscall.UTF16PtrFromString("LongPathsEnabled")
+	var value, typ uint32
+	size := uint32(unsafe.Sizeof(value))
+	if syscall.RegQueryValueEx(key, name, nil, &typ, (*byte)(unsafe.Pointer(&value)), &size) != nil {
+		return false
+	}
+	return typ == syscall.REG_DWORD && value == 1
+}
@@ -0,0 +1,138 @@
+// Package preflight 在运行任何安装程序之前检查磁盘空间、目录权限、PowerShell 和长路径支持，
+// 把问题整理成用户可以照着处理的提示
+package preflight
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+	"go2exe/internal/runner"
+)
+
+// 未启用长路径支持时，安装根目录的最大长度。
+// Python 包在 site-packages 下的路径可能超过 150 个字符，加上根目录不能超过 MAX_PATH（260）
+const maxRootLen = 100
+
+// 检查选项
+type Options struct {
+	Runner     runner.CommandRunner
+	Dirs       []string      // 安装会写入的目录
+	CheckWrite bool          // 是否检查写入权限（可以提权安装时由调用方处理）
+	MinFreeMB  int64         // 每个目标磁盘至少需要的剩余空间（MB）
+	PathRoots  []string      // 安装后会包含很深路径的根目录，用于检查长路径支持
+	PowerShell bool          // 是否检查 PowerShell（需要运行 uv 的安装脚本时）
+	Timeout    time.Duration // 检查命令的最长执行时间，0 表示不限制
+}
+
+// 一项检查未通过
+type Problem struct {
+	Check   string // 检查项名称
+	Message string // 问题描述和处理办法
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("【%s】%s", p.Check, p.Message)
+}
+
+// 执行所有检查，返回未通过的项，全部通过时返回空
+func Run(o Options) []Problem {
+	var problems []Problem
+	problems = append(problems, checkDiskSpace(o.Dirs, o.MinFreeMB)...)
+	if o.CheckWrite {
+		for _, dir := range o.Dirs {
+			if err := CheckWritable(dir); err != nil {
+				problems = append(problems, Problem{i18n.T("写入权限"), i18n.T("无法写入 %s：%v\n请以管理员身份运行，或把 SpeakMyBook 移动到当前用户可以写入的目录。", dir, err)})
+			}
+		}
+	}
+	if o.PowerShell {
+		if p, ok := checkPowerShell(o.Runner, o.Timeout); !ok {
+			problems = append(problems, p)
+		}
+	}
+	problems = append(problems, checkLongPaths(o.PathRoots)...)
+	for _, p := range problems {
+		log.Printf("预检未通过: %s", p)
+	}
+	return problems
+}
+
+// 检查目标磁盘的剩余空间
+func checkDiskSpace(dirs []string, minFreeMB int64) []Problem {
+	var problems []Problem
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		vol := filepath.VolumeName(dir)
+		if vol == "" || seen[strings.ToUpper(vol)] {
+			continue
+		}
+		seen[strings.ToUpper(vol)] = true
+		free, err := install.FreeDiskSpace(vol + `\`)
+		if err != nil {
+			log.Printf("无法获取磁盘 %s 的剩余空间: %v", vol, err)
+			continue
+		}
+		if int64(free>>20) < minFreeMB {
+			problems = append(problems, Problem{i18n.T("磁盘空间"), i18n.T("磁盘 %s 剩余 %d MB，安装至少需要 %d MB。\n请清理该磁盘，或在 apprun.toml 的 [install] 中把 temp_dir 设到其他磁盘。", vol, free>>20, minFreeMB)})
+		}
+	}
+	return problems
+}
+
+// 检查是否能在目录中创建文件，目录不存在时检查最近的已存在的上级目录
+func CheckWritable(dir string) error {
+	probe := dir
+	for {
+		if _, err := os.Stat(probe); err == nil {
+			break
+		}
+		parent := filepath.Dir(probe)
+		if parent == probe {
+			return nil
+		}
+		probe = parent
+	}
+	f, err := os.CreateTemp(probe, ".speakmybook-write-test-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// 检查 PowerShell 是否可用，uv 的安装脚本（uv-installer.ps1）通过它执行。其他 uv 命令直接运行 uv.exe，不需要 PowerShell
+func checkPowerShell(r runner.CommandRunner, timeout time.Duration) (Problem, bool) {
+	output, err := r.Output(runner.Command{
+		Name:       "powershell",
+		Args:       []string{"-NoProfile", "-NonInteractive", "-Command", "$PSVersionTable.PSVersion.Major"},
+		HideWindow: true,
+		Timeout:    timeout,
+	})
+	if err != nil {
+		return Problem{"PowerShell", i18n.T("无法运行 PowerShell：%v\n请确认系统中的 Windows PowerShell 没有被删除或被组策略禁用。", err)}, false
+	}
+	log.Printf("PowerShell 主版本: %s", strings.TrimSpace(output))
+	return Problem{}, true
+}
+
+// 未启用长路径支持时，检查安装根目录是否过长
+func checkLongPaths(roots []string) []Problem {
+	if longPathsEnabled() {
+		return nil
+	}
+	var problems []Problem
+	for _, root := range roots {
+		if len(root) > maxRootLen {
+			problems = append(problems, Problem{i18n.T("路径长度"), i18n.T("%s 的路径过长（%d 个字符），系统未启用长路径支持，安装 Python 包时可能失败。\n"+
+				"请把 SpeakMyBook 移动到较短的路径（例如 D:\\SpeakMyBook），或在组策略“启用 Win32 长路径”中开启长路径支持。", root, len(root))})
+		}
+	}
+	return problems
+}
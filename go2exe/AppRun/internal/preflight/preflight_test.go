@@ -0,0 +1,41 @@
+package preflight
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"go2exe/internal/runner"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func TestCheckPowerShell(t *testing.T) {
+	ok := &runner.Mock{Handler: func(c runner.Command) (string, error) { return "5\r\n", nil }}
+	if _, passed := checkPowerShell(ok, 0); !passed {
+		t.Errorf("PowerShell 可用时检查未通过")
+	}
+	missing := &runner.Mock{Handler: func(c runner.Command) (string, error) {
+		return "", errors.New(`exec: "powershell": executable file not found in %PATH%`)
+	}}
+	if p, passed := checkPowerShell(missing, 0); passed || p.Check != "PowerShell" {
+		t.Errorf("PowerShell 不可用时返回 %v, %v", p, passed)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	// 不存在的子目录按已存在的上级目录检查
+	if err := CheckWritable(dir + `\not\yet\created`); err != nil {
+		t.Errorf("CheckWritable() = %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("检查后留下了测试文件: %v", entries)
+	}
+}
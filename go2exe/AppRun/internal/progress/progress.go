@@ -0,0 +1,159 @@
+// Package progress 从 uv 和 pip 的输出中解析安装进度：包的数量、下载百分比和剩余时间
+package progress
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 某一时刻的进度
+type Status struct {
+	Percent int           // 0-100，未知时为 -1
+	Done    int           // 已完成的包数量
+	Total   int           // 包的总数，未知时为 0
+	ETA     time.Duration // 预计剩余时间，未知时为 -1
+}
+
+var (
+	// uv：Prepared 12 packages in 3.4s、Installed 42 packages in 500ms。
+	// Resolved 的数量包括已安装的包，不能作为下载的总数
+	uvSummary = regexp.MustCompile(`^(Prepared|Installed|Audited) (\d+) packages?\b`)
+	// uv python install：Installed Python 3.11.9 in 2.51s
+	pythonInstalled = regexp.MustCompile(`^Installed Python \d`)
+	// uv：Downloading numpy (15.3MiB)；pip：Downloading numpy-1.26.4-cp311-cp311-win_amd64.whl (15.8 MB)
+	downloading = regexp.MustCompile(`^Downloading (\S+)`)
+	// uv：Downloaded numpy
+	downloaded = regexp.MustCompile(`^Downloaded (\S+)`)
+	// pip：Collecting numpy
+	collecting = regexp.MustCompile(`^Collecting \S`)
+	// pip：Installing collected packages: a, b, c
+	installing = regexp.MustCompile(`^Installing collected packages: (.+)$`)
+	// pip 的下载进度条：━━━━━ 5.2/15.8 MB 3.1 MB/s eta 0:00:04
+	bytesDone = regexp.MustCompile(`([\d.]+)\s*/\s*([\d.]+)\s*(?:[KMG]i?B|kB|B)\b`)
+	eta       = regexp.MustCompile(`\beta (\d+):(\d{2}):(\d{2})\b`)
+	// 其他输出中的百分比，例如安装脚本的 45%
+	percent = regexp.MustCompile(`(\d{1,3}(?:\.\d+)?)%`)
+)
+
+// 跟踪一条命令的进度，按顺序把输出的每一行交给 Feed
+type Tracker struct {
+	now     func() time.Time
+	start   time.Time // 第一次得到百分比的时间，用于估算剩余时间
+	status  Status
+	started map[string]bool // 已开始下载的包
+	resumed int             // 开始前已有的包数量，见 Resume
+	total   int
+}
+
+// 创建跟踪器
+func New() *Tracker {
+	return &Tracker{now: time.Now, status: Status{Percent: -1, ETA: -1}, started: map[string]bool{}}
+}
+
+// 解析一行输出，进度有变化时返回新的进度和 true
+func (t *Tracker) Feed(line string) (Status, bool) {
+	line = strings.TrimSpace(line)
+	old := t.view()
+	switch {
+	case uvSummary.MatchString(line):
+		m := uvSummary.FindStringSubmatch(line)
+		n, _ := strconv.Atoi(m[2])
+		switch m[1] {
+		case "Prepared":
+			t.status.Done, t.status.Total = n, max(n, len(t.started))
+			t.setPercent(100)
+		case "Installed", "Audited":
+			t.status.Done, t.status.Total = max(n, t.status.Done), max(n, t.status.Total)
+			t.setPercent(100)
+		}
+	case pythonInstalled.MatchString(line):
+		t.setPercent(100)
+	case downloading.MatchString(line):
+		name := downloading.FindStringSubmatch(line)[1]
+		if !t.started[name] {
+			t.started[name] = true
+			t.status.Total = max(t.status.Total, len(t.started))
+			t.packagePercent()
+		}
+	case downloaded.MatchString(line):
+		t.status.Done++
+		t.status.Total = max(t.status.Total, t.status.Done, len(t.started))
+		t.packagePercent()
+	case collecting.MatchString(line):
+		t.status.Total++
+	case installing.MatchString(line):
+		names := strings.Split(installing.FindStringSubmatch(line)[1], ",")
+		t.status.Done, t.status.Total = len(names), len(names)
+	case bytesDone.MatchString(line):
+		m := bytesDone.FindStringSubmatch(line)
+		done, _ := strconv.ParseFloat(m[1], 64)
+		total, _ := strconv.ParseFloat(m[2], 64)
+		if total > 0 && done <= total {
+			t.setPercent(int(done / total * 100))
+		}
+		if m := eta.FindStringSubmatch(line); m != nil {
+			h, _ := strconv.Atoi(m[1])
+			min, _ := strconv.Atoi(m[2])
+			s, _ := strconv.Atoi(m[3])
+			t.status.ETA = time.Duration(h)*time.Hour + time.Duration(min)*time.Minute + time.Duration(s)*time.Second
+		}
+	case percent.MatchString(line):
+		p, _ := strconv.ParseFloat(percent.FindStringSubmatch(line)[1], 64)
+		if p <= 100 {
+			t.setPercent(int(p))
+		}
+	}
+	status := t.view()
+	return status, status != old
+}
+
+// 从上次中断的地方继续：total 个包中已有 done 个（例如已在 uv 缓存中），
+// 之后解析出的包数量累加在 done 之上，返回开始时的进度
+func (t *Tracker) Resume(done, total int) Status {
+	if total > 0 {
+		t.resumed, t.total = min(done, total), total
+	}
+	return t.view()
+}
+
+// 对外报告的进度，继续时把本次的包数量加上已有的包
+func (t *Tracker) view() Status {
+	s := t.status
+	if t.resumed == 0 {
+		return s
+	}
+	s.Done, s.Total = min(t.resumed+s.Done, t.total), t.total
+	s.Percent = s.Done * 100 / s.Total
+	if s.Percent < 100 && s.ETA == 0 {
+		// 本次要下载的包已完成，但还有包没有开始下载，剩余时间未知
+		s.ETA = -1
+	}
+	return s
+}
+
+// 按已下载的包数量计算百分比
+func (t *Tracker) packagePercent() {
+	if t.status.Total > 0 {
+		t.setPercent(t.status.Done * 100 / t.status.Total)
+	}
+}
+
+// 更新百分比，并按开始以来的平均速度估算剩余时间
+func (t *Tracker) setPercent(p int) {
+	now := t.now()
+	if t.start.IsZero() {
+		t.start = now
+	}
+	t.status.Percent = p
+	switch {
+	case p >= 100:
+		t.status.ETA = 0
+	case p > 0:
+		elapsed := now.Sub(t.start)
+		t.status.ETA = (elapsed * time.Duration(100-p) / time.Duration(p)).Round(time.Second)
+	default:
+		t.status.ETA = -1
+	}
+}
@@ -0,0 +1,100 @@
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+// 返回可以手动推进的时钟
+func fakeClock() (func() time.Time, func(time.Duration)) {
+	now := time.Date(2024, 8, 14, 10, 0, 0, 0, time.UTC)
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestTrackerUV(t *testing.T) {
+	tr := New()
+	clock, advance := fakeClock()
+	tr.now = clock
+
+	steps := []struct {
+		line    string
+		changed bool
+		want    Status
+	}{
+		{"Using CPython 3.11.9", false, Status{Percent: -1, ETA: -1}},
+		{"Resolved 42 packages in 1.20s", false, Status{Percent: -1, ETA: -1}},
+		{"Downloading numpy (15.3MiB)", true, Status{Percent: 0, Total: 1, ETA: -1}},
+		{"Downloading pillow (2.5MiB)", true, Status{Percent: 0, Total: 2, ETA: -1}},
+		{"   Downloaded pillow", true, Status{Percent: 50, Done: 1, Total: 2, ETA: 20 * time.Second}},
+		{" Downloaded numpy", true, Status{Percent: 100, Done: 2, Total: 2, ETA: 0}},
+		{"Prepared 2 packages in 20.31s", false, Status{Percent: 100, Done: 2, Total: 2, ETA: 0}},
+		{"Installed 42 packages in 900ms", true, Status{Percent: 100, Done: 42, Total: 42, ETA: 0}},
+	}
+	for _, s := range steps {
+		advance(10 * time.Second)
+		got, changed := tr.Feed(s.line)
+		if got != s.want || changed != s.changed {
+			t.Errorf("Feed(%q) = %+v, %v，期望 %+v, %v", s.line, got, changed, s.want, s.changed)
+		}
+	}
+}
+
+func TestTrackerResume(t *testing.T) {
+	tr := New()
+	clock, _ := fakeClock()
+	tr.now = clock
+
+	if got := tr.Resume(93, 120); got.Done != 93 || got.Total != 120 || got.Percent != 77 {
+		t.Errorf("Resume() = %+v，期望 93/120", got)
+	}
+	tr.Feed("Downloading numpy (15.3MiB)")
+	got, changed := tr.Feed("Downloaded numpy")
+	if !changed || got.Done != 94 || got.Total != 120 || got.Percent != 78 {
+		t.Errorf("继续下载一个包后进度为 %+v, %v，期望 94/120", got, changed)
+	}
+	if got, _ := tr.Feed("Installed 120 packages in 2s"); got.Done != 120 || got.Total != 120 || got.Percent != 100 {
+		t.Errorf("安装完成后进度为 %+v", got)
+	}
+}
+
+func TestTrackerPip(t *testing.T) {
+	tr := New()
+	clock, _ := fakeClock()
+	tr.now = clock
+
+	tr.Feed("Collecting numpy")
+	tr.Feed("Collecting pillow")
+	got, _ := tr.Feed("Downloading numpy-1.26.4-cp311-cp311-win_amd64.whl (15.8 MB)")
+	if got.Total != 2 {
+		t.Errorf("Collecting 两个包后 Total = %d，期望 2", got.Total)
+	}
+	got, _ = tr.Feed("   ━━━━━━━━━━━━━╸━━━━━━━━━━━━ 5.2/15.8 MB 3.1 MB/s eta 0:00:04")
+	if got.Percent != 32 || got.ETA != 4*time.Second {
+		t.Errorf("下载进度条解析为 %+v，期望 32%% 和剩余 4 秒", got)
+	}
+	got, _ = tr.Feed("Installing collected packages: numpy, pillow")
+	if got.Done != 2 || got.Total != 2 {
+		t.Errorf("Installing collected packages 解析为 %+v", got)
+	}
+}
+
+func TestTrackerPercent(t *testing.T) {
+	tr := New()
+	clock, advance := fakeClock()
+	tr.now = clock
+
+	tr.Feed("Extracting... 0%")
+	advance(30 * time.Second)
+	got, changed := tr.Feed("Extracting... 25.5%")
+	if !changed || got.Percent != 25 || got.ETA != 90*time.Second {
+		t.Errorf("Feed() = %+v, %v，期望 25%% 和剩余 90 秒", got, changed)
+	}
+	if got, _ := tr.Feed("Installed Python 3.11.9 in 2.51s"); got.Percent != 100 || got.ETA != 0 {
+		t.Errorf("Python 安装完成后进度为 %+v", got)
+	}
+	// 超过 100 的数字不是百分比
+	tr = New()
+	if got, changed := tr.Feed("status 404%"); changed {
+		t.Errorf("无效的百分比被解析为 %+v", got)
+	}
+}
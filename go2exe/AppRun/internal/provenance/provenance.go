@@ -0,0 +1,137 @@
+// Package provenance 记录每个安装内容（uv、Python、依赖包、模型文件）的来源和哈希：随程序分发、从哪个镜像或地址下载、
+// 从局域网的哪台电脑或共享目录复制。记录追加到一个 JSON Lines 文件，放进诊断包，
+// 事后可以回答“这个 wheel 是哪个镜像提供的”之类的供应链问题
+package provenance
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 安装内容的类型
+const (
+	KindUV      = "uv"
+	KindPython  = "python"
+	KindPackage = "package"  // 虚拟环境中的依赖包
+	KindModel   = "model"    // 语音和模型文件
+	KindUVCache = "uv-cache" // 从其他来源复制的 uv 缓存
+)
+
+// 来源
+const (
+	SourceBundled  = "bundled"  // 随程序分发（程序目录或内置在启动器中）
+	SourceDownload = "download" // 从 URL 下载
+	SourceMirror   = "mirror"   // 从 PyPI 镜像下载
+	SourceOffline  = "offline"  // 离线安装包目录
+	SourceCache    = "cache"    // 同步前已在 uv 缓存中
+	SourceGateway  = "ipfs"     // 从 IPFS 网关下载
+	SourceSeed     = "seed"     // 从局域网共享的预热目录复制
+	SourcePeer     = "peer"     // 从局域网中的其他电脑复制
+)
+
+// 一条来源记录
+type Record struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Name    string    `json:"name"`
+	Version string    `json:"version,omitempty"`
+	Source  string    `json:"source"`
+	URL     string    `json:"url,omitempty"`    // 下载地址或本地路径
+	Mirror  string    `json:"mirror,omitempty"` // 使用的 PyPI 镜像
+	SHA256  string    `json:"sha256,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+// 来源记录文件，nil 时不记录
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// 记录到 path 的 Log，第一次写入时创建
+func Open(path string) *Log {
+	return &Log{path: path}
+}
+
+func (l *Log) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// 追加记录，没有设置时间的使用当前时间。写入失败只影响记录，不影响安装，不返回错误
+func (l *Log) Add(records ...Record) {
+	if l == nil || len(records) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	now := time.Now()
+	for _, r := range records {
+		if r.Time.IsZero() {
+			r.Time = now
+		}
+		data, _ := json.Marshal(r)
+		w.Write(append(data, '\n'))
+	}
+	w.Flush()
+}
+
+// 读取全部记录，跳过无法解析的行。文件不存在时返回空
+func Load(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var r Record
+		if json.Unmarshal(scanner.Bytes(), &r) == nil {
+			records = append(records, r)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// 每个安装内容（类型和名称相同）最新的记录，按类型和名称排序，即当前安装的内容的来源
+func Latest(records []Record) []Record {
+	latest := map[[2]string]Record{}
+	for _, r := range records {
+		key := [2]string{r.Kind, r.Name}
+		if old, ok := latest[key]; !ok || !r.Time.Before(old.Time) {
+			latest[key] = r
+		}
+	}
+	result := make([]Record, 0, len(latest))
+	for _, r := range latest {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].Kind != result[b].Kind {
+			return result[a].Kind < result[b].Kind
+		}
+		return result[a].Name < result[b].Name
+	})
+	return result
+}
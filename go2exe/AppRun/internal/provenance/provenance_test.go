@@ -0,0 +1,33 @@
+package provenance
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLogAndLatest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "provenance.jsonl")
+	l := Open(path)
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.Add(
+		Record{Time: t0, Kind: KindPackage, Name: "numpy", Version: "1.26.3", Source: SourceMirror, Mirror: "https://pypi.tuna.tsinghua.edu.cn/simple"},
+		Record{Time: t0, Kind: KindUV, Name: "uv", Source: SourceBundled},
+	)
+	l.Add(Record{Time: t0.Add(time.Hour), Kind: KindPackage, Name: "numpy", Version: "1.26.4", Source: SourceOffline})
+
+	records, err := Load(path)
+	if err != nil || len(records) != 3 {
+		t.Fatalf("Load() = %d 条, %v", len(records), err)
+	}
+	latest := Latest(records)
+	if len(latest) != 2 || latest[0].Name != "numpy" || latest[0].Version != "1.26.4" || latest[1].Kind != KindUV {
+		t.Errorf("Latest() = %+v", latest)
+	}
+
+	var none *Log
+	none.Add(Record{Kind: KindUV}) // nil 时不记录
+	if records, err := Load(filepath.Join(t.TempDir(), "missing.jsonl")); records != nil || err != nil {
+		t.Errorf("文件不存在时 Load() = %v, %v", records, err)
+	}
+}
@@ -0,0 +1,238 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// 磁带：录下的外部命令及其输出，回放时按顺序返回，用真实的 uv 输出做解析和错误分类的回归测试
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// 一次命令执行
+type Interaction struct {
+	Name   string   `json:"name"`
+	Args   []string `json:"args"`
+	Dir    string   `json:"dir,omitempty"`
+	Output string   `json:"output,omitempty"` // Output 的输出
+	Lines  []Line   `json:"lines,omitempty"`  // Stream 逐行的输出
+	Start  bool     `json:"start,omitempty"`  // 通过 Start 启动，只记录能否启动
+	Error  string   `json:"error,omitempty"`  // 命令失败时的错误信息
+}
+
+// Stream 输出的一行
+type Line struct {
+	Text   string `json:"text"`
+	Stderr bool   `json:"stderr,omitempty"`
+}
+
+// 读取磁带文件
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("磁带文件 %s 格式错误: %v", path, err)
+	}
+	return &c, nil
+}
+
+// 写入磁带文件
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// 录制执行器：用 Runner 执行命令，每条命令结束后把它和输出追加到 Path 指定的磁带文件
+type Recorder struct {
+	Runner CommandRunner
+	Path   string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+func (r *Recorder) record(i Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, i)
+	// 每条命令后都写入，启动器异常退出时已录下的部分也不会丢失
+	if err := r.cassette.Save(r.Path); err != nil {
+		log.Printf("写入磁带文件 %s 失败: %v", r.Path, err)
+	}
+}
+
+func (r *Recorder) Output(c Command) (string, error) {
+	output, err := r.Runner.Output(c)
+	r.record(Interaction{Name: c.Name, Args: c.Args, Dir: c.Dir, Output: output, Error: errorText(err)})
+	return output, err
+}
+
+func (r *Recorder) Stream(c Command, onLine func(line string, isError bool)) error {
+	var mu sync.Mutex
+	var lines []Line
+	err := r.Runner.Stream(c, func(line string, isError bool) {
+		mu.Lock()
+		lines = append(lines, Line{line, isError})
+		mu.Unlock()
+		onLine(line, isError)
+	})
+	r.record(Interaction{Name: c.Name, Args: c.Args, Dir: c.Dir, Lines: lines, Error: errorText(err)})
+	return err
+}
+
+func (r *Recorder) Start(c Command) (Process, error) {
+	p, err := r.Runner.Start(c)
+	r.record(Interaction{Name: c.Name, Args: c.Args, Dir: c.Dir, Start: true, Error: errorText(err)})
+	return p, err
+}
+
+// 回放执行器：按磁带返回命令的输出，不执行任何命令。
+// 每条录下的命令只回放一次，按顺序取第一条参数完全相同的；没有时取第一条子命令相同（第一个 - 开头的参数之前的参数相同）的，
+// 临时目录等每次不同的参数不影响回放
+type Replay struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// 创建按 c 回放的执行器
+func NewReplay(c *Cassette) *Replay {
+	return &Replay{cassette: c, used: make([]bool, len(c.Interactions))}
+}
+
+// 还没有回放的命令数
+func (r *Replay) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// 命令的子命令部分，例如 uv python install 3.11 --mirror ... 为 [python install 3.11]
+func subcommand(args []string) []string {
+	for n, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			return args[:n]
+		}
+	}
+	return args
+}
+
+func (r *Replay) next(c Command) (Interaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c.Context != nil && c.Context.Err() != nil {
+		return Interaction{}, ErrCanceled
+	}
+	found := -1
+	for n, i := range r.cassette.Interactions {
+		if !r.used[n] && i.Name == c.Name && slices.Equal(i.Args, c.Args) {
+			found = n
+			break
+		}
+	}
+	if found < 0 {
+		for n, i := range r.cassette.Interactions {
+			if !r.used[n] && i.Name == c.Name && slices.Equal(subcommand(i.Args), subcommand(c.Args)) {
+				found = n
+				break
+			}
+		}
+	}
+	if found < 0 {
+		return Interaction{}, fmt.Errorf("磁带中没有可回放的命令: %s", strings.Join(append([]string{c.Name}, c.Args...), " "))
+	}
+	r.used[found] = true
+	return r.cassette.Interactions[found], nil
+}
+
+// 录下的错误，取消的命令回放为 ErrCanceled
+func (i Interaction) err() error {
+	switch i.Error {
+	case "":
+		return nil
+	case ErrCanceled.Error():
+		return ErrCanceled
+	}
+	return errors.New(i.Error)
+}
+
+// 合并后的输出：用 Stream 录下的命令按行拼接
+func (i Interaction) output() string {
+	if i.Output != "" || len(i.Lines) == 0 {
+		return i.Output
+	}
+	var texts []string
+	for _, line := range i.Lines {
+		texts = append(texts, line.Text)
+	}
+	return strings.Join(texts, "\n") + "\n"
+}
+
+func (r *Replay) Output(c Command) (string, error) {
+	i, err := r.next(c)
+	if err != nil {
+		return "", err
+	}
+	return i.output(), i.err()
+}
+
+func (r *Replay) Stream(c Command, onLine func(line string, isError bool)) error {
+	i, err := r.next(c)
+	if err != nil {
+		return err
+	}
+	lines := i.Lines
+	if len(lines) == 0 {
+		for _, text := range strings.Split(strings.TrimRight(i.Output, "\n"), "\n") {
+			if text != "" {
+				lines = append(lines, Line{Text: text})
+			}
+		}
+	}
+	for _, line := range lines {
+		onLine(line.Text, line.Stderr)
+	}
+	return i.err()
+}
+
+// 回放启动的进程一直运行，与录制时应用在启动器退出前一直运行一致
+func (r *Replay) Start(c Command) (Process, error) {
+	i, err := r.next(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.err(); err != nil {
+		return nil, err
+	}
+	return replayProcess{}, nil
+}
+
+type replayProcess struct{}
+
+func (replayProcess) Wait() error { select {} }
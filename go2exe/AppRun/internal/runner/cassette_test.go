@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	m := &Mock{Handler: func(c Command) (string, error) {
+		if c.Args[0] == "sync" {
+			return "error: Failed to fetch\n", errors.New("exit status 2")
+		}
+		return "uv 0.4.18\n", nil
+	}}
+	rec := &Recorder{Runner: m, Path: path}
+	rec.Output(Command{Name: "uv", Args: []string{"-V"}})
+	rec.Stream(Command{Name: "uv", Args: []string{"sync", "--default-index", "https://a.example/simple"}}, func(string, bool) {})
+
+	cassette, err := LoadCassette(path)
+	if err != nil || len(cassette.Interactions) != 2 {
+		t.Fatalf("LoadCassette() = %+v, %v", cassette, err)
+	}
+	replay := NewReplay(cassette)
+	// 参数不同、子命令相同的命令也能回放
+	var lines []string
+	err = replay.Stream(Command{Name: "uv", Args: []string{"sync", "--default-index", "https://b.example/simple"}}, func(line string, _ bool) { lines = append(lines, line) })
+	if err == nil || err.Error() != "exit status 2" || len(lines) != 1 || lines[0] != "error: Failed to fetch" {
+		t.Errorf("回放 uv sync = %v, %v", lines, err)
+	}
+	if output, err := replay.Output(Command{Name: "uv", Args: []string{"-V"}}); err != nil || output != "uv 0.4.18\n" {
+		t.Errorf("回放 uv -V = %q, %v", output, err)
+	}
+	if _, err := replay.Output(Command{Name: "uv", Args: []string{"-V"}}); err == nil {
+		t.Error("每条命令只应回放一次")
+	}
+}
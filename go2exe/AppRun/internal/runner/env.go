@@ -0,0 +1,187 @@
+package runner
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// 传给外部命令的环境变量：在本进程的环境变量基础上设置或删除，不修改本进程的环境变量（os.Setenv），
+// 启动器嵌入其他程序、并行执行或在测试中使用时互不影响。零值可用，可以在多个 goroutine 中使用
+type Environment struct {
+	mu   sync.RWMutex
+	vars map[string]envValue
+}
+
+type envValue struct {
+	key   string // 原始的名称
+	value string
+	unset bool // 不传给外部命令，即使本进程的环境中有
+}
+
+// Windows 的环境变量名不区分大小写
+func envKey(key string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(key)
+	}
+	return key
+}
+
+// 设置环境变量
+func (e *Environment) Set(key, value string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.vars == nil {
+		e.vars = map[string]envValue{}
+	}
+	e.vars[envKey(key)] = envValue{key: key, value: value}
+}
+
+// 删除环境变量
+func (e *Environment) Unset(key string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.vars == nil {
+		e.vars = map[string]envValue{}
+	}
+	e.vars[envKey(key)] = envValue{key: key, unset: true}
+}
+
+// 外部命令看到的值，没有设置时使用本进程的环境变量
+func (e *Environment) Lookup(key string) (string, bool) {
+	if e != nil {
+		e.mu.RLock()
+		v, ok := e.vars[envKey(key)]
+		e.mu.RUnlock()
+		if ok {
+			return v.value, !v.unset
+		}
+	}
+	return os.LookupEnv(key)
+}
+
+// 外部命令看到的值，没有时为空字符串
+func (e *Environment) Get(key string) string {
+	v, _ := e.Lookup(key)
+	return v
+}
+
+// 外部命令的全部环境变量（KEY=value），e 为 nil 时与本进程相同
+func (e *Environment) Environ() []string {
+	env := os.Environ()
+	if e == nil {
+		return env
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var result []string
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		// Windows 上每个盘符的当前目录记录在以 = 开头的变量中，原样保留
+		if _, ok := e.vars[envKey(key)]; !ok || key == "" {
+			result = append(result, kv)
+		}
+	}
+	var keys []string
+	for k, v := range e.vars {
+		if !v.unset {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		result = append(result, e.vars[k].key+"="+e.vars[k].value)
+	}
+	return result
+}
+
+// 在 base（KEY=value 列表）上应用 overrides：同名的变量（Windows 上不区分大小写）替换原来的值并留在原位置，
+// 新的变量追加在末尾，overrides 中靠后的优先。不修改 base，用于为单个命令或步骤组合环境变量
+func Overlay(base []string, overrides ...string) []string {
+	result := slices.Clone(base)
+	index := map[string]int{}
+	for i, kv := range result {
+		if key, _, _ := strings.Cut(kv, "="); key != "" {
+			index[envKey(key)] = i
+		}
+	}
+	for _, kv := range overrides {
+		key, _, _ := strings.Cut(kv, "=")
+		if i, ok := index[envKey(key)]; ok && key != "" {
+			result[i] = kv
+			continue
+		}
+		index[envKey(key)] = len(result)
+		result = append(result, kv)
+	}
+	return result
+}
+
+// 一个命令的全部环境变量：Environ() 再加上只对这个命令生效的 overrides
+func (e *Environment) With(overrides ...string) []string {
+	return Overlay(e.Environ(), overrides...)
+}
+
+// 按外部命令看到的 PATH 查找可执行文件，name 中有路径分隔符时与 exec.LookPath 相同。
+// 启动器安装 uv 后只把它的目录加到这里的 PATH 中，不修改本进程的 PATH
+func (e *Environment) LookPath(name string) (string, error) {
+	if e == nil || strings.ContainsAny(name, `/\`) {
+		return exec.LookPath(name)
+	}
+	return lookPathIn(e.Get("PATH"), name)
+}
+
+// 在 pathList 的各个目录中查找 name，Windows 上按 PATHEXT 补全扩展名
+func lookPathIn(pathList, name string) (string, error) {
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir == "" {
+			continue
+		}
+		if path, err := exec.LookPath(filepath.Join(dir, name)); err == nil {
+			return path, nil
+		}
+	}
+	return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
+
+// 按外部命令看到的 HTTP_PROXY、HTTPS_PROXY 和 NO_PROXY 选择代理，用作 http.Transport 的 Proxy，
+// 使启动器自己的下载与 uv 使用相同的代理。e 为 nil 时与 http.ProxyFromEnvironment 相同
+func (e *Environment) Proxy(req *http.Request) (*url.URL, error) {
+	if e == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+	get := func(keys ...string) string {
+		for _, key := range keys {
+			if v := e.Get(key); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	cfg := httpproxy.Config{
+		HTTPProxy:  get("HTTP_PROXY", "http_proxy"),
+		HTTPSProxy: get("HTTPS_PROXY", "https_proxy"),
+		NoProxy:    get("NO_PROXY", "no_proxy"),
+	}
+	return cfg.ProxyFunc()(req.URL)
+}
+
+// env（KEY=value 列表）中 key 的值，同名的变量以最后一个为准
+func lookupEnv(env []string, key string) string {
+	value := ""
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && envKey(k) == envKey(key) {
+			value = v
+		}
+	}
+	return value
+}
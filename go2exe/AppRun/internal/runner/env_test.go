@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestEnvironment(t *testing.T) {
+	t.Setenv("APPRUN_TEST_KEEP", "1")
+	t.Setenv("APPRUN_TEST_DROP", "1")
+	t.Setenv("APPRUN_TEST_OVERRIDE", "old")
+
+	var e Environment
+	e.Set("APPRUN_TEST_OVERRIDE", "new")
+	e.Set("APPRUN_TEST_NEW", "2")
+	e.Unset("APPRUN_TEST_DROP")
+
+	env := e.Environ()
+	for _, kv := range []string{"APPRUN_TEST_KEEP=1", "APPRUN_TEST_OVERRIDE=new", "APPRUN_TEST_NEW=2"} {
+		if !slices.Contains(env, kv) {
+			t.Errorf("Environ() 中没有 %s", kv)
+		}
+	}
+	for _, kv := range []string{"APPRUN_TEST_DROP=1", "APPRUN_TEST_OVERRIDE=old"} {
+		if slices.Contains(env, kv) {
+			t.Errorf("Environ() 中仍有 %s", kv)
+		}
+	}
+	if v, ok := e.Lookup("APPRUN_TEST_DROP"); ok {
+		t.Errorf("Lookup(删除的变量) = %q, true", v)
+	}
+	if got := e.Get("APPRUN_TEST_KEEP"); got != "1" {
+		t.Errorf("Get(本进程的变量) = %q", got)
+	}
+	// 本进程的环境变量不受影响
+	var none *Environment
+	if got := none.Get("APPRUN_TEST_OVERRIDE"); got != "old" {
+		t.Errorf("本进程的环境变量被修改为 %q", got)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	base := []string{"A=1", "B=2", "=C:=C:\\"}
+	got := Overlay(base, "B=3", "D=4", "D=5")
+	if want := []string{"A=1", "B=3", "=C:=C:\\", "D=5"}; !slices.Equal(got, want) {
+		t.Errorf("Overlay() = %q, want %q", got, want)
+	}
+	if base[1] != "B=2" {
+		t.Error("Overlay() 不应修改 base")
+	}
+
+	// 只对一个命令生效的变量不影响共用的环境
+	t.Setenv("APPRUN_TEST_STEP", "base")
+	var e Environment
+	if env := e.With("APPRUN_TEST_STEP=step"); !slices.Contains(env, "APPRUN_TEST_STEP=step") || slices.Contains(env, "APPRUN_TEST_STEP=base") {
+		t.Errorf("With() 没有覆盖同名的变量")
+	}
+	if got := e.Get("APPRUN_TEST_STEP"); got != "base" {
+		t.Errorf("With() 之后 Get() = %q，共用的环境被修改", got)
+	}
+}
+
+func TestLookPath(t *testing.T) {
+	dir := t.TempDir()
+	name := "apprun-test-tool"
+	file := name
+	if runtime.GOOS == "windows" {
+		file += ".exe"
+	}
+	if err := os.WriteFile(filepath.Join(dir, file), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var e Environment
+	if _, err := e.LookPath(name); err == nil {
+		t.Fatalf("PATH 中没有 %s 时 LookPath() 应失败", dir)
+	}
+	e.Set("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if got, err := e.LookPath(name); err != nil || filepath.Dir(got) != dir {
+		t.Errorf("LookPath() = %q, %v, want %s 中的文件", got, err, dir)
+	}
+	// Exec 按 Environment 中的 PATH 启动命令（Windows 上写入的不是可执行文件，不运行）
+	if runtime.GOOS != "windows" {
+		if _, err := (Exec{Env: &e}).Output(Command{Name: name}); err != nil {
+			t.Errorf("Exec 没有按 Environment 的 PATH 找到命令: %v", err)
+		}
+	}
+	// 本进程的 PATH 不变
+	var none *Environment
+	if _, err := none.LookPath(name); err == nil {
+		t.Errorf("本进程的 PATH 中找到了 %s", name)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("NO_PROXY", "")
+	var e Environment
+	e.Set("HTTPS_PROXY", "http://proxy.corp:8080")
+	e.Set("NO_PROXY", "intranet.corp")
+
+	tests := []struct {
+		url, want string
+	}{
+		{"https://pypi.org/simple/", "http://proxy.corp:8080"},
+		{"https://intranet.corp/simple/", ""},
+		{"http://pypi.org/simple/", ""},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		u, err := e.Proxy(req)
+		got := ""
+		if u != nil {
+			got = u.String()
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Proxy(%s) = %q, %v, want %q", tt.url, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"log"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                           = syscall.NewLazyDLL("kernel32.dll")
+	createJobObject                    = kernel32.NewProc("CreateJobObjectW")
+	setInformationJobObject            = kernel32.NewProc("SetInformationJobObject")
+	assignProcessToJobObject           = kernel32.NewProc("AssignProcessToJobObject")
+	JobObjectExtendedLimitInformation  = 9
+	JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE = 0x00002000
+	PROCESS_SET_QUOTA                  = 0x0100
+)
+
+type jobObjectBasicLimitInformation struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+}
+
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+type jobObjectExtendedLimitInformation struct {
+	BasicLimitInformation jobObjectBasicLimitInformation
+	IoInfo                ioCounters
+	ProcessMemoryLimit    uintptr
+	JobMemoryLimit        uintptr
+	PeakProcessMemoryUsed uintptr
+	PeakJobMemoryUsed     uintptr
+}
+
+var (
+	jobOnce sync.Once
+	job     syscall.Handle
+)
+
+// 安装命令所在的作业对象。句柄在启动器退出（包括崩溃、被结束、关闭进度窗口）时由系统关闭，
+// 设置了 KILL_ON_JOB_CLOSE，作业中的进程及其之后创建的子进程随之全部结束
+func installJob() syscall.Handle {
+	jobOnce.Do(func() {
+		h, _, err := createJobObject.Call(0, 0)
+		if h == 0 {
+			log.Printf("创建作业对象失败: %v", err)
+			return
+		}
+		var info jobObjectExtendedLimitInformation
+		info.BasicLimitInformation.LimitFlags = uint32(JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE)
+		r, _, err := setInformationJobObject.Call(h, uintptr(JobObjectExtendedLimitInformation),
+			uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info))
+		if r == 0 {
+			log.Printf("设置作业对象失败: %v", err)
+			syscall.CloseHandle(syscall.Handle(h))
+			return
+		}
+		job = syscall.Handle(h)
+	})
+	return job
+}
+
+// 把进程加入作业对象，启动器退出时它和它的子进程都会被结束。
+// 失败时（例如系统不支持嵌套作业）只记录日志，取消和超时仍会通过 KillTree 结束进程树
+func assignToJob(pid int) {
+	j := installJob()
+	if j == 0 {
+		return
+	}
+	h, err := syscall.OpenProcess(uint32(PROCESS_SET_QUOTA|PROCESS_TERMINATE), false, uint32(pid))
+	if err != nil {
+		log.Printf("打开进程 %d 失败: %v", pid, err)
+		return
+	}
+	defer syscall.CloseHandle(h)
+	if r, _, err := assignProcessToJobObject.Call(uintptr(j), uintptr(h)); r == 0 {
+		log.Printf("把进程 %d 加入作业对象失败: %v", pid, err)
+	}
+}
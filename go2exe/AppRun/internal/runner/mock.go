@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"strings"
+	"sync"
+)
+
+// 模拟的命令执行器，按 Handler 返回预设的输出和错误，并记录所有调用
+type Mock struct {
+	mu      sync.Mutex
+	Calls   []Command
+	Handler func(c Command) (string, error) // 为 nil 时所有命令都成功且没有输出
+}
+
+// 已启动的模拟进程
+type mockProcess struct {
+	err error
+}
+
+func (p mockProcess) Wait() error {
+	return p.err
+}
+
+func (m *Mock) run(c Command) (string, error) {
+	m.mu.Lock()
+	m.Calls = append(m.Calls, c)
+	m.mu.Unlock()
+	if c.Context != nil && c.Context.Err() != nil {
+		return "", ErrCanceled
+	}
+	if m.Handler == nil {
+		return "", nil
+	}
+	return m.Handler(c)
+}
+
+func (m *Mock) Output(c Command) (string, error) {
+	return m.run(c)
+}
+
+func (m *Mock) Stream(c Command, onLine func(line string, isError bool)) error {
+	output, err := m.run(c)
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if line != "" {
+			onLine(line, false)
+		}
+	}
+	return err
+}
+
+func (m *Mock) Start(c Command) (Process, error) {
+	_, err := m.run(c)
+	if err != nil {
+		return nil, err
+	}
+	return mockProcess{}, nil
+}
+
+// 返回所有调用的命令行，便于断言
+func (m *Mock) CommandLines() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var lines []string
+	for _, c := range m.Calls {
+		lines = append(lines, strings.Join(append([]string{c.Name}, c.Args...), " "))
+	}
+	return lines
+}
@@ -0,0 +1,48 @@
+//go:build !windows
+
+package runner
+
+import (
+	"log"
+	"os/exec"
+	"syscall"
+)
+
+// 非 Windows 系统没有作业对象和控制台窗口。安装命令（HideWindow）放在自己的进程组中：
+// 终端中的 Ctrl+C 只交给启动器处理，取消时按进程组结束，uv 启动的子进程不会残留
+
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// 应用在新的会话中运行，没有控制终端，关闭启动它的终端时不会收到 SIGHUP
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+func assignToJob(pid int) {}
+
+// 向 pid 为组长的进程组发送信号，pid 不是进程组组长时只发给它自己
+func signal(pid uint32, sig syscall.Signal) {
+	if syscall.Kill(-int(pid), sig) != nil {
+		syscall.Kill(int(pid), sig)
+	}
+}
+
+// 暂停进程
+func SuspendTree(pid uint32) {
+	signal(pid, syscall.SIGSTOP)
+	log.Printf("已暂停进程 %d", pid)
+}
+
+// 恢复进程
+func ResumeTree(pid uint32) {
+	signal(pid, syscall.SIGCONT)
+	log.Printf("已恢复进程 %d", pid)
+}
+
+// 结束进程
+func KillTree(pid uint32) {
+	signal(pid, syscall.SIGKILL)
+	log.Printf("已结束进程 %d", pid)
+}
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"log"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	ntdll                             = syscall.NewLazyDLL("ntdll.dll")
+	ntSuspendProcess                  = ntdll.NewProc("NtSuspendProcess")
+	ntResumeProcess                   = ntdll.NewProc("NtResumeProcess")
+	PROCESS_TERMINATE                 = 0x0001
+	PROCESS_SUSPEND_RESUME            = 0x0800
+	PROCESS_QUERY_LIMITED_INFORMATION = 0x1000
+)
+
+// 不为命令创建控制台窗口
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		HideWindow: true,
+	}
+}
+
+// 启动器是窗口程序，启动的应用本来就不依附于控制台
+func detach(cmd *exec.Cmd) {}
+
+// 返回 pid 及其所有子孙进程的 ID（父进程在前）
+func processTree(pid uint32) []uint32 {
+	snap, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return []uint32{pid}
+	}
+	defer syscall.CloseHandle(snap)
+
+	children := map[uint32][]uint32{}
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = syscall.Process32First(snap, &entry); err == nil; err = syscall.Process32Next(snap, &entry) {
+		children[entry.ParentProcessID] = append(children[entry.ParentProcessID], entry.ProcessID)
+	}
+
+	tree := []uint32{pid}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i]]...)
+	}
+	return tree
+}
+
+// 对进程树中的每个进程执行操作
+func eachInTree(pid uint32, access uint32, fn func(h syscall.Handle)) {
+	for _, id := range processTree(pid) {
+		h, err := syscall.OpenProcess(access, false, id)
+		if err != nil {
+			continue
+		}
+		fn(h)
+		syscall.CloseHandle(h)
+	}
+}
+
+// 暂停进程树
+func SuspendTree(pid uint32) {
+	eachInTree(pid, uint32(PROCESS_SUSPEND_RESUME), func(h syscall.Handle) {
+		ntSuspendProcess.Call(uintptr(h))
+	})
+	log.Printf("已暂停进程树 %d", pid)
+}
+
+// 恢复进程树
+func ResumeTree(pid uint32) {
+	eachInTree(pid, uint32(PROCESS_SUSPEND_RESUME), func(h syscall.Handle) {
+		ntResumeProcess.Call(uintptr(h))
+	})
+	log.Printf("已恢复进程树 %d", pid)
+}
+
+// 结束进程树
+func KillTree(pid uint32) {
+	eachInTree(pid, uint32(PROCESS_TERMINATE), func(h syscall.Handle) {
+		syscall.TerminateProcess(h, 1)
+	})
+	log.Printf("已结束进程树 %d", pid)
+}
@@ -0,0 +1,208 @@
+// Package runner 封装外部命令的执行，测试中可替换为 Mock
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 要执行的外部命令
+type Command struct {
+	Name       string
+	Args       []string
+	Env        []string // 只对这个命令生效的环境变量，覆盖同名的变量
+	Dir        string   // 工作目录，留空使用当前目录
+	HideWindow bool     // 不显示控制台窗口
+	Detach     bool     // Start 使用：与启动器所在的终端分离，不继承标准输入输出，终端关闭后继续运行
+
+	// Output 和 Stream 使用：Context 被取消或超过 Timeout 时结束整个进程树。
+	// Context 为 nil 时不可取消，Timeout 为 0 时不限制时长
+	Context context.Context
+	Timeout time.Duration
+}
+
+// 命令因 Context 被取消而终止
+var ErrCanceled = errors.New("操作已取消")
+
+// 已启动、不等待结束的进程
+type Process interface {
+	Wait() error
+}
+
+// 外部命令的执行方式，测试中可替换为模拟实现
+type CommandRunner interface {
+	// 运行命令并返回合并后的标准输出和错误输出
+	Output(c Command) (string, error)
+	// 运行命令直到结束，每行输出回调一次
+	Stream(c Command, onLine func(line string, isError bool)) error
+	// 启动命令后立即返回
+	Start(c Command) (Process, error)
+}
+
+var (
+	activeMutex     sync.Mutex
+	activeProcesses = map[int]bool{}
+)
+
+// 记录正在运行、等待结束的子进程，供磁盘监控等功能暂停或结束它们
+func track(pid int, running bool) {
+	activeMutex.Lock()
+	defer activeMutex.Unlock()
+	if running {
+		activeProcesses[pid] = true
+	} else {
+		delete(activeProcesses, pid)
+	}
+}
+
+// 正在运行的子进程 ID
+func ActiveProcessIDs() []int {
+	activeMutex.Lock()
+	defer activeMutex.Unlock()
+	var pids []int
+	for pid := range activeProcesses {
+		pids = append(pids, pid)
+	}
+	return pids
+}
+
+// 基于 os/exec 的实际实现
+type Exec struct {
+	Env *Environment // 所有命令共用的环境变量，Command.Env 覆盖其中的同名变量；为 nil 时使用本进程的环境变量
+}
+
+// 按 Context 和 Timeout 创建命令，取消或超时时结束整个进程树（powershell 启动的 uv 等子进程也一并结束）
+func (r Exec) commandContext(c Command) (*exec.Cmd, context.Context, context.CancelFunc) {
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cancel := context.CancelFunc(func() {})
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+	}
+	cmd := r.command(ctx, c)
+	cmd.Cancel = func() error {
+		KillTree(uint32(cmd.Process.Pid))
+		return nil
+	}
+	// 孙进程可能继承了输出管道，进程树结束后不再等待管道关闭
+	cmd.WaitDelay = 5 * time.Second
+	return cmd, ctx, cancel
+}
+
+// 命令被取消或超时时，把错误换成说明原因的错误并记录日志
+func contextErr(ctx context.Context, c Command, err error) error {
+	if err == nil {
+		return nil
+	}
+	line := strings.Join(append([]string{c.Name}, c.Args...), " ")
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		log.Printf("命令超过 %v 未结束，已结束进程树: %s", c.Timeout, line)
+		return fmt.Errorf("超过 %v 未结束，已终止", c.Timeout)
+	case context.Canceled:
+		log.Printf("命令已取消，已结束进程树: %s", line)
+		return ErrCanceled
+	}
+	return err
+}
+
+func (r Exec) command(ctx context.Context, c Command) *exec.Cmd {
+	name := c.Name
+	var env []string
+	if r.Env != nil || len(c.Env) > 0 {
+		env = r.Env.With(c.Env...)
+		// 按命令看到的 PATH 查找可执行文件（exec.Command 只查本进程的 PATH），找不到时由 exec 报告错误
+		if !strings.ContainsAny(name, `/\`) {
+			if path, err := lookPathIn(lookupEnv(env, "PATH"), name); err == nil {
+				name = path
+			}
+		}
+	}
+	cmd := exec.CommandContext(ctx, name, c.Args...)
+	if c.HideWindow {
+		hideWindow(cmd)
+	}
+	cmd.Env = env
+	cmd.Dir = c.Dir
+	return cmd
+}
+
+func (r Exec) Output(c Command) (string, error) {
+	cmd, ctx, cancel := r.commandContext(c)
+	defer cancel()
+	var outBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &outBuf
+	if err := cmd.Start(); err != nil {
+		return "", contextErr(ctx, c, err)
+	}
+	assignToJob(cmd.Process.Pid)
+	track(cmd.Process.Pid, true)
+	defer track(cmd.Process.Pid, false)
+	err := cmd.Wait()
+	return outBuf.String(), contextErr(ctx, c, err)
+}
+
+func (r Exec) Stream(c Command, onLine func(line string, isError bool)) error {
+	cmd, ctx, cancel := r.commandContext(c)
+	defer cancel()
+	// 获取标准输出和错误输出管道
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return contextErr(ctx, c, err)
+	}
+	assignToJob(cmd.Process.Pid)
+	track(cmd.Process.Pid, true)
+	defer track(cmd.Process.Pid, false)
+
+	// 实时处理输出，读完两个管道后再等待进程结束
+	var wg sync.WaitGroup
+	scan := func(reader io.Reader, isError bool) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			onLine(scanner.Text(), isError)
+		}
+	}
+	wg.Add(2)
+	go scan(stdout, false)
+	go scan(stderr, true)
+	wg.Wait()
+	return contextErr(ctx, c, cmd.Wait())
+}
+
+func (r Exec) Start(c Command) (Process, error) {
+	// 启动的应用独立运行，不受 Context 和 Timeout 限制，也不加入作业对象，启动器退出后继续运行
+	cmd := r.command(context.Background(), c)
+	if c.Detach {
+		// 启动器退出后没有人读取输出，应用的输出由它自己写入日志
+		detach(cmd)
+	} else {
+		// 获取输出以便记录可能的错误
+		var outBuf bytes.Buffer
+		cmd.Stdout = &outBuf
+		cmd.Stderr = &outBuf
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
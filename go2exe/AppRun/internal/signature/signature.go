@@ -0,0 +1,66 @@
+// Package signature 在执行下载的可执行文件和安装脚本前校验它们的 Authenticode 签名（Windows 上通过 WinVerifyTrust），
+// 拒绝执行没有签名、签名无效或发布者不在信任列表中的文件，除非配置明确允许
+package signature
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// 当前系统不支持 Authenticode（非 Windows），不校验
+var ErrUnsupported = errors.New("当前系统不支持校验 Authenticode 签名")
+
+// 可以带 Authenticode 签名的文件类型，其他文件（例如 Linux 的 shell 脚本）不校验
+var signable = []string{".exe", ".dll", ".msi", ".ps1", ".psm1", ".cat"}
+
+// 签名校验策略，零值要求有效的签名，不限制发布者
+type Policy struct {
+	AllowUnsigned bool     // 签名缺失或无效时只记录，仍然执行
+	Publishers    []string // 信任的发布者（签名证书的主题名称，不区分大小写），为空时接受任何有效签名
+}
+
+// 签名校验失败
+type Error struct {
+	Path      string
+	Publisher string // 签名证书的主题名称，没有有效签名时为空
+	Err       error  // 签名无效的原因，发布者不在信任列表中时为 nil
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s 的数字签名校验失败，已拒绝执行: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s 的发布者 %q 不在信任列表中，已拒绝执行", e.Path, e.Publisher)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// 校验签名的实现，测试时替换
+var verifyFile = verify
+
+// 按策略校验 path 的签名，不能签名的文件类型和不支持 Authenticode 的系统上直接通过
+func (p Policy) Check(path string) error {
+	if !slices.Contains(signable, strings.ToLower(filepath.Ext(path))) {
+		return nil
+	}
+	publisher, err := verifyFile(path)
+	if errors.Is(err, ErrUnsupported) {
+		return nil
+	}
+	if err == nil && (len(p.Publishers) == 0 || slices.ContainsFunc(p.Publishers, func(s string) bool { return strings.EqualFold(s, publisher) })) {
+		log.Printf("%s 的数字签名有效，发布者: %s", path, publisher)
+		return nil
+	}
+	serr := &Error{Path: path, Publisher: publisher, Err: err}
+	if p.AllowUnsigned {
+		log.Printf("%v（配置允许执行未签名的文件，继续）", serr)
+		return nil
+	}
+	return serr
+}
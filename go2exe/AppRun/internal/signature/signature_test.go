@@ -0,0 +1,46 @@
+package signature
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	defer func(v func(string) (string, error)) { verifyFile = v }(verifyFile)
+	signers := map[string]string{"uv.exe": "Astral Software Inc.", "other.exe": "Someone Else"}
+	verifyFile = func(path string) (string, error) {
+		if s, ok := signers[path]; ok {
+			return s, nil
+		}
+		return "", errors.New("文件没有数字签名")
+	}
+
+	cases := []struct {
+		policy Policy
+		path   string
+		ok     bool
+	}{
+		{Policy{}, "uv.exe", true},
+		{Policy{}, "unsigned.exe", false},
+		{Policy{}, "install.sh", true}, // 不能签名的文件类型不校验
+		{Policy{Publishers: []string{"astral software inc."}}, "uv.exe", true},
+		{Policy{Publishers: []string{"Astral Software Inc."}}, "other.exe", false},
+		{Policy{AllowUnsigned: true}, "unsigned.exe", true},
+	}
+	for _, c := range cases {
+		err := c.policy.Check(c.path)
+		if (err == nil) != c.ok {
+			t.Errorf("%+v.Check(%s) = %v", c.policy, c.path, err)
+		}
+		var serr *Error
+		if err != nil && !errors.As(err, &serr) {
+			t.Errorf("Check(%s) 应返回 *Error，实际为 %T", c.path, err)
+		}
+	}
+
+	// 不支持 Authenticode 的系统上不校验
+	verifyFile = func(string) (string, error) { return "", ErrUnsupported }
+	if err := (Policy{}).Check("unsigned.exe"); err != nil {
+		t.Errorf("不支持时 Check() = %v", err)
+	}
+}
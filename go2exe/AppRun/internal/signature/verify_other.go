@@ -0,0 +1,8 @@
+//go:build !windows
+
+package signature
+
+// 非 Windows 系统没有 Authenticode，下载的是 shell 脚本等不带签名的文件
+func verify(path string) (string, error) {
+	return "", ErrUnsupported
+}
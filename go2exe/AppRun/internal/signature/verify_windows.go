@@ -0,0 +1,131 @@
+package signature
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	wintrust                       = syscall.NewLazyDLL("wintrust.dll")
+	crypt32                        = syscall.NewLazyDLL("crypt32.dll")
+	winVerifyTrust                 = wintrust.NewProc("WinVerifyTrust")
+	wtHelperProvDataFromStateData  = wintrust.NewProc("WTHelperProvDataFromStateData")
+	wtHelperGetProvSignerFromChain = wintrust.NewProc("WTHelperGetProvSignerFromChain")
+	certGetNameString              = crypt32.NewProc("CertGetNameStringW")
+	WTD_UI_NONE                    = 2
+	WTD_REVOKE_NONE                = 0
+	WTD_CHOICE_FILE                = 1
+	WTD_STATEACTION_VERIFY         = 1
+	WTD_STATEACTION_CLOSE          = 2
+	WTD_CACHE_ONLY_URL_RETRIEVAL   = 0x1000
+	CERT_NAME_SIMPLE_DISPLAY_TYPE  = 4
+)
+
+// WINTRUST_ACTION_GENERIC_VERIFY_V2：按 Authenticode 策略校验
+var actionGenericVerifyV2 = syscall.GUID{Data1: 0x00AAC56B, Data2: 0xCD44, Data3: 0x11D0, Data4: [8]byte{0x8C, 0xC2, 0x00, 0xC0, 0x4F, 0xC2, 0x95, 0xEE}}
+
+// WINTRUST_FILE_INFO
+type winTrustFileInfo struct {
+	cbStruct     uint32
+	filePath     *uint16
+	file         syscall.Handle
+	knownSubject *syscall.GUID
+}
+
+// WINTRUST_DATA
+type winTrustData struct {
+	cbStruct           uint32
+	policyCallbackData uintptr
+	sipClientData      uintptr
+	uiChoice           uint32
+	revocationChecks   uint32
+	unionChoice        uint32
+	file               *winTrustFileInfo
+	stateAction        uint32
+	stateData          syscall.Handle
+	urlReference       *uint16
+	provFlags          uint32
+	uiContext          uint32
+	signatureSettings  uintptr
+}
+
+// CRYPT_PROVIDER_SGNR 的开头部分
+type cryptProviderSgnr struct {
+	cbStruct      uint32
+	verifyAsOf    syscall.Filetime
+	certChainSize uint32
+	certChain     *cryptProviderCert
+}
+
+// CRYPT_PROVIDER_CERT 的开头部分
+type cryptProviderCert struct {
+	cbStruct uint32
+	cert     uintptr // PCCERT_CONTEXT
+}
+
+// WinVerifyTrust 的常见错误
+var trustErrors = map[uint32]string{
+	0x800B0100: "文件没有数字签名",
+	0x80096010: "文件在签名后被修改",
+	0x800B0109: "签名证书不受信任",
+	0x800B0111: "签名证书被明确标记为不信任",
+	0x800B0101: "签名证书已过期",
+	0x800B0003: "无法识别的文件格式",
+	0x80092026: "系统安全设置禁止此签名",
+	0x800B0004: "签名者不受信任",
+}
+
+// 用 WinVerifyTrust 校验 path 的签名，返回签名证书的主题名称。
+// 不检查证书吊销：机房等离线环境访问吊销列表会超时，只使用系统缓存
+func verify(path string) (string, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+	info := winTrustFileInfo{filePath: name}
+	info.cbStruct = uint32(unsafe.Sizeof(info))
+	data := winTrustData{
+		uiChoice:         uint32(WTD_UI_NONE),
+		revocationChecks: uint32(WTD_REVOKE_NONE),
+		unionChoice:      uint32(WTD_CHOICE_FILE),
+		file:             &info,
+		stateAction:      uint32(WTD_STATEACTION_VERIFY),
+		provFlags:        uint32(WTD_CACHE_ONLY_URL_RETRIEVAL),
+	}
+	data.cbStruct = uint32(unsafe.Sizeof(data))
+	noUI := ^uintptr(0) // INVALID_HANDLE_VALUE：不显示任何界面
+	r, _, _ := winVerifyTrust.Call(noUI, uintptr(unsafe.Pointer(&actionGenericVerifyV2)), uintptr(unsafe.Pointer(&data)))
+	defer func() {
+		data.stateAction = uint32(WTD_STATEACTION_CLOSE)
+		winVerifyTrust.Call(noUI, uintptr(unsafe.Pointer(&actionGenericVerifyV2)), uintptr(unsafe.Pointer(&data)))
+	}()
+	if r != 0 {
+		if msg, ok := trustErrors[uint32(r)]; ok {
+			return "", fmt.Errorf("%s（0x%08X）", msg, uint32(r))
+		}
+		return "", fmt.Errorf("WinVerifyTrust 返回 0x%08X", uint32(r))
+	}
+	return signerName(data.stateData), nil
+}
+
+// 签名证书的主题名称，取不到时为空
+func signerName(state syscall.Handle) string {
+	provData, _, _ := wtHelperProvDataFromStateData.Call(uintptr(state))
+	if provData == 0 {
+		return ""
+	}
+	r, _, _ := wtHelperGetProvSignerFromChain.Call(provData, 0, 0, 0)
+	sgnr := *(**cryptProviderSgnr)(unsafe.Pointer(&r))
+	if sgnr == nil || sgnr.certChainSize == 0 || sgnr.certChain == nil {
+		return ""
+	}
+	cert := sgnr.certChain.cert
+	n, _, _ := certGetNameString.Call(cert, uintptr(CERT_NAME_SIMPLE_DISPLAY_TYPE), 0, 0, 0, 0)
+	if n <= 1 {
+		return ""
+	}
+	buf := make([]uint16, n)
+	certGetNameString.Call(cert, uintptr(CERT_NAME_SIMPLE_DISPLAY_TYPE), 0, 0, uintptr(unsafe.Pointer(&buf[0])), n)
+	return syscall.UTF16ToString(buf)
+}
@@ -0,0 +1,265 @@
+package simulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go2exe/internal/runner"
+)
+
+// 下载文件（模型文件、应用更新包）的 HTTP 请求，用于注入下载故障
+const StepDownload = "download"
+
+// 注入的故障动作
+const (
+	ActionFail      = "fail"      // 命令或请求失败（输出为网络错误，会触发重试）
+	ActionDelay     = "delay"     // 等待一段时间后再执行，模拟卡住的命令或很慢的网络
+	ActionCorrupt   = "corrupt"   // 下载的内容被篡改，校验失败
+	ActionInterrupt = "interrupt" // 下载到一半时连接中断，下次从断开的位置继续
+)
+
+// 注入故障时命令的输出：看起来像网络错误，安装步骤会按配置重试
+const injectedOutput = "error: Failed to fetch: `https://pypi.org/simple/`\n  Caused by: dns error: No such host is known. (os error 11001) [injected fault]"
+
+// 一个注入的故障
+type Injection struct {
+	Step   string        // 步骤名，见 Step 开头的常量
+	Action string        // 故障动作，见 Action 开头的常量
+	Times  int           // 前几次执行时生效，0 表示每次都生效
+	Delay  time.Duration // ActionDelay 等待的时间
+}
+
+func (f Injection) String() string {
+	s := f.Step + ":" + f.Action
+	if f.Action == ActionDelay {
+		return s + ":" + f.Delay.String()
+	}
+	return s + ":" + strconv.Itoa(f.Times)
+}
+
+// 在真实的命令和网络上注入故障，用于测试和重现重试、回退、断点续传等恢复流程
+type Injector struct {
+	faults []Injection
+
+	mu   sync.Mutex
+	hits []int // 每个故障匹配到的次数
+}
+
+// 解析故障描述，多个故障用逗号分隔，每个故障的格式为 步骤:动作[:参数]，例如
+// "sync:fail:1,download:corrupt,python-install:delay:30s"。
+// fail、corrupt 和 interrupt 的参数为生效的次数（默认 1，0 表示每次），delay 的参数为等待时间（默认 30s）
+func ParseFaults(spec string) (*Injector, error) {
+	in := &Injector{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("故障 %q 的格式应为 步骤:动作[:参数]", item)
+		}
+		f := Injection{Step: parts[0], Action: parts[1], Times: 1, Delay: 30 * time.Second}
+		if !knownStep(f.Step) {
+			return nil, fmt.Errorf("故障 %q 的步骤 %s 无效", item, f.Step)
+		}
+		switch f.Action {
+		case ActionFail, ActionDelay:
+		case ActionCorrupt, ActionInterrupt:
+			if f.Step != StepDownload {
+				return nil, fmt.Errorf("故障 %q 无效：%s 只能用于 %s", item, f.Action, StepDownload)
+			}
+		default:
+			return nil, fmt.Errorf("故障 %q 的动作 %s 无效，可用 fail、delay、corrupt 或 interrupt", item, f.Action)
+		}
+		if len(parts) == 3 {
+			var err error
+			if f.Action == ActionDelay {
+				f.Delay, err = time.ParseDuration(parts[2])
+			} else {
+				f.Times, err = strconv.Atoi(parts[2])
+			}
+			if err != nil || f.Times < 0 || f.Delay < 0 {
+				return nil, fmt.Errorf("故障 %q 的参数 %s 无效", item, parts[2])
+			}
+		}
+		in.faults = append(in.faults, f)
+	}
+	in.hits = make([]int, len(in.faults))
+	return in, nil
+}
+
+func knownStep(step string) bool {
+	switch step {
+	case StepUVVersion, StepUVInstall, StepPythonList, StepPythonInstall, StepResolve, StepSync, StepApp, StepDownload:
+		return true
+	}
+	return false
+}
+
+// 要注入的故障
+func (in *Injector) Faults() []Injection { return in.faults }
+
+// 本次执行 step 时生效的故障
+func (in *Injector) match(step string) []Injection {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	var active []Injection
+	for n, f := range in.faults {
+		if f.Step != step {
+			continue
+		}
+		in.hits[n]++
+		// delay 的参数是时间，每次都生效
+		if f.Action == ActionDelay || f.Times == 0 || in.hits[n] <= f.Times {
+			log.Printf("注入故障 %s（第 %d 次）", f, in.hits[n])
+			active = append(active, f)
+		}
+	}
+	return active
+}
+
+// 按故障执行 step：先执行 delay，有 fail 时返回注入的错误
+func (in *Injector) apply(ctx context.Context, step string) error {
+	for _, f := range in.match(step) {
+		switch f.Action {
+		case ActionDelay:
+			if err := sleep(ctx, f.Delay); err != nil {
+				return err
+			}
+		case ActionFail:
+			return errors.New(injectedOutput)
+		}
+	}
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 在 r 执行的命令上注入故障
+func (in *Injector) Runner(r runner.CommandRunner) runner.CommandRunner {
+	return injectRunner{in, r}
+}
+
+type injectRunner struct {
+	in *Injector
+	r  runner.CommandRunner
+}
+
+func (r injectRunner) before(c runner.Command) error {
+	err := r.in.apply(c.Context, stepOf(c))
+	if err != nil && c.Context != nil && c.Context.Err() != nil {
+		return runner.ErrCanceled
+	}
+	return err
+}
+
+func (r injectRunner) Output(c runner.Command) (string, error) {
+	if err := r.before(c); err != nil {
+		return err.Error(), err
+	}
+	return r.r.Output(c)
+}
+
+func (r injectRunner) Stream(c runner.Command, onLine func(line string, isError bool)) error {
+	if err := r.before(c); err != nil {
+		if !errors.Is(err, runner.ErrCanceled) {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				onLine(line, true)
+			}
+		}
+		return err
+	}
+	return r.r.Stream(c, onLine)
+}
+
+func (r injectRunner) Start(c runner.Command) (runner.Process, error) {
+	if err := r.before(c); err != nil {
+		return nil, err
+	}
+	return r.r.Start(c)
+}
+
+// 在 rt 发出的 HTTP 请求上注入下载故障
+func (in *Injector) Transport(rt http.RoundTripper) http.RoundTripper {
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		var corrupt, interrupt bool
+		for _, f := range in.match(StepDownload) {
+			switch f.Action {
+			case ActionDelay:
+				if err := sleep(req.Context(), f.Delay); err != nil {
+					return nil, err
+				}
+			case ActionFail:
+				return nil, fmt.Errorf("read tcp: connection reset by peer [injected fault]")
+			case ActionCorrupt:
+				corrupt = true
+			case ActionInterrupt:
+				interrupt = true
+			}
+		}
+		resp, err := rt.RoundTrip(req)
+		if err != nil || resp.StatusCode >= 300 {
+			return resp, err
+		}
+		if corrupt {
+			resp.Body = &corruptBody{ReadCloser: resp.Body}
+		}
+		if interrupt {
+			// 长度未知时读到 64KB 后中断
+			limit := int64(64 << 10)
+			if resp.ContentLength > 0 {
+				limit = resp.ContentLength / 2
+			}
+			resp.Body = &interruptBody{ReadCloser: resp.Body, left: limit}
+		}
+		return resp, nil
+	})
+}
+
+// 把内容中的每个字节取反
+type corruptBody struct{ io.ReadCloser }
+
+func (b *corruptBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	for k := range p[:n] {
+		p[k] = ^p[k]
+	}
+	return n, err
+}
+
+// 读到 left 个字节后连接中断
+type interruptBody struct {
+	io.ReadCloser
+	left int64
+}
+
+func (b *interruptBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		return 0, fmt.Errorf("unexpected EOF: connection reset by peer [injected fault]")
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= int64(n)
+	return n, err
+}
@@ -0,0 +1,265 @@
+// Package simulate 按可编排的场景模拟 uv、PowerShell 和网络（例如没有 uv、杀毒软件拦截、镜像无法访问），
+// 用于在 CI 和非 Windows 的开发机上端到端测试安装和启动流程，也可以通过 --simulate 在真实的启动器中演示。
+// Injector 在真实的命令和下载上注入故障（--inject-fault），用于重现重试、回退和断点续传等恢复流程
+package simulate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go2exe/internal/runner"
+)
+
+// 模拟的命令，Scenario.Faults 按这些名称注入故障
+const (
+	StepUVVersion     = "uv-version"     // uv -V
+	StepUVInstall     = "uv-install"     // 运行 uv-installer.ps1
+	StepPythonList    = "python-list"    // uv python list
+	StepPythonInstall = "python-install" // uv python install
+	StepResolve       = "resolve"        // uv sync --locked --dry-run
+	StepSync          = "sync"           // uv sync
+	StepApp           = "app"            // 启动应用
+)
+
+// 一个模拟场景：初始环境以及在哪些步骤失败
+type Scenario struct {
+	Name        string            `json:"name"`
+	UVInstalled bool              `json:"uv_installed"` // 开始时 uv 已安装
+	Pythons     []string          `json:"pythons"`      // 开始时已安装的 Python，例如 cpython-3.11.9-windows-x86_64-none
+	Faults      map[string]Fault  `json:"faults"`       // 按步骤名注入的故障
+	Offline     bool              `json:"offline"`      // 所有 HTTP 请求都因无法连接而失败
+	Responses   map[string]string `json:"responses"`    // 按 URL 返回的 HTTP 响应内容，其他 URL 返回 404
+}
+
+// 一个步骤的故障
+type Fault struct {
+	Output string `json:"output"`   // 失败时命令的输出，也用作错误信息
+	Times  int    `json:"times"`    // 前几次执行失败，0 表示每次都失败
+	Delay  int    `json:"delay_ms"` // 失败前等待的时间（毫秒），模拟超时前卡住的命令
+}
+
+// 模拟的 uv 版本
+const uvVersion = "uv 0.4.18 (simulated)"
+
+// 未安装 uv 时执行 uv 命令的错误
+var errUVNotFound = errors.New(`exec: "uv": executable file not found in %PATH%`)
+
+// 内置的场景
+var scenarios = map[string]Scenario{
+	// uv 和 Python 都已安装，所有步骤成功
+	"ok": {UVInstalled: true, Pythons: []string{"cpython-3.11.9-windows-x86_64-none"}},
+	// 首次运行：没有 uv 和 Python，安装成功
+	"uv-missing": {},
+	// 杀毒软件拦截 uv 的安装
+	"av-blocking": {Faults: map[string]Fault{
+		StepUVInstall: {Output: "Access is denied. (os error 5)\nThe file may have been quarantined by antivirus software."},
+	}},
+	// PyPI 镜像无法访问：依赖检查和同步因网络错误失败
+	"mirror-down": {
+		UVInstalled: true,
+		Pythons:     []string{"cpython-3.11.9-windows-x86_64-none"},
+		Offline:     true,
+		Faults: map[string]Fault{
+			StepResolve: {Output: "error: Failed to fetch: `https://pypi.tuna.tsinghua.edu.cn/simple/numpy/`\n  Caused by: dns error: No such host is known. (os error 11001)"},
+			StepSync:    {Output: "error: Failed to fetch: `https://pypi.tuna.tsinghua.edu.cn/simple/numpy/`\n  Caused by: dns error: No such host is known. (os error 11001)"},
+		},
+	},
+}
+
+// 内置场景的名称
+func Scenarios() []string {
+	var names []string
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 读取场景：name 为内置场景的名称或 JSON 场景文件的路径
+func Load(name string) (Scenario, error) {
+	if sc, ok := scenarios[name]; ok {
+		sc.Name = name
+		return sc, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("没有名为 %s 的场景（内置场景：%s），也无法读取场景文件: %v", name, strings.Join(Scenarios(), "、"), err)
+	}
+	var sc Scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return Scenario{}, fmt.Errorf("解析场景文件 %s 失败: %v", name, err)
+	}
+	if sc.Name == "" {
+		sc.Name = name
+	}
+	return sc, nil
+}
+
+// 按场景模拟的环境，记录执行过程中 uv 和 Python 的安装状态
+type Env struct {
+	scenario Scenario
+
+	mu          sync.Mutex
+	uvInstalled bool
+	pythons     []string
+	runs        map[string]int // 每个步骤执行的次数
+}
+
+// 创建处于场景初始状态的环境
+func New(sc Scenario) *Env {
+	return &Env{
+		scenario:    sc,
+		uvInstalled: sc.UVInstalled,
+		pythons:     append([]string(nil), sc.Pythons...),
+		runs:        map[string]int{},
+	}
+}
+
+// 按场景执行命令的执行器。模拟的应用启动后一直运行，直到启动器退出
+func (e *Env) Runner() runner.CommandRunner {
+	return simRunner{&runner.Mock{Handler: e.run}}
+}
+
+type simRunner struct{ *runner.Mock }
+
+func (r simRunner) Start(c runner.Command) (runner.Process, error) {
+	if stepOf(c) != StepApp {
+		return r.Mock.Start(c)
+	}
+	if _, err := r.Mock.Output(c); err != nil {
+		return nil, err
+	}
+	return runningApp{}, nil
+}
+
+// 一直运行的模拟应用
+type runningApp struct{}
+
+func (runningApp) Wait() error { select {} }
+
+// 步骤执行的次数
+func (e *Env) Runs(step string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.runs[step]
+}
+
+// 当前已安装的 uv 和 Python
+func (e *Env) Installed() (uv bool, pythons []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.uvInstalled, append([]string(nil), e.pythons...)
+}
+
+// 判断命令对应的步骤，不认识的命令返回空字符串
+func stepOf(c runner.Command) string {
+	line := strings.Join(append([]string{c.Name}, c.Args...), " ")
+	switch {
+	case c.Name == "uv" && len(c.Args) > 0 && c.Args[0] == "-V":
+		return StepUVVersion
+	case strings.Contains(line, "uv-installer.ps1"):
+		return StepUVInstall
+	case strings.Contains(line, "uv python list"):
+		return StepPythonList
+	case strings.Contains(line, "uv python install"):
+		return StepPythonInstall
+	case strings.Contains(line, "uv sync") && strings.Contains(line, "--dry-run"):
+		return StepResolve
+	case strings.Contains(line, "uv sync"):
+		return StepSync
+	case strings.HasSuffix(c.Name, "pythonw.exe"):
+		return StepApp
+	}
+	return ""
+}
+
+func (e *Env) run(c runner.Command) (string, error) {
+	step := stepOf(c)
+	e.mu.Lock()
+	e.runs[step]++
+	n := e.runs[step]
+	uvInstalled := e.uvInstalled
+	e.mu.Unlock()
+
+	if f, ok := e.scenario.Faults[step]; ok && (f.Times == 0 || n <= f.Times) {
+		time.Sleep(time.Duration(f.Delay) * time.Millisecond)
+		return f.Output, errors.New(f.Output)
+	}
+	if !uvInstalled && step != StepUVInstall && step != StepApp && step != "" {
+		return "", errUVNotFound
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch step {
+	case StepUVVersion:
+		return uvVersion, nil
+	case StepUVInstall:
+		e.uvInstalled = true
+		return "installing to C:\\Users\\sim\\.local\\bin\neverything's installed!", nil
+	case StepPythonList:
+		var lines []string
+		for _, key := range e.pythons {
+			lines = append(lines, fmt.Sprintf("%s    C:\\Users\\sim\\AppData\\Roaming\\uv\\python\\%s\\python.exe", key, key))
+		}
+		return strings.Join(lines, "\n"), nil
+	case StepPythonInstall:
+		key := installKey(c.Args)
+		e.pythons = append(e.pythons, key)
+		return "Installed Python " + key, nil
+	case StepResolve:
+		return "Resolved 3 packages in 10ms\nWould install 3 packages", nil
+	case StepSync:
+		return "Resolved 3 packages in 10ms\nPrepared 3 packages in 1.2s\nInstalled 3 packages in 30ms", nil
+	}
+	return "", nil
+}
+
+// uv python install 安装的版本：install 后面的版本请求，只有版本号时补全为 Windows x86_64 的 CPython
+func installKey(args []string) string {
+	var request string
+	for n, arg := range args {
+		if arg == "install" && n+1 < len(args) {
+			request = args[n+1]
+			break
+		}
+	}
+	if strings.HasPrefix(request, "cpython-") {
+		return request
+	}
+	return "cpython-" + request + "-windows-x86_64-none"
+}
+
+// 按场景响应 HTTP 请求的 Transport，用于替换 http.DefaultTransport
+func (e *Env) Transport() http.RoundTripper {
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		if e.scenario.Offline {
+			return nil, fmt.Errorf("dial tcp: lookup %s: no such host", req.URL.Hostname())
+		}
+		body, ok := e.scenario.Responses[req.URL.String()]
+		status := http.StatusOK
+		if !ok {
+			status = http.StatusNotFound
+		}
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     http.Header{},
+			Request:    req,
+		}, nil
+	})
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
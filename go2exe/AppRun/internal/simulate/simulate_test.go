@@ -0,0 +1,198 @@
+package simulate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/install"
+	"go2exe/internal/models"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// 与启动器相同的安装流程：检查 uv 和 Python，缺少时安装，然后预检并同步依赖
+func setup(t *testing.T, env *Env) error {
+	r := env.Runner()
+	check := envcheck.Checker{Runner: r}
+	inst := &install.Installer{ExeDir: t.TempDir(), Runner: r, Out: ui.Discard, TempDir: t.TempDir(), Retry: install.RetryPolicy{Attempts: 2}}
+	// 与发布的程序目录相同，随程序分发 uv 的安装脚本和安装包
+	uvDir := filepath.Join(inst.ExeDir, "uv")
+	os.MkdirAll(uvDir, 0755)
+	os.WriteFile(filepath.Join(uvDir, "uv-installer.ps1"), nil, 0644)
+	os.WriteFile(filepath.Join(uvDir, "uv-x86_64-pc-windows-msvc.zip"), nil, 0644)
+	if ok, _ := check.UVInstalled(); !ok {
+		if err := inst.RunStep("安装 uv", inst.InstallUV); err != nil {
+			return err
+		}
+	}
+	python, err := check.FindPython()
+	if err != nil {
+		return err
+	}
+	if python == nil {
+		if err := inst.RunStep("安装 Python", inst.InstallPython); err != nil {
+			return err
+		}
+	}
+	if err := inst.CheckResolution(); err != nil {
+		return err
+	}
+	return inst.RunStep("同步依赖", inst.Sync)
+}
+
+func TestScenarios(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		env := New(scenarios["ok"])
+		if err := setup(t, env); err != nil {
+			t.Fatalf("setup() = %v", err)
+		}
+		if env.Runs(StepUVInstall) != 0 || env.Runs(StepPythonInstall) != 0 {
+			t.Errorf("环境已安装时仍执行了安装")
+		}
+	})
+
+	t.Run("uv-missing", func(t *testing.T) {
+		env := New(scenarios["uv-missing"])
+		if err := setup(t, env); err != nil {
+			t.Fatalf("setup() = %v", err)
+		}
+		uv, pythons := env.Installed()
+		if !uv || len(pythons) != 1 {
+			t.Errorf("安装后 uv %v，Python %v", uv, pythons)
+		}
+		if env.Runs(StepSync) != 1 {
+			t.Errorf("同步执行了 %d 次", env.Runs(StepSync))
+		}
+	})
+
+	t.Run("av-blocking", func(t *testing.T) {
+		env := New(scenarios["av-blocking"])
+		err := setup(t, env)
+		if err == nil {
+			t.Fatal("uv 安装被拦截时 setup() 没有返回错误")
+		}
+		// 拦截不是网络问题，不重试
+		if env.Runs(StepUVInstall) != 1 || env.Runs(StepSync) != 0 {
+			t.Errorf("安装 uv 执行了 %d 次，同步执行了 %d 次", env.Runs(StepUVInstall), env.Runs(StepSync))
+		}
+	})
+
+	t.Run("mirror-down", func(t *testing.T) {
+		env := New(scenarios["mirror-down"])
+		err := setup(t, env)
+		var rerr *install.ResolveError
+		if !errors.As(err, &rerr) || rerr.Kind != install.ResolveNetwork {
+			t.Fatalf("setup() = %v，want 网络原因的 ResolveError", err)
+		}
+		if _, err := (&http.Client{Transport: env.Transport()}).Get("https://pypi.org/simple/"); err == nil {
+			t.Error("镜像无法访问时 HTTP 请求成功")
+		}
+	})
+}
+
+func TestFaultTimes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flaky.json")
+	os.WriteFile(path, []byte(`{"uv_installed": true, "pythons": ["cpython-3.11.9-windows-x86_64-none"],
+		"faults": {"sync": {"output": "error: Failed to fetch: connection reset", "times": 1}}}`), 0644)
+	sc, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	env := New(sc)
+	// 第一次同步因网络错误失败，重试后成功
+	if err := setup(t, env); err != nil {
+		t.Fatalf("setup() = %v", err)
+	}
+	if env.Runs(StepSync) != 2 {
+		t.Errorf("同步执行了 %d 次，want 2", env.Runs(StepSync))
+	}
+}
+
+func TestLoadUnknown(t *testing.T) {
+	if _, err := Load("no-such-scenario"); err == nil {
+		t.Error("Load() 未知的场景没有返回错误")
+	}
+	if _, err := New(Scenario{}).Runner().Output(runner.Command{Name: "uv", Args: []string{"-V"}}); err == nil {
+		t.Error("没有 uv 时 uv -V 成功")
+	}
+}
+
+func TestParseFaults(t *testing.T) {
+	in, err := ParseFaults("sync:fail, download:corrupt:0,python-install:delay:2s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Injection{
+		{Step: StepSync, Action: ActionFail, Times: 1, Delay: 30 * time.Second},
+		{Step: StepDownload, Action: ActionCorrupt, Times: 0, Delay: 30 * time.Second},
+		{Step: StepPythonInstall, Action: ActionDelay, Times: 1, Delay: 2 * time.Second},
+	}
+	if !reflect.DeepEqual(in.Faults(), want) {
+		t.Errorf("Faults() = %+v, want %+v", in.Faults(), want)
+	}
+	for _, spec := range []string{"sync", "nope:fail", "sync:explode", "sync:corrupt", "sync:fail:x", "sync:delay:-1s"} {
+		if _, err := ParseFaults(spec); err == nil {
+			t.Errorf("ParseFaults(%q) 没有返回错误", spec)
+		}
+	}
+}
+
+func TestInjectSyncFailOnce(t *testing.T) {
+	in, _ := ParseFaults("sync:fail:1")
+	m := &runner.Mock{}
+	inst := &install.Installer{ExeDir: t.TempDir(), Runner: in.Runner(m), Out: ui.Discard, Retry: install.RetryPolicy{Attempts: 2}}
+	if err := inst.RunStep("同步依赖", inst.Sync); err != nil {
+		t.Fatalf("注入的故障没有在重试后恢复: %v", err)
+	}
+	if len(m.Calls) != 1 {
+		t.Errorf("执行了 %d 次 uv sync，want 1（第一次被注入的故障拦截）", len(m.Calls))
+	}
+}
+
+func TestInjectDownload(t *testing.T) {
+	content := []byte(strings.Repeat("speakmybook ", 1000))
+	sum := sha256.Sum256(content)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "model.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+	f := models.File{Name: "model", Path: "model.bin", URL: srv.URL, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+
+	t.Run("中断后继续", func(t *testing.T) {
+		in, _ := ParseFaults("download:interrupt")
+		client := &http.Client{Transport: in.Transport(http.DefaultTransport)}
+		dir := t.TempDir()
+		if err := models.Download(context.Background(), client, dir, f, nil); err == nil {
+			t.Fatal("下载没有中断")
+		}
+		if err := models.Download(context.Background(), client, dir, f, nil); err != nil {
+			t.Fatalf("没有从断开的位置继续下载: %v", err)
+		}
+	})
+	t.Run("内容被篡改", func(t *testing.T) {
+		in, _ := ParseFaults("download:corrupt")
+		client := &http.Client{Transport: in.Transport(http.DefaultTransport)}
+		if err := models.Download(context.Background(), client, t.TempDir(), f, nil); err == nil {
+			t.Fatal("被篡改的文件通过了校验")
+		}
+	})
+}
@@ -0,0 +1,300 @@
+package timeline
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+
+	"go2exe/internal/i18n"
+)
+
+// 时间线图的尺寸（像素）
+const (
+	labelWidth = 320
+	chartWidth = 640
+	rowHeight  = 22
+	axisHeight = 28
+)
+
+// 各种记录的颜色，失败的记录为红色
+var kindColors = map[Kind]string{
+	KindRun:      "#9e9e9e",
+	KindStep:     "#1976d2",
+	KindCommand:  "#43a047",
+	KindDownload: "#fb8c00",
+	KindRetry:    "#e53935",
+}
+
+const failedColor = "#e53935"
+
+// 时间线中的一行
+type row struct {
+	Y, X, W, LabelX int
+	Label, Text     string
+	Title           string
+	Color           string
+	Open            bool // 没有结束
+}
+
+// 时间轴上的刻度
+type tick struct {
+	X    int
+	Text string
+}
+
+// 重试的时间点
+type marker struct {
+	X     int
+	Title string
+}
+
+// 汇总表中的一行
+type summaryRow struct {
+	Kind, Name, Duration, Size, Result string
+	Failed                             bool
+}
+
+type page struct {
+	Title                      string
+	Overview                   string
+	Width, Height, ChartBottom int
+	Rows                       []row
+	Ticks                      []tick
+	Markers                    []marker
+	Headers                    []string
+	Summary                    []summaryRow
+	Legend                     []legendItem
+}
+
+type legendItem struct {
+	Color, Text string
+}
+
+// 记录种类的显示名称
+func kindName(k Kind) string {
+	switch k {
+	case KindRun:
+		return i18n.T("启动")
+	case KindStep:
+		return i18n.T("安装步骤")
+	case KindCommand:
+		return i18n.T("命令")
+	case KindDownload:
+		return i18n.T("下载")
+	case KindRetry:
+		return i18n.T("重试")
+	}
+	return string(k)
+}
+
+// 行的缩进层级：启动、步骤、步骤中的命令和下载
+func depth(k Kind) int {
+	switch k {
+	case KindRun:
+		return 0
+	case KindStep:
+		return 1
+	}
+	return 2
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// 刻度间隔：不超过 10 个刻度的最小间隔
+func tickStep(total time.Duration) time.Duration {
+	for _, step := range []time.Duration{
+		time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+		time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	} {
+		if total/step <= 10 {
+			return step
+		}
+	}
+	return time.Hour
+}
+
+// 生成时间线的 HTML 页面（内嵌 SVG）：每个步骤、命令和下载一行，重试标为红色竖线，失败的记录为红色，
+// 鼠标悬停显示详情；下方按用时从长到短列出步骤和下载
+func Render(w io.Writer, events []Event) error {
+	if len(events) == 0 {
+		return fmt.Errorf("时间线中没有记录")
+	}
+	start, end := events[0].Start, events[0].Start
+	for _, e := range events {
+		if e.Start.Before(start) {
+			start = e.Start
+		}
+		if e.Start.After(end) {
+			end = e.Start
+		}
+		if e.End != nil && e.End.After(end) {
+			end = *e.End
+		}
+	}
+	total := max(end.Sub(start), time.Second)
+	x := func(t time.Time) int {
+		return labelWidth + int(float64(chartWidth)*float64(t.Sub(start))/float64(total))
+	}
+
+	p := page{
+		Title:   i18n.T("SpeakMyBook 安装时间线"),
+		Width:   labelWidth + chartWidth + 90,
+		Headers: []string{i18n.T("种类"), i18n.T("名称"), i18n.T("用时"), i18n.T("大小"), i18n.T("结果")},
+	}
+	for _, k := range []Kind{KindRun, KindStep, KindCommand, KindDownload, KindRetry} {
+		p.Legend = append(p.Legend, legendItem{kindColors[k], kindName(k)})
+	}
+
+	var steps, commands, downloads, retries, failures int
+	y := axisHeight
+	for _, e := range events {
+		if e.Error != "" {
+			failures++
+		}
+		switch e.Kind {
+		case KindStep:
+			steps++
+		case KindCommand:
+			commands++
+		case KindDownload:
+			downloads++
+		case KindRetry:
+			retries++
+			title := fmt.Sprintf("%s %s +%s", kindName(e.Kind), i18n.T(e.Name), formatDuration(e.Start.Sub(start)))
+			if e.Detail != "" {
+				title += "\n" + e.Detail
+			}
+			p.Markers = append(p.Markers, marker{X: x(e.Start), Title: title})
+			continue
+		}
+
+		stop, open := end, e.End == nil
+		if !open {
+			stop = *e.End
+		}
+		r := row{
+			Y:      y,
+			X:      x(e.Start),
+			W:      max(x(stop)-x(e.Start), 2),
+			LabelX: 8 + depth(e.Kind)*14,
+			Label:  truncate(i18n.T(e.Name), 40-depth(e.Kind)*2),
+			Color:  kindColors[e.Kind],
+			Open:   open,
+		}
+		r.Text = formatDuration(stop.Sub(e.Start))
+		if open {
+			r.Text = i18n.T("未结束")
+		}
+		title := fmt.Sprintf("%s: %s\n+%s, %s", kindName(e.Kind), i18n.T(e.Name), formatDuration(e.Start.Sub(start)), r.Text)
+		if e.Bytes > 0 {
+			title += fmt.Sprintf(", %.1f MB", float64(e.Bytes)/(1<<20))
+		}
+		if e.Detail != "" {
+			title += "\n" + e.Detail
+		}
+		if e.Error != "" {
+			r.Color = failedColor
+			title += "\n" + i18n.T("失败: %s", e.Error)
+		}
+		r.Title = title
+		p.Rows = append(p.Rows, r)
+		y += rowHeight
+	}
+	p.ChartBottom = y
+	p.Height = y + 10
+	for t := time.Duration(0); t <= total; t += tickStep(total) {
+		p.Ticks = append(p.Ticks, tick{X: labelWidth + int(float64(chartWidth)*float64(t)/float64(total)), Text: formatDuration(t)})
+	}
+	p.Overview = i18n.T("总用时 %s：%d 个安装步骤，%d 个命令，%d 个下载，%d 次重试，%d 个失败", formatDuration(end.Sub(start)), steps, commands, downloads, retries, failures)
+
+	// 汇总表：步骤和下载按用时从长到短排列
+	var listed []Event
+	for _, e := range events {
+		if e.Kind == KindStep || e.Kind == KindDownload {
+			listed = append(listed, e)
+		}
+	}
+	sort.SliceStable(listed, func(a, b int) bool { return listed[a].Duration() > listed[b].Duration() })
+	for _, e := range listed {
+		s := summaryRow{Kind: kindName(e.Kind), Name: i18n.T(e.Name), Duration: formatDuration(e.Duration()), Result: i18n.T("成功")}
+		if e.Bytes > 0 {
+			s.Size = fmt.Sprintf("%.1f MB", float64(e.Bytes)/(1<<20))
+			if secs := e.Duration().Seconds(); secs > 0 {
+				s.Size += fmt.Sprintf(" (%.1f MB/s)", float64(e.Bytes)/(1<<20)/secs)
+			}
+		}
+		switch {
+		case e.Error != "":
+			s.Result, s.Failed = e.Error, true
+		case e.End == nil:
+			s.Duration, s.Result = "", i18n.T("未结束")
+		}
+		p.Summary = append(p.Summary, s)
+	}
+	return pageTemplate.Execute(w, p)
+}
+
+// 截断过长的名称
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+var pageTemplate = template.Must(template.New("timeline").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: "Segoe UI", "Microsoft YaHei", sans-serif; margin: 24px; color: #212121; }
+svg text { font-size: 12px; }
+.legend span { display: inline-block; margin-right: 16px; }
+.legend i { display: inline-block; width: 12px; height: 12px; margin-right: 4px; vertical-align: middle; }
+table { border-collapse: collapse; margin-top: 24px; }
+th, td { border-bottom: 1px solid #e0e0e0; padding: 4px 12px; text-align: left; }
+td.failed { color: #e53935; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Overview}}</p>
+<p class="legend">{{range .Legend}}<span><i style="background: {{.Color}}"></i>{{.Text}}</span>{{end}}</p>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}">
+{{- range .Ticks}}
+<line x1="{{.X}}" y1="18" x2="{{.X}}" y2="{{$.ChartBottom}}" stroke="#eeeeee"/>
+<text x="{{.X}}" y="14" text-anchor="middle" fill="#757575">{{.Text}}</text>
+{{- end}}
+{{- range .Rows}}
+<g><title>{{.Title}}</title>
+<text x="{{.LabelX}}" y="{{.Y}}" dy="15">{{.Label}}</text>
+<rect x="{{.X}}" y="{{.Y}}" width="{{.W}}" height="16" rx="2" fill="{{.Color}}"{{if .Open}} fill-opacity="0.4" stroke="{{.Color}}" stroke-dasharray="4 2"{{end}}/>
+<text x="{{.X}}" y="{{.Y}}" dx="{{.W}}" dy="15" fill="#616161"> {{.Text}}</text>
+</g>
+{{- end}}
+{{- range .Markers}}
+<g><title>{{.Title}}</title>
+<line x1="{{.X}}" y1="18" x2="{{.X}}" y2="{{$.ChartBottom}}" stroke="#e53935" stroke-dasharray="3 3"/>
+<path d="M{{.X}} 18 l-5 -7 h10 z" fill="#e53935"/>
+</g>
+{{- end}}
+</svg>
+{{- if .Summary}}
+<table>
+<tr>{{range .Headers}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Summary}}
+<tr><td>{{.Kind}}</td><td>{{.Name}}</td><td>{{.Duration}}</td><td>{{.Size}}</td><td{{if .Failed}} class="failed"{{end}}>{{.Result}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
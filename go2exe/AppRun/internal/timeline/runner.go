@@ -0,0 +1,28 @@
+package timeline
+
+import "go2exe/internal/runner"
+
+// 记录每个外部命令执行时间的 CommandRunner。Start 启动的命令不等待结束，只记录启动的时间点
+type Runner struct {
+	Next     runner.CommandRunner
+	Recorder *Recorder
+}
+
+func (r Runner) Output(c runner.Command) (string, error) {
+	span := r.Recorder.Begin(KindCommand, commandName(c.Name, c.Args))
+	out, err := r.Next.Output(c)
+	span.End(err)
+	return out, err
+}
+
+func (r Runner) Stream(c runner.Command, onLine func(line string, isError bool)) error {
+	span := r.Recorder.Begin(KindCommand, commandName(c.Name, c.Args))
+	err := r.Next.Stream(c, onLine)
+	span.End(err)
+	return err
+}
+
+func (r Runner) Start(c runner.Command) (runner.Process, error) {
+	r.Recorder.Mark(KindCommand, commandName(c.Name, c.Args), "")
+	return r.Next.Start(c)
+}
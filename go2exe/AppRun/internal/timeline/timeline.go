@@ -0,0 +1,231 @@
+// Package timeline 记录一次启动的时间线（安装步骤、外部命令、下载和重试），并生成 HTML/SVG 时间线图，
+// 用来查看首次运行的时间都花在了哪里
+package timeline
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 时间线中记录的种类
+type Kind string
+
+const (
+	KindRun      Kind = "run"      // 整次启动，到应用启动为止
+	KindStep     Kind = "step"     // 安装步骤（Installer.RunStep）
+	KindCommand  Kind = "command"  // 外部命令（uv、安装脚本）
+	KindDownload Kind = "download" // 下载的文件
+	KindRetry    Kind = "retry"    // 步骤失败后重试，只有时间点
+)
+
+// 时间线中的一条记录。开始时写一条没有 End 的记录，结束时再写一条 ID 相同、带 End 的记录，
+// 启动器中途崩溃时也能看到哪一步没有结束
+type Event struct {
+	ID     string     `json:"id"`
+	Kind   Kind       `json:"kind"`
+	Name   string     `json:"name"`
+	Start  time.Time  `json:"start"`
+	End    *time.Time `json:"end,omitempty"`
+	Detail string     `json:"detail,omitempty"`
+	Error  string     `json:"error,omitempty"`
+	Bytes  int64      `json:"bytes,omitempty"`
+}
+
+// 用时，没有结束的记录为 0
+func (e Event) Duration() time.Duration {
+	if e.End == nil {
+		return 0
+	}
+	return e.End.Sub(e.Start)
+}
+
+// 转录文件（JSON Lines）的写入器。多个进程（例如提权的安装进程）可以追加到同一个文件，ID 中带有进程号。
+// 所有方法在 r 为 nil 时什么都不做，调用方不需要判断是否启用了记录
+type Recorder struct {
+	mu     sync.Mutex
+	f      *os.File
+	next   int
+	closed bool
+}
+
+// 打开 path 追加记录，目录不存在时创建
+func Open(path string) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{f: f}, nil
+}
+
+// 转录文件的路径
+func (r *Recorder) Path() string {
+	if r == nil {
+		return ""
+	}
+	return r.f.Name()
+}
+
+// 关闭转录文件，之后的记录不再写入
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return r.f.Close()
+}
+
+func (r *Recorder) write(e Event) {
+	data, _ := json.Marshal(e)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.closed {
+		r.f.Write(append(data, '\n'))
+	}
+}
+
+// 一段正在记录的时间，End 后写入结束时间
+type Span struct {
+	r     *Recorder
+	event Event
+	once  sync.Once
+}
+
+// 开始记录一段时间
+func (r *Recorder) Begin(kind Kind, name string) *Span {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	r.next++
+	id := fmt.Sprintf("%d.%d", os.Getpid(), r.next)
+	r.mu.Unlock()
+	s := &Span{r: r, event: Event{ID: id, Kind: kind, Name: name, Start: time.Now()}}
+	r.write(s.event)
+	return s
+}
+
+// 设置说明，在 End 时写入
+func (s *Span) SetDetail(detail string) {
+	if s != nil {
+		s.event.Detail = detail
+	}
+}
+
+// 设置传输的字节数，在 End 时写入
+func (s *Span) SetBytes(n int64) {
+	if s != nil {
+		s.event.Bytes = n
+	}
+}
+
+// 结束记录，err 不为 nil 时标记为失败。只有第一次调用有效
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		end := time.Now()
+		s.event.End = &end
+		if err != nil {
+			s.event.Error = err.Error()
+		}
+		s.r.write(s.event)
+	})
+}
+
+// 记录一个时间点，例如重试
+func (r *Recorder) Mark(kind Kind, name, detail string) {
+	if r == nil {
+		return
+	}
+	r.Begin(kind, name).mark(detail)
+}
+
+func (s *Span) mark(detail string) {
+	s.event.Detail = detail
+	s.event.End = &s.event.Start
+	s.r.write(s.event)
+}
+
+// 读取转录文件，同一 ID 的记录以最后一条为准，按开始时间排列
+func Load(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	byID := map[string]Event{}
+	var order []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.ID == "" {
+			// 进程被强制结束时最后一行可能不完整
+			continue
+		}
+		if _, ok := byID[e.ID]; !ok {
+			order = append(order, e.ID)
+		}
+		byID[e.ID] = e
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, len(order))
+	for _, id := range order {
+		events = append(events, byID[id])
+	}
+	sort.SliceStable(events, func(a, b int) bool { return events[a].Start.Before(events[b].Start) })
+	return events, nil
+}
+
+// dir 中最近一次有安装活动（步骤、命令或下载）的转录文件，没有时返回空字符串
+func Latest(dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	// 文件名以开始时间命名，按名称倒序即按时间倒序
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	for _, path := range files {
+		events, err := Load(path)
+		if err != nil {
+			continue
+		}
+		if slices.ContainsFunc(events, func(e Event) bool { return e.Kind != KindRun }) {
+			return path
+		}
+	}
+	return ""
+}
+
+// 新的转录文件的路径：dir 下以当前时间命名，并删除 keep 个以外较旧的文件
+func NewPath(dir string, keep int) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	sort.Strings(files)
+	for len(files) >= keep && len(files) > 0 {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+	return filepath.Join(dir, time.Now().Format("20060102-150405.000")+".jsonl")
+}
+
+// 命令在时间线中显示的名称：程序名加参数，过长时截断
+func commandName(name string, args []string) string {
+	s := strings.Join(append([]string{filepath.Base(name)}, args...), " ")
+	if r := []rune(s); len(r) > 80 {
+		s = string(r[:79]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,85 @@
+package timeline
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go2exe/internal/runner"
+)
+
+func TestRecordAndRender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timeline", "run.jsonl")
+	rec, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := rec.Begin(KindRun, "启动")
+	step := rec.Begin(KindStep, "安装 Python")
+	mock := &runner.Mock{}
+	r := Runner{Next: mock, Recorder: rec}
+	if err := r.Stream(runner.Command{Name: "uv", Args: []string{"python", "install"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	rec.Mark(KindRetry, "安装 Python", "connection reset")
+	download := rec.Begin(KindDownload, "model.bin")
+	download.SetBytes(3 << 20)
+	download.End(nil)
+	step.End(errors.New("网络错误"))
+	step.End(nil) // 只有第一次有效
+	rec.Begin(KindStep, "同步依赖")
+	run.End(nil)
+	rec.Close()
+
+	events, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 6 {
+		t.Fatalf("读取到 %d 条记录，应为 6 条: %+v", len(events), events)
+	}
+	if events[1].Name != "安装 Python" || events[1].Error != "网络错误" || events[1].End == nil {
+		t.Errorf("步骤记录 = %+v", events[1])
+	}
+	if events[2].Kind != KindCommand || events[2].Name != "uv python install" {
+		t.Errorf("命令记录 = %+v", events[2])
+	}
+	if events[5].End != nil {
+		t.Errorf("没有结束的步骤不应有结束时间: %+v", events[5])
+	}
+
+	var b strings.Builder
+	if err := Render(&b, events); err != nil {
+		t.Fatal(err)
+	}
+	html := b.String()
+	for _, want := range []string{"<svg", "uv python install", "connection reset", "3.0 MB", failedColor, "stroke-dasharray=\"4 2\""} {
+		if !strings.Contains(html, want) {
+			t.Errorf("时间线中缺少 %q", want)
+		}
+	}
+}
+
+func TestLatestAndNewPath(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("20260101-100000.000.jsonl", `{"id":"1.1","kind":"run","name":"启动","start":"2026-01-01T10:00:00Z"}`+"\n"+
+		`{"id":"1.2","kind":"step","name":"安装 UV","start":"2026-01-01T10:00:01Z"}`+"\n")
+	// 只有启动记录（已安装，直接启动应用）的转录不算
+	write("20260102-100000.000.jsonl", `{"id":"2.1","kind":"run","name":"启动","start":"2026-01-02T10:00:00Z"}`+"\n"+`{"id":"2.1","kind":`)
+	if got := Latest(dir); filepath.Base(got) != "20260101-100000.000.jsonl" {
+		t.Errorf("Latest() = %q", got)
+	}
+
+	NewPath(dir, 2)
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if len(files) != 1 || filepath.Base(files[0]) != "20260102-100000.000.jsonl" {
+		t.Errorf("NewPath 应只保留最新的 1 个旧文件，实际为 %v", files)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package ui
+
+// 非 Windows 系统没有窗口，按 Ctrl+C 取消。返回停止响应 Ctrl+C 的函数
+func ShowCancelButton(title string, onCancel func()) (close func()) {
+	return textCancel(onCancel)
+}
@@ -0,0 +1,261 @@
+package ui
+
+import (
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/progress"
+)
+
+var (
+	createWindowEx   = user32.NewProc("CreateWindowExW")
+	defWindowProc    = user32.NewProc("DefWindowProcW")
+	registerClassEx  = user32.NewProc("RegisterClassExW")
+	destroyWindow    = user32.NewProc("DestroyWindow")
+	postMessage      = user32.NewProc("PostMessageW")
+	postQuitMessage  = user32.NewProc("PostQuitMessage")
+	getMessage       = user32.NewProc("GetMessageW")
+	translateMessage = user32.NewProc("TranslateMessage")
+	dispatchMessage  = user32.NewProc("DispatchMessageW")
+	enableWindow     = user32.NewProc("EnableWindow")
+	getDlgItem       = user32.NewProc("GetDlgItem")
+	getSystemMetrics = user32.NewProc("GetSystemMetrics")
+	getModuleHandle  = kernel32.NewProc("GetModuleHandleW")
+	comctl32         = syscall.NewLazyDLL("comctl32.dll")
+	initCommonCtrls  = comctl32.NewProc("InitCommonControlsEx")
+	WS_CAPTION       = 0x00C00000
+	WS_VISIBLE       = 0x10000000
+	WS_CHILD         = 0x40000000
+	WS_EX_TOPMOST    = 0x00000008
+	WS_EX_TOOLWINDOW = 0x00000080
+	WM_DESTROY       = 0x0002
+	WM_CLOSE         = 0x0010
+	WM_COMMAND       = 0x0111
+	WM_APP           = 0x8000
+	COLOR_BTNFACE    = 15
+	SM_CXSCREEN      = 0
+	SM_CYSCREEN      = 1
+	ICC_PROGRESS     = 0x00000020
+	PBM_SETPOS       = 0x0402
+)
+
+// 取消窗口中控件的 ID
+const (
+	cancelButtonID = 1
+	cancelBarID    = 2
+	cancelLabelID  = 3
+)
+
+// 其他线程通知取消窗口有新的进度
+const cancelProgressMsg = 0x8001 // WM_APP+1
+
+// 窗口的尺寸
+const (
+	cancelWindowWidth  = 320
+	cancelWindowHeight = 150
+)
+
+// 进度条控件（msctls_progress32）所在的 comctl32 需要先初始化
+var initProgressClass sync.Once
+
+func initProgressControl() {
+	initProgressClass.Do(func() {
+		icc := struct{ size, classes uint32 }{8, uint32(ICC_PROGRESS)}
+		initCommonCtrls.Call(uintptr(unsafe.Pointer(&icc)))
+	})
+}
+
+type wndClassEx struct {
+	cbSize        uint32
+	style         uint32
+	lpfnWndProc   uintptr
+	cbClsExtra    int32
+	cbWndExtra    int32
+	hInstance     syscall.Handle
+	hIcon         syscall.Handle
+	hCursor       syscall.Handle
+	hbrBackground syscall.Handle
+	lpszMenuName  *uint16
+	lpszClassName *uint16
+	hIconSm       syscall.Handle
+}
+
+type winMsg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	ptX     int32
+	ptY     int32
+}
+
+var (
+	registerCancelClass sync.Once
+	cancelClassName, _  = syscall.UTF16PtrFromString("SpeakMyBookCancel")
+	cancelWndProc       = syscall.NewCallback(cancelWindowProc)
+
+	// 同一时间只显示一个取消窗口
+	cancelMu       sync.Mutex
+	cancelCallback func()
+	cancelHwnd     uintptr
+	cancelStatus   progress.Status // 等待窗口线程显示的进度
+)
+
+// 显示置顶的小窗口，包含安装进度条和一个“取消”按钮，点击按钮后调用 onCancel。
+// 返回关闭窗口的函数，可以在任意 goroutine 中调用。使用文本界面或无法创建窗口时改为按 Ctrl+C 取消
+func ShowCancelButton(title string, onCancel func()) (close func()) {
+	if TextMode() {
+		return textCancel(onCancel)
+	}
+	cancelMu.Lock()
+	cancelCallback = onCancel
+	cancelMu.Unlock()
+
+	created := make(chan uintptr)
+	go func() {
+		// 窗口的消息必须由创建它的线程处理
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		hInstance, _, _ := getModuleHandle.Call(0)
+		initProgressControl()
+		registerCancelClass.Do(func() {
+			wc := wndClassEx{
+				lpfnWndProc:   cancelWndProc,
+				hInstance:     syscall.Handle(hInstance),
+				hbrBackground: syscall.Handle(COLOR_BTNFACE + 1),
+				lpszClassName: cancelClassName,
+			}
+			wc.cbSize = uint32(unsafe.Sizeof(wc))
+			registerClassEx.Call(uintptr(unsafe.Pointer(&wc)))
+		})
+
+		// 放在屏幕右下角，不挡住安装进度
+		cx, _, _ := getSystemMetrics.Call(uintptr(SM_CXSCREEN))
+		cy, _, _ := getSystemMetrics.Call(uintptr(SM_CYSCREEN))
+		titlePtr, _ := syscall.UTF16PtrFromString(title)
+		hwnd, _, _ := createWindowEx.Call(
+			uintptr(WS_EX_TOPMOST|WS_EX_TOOLWINDOW),
+			uintptr(unsafe.Pointer(cancelClassName)),
+			uintptr(unsafe.Pointer(titlePtr)),
+			uintptr(WS_CAPTION|WS_VISIBLE),
+			cx-cancelWindowWidth-40, cy-cancelWindowHeight-80, cancelWindowWidth, cancelWindowHeight,
+			0, 0, hInstance, 0,
+		)
+		if hwnd != 0 {
+			font, _, _ := getStockObject.Call(uintptr(DEFAULT_GUI_FONT))
+			add := func(id int, class, text string, x, y, width, height int) {
+				classPtr, _ := syscall.UTF16PtrFromString(class)
+				textPtr, _ := syscall.UTF16PtrFromString(text)
+				h, _, _ := createWindowEx.Call(
+					0,
+					uintptr(unsafe.Pointer(classPtr)),
+					uintptr(unsafe.Pointer(textPtr)),
+					uintptr(WS_CHILD|WS_VISIBLE),
+					uintptr(x), uintptr(y), uintptr(width), uintptr(height),
+					hwnd, uintptr(id), hInstance, 0,
+				)
+				sendMessage.Call(h, uintptr(WM_SETFONT), font, 0)
+			}
+			add(cancelBarID, "msctls_progress32", "", 15, 12, 275, 16)
+			add(cancelLabelID, "STATIC", "", 15, 34, 275, 18)
+			add(cancelButtonID, "BUTTON", i18n.T("取消"), 100, 60, 110, 30)
+			cancelMu.Lock()
+			cancelHwnd = hwnd
+			cancelMu.Unlock()
+		}
+		created <- hwnd
+		if hwnd == 0 {
+			return
+		}
+
+		var msg winMsg
+		for {
+			r, _, _ := getMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(r) <= 0 {
+				break
+			}
+			translateMessage.Call(uintptr(unsafe.Pointer(&msg)))
+			dispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
+		}
+	}()
+
+	hwnd := <-created
+	if hwnd == 0 {
+		UseTextMode("无法创建取消窗口")
+		return textCancel(onCancel)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if hwnd != 0 {
+				postMessage.Call(hwnd, uintptr(WM_APP), 0, 0)
+			}
+		})
+	}
+}
+
+// 在取消窗口中显示进度，可以在任意 goroutine 中调用；窗口未打开时忽略
+func setCancelProgress(s progress.Status) {
+	cancelMu.Lock()
+	defer cancelMu.Unlock()
+	cancelStatus = s
+	if cancelHwnd != 0 {
+		postMessage.Call(cancelHwnd, cancelProgressMsg, 0, 0)
+	}
+}
+
+// 把最新的进度显示到进度条和说明文字
+func showProgress(bar, label uintptr, s progress.Status) {
+	pos := s.Percent
+	if pos < 0 {
+		pos = 0
+	}
+	sendMessage.Call(bar, uintptr(PBM_SETPOS), uintptr(pos), 0)
+	textPtr, _ := syscall.UTF16PtrFromString(ProgressLabel(s))
+	setWindowText.Call(label, uintptr(unsafe.Pointer(textPtr)))
+}
+
+func cancelWindowProc(hwnd, msg, wParam, lParam uintptr) uintptr {
+	switch int(msg) {
+	case cancelProgressMsg:
+		cancelMu.Lock()
+		s := cancelStatus
+		cancelMu.Unlock()
+		bar, _, _ := getDlgItem.Call(hwnd, cancelBarID)
+		label, _, _ := getDlgItem.Call(hwnd, cancelLabelID)
+		showProgress(bar, label, s)
+		return 0
+	case WM_COMMAND:
+		if wParam&0xffff == cancelButtonID {
+			cancelMu.Lock()
+			onCancel := cancelCallback
+			cancelMu.Unlock()
+			if onCancel != nil {
+				// 回调期间禁用窗口，避免重复点击
+				enableWindow.Call(hwnd, 0)
+				onCancel()
+				enableWindow.Call(hwnd, 1)
+			}
+		}
+		return 0
+	case WM_CLOSE:
+		// 没有关闭按钮，忽略 Alt+F4
+		return 0
+	case WM_APP:
+		destroyWindow.Call(hwnd)
+		return 0
+	case WM_DESTROY:
+		cancelMu.Lock()
+		cancelHwnd = 0
+		cancelMu.Unlock()
+		postQuitMessage.Call(0)
+		return 0
+	}
+	r, _, _ := defWindowProc.Call(hwnd, msg, wParam, lParam)
+	return r
+}
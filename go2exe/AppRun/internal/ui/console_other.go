@@ -0,0 +1,85 @@
+//go:build !windows
+
+package ui
+
+import (
+	"sync"
+
+	"go2exe/internal/progress"
+)
+
+// 安装进度输出。非 Windows 系统没有控制台窗口，输出写到启动器所在的终端，按 Ctrl+C 取消
+type Console struct {
+	// 设置后打开控制台时响应 Ctrl+C，按下时调用
+	OnCancel func()
+
+	title       string
+	mu          sync.Mutex
+	lines       []string
+	closeCancel func()
+	view        Output // 代替终端显示输出的界面
+}
+
+// 创建控制台
+func NewConsole(title string) *Console {
+	return &Console{title: title}
+}
+
+// 把输出显示在 view 中，不再写到终端；view 为 nil 时恢复使用终端
+func (c *Console) UseView(view Output) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.view = view
+}
+
+// 开始响应 Ctrl+C
+func (c *Console) Open() {
+	c.mu.Lock()
+	hasView := c.view != nil
+	c.mu.Unlock()
+	if !hasView && c.OnCancel != nil && c.closeCancel == nil {
+		c.closeCancel = ShowCancelButton(c.title, c.OnCancel)
+	}
+}
+
+// 停止响应 Ctrl+C，不再向 view 输出
+func (c *Console) Close() {
+	c.UseView(nil)
+	if c.closeCancel != nil {
+		c.closeCancel()
+		c.closeCancel = nil
+	}
+}
+
+// 添加输出文本
+func (c *Console) Line(text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines = append(c.lines, text)
+	if c.view != nil {
+		c.view.Line(text)
+		return
+	}
+	textLine(text)
+}
+
+// 更新进度：有 view 时交给 view，否则在终端中显示进度条
+func (c *Console) Progress(s progress.Status) {
+	c.mu.Lock()
+	view := c.view
+	c.mu.Unlock()
+	if p, ok := view.(ProgressOutput); ok {
+		p.Progress(s)
+		return
+	}
+	if view == nil {
+		textProgress(s)
+	}
+}
+
+// 已输出的所有文本
+func (c *Console) Lines() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
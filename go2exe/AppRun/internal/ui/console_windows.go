@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/progress"
+)
+
+var (
+	getStdHandle      = kernel32.NewProc("GetStdHandle")
+	allocConsole      = kernel32.NewProc("AllocConsole")
+	freeConsole       = kernel32.NewProc("FreeConsole")
+	writeConsole      = kernel32.NewProc("WriteConsoleW")
+	setConsoleTitle   = kernel32.NewProc("SetConsoleTitleW")
+	STD_OUTPUT_HANDLE = -11
+)
+
+// 安装进度控制台窗口，未打开时输出只保留在内存中
+type Console struct {
+	// 设置后打开控制台时同时显示“取消”按钮，点击时调用
+	OnCancel func()
+
+	title       string
+	mu          sync.Mutex
+	lines       []string
+	closeCancel func()
+	view        Output // 代替控制台窗口显示输出的界面
+}
+
+// 创建控制台，窗口在 Open 时才显示
+func NewConsole(title string) *Console {
+	return &Console{title: title}
+}
+
+// 把输出显示在 view（例如首次运行向导）中，不再打开控制台窗口；view 为 nil 时恢复使用控制台
+func (c *Console) UseView(view Output) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.view = view
+}
+
+// 初始化控制台窗口。使用文本界面时输出到启动器所在的控制台，按 Ctrl+C 取消
+func (c *Console) Open() {
+	c.mu.Lock()
+	hasView := c.view != nil
+	c.mu.Unlock()
+	if hasView {
+		return
+	}
+	if TextMode() {
+		if c.OnCancel != nil && c.closeCancel == nil {
+			c.closeCancel = ShowCancelButton(i18n.T(c.title), c.OnCancel)
+		}
+		return
+	}
+	allocConsole.Call()
+	titlePtr, _ := syscall.UTF16PtrFromString(i18n.T(c.title))
+	setConsoleTitle.Call(uintptr(unsafe.Pointer(titlePtr)))
+	if c.OnCancel != nil && c.closeCancel == nil {
+		c.closeCancel = ShowCancelButton(i18n.T(c.title), c.OnCancel)
+	}
+}
+
+// 关闭控制台窗口，不再向 view 输出
+func (c *Console) Close() {
+	c.UseView(nil)
+	if c.closeCancel != nil {
+		c.closeCancel()
+		c.closeCancel = nil
+	}
+	if !TextMode() {
+		freeConsole.Call()
+	}
+}
+
+// 添加输出文本
+func (c *Console) Line(text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines = append(c.lines, text)
+	if c.view != nil {
+		c.view.Line(text)
+		return
+	}
+	if TextMode() {
+		textLine(text)
+		return
+	}
+	writeToConsole(text)
+}
+
+// 更新进度：有 view 时交给 view，否则显示在“取消”按钮窗口中
+func (c *Console) Progress(s progress.Status) {
+	c.mu.Lock()
+	view := c.view
+	c.mu.Unlock()
+	if p, ok := view.(ProgressOutput); ok {
+		p.Progress(s)
+		return
+	}
+	switch {
+	case view != nil:
+	case TextMode():
+		textProgress(s)
+	default:
+		setCancelProgress(s)
+	}
+}
+
+// 已输出的所有文本
+func (c *Console) Lines() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
+
+// 写入控制台
+func writeToConsole(text string) {
+	handle, _, _ := getStdHandle.Call(uintptr(STD_OUTPUT_HANDLE))
+	textPtr, _ := syscall.UTF16FromString(text + "\r\n")
+	var written uint32
+	writeConsole.Call(
+		handle,
+		uintptr(unsafe.Pointer(&textPtr[0])),
+		uintptr(len(textPtr)),
+		uintptr(unsafe.Pointer(&written)),
+		0,
+	)
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"go2exe/internal/i18n"
+)
+
+// 用 osascript 显示系统对话框代替只写日志，由 macOS 版启动器调用。
+// 测试和开发机上不调用，不会弹出对话框
+func UseNativeDialogs() {
+	nativeBox = osascriptBox
+}
+
+// AppleScript 字符串字面量
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// 用 display dialog 显示消息，按钮从左到右为 choices 的倒序，第一个选项是默认按钮（最右侧）
+func osascriptBox(title, message string, choices []textChoice, fallback int, warning bool) int {
+	log.Printf("消息框: %s: %s", title, message)
+	if len(choices) == 0 {
+		choices = []textChoice{{"O", i18n.T("好")}}
+	}
+	var buttons []string
+	for n := len(choices) - 1; n >= 0; n-- {
+		buttons = append(buttons, appleScriptString(choices[n].label))
+	}
+	script := "display dialog " + appleScriptString(message) +
+		" with title " + appleScriptString(title) +
+		" buttons {" + strings.Join(buttons, ", ") + "}" +
+		" default button " + strconv.Itoa(len(buttons))
+	if warning {
+		script += " with icon caution"
+	}
+	out, err := exec.Command("osascript", "-e", script).Output()
+	if err != nil {
+		log.Printf("无法显示对话框: %v", err)
+		return fallback
+	}
+	label := strings.TrimPrefix(strings.TrimSpace(string(out)), "button returned:")
+	for n, c := range choices {
+		if c.label == label {
+			log.Printf("选择: %s", label)
+			return n
+		}
+	}
+	return fallback
+}
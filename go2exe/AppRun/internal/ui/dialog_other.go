@@ -0,0 +1,80 @@
+//go:build !windows
+
+package ui
+
+import "log"
+
+// 没有 Win32 消息框。调用 UseNativeDialogs 后（macOS 版启动器）显示系统对话框，使用文本界面时在控制台中询问，
+// 都没有时消息只写入日志，需要用户选择时由 Answer 决定，未设置时选择“取消”或“否”
+var Answer func(title, message string) bool
+
+// 系统对话框：显示消息和 choices 中的按钮（没有选项时只有“好”），返回选中的序号，无法显示时返回 fallback
+var nativeBox func(title, message string, choices []textChoice, fallback int, warning bool) int
+
+const (
+	IDCANCEL = 2
+	IDYES    = 6
+	IDNO     = 7
+)
+
+func answer(title, message string) bool {
+	if TextMode() {
+		return textBox(title, message, yesNoChoices(), 1) == 0
+	}
+	if nativeBox != nil {
+		return nativeBox(title, message, yesNoChoices(), 1, false) == 0
+	}
+	log.Printf("消息框: %s: %s", title, message)
+	return Answer != nil && Answer(title, message)
+}
+
+// 显示消息框
+func MessageBox(title, message string) {
+	showMessage(title, message, false)
+}
+
+// 显示只有“好”按钮的消息框，warning 为 true 时使用警告图标
+func showMessage(title, message string, warning bool) {
+	switch {
+	case TextMode():
+		textBox(title, message, nil, 0)
+	case nativeBox != nil:
+		nativeBox(title, message, nil, 0, warning)
+	default:
+		log.Printf("消息框: %s: %s", title, message)
+	}
+}
+
+// 显示警告消息框
+func ErrorBox(title, message string) {
+	showMessage(title, message, true)
+}
+
+// 显示“是/否”确认框，用户选择“是”时返回 true
+func ConfirmBox(title, message string) bool {
+	return answer(title, message)
+}
+
+// 显示“重试/取消”警告框，用户选择“重试”时返回 true
+func RetryBox(title, message string) bool {
+	if nativeBox != nil && !TextMode() {
+		return nativeBox(title, message, retryCancelChoices(), 1, true) == 0
+	}
+	return answer(title, message)
+}
+
+// 显示“是/否/取消”警告框，返回 IDYES、IDNO 或 IDCANCEL；只写日志时没有 IDNO
+func YesNoCancelBox(title, message string) int {
+	if nativeBox != nil && !TextMode() {
+		return []int{IDYES, IDNO, IDCANCEL}[nativeBox(title, message, yesNoCancelChoices(), 2, true)]
+	}
+	if answer(title, message) {
+		return IDYES
+	}
+	return IDCANCEL
+}
+
+// 显示“是/否/取消”提问框，返回 IDYES 或 IDCANCEL
+func AskBox(title, message string) int {
+	return YesNoCancelBox(title, message)
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package ui
+
+import "testing"
+
+func TestNativeBox(t *testing.T) {
+	var got []string
+	answerWith := -1
+	nativeBox = func(title, message string, choices []textChoice, fallback int, warning bool) int {
+		var labels string
+		for _, c := range choices {
+			labels += c.label + "/"
+		}
+		got = append(got, labels)
+		if answerWith < 0 {
+			return fallback
+		}
+		return answerWith
+	}
+	t.Cleanup(func() { nativeBox = nil })
+
+	MessageBox("完成", "安装完成")
+	if ConfirmBox("确认", "继续吗？") {
+		t.Error("对话框无法显示时 ConfirmBox() = true")
+	}
+	answerWith = 1
+	if n := YesNoCancelBox("关闭应用", "要关闭正在运行的应用吗？"); n != IDNO {
+		t.Errorf("选择“否”时 YesNoCancelBox() = %d, want IDNO", n)
+	}
+	want := []string{"", "是/否/", "是/否/取消/"}
+	if len(got) != len(want) {
+		t.Fatalf("显示的对话框 = %q", got)
+	}
+	for n := range want {
+		if got[n] != want[n] {
+			t.Errorf("第 %d 个对话框的按钮 = %q, want %q", n+1, got[n], want[n])
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// Windows API 函数声明
+var (
+	user32             = syscall.NewLazyDLL("user32.dll")
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	messageBox         = user32.NewProc("MessageBoxW")
+	MB_OK              = 0x00000000
+	MB_YESNOCANCEL     = 0x00000003
+	MB_RETRYCANCEL     = 0x00000005
+	MB_YESNO           = 0x00000004
+	MB_ICONQUESTION    = 0x00000020
+	MB_ICONINFORMATION = 0x00000040
+	MB_ICONEXCLAMATION = 0x00000030
+	IDOK               = 1
+	IDCANCEL           = 2
+	IDRETRY            = 4
+	IDYES              = 6
+	IDNO               = 7
+)
+
+// 显示消息框并返回用户点击的按钮。使用文本界面时在控制台中显示，消息框无法显示时改用文本界面
+func show(title, message string, flags int) int {
+	if TextMode() {
+		return textShow(title, message, flags)
+	}
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+	messagePtr, _ := syscall.UTF16PtrFromString(message)
+	r, _, err := messageBox.Call(
+		0,
+		uintptr(unsafe.Pointer(messagePtr)),
+		uintptr(unsafe.Pointer(titlePtr)),
+		uintptr(flags),
+	)
+	if r == 0 {
+		UseTextMode(fmt.Sprintf("无法显示消息框: %v", err))
+		return textShow(title, message, flags)
+	}
+	return int(r)
+}
+
+// 在文本界面中显示消息框，返回与消息框相同的按钮；无法读取输入时选择“否”或“取消”
+func textShow(title, message string, flags int) int {
+	switch flags & 0x0f {
+	case MB_YESNO:
+		return []int{IDYES, IDNO}[textBox(title, message, yesNoChoices(), 1)]
+	case MB_YESNOCANCEL:
+		return []int{IDYES, IDNO, IDCANCEL}[textBox(title, message, yesNoCancelChoices(), 2)]
+	case MB_RETRYCANCEL:
+		return []int{IDRETRY, IDCANCEL}[textBox(title, message, retryCancelChoices(), 1)]
+	}
+	textBox(title, message, nil, 0)
+	return IDOK
+}
+
+// 显示消息框
+func MessageBox(title, message string) {
+	show(title, message, MB_OK|MB_ICONINFORMATION)
+}
+
+// 显示警告消息框
+func ErrorBox(title, message string) {
+	show(title, message, MB_OK|MB_ICONEXCLAMATION)
+}
+
+// 显示“是/否”确认框，用户选择“是”时返回 true
+func ConfirmBox(title, message string) bool {
+	return show(title, message, MB_YESNO|MB_ICONQUESTION) == IDYES
+}
+
+// 显示“重试/取消”警告框，用户选择“重试”时返回 true
+func RetryBox(title, message string) bool {
+	return show(title, message, MB_RETRYCANCEL|MB_ICONEXCLAMATION) == IDRETRY
+}
+
+// 显示“是/否/取消”警告框，返回 IDYES、IDNO 或 IDCANCEL
+func YesNoCancelBox(title, message string) int {
+	return show(title, message, MB_YESNOCANCEL|MB_ICONEXCLAMATION)
+}
+
+// 显示“是/否/取消”提问框，返回 IDYES、IDNO 或 IDCANCEL
+func AskBox(title, message string) int {
+	return show(title, message, MB_YESNOCANCEL|MB_ICONQUESTION)
+}
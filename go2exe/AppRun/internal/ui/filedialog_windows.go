@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"path/filepath"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+)
+
+var (
+	comdlg32            = syscall.NewLazyDLL("comdlg32.dll")
+	getSaveFileName     = comdlg32.NewProc("GetSaveFileNameW")
+	getOpenFileName     = comdlg32.NewProc("GetOpenFileNameW")
+	OFN_OVERWRITEPROMPT = 0x00000002
+	OFN_NOCHANGEDIR     = 0x00000008
+	OFN_PATHMUSTEXIST   = 0x00000800
+	OFN_FILEMUSTEXIST   = 0x00001000
+	OFN_EXPLORER        = 0x00080000
+)
+
+// OPENFILENAMEW 结构
+type openFileName struct {
+	structSize    uint32
+	owner         uintptr
+	instance      uintptr
+	filter        *uint16
+	customFilter  *uint16
+	maxCustFilter uint32
+	filterIndex   uint32
+	file          *uint16
+	maxFile       uint32
+	fileTitle     *uint16
+	maxFileTitle  uint32
+	initialDir    *uint16
+	title         *uint16
+	flags         uint32
+	fileOffset    uint16
+	fileExtension uint16
+	defExt        *uint16
+	custData      uintptr
+	hook          uintptr
+	templateName  *uint16
+	reserved      uintptr
+	reservedEx    uint32
+	flagsEx       uint32
+}
+
+// 只显示 zip 文件的过滤器，各项之间以 NUL 分隔，以两个 NUL 结束
+func zipFilter() *uint16 {
+	s := utf16.Encode([]rune("ZIP (*.zip)\x00*.zip\x00\x00"))
+	return &s[0]
+}
+
+// 只显示文本文件的过滤器
+func textFilter() *uint16 {
+	s := utf16.Encode([]rune("Text (*.txt)\x00*.txt\x00\x00"))
+	return &s[0]
+}
+
+// 显示文件对话框，用户取消时返回 false。ext 为默认的扩展名
+func fileDialog(proc *syscall.LazyProc, title, initial string, filter *uint16, ext string, flags int) (string, bool) {
+	if TextMode() {
+		return textFileDialog(title, initial, ext)
+	}
+	buf := make([]uint16, syscall.MAX_LONG_PATH)
+	copy(buf, syscall.StringToUTF16(filepath.Base(initial)))
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+	dirPtr, _ := syscall.UTF16PtrFromString(filepath.Dir(initial))
+	defExt, _ := syscall.UTF16PtrFromString(ext)
+	ofn := openFileName{
+		filter:     filter,
+		file:       &buf[0],
+		maxFile:    uint32(len(buf)),
+		initialDir: dirPtr,
+		title:      titlePtr,
+		flags:      uint32(flags | OFN_EXPLORER | OFN_NOCHANGEDIR | OFN_PATHMUSTEXIST),
+		defExt:     defExt,
+	}
+	ofn.structSize = uint32(unsafe.Sizeof(ofn))
+	if initial == "" {
+		buf[0], ofn.initialDir = 0, nil
+	}
+	r, _, _ := proc.Call(uintptr(unsafe.Pointer(&ofn)))
+	if r == 0 {
+		return "", false
+	}
+	return syscall.UTF16ToString(buf), true
+}
+
+// 显示“另存为”对话框选择要保存的 zip 文件，initial 为默认的完整路径
+func SaveFileDialog(title, initial string) (string, bool) {
+	return fileDialog(getSaveFileName, title, initial, zipFilter(), "zip", OFN_OVERWRITEPROMPT)
+}
+
+// 显示“另存为”对话框选择要保存的文本文件，initial 为默认的完整路径
+func SaveTextDialog(title, initial string) (string, bool) {
+	return fileDialog(getSaveFileName, title, initial, textFilter(), "txt", OFN_OVERWRITEPROMPT)
+}
+
+// 显示“打开”对话框选择已有的 zip 文件
+func OpenFileDialog(title string) (string, bool) {
+	return fileDialog(getOpenFileName, title, "", zipFilter(), "zip", OFN_FILEMUSTEXIST)
+}
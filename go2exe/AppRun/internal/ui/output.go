@@ -0,0 +1,73 @@
+// Package ui 提供启动器使用的消息框、安装进度控制台和首次运行安装向导，无法显示窗口时改为在控制台中显示的文本界面
+package ui
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/progress"
+)
+
+// 进度输出，安装和启动过程中的每一行提示都写到这里
+type Output interface {
+	Line(text string)
+}
+
+// 能显示进度条的输出，命令输出中解析出的进度交给 Progress
+type ProgressOutput interface {
+	Output
+	Progress(s progress.Status)
+}
+
+// 丢弃所有输出
+var Discard Output = discard{}
+
+type discard struct{}
+
+func (discard) Line(string) {}
+
+// 返回处理命令输出的回调：每行加上 INFO/ERROR 前缀后写入日志和 out，
+// out 能显示进度时同时更新进度（uv 把下载进度写到标准错误，两种输出都要解析）
+func CommandOutput(out Output) func(line string, isError bool) {
+	tracker := progress.New()
+	return func(line string, isError bool) {
+		prefix := "INFO: "
+		if isError {
+			prefix = "ERROR: "
+		}
+		log.Println(prefix + line)
+		out.Line(prefix + line)
+		if p, ok := out.(ProgressOutput); ok {
+			if s, changed := tracker.Feed(line); changed {
+				p.Progress(s)
+			}
+		}
+	}
+}
+
+// 进度条旁边显示的文字，例如“3/12 个包  45%  剩余约 1 分 20 秒”
+func ProgressLabel(s progress.Status) string {
+	var parts []string
+	if s.Total > 0 {
+		parts = append(parts, i18n.T("%d/%d 个包", s.Done, s.Total))
+	}
+	if s.Percent >= 0 {
+		parts = append(parts, fmt.Sprintf("%d%%", s.Percent))
+	}
+	if s.ETA > 0 {
+		parts = append(parts, i18n.T("剩余约 %s", formatETA(s.ETA)))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// 把剩余时间格式化为“45 秒”或“2 分 5 秒”
+func formatETA(d time.Duration) string {
+	secs := int(d.Round(time.Second) / time.Second)
+	if secs < 60 {
+		return i18n.T("%d 秒", secs)
+	}
+	return i18n.T("%d 分 %d 秒", secs/60, secs%60)
+}
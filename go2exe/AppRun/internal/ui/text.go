@@ -0,0 +1,309 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/progress"
+)
+
+// 文本界面：无法显示窗口时（会话 0 中运行、显卡驱动损坏、通过 SSH 登录）消息框、进度和安装向导改为在控制台中显示，
+// 需要选择时从标准输入读取，不会停在没有人能看到的消息框上
+var textMode atomic.Bool
+
+// 文本界面的输入和输出，测试时替换
+var (
+	textIn       io.Reader = os.Stdin
+	textOut      io.Writer = os.Stdout
+	textTerminal bool      // 输出是控制台：进度条在同一行中刷新，否则只在进度有明显变化时输出一行
+)
+
+var (
+	textOutMu    sync.Mutex
+	textBarWidth int  // 当前显示的进度条的宽度，0 表示没有显示进度条
+	textLastStep = -1 // 输出被重定向时上次输出的进度所在的 10% 区间
+
+	textInMu     sync.Mutex
+	textReader   *bufio.Reader
+	textInClosed bool // 标准输入已关闭（无人值守），之后的选择都使用默认值
+)
+
+// 改用文本界面，reason 记录在日志中
+func UseTextMode(reason string) {
+	if textMode.Swap(true) {
+		return
+	}
+	log.Printf("无法使用图形界面（%s），改用文本界面", reason)
+	openTextConsole()
+	if f, ok := textOut.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil {
+			textTerminal = fi.Mode()&os.ModeCharDevice != 0
+		}
+	}
+}
+
+// 是否使用文本界面
+func TextMode() bool {
+	return textMode.Load()
+}
+
+// 静默模式：不显示任何窗口，也不在控制台中提示或等待输入，输出只写入日志，需要选择时使用默认值。
+// 由 --silent 开启，在服务、会话 0 等没有人能操作的环境中自动开启，从自动化任务中意外启动时不会一直停在看不到的消息框上
+var silentMode atomic.Bool
+
+// 改用静默模式，reason 记录在日志中
+func UseSilentMode(reason string) {
+	if silentMode.Swap(true) {
+		return
+	}
+	log.Printf("静默模式（%s）：不显示窗口和提示，输出只写入日志", reason)
+	// 沿用文本界面的处理：消息框和向导使用默认选择，进度不再显示
+	textMode.Store(true)
+	textOutMu.Lock()
+	textOut, textTerminal = io.Discard, false
+	textOutMu.Unlock()
+	textInMu.Lock()
+	textInClosed = true
+	textInMu.Unlock()
+}
+
+// 是否使用静默模式
+func SilentMode() bool {
+	return silentMode.Load()
+}
+
+// 字符在控制台中占的列数：中日韩文字和全角符号占两列
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x1100 && (r <= 0x115f || r >= 0x2e80 && r <= 0xa4cf || r >= 0xac00 && r <= 0xd7a3 ||
+			r >= 0xf900 && r <= 0xfaff || r >= 0xfe30 && r <= 0xfe4f || r >= 0xff00 && r <= 0xff60 || r >= 0xffe0 && r <= 0xffe6) {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// 擦除进度条所在的行，调用方持有 textOutMu
+func clearTextBar() {
+	if textBarWidth > 0 {
+		fmt.Fprint(textOut, "\r"+strings.Repeat(" ", textBarWidth)+"\r")
+		textBarWidth = 0
+	}
+}
+
+// 在文本界面中输出一行，进度条显示在最后一行时先擦除它。静默模式下写入日志
+func textLine(text string) {
+	if silentMode.Load() {
+		if text != "" {
+			log.Print(text)
+		}
+		return
+	}
+	textOutMu.Lock()
+	defer textOutMu.Unlock()
+	clearTextBar()
+	fmt.Fprintln(textOut, text)
+}
+
+// 进度条，例如“[########------------] 3/12 个包  45%  剩余约 1 分 20 秒”，百分比未知时只有说明文字
+func textProgressBar(s progress.Status) string {
+	label := ProgressLabel(s)
+	if s.Percent < 0 {
+		return label
+	}
+	const width = 20
+	filled := min(s.Percent, 100) * width / 100
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "] " + label
+}
+
+// 在文本界面中更新进度条
+func textProgress(s progress.Status) {
+	bar := textProgressBar(s)
+	textOutMu.Lock()
+	defer textOutMu.Unlock()
+	if !textTerminal {
+		// 输出被重定向时不能刷新同一行，只在百分比每变化 10% 时输出
+		if s.Percent < 0 || s.Percent/10 == textLastStep {
+			return
+		}
+		textLastStep = s.Percent / 10
+		fmt.Fprintln(textOut, bar)
+		return
+	}
+	clearTextBar()
+	fmt.Fprint(textOut, bar)
+	textBarWidth = displayWidth(bar)
+}
+
+// 读取一行输入，标准输入已关闭时返回 false
+func textReadLine() (string, bool) {
+	textInMu.Lock()
+	defer textInMu.Unlock()
+	if textInClosed {
+		return "", false
+	}
+	if textReader == nil {
+		textReader = bufio.NewReader(textIn)
+	}
+	line, err := textReader.ReadString('\n')
+	if err != nil && line == "" {
+		log.Printf("无法读取输入（%v），使用默认选择", err)
+		textInClosed = true
+		return "", false
+	}
+	return strings.TrimSpace(line), true
+}
+
+// 文本界面中的一个选项：输入 key（不区分大小写）选择
+type textChoice struct {
+	key   string
+	label string
+}
+
+// 显示标题和消息，让用户从 choices 中选择，返回选中的序号；没有选项时只显示消息。
+// 标准输入已关闭时返回 fallback
+func textPrompt(title, message string, choices []textChoice, fallback int) int {
+	textOutMu.Lock()
+	clearTextBar()
+	fmt.Fprintf(textOut, "\n== %s ==\n%s\n", title, message)
+	textOutMu.Unlock()
+	if len(choices) == 0 {
+		return fallback
+	}
+	var options, keys []string
+	for _, c := range choices {
+		options = append(options, fmt.Sprintf("[%s] %s", c.key, c.label))
+		keys = append(keys, c.key)
+	}
+	for {
+		textOutMu.Lock()
+		fmt.Fprint(textOut, strings.Join(options, "  ")+": ")
+		textOutMu.Unlock()
+		line, ok := textReadLine()
+		if !ok {
+			textLine("")
+			return fallback
+		}
+		for n, c := range choices {
+			if strings.EqualFold(line, c.key) || line == c.label {
+				return n
+			}
+		}
+		textLine(i18n.T("请输入 %s 中的一个。", strings.Join(keys, "、")))
+	}
+}
+
+// 标准输入是否已关闭
+func textInputClosed() bool {
+	textInMu.Lock()
+	defer textInMu.Unlock()
+	return textInClosed
+}
+
+// 让用户输入文字，label 以冒号结尾；直接回车或标准输入已关闭时使用 initial
+func textInput(label, initial string) string {
+	textOutMu.Lock()
+	clearTextBar()
+	if initial != "" {
+		fmt.Fprintf(textOut, "%s [%s] ", label, initial)
+	} else {
+		fmt.Fprint(textOut, label+" ")
+	}
+	textOutMu.Unlock()
+	line, ok := textReadLine()
+	if !ok {
+		textLine("")
+	}
+	if !ok || line == "" {
+		return initial
+	}
+	return line
+}
+
+// 文本界面中代替文件对话框：输入完整路径，没有扩展名时加上 ext。直接回车使用 initial，
+// 没有默认路径时直接回车或标准输入已关闭表示取消
+func textFileDialog(title, initial, ext string) (string, bool) {
+	if textInputClosed() {
+		return "", false
+	}
+	path := strings.Trim(textInput(title+i18n.T("（输入完整路径）："), initial), `"`)
+	if path == "" || textInputClosed() && path == initial {
+		return "", false
+	}
+	if filepath.Ext(path) == "" {
+		path += "." + ext
+	}
+	return path, true
+}
+
+// 常用的选项
+func yesNoChoices() []textChoice {
+	return []textChoice{{"Y", i18n.T("是")}, {"N", i18n.T("否")}}
+}
+
+func yesNoCancelChoices() []textChoice {
+	return []textChoice{{"Y", i18n.T("是")}, {"N", i18n.T("否")}, {"C", i18n.T("取消")}}
+}
+
+func retryCancelChoices() []textChoice {
+	return []textChoice{{"R", i18n.T("重试")}, {"C", i18n.T("取消")}}
+}
+
+// 在文本界面中按 Ctrl+C 时调用 onCancel，代替窗口中的“取消”按钮。返回停止响应 Ctrl+C 的函数，静默模式下不响应
+func textCancel(onCancel func()) (stop func()) {
+	if silentMode.Load() {
+		return func() {}
+	}
+	textLine(i18n.T("按 Ctrl+C 取消。"))
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, os.Interrupt)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				onCancel()
+				// 回调中会再次询问是否取消，期间按下的 Ctrl+C 不再处理
+				select {
+				case <-ch:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// 文本界面中的消息框，序号对应 choices
+func textBox(title, message string, choices []textChoice, fallback int) int {
+	if silentMode.Load() {
+		log.Printf("消息框（静默模式）: %s: %s", title, message)
+	} else {
+		log.Printf("消息框（文本界面）: %s: %s", title, message)
+	}
+	n := textPrompt(title, message, choices, fallback)
+	if n >= 0 && n < len(choices) {
+		log.Printf("选择: %s", choices[n].label)
+	}
+	return n
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package ui
+
+// 不检查图形界面是否可用，cmd/apprun-unix 按自己的判断调用 UseTextMode 改用文本界面
+func GUIUnavailable() string {
+	return ""
+}
+
+// 不检查会话是否有人操作，只在调用 UseSilentMode 后使用静默模式
+func NonInteractive() string {
+	return ""
+}
+
+// 直接使用进程的标准输入和输出
+func openTextConsole() {}
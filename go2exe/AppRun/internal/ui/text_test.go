@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"go2exe/internal/progress"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// 使用文本界面，从 input 读取输入，返回输出
+func useTextUI(t *testing.T, input string) *bytes.Buffer {
+	var out bytes.Buffer
+	oldIn, oldOut := textIn, textOut
+	textIn, textOut = strings.NewReader(input), &out
+	textReader, textInClosed, textBarWidth, textLastStep = nil, false, 0, -1
+	textMode.Store(true)
+	t.Cleanup(func() {
+		textIn, textOut = oldIn, oldOut
+		textReader, textInClosed = nil, false
+		textMode.Store(false)
+	})
+	return &out
+}
+
+func TestTextBoxes(t *testing.T) {
+	out := useTextUI(t, "x\ny\n")
+	if !ConfirmBox("确认", "继续吗？") {
+		t.Error("输入 y 后 ConfirmBox() = false")
+	}
+	if s := out.String(); !strings.Contains(s, "== 确认 ==\n继续吗？") || !strings.Contains(s, "请输入 Y、N 中的一个。") {
+		t.Errorf("输出:\n%s", s)
+	}
+	// 标准输入关闭后选择“否”或“取消”，不会一直等待
+	if ConfirmBox("确认", "继续吗？") || RetryBox("重试", "网络错误") {
+		t.Error("无法读取输入时选择了“是”或“重试”")
+	}
+	MessageBox("完成", "安装完成")
+	if !strings.Contains(out.String(), "== 完成 ==\n安装完成") {
+		t.Errorf("MessageBox 没有输出消息:\n%s", out)
+	}
+}
+
+func TestTextFileDialog(t *testing.T) {
+	useTextUI(t, "\"D:\\backup\\report\"\n\n")
+	if path, ok := textFileDialog("保存", "C:\\report.txt", "txt"); !ok || path != "D:\\backup\\report.txt" {
+		t.Errorf("textFileDialog() = %q, %v", path, ok)
+	}
+	if path, ok := textFileDialog("保存", "C:\\report.txt", "txt"); !ok || path != "C:\\report.txt" {
+		t.Errorf("直接回车时 textFileDialog() = %q, %v，want 默认路径", path, ok)
+	}
+	if _, ok := textFileDialog("打开", "", "zip"); ok {
+		t.Error("标准输入关闭后 textFileDialog() 选择了文件")
+	}
+}
+
+func TestTextProgress(t *testing.T) {
+	if got, want := textProgressBar(progress.Status{Percent: 45, Done: 3, Total: 12}), "[#########-----------] 3/12 个包  45%"; got != want {
+		t.Errorf("textProgressBar() = %q, want %q", got, want)
+	}
+	if got := textProgressBar(progress.Status{Percent: -1, Done: 3, Total: 12}); got != "3/12 个包" {
+		t.Errorf("百分比未知时 textProgressBar() = %q", got)
+	}
+
+	// 输出被重定向时每 10% 输出一行
+	out := useTextUI(t, "")
+	textTerminal = false
+	for _, p := range []int{1, 5, 12, 18, 25, 100} {
+		textProgress(progress.Status{Percent: p})
+	}
+	if n := strings.Count(out.String(), "\n"); n != 4 {
+		t.Errorf("输出了 %d 行，want 4:\n%s", n, out)
+	}
+
+	// 控制台中在同一行刷新，输出文字前擦除进度条
+	out.Reset()
+	textTerminal = true
+	defer func() { textTerminal = false }()
+	textProgress(progress.Status{Percent: 50})
+	textLine("安装完成")
+	if s := out.String(); !strings.HasPrefix(s, "[##########") || !strings.HasSuffix(s, "\r安装完成\n") {
+		t.Errorf("输出 = %q", s)
+	}
+}
+
+func TestSilentMode(t *testing.T) {
+	useTextUI(t, "y\n")
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() {
+		log.SetOutput(io.Discard)
+		silentMode.Store(false)
+	})
+
+	UseSilentMode("测试")
+	// 即使标准输入中有回答也不等待输入，直接使用默认选择
+	if ConfirmBox("确认", "继续吗？") {
+		t.Error("静默模式下 ConfirmBox() = true")
+	}
+	if _, ok := textFileDialog("保存", "C:\\report.txt", "txt"); ok {
+		t.Error("静默模式下文件对话框没有取消")
+	}
+	textLine("正在安装uv...")
+	if s := logs.String(); !strings.Contains(s, "消息框（静默模式）: 确认: 继续吗？") || !strings.Contains(s, "正在安装uv...") {
+		t.Errorf("日志:\n%s", s)
+	}
+	if textOut != io.Discard {
+		t.Error("静默模式下仍然输出到控制台")
+	}
+}
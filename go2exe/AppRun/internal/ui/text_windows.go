@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	processIdToSessionId     = kernel32.NewProc("ProcessIdToSessionId")
+	getProcessWindowStation  = user32.NewProc("GetProcessWindowStation")
+	getUserObjectInformation = user32.NewProc("GetUserObjectInformationW")
+	UOI_FLAGS                = 1
+	WSF_VISIBLE              = 0x0001
+)
+
+type userObjectFlags struct {
+	fInherit  int32
+	fReserved int32
+	dwFlags   uint32
+}
+
+// 没有人能操作的原因，可以交互时返回空字符串：服务和计划任务在会话 0 中运行，
+// 窗口站不可见时窗口和消息框都不会出现，也没有控制台可以回答提示
+func NonInteractive() string {
+	var session uint32
+	if r, _, _ := processIdToSessionId.Call(uintptr(os.Getpid()), uintptr(unsafe.Pointer(&session))); r != 0 && session == 0 {
+		return "会话 0"
+	}
+	if winsta, _, _ := getProcessWindowStation.Call(); winsta != 0 {
+		var flags userObjectFlags
+		var needed uint32
+		r, _, _ := getUserObjectInformation.Call(winsta, uintptr(UOI_FLAGS), uintptr(unsafe.Pointer(&flags)), unsafe.Sizeof(flags), uintptr(unsafe.Pointer(&needed)))
+		if r != 0 && flags.dwFlags&uint32(WSF_VISIBLE) == 0 {
+			return "窗口站不可见"
+		}
+	}
+	return ""
+}
+
+// 无法显示窗口的原因，能显示时返回空字符串：通过 SSH 运行时登录的用户看不到桌面上的窗口，但可以在终端中回答提示
+func GUIUnavailable() string {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_CLIENT") != "" {
+		return "通过 SSH 运行"
+	}
+	return ""
+}
+
+// 当前的标准句柄，不可用时返回 nil。进程启动后 AllocConsole 打开的控制台不会反映到 os.Stdout，每次重新获取
+func stdHandle(id int, name string) *os.File {
+	h, err := syscall.GetStdHandle(id)
+	if err != nil || h == 0 || h == syscall.InvalidHandle {
+		return nil
+	}
+	return os.NewFile(uintptr(h), name)
+}
+
+// 启动器是窗口程序，从控制台或 SSH 运行时继承其标准输入输出。
+// 没有标准输入输出时（例如由服务或计划任务启动）没有人能看到提示，也无法回答：
+// 输出只写入日志，需要选择时使用默认值，不能打开一个没有人看得到的控制台等待输入
+func openTextConsole() {
+	textOut, textIn = io.Discard, strings.NewReader("")
+	if f := stdHandle(syscall.STD_OUTPUT_HANDLE, "stdout"); f != nil {
+		textOut = f
+	}
+	if f := stdHandle(syscall.STD_INPUT_HANDLE, "stdin"); f != nil {
+		textIn = f
+	}
+}
@@ -0,0 +1,321 @@
+package ui
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	shellNotifyIcon       = shell32.NewProc("Shell_NotifyIconW")
+	extractIconEx         = shell32.NewProc("ExtractIconExW")
+	loadIcon              = user32.NewProc("LoadIconW")
+	destroyIcon           = user32.NewProc("DestroyIcon")
+	createPopupMenu       = user32.NewProc("CreatePopupMenu")
+	appendMenu            = user32.NewProc("AppendMenuW")
+	trackPopupMenu        = user32.NewProc("TrackPopupMenu")
+	destroyMenu           = user32.NewProc("DestroyMenu")
+	setForegroundWindow   = user32.NewProc("SetForegroundWindow")
+	getCursorPos          = user32.NewProc("GetCursorPos")
+	registerWindowMessage = user32.NewProc("RegisterWindowMessageW")
+	NIM_ADD               = 0x00000000
+	NIM_MODIFY            = 0x00000001
+	NIM_DELETE            = 0x00000002
+	NIF_MESSAGE           = 0x00000001
+	NIF_ICON              = 0x00000002
+	NIF_TIP               = 0x00000004
+	NIF_INFO              = 0x00000010
+	NIIF_INFO             = 0x00000001
+	NIIF_WARNING          = 0x00000002
+	NIN_BALLOONUSERCLICK  = 0x0405
+	MF_STRING             = 0x00000000
+	MF_GRAYED             = 0x00000001
+	MF_SEPARATOR          = 0x00000800
+	TPM_RIGHTBUTTON       = 0x0002
+	TPM_RETURNCMD         = 0x0100
+	WM_NULL               = 0x0000
+	WM_LBUTTONUP          = 0x0202
+	WM_RBUTTONUP          = 0x0205
+	IDI_APPLICATION       = 32512
+	registerTrayClass     sync.Once
+	trayClassName, _      = syscall.UTF16PtrFromString("SpeakMyBookTray")
+	trayWndProc           = syscall.NewCallback(trayWindowProc)
+	trayByHwnd            = map[uintptr]*Tray{}
+	trayByHwndMu          sync.Mutex
+	wmTaskbarCreated      uintptr // 资源管理器重启后广播的消息，注册窗口类时取得
+)
+
+// 托盘图标的回调消息
+const trayCallbackMsg = 0x8002 // WM_APP+2
+
+// 其他线程请求更新提示文字
+const trayTipMsg = 0x8003 // WM_APP+3
+
+// 其他线程请求显示通知
+const trayInfoMsg = 0x8004 // WM_APP+4
+
+// NOTIFYICONDATAW
+type notifyIconData struct {
+	cbSize           uint32
+	hWnd             uintptr
+	uID              uint32
+	uFlags           uint32
+	uCallbackMessage uint32
+	hIcon            uintptr
+	szTip            [128]uint16
+	dwState          uint32
+	dwStateMask      uint32
+	szInfo           [256]uint16
+	uVersion         uint32
+	szInfoTitle      [64]uint16
+	dwInfoFlags      uint32
+	guidItem         [16]byte
+	hBalloonIcon     uintptr
+}
+
+// 托盘菜单中的一项。Label 为空时是分隔线，Action 为 nil 时显示为灰色的说明文字
+type TrayItem struct {
+	Label  string
+	Action func()
+}
+
+// 通知区域中的图标，点击后弹出菜单
+type Tray struct {
+	hwnd  uintptr
+	icon  uintptr
+	items func() []TrayItem
+
+	mu      sync.Mutex
+	tip     string
+	info    trayInfo
+	onClick func() // 用户点击当前通知时执行
+}
+
+// 等待显示的通知
+type trayInfo struct {
+	title, text string
+	warning     bool
+}
+
+// 在通知区域显示图标，图标取自 iconPath（exe 或 ico 文件），取不到时使用系统默认图标。
+// 每次点击图标时调用 items 生成菜单，选中的菜单项在新的 goroutine 中执行。使用文本界面时没有人能看到图标，返回错误
+func ShowTray(tip, iconPath string, items func() []TrayItem) (*Tray, error) {
+	if TextMode() {
+		return nil, fmt.Errorf("使用文本界面，不显示托盘图标")
+	}
+	t := &Tray{tip: tip, items: items}
+	created := make(chan error)
+	go func() {
+		// 窗口的消息必须由创建它的线程处理
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		hInstance, _, _ := getModuleHandle.Call(0)
+		registerTrayClass.Do(func() {
+			wc := wndClassEx{
+				lpfnWndProc:   trayWndProc,
+				hInstance:     syscall.Handle(hInstance),
+				lpszClassName: trayClassName,
+			}
+			wc.cbSize = uint32(unsafe.Sizeof(wc))
+			registerClassEx.Call(uintptr(unsafe.Pointer(&wc)))
+			namePtr, _ := syscall.UTF16PtrFromString("TaskbarCreated")
+			wmTaskbarCreated, _, _ = registerWindowMessage.Call(uintptr(unsafe.Pointer(namePtr)))
+		})
+		// 不可见的窗口，只用于接收图标的消息
+		hwnd, _, err := createWindowEx.Call(0, uintptr(unsafe.Pointer(trayClassName)), 0, 0, 0, 0, 0, 0, 0, 0, hInstance, 0)
+		if hwnd == 0 {
+			created <- fmt.Errorf("创建托盘窗口失败: %v", err)
+			return
+		}
+		t.hwnd = hwnd
+		t.icon = trayIcon(iconPath)
+		trayByHwndMu.Lock()
+		trayByHwnd[hwnd] = t
+		trayByHwndMu.Unlock()
+		if !t.notify(NIM_ADD) {
+			destroyWindow.Call(hwnd)
+			created <- fmt.Errorf("添加托盘图标失败")
+			return
+		}
+		created <- nil
+
+		var msg winMsg
+		for {
+			r, _, _ := getMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(r) <= 0 {
+				break
+			}
+			translateMessage.Call(uintptr(unsafe.Pointer(&msg)))
+			dispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
+		}
+	}()
+	if err := <-created; err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// 加载图标，失败时使用系统默认的应用程序图标
+func trayIcon(path string) uintptr {
+	if path != "" {
+		pathPtr, _ := syscall.UTF16PtrFromString(path)
+		var small uintptr
+		if n, _, _ := extractIconEx.Call(uintptr(unsafe.Pointer(pathPtr)), 0, 0, uintptr(unsafe.Pointer(&small)), 1); n != 0 && small != 0 {
+			return small
+		}
+	}
+	icon, _, _ := loadIcon.Call(0, uintptr(IDI_APPLICATION))
+	return icon
+}
+
+// 添加、更新或删除图标
+func (t *Tray) notify(op int) bool {
+	nid := notifyIconData{
+		hWnd:             t.hwnd,
+		uID:              1,
+		uFlags:           uint32(NIF_MESSAGE | NIF_ICON | NIF_TIP),
+		uCallbackMessage: trayCallbackMsg,
+		hIcon:            t.icon,
+	}
+	nid.cbSize = uint32(unsafe.Sizeof(nid))
+	t.mu.Lock()
+	tip := t.tip
+	t.mu.Unlock()
+	copyTruncated(nid.szTip[:], tip)
+	r, _, _ := shellNotifyIcon.Call(uintptr(op), uintptr(unsafe.Pointer(&nid)))
+	return r != 0
+}
+
+// 显示通知（Windows 10 起显示为系统通知），由窗口线程调用
+func (t *Tray) showInfo() {
+	nid := notifyIconData{
+		hWnd:   t.hwnd,
+		uID:    1,
+		uFlags: uint32(NIF_INFO),
+	}
+	nid.cbSize = uint32(unsafe.Sizeof(nid))
+	t.mu.Lock()
+	info := t.info
+	t.mu.Unlock()
+	copyTruncated(nid.szInfoTitle[:], info.title)
+	copyTruncated(nid.szInfo[:], info.text)
+	nid.dwInfoFlags = uint32(NIIF_INFO)
+	if info.warning {
+		nid.dwInfoFlags = uint32(NIIF_WARNING)
+	}
+	shellNotifyIcon.Call(uintptr(NIM_MODIFY), uintptr(unsafe.Pointer(&nid)))
+}
+
+// 把 s 复制到定长的 UTF-16 缓冲区，过长时截断
+func copyTruncated(dst []uint16, s string) {
+	u, _ := syscall.UTF16FromString(s)
+	if len(u) > len(dst) {
+		u = append(u[:len(dst)-1], 0)
+	}
+	copy(dst, u)
+}
+
+// 在图标旁显示一条通知，用户点击通知时在新的 goroutine 中执行 onClick（可以为 nil）。
+// warning 为 true 时显示警告图标。可以在任意 goroutine 中调用
+func (t *Tray) Notify(title, text string, warning bool, onClick func()) {
+	t.mu.Lock()
+	t.info = trayInfo{title: title, text: text, warning: warning}
+	t.onClick = onClick
+	t.mu.Unlock()
+	postMessage.Call(t.hwnd, trayInfoMsg, 0, 0)
+}
+
+// 更新鼠标悬停时的提示文字，可以在任意 goroutine 中调用
+func (t *Tray) SetTip(tip string) {
+	t.mu.Lock()
+	t.tip = tip
+	t.mu.Unlock()
+	postMessage.Call(t.hwnd, trayTipMsg, 0, 0)
+}
+
+// 移除图标，等图标移除后返回，可以在任意 goroutine 中调用
+func (t *Tray) Close() {
+	sendMessage.Call(t.hwnd, uintptr(WM_APP), 0, 0)
+}
+
+// 在鼠标位置弹出菜单，执行选中的项
+func (t *Tray) showMenu() {
+	items := t.items()
+	menu, _, _ := createPopupMenu.Call()
+	if menu == 0 {
+		return
+	}
+	defer destroyMenu.Call(menu)
+	for i, item := range items {
+		switch {
+		case item.Label == "":
+			appendMenu.Call(menu, uintptr(MF_SEPARATOR), 0, 0)
+		default:
+			flags := MF_STRING
+			if item.Action == nil {
+				flags |= MF_GRAYED
+			}
+			labelPtr, _ := syscall.UTF16PtrFromString(item.Label)
+			appendMenu.Call(menu, uintptr(flags), uintptr(i+1), uintptr(unsafe.Pointer(labelPtr)))
+		}
+	}
+
+	// 先把窗口设为前台，否则点击菜单以外的地方时菜单不会消失
+	var pt struct{ x, y int32 }
+	getCursorPos.Call(uintptr(unsafe.Pointer(&pt)))
+	setForegroundWindow.Call(t.hwnd)
+	id, _, _ := trackPopupMenu.Call(menu, uintptr(TPM_RIGHTBUTTON|TPM_RETURNCMD), uintptr(pt.x), uintptr(pt.y), 0, t.hwnd, 0)
+	postMessage.Call(t.hwnd, uintptr(WM_NULL), 0, 0)
+	if id > 0 && int(id) <= len(items) && items[id-1].Action != nil {
+		go items[id-1].Action()
+	}
+}
+
+func trayWindowProc(hwnd, msg, wParam, lParam uintptr) uintptr {
+	trayByHwndMu.Lock()
+	t := trayByHwnd[hwnd]
+	trayByHwndMu.Unlock()
+	switch {
+	case t == nil:
+	case msg == trayCallbackMsg:
+		switch lParam {
+		case uintptr(WM_RBUTTONUP), uintptr(WM_LBUTTONUP):
+			t.showMenu()
+		case uintptr(NIN_BALLOONUSERCLICK):
+			t.mu.Lock()
+			onClick := t.onClick
+			t.onClick = nil
+			t.mu.Unlock()
+			if onClick != nil {
+				go onClick()
+			}
+		}
+		return 0
+	case msg == trayInfoMsg:
+		t.showInfo()
+		return 0
+	case msg == trayTipMsg:
+		t.notify(NIM_MODIFY)
+		return 0
+	case msg == wmTaskbarCreated && msg != 0:
+		// 资源管理器重启后通知区域是新的，需要重新添加图标
+		t.notify(NIM_ADD)
+		return 0
+	case msg == uintptr(WM_APP):
+		t.notify(NIM_DELETE)
+		destroyIcon.Call(t.icon)
+		destroyWindow.Call(hwnd)
+		return 0
+	case msg == uintptr(WM_DESTROY):
+		trayByHwndMu.Lock()
+		delete(trayByHwnd, hwnd)
+		trayByHwndMu.Unlock()
+		postQuitMessage.Call(0)
+		return 0
+	}
+	r, _, _ := defWindowProc.Call(hwnd, msg, wParam, lParam)
+	return r
+}
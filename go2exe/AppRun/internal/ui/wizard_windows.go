@@ -0,0 +1,617 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/progress"
+)
+
+var (
+	gdi32                    = syscall.NewLazyDLL("gdi32.dll")
+	shell32                  = syscall.NewLazyDLL("shell32.dll")
+	ole32                    = syscall.NewLazyDLL("ole32.dll")
+	showWindow               = user32.NewProc("ShowWindow")
+	setWindowText            = user32.NewProc("SetWindowTextW")
+	getWindowText            = user32.NewProc("GetWindowTextW")
+	getWindowTextLength      = user32.NewProc("GetWindowTextLengthW")
+	sendMessage              = user32.NewProc("SendMessageW")
+	isDialogMessage          = user32.NewProc("IsDialogMessageW")
+	getStockObject           = gdi32.NewProc("GetStockObject")
+	shBrowseForFolder        = shell32.NewProc("SHBrowseForFolderW")
+	shGetPathFromIDList      = shell32.NewProc("SHGetPathFromIDListW")
+	coInitializeEx           = ole32.NewProc("CoInitializeEx")
+	coTaskMemFree            = ole32.NewProc("CoTaskMemFree")
+	WS_SYSMENU               = 0x00080000
+	WS_MINIMIZEBOX           = 0x00020000
+	WS_TABSTOP               = 0x00010000
+	WS_VSCROLL               = 0x00200000
+	WS_EX_CLIENTEDGE         = 0x00000200
+	ES_MULTILINE             = 0x0004
+	ES_AUTOVSCROLL           = 0x0040
+	ES_AUTOHSCROLL           = 0x0080
+	ES_READONLY              = 0x0800
+	BS_DEFPUSHBUTTON         = 0x0001
+	BS_AUTOCHECKBOX          = 0x0003
+	SW_HIDE                  = 0
+	SW_SHOW                  = 5
+	WM_SETFONT               = 0x0030
+	EM_SETSEL                = 0x00B1
+	EM_REPLACESEL            = 0x00C2
+	BM_GETCHECK              = 0x00F0
+	BST_CHECKED              = 1
+	DEFAULT_GUI_FONT         = 17
+	BIF_RETURNONLYFSDIRS     = 0x0001
+	BIF_NEWDIALOGSTYLE       = 0x0040
+	COINIT_APARTMENTTHREADED = 0x2
+	MAX_PATH                 = 260
+)
+
+// 向导的页面
+const (
+	pageWelcome = iota
+	pageLicense
+	pageDirs
+	pageProgress
+	pageFinish
+)
+
+// 向导中控件的 ID
+const (
+	wizHeading = 100 + iota
+	wizBody
+	wizLicense
+	wizAccept
+	wizUVLabel
+	wizUVDir
+	wizUVBrowse
+	wizPythonLabel
+	wizPythonDir
+	wizPythonBrowse
+	wizBar
+	wizETA
+	wizLog
+	wizBack
+	wizNext
+	wizCancel
+	wizSaveReport
+)
+
+// 其他线程通知向导窗口的消息
+const (
+	wizardLineMsg     = 0x8001 // WM_APP+1：有新的进度输出
+	wizardFinishMsg   = 0x8002 // WM_APP+2：安装结束，显示完成页
+	wizardProgressMsg = 0x8003 // WM_APP+3：进度有变化
+)
+
+// 窗口的尺寸
+const (
+	wizardWidth  = 560
+	wizardHeight = 420
+)
+
+// 每一页显示的控件
+var wizardPages = map[int][]int{
+	pageWelcome:  {wizHeading, wizBody, wizNext, wizCancel},
+	pageLicense:  {wizHeading, wizBody, wizLicense, wizAccept, wizBack, wizNext, wizCancel},
+	pageDirs:     {wizHeading, wizBody, wizUVLabel, wizUVDir, wizUVBrowse, wizPythonLabel, wizPythonDir, wizPythonBrowse, wizBack, wizNext, wizCancel},
+	pageProgress: {wizHeading, wizBody, wizBar, wizETA, wizLog, wizCancel},
+	pageFinish:   {wizHeading, wizBody, wizBar, wizETA, wizLog, wizNext},
+}
+
+// 有安装报告时完成页另外显示的控件
+var wizardReportControls = []int{wizSaveReport}
+
+type browseInfo struct {
+	hwndOwner      uintptr
+	pidlRoot       uintptr
+	pszDisplayName *uint16
+	lpszTitle      *uint16
+	ulFlags        uint32
+	lpfn           uintptr
+	lParam         uintptr
+	iImage         int32
+}
+
+// 首次运行的安装向导：欢迎 → 许可协议 → 安装位置 → 安装进度 → 完成。
+// Run 在用户点击“安装”后返回，之后向导显示进度输出和进度条（实现 ProgressOutput），Finish 显示结果并等待用户关闭
+type Wizard struct {
+	Title     string // 应用名称，窗口标题为“<名称> 安装向导”
+	License   string // 许可协议全文，为空时跳过许可协议页
+	UVDir     string // uv 安装目录的默认值，Run 返回后为用户选择的目录
+	PythonDir string // Python 安装目录的默认值，Run 返回后为用户选择的目录
+	OnCancel  func() // 安装过程中点击“取消”或关闭窗口时调用
+
+	hwnd     uintptr
+	controls map[int]uintptr
+	page     int
+	choice   chan bool
+	done     chan struct{}
+
+	mu          sync.Mutex
+	pending     []string
+	status      progress.Status // 等待窗口线程显示的进度
+	finishTitle string
+	finishText  string
+	report      string // 安装报告，显示在完成页的输出框中，可以另存为文本文件
+	reportPath  string // 保存报告时默认的文件路径
+	finishOnce  sync.Once
+
+	textUI     bool   // 在文本界面中运行，见 runText
+	stopCancel func() // 文本界面中停止响应 Ctrl+C
+}
+
+var (
+	registerWizardClass sync.Once
+	wizardClassName, _  = syscall.UTF16PtrFromString("SpeakMyBookWizard")
+	wizardWndProc       = syscall.NewCallback(wizardWindowProc)
+
+	// 同一时间只显示一个向导，窗口过程通过它找到向导
+	activeWizard *Wizard
+)
+
+// 显示向导，等待用户完成欢迎、许可协议和安装位置页。
+// 用户点击“安装”时返回 true，取消或关闭窗口时返回 false 并关闭向导。
+// 使用文本界面或无法创建窗口时在控制台中完成同样的步骤
+func (w *Wizard) Run() (bool, error) {
+	if TextMode() {
+		return w.runText(), nil
+	}
+	w.choice = make(chan bool, 1)
+	w.done = make(chan struct{})
+	created := make(chan bool)
+	go func() {
+		// 窗口的消息必须由创建它的线程处理
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(w.done)
+		// 选择文件夹的对话框需要 COM
+		coInitializeEx.Call(0, uintptr(COINIT_APARTMENTTHREADED))
+
+		activeWizard = w
+		if !w.create() {
+			created <- false
+			return
+		}
+		created <- true
+
+		var msg winMsg
+		for {
+			r, _, _ := getMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(r) <= 0 {
+				break
+			}
+			// 处理 Tab 切换焦点和回车
+			if r, _, _ := isDialogMessage.Call(w.hwnd, uintptr(unsafe.Pointer(&msg))); r != 0 {
+				continue
+			}
+			translateMessage.Call(uintptr(unsafe.Pointer(&msg)))
+			dispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
+		}
+	}()
+	if !<-created {
+		UseTextMode("无法创建安装向导窗口")
+		return w.runText(), nil
+	}
+	return <-w.choice, nil
+}
+
+// 文本界面中的向导：依次显示欢迎、许可协议和安装位置，之后的进度和结果输出到控制台，按 Ctrl+C 取消。
+// 无法读取输入时使用默认的安装位置，但不会替用户接受许可协议
+func (w *Wizard) runText() bool {
+	w.textUI = true
+	textPrompt(i18n.T("%s 安装向导", w.Title), i18n.T("欢迎使用 %s", w.Title)+"\n"+
+		i18n.T("首次运行需要安装 uv 和 Python 运行环境，大约需要 300 MB 磁盘空间和几分钟时间。"), nil, 0)
+	if w.License != "" {
+		choices := []textChoice{{"Y", i18n.T("我接受许可协议")}, {"N", i18n.T("取消")}}
+		if textBox(i18n.T("许可协议"), i18n.T("请阅读以下许可协议，接受后才能继续安装。")+"\n\n"+w.License, choices, 1) != 0 {
+			return false
+		}
+	}
+	textPrompt(i18n.T("安装位置"), i18n.T("选择 uv 和 Python 的安装位置，应用本身仍保留在程序所在目录。"), nil, 0)
+	for {
+		uvDir := strings.TrimSpace(textInput(i18n.T("uv 安装目录："), w.UVDir))
+		pythonDir := strings.TrimSpace(textInput(i18n.T("Python 安装目录："), w.PythonDir))
+		if filepath.IsAbs(uvDir) && filepath.IsAbs(pythonDir) {
+			w.UVDir, w.PythonDir = filepath.Clean(uvDir), filepath.Clean(pythonDir)
+			break
+		}
+		textLine(i18n.T("请选择完整的目录路径，例如 D:\\SpeakMyBook\\python。"))
+		if textInputClosed() {
+			return false
+		}
+	}
+	if textBox(i18n.T("安装"), fmt.Sprintf("uv: %s\nPython: %s", w.UVDir, w.PythonDir), []textChoice{{"Y", i18n.T("安装")}, {"N", i18n.T("取消")}}, 0) != 0 {
+		return false
+	}
+	textPrompt(i18n.T("正在安装"), i18n.T("正在安装运行环境，请稍候..."), nil, 0)
+	if w.OnCancel != nil {
+		w.stopCancel = textCancel(w.OnCancel)
+	}
+	return true
+}
+
+// 在进度页追加一行输出，可以在任意 goroutine 中调用
+func (w *Wizard) Line(text string) {
+	if w.textUI {
+		textLine(text)
+		return
+	}
+	w.mu.Lock()
+	w.pending = append(w.pending, text)
+	w.mu.Unlock()
+	postMessage.Call(w.hwnd, wizardLineMsg, 0, 0)
+}
+
+// 在进度页更新进度条和剩余时间，可以在任意 goroutine 中调用
+func (w *Wizard) Progress(s progress.Status) {
+	if w.textUI {
+		textProgress(s)
+		return
+	}
+	w.mu.Lock()
+	w.status = s
+	w.mu.Unlock()
+	postMessage.Call(w.hwnd, wizardProgressMsg, 0, 0)
+}
+
+// 显示完成页，等待用户点击“完成”后关闭向导。多次调用时只有第一次有效
+func (w *Wizard) Finish(title, message string) {
+	if w.textUI {
+		w.finishOnce.Do(func() { w.finishInText(title, message) })
+		return
+	}
+	w.finishOnce.Do(func() {
+		w.mu.Lock()
+		w.finishTitle, w.finishText = title, message
+		w.mu.Unlock()
+		postMessage.Call(w.hwnd, wizardFinishMsg, 0, 0)
+		<-w.done
+	})
+}
+
+// 显示带安装报告的完成页：输出框中显示报告，并提供“保存报告”按钮，默认保存到 path
+func (w *Wizard) FinishReport(title, message, report, path string) {
+	w.mu.Lock()
+	w.report, w.reportPath = report, path
+	w.mu.Unlock()
+	w.Finish(title, message)
+}
+
+// 在文本界面中显示安装结果，有安装报告时输出报告并询问是否保存
+func (w *Wizard) finishInText(title, message string) {
+	if w.stopCancel != nil {
+		w.stopCancel()
+	}
+	textBox(title, message, nil, 0)
+	w.mu.Lock()
+	report, path := w.report, w.reportPath
+	w.mu.Unlock()
+	if report == "" {
+		return
+	}
+	textLine(report)
+	if textBox(i18n.T("保存安装报告"), i18n.T("把安装报告保存到 %s？", path), yesNoChoices(), 1) != 0 {
+		return
+	}
+	if err := os.WriteFile(path, []byte(crlf(report)+"\r\n"), 0644); err != nil {
+		textLine(i18n.T("保存安装报告失败: %v", err))
+	}
+}
+
+// 创建窗口和所有控件，显示欢迎页
+func (w *Wizard) create() bool {
+	hInstance, _, _ := getModuleHandle.Call(0)
+	initProgressControl()
+	registerWizardClass.Do(func() {
+		wc := wndClassEx{
+			lpfnWndProc:   wizardWndProc,
+			hInstance:     syscall.Handle(hInstance),
+			hbrBackground: syscall.Handle(COLOR_BTNFACE + 1),
+			lpszClassName: wizardClassName,
+		}
+		wc.cbSize = uint32(unsafe.Sizeof(wc))
+		registerClassEx.Call(uintptr(unsafe.Pointer(&wc)))
+	})
+
+	// 放在屏幕中央
+	cx, _, _ := getSystemMetrics.Call(uintptr(SM_CXSCREEN))
+	cy, _, _ := getSystemMetrics.Call(uintptr(SM_CYSCREEN))
+	titlePtr, _ := syscall.UTF16PtrFromString(i18n.T("%s 安装向导", w.Title))
+	w.hwnd, _, _ = createWindowEx.Call(
+		0,
+		uintptr(unsafe.Pointer(wizardClassName)),
+		uintptr(unsafe.Pointer(titlePtr)),
+		uintptr(WS_CAPTION|WS_SYSMENU|WS_MINIMIZEBOX),
+		(cx-wizardWidth)/2, (cy-wizardHeight)/2, wizardWidth, wizardHeight,
+		0, 0, hInstance, 0,
+	)
+	if w.hwnd == 0 {
+		return false
+	}
+
+	font, _, _ := getStockObject.Call(uintptr(DEFAULT_GUI_FONT))
+	w.controls = map[int]uintptr{}
+	add := func(id int, class, text string, style, exStyle, x, y, width, height int) {
+		classPtr, _ := syscall.UTF16PtrFromString(class)
+		textPtr, _ := syscall.UTF16PtrFromString(text)
+		h, _, _ := createWindowEx.Call(
+			uintptr(exStyle),
+			uintptr(unsafe.Pointer(classPtr)),
+			uintptr(unsafe.Pointer(textPtr)),
+			uintptr(WS_CHILD|style),
+			uintptr(x), uintptr(y), uintptr(width), uintptr(height),
+			w.hwnd, uintptr(id), hInstance, 0,
+		)
+		sendMessage.Call(h, uintptr(WM_SETFONT), font, 0)
+		w.controls[id] = h
+	}
+	readOnlyText := ES_MULTILINE | ES_AUTOVSCROLL | ES_READONLY | WS_VSCROLL
+	add(wizHeading, "STATIC", "", 0, 0, 20, 15, 500, 24)
+	add(wizBody, "STATIC", "", 0, 0, 20, 45, 500, 55)
+	add(wizLicense, "EDIT", crlf(w.License), readOnlyText|WS_TABSTOP, WS_EX_CLIENTEDGE, 20, 100, 500, 190)
+	add(wizAccept, "BUTTON", i18n.T("我接受许可协议"), BS_AUTOCHECKBOX|WS_TABSTOP, 0, 20, 298, 500, 24)
+	add(wizUVLabel, "STATIC", i18n.T("uv 安装目录："), 0, 0, 20, 110, 500, 20)
+	add(wizUVDir, "EDIT", w.UVDir, ES_AUTOHSCROLL|WS_TABSTOP, WS_EX_CLIENTEDGE, 20, 132, 400, 24)
+	add(wizUVBrowse, "BUTTON", i18n.T("浏览..."), WS_TABSTOP, 0, 430, 132, 90, 24)
+	add(wizPythonLabel, "STATIC", i18n.T("Python 安装目录："), 0, 0, 20, 172, 500, 20)
+	add(wizPythonDir, "EDIT", w.PythonDir, ES_AUTOHSCROLL|WS_TABSTOP, WS_EX_CLIENTEDGE, 20, 194, 400, 24)
+	add(wizPythonBrowse, "BUTTON", i18n.T("浏览..."), WS_TABSTOP, 0, 430, 194, 90, 24)
+	add(wizBar, "msctls_progress32", "", 0, 0, 20, 100, 500, 16)
+	add(wizETA, "STATIC", "", 0, 0, 20, 120, 500, 18)
+	add(wizLog, "EDIT", "", readOnlyText, WS_EX_CLIENTEDGE, 20, 142, 500, 183)
+	add(wizBack, "BUTTON", i18n.T("< 上一步"), WS_TABSTOP, 0, 230, 340, 90, 28)
+	add(wizNext, "BUTTON", "", BS_DEFPUSHBUTTON|WS_TABSTOP, 0, 330, 340, 90, 28)
+	add(wizCancel, "BUTTON", i18n.T("取消"), WS_TABSTOP, 0, 430, 340, 90, 28)
+	add(wizSaveReport, "BUTTON", i18n.T("保存报告..."), WS_TABSTOP, 0, 20, 340, 110, 28)
+
+	w.showPage(pageWelcome)
+	showWindow.Call(w.hwnd, uintptr(SW_SHOW))
+	return true
+}
+
+// 切换到指定页面：只显示该页的控件，并更新标题、说明和按钮
+func (w *Wizard) showPage(page int) {
+	w.page = page
+	visible := map[int]bool{}
+	for _, id := range wizardPages[page] {
+		visible[id] = true
+	}
+	w.mu.Lock()
+	report := w.report
+	w.mu.Unlock()
+	if page == pageFinish && report != "" {
+		for _, id := range wizardReportControls {
+			visible[id] = true
+		}
+	}
+	for id, h := range w.controls {
+		if visible[id] {
+			showWindow.Call(h, uintptr(SW_SHOW))
+		} else {
+			showWindow.Call(h, uintptr(SW_HIDE))
+		}
+	}
+
+	next := i18n.T("下一步 >")
+	switch page {
+	case pageWelcome:
+		w.setText(wizHeading, i18n.T("欢迎使用 %s", w.Title))
+		w.setText(wizBody, i18n.T("首次运行需要安装 uv 和 Python 运行环境，大约需要 300 MB 磁盘空间和几分钟时间。\n\n点击“下一步”继续。"))
+	case pageLicense:
+		w.setText(wizHeading, i18n.T("许可协议"))
+		w.setText(wizBody, i18n.T("请阅读以下许可协议，接受后才能继续安装。"))
+	case pageDirs:
+		w.setText(wizHeading, i18n.T("安装位置"))
+		w.setText(wizBody, i18n.T("选择 uv 和 Python 的安装位置，应用本身仍保留在程序所在目录。"))
+		next = i18n.T("安装")
+	case pageProgress:
+		w.setText(wizHeading, i18n.T("正在安装"))
+		w.setText(wizBody, i18n.T("正在安装运行环境，请稍候..."))
+	case pageFinish:
+		w.mu.Lock()
+		w.setText(wizHeading, w.finishTitle)
+		w.setText(wizBody, w.finishText)
+		w.mu.Unlock()
+		if report != "" {
+			w.setText(wizLog, report)
+		}
+		next = i18n.T("完成")
+	}
+	w.setText(wizNext, next)
+	w.updateNext()
+}
+
+// 许可协议页未勾选“我接受”时不能继续
+func (w *Wizard) updateNext() {
+	enabled := uintptr(1)
+	if w.page == pageLicense {
+		if r, _, _ := sendMessage.Call(w.controls[wizAccept], uintptr(BM_GETCHECK), 0, 0); int(r) != BST_CHECKED {
+			enabled = 0
+		}
+	}
+	enableWindow.Call(w.controls[wizNext], enabled)
+}
+
+// 点击“下一步”：完成安装位置页时检查目录并开始安装
+func (w *Wizard) next() {
+	switch w.page {
+	case pageWelcome:
+		if w.License != "" {
+			w.showPage(pageLicense)
+		} else {
+			w.showPage(pageDirs)
+		}
+	case pageLicense:
+		w.showPage(pageDirs)
+	case pageDirs:
+		uvDir := strings.TrimSpace(w.text(wizUVDir))
+		pythonDir := strings.TrimSpace(w.text(wizPythonDir))
+		if !filepath.IsAbs(uvDir) || !filepath.IsAbs(pythonDir) {
+			ErrorBox(w.Title, i18n.T("请选择完整的目录路径，例如 D:\\SpeakMyBook\\python。"))
+			return
+		}
+		w.UVDir, w.PythonDir = filepath.Clean(uvDir), filepath.Clean(pythonDir)
+		w.showPage(pageProgress)
+		w.choice <- true
+	case pageFinish:
+		destroyWindow.Call(w.hwnd)
+	}
+}
+
+// 点击“上一步”
+func (w *Wizard) back() {
+	switch {
+	case w.page == pageDirs && w.License != "":
+		w.showPage(pageLicense)
+	case w.page == pageDirs || w.page == pageLicense:
+		w.showPage(pageWelcome)
+	}
+}
+
+// 点击“取消”或关闭窗口：开始安装前直接关闭向导，安装过程中交给 OnCancel
+func (w *Wizard) cancel() {
+	switch w.page {
+	case pageProgress:
+		if w.OnCancel != nil {
+			// 回调期间禁用窗口，避免重复点击
+			enableWindow.Call(w.hwnd, 0)
+			w.OnCancel()
+			enableWindow.Call(w.hwnd, 1)
+		}
+	default:
+		destroyWindow.Call(w.hwnd)
+	}
+}
+
+// 把进度输出追加到输出框末尾
+func (w *Wizard) flushLines() {
+	w.mu.Lock()
+	lines := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+	h := w.controls[wizLog]
+	for _, line := range lines {
+		text, _ := syscall.UTF16PtrFromString(crlf(line) + "\r\n")
+		sendMessage.Call(h, uintptr(EM_SETSEL), ^uintptr(0), ^uintptr(0))
+		sendMessage.Call(h, uintptr(EM_REPLACESEL), 0, uintptr(unsafe.Pointer(text)))
+	}
+}
+
+// 把安装报告另存为文本文件
+func (w *Wizard) saveReport() {
+	w.mu.Lock()
+	report, initial := w.report, w.reportPath
+	w.mu.Unlock()
+	path, ok := SaveTextDialog(i18n.T("保存安装报告"), initial)
+	if !ok {
+		return
+	}
+	if err := os.WriteFile(path, []byte(crlf(report)+"\r\n"), 0644); err != nil {
+		ErrorBox(w.Title, i18n.T("保存安装报告失败: %v", err))
+	}
+}
+
+// 打开选择文件夹的对话框，把选择的目录填入 editID 对应的输入框
+func (w *Wizard) browse(editID int) {
+	title, _ := syscall.UTF16PtrFromString(i18n.T("选择安装目录"))
+	name := make([]uint16, MAX_PATH)
+	bi := browseInfo{
+		hwndOwner:      w.hwnd,
+		pszDisplayName: &name[0],
+		lpszTitle:      title,
+		ulFlags:        uint32(BIF_RETURNONLYFSDIRS | BIF_NEWDIALOGSTYLE),
+	}
+	pidl, _, _ := shBrowseForFolder.Call(uintptr(unsafe.Pointer(&bi)))
+	if pidl == 0 {
+		return
+	}
+	defer coTaskMemFree.Call(pidl)
+	path := make([]uint16, MAX_PATH)
+	if r, _, _ := shGetPathFromIDList.Call(pidl, uintptr(unsafe.Pointer(&path[0]))); r != 0 {
+		w.setText(editID, syscall.UTF16ToString(path))
+	}
+}
+
+func (w *Wizard) setText(id int, text string) {
+	textPtr, _ := syscall.UTF16PtrFromString(crlf(text))
+	setWindowText.Call(w.controls[id], uintptr(unsafe.Pointer(textPtr)))
+}
+
+func (w *Wizard) text(id int) string {
+	h := w.controls[id]
+	n, _, _ := getWindowTextLength.Call(h)
+	buf := make([]uint16, n+1)
+	getWindowText.Call(h, uintptr(unsafe.Pointer(&buf[0])), n+1)
+	return syscall.UTF16ToString(buf)
+}
+
+// 多行文本框只识别 \r\n 换行
+func crlf(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+}
+
+func wizardWindowProc(hwnd, msg, wParam, lParam uintptr) uintptr {
+	w := activeWizard
+	if w == nil || w.hwnd != hwnd {
+		r, _, _ := defWindowProc.Call(hwnd, msg, wParam, lParam)
+		return r
+	}
+	switch int(msg) {
+	case WM_COMMAND:
+		switch int(wParam & 0xffff) {
+		case wizNext:
+			w.next()
+		case wizBack:
+			w.back()
+		case wizCancel:
+			w.cancel()
+		case wizAccept:
+			w.updateNext()
+		case wizUVBrowse:
+			w.browse(wizUVDir)
+		case wizPythonBrowse:
+			w.browse(wizPythonDir)
+		case wizSaveReport:
+			w.saveReport()
+		}
+		return 0
+	case WM_CLOSE:
+		if w.page == pageFinish {
+			destroyWindow.Call(hwnd)
+		} else {
+			w.cancel()
+		}
+		return 0
+	case wizardLineMsg:
+		w.flushLines()
+		return 0
+	case wizardProgressMsg:
+		w.mu.Lock()
+		status := w.status
+		w.mu.Unlock()
+		showProgress(w.controls[wizBar], w.controls[wizETA], status)
+		return 0
+	case wizardFinishMsg:
+		w.flushLines()
+		w.showPage(pageFinish)
+		return 0
+	case WM_DESTROY:
+		// 未点击“安装”就关闭了向导
+		select {
+		case w.choice <- false:
+		default:
+		}
+		postQuitMessage.Call(0)
+		return 0
+	}
+	r, _, _ := defWindowProc.Call(hwnd, msg, wParam, lParam)
+	return r
+}
@@ -0,0 +1,309 @@
+package main
+
+// 启动器与 Python 应用之间的命名管道通信
+//
+// 管道名通过环境变量 SPEAKMYBOOK_IPC_PIPE 传给应用。每条消息是一行 JSON：
+//   {"verb": "activate", "args": []}
+// 应用连接后先发送 {"verb": "hello"}，之后启动器只通过这条连接向应用推送动作
+// （activate、read_selection、open、exit，以及读屏软件开启或关闭时的 screen_reader on/off 等）。同步管道句柄上的读写会互相阻塞，所以应用发给启动器的
+// 通知和其他启动器实例（如右键菜单、快捷方式）的命令一样，单独建立连接发送一条消息，
+// 收到 {"verb": "ok"} 后断开。
+//
+// 应用显示主窗口后发送 {"verb": "ready"}；启动失败时发送 {"verb": "startup_error", "args": [错误信息, 详细信息]}，
+// 启动器据此显示准确的启动结果。
+// 应用打开一本书后发送 {"verb": "book_opened", "args": [路径]}，启动器据此更新跳转列表。
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	createNamedPipe     = kernel32.NewProc("CreateNamedPipeW")
+	connectNamedPipe    = kernel32.NewProc("ConnectNamedPipe")
+	disconnectNamedPipe = kernel32.NewProc("DisconnectNamedPipe")
+)
+
+const (
+	PIPE_ACCESS_DUPLEX         = 0x00000003
+	PIPE_TYPE_BYTE             = 0x00000000
+	PIPE_REJECT_REMOTE_CLIENTS = 0x00000008
+	PIPE_UNLIMITED_INSTANCES   = 255
+	ERROR_PIPE_CONNECTED       = 535
+)
+
+// 应用正在启动、尚未连接时最多排队的消息数和保留时间，超出的旧消息丢弃，避免常驻时无限增长
+const (
+	ipcPendingMax = 16
+	ipcPendingTTL = time.Minute
+)
+
+// IPC 消息
+type ipcMessage struct {
+	Verb    string            `json:"verb"`
+	Args    []string          `json:"args,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+	AppArgs []string          `json:"app_args,omitempty"` // 启动器之间转发的应用参数（命令行中 -- 之后的部分）
+}
+
+// 等待应用连接后发送的消息
+type pendingMessage struct {
+	msg    ipcMessage
+	queued time.Time
+}
+
+// IPC 消息处理函数
+type ipcHandler func(msg ipcMessage) error
+
+var (
+	ipcMutex    sync.Mutex
+	ipcWriteMu  sync.Mutex
+	ipcAppConn  *os.File
+	ipcAppPipe  syscall.Handle
+	ipcPending  []pendingMessage
+	ipcHandlers = map[string]ipcHandler{}
+	ipcRunning  bool // IPC 服务已启动
+)
+
+// 当前用户的管道名，避免多用户会话互相干扰
+func ipcPipeName() string {
+	return `\\.\pipe\SpeakMyBook-` + os.Getenv("USERNAME")
+}
+
+// 注册 IPC 动作处理函数
+func handleIPC(verb string, h ipcHandler) {
+	ipcMutex.Lock()
+	defer ipcMutex.Unlock()
+	ipcHandlers[verb] = h
+}
+
+// 启动命名管道服务，在后台循环接受连接
+func startIPCServer() error {
+	// 先创建第一个实例，确保管道名可用后再返回
+	h, err := newPipeInstance()
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			if err := acceptPipe(h); err != nil {
+				log.Printf("IPC 等待连接失败: %v", err)
+			}
+			h, err = newPipeInstance()
+			if err != nil {
+				log.Printf("IPC 创建管道失败，停止服务: %v", err)
+				return
+			}
+		}
+	}()
+	ipcRunning = true
+	log.Printf("IPC 服务已启动: %s", ipcPipeName())
+	return nil
+}
+
+// 创建一个管道实例
+func newPipeInstance() (syscall.Handle, error) {
+	namePtr, _ := syscall.UTF16PtrFromString(ipcPipeName())
+	h, _, err := createNamedPipe.Call(
+		uintptr(unsafe.Pointer(namePtr)),
+		PIPE_ACCESS_DUPLEX,
+		PIPE_TYPE_BYTE|PIPE_REJECT_REMOTE_CLIENTS,
+		PIPE_UNLIMITED_INSTANCES,
+		4096,
+		4096,
+		0,
+		0,
+	)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, fmt.Errorf("创建命名管道失败: %v", err)
+	}
+	return syscall.Handle(h), nil
+}
+
+// 等待客户端连接，并在后台处理该连接
+func acceptPipe(h syscall.Handle) error {
+	r, _, err := connectNamedPipe.Call(uintptr(h), 0)
+	if r == 0 && err != syscall.Errno(ERROR_PIPE_CONNECTED) {
+		syscall.CloseHandle(h)
+		return err
+	}
+	conn := os.NewFile(uintptr(h), ipcPipeName())
+	go serveIPCConn(conn, h)
+	return nil
+}
+
+// 处理一个连接：应用的 hello 连接会被保留用于推送，其他连接处理完一条命令后关闭
+func serveIPCConn(conn *os.File, h syscall.Handle) {
+	scanner := bufio.NewScanner(conn)
+	var first ipcMessage
+	if !scanner.Scan() || json.Unmarshal(scanner.Bytes(), &first) != nil {
+		log.Printf("IPC 消息格式错误")
+		closePipe(conn, h)
+		return
+	}
+
+	if first.Verb == "hello" {
+		log.Printf("Python 应用已连接 IPC")
+		ipcMutex.Lock()
+		if ipcAppConn != nil {
+			closePipe(ipcAppConn, ipcAppPipe)
+		}
+		ipcAppConn, ipcAppPipe = conn, h
+		pending := ipcPending
+		ipcPending = nil
+		ipcMutex.Unlock()
+		for _, p := range pending {
+			if time.Since(p.queued) > ipcPendingTTL {
+				log.Printf("动作 %s 排队超过 %v，不再发送", p.msg.Verb, ipcPendingTTL)
+				continue
+			}
+			sendMessageToApp(p.msg)
+		}
+		return
+	}
+
+	defer closePipe(conn, h)
+	reply := ipcMessage{Verb: "ok"}
+	if err := dispatchIPC(first); err != nil {
+		log.Printf("处理 IPC 动作 %s 失败: %v", first.Verb, err)
+		reply = ipcMessage{Verb: "error", Args: []string{err.Error()}}
+	}
+	writeIPC(conn, reply)
+}
+
+// 断开并关闭一个管道实例
+func closePipe(conn *os.File, h syscall.Handle) {
+	disconnectNamedPipe.Call(uintptr(h))
+	conn.Close()
+}
+
+// 调用已注册的处理函数
+func dispatchIPC(msg ipcMessage) error {
+	ipcMutex.Lock()
+	h, ok := ipcHandlers[msg.Verb]
+	ipcMutex.Unlock()
+	if !ok {
+		return fmt.Errorf("未知的 IPC 动作: %s", msg.Verb)
+	}
+	return h(msg)
+}
+
+// 写入一条消息
+func writeIPC(conn *os.File, msg ipcMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(append(data, '\n'))
+	return err
+}
+
+// 把动作推送给应用
+func sendToApp(verb string, args ...string) {
+	sendMessageToApp(ipcMessage{Verb: verb, Args: args})
+}
+
+// 把消息推送给应用；应用正在启动、尚未连接时先排队，连接后再发送，应用没有运行时丢弃
+func sendMessageToApp(msg ipcMessage) {
+	verb := msg.Verb
+	ipcMutex.Lock()
+	conn := ipcAppConn
+	if conn == nil {
+		queued := queueForApp(msg)
+		ipcMutex.Unlock()
+		if queued {
+			log.Printf("应用尚未连接，动作 %s 已排队", verb)
+		} else {
+			log.Printf("应用没有运行，丢弃动作 %s", verb)
+		}
+		return
+	}
+	ipcMutex.Unlock()
+	ipcWriteMu.Lock()
+	defer ipcWriteMu.Unlock()
+	if err := writeIPC(conn, msg); err != nil {
+		// 写入失败说明应用已断开，消息重新排队，应用重新连接后再发送；
+		// 期间应用已经重新连接时直接发给新的连接
+		log.Printf("发送动作 %s 给应用失败: %v", verb, err)
+		ipcMutex.Lock()
+		if ipcAppConn == conn {
+			closePipe(ipcAppConn, ipcAppPipe)
+			ipcAppConn = nil
+		}
+		retry := ipcAppConn
+		if retry == nil {
+			queueForApp(msg)
+		}
+		ipcMutex.Unlock()
+		if retry != nil {
+			if err := writeIPC(retry, msg); err != nil {
+				log.Printf("发送动作 %s 给应用失败: %v", verb, err)
+			}
+		}
+	}
+}
+
+// 应用进程在运行时把消息排队，等它连接后发送；丢弃过期的消息，超出 ipcPendingMax 时丢弃最早的。
+// 调用方需持有 ipcMutex
+func queueForApp(msg ipcMessage) bool {
+	if !isAppRunning() {
+		return false
+	}
+	now := time.Now()
+	n := 0
+	for n < len(ipcPending) && now.Sub(ipcPending[n].queued) > ipcPendingTTL {
+		n++
+	}
+	if len(ipcPending)-n >= ipcPendingMax {
+		n = len(ipcPending) - ipcPendingMax + 1
+	}
+	if n > 0 {
+		log.Printf("丢弃 %d 条排队过久或过多的动作", n)
+	}
+	ipcPending = append(ipcPending[n:], pendingMessage{msg: msg, queued: now})
+	return true
+}
+
+// 应用是否已通过 IPC 连接
+func isAppConnected() bool {
+	ipcMutex.Lock()
+	defer ipcMutex.Unlock()
+	return ipcAppConn != nil
+}
+
+// 作为客户端把命令发送给已运行的启动器
+func sendToLauncher(verb string, args ...string) error {
+	return sendMessageToLauncher(ipcMessage{Verb: verb, Args: args})
+}
+
+// 作为客户端把消息发送给已运行的启动器，等待处理结果
+func sendMessageToLauncher(msg ipcMessage) error {
+	conn, err := os.OpenFile(ipcPipeName(), os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := writeIPC(conn, msg); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(conn)
+	if !scanner.Scan() {
+		return fmt.Errorf("启动器未响应")
+	}
+	var reply ipcMessage
+	if err := json.Unmarshal(scanner.Bytes(), &reply); err != nil {
+		return err
+	}
+	if reply.Verb == "error" && len(reply.Args) > 0 {
+		return fmt.Errorf("%s", reply.Args[0])
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"go2exe/internal/i18n"
+)
+
+var (
+	CLSID_DestinationList            = mustGUID("77f10cf0-3db5-4966-b520-b7c54fd35ed6")
+	IID_ICustomDestinationList       = mustGUID("6332debf-87b5-4670-90c0-5e57b408a49e")
+	CLSID_EnumerableObjectCollection = mustGUID("2d3468c1-36a7-43b6-ac24-d3f02fd9607a")
+	IID_IObjectCollection            = mustGUID("5632b1a4-e38a-400a-928a-d4cd63230295")
+	IID_IObjectArray                 = mustGUID("92ca9dcd-5622-4bba-a805-5e9f541bd8c9")
+	CLSID_ShellLink                  = mustGUID("00021401-0000-0000-c000-000000000046")
+	IID_IShellLinkW                  = mustGUID("000214f9-0000-0000-c000-000000000046")
+	IID_IPropertyStore               = mustGUID("886d8eeb-8cf2-4446-8d02-cdba1dbdcf99")
+	PKEY_Title                       = propertyKey{mustGUID("f29f85e0-4ff9-1068-ab91-08002b27b3d9"), 2}
+)
+
+// 最近打开的书最多保留的数量
+const maxRecentBooks = 10
+
+var recentMutex sync.Mutex
+
+// 最近打开的书列表文件
+func recentBooksFile() string {
+	return filepath.Join(dataDir(), "recent.json")
+}
+
+// 读取最近打开的书
+func loadRecentBooks() []string {
+	var books []string
+	data, err := os.ReadFile(recentBooksFile())
+	if err == nil {
+		json.Unmarshal(data, &books)
+	}
+	return books
+}
+
+// 记录一本刚打开的书并刷新跳转列表
+func addRecentBook(exePath, book string) error {
+	recentMutex.Lock()
+	defer recentMutex.Unlock()
+
+	books := []string{book}
+	for _, b := range loadRecentBooks() {
+		if !strings.EqualFold(b, book) && len(books) < maxRecentBooks {
+			books = append(books, b)
+		}
+	}
+	if err := os.MkdirAll(dataDir(), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(books, "", "  ")
+	if err := os.WriteFile(recentBooksFile(), data, 0644); err != nil {
+		return err
+	}
+	return updateJumpList(exePath, books)
+}
+
+// 用最近打开的书重建任务栏跳转列表，每一项都通过启动器打开对应的书
+func updateJumpList(exePath string, books []string) error {
+	// COM 对象必须在同一个 STA 线程上创建和使用
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	coInitializeEx.Call(0, uintptr(COINIT_APARTMENTTHREADED))
+	defer coUninitialize.Call()
+
+	list, err := createInstance(&CLSID_DestinationList, &IID_ICustomDestinationList)
+	if err != nil {
+		return err
+	}
+	defer list.release()
+
+	var maxSlots uint32
+	var removed *comObject
+	if err := list.call(4, uintptr(unsafe.Pointer(&maxSlots)), uintptr(unsafe.Pointer(&IID_IObjectArray)), uintptr(unsafe.Pointer(&removed))); err != nil {
+		return fmt.Errorf("BeginList 失败: %v", err)
+	}
+	// 用户从跳转列表中手动移除的项不能再加回去，否则 AppendCategory 会失败
+	removedArgs := removedLinkArgs(removed)
+	removed.release()
+
+	collection, err := createInstance(&CLSID_EnumerableObjectCollection, &IID_IObjectCollection)
+	if err != nil {
+		list.call(11) // AbortList
+		return err
+	}
+	defer collection.release()
+
+	added := 0
+	for _, book := range books {
+		args := fmt.Sprintf(`"%s"`, book)
+		if removedArgs[args] {
+			continue
+		}
+		link, err := newBookLink(exePath, book, args)
+		if err != nil {
+			log.Printf("创建跳转列表项失败: %v", err)
+			continue
+		}
+		collection.call(5, link.ptr()) // AddObject
+		link.release()
+		added++
+	}
+
+	if added > 0 {
+		category, _ := syscall.UTF16PtrFromString(i18n.T("最近的书"))
+		array, err := collection.queryInterface(&IID_IObjectArray)
+		if err != nil {
+			list.call(11)
+			return err
+		}
+		err = list.call(5, uintptr(unsafe.Pointer(category)), array.ptr()) // AppendCategory
+		array.release()
+		if err != nil {
+			list.call(11)
+			return fmt.Errorf("AppendCategory 失败: %v", err)
+		}
+	}
+	if err := list.call(8); err != nil { // CommitList
+		return fmt.Errorf("CommitList 失败: %v", err)
+	}
+	log.Printf("跳转列表已更新，共 %d 本书", added)
+	return nil
+}
+
+// 创建指向启动器的 IShellLink，标题为书名
+func newBookLink(exePath, book, args string) (*comObject, error) {
+	link, err := createInstance(&CLSID_ShellLink, &IID_IShellLinkW)
+	if err != nil {
+		return nil, err
+	}
+	pathPtr, _ := syscall.UTF16PtrFromString(exePath)
+	argsPtr, _ := syscall.UTF16PtrFromString(args)
+	descPtr, _ := syscall.UTF16PtrFromString(book)
+	link.call(20, uintptr(unsafe.Pointer(pathPtr)))    // SetPath
+	link.call(11, uintptr(unsafe.Pointer(argsPtr)))    // SetArguments
+	link.call(7, uintptr(unsafe.Pointer(descPtr)))     // SetDescription
+	link.call(17, uintptr(unsafe.Pointer(pathPtr)), 0) // SetIconLocation
+
+	store, err := link.queryInterface(&IID_IPropertyStore)
+	if err != nil {
+		link.release()
+		return nil, err
+	}
+	defer store.release()
+	title := strings.TrimSuffix(filepath.Base(book), filepath.Ext(book))
+	pv, buf := stringPropVariant(title)
+	err = store.call(6, uintptr(unsafe.Pointer(&PKEY_Title)), uintptr(unsafe.Pointer(&pv))) // SetValue
+	runtime.KeepAlive(buf)
+	if err == nil {
+		err = store.call(7) // Commit
+	}
+	if err != nil {
+		link.release()
+		return nil, err
+	}
+	return link, nil
+}
+
+// 读取被用户移除的跳转列表项的参数
+func removedLinkArgs(removed *comObject) map[string]bool {
+	result := map[string]bool{}
+	if removed == nil {
+		return result
+	}
+	var count uint32
+	if removed.call(3, uintptr(unsafe.Pointer(&count))) != nil { // GetCount
+		return result
+	}
+	for i := uint32(0); i < count; i++ {
+		var link *comObject
+		if removed.call(4, uintptr(i), uintptr(unsafe.Pointer(&IID_IShellLinkW)), uintptr(unsafe.Pointer(&link))) != nil { // GetAt
+			continue
+		}
+		buf := make([]uint16, syscall.MAX_PATH*2)
+		if link.call(10, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf))) == nil { // GetArguments
+			result[syscall.UTF16ToString(buf)] = true
+		}
+		link.release()
+	}
+	return result
+}
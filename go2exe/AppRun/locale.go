@@ -0,0 +1,111 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	getUserDefaultLocaleName = kernel32.NewProc("GetUserDefaultLocaleName")
+	lcidToLocaleName         = kernel32.NewProc("LCIDToLocaleName")
+	LOCALE_NAME_MAX_LENGTH   = 85
+)
+
+// 常见 Windows 时区对应的 IANA 名称，其他时区只传 Windows 名称和 UTC 偏移
+var ianaTimeZones = map[string]string{
+	"China Standard Time":       "Asia/Shanghai",
+	"Taipei Standard Time":      "Asia/Taipei",
+	"Tokyo Standard Time":       "Asia/Tokyo",
+	"Korea Standard Time":       "Asia/Seoul",
+	"Singapore Standard Time":   "Asia/Singapore",
+	"India Standard Time":       "Asia/Kolkata",
+	"AUS Eastern Standard Time": "Australia/Sydney",
+	"GMT Standard Time":         "Europe/London",
+	"W. Europe Standard Time":   "Europe/Berlin",
+	"Romance Standard Time":     "Europe/Paris",
+	"Eastern Standard Time":     "America/New_York",
+	"Central Standard Time":     "America/Chicago",
+	"Mountain Standard Time":    "America/Denver",
+	"Pacific Standard Time":     "America/Los_Angeles",
+	"UTC":                       "Etc/UTC",
+}
+
+// 传给应用的系统区域设置、时区和朗读语言，让阅读界面和默认语音与系统一致。
+// speechLanguage 为配置的朗读语言，为空或 "auto" 时使用系统默认语音的语言
+func localeEnv(speechLanguage string) []string {
+	locale := userLocale()
+	if speechLanguage == "" || strings.EqualFold(speechLanguage, "auto") {
+		speechLanguage = systemSpeechLanguage()
+		if speechLanguage == "" {
+			speechLanguage = locale
+		}
+	}
+	_, offset := time.Now().Zone()
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	winZone, _ := regGetString(HKEY_LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\TimeZoneInformation`, "TimeZoneKeyName")
+
+	env := []string{
+		"SPEAKMYBOOK_LOCALE=" + locale,
+		"SPEAKMYBOOK_SPEECH_LANG=" + speechLanguage,
+		"SPEAKMYBOOK_TIMEZONE_WINDOWS=" + winZone,
+		"SPEAKMYBOOK_UTC_OFFSET=" + sign + time.Unix(int64(offset), 0).UTC().Format("15:04"),
+	}
+	if zone := ianaTimeZones[winZone]; zone != "" {
+		env = append(env, "SPEAKMYBOOK_TIMEZONE="+zone)
+	}
+	log.Printf("区域设置: %s，朗读语言: %s，时区: %s", locale, speechLanguage, winZone)
+	return env
+}
+
+// 用户的区域设置，例如 "zh-CN"
+func userLocale() string {
+	buf := make([]uint16, LOCALE_NAME_MAX_LENGTH)
+	if n, _, _ := getUserDefaultLocaleName.Call(uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf))); n == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf)
+}
+
+// 系统默认语音（控制面板“语音”中选择的语音）的语言，读取失败时为空
+func systemSpeechLanguage() string {
+	token, err := regGetString(HKEY_CURRENT_USER, `Software\Microsoft\Speech\Voices`, "DefaultTokenId")
+	if err != nil || token == "" {
+		return ""
+	}
+	root := HKEY_LOCAL_MACHINE
+	if path, ok := strings.CutPrefix(token, `HKEY_LOCAL_MACHINE\`); ok {
+		token = path
+	} else if path, ok := strings.CutPrefix(token, `HKEY_CURRENT_USER\`); ok {
+		root, token = HKEY_CURRENT_USER, path
+	}
+	value, err := regGetString(root, token+`\Attributes`, "Language")
+	if err != nil {
+		return ""
+	}
+	lcid, ok := parseLanguageID(value)
+	if !ok {
+		return ""
+	}
+	buf := make([]uint16, LOCALE_NAME_MAX_LENGTH)
+	if n, _, _ := lcidToLocaleName.Call(uintptr(lcid), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 0); n == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf)
+}
+
+// 解析语音属性中的语言，格式为十六进制的 LCID，多个语言用分号分隔时取第一个，例如 "804" 或 "409;9"
+func parseLanguageID(value string) (uint32, bool) {
+	first, _, _ := strings.Cut(value, ";")
+	id, err := strconv.ParseUint(strings.TrimSpace(first), 16, 32)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint32(id), true
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go2exe/internal/logship"
+)
+
+// 应用异常退出时发送的应用日志长度（字节，取日志末尾）
+const appCrashLogTail = 32 << 10
+
+var (
+	// 远程日志发送器，未配置时为 nil
+	shipper *logship.Shipper
+	// 应用的日志文件
+	appLogPath string
+)
+
+// 按配置开始把启动器日志发送到日志收集器，发送前按诊断包的设置去除个人信息
+func startLogShipping(exeDir string, cfg Config) {
+	appLogPath = filepath.Join(exeDir, "python", "app.log")
+	if cfg.Logging.RemoteURL == "" {
+		return
+	}
+	s, err := logship.New(logship.Options{
+		URL:           cfg.Logging.RemoteURL,
+		BatchSize:     cfg.Logging.BatchSize,
+		FlushInterval: time.Duration(cfg.Logging.FlushSeconds) * time.Second,
+		Scrub:         newScrubber(cfg.Diagnostics).Scrub,
+	})
+	if err != nil {
+		log.Printf("无法发送远程日志: %v", err)
+		return
+	}
+	shipper = s
+	if logFile != nil {
+		log.SetOutput(io.MultiWriter(logFile, recentLog, s))
+	} else {
+		log.SetOutput(io.MultiWriter(recentLog, s))
+	}
+	log.Printf("日志将发送到 %s", cfg.Logging.RemoteURL)
+}
+
+// 退出前发送剩余的日志
+func stopLogShipping() {
+	if shipper != nil {
+		shipper.Close(5 * time.Second)
+	}
+}
+
+// 应用异常退出时，把应用日志的末尾发送到日志收集器
+func shipAppCrash(exitErr error) {
+	if shipper == nil {
+		return
+	}
+	f, err := os.Open(appLogPath)
+	if err != nil {
+		log.Printf("读取应用日志失败: %v", err)
+		return
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() > appCrashLogTail {
+		f.Seek(-appCrashLogTail, io.SeekEnd)
+	}
+	data, _ := io.ReadAll(f)
+
+	shipper.Send("app", "应用异常退出: "+exitErr.Error())
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			shipper.Send("app", line)
+		}
+	}
+}
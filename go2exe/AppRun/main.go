@@ -1,466 +1,680 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
-	"sync"
 	"syscall"
 	"time"
-	"unsafe"
+
+	"go2exe/internal/exitcode"
+	"go2exe/internal/health"
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+	"go2exe/internal/launch"
+	"go2exe/internal/mirror"
+	"go2exe/internal/runner"
+	"go2exe/internal/signature"
+	"go2exe/internal/ui"
 )
 
 // Windows API 函数声明
 var (
-	user32             = syscall.NewLazyDLL("user32.dll")
-	kernel32           = syscall.NewLazyDLL("kernel32.dll")
-	messageBox         = user32.NewProc("MessageBoxW")
-	createWindowEx     = user32.NewProc("CreateWindowExW")
-	defWindowProc      = user32.NewProc("DefWindowProcW")
-	registerClassEx    = user32.NewProc("RegisterClassExW")
-	getModuleHandle    = kernel32.NewProc("GetModuleHandleW")
-	postQuitMessage    = user32.NewProc("PostQuitMessage")
-	showWindow         = user32.NewProc("ShowWindow")
-	updateWindow       = user32.NewProc("UpdateWindow")
-	destroyWindow      = user32.NewProc("DestroyWindow")
-	getMessage         = user32.NewProc("GetMessageW")
-	translateMessage   = user32.NewProc("TranslateMessage")
-	dispatchMessage    = user32.NewProc("DispatchMessageW")
-	setWindowText      = user32.NewProc("SetWindowTextW")
-	createWindowExW    = user32.NewProc("CreateWindowExW")
-	sendMessage        = user32.NewProc("SendMessageW")
-	getWindowRect      = user32.NewProc("GetWindowRect")
-	createFont         = user32.NewProc("CreateFontW")
-	getStdHandle       = kernel32.NewProc("GetStdHandle")
-	allocConsole       = kernel32.NewProc("AllocConsole")
-	freeConsole        = kernel32.NewProc("FreeConsole")
-	writeConsole       = kernel32.NewProc("WriteConsoleW")
-	setConsoleTitle    = kernel32.NewProc("SetConsoleTitleW")
-	MB_OK              = 0x00000000
-	MB_ICONINFORMATION = 0x00000040
-	MB_ICONEXCLAMATION = 0x00000030
-	STD_OUTPUT_HANDLE  = -11
+	user32           = syscall.NewLazyDLL("user32.dll")
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	createWindowEx   = user32.NewProc("CreateWindowExW")
+	defWindowProc    = user32.NewProc("DefWindowProcW")
+	registerClassEx  = user32.NewProc("RegisterClassExW")
+	getModuleHandle  = kernel32.NewProc("GetModuleHandleW")
+	postQuitMessage  = user32.NewProc("PostQuitMessage")
+	showWindow       = user32.NewProc("ShowWindow")
+	updateWindow     = user32.NewProc("UpdateWindow")
+	destroyWindow    = user32.NewProc("DestroyWindow")
+	getMessage       = user32.NewProc("GetMessageW")
+	translateMessage = user32.NewProc("TranslateMessage")
+	dispatchMessage  = user32.NewProc("DispatchMessageW")
+	setWindowText    = user32.NewProc("SetWindowTextW")
+	createWindowExW  = user32.NewProc("CreateWindowExW")
+	sendMessage      = user32.NewProc("SendMessageW")
+	getWindowRect    = user32.NewProc("GetWindowRect")
+	createFont       = user32.NewProc("CreateFontW")
 )
 
-// 显示消息框
-func showMessageBox(title, message string) {
-	titlePtr, _ := syscall.UTF16PtrFromString(title)
-	messagePtr, _ := syscall.UTF16PtrFromString(message)
-	messageBox.Call(
-		0,
-		uintptr(unsafe.Pointer(messagePtr)),
-		uintptr(unsafe.Pointer(titlePtr)),
-		uintptr(MB_OK|MB_ICONINFORMATION),
-	)
-}
-
 var (
-	hwndProgressWindow uintptr
-	outputText         []string
-	outputMutex        sync.Mutex
-	progressTitle      string
+	// 传给 uv、安装脚本和应用的环境变量（代理、Python 编码、安装位置等），不修改启动器自己的环境变量
+	childEnv = &runner.Environment{}
+	// 全局使用的命令执行器
+	cmdRunner runner.CommandRunner = runner.Exec{Env: childEnv}
+	// 安装进度控制台
+	console = ui.NewConsole("安装进度")
+	// Python 应用启动器，应用退出后通知常驻模式
+	app = &launch.Launcher{
+		Runner: cmdRunner,
+		Out:    console,
+		Index:  mirror.Default.URL,
+		// 告诉应用如何连接启动器
+		Env: []string{"SPEAKMYBOOK_IPC_PIPE=" + ipcPipeName()},
+		OnExit: func(err error) {
+			if err != nil {
+				shipAppCrash(err)
+				appHealth.Fail(health.StateCrashed, err)
+			} else {
+				appHealth.Set(health.StateExited, "")
+			}
+			signalAppReady(appExitedEarly(err))
+			onAppExited()
+		},
+	}
+	// 安装设置，由配置文件设置
+	installConfig = defaultConfig().Install
+	// 用户在进度窗口点击“取消”后取消，正在执行的安装命令随之结束
+	installCtx, cancelInstall = context.WithCancel(context.Background())
+	// --force-sync：不论 uv.lock 和 pyproject.toml 是否变化都同步依赖
+	forceSync bool
 )
 
-// 初始化控制台窗口
-func initConsole() {
-	allocConsole.Call()
-	titlePtr, _ := syscall.UTF16PtrFromString("安装进度")
-	setConsoleTitle.Call(uintptr(unsafe.Pointer(titlePtr)))
-}
-
-// 关闭控制台窗口
-func closeConsole() {
-	freeConsole.Call()
-}
+// 启动器的日志文件，无法创建时为 nil
+var logFile *os.File
 
-// 写入控制台
-func writeToConsole(text string) {
-	handle, _, _ := getStdHandle.Call(uintptr(STD_OUTPUT_HANDLE))
-	textPtr, _ := syscall.UTF16FromString(text + "\r\n")
-	var written uint32
-	writeConsole.Call(
-		handle,
-		uintptr(unsafe.Pointer(&textPtr[0])),
-		uintptr(len(textPtr)),
-		uintptr(unsafe.Pointer(&written)),
-		0,
-	)
+// 启动器所在的程序目录（绝对路径）。python、uv 目录和日志都从它计算，启动器不改变当前目录，与从哪个目录启动无关
+func executableDir() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(exePath), nil
 }
 
 func init() {
-	// 创建日志文件
-	logFile, err := os.OpenFile("app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	// 创建日志文件，放在程序目录下
+	exeDir, err := executableDir()
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(launcherLogPath(exeDir), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		// 如果无法创建日志文件，继续执行但不记录日志
 		return
 	}
-	log.SetOutput(logFile)
+	logFile = f
+	log.SetOutput(io.MultiWriter(logFile, recentLog))
 }
 
-// 检查是否安装了uv
-func isUVInstalled() (bool, string) {
-	// 执行 uv -V 命令
-	cmd := exec.Command("uv", "-V")
-	// 隐藏窗口
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		HideWindow: true,
-	}
-
-	var outBuf bytes.Buffer
-	cmd.Stdout = &outBuf
-	cmd.Stderr = &outBuf
-
-	err := cmd.Run()
-
-	// 将输出转换为字符串
-	outputStr := strings.TrimSpace(outBuf.String())
-
-	// 记录日志
-	log.Printf("UV 检查结果: %v, 输出: %s", err == nil, outputStr)
+// 添加输出文本
+func addOutputText(text string) {
+	console.Line(text)
+}
 
-	// 如果命令执行出错，或输出包含错误信息，说明未安装
-	if err != nil || strings.Contains(outputStr, "不是内部或外部命令") {
-		return false, outputStr
+// 创建使用全局执行器和控制台的安装器
+func newInstaller(exeDir string) *install.Installer {
+	return &install.Installer{
+		ExeDir:         exeDir,
+		Runner:         cmdRunner,
+		Out:            console,
+		MinFreeSpaceMB: installConfig.MinFreeSpaceMB,
+		UVInstallDir:   installConfig.UVDir,
+		Proxy:          childEnv.Proxy,
+		TempDir:        installConfig.TempDir,
+		Context:        installCtx,
+		StepTimeout:    stepTimeout(),
+		Python:         pythonRequest(),
+		Index:          pypiMirror.URL,
+		WheelsDir:      offlineWheelsDir(exeDir),
+		Arch:           pythonArch(),
+		Extras:         syncExtras,
+		MaxDownloads:   installConfig.MaxParallelDownloads,
+		Gateways:       installConfig.IPFSGateways,
+		PreferGateway:  installConfig.PreferIPFS,
+		Timeline:       runTimeline,
+		Provenance:     provenanceRecords(),
+		Signature: signature.Policy{
+			AllowUnsigned: installConfig.AllowUnsigned,
+			Publishers:    installConfig.TrustedPublishers,
+		},
+		UVConcurrency: install.UVConcurrency{
+			Downloads: installConfig.UVConcurrentDownloads,
+			Builds:    installConfig.UVConcurrentBuilds,
+			Installs:  installConfig.UVConcurrentInstalls,
+		},
+		Retry: install.RetryPolicy{
+			Attempts:   installConfig.RetryAttempts,
+			Backoff:    time.Duration(installConfig.RetryBackoffSeconds) * time.Second,
+			MaxBackoff: time.Minute,
+		},
 	}
+}
 
-	// 如果输出包含版本号，说明已安装
-	if strings.Contains(outputStr, "uv") {
-		return true, outputStr
-	}
+// 每条安装命令的最长执行时间
+func stepTimeout() time.Duration {
+	return time.Duration(installConfig.StepTimeoutMinutes) * time.Minute
+}
 
-	return false, outputStr
+// 每条检查命令的最长执行时间
+func checkTimeout() time.Duration {
+	return time.Duration(installConfig.CheckTimeoutSeconds) * time.Second
 }
 
-// 检查是否安装了Python3.11.9
-func isPython3119Installed() (bool, error) {
-	// 执行 uv python list 命令，使用PowerShell
-	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", "uv python list")
-	// 隐藏窗口
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		HideWindow: true,
+// 运行Python应用，exeDir 为程序所在目录，应用在其中的 python 目录中运行
+func runPythonApp(exeDir string, appArgs []string) error {
+	if fi, err := os.Stat(filepath.Join(exeDir, "python")); err != nil || !fi.IsDir() {
+		if err == nil {
+			err = fmt.Errorf("%s 不是目录", filepath.Join(exeDir, "python"))
+		}
+		log.Printf("无法进入python目录: %v", err)
+		addOutputText(i18n.T("无法进入python目录: %v", err))
+		return withExitCode(exitcode.AppStart, fmt.Errorf("无法进入python目录: %v", err))
 	}
 
-	var outBuf bytes.Buffer
-	cmd.Stdout = &outBuf
-	cmd.Stderr = &outBuf
+	log.Printf("正在启动 Python 应用")
+	addOutputText(i18n.T("正在启动 Python 应用..."))
 
-	err := cmd.Run()
+	// 同步依赖和下载模型文件互不依赖，同时执行
+	results, err := install.RunGraph([]install.Task{
+		{Name: "同步依赖", Run: func(inst *install.Installer) error { return syncDependencies(exeDir, inst) }},
+		{Name: "下载模型文件", Run: func(inst *install.Installer) error { return ensureModels(exeDir, inst) }},
+	}, installConfig.MaxParallelSteps, func() *install.Installer { return newInstaller(exeDir) })
 	if err != nil {
-		log.Printf("Python 检查命令失败: %v", err)
-		return false, fmt.Errorf("执行命令失败: %v", err)
-	}
-
-	// 将输出转换为字符串并按行分割
-	outputStr := outBuf.String()
-	lines := strings.Split(outputStr, "\n")
-
-	// 查找包含 Python 3.11.9 的行
-	targetVersion := "cpython-3.11.9-windows-x86_64-none"
-	for _, line := range lines {
-		if strings.Contains(line, targetVersion) {
-			// 检查行是否包含路径而不是 "<download available>"
-			if !strings.Contains(line, "<download available>") {
-				log.Printf("找到已安装的 Python 3.11.9")
-				return true, nil
-			}
+		return withExitCode(exitcode.AppStart, err)
+	}
+	// 同步失败时仍尝试用现有的虚拟环境启动，模型文件下载失败时缺少的语音暂时不可用；只有取消或没有虚拟环境时不启动
+	syncErr := results["同步依赖"]
+	for _, err := range results {
+		if errors.Is(err, runner.ErrCanceled) {
+			return err
 		}
 	}
-	log.Printf("未找到已安装的 Python 3.11.9")
-	return false, nil
-}
-
-// 处理命令输出的辅助函数
-func processCommandOutput(reader io.ReadCloser, isError bool) {
-	scanner := bufio.NewScanner(reader)
-	prefix := "INFO: "
-	if isError {
-		prefix = "ERROR: "
+	var venvErr *venvMissingError
+	if errors.As(syncErr, &venvErr) {
+		ui.ErrorBox(i18n.T("无法安装依赖"), i18n.T("%s\n\n详细信息请查看 app.log。", venvErr.err.Error()))
+		return withExitCode(exitcode.Sync, venvErr.err)
 	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		log.Println(prefix + line)
-		addOutputText(prefix + line)
+	resetAppReady()
+	err = startPythonApp(appArgs)
+	if err == nil {
+		err = waitAppReady(readyTimeout())
 	}
+	if err != nil {
+		// 依赖同步失败时虚拟环境可能不完整，这才是应用无法启动的原因
+		if syncErr != nil {
+			return withExitCode(exitcode.Sync, syncErr)
+		}
+		var startupErr *appStartupError
+		if errors.As(err, &startupErr) {
+			ui.ErrorBox(i18n.T("SpeakMyBook 启动失败"), i18n.T("%s\n\n详细信息请查看 app.log。", startupErr.message))
+		}
+		return withExitCode(exitcode.AppStart, err)
+	}
+	appHealth.Running(app.PID())
+	console.Close() // 主动关闭控制台
+	return nil
 }
 
-// 添加输出文本
-func addOutputText(text string) {
-	outputMutex.Lock()
-	defer outputMutex.Unlock()
-	outputText = append(outputText, text)
-	writeToConsole(text)
-}
+// 没有虚拟环境时依赖检查未通过，应用无法启动
+type venvMissingError struct{ err error }
 
-// 安装uv
-func installUV(exeDir string) error {
-	// 设置环境变量 INSTALLER_DOWNLOAD_URL
-	os.Setenv("INSTALLER_DOWNLOAD_URL", filepath.Join(exeDir, "uv"))
-	log.Printf("正在安装 UV，使用本地路径: %s", filepath.Join(exeDir, "uv"))
-	addOutputText(fmt.Sprintf("正在安装 UV，使用本地路径: %s", filepath.Join(exeDir, "uv")))
+func (e *venvMissingError) Error() string { return e.err.Error() }
 
-	// 执行 uv-installer.ps1 脚本
-	cmd := exec.Command("powershell", "-ExecutionPolicy", "ByPass", "-File", filepath.Join(exeDir, "uv", "uv-installer.ps1"))
-	// 隐藏窗口
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		HideWindow: true,
+// 按配置同步依赖。同步失败时仍然尝试用现有的虚拟环境启动应用；还没有虚拟环境时不能按配置跳过，
+// 依赖检查未通过时返回 *venvMissingError
+func syncDependencies(exeDir string, inst *install.Installer) error {
+	_, statErr := os.Stat(appPython(exeDir))
+	venvMissing := statErr != nil
+	if !venvMissing && !checks.sync() {
+		log.Printf("按配置跳过依赖同步")
+		return nil
 	}
-
-	// 获取标准输出和错误输出管道
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		log.Printf("获取标准输出管道失败: %v", err)
+	if !venvMissing && !forceSync && dependenciesSynced(exeDir) {
+		log.Printf("uv.lock 和 pyproject.toml 与上次同步时相同，跳过依赖同步")
+		return nil
+	}
+	// 没有虚拟环境或安装包有更新时要下载大量依赖，先确认能够解析
+	var err error
+	if venvMissing || checks.firstRun || checks.updated {
+		err = precheckSync(inst)
+	}
+	switch {
+	case errors.Is(err, runner.ErrCanceled):
+		return err
+	case err != nil && venvMissing:
+		return &venvMissingError{err}
+	case err != nil:
+		log.Printf("依赖检查未通过，不同步依赖，继续使用现有的虚拟环境: %v", err)
+		addOutputText(i18n.T("依赖检查未通过，继续使用现有的虚拟环境: %v", err))
 		return err
 	}
+	return syncVenv(exeDir, inst)
+}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		log.Printf("获取标准错误输出管道失败: %v", err)
-		return err
+// 同步依赖，成功后记下 uv.lock 和 pyproject.toml 的哈希，之后的启动在它们变化前不再同步
+func syncVenv(exeDir string, inst *install.Installer) error {
+	appHealth.Set(health.StateSyncing, "")
+	if inst.WheelsDir == "" {
+		seedUVCache()
 	}
+	err := inst.RunStep("同步依赖", inst.Sync)
+	recordDependencySync(exeDir, err == nil)
+	return err
+}
 
-	// 启动命令
-	if err := cmd.Start(); err != nil {
-		log.Printf("启动UV安装命令失败: %v", err)
+// 下载依赖前的预检，只在确定同步必然失败（锁文件过期、包被撤回、没有适合本机的安装包）时返回错误。
+// 无法访问镜像或原因不明时只记录，交给同步步骤处理（所需的包可能已在缓存中）
+func precheckSync(inst *install.Installer) error {
+	err := inst.CheckResolution()
+	var rerr *install.ResolveError
+	if !errors.As(err, &rerr) {
+		return err
+	}
+	switch rerr.Kind {
+	case install.ResolveOutdated, install.ResolvePlatform, install.ResolveYanked, install.ResolveMissing:
 		return err
 	}
+	log.Printf("依赖检查未能完成，继续同步: %v", err)
+	return nil
+}
 
-	// 实时处理输出
-	go processCommandOutput(stdout, false)
-	go processCommandOutput(stderr, true)
+// 启动 Python 应用，appArgs 追加在应用参数末尾
+func startPythonApp(appArgs []string) error {
+	// 读屏软件可能在两次启动之间开启或关闭，每次启动时重新检测
+	app.Env = runner.Overlay(app.Env, screenReaderEnv(screenReaderActive()))
+	return app.Start(appArgs)
+}
 
-	// 等待命令完成
-	err = cmd.Wait()
-	if err != nil {
-		log.Printf("UV 安装失败: %v", err)
-		addOutputText(fmt.Sprintf("UV 安装失败: %v", err))
-	} else {
-		addOutputText("UV 安装成功！")
-		log.Printf("UV 安装成功")
-	}
-	return err
+// 应用进程是否仍在运行
+func isAppRunning() bool {
+	return app.Running()
 }
 
-// 安装Python3.11.9
-func installPython(exeDir string) error {
-	localMirror := "file:///" + filepath.Join(exeDir, "python")
-	log.Printf("正在安装 Python 3.11.9，使用本地镜像: %s", localMirror)
-	addOutputText(fmt.Sprintf("正在安装 Python 3.11.9，使用本地镜像: %s", localMirror))
+func main() {
+	code := run()
+	stopLogShipping()
+	os.Exit(code)
+}
 
-	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
-		fmt.Sprintf("uv python install 3.11.9 --mirror '%s'", localMirror))
-	// 隐藏窗口
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		HideWindow: true,
+// 启动器的主流程，返回进程退出码
+func run() int {
+	voice := flag.String("voice", "", "朗读使用的语音，转发给应用")
+	shortcutBook := flag.String("create-shortcut", "", "在桌面为指定的书创建快捷方式后退出")
+	fileAssoc := flag.String("file-associations", "", "注册（yes）或删除（no）.epub、.txt、.pdf 的文件关联，保存到配置文件后退出")
+	uninstall := flag.Bool("uninstall", false, "卸载 uv 安装的 Python、虚拟环境和缓存")
+	repair := flag.Bool("repair", false, "删除并重新创建虚拟环境")
+	collect := flag.Bool("collect-diagnostics", false, "把日志和配置打包到桌面，用于反馈问题")
+	adopt := flag.String("adopt", "", "按 uv.lock 检查指定的虚拟环境，通过后以后用它代替程序目录中的 .venv 运行应用")
+	exportEnv := flag.String("export-env", "", "把解释器、虚拟环境和应用打包到指定的 zip 文件，用于离线复制到其他电脑")
+	importEnv := flag.String("import-env", "", "导入 --export-env 生成的 zip 文件")
+	transfer := flag.Bool("transfer", false, "打开“移到另一台电脑”向导：打包环境和用户数据，或在新电脑上导入迁移文件")
+	dryRun := flag.Bool("dry-run", false, "只检测 uv、Python、虚拟环境和磁盘空间，列出会执行的操作后退出，不安装也不启动应用")
+	flag.BoolVar(&forceSync, "force-sync", false, "即使 uv.lock 和 pyproject.toml 与上次同步时相同也同步依赖")
+	provisionGolden := flag.Bool("provision-golden", false, "（管理员使用）把 uv、Python、依赖和模型文件准备在程序目录中，之后开启黄金镜像模式，用户启动时只读使用，不再安装")
+	invalidate := flag.Bool("invalidate", false, "将记录的环境指纹标记为失效，下次启动时执行全部检查后退出，与 invalidate 子命令相同")
+	installOnly := flag.Bool("install-only", false, "（内部使用）以管理员身份只执行安装步骤")
+	userEnv := flag.String("user-env", "", "（内部使用）提权进程沿用的普通用户环境变量")
+	simulation := flag.String("simulate", "", "（开发和测试使用）按场景模拟 uv、PowerShell 和网络：ok、uv-missing、av-blocking、mirror-down 或 JSON 场景文件")
+	record := flag.String("record", "", "（开发和测试使用）把执行的外部命令及其输出录制到指定的磁带文件")
+	replay := flag.String("replay", "", "（开发和测试使用）按 --record 录制的磁带文件返回命令的输出，不执行真实的命令")
+	faults := flag.String("inject-fault", "", "（测试使用）注入故障，例如 sync:fail:1,download:corrupt,python-install:delay:30s")
+	flag.StringVar(&resultFile, "result-file", "", "把运行结果（退出码、失败的步骤和错误信息）以 JSON 写入指定文件，供部署工具读取")
+	textUI := flag.Bool("text-ui", false, "在控制台中显示提示、进度和安装向导，不显示窗口（无法显示窗口时自动使用）")
+	silent := flag.Bool("silent", false, "不显示任何窗口和提示，输出只写入日志，需要选择时使用默认值（在服务等非交互环境中自动使用）")
+	flag.Usage = printUsage
+	// -- 之后的参数原样传给应用，例如 --book a.epub 或调试参数
+	launcherArgs, forwardedArgs := launch.SplitArgs(os.Args[1:])
+	flag.CommandLine.Parse(launcherArgs)
+	// 作为服务或在会话 0 中运行时没有人能操作，改用静默模式；通过 SSH 运行等无法显示窗口时改用文本界面。
+	// 都不能停在没有人能看到的消息框上
+	if *silent {
+		ui.UseSilentMode("--silent")
+	} else if reason := ui.NonInteractive(); reason != "" {
+		ui.UseSilentMode(reason)
+	} else if *textUI {
+		ui.UseTextMode("--text-ui")
+	} else if reason := ui.GUIUnavailable(); reason != "" {
+		ui.UseTextMode(reason)
 	}
-
-	// 获取标准输出和错误输出管道
-	stdout, err := cmd.StdoutPipe()
+	console.OnCancel = confirmCancel
+	if *simulation != "" {
+		if err := applySimulation(*simulation); err != nil {
+			log.Printf("无法开始模拟: %v", err)
+			return finish(err)
+		}
+	}
+	if err := applyCassette(*record, *replay); err != nil {
+		log.Printf("无法读取磁带文件: %v", err)
+		return finish(err)
+	}
+	if err := applyFaults(*faults); err != nil {
+		log.Printf("无法注入故障: %v", err)
+		return finish(err)
+	}
+	// 提权的安装进程由已持有单实例锁的启动器启动，不经过单实例检查
+	if *installOnly {
+		return runInstallOnly(*userEnv)
+	}
+	// 获取可执行文件的完整路径
+	exePath, err := os.Executable()
 	if err != nil {
-		log.Printf("获取标准输出管道失败: %v", err)
-		return err
+		log.Printf("无法获取可执行文件路径: %v", err)
+		addOutputText(i18n.T("无法获取可执行文件路径: %v", err))
+		return finish(err)
 	}
+	exeDir := filepath.Dir(exePath)
+	app.Dir = filepath.Join(exeDir, "python")
 
-	stderr, err := cmd.StderrPipe()
+	// 读取配置文件，确定界面语言后再输出任何提示
+	cfg, err := loadConfig(exeDir)
+	i18n.SetLanguage(cfg.UI.Language)
 	if err != nil {
-		log.Printf("获取标准错误输出管道失败: %v", err)
-		return err
+		log.Printf("配置有问题，有问题的设置使用默认值:\n%v", err)
+		addOutputText(i18n.T("配置有问题，有问题的设置使用默认值:\n%v", err))
+	}
+	log.Printf("程序所在目录: %s", exeDir)
+	addOutputText(i18n.T("程序所在目录: %s", exeDir))
+	// 读写数据目录之前确定是否使用黄金镜像模式和便携模式
+	applyGolden(exeDir, &cfg, *provisionGolden)
+	applyPortable(exeDir, &cfg)
+	startLogShipping(exeDir, cfg)
+	// 应用使用与启动器相同的界面语言
+	app.Env = append(app.Env, "SPEAKMYBOOK_LANG="+i18n.Language())
+	// 以及系统的区域设置、时区和朗读语言
+	app.Env = append(app.Env, localeEnv(cfg.UI.SpeechLanguage)...)
+	useAdoptedVenv()
+	applyCodePage(cfg.Encoding)
+
+	// config validate、config print-effective 子命令
+	if flag.Arg(0) == "config" {
+		return finish(runConfigCommand(exeDir, flag.Args()[1:]))
+	}
+	// doctor --timeline、--network、--encoding 子命令
+	if flag.Arg(0) == "doctor" {
+		return finish(runDoctorCommand(cfg, flag.Args()[1:]))
+	}
+	// invalidate 子命令，--invalidate 是它的别名
+	if flag.Arg(0) == "invalidate" {
+		return finish(runInvalidate())
 	}
 
-	// 启动命令
-	if err := cmd.Start(); err != nil {
-		log.Printf("启动Python安装命令失败: %v", err)
-		return err
+	// 命令行中的书籍路径（来自右键菜单等），转成绝对路径以免切换目录后失效
+	var bookPaths []string
+	for _, p := range flag.Args() {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		bookPaths = append(bookPaths, p)
+	}
+	// 只创建快捷方式，不启动应用
+	if *shortcutBook != "" {
+		book, _ := filepath.Abs(*shortcutBook)
+		path, err := createBookShortcut(exePath, book, *voice)
+		if err != nil {
+			log.Printf("创建快捷方式失败: %v", err)
+			ui.ErrorBox(i18n.T("创建快捷方式失败"), err.Error())
+			return finish(err)
+		}
+		log.Printf("已创建快捷方式: %s", path)
+		return finish(nil)
+	}
+	// 只注册或删除文件关联，不启动应用
+	if *fileAssoc != "" {
+		err := setFileAssociations(exeDir, exePath, *fileAssoc)
+		if err != nil {
+			log.Printf("更新文件关联失败: %v", err)
+		}
+		return finish(err)
 	}
 
-	// 实时处理输出
-	go processCommandOutput(stdout, false)
-	go processCommandOutput(stderr, true)
+	// 只生成诊断包，应用运行时也可以执行
+	if *collect {
+		installConfig = cfg.Install
+		applyInstallDirs(cfg.Install)
+		path, err := collectDiagnostics(exeDir, cfg, nil)
+		if err != nil {
+			log.Printf("生成诊断包失败: %v", err)
+			ui.ErrorBox(i18n.T("生成诊断包失败"), err.Error())
+			return finish(err)
+		}
+		log.Printf("已生成诊断包: %s", path)
+		ui.MessageBox(i18n.T("诊断包已生成"), i18n.T("诊断包已保存到桌面：\n%s\n\n反馈问题时请附上这个文件。", path))
+		return finish(nil)
+	}
 
-	// 等待命令完成
-	err = cmd.Wait()
-	if err != nil {
-		log.Printf("Python 安装失败: %v", err)
-		addOutputText(fmt.Sprintf("Python 安装失败: %v", err))
-	} else {
-		addOutputText("Python 3.11.9 安装成功！")
-		log.Printf("Python 安装成功")
+	// 只演练，不安装也不启动应用；检测时使用的安装位置与正常启动相同
+	if *dryRun {
+		installConfig = cfg.Install
+		applyInstallDirs(cfg.Install)
+		return finish(runDryRun(exeDir, cfg))
 	}
-	return err
-}
 
-// 运行Python应用
-func runPythonApp() error {
-	// 进入当前目录中的python目录
-	err := os.Chdir("python")
-	if err != nil {
-		log.Printf("无法进入python目录: %v", err)
-		addOutputText(fmt.Sprintf("无法进入python目录: %v", err))
-		return fmt.Errorf("无法进入python目录: %v", err)
+	if *exportEnv != "" {
+		installConfig = cfg.Install
+		applyInstallDirs(cfg.Install)
+		return finish(runExportEnv(exeDir, *exportEnv))
 	}
 
-	log.Printf("正在启动 Python 应用")
-	addOutputText("正在启动 Python 应用...")
+	if *transfer {
+		installConfig = cfg.Install
+		applyInstallDirs(cfg.Install)
+		return finish(runTransfer(exeDir))
+	}
 
-	// 执行 uv sync 命令，配置清华源
-	log.Printf("正在执行 uv sync 配置清华源...")
-	addOutputText("正在执行 uv sync 配置清华源...")
+	if *adopt != "" {
+		installConfig = cfg.Install
+		return finish(runAdopt(exeDir, *adopt))
+	}
 
-	syncCmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
-		"uv sync --default-index 'https://pypi.tuna.tsinghua.edu.cn/simple'")
-	// 隐藏窗口
-	syncCmd.SysProcAttr = &syscall.SysProcAttr{
-		HideWindow: true,
+	// 只标记环境指纹失效，与 invalidate 子命令相同
+	if *invalidate {
+		return finish(runInvalidate())
 	}
 
-	// 获取标准输出和错误输出管道
-	stdout, err := syncCmd.StdoutPipe()
-	if err != nil {
-		log.Printf("获取标准输出管道失败: %v", err)
-		return err
+	// 已有常驻的启动器时直接转发给它，不再重复检查环境
+	if len(bookPaths) > 0 || len(forwardedArgs) > 0 {
+		msg := ipcMessage{Verb: "open", Args: bookPaths, AppArgs: forwardedArgs}
+		if *voice != "" {
+			msg.Options = map[string]string{"voice": *voice}
+		}
+		if err := sendMessageToLauncher(msg); err == nil {
+			log.Printf("已将 %v 转发给正在运行的启动器", bookPaths)
+			return finish(nil)
+		}
 	}
 
-	stderr, err := syncCmd.StderrPipe()
-	if err != nil {
-		log.Printf("获取标准错误输出管道失败: %v", err)
-		return err
+	if *uninstall || *repair || *importEnv != "" {
+		// 维护操作需要应用先退出
+		if err := stopRunningApp(); err != nil {
+			log.Printf("无法开始维护操作: %v", err)
+			return finish(withExitCode(exitcode.Cancelled, err))
+		}
+	} else if !acquireInstanceLock() || findAppWindow() != 0 {
+		// 单实例保护：已有启动器在运行，或应用窗口已打开时，切换到已有窗口后退出
+		log.Printf("SpeakMyBook 已在运行")
+		// 常驻的启动器会在应用未运行时重新启动它
+		if !bringAppToFront() && sendToLauncher("open") != nil {
+			ui.MessageBox("SpeakMyBook", i18n.T("SpeakMyBook 正在启动，请稍候..."))
+		}
+		return finish(nil)
 	}
 
-	// 启动命令
-	if err := syncCmd.Start(); err != nil {
-		log.Printf("启动uv sync命令失败: %v", err)
-		return err
+	// 在沙盒等关闭后会丢失用户目录的环境中，安装之前询问是否改用便携模式
+	if offerPortable(exeDir, &cfg) {
+		applyPortable(exeDir, &cfg)
+	}
+	// 记录安装和启动的时间线，供 doctor --timeline 查看
+	startTimeline("", "启动")
+
+	// 在执行任何网络操作之前确定代理
+	applyProxy(cfg)
+	applyIndex(exeDir, cfg.Network)
+	installConfig = cfg.Install
+	updateConfig = cfg.Update
+	applyInstallDirs(cfg.Install)
+	applyGPU(exeDir, cfg.Install.GPU)
+	startHealthServer(exeDir, cfg)
+	inst := newInstaller(exeDir)
+	// 上次运行崩溃或被强制结束时遗留的暂存目录
+	inst.CleanStaging()
+
+	if *uninstall {
+		err := runUninstall(exeDir)
+		if err != nil {
+			log.Printf("卸载失败: %v", err)
+		}
+		return finish(withExitCode(exitcode.Uninstall, err))
 	}
 
-	// 实时处理输出
-	go processCommandOutput(stdout, false)
-	go processCommandOutput(stderr, true)
+	if *importEnv != "" {
+		return finish(runImportEnv(exeDir, *importEnv))
+	}
 
-	// 等待命令完成
-	err = syncCmd.Wait()
-	if err != nil {
-		log.Printf("uv sync 配置失败: %v", err)
-		addOutputText(fmt.Sprintf("uv sync 配置失败: %v", err))
-		// 尽管配置失败，仍然继续尝试启动应用
-	} else {
-		addOutputText("uv sync 配置成功！")
-		log.Printf("uv sync 配置成功")
+	if *repair {
+		err := runRepair(exeDir)
+		if err != nil {
+			log.Printf("修复失败: %v", err)
+		}
+		return finish(withExitCode(exitcode.Repair, err))
 	}
 
-	// 执行Python应用
-	cmd := exec.Command("./.venv/Scripts/pythonw.exe", "app.pyw", "--default-index", "https://pypi.tuna.tsinghua.edu.cn/simple")
-	// 这里不要隐藏窗口，因为是启动真正的应用程序
+	if *provisionGolden {
+		err := runProvisionGolden(exeDir, inst)
+		if err != nil {
+			log.Printf("准备黄金镜像失败: %v", err)
+		}
+		return finish(err)
+	}
 
-	// 获取输出以便记录可能的错误
-	var outBuf bytes.Buffer
-	cmd.Stdout = &outBuf
-	cmd.Stderr = &outBuf
+	syncContextMenu(cfg, exePath)
+	syncFileAssociations(cfg, exePath)
+
+	// 按配置确定本次启动执行哪些检查
+	checks = loadLaunchChecks(exeDir, cfg.Checks)
+	if goldenMode {
+		// 环境由管理员准备，程序目录只读，不检查也不安装，只确认准备好的环境仍然可用
+		if err := checkGoldenImage(exeDir); err != nil {
+			log.Printf("黄金镜像不可用: %v", err)
+			ui.ErrorBox(i18n.T("运行环境未准备好"), i18n.T("%v\n\n这台电脑上的 SpeakMyBook 由管理员统一准备，请联系管理员以管理员身份运行 SpeakMyBook.exe --provision-golden。", err))
+			return finish(withExitCode(exitcode.Golden, err))
+		}
+		checks.warm = true
+	}
+	if checks.warm {
+		// 不运行任何检查命令，但托盘菜单中的更新和修复仍要用到 uv
+		state, _ := readCheckState()
+		addUVToPath(state.UVPath)
+		log.Printf("环境与上次成功启动时相同，跳过全部检查和依赖同步")
+	}
+	if checks.verify() {
+		if err := verifyInstallFiles(exeDir); err != nil {
+			log.Printf("安装文件校验失败: %v", err)
+			ui.ErrorBox(i18n.T("安装文件校验失败"), i18n.T("%v\n\n请重新下载完整的安装包后再试。", err))
+			resetLaunchChecks()
+			err = withExitCode(exitcode.Verify, err)
+			collectOnFailure(exeDir, cfg, err)
+			return finish(err)
+		}
+	}
 
-	err = cmd.Start()
+	// 检查并安装 uv 和 Python，需要管理员权限时只把这一步提权执行
+	setupPerformed, err := ensureEnvironment(exeDir, inst, setupOptions{
+		// 静默模式下不能弹出管理员权限请求，也没有人操作向导，与部署工具的安装一样直接安装
+		allowElevate: !ui.SilentMode(),
+		wizard:       !ui.SilentMode() && !checks.restored,
+		restore:      checks.restored,
+		skipUV:       !checks.uv(),
+		skipPython:   !checks.python(),
+	})
 	if err != nil {
-		log.Printf("Python 应用启动失败: %v", err)
-		addOutputText(fmt.Sprintf("Python 应用启动失败: %v", err))
-		return err
+		resetLaunchChecks()
+		collectOnFailure(exeDir, cfg, err)
+		holdOnError(err) // 给用户时间查看错误信息
+		return finish(err)
 	}
-	// 应用成功启动，记录信息并关闭控制台
-	log.Printf("Python 应用已启动")
-	addOutputText("Python 应用已启动")
-	closeConsole() // 主动关闭控制台
-	// 不调用 cmd.Wait()，让 Python 应用独立运行
-	return nil
-
-}
 
-func main() {
-	// 获取可执行文件的完整路径
-	exePath, err := os.Executable()
-	if err != nil {
-		log.Printf("无法获取可执行文件路径: %v", err)
-		addOutputText(fmt.Sprintf("无法获取可执行文件路径: %v", err))
-		return
+	// 有新版本时询问是否更新，应用还没有启动，可以直接替换 python 目录。
+	// 应用启动后要在后台检查时由后台检查查询，不让启动等待网络
+	if deferUpdateCheck(cfg) {
+		log.Printf("应用启动后在后台检查更新")
+	} else if err := checkAppUpdateOnStart(exeDir); err != nil {
+		return finish(err)
 	}
-	exeDir := filepath.Dir(exePath)
-	log.Printf("程序所在目录: %s", exeDir)
-	addOutputText(fmt.Sprintf("程序所在目录: %s", exeDir))
-
-	// 第一步：检查是否安装了uv
-	uvInstalled, output := isUVInstalled()
-	log.Printf("uv安装状态: %v, 输出: %s", uvInstalled, output)
-	addOutputText(fmt.Sprintf("uv安装状态: %v", uvInstalled))
-
-	// 如果未安装uv，则安装
-	if !uvInstalled {
-		// 首先弹出一个简单的消息框告知用户
-		showMessageBox("环境安装", "即将开始安装必要的环境组件，请稍候...\n\n这个过程只在首次运行时执行，可能需要几分钟。")
-		// 初始化控制台窗口
-		initConsole()
-		defer closeConsole()
-		log.Printf("正在安装uv...")
-		addOutputText("正在安装uv...")
-		err = installUV(exeDir)
-		if err != nil {
-			log.Printf("安装uv失败: %v", err)
-			addOutputText(fmt.Sprintf("安装uv失败: %v", err))
-			time.Sleep(5 * time.Second) // 给用户时间查看错误信息
-			return
+
+	// 首次安装时在“发送到”菜单中添加入口
+	if setupPerformed && cfg.Shell.SendTo {
+		if err := installSendTo(exePath); err != nil {
+			log.Printf("添加“发送到”菜单失败: %v", err)
+			addOutputText(i18n.T("添加“发送到”菜单失败: %v", err))
+		} else {
+			addOutputText(i18n.T("已在“发送到”菜单中添加 SpeakMyBook"))
 		}
-		log.Printf("uv安装完成")
-		addOutputText("uv安装完成")
-
-		// 重新检查uv安装状态
-		uvInstalled, _ = isUVInstalled()
-		if !uvInstalled {
-			log.Printf("安装后仍无法检测到uv，请检查安装过程")
-			addOutputText("安装后仍无法检测到uv，请检查安装过程")
-			time.Sleep(5 * time.Second) // 给用户时间查看错误信息
-			return
+	}
+	// 以及开始菜单和桌面快捷方式，安装完成后可以从那里再次启动
+	if setupPerformed && (cfg.Shell.StartMenu || cfg.Shell.DesktopShortcut) {
+		if err := installAppShortcuts(exePath, cfg.Shell); err != nil {
+			log.Printf("添加快捷方式失败: %v", err)
+			addOutputText(i18n.T("添加快捷方式失败: %v", err))
+		} else {
+			addOutputText(i18n.T("已添加 SpeakMyBook 的快捷方式"))
 		}
-	} else {
-		log.Printf("uv已安装，跳过安装步骤")
-		addOutputText("uv已安装，跳过安装步骤")
 	}
-
-	// 检查是否安装了Python3.11.9
-	pythonInstalled, err := isPython3119Installed()
-	if err != nil {
-		log.Printf("检查Python安装状态失败: %v", err)
-		addOutputText(fmt.Sprintf("检查Python安装状态失败: %v", err))
-		time.Sleep(5 * time.Second) // 给用户时间查看错误信息
-		return
+	if setupPerformed && !checks.restored {
+		askFileAssociations(exeDir, exePath, &cfg)
 	}
 
-	// 如果未安装Python3.11.9，则安装
-	if !pythonInstalled {
-		log.Printf("正在安装Python 3.11.9...")
-		addOutputText("正在安装Python 3.11.9...")
-		err = installPython(exeDir)
-		if err != nil {
-			log.Printf("安装Python 3.11.9失败: %v", err)
-			addOutputText(fmt.Sprintf("安装Python 3.11.9失败: %v", err))
-			time.Sleep(5 * time.Second) // 给用户时间查看错误信息
-			return
+	// 先启动 IPC 服务，应用启动后即可连接并报告是否就绪
+	handleAppReady()
+	if err := startIPCServer(); err != nil {
+		log.Printf("启动 IPC 服务失败: %v", err)
+	}
+	resident := needResident(cfg) && ipcRunning
+	if resident {
+		startResident(cfg, exePath)
+	}
+	if resident && cfg.Control.Enabled {
+		if err := startControlServer(exeDir, cfg); err != nil {
+			log.Printf("启动控制接口失败: %v", err)
 		}
-		log.Printf("Python 3.11.9安装完成")
-		addOutputText("Python 3.11.9安装完成")
-	} else {
-		log.Printf("Python 3.11.9已安装，跳过安装步骤")
-		addOutputText("Python 3.11.9已安装，跳过安装步骤")
+	}
+	if resident && cfg.Install.PeerShare {
+		startPeerSharing(exeDir, cfg)
 	}
 
+	// 没有音频输出设备时应用朗读会静默失败，启动前检测并告诉应用
+	checkDevices(checks.firstRun)
+
 	// 运行Python应用
 	log.Printf("正在运行Python应用...")
-	addOutputText("正在运行Python应用...")
-	err = runPythonApp()
+	addOutputText(i18n.T("正在运行Python应用..."))
+	appArgs := append(launch.AppArgs(bookPaths, *voice), forwardedArgs...)
+	if len(forwardedArgs) > 0 {
+		log.Printf("传给应用的参数: %q", forwardedArgs)
+	}
+	err = runPythonApp(exeDir, appArgs)
+	if err != nil && !errors.Is(err, runner.ErrCanceled) && !goldenMode && offerRollback(exeDir, err) {
+		// 已回退到上一个版本，再启动一次
+		err = runPythonApp(exeDir, appArgs)
+	}
 	if err != nil {
 		log.Printf("运行Python应用失败: %v", err)
-		addOutputText(fmt.Sprintf("运行Python应用失败: %v", err))
-		time.Sleep(5 * time.Second) // 给用户时间查看错误信息
-		return
+		addOutputText(i18n.T("运行Python应用失败: %v", err))
+		resetLaunchChecks()
+		collectOnFailure(exeDir, cfg, err)
+		holdOnError(err) // 给用户时间查看错误信息
+		return finish(err)
+	}
+	checks.save()
+	clearStartupFailures()
+	finishSetupWizard(exeDir, nil)
+
+	// 应用已启动即视为成功，常驻模式下不等启动器退出就写入结果
+	code := finish(nil)
+	if resident {
+		if checks.background() {
+			startBackgroundChecks(exeDir)
+		}
+		runResident(cfg)
 	}
+	return code
 }
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go2exe/internal/appconfig"
+	"go2exe/internal/exitcode"
+	"go2exe/internal/runner"
+)
+
+func TestMain(m *testing.M) {
+	// 测试不写 app.log
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// 在测试期间替换全局执行器
+func useMockRunner(handler func(c runner.Command) (string, error)) (*runner.Mock, func()) {
+	m := &runner.Mock{Handler: handler}
+	old := cmdRunner
+	cmdRunner, app.Runner = m, m
+	return m, func() { cmdRunner, app.Runner = old, old }
+}
+
+// 用作程序目录的临时目录，withPython 时包含 python 子目录，应用在其中启动
+func tempExeDir(t *testing.T, withPython bool) string {
+	t.Helper()
+	dir := t.TempDir()
+	if withPython {
+		os.Mkdir(filepath.Join(dir, "python"), 0755)
+	}
+	old := app.Dir
+	app.Dir = filepath.Join(dir, "python")
+	t.Cleanup(func() { app.Dir = old })
+	return dir
+}
+
+func TestRunPythonApp(t *testing.T) {
+	t.Run("同步失败仍启动应用", func(t *testing.T) {
+		dir := tempExeDir(t, true)
+		m, restore := useMockRunner(func(c runner.Command) (string, error) {
+			if c.Name == "uv" {
+				return "", errors.New("network unreachable")
+			}
+			return "", nil
+		})
+		defer restore()
+
+		if err := runPythonApp(dir, []string{"--book", "a.epub"}); err != nil {
+			t.Fatalf("runPythonApp() = %v", err)
+		}
+		lines := m.CommandLines()
+		if len(lines) < 2 || !strings.Contains(lines[0], "uv sync") {
+			t.Fatalf("执行的命令 = %v", lines)
+		}
+		last := m.Calls[len(m.Calls)-1]
+		if last.Name != filepath.Join(dir, "python", ".venv", "Scripts", "pythonw.exe") || last.Dir != filepath.Join(dir, "python") || last.Args[len(last.Args)-1] != "a.epub" {
+			t.Errorf("启动命令 = %v", lines[len(lines)-1])
+		}
+	})
+
+	t.Run("应用启动失败", func(t *testing.T) {
+		dir := tempExeDir(t, true)
+		_, restore := useMockRunner(func(c runner.Command) (string, error) {
+			if strings.HasSuffix(c.Name, "pythonw.exe") {
+				return "", errors.New("file not found")
+			}
+			return "", nil
+		})
+		defer restore()
+
+		if err := runPythonApp(dir, nil); exitCode(err) != exitcode.AppStart {
+			t.Errorf("应用启动失败时应返回退出码 %d，实际 %v", exitcode.AppStart, err)
+		}
+	})
+
+	t.Run("同步失败且应用无法启动", func(t *testing.T) {
+		dir := tempExeDir(t, true)
+		_, restore := useMockRunner(func(c runner.Command) (string, error) {
+			return "", errors.New("failed")
+		})
+		defer restore()
+
+		if err := runPythonApp(dir, nil); exitCode(err) != exitcode.Sync {
+			t.Errorf("应返回退出码 %d，实际 %v", exitcode.Sync, err)
+		}
+	})
+
+	t.Run("缺少 python 目录", func(t *testing.T) {
+		dir := tempExeDir(t, false)
+		m, restore := useMockRunner(nil)
+		defer restore()
+
+		if err := runPythonApp(dir, nil); err == nil {
+			t.Errorf("缺少 python 目录时应返回错误")
+		}
+		if len(m.Calls) != 0 {
+			t.Errorf("不应执行任何命令: %v", m.CommandLines())
+		}
+	})
+}
+
+func TestFinishResultFile(t *testing.T) {
+	resultFile = filepath.Join(t.TempDir(), "result.json")
+	defer func() { resultFile = "" }()
+
+	err := withExitCode(exitcode.PythonInstall, errors.New("exit status 2"))
+	if code := finish(err); code != exitcode.PythonInstall {
+		t.Errorf("finish() = %d, want %d", code, exitcode.PythonInstall)
+	}
+	data, readErr := os.ReadFile(resultFile)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	var result launchResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("结果文件不是有效的 JSON: %v", err)
+	}
+	if result.ExitCode != exitcode.PythonInstall || result.Step != "install_python" || !strings.Contains(result.Error, "exit status 2") {
+		t.Errorf("结果 = %+v", result)
+	}
+	if result.FinishedAt.Before(result.StartedAt) {
+		t.Errorf("结束时间早于开始时间: %+v", result)
+	}
+
+	if code := finish(nil); code != exitcode.OK {
+		t.Errorf("finish(nil) = %d", code)
+	}
+}
+
+func TestParseLanguageID(t *testing.T) {
+	tests := []struct {
+		value string
+		want  uint32
+		ok    bool
+	}{
+		{"804", 0x804, true},
+		{"409;9", 0x409, true},
+		{"", 0, false},
+		{"zh-CN", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseLanguageID(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseLanguageID(%q) = %#x, %v, want %#x, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSaveConfigValues(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, configFileName)
+	os.WriteFile(path, []byte("[install]\r\n# 注释\r\nmin_free_space_mb = 100\r\nuv_dir = 'C:\\old'\r\n\r\n[ui]\r\nlanguage = \"en-US\"\r\n"), 0644)
+
+	err := saveConfigValues(dir, "install", map[string]interface{}{
+		"uv_dir":           `D:\Apps\uv`,
+		"license_accepted": true,
+	})
+	if err != nil {
+		t.Fatalf("saveConfigValues() = %v", err)
+	}
+	if err := saveConfigValues(dir, "logging", map[string]interface{}{"batch_size": 10}); err != nil {
+		t.Fatalf("saveConfigValues() = %v", err)
+	}
+
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		t.Fatalf("loadConfig() = %v", err)
+	}
+	if cfg.Install.UVDir != `D:\Apps\uv` || !cfg.Install.LicenseAccepted || cfg.Install.MinFreeSpaceMB != 100 {
+		t.Errorf("安装设置 = %+v", cfg.Install)
+	}
+	if cfg.UI.Language != "en-US" || cfg.Logging.BatchSize != 10 {
+		t.Errorf("其他设置 = %+v, %+v", cfg.UI, cfg.Logging)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# 注释\r\n") {
+		t.Errorf("注释或换行符未保留:\n%s", data)
+	}
+}
+
+func TestLaunchChecksNeed(t *testing.T) {
+	tests := []struct {
+		when              string
+		firstRun, updated bool
+		want              bool
+	}{
+		{"always", false, false, true},
+		{"", false, false, true},
+		{"after_update", false, false, false},
+		{"after_update", false, true, true},
+		{"after_update", true, false, true},
+		{"first_run", false, true, false},
+		{"first_run", true, false, true},
+		{"never", true, true, false},
+		{"sometimes", false, false, true},
+	}
+	for _, tt := range tests {
+		c := launchChecks{firstRun: tt.firstRun, updated: tt.updated}
+		if got := c.need("sync", tt.when); got != tt.want {
+			t.Errorf("need(%q) 首次运行 %v 已更新 %v = %v, want %v", tt.when, tt.firstRun, tt.updated, got, tt.want)
+		}
+	}
+	// 磁盘被还原后不论配置都要检查
+	if c := (launchChecks{restored: true}); !c.need("sync", "never") {
+		t.Error("磁盘被还原后应执行检查")
+	}
+}
+
+func TestLaunchChecksBackground(t *testing.T) {
+	tests := []struct {
+		cfg  ChecksConfig
+		want bool
+	}{
+		{ChecksConfig{Sync: "always", Verify: "never", Background: true}, true},
+		{ChecksConfig{Sync: "never", Verify: "always", Background: true}, true},
+		{ChecksConfig{Sync: "always", Verify: "always", Background: true}, false},
+		{ChecksConfig{Sync: "never", Verify: "never", Background: false}, false},
+	}
+	for _, tt := range tests {
+		c := launchChecks{cfg: tt.cfg}
+		if got := c.background(); got != tt.want {
+			t.Errorf("background() %+v = %v, want %v", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestEnvironmentStamp(t *testing.T) {
+	dir := tempExeDir(t, true)
+	uv := filepath.Join(dir, "uv.exe")
+	python := filepath.Join(dir, "python.exe")
+	venvPython := appPython(dir)
+	lock := filepath.Join(dir, "python", "uv.lock")
+	os.MkdirAll(filepath.Dir(venvPython), 0755)
+	for _, path := range []string{uv, python, venvPython, filepath.Join(filepath.Dir(filepath.Dir(venvPython)), "pyvenv.cfg"), lock} {
+		os.WriteFile(path, []byte("1"), 0644)
+	}
+	state := checkState{UVPath: uv, PythonPath: python}
+
+	stamp := environmentStamp(dir, state)
+	if stamp == "" {
+		t.Fatal("文件都存在时环境戳为空")
+	}
+	if got := environmentStamp(dir, state); got != stamp {
+		t.Errorf("环境没有变化时环境戳不同: %s != %s", got, stamp)
+	}
+	if got := environmentStamp(dir, checkState{UVPath: uv}); got != "" {
+		t.Errorf("没有检测到 Python 时环境戳 = %s，want 空", got)
+	}
+	os.WriteFile(lock, []byte("2"), 0644)
+	if got := environmentStamp(dir, state); got == stamp {
+		t.Error("uv.lock 变化后环境戳没有变化")
+	}
+	os.Remove(uv)
+	if got := environmentStamp(dir, state); got != "" {
+		t.Errorf("uv 不存在时环境戳 = %s，want 空", got)
+	}
+}
+
+func TestLaunchChecksWarm(t *testing.T) {
+	c := launchChecks{cfg: ChecksConfig{UV: "always", Sync: "always"}, warm: true}
+	if c.uv() || c.sync() {
+		t.Error("环境未变化时仍执行检查")
+	}
+}
+
+func TestDependencySync(t *testing.T) {
+	t.Setenv("LOCALAPPDATA", t.TempDir())
+	dir := tempExeDir(t, true)
+	lock := filepath.Join(dir, "python", "uv.lock")
+	os.WriteFile(lock, []byte("1"), 0644)
+	os.WriteFile(filepath.Join(dir, "python", "pyproject.toml"), []byte("[project]"), 0644)
+
+	if dependenciesSynced(dir) {
+		t.Fatal("还没有同步过时视为已同步")
+	}
+	recordDependencySync(dir, true)
+	if !dependenciesSynced(dir) {
+		t.Fatal("同步后依赖声明没有变化时仍需同步")
+	}
+	os.WriteFile(lock, []byte("2"), 0644)
+	if dependenciesSynced(dir) {
+		t.Error("uv.lock 变化后仍视为已同步")
+	}
+	recordDependencySync(dir, true)
+	recordDependencySync(dir, false)
+	if dependenciesSynced(dir) {
+		t.Error("同步失败后仍视为已同步")
+	}
+}
+
+func TestEffectiveConfig(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, configFileName), []byte(`[install]
+gpu = "cuda"
+gpuu = "cpu"
+retry_attempts = "3"
+max_parallel_downloads = -1
+
+[update]
+manifest_url = "ftp://example.com/releases.json"
+
+[checks]
+sync = "after_update"
+`), 0644)
+	t.Setenv("SPEAKMYBOOK_PYTHON_VERSION", "3.10.*")
+	t.Setenv("APPRUN_INSTALL_PYTHON_VERSION", "3.11.*")
+	t.Setenv("SPEAKMYBOOK_INSTALL_PYTHON_VERSION", "3.12.*")
+	t.Setenv("SPEAKMYBOOK_MIRROR", "aliyun")
+	t.Setenv("APPRUN_TRAY_ICON", "true")
+	t.Setenv("APPRUN_NOPE", "1")
+	t.Setenv("SPEAKMYBOOK_INSTALL_NOPE", "1")
+	// 启动器传给应用的变量不是配置项
+	t.Setenv("SPEAKMYBOOK_GPU", "NVIDIA")
+	oldPolicy := policyConfigValues
+	policyConfigValues = func([]appconfig.Field) map[string]appconfig.Value {
+		return map[string]appconfig.Value{"checks.sync": {Value: "never", Source: "policy"}}
+	}
+	defer func() { policyConfigValues = oldPolicy }()
+
+	e := readEffectiveConfig(dir)
+	if e.cfg.Install.GPU != "cuda" || e.cfg.Install.PythonVersion != "3.12.*" || !e.cfg.Tray.Icon || e.cfg.Network.Index != "aliyun" {
+		t.Errorf("配置文件和环境变量的设置未生效: %+v, %+v, %+v", e.cfg.Install, e.cfg.Tray, e.cfg.Network)
+	}
+	if e.cfg.Checks.Sync != "never" || e.Sources["checks.sync"] != "policy" {
+		t.Errorf("策略未覆盖配置文件: %s（%s）", e.cfg.Checks.Sync, e.Sources["checks.sync"])
+	}
+	if e.cfg.Install.RetryAttempts != defaultConfig().Install.RetryAttempts || e.cfg.Install.MaxParallelDownloads != 4 || e.cfg.Update.ManifestURL != "" {
+		t.Errorf("有问题的设置应使用默认值: %+v, %+v", e.cfg.Install, e.cfg.Update)
+	}
+	var problems []string
+	for _, p := range e.Problems {
+		problems = append(problems, p.String())
+	}
+	want := []string{
+		"apprun.toml 第 3 行 install.gpuu: 未知的配置项，是否为 install.gpu？",
+		"apprun.toml 第 4 行 install.retry_attempts: 应为整数",
+		"apprun.toml 第 5 行 install.max_parallel_downloads: 不能为负数",
+		"apprun.toml 第 8 行 update.manifest_url",
+		"环境变量 APPRUN_NOPE: 没有对应的配置项",
+		"环境变量 SPEAKMYBOOK_INSTALL_NOPE: 没有对应的配置项",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("问题 = %q", problems)
+	}
+	for i := range want {
+		if !strings.HasPrefix(problems[i], want[i]) {
+			t.Errorf("第 %d 个问题 = %q，want %q", i+1, problems[i], want[i])
+		}
+	}
+
+	lines := e.format()
+	if !slices.Contains(lines, `python_version = "3.12.*"  # 环境变量 SPEAKMYBOOK_INSTALL_PYTHON_VERSION`) || !slices.Contains(lines, `gpu = "cuda"  # apprun.toml 第 2 行`) {
+		t.Errorf("生效的配置:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestProjectDependencies(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"[project]\nname = \"speakmybook\"\ndependencies = [\n    \"beautifulsoup4>=4.13.4\",\n    \"edge-tts>=7.0.2\",\n]\n", []string{"beautifulsoup4", "edge-tts"}},
+		{"[project]\ndependencies = [\"pygame>=2.6.1\", 'pillow[jpeg] ; sys_platform == \"win32\"']\n", []string{"pygame", "pillow"}},
+		// 没有依赖或无法解析时返回错误，不能用空列表清理整个 uv 缓存
+		{"[project]\nname = \"speakmybook\"\n", nil},
+		{"[project]\ndependencies = []\n", nil},
+		{"[tool.uv]\nindex = [{ url = \"https://mirror.example/simple\" }]\n[project]\ndependencies = [\"pygame\"]\n", nil},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "pyproject.toml"), []byte(tt.content), 0644)
+		deps, err := projectDependencies(dir)
+		if (err == nil) != (tt.want != nil) || !slices.Equal(deps, tt.want) {
+			t.Errorf("projectDependencies(%q) = %q, %v, want %q", tt.content, deps, err, tt.want)
+		}
+	}
+}
+
+func TestBookShortcuts(t *testing.T) {
+	old := dataRoot
+	dataRoot = t.TempDir()
+	t.Cleanup(func() { dataRoot = old })
+
+	desktop := t.TempDir()
+	a, b := filepath.Join(desktop, "继续收听 A.lnk"), filepath.Join(desktop, "继续收听 B.lnk")
+	for _, p := range []string{a, b, a} {
+		os.WriteFile(p, []byte("lnk"), 0644)
+		if err := recordBookShortcut(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := loadBookShortcuts(); !slices.Equal(got, []string{a, b}) {
+		t.Errorf("loadBookShortcuts() = %q, want %q", got, []string{a, b})
+	}
+
+	// 用户已手动删除的快捷方式不算失败
+	os.Remove(b)
+	if err := removeBookShortcuts(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(a); !os.IsNotExist(err) {
+		t.Error("快捷方式没有删除")
+	}
+	if got := loadBookShortcuts(); len(got) != 0 {
+		t.Errorf("删除后仍记录了 %q", got)
+	}
+}
+
+func TestUpdateAvailable(t *testing.T) {
+	tests := []struct {
+		latest, current, rolledBack string
+		want                        bool
+	}{
+		{"2.2.0", "2.1.0", "", true},
+		{"2.1.0", "2.1.0", "", false},
+		// 回退掉的版本和更旧的版本不再提示，发布了更新的版本后恢复提示
+		{"2.2.0", "2.1.0", "2.2.0", false},
+		{"2.2.1", "2.1.0", "2.2.0", true},
+	}
+	for _, tt := range tests {
+		if got := updateAvailable(tt.latest, tt.current, tt.rolledBack); got != tt.want {
+			t.Errorf("updateAvailable(%q, %q, %q) = %v, want %v", tt.latest, tt.current, tt.rolledBack, got, tt.want)
+		}
+	}
+}
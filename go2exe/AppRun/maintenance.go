@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"unsafe"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+var (
+	getWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
+	postMessage              = user32.NewProc("PostMessageW")
+	WM_CLOSE                 = 0x0010
+)
+
+// 请求退出后等待应用和启动器退出的时间
+const shutdownTimeout = 20 * time.Second
+
+// 维护操作（修复、卸载）前确保应用和常驻的启动器都已退出，并取得单实例锁，
+// 避免维护期间应用被重新启动。用户拒绝关闭应用时返回错误
+func stopRunningApp() error {
+	locked := acquireInstanceLock()
+	hwnd := findAppWindow()
+	if locked && hwnd == 0 {
+		return nil
+	}
+
+	var appPID uint32
+	if hwnd != 0 {
+		getWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&appPID)))
+		if !ui.ConfirmBox(i18n.T("关闭 SpeakMyBook"), i18n.T("SpeakMyBook 正在运行，需要先关闭它才能继续。\n\n"+
+			"请先保存正在进行的工作（例如正在导出的音频），然后点击“是”关闭 SpeakMyBook；点击“否”取消本次操作。")) {
+			return fmt.Errorf("用户取消了关闭应用")
+		}
+	}
+
+	// 优先让常驻的启动器通知应用退出；没有常驻的启动器时直接关闭应用窗口
+	log.Printf("请求 SpeakMyBook 退出")
+	if err := sendToLauncher("shutdown"); err != nil {
+		log.Printf("通知启动器退出失败，直接关闭应用窗口: %v", err)
+		closeAppWindow()
+	}
+
+	deadline := time.Now().Add(shutdownTimeout)
+	for time.Now().Before(deadline) {
+		if !locked {
+			locked = acquireInstanceLock()
+		}
+		if locked && findAppWindow() == 0 {
+			log.Printf("SpeakMyBook 已退出")
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	if findAppWindow() != 0 {
+		if !ui.ConfirmBox(i18n.T("SpeakMyBook 未响应"), i18n.T("SpeakMyBook 在 %d 秒内没有退出。\n\n"+
+			"是否强制结束？未保存的内容将会丢失。", int(shutdownTimeout/time.Second))) {
+			return fmt.Errorf("应用未退出，用户取消了强制结束")
+		}
+		log.Printf("强制结束应用进程 %d", appPID)
+		runner.KillTree(appPID)
+	}
+	if !locked && !acquireInstanceLock() {
+		// 启动器没有退出也不影响维护，只是维护期间它仍可能响应快捷键
+		log.Printf("常驻的启动器仍在运行，继续执行维护操作")
+	}
+	return nil
+}
+
+// 向应用主窗口发送 WM_CLOSE，与用户点击关闭按钮相同
+func closeAppWindow() bool {
+	hwnd := findAppWindow()
+	if hwnd == 0 {
+		return false
+	}
+	postMessage.Call(hwnd, uintptr(WM_CLOSE), 0, 0)
+	return true
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+	"go2exe/internal/models"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+// 应用需要的语音和模型文件的清单，相对于程序目录
+const modelsManifest = "python/models.json"
+
+// 模型文件的存放目录
+func modelsDir() string {
+	if installConfig.ModelsDir != "" {
+		return installConfig.ModelsDir
+	}
+	return filepath.Join(dataDir(), "models")
+}
+
+// 按清单下载缺少的模型文件，并通过环境变量告诉应用模型目录和每个文件的位置：
+// SPEAKMYBOOK_MODELS_DIR，以及每个已下载的文件的 SPEAKMYBOOK_MODEL_<名称>。
+// 下载失败时提示用户，应用仍然启动，缺少的文件没有对应的环境变量
+func ensureModels(exeDir string, inst *install.Installer) error {
+	manifest, err := models.Load(filepath.Join(exeDir, filepath.FromSlash(modelsManifest)))
+	if err != nil {
+		log.Printf("读取模型清单失败: %v", err)
+		addOutputText(i18n.T("读取模型清单失败: %v", err))
+		return err
+	}
+	if manifest == nil {
+		return nil
+	}
+	dir := modelsDir()
+	app.Env = runner.Overlay(app.Env, "SPEAKMYBOOK_MODELS_DIR="+dir)
+
+	// 黄金镜像模式下模型目录只读，只使用管理员准备好的文件
+	if !goldenMode {
+		err = inst.RunStep("下载模型文件", func() error {
+			seedModels(dir, manifest.Files)
+			fetchPeerModels(dir, manifest.Files)
+			return inst.DownloadFiles(dir, manifest.Files)
+		})
+	}
+	if errors.Is(err, runner.ErrCanceled) {
+		return err
+	}
+	missing := map[string]bool{}
+	for _, f := range models.Missing(dir, manifest.Files) {
+		missing[f.Name] = true
+	}
+	for _, f := range manifest.Files {
+		if !missing[f.Name] {
+			app.Env = runner.Overlay(app.Env, f.EnvName()+"="+f.Local(dir))
+		}
+	}
+	if goldenMode && len(missing) > 0 {
+		log.Printf("黄金镜像中缺少 %d 个模型文件，相关的语音无法使用，请管理员重新准备", len(missing))
+	}
+	if err != nil {
+		log.Printf("下载模型文件失败，%d 个文件缺失: %v", len(missing), err)
+		ui.ErrorBox(i18n.T("下载模型文件失败"), i18n.T("%d 个语音或模型文件没有下载完成，相关的语音暂时无法使用：%v\n\n"+
+			"下次启动时会从断开的位置继续下载。", len(missing), err))
+	}
+	return err
+}
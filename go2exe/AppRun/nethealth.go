@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/mirror"
+)
+
+// 检查各镜像能否下载的关键依赖，应用朗读依赖它
+const keyPackage = "edge-tts"
+
+// edge-tts 使用的语音合成服务，应用朗读时需要访问
+const ttsHost = "https://speech.platform.bing.com/"
+
+// 网络检查时每个请求的最长等待时间
+const healthTimeout = 5 * time.Second
+
+// 要检查的镜像：内置镜像，以及配置中的自定义镜像
+func healthMirrors(current mirror.Mirror) []mirror.Mirror {
+	mirrors := append([]mirror.Mirror{}, mirror.Builtin...)
+	if current.Name == "custom" {
+		mirrors = append(mirrors, current)
+	}
+	return mirrors
+}
+
+// 配置指定或之前自动选出的镜像，都没有时返回 false
+func currentIndex(cfg NetworkConfig) (mirror.Mirror, bool) {
+	if m, ok, _ := mirror.Parse(cfg.Index); ok {
+		return m, true
+	}
+	state, _ := readCheckState()
+	m, ok, _ := mirror.Parse(state.Index)
+	return m, ok
+}
+
+// 检查每个 PyPI 镜像、镜像上的关键依赖和语音合成服务能否访问，返回每一项的说明。
+// 经过与正常启动相同的代理，需在 applyProxy 之后调用
+func networkHealth(cfg NetworkConfig) []string {
+	current, hasCurrent := currentIndex(cfg)
+	var targets []mirror.Target
+	for _, m := range healthMirrors(current) {
+		name := i18n.T("PyPI 镜像 %s（%s）", m.Name, m.URL)
+		if hasCurrent && m.URL == current.URL {
+			name += i18n.T("，当前使用")
+		}
+		targets = append(targets,
+			mirror.Target{Name: name, URL: m.URL + "/"},
+			mirror.Target{Name: i18n.T("%s 上的 %s", m.Name, keyPackage), URL: m.URL + "/" + keyPackage + "/"},
+		)
+	}
+	targets = append(targets, mirror.Target{Name: i18n.T("语音合成服务（%s）", ttsHost), URL: ttsHost, AnyStatus: true})
+
+	log.Printf("正在检查 %d 个网络地址", len(targets))
+	var lines []string
+	for _, h := range mirror.Check(context.Background(), nil, targets, healthTimeout) {
+		if h.Err != nil {
+			log.Printf("%s 无法访问: %v", h.Target.URL, h.Err)
+		} else {
+			log.Printf("%s 响应时间 %v，%s", h.Target.URL, h.Latency.Round(time.Millisecond), h.TLS)
+		}
+		lines = append(lines, i18n.T("%s：%s", h.Target.Name, describeHealth(h)))
+	}
+	return lines
+}
+
+// 一项检查结果的简短说明
+func describeHealth(h mirror.Health) string {
+	switch h.Problem() {
+	case mirror.ProblemNone:
+		tls := h.TLS
+		if tls == "" {
+			tls = i18n.T("未加密")
+		}
+		return i18n.T("正常，%d 毫秒，%s", h.Latency.Milliseconds(), tls)
+	case mirror.ProblemDNS:
+		return i18n.T("域名无法解析，请检查网络或 DNS 设置")
+	case mirror.ProblemTimeout:
+		return i18n.T("%d 秒内没有响应", int(healthTimeout.Seconds()))
+	case mirror.ProblemTLS:
+		return i18n.T("证书无法验证，可能被代理或安全软件拦截了 HTTPS")
+	case mirror.ProblemHTTP:
+		return i18n.T("服务器返回 %v", h.Err)
+	}
+	return i18n.T("无法访问：%v", h.Err)
+}
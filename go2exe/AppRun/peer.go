@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/models"
+	"go2exe/internal/peercache"
+	"go2exe/internal/provenance"
+)
+
+// 在局域网中查找共享缓存的其他电脑时等待应答的时间
+const peerDiscoverTimeout = 2 * time.Second
+
+var (
+	peersOnce sync.Once
+	lanPeers  []peercache.Peer // 局域网中共享缓存的其他电脑，每次启动只查找一次
+)
+
+// 从其他电脑复制用的 HTTP 客户端：局域网内的地址不走代理，只限制等待响应的时间，大文件不限制总时长
+var peerClient = &http.Client{Transport: &http.Transport{ResponseHeaderTimeout: 10 * time.Second}}
+
+// 开启了 peer_fetch 时，查找局域网中共享缓存的其他电脑
+func findPeers() []peercache.Peer {
+	if !installConfig.PeerFetch {
+		return nil
+	}
+	peersOnce.Do(func() {
+		found, err := peercache.Discover(installCtx, peerDiscoverTimeout)
+		if err != nil {
+			log.Printf("查找局域网中的共享缓存失败: %v", err)
+		}
+		host, _ := os.Hostname()
+		for _, p := range found {
+			if !p.IsSelf(host) {
+				lanPeers = append(lanPeers, p)
+			}
+		}
+		log.Printf("局域网中有 %d 台电脑共享缓存: %v", len(lanPeers), lanPeers)
+	})
+	return lanPeers
+}
+
+// 下载模型文件前从局域网中的其他电脑下载本机缺少的文件，同样校验 SHA-256；都失败的文件仍然从清单中的地址下载
+func fetchPeerModels(dir string, files []models.File) {
+	peers := findPeers()
+	if len(peers) == 0 {
+		return
+	}
+	for _, f := range models.Missing(dir, files) {
+		for _, p := range peers {
+			local := f
+			local.URL = p.ModelURL(f)
+			addOutputText(i18n.T("正在从局域网中的 %s 复制 %s...", p.Name, f.Path))
+			if err := models.Download(installCtx, peerClient, dir, local, nil); err != nil {
+				log.Printf("从 %s（%s）下载 %s 失败: %v", p.Name, p.Addr, f.Path, err)
+				continue
+			}
+			log.Printf("已从 %s（%s）下载 %s", p.Name, p.Addr, f.Path)
+			provenanceRecords().Add(provenance.Record{Kind: provenance.KindModel, Name: f.Path, Source: provenance.SourcePeer,
+				URL: local.URL, SHA256: f.SHA256, Detail: p.Name})
+			break
+		}
+	}
+}
+
+// 常驻时在局域网中共享本机的模型文件，供开启了 peer_fetch 的其他电脑使用
+func startPeerSharing(exeDir string, cfg Config) {
+	manifest, err := models.Load(filepath.Join(exeDir, filepath.FromSlash(modelsManifest)))
+	if err != nil {
+		log.Printf("读取模型清单失败，不共享模型文件: %v", err)
+	}
+	server := &peercache.Server{ModelsDir: modelsDir()}
+	if manifest != nil {
+		server.Models = manifest.Files
+	}
+	host, _ := os.Hostname()
+	go func() {
+		if err := peercache.Share(context.Background(), cfg.Install.PeerListen, host, server); err != nil {
+			log.Printf("共享缓存停止: %v", err)
+		}
+	}()
+}
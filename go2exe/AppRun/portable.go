@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+
+	"go2exe/internal/hostenv"
+	"go2exe/internal/i18n"
+	"go2exe/internal/ui"
+)
+
+// 便携模式下 uv、Python 和启动器数据所在的目录（程序目录下）
+const portableDirName = "runtime"
+
+// 便携模式：uv、Python 和启动器的数据都放在程序目录下的 runtime 目录中，不写入用户目录，
+// 也不添加“发送到”、右键菜单、文件关联和开始菜单、桌面快捷方式。配置中明确指定的安装位置不变
+func applyPortable(exeDir string, cfg *Config) {
+	if cfg.Install.Portable != "yes" {
+		return
+	}
+	root := filepath.Join(exeDir, portableDirName)
+	if cfg.Install.UVDir == "" {
+		cfg.Install.UVDir = filepath.Join(root, "uv")
+	}
+	if cfg.Install.PythonDir == "" {
+		cfg.Install.PythonDir = filepath.Join(root, "python")
+	}
+	dataRoot = root
+	cfg.Shell.SendTo = false
+	cfg.Shell.StartMenu = false
+	cfg.Shell.DesktopShortcut = false
+	cfg.Shell.ContextMenu = false
+	if cfg.Shell.FileAssociations == "ask" {
+		cfg.Shell.FileAssociations = "no"
+	}
+	log.Printf("便携模式：uv、Python 和启动器数据放在 %s", root)
+}
+
+// portable 为 auto 时检测运行环境：在 Windows 沙盒或临时用户配置文件中，用户目录下的安装和设置关闭沙盒或注销后会丢失，
+// 提示用户并询问是否改用便携模式，回答保存到配置文件。改用便携模式时返回 true
+func offerPortable(exeDir string, cfg *Config) bool {
+	if cfg.Install.Portable != "auto" {
+		return false
+	}
+	host := hostenv.Detect()
+	if host.VM != "" {
+		log.Printf("运行在虚拟机中: %s", host.VM)
+	}
+	if !host.Ephemeral() {
+		return false
+	}
+	log.Printf("运行在%s中，用户目录下的内容关闭或注销后会丢失", host.Reason())
+	if ui.SilentMode() {
+		return false
+	}
+	answer := "no"
+	if ui.ConfirmBox("SpeakMyBook", i18n.T("SpeakMyBook 运行在%s中，关闭或注销后安装到用户目录中的 uv、Python 和设置都会丢失，下次启动需要重新安装。\n\n是否改用便携模式，把它们都放在程序目录下？", i18n.T(host.Reason()))) {
+		answer = "yes"
+	}
+	cfg.Install.Portable = answer
+	if err := saveConfigValues(exeDir, "install", map[string]interface{}{"portable": answer}); err != nil {
+		log.Printf("保存便携模式设置失败: %v", err)
+	}
+	return answer == "yes"
+}
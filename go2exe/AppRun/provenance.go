@@ -0,0 +1,24 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+
+	"go2exe/internal/provenance"
+)
+
+var (
+	provenanceMu  sync.Mutex
+	provenanceLog *provenance.Log
+)
+
+// 安装内容的来源记录，放在数据目录中，重新安装程序后仍然保留，随诊断包一起导出
+func provenanceRecords() *provenance.Log {
+	provenanceMu.Lock()
+	defer provenanceMu.Unlock()
+	path := filepath.Join(dataDir(), "provenance.jsonl")
+	if provenanceLog == nil || provenanceLog.Path() != path {
+		provenanceLog = provenance.Open(path)
+	}
+	return provenanceLog
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"go2exe/internal/diagnostics"
+)
+
+var (
+	winhttp                               = syscall.NewLazyDLL("winhttp.dll")
+	winHttpGetIEProxyConfigForCurrentUser = winhttp.NewProc("WinHttpGetIEProxyConfigForCurrentUser")
+	winHttpGetDefaultProxyConfiguration   = winhttp.NewProc("WinHttpGetDefaultProxyConfiguration")
+	globalFree                            = kernel32.NewProc("GlobalFree")
+	WINHTTP_ACCESS_TYPE_NAMED_PROXY       = 3
+)
+
+// WINHTTP_CURRENT_USER_IE_PROXY_CONFIG
+type ieProxyConfig struct {
+	autoDetect    int32
+	autoConfigURL *uint16
+	proxy         *uint16
+	proxyBypass   *uint16
+}
+
+// WINHTTP_PROXY_INFO
+type winhttpProxyInfo struct {
+	accessType  uint32
+	proxy       *uint16
+	proxyBypass *uint16
+}
+
+// 启动器自己的下载（http.DefaultTransport）按 childEnv 中的代理设置选择代理，与 uv 相同。
+// 在模拟场景和故障注入替换或包装 http.DefaultTransport 之前设置
+func init() {
+	http.DefaultTransport.(*http.Transport).Proxy = childEnv.Proxy
+}
+
+// 读取并释放 WinHTTP 分配的字符串
+func takeWinHTTPString(p *uint16) string {
+	if p == nil {
+		return ""
+	}
+	s := syscall.UTF16ToString((*[1 << 15]uint16)(unsafe.Pointer(p))[:])
+	globalFree.Call(uintptr(unsafe.Pointer(p)))
+	return s
+}
+
+// 读取 IE（系统“设置 → 代理”）和 WinHTTP 的代理配置，返回代理和例外列表的原始字符串
+func systemProxy() (proxy, bypass, source string) {
+	var ie ieProxyConfig
+	if r, _, _ := winHttpGetIEProxyConfigForCurrentUser.Call(uintptr(unsafe.Pointer(&ie))); r != 0 {
+		autoConfig := takeWinHTTPString(ie.autoConfigURL)
+		proxy, bypass = takeWinHTTPString(ie.proxy), takeWinHTTPString(ie.proxyBypass)
+		if proxy != "" {
+			return proxy, bypass, "系统代理设置"
+		}
+		if autoConfig != "" {
+			log.Printf("系统使用自动配置脚本 %s，启动器不解析 PAC，请在 %s 中显式配置代理", autoConfig, configFileName)
+		}
+	}
+
+	var info winhttpProxyInfo
+	if r, _, _ := winHttpGetDefaultProxyConfiguration.Call(uintptr(unsafe.Pointer(&info))); r != 0 {
+		proxy, bypass = takeWinHTTPString(info.proxy), takeWinHTTPString(info.proxyBypass)
+		if info.accessType == uint32(WINHTTP_ACCESS_TYPE_NAMED_PROXY) && proxy != "" {
+			return proxy, bypass, "WinHTTP"
+		}
+	}
+	return "", "", ""
+}
+
+// 把 Windows 代理字符串（"host:port" 或 "http=host:port;https=host:port"）转换为代理 URL
+func parseWindowsProxy(s string) (httpProxy, httpsProxy string) {
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ' ' }) {
+		scheme, addr, hasScheme := strings.Cut(part, "=")
+		if !hasScheme {
+			addr = part
+		}
+		if !strings.Contains(addr, "://") {
+			addr = "http://" + addr
+		}
+		switch {
+		case !hasScheme:
+			httpProxy, httpsProxy = addr, addr
+		case strings.EqualFold(scheme, "http"):
+			httpProxy = addr
+		case strings.EqualFold(scheme, "https"):
+			httpsProxy = addr
+		}
+	}
+	if httpsProxy == "" {
+		httpsProxy = httpProxy
+	}
+	return httpProxy, httpsProxy
+}
+
+// 把 Windows 代理例外列表转换为 NO_PROXY 格式
+func parseWindowsBypass(s string) string {
+	var hosts []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ' ' || r == ',' }) {
+		if item == "<local>" {
+			hosts = append(hosts, "localhost", "127.0.0.1")
+			continue
+		}
+		hosts = append(hosts, strings.TrimPrefix(item, "*"))
+	}
+	return strings.Join(hosts, ",")
+}
+
+// 确定代理设置并写入传给外部命令的环境变量（childEnv），uv 和应用都会读取 HTTP_PROXY/HTTPS_PROXY/NO_PROXY；
+// 启动器自己的下载通过 http.DefaultTransport 和 Installer.Proxy 使用同样的设置（见 childEnv.Proxy）。
+// 优先级：配置文件 > 已有的环境变量 > 系统代理（IE/WinHTTP）
+func applyProxy(cfg Config) {
+	httpProxy, httpsProxy, noProxy, source := "", "", cfg.Network.NoProxy, ""
+	switch {
+	case strings.EqualFold(cfg.Network.Proxy, "none"):
+		for _, key := range []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "http_proxy", "https_proxy", "all_proxy"} {
+			childEnv.Unset(key)
+		}
+		log.Printf("配置文件禁用了代理")
+		return
+	case cfg.Network.Proxy != "":
+		httpProxy, httpsProxy = parseWindowsProxy(cfg.Network.Proxy)
+		source = configFileName
+	case childEnv.Get("HTTP_PROXY") != "" || childEnv.Get("HTTPS_PROXY") != "":
+		log.Printf("使用环境变量中的代理: HTTP_PROXY=%s HTTPS_PROXY=%s",
+			diagnostics.RedactURL(childEnv.Get("HTTP_PROXY")), diagnostics.RedactURL(childEnv.Get("HTTPS_PROXY")))
+		return
+	default:
+		proxy, bypass, from := systemProxy()
+		if proxy == "" {
+			return
+		}
+		httpProxy, httpsProxy = parseWindowsProxy(proxy)
+		if noProxy == "" {
+			noProxy = parseWindowsBypass(bypass)
+		}
+		source = from
+	}
+
+	childEnv.Set("HTTP_PROXY", httpProxy)
+	childEnv.Set("HTTPS_PROXY", httpsProxy)
+	if noProxy != "" {
+		childEnv.Set("NO_PROXY", noProxy)
+	}
+	// 代理地址中可能有用户名和密码，不写入日志
+	log.Printf("使用%s中的代理: HTTP_PROXY=%s HTTPS_PROXY=%s NO_PROXY=%s",
+		source, diagnostics.RedactURL(httpProxy), diagnostics.RedactURL(httpsProxy), noProxy)
+}
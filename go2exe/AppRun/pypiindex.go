@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/mirror"
+)
+
+// 同步依赖和应用使用的 PyPI 镜像，由 applyIndex 确定
+var pypiMirror = mirror.Default
+
+// 镜像测速时每个请求的最长等待时间
+const indexProbeTimeout = 3 * time.Second
+
+// 按配置确定 PyPI 镜像：指定了镜像时直接使用；auto 时使用之前测速选出的镜像，
+// 还没有选过时测速并记下最快的一个。需在 applyProxy 之后调用，测速和 uv 一样经过代理。
+// 使用离线安装包时不访问镜像，也不测速
+func applyIndex(exeDir string, cfg NetworkConfig) {
+	m, ok, err := mirror.Parse(cfg.Index)
+	if err != nil {
+		log.Printf("%v，改为自动选择", err)
+	}
+	if dir := offlineWheelsDir(exeDir); dir != "" && !ok {
+		log.Printf("使用离线安装包目录 %s，不测试镜像速度", dir)
+		m, ok = mirror.Default, true
+	}
+	if !ok {
+		m = autoIndex()
+	}
+	pypiMirror = m
+	app.Index = m.URL
+	log.Printf("PyPI 镜像: %s（%s）", m.Name, m.URL)
+}
+
+// 自动选择的镜像，之前没有选出过时测速
+func autoIndex() mirror.Mirror {
+	state, _ := readCheckState()
+	if m, ok, _ := mirror.Parse(state.Index); ok && m.Name != "custom" {
+		return m
+	}
+
+	log.Printf("正在测试各 PyPI 镜像的速度")
+	addOutputText(i18n.T("正在选择最快的 PyPI 镜像..."))
+	results := mirror.Probe(context.Background(), nil, mirror.Builtin, indexProbeTimeout)
+	for _, r := range results {
+		if r.Err != nil {
+			log.Printf("镜像 %s 不可用: %v", r.Mirror.Name, r.Err)
+		} else {
+			log.Printf("镜像 %s 响应时间 %v", r.Mirror.Name, r.Latency.Round(time.Millisecond))
+		}
+	}
+	m, ok := mirror.Fastest(results)
+	if !ok {
+		// 可能暂时没有网络，不记录结果，下次启动再测
+		log.Printf("所有镜像都不可用，本次使用 %s", mirror.Default.Name)
+		return mirror.Default
+	}
+	addOutputText(i18n.T("使用 PyPI 镜像: %s", m.URL))
+	state, _ = readCheckState()
+	state.Index = m.Name
+	if err := writeCheckState(state); err != nil {
+		log.Printf("保存启动检查状态失败: %v", err)
+	}
+	return m
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go2exe/internal/i18n"
+)
+
+// 应用报告的启动结果：ready 时为 nil，startup_error 或就绪前退出时为错误
+var appReady = make(chan error, 1)
+
+// 应用启动失败时报告的错误
+type appStartupError struct {
+	message string
+	details string // 例如 Python 的 traceback
+}
+
+func (e *appStartupError) Error() string {
+	return e.message
+}
+
+// 注册应用启动握手的 IPC 动作：
+// 应用显示主窗口后发送 {"verb": "ready"}，启动失败时发送 {"verb": "startup_error", "args": [错误信息, 详细信息]}
+func handleAppReady() {
+	handleIPC("ready", func(msg ipcMessage) error {
+		log.Printf("Python 应用已就绪")
+		signalAppReady(nil)
+		return nil
+	})
+	handleIPC("startup_error", func(msg ipcMessage) error {
+		e := &appStartupError{message: "未知错误"}
+		if len(msg.Args) > 0 {
+			e.message = msg.Args[0]
+		}
+		if len(msg.Args) > 1 {
+			e.details = strings.Join(msg.Args[1:], "\n")
+		}
+		log.Printf("Python 应用报告启动失败: %s\n%s", e.message, e.details)
+		signalAppReady(e)
+		return nil
+	})
+}
+
+// 记录启动结果，只保留第一次
+func signalAppReady(err error) {
+	select {
+	case appReady <- err:
+	default:
+	}
+}
+
+// 清除上次启动的结果
+func resetAppReady() {
+	select {
+	case <-appReady:
+	default:
+	}
+}
+
+// 就绪握手的最长等待时间，0 表示不等待
+func readyTimeout() time.Duration {
+	return time.Duration(installConfig.ReadyTimeoutSeconds) * time.Second
+}
+
+// 等待应用报告就绪。应用报告启动失败或就绪前退出时返回错误；
+// 超时只记录日志，不支持握手的旧版本应用视为已启动
+func waitAppReady(timeout time.Duration) error {
+	if timeout <= 0 || !ipcRunning {
+		return nil
+	}
+	addOutputText(i18n.T("正在等待应用就绪..."))
+	select {
+	case err := <-appReady:
+		if err != nil {
+			return err
+		}
+		addOutputText(i18n.T("Python 应用已就绪"))
+		return nil
+	case <-time.After(timeout):
+		log.Printf("应用在 %v 内未报告就绪，视为已启动", timeout)
+		return nil
+	}
+}
+
+// 应用就绪前退出
+func appExitedEarly(err error) error {
+	if err == nil {
+		return fmt.Errorf("应用启动后立即退出")
+	}
+	return fmt.Errorf("应用启动后立即退出: %v", err)
+}
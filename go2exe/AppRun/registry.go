@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32           = syscall.NewLazyDLL("advapi32.dll")
+	regCreateKeyEx     = advapi32.NewProc("RegCreateKeyExW")
+	regSetValueEx      = advapi32.NewProc("RegSetValueExW")
+	regDeleteTree      = advapi32.NewProc("RegDeleteTreeW")
+	regDeleteKeyValue  = advapi32.NewProc("RegDeleteKeyValueW")
+	HKEY_CLASSES_ROOT  = uintptr(0x80000000)
+	HKEY_CURRENT_USER  = uintptr(0x80000001)
+	HKEY_LOCAL_MACHINE = uintptr(0x80000002)
+	REG_SZ             = 1
+	KEY_WRITE          = 0x20006
+)
+
+// 写入注册表字符串值，键不存在时自动创建；name 为空表示默认值
+func regSetString(root uintptr, path, name, value string) error {
+	pathPtr, _ := syscall.UTF16PtrFromString(path)
+	var key syscall.Handle
+	r, _, _ := regCreateKeyEx.Call(
+		root,
+		uintptr(unsafe.Pointer(pathPtr)),
+		0, 0, 0,
+		uintptr(KEY_WRITE),
+		0,
+		uintptr(unsafe.Pointer(&key)),
+		0,
+	)
+	if r != 0 {
+		return fmt.Errorf("创建注册表项 %s 失败: %v", path, syscall.Errno(r))
+	}
+	defer syscall.RegCloseKey(key)
+
+	var namePtr *uint16
+	if name != "" {
+		namePtr, _ = syscall.UTF16PtrFromString(name)
+	}
+	data, _ := syscall.UTF16FromString(value)
+	r, _, _ = regSetValueEx.Call(
+		uintptr(key),
+		uintptr(unsafe.Pointer(namePtr)),
+		0,
+		uintptr(REG_SZ),
+		uintptr(unsafe.Pointer(&data[0])),
+		uintptr(len(data)*2),
+	)
+	if r != 0 {
+		return fmt.Errorf("写入注册表值 %s\\%s 失败: %v", path, name, syscall.Errno(r))
+	}
+	return nil
+}
+
+// 读取注册表字符串值，name 为空表示默认值
+func regGetString(root uintptr, path, name string) (string, error) {
+	pathPtr, _ := syscall.UTF16PtrFromString(path)
+	var key syscall.Handle
+	if err := syscall.RegOpenKeyEx(syscall.Handle(root), pathPtr, 0, syscall.KEY_READ, &key); err != nil {
+		return "", err
+	}
+	defer syscall.RegCloseKey(key)
+
+	namePtr, _ := syscall.UTF16PtrFromString(name)
+	var typ, size uint32
+	if err := syscall.RegQueryValueEx(key, namePtr, nil, &typ, nil, &size); err != nil {
+		return "", err
+	}
+	if size == 0 {
+		return "", nil
+	}
+	buf := make([]uint16, size/2)
+	if err := syscall.RegQueryValueEx(key, namePtr, nil, &typ, (*byte)(unsafe.Pointer(&buf[0])), &size); err != nil {
+		return "", err
+	}
+	return syscall.UTF16ToString(buf), nil
+}
+
+// 读取注册表中的字符串或 DWORD 值，DWORD 转为十进制字符串
+func regGetValue(root uintptr, path, name string) (string, error) {
+	pathPtr, _ := syscall.UTF16PtrFromString(path)
+	var key syscall.Handle
+	if err := syscall.RegOpenKeyEx(syscall.Handle(root), pathPtr, 0, syscall.KEY_READ, &key); err != nil {
+		return "", err
+	}
+	defer syscall.RegCloseKey(key)
+
+	namePtr, _ := syscall.UTF16PtrFromString(name)
+	var typ, size uint32
+	if err := syscall.RegQueryValueEx(key, namePtr, nil, &typ, nil, &size); err != nil {
+		return "", err
+	}
+	if size == 0 {
+		return "", nil
+	}
+	buf := make([]byte, size)
+	if err := syscall.RegQueryValueEx(key, namePtr, nil, &typ, &buf[0], &size); err != nil {
+		return "", err
+	}
+	switch typ {
+	case syscall.REG_DWORD:
+		return fmt.Sprint(*(*uint32)(unsafe.Pointer(&buf[0]))), nil
+	case syscall.REG_SZ, syscall.REG_EXPAND_SZ:
+		return syscall.UTF16ToString(unsafe.Slice((*uint16)(unsafe.Pointer(&buf[0])), size/2)), nil
+	}
+	return "", fmt.Errorf("注册表值 %s\\%s 的类型 %d 不受支持", path, name, typ)
+}
+
+// 删除注册表项及其所有子项，不存在时不报错
+func regDeleteKeyTree(root uintptr, path string) error {
+	pathPtr, _ := syscall.UTF16PtrFromString(path)
+	r, _, _ := regDeleteTree.Call(root, uintptr(unsafe.Pointer(pathPtr)))
+	if r != 0 && syscall.Errno(r) != syscall.ERROR_FILE_NOT_FOUND {
+		return fmt.Errorf("删除注册表项 %s 失败: %v", path, syscall.Errno(r))
+	}
+	return nil
+}
+
+// 删除注册表值，name 为空表示默认值；键或值不存在时不报错
+func regDeleteValue(root uintptr, path, name string) error {
+	pathPtr, _ := syscall.UTF16PtrFromString(path)
+	var namePtr *uint16
+	if name != "" {
+		namePtr, _ = syscall.UTF16PtrFromString(name)
+	}
+	r, _, _ := regDeleteKeyValue.Call(root, uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(namePtr)))
+	if r != 0 && syscall.Errno(r) != syscall.ERROR_FILE_NOT_FOUND {
+		return fmt.Errorf("删除注册表值 %s\\%s 失败: %v", path, name, syscall.Errno(r))
+	}
+	return nil
+}
+
+var (
+	shell32            = syscall.NewLazyDLL("shell32.dll")
+	shChangeNotify     = shell32.NewProc("SHChangeNotify")
+	SHCNE_ASSOCCHANGED = 0x08000000
+)
+
+// 通知资源管理器文件关联已变化，立即刷新右键菜单和图标
+func notifyAssocChanged() {
+	shChangeNotify.Call(uintptr(SHCNE_ASSOCCHANGED), 0, 0, 0)
+}
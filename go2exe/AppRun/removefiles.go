@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"go2exe/internal/filelock"
+	"go2exe/internal/i18n"
+	"go2exe/internal/ui"
+)
+
+// 删除文件或目录。因文件被其他程序占用而失败时，列出占用的程序，
+// 让用户选择关闭它们后重试、自行关闭后重试或放弃
+func removeAllWithRetry(path string) error {
+	for {
+		err := os.RemoveAll(path)
+		if err == nil {
+			return nil
+		}
+		log.Printf("删除 %s 失败: %v", path, err)
+
+		owners, lockErr := filelock.Owners(filelock.RemainingFiles(path))
+		if lockErr != nil {
+			log.Printf("查询占用文件的程序失败: %v", lockErr)
+		}
+		if len(owners) == 0 {
+			// 找不到占用者（权限问题等），只提供重试
+			if !ui.RetryBox(i18n.T("删除失败"), i18n.T("无法删除 %s：\n%v\n\n点击“重试”再试一次，或点击“取消”跳过。", path, err)) {
+				return err
+			}
+			continue
+		}
+
+		// 资源管理器、系统关键进程和服务不自动关闭，列出来让用户手动处理
+		var names, closable, manual []string
+		for _, o := range owners {
+			names = append(names, "  "+o.String())
+			if o.Manual {
+				manual = append(manual, "  "+o.String())
+			} else {
+				closable = append(closable, "  "+o.String())
+			}
+		}
+		log.Printf("%s 被以下程序占用: %s", path, strings.Join(names, "; "))
+		addOutputText(i18n.T("%s 被以下程序占用:\n%s", path, strings.Join(names, "\n")))
+		if len(closable) == 0 {
+			if !ui.RetryBox(i18n.T("文件被占用"), i18n.T("无法删除 %s，以下程序正在使用其中的文件，需要您手动关闭：\n\n%s\n\n关闭后点击“重试”，或点击“取消”跳过。", path, strings.Join(manual, "\n"))) {
+				return fmt.Errorf("%v（文件被 %s 占用）", err, owners[0].Name)
+			}
+			continue
+		}
+		msg := i18n.T("无法删除 %s，以下程序正在使用其中的文件：\n\n%s\n\n"+
+			"点击“是”关闭这些程序后重试（未保存的内容可能丢失）；\n点击“否”在您手动关闭它们后重试；\n点击“取消”跳过。",
+			path, strings.Join(closable, "\n"))
+		if len(manual) > 0 {
+			msg += "\n\n" + i18n.T("以下程序不会自动关闭，请手动关闭：\n%s", strings.Join(manual, "\n"))
+		}
+		switch ui.YesNoCancelBox(i18n.T("文件被占用"), msg) {
+		case ui.IDYES:
+			for _, o := range owners {
+				if o.Manual {
+					continue
+				}
+				if err := filelock.Close(o.PID, 5*time.Second); err != nil {
+					log.Printf("关闭 %s 失败: %v", o, err)
+					addOutputText(i18n.T("关闭 %s 失败: %v", o, err))
+				} else {
+					log.Printf("已关闭 %s", o)
+					addOutputText(i18n.T("已关闭 %s", o))
+				}
+			}
+		case ui.IDNO:
+		default:
+			return fmt.Errorf("%v（文件被 %s 占用）", err, owners[0].Name)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go2exe/internal/appconfig"
+	"go2exe/internal/i18n"
+	"go2exe/internal/ui"
+)
+
+// 用配置文件的 TOML 解析读取 pyproject.toml 中 [project] dependencies 的包名。
+// 文件中有解析不了的写法或没有依赖时返回错误，调用方据此跳过清理，不能把空列表传给 uv cache clean（会清空整个缓存）
+func projectDependencies(projectDir string) ([]string, error) {
+	values, err := appconfig.ParseFile(filepath.Join(projectDir, "pyproject.toml"))
+	if err != nil {
+		return nil, err
+	}
+	specs, _ := values["project.dependencies"].Value.([]string)
+	var deps []string
+	for _, spec := range specs {
+		// "edge-tts>=7.0.2" -> edge-tts
+		name := strings.FieldsFunc(spec, func(r rune) bool {
+			return strings.ContainsRune("<>=!~;[@ ", r)
+		})
+		if len(name) > 0 {
+			deps = append(deps, name[0])
+		}
+	}
+	if len(deps) == 0 {
+		return nil, fmt.Errorf("pyproject.toml 中没有 [project] dependencies")
+	}
+	return deps, nil
+}
+
+// 修复环境：删除 .venv，清理项目依赖的 uv 缓存，再重新执行 uv sync
+func runRepair(exeDir string) error {
+	console.Open()
+	defer console.Close()
+
+	// 修复总是重新创建程序目录中的虚拟环境，不修改用户自己的环境
+	forgetAdoptedVenv()
+	projectDir := filepath.Join(exeDir, "python")
+	fail := func(err error) error {
+		log.Printf("修复环境失败: %v", err)
+		addOutputText(i18n.T("修复环境失败: %v", err))
+		ui.ErrorBox(i18n.T("修复失败"), i18n.T("修复环境失败: %v\n\n详细信息请查看 app.log。", err))
+		return err
+	}
+
+	// 先确认依赖能够重新安装，否则删除虚拟环境后应用就无法启动了
+	inst := newInstaller(exeDir)
+	if err := precheckSync(inst); err != nil {
+		return fail(err)
+	}
+
+	log.Printf("正在删除虚拟环境...")
+	addOutputText(i18n.T("正在删除虚拟环境..."))
+	if err := removeAllWithRetry(filepath.Join(projectDir, ".venv")); err != nil {
+		return fail(fmt.Errorf("删除虚拟环境失败: %v", err))
+	}
+
+	// 只清理项目依赖的缓存，uv 缓存可能还被其他项目使用；读不出依赖时跳过清理，直接重新同步
+	if deps, err := projectDependencies(projectDir); err != nil {
+		log.Printf("无法读取项目依赖，跳过清理缓存: %v", err)
+		addOutputText(i18n.T("无法读取项目依赖，跳过清理缓存: %v", err))
+	} else {
+		log.Printf("正在清理依赖缓存: %v", deps)
+		addOutputText(i18n.T("正在清理依赖缓存: %s", strings.Join(deps, ", ")))
+		if err := runLoggedCommand("uv", append([]string{"cache", "clean"}, deps...)...); err != nil {
+			// 缓存清理失败不影响重新同步
+			log.Printf("清理缓存失败: %v", err)
+			addOutputText(i18n.T("清理缓存失败: %v", err))
+		}
+	}
+
+	if err := syncVenv(exeDir, inst); err != nil {
+		return fail(err)
+	}
+
+	log.Printf("环境修复完成")
+	addOutputText(i18n.T("环境修复完成！"))
+	time.Sleep(2 * time.Second)
+	ui.MessageBox(i18n.T("修复完成"), i18n.T("SpeakMyBook 的运行环境已重新安装，可以正常启动了。"))
+	return nil
+}
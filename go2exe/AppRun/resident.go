@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+
+	"go2exe/internal/launch"
+)
+
+var (
+	postThreadMessage  = user32.NewProc("PostThreadMessageW")
+	getCurrentThreadId = kernel32.NewProc("GetCurrentThreadId")
+	WM_QUIT            = 0x0012
+	residentThreadID   atomic.Uintptr // 常驻消息循环所在的系统线程，其他 goroutine 通过它请求退出
+	residentKeepAlive  atomic.Bool    // 应用退出后是否继续常驻，托盘和 IPC 的 goroutine 会修改它
+)
+
+// Windows 消息结构
+type winMsg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	ptX     int32
+	ptY     int32
+}
+
+// 应用启动后启动器是否需要常驻（快捷键、托盘图标、右键菜单和文件关联的转发、跳转列表、控制接口、局域网共享、健康检查接口等功能需要）
+func needResident(cfg Config) bool {
+	return cfg.Tray.Hotkey != "" || cfg.Tray.Icon || cfg.Shell.ContextMenu || cfg.Shell.FileAssociations == "yes" ||
+		cfg.Shell.JumpList || cfg.Control.Enabled || cfg.Install.PeerShare || cfg.Health.Enabled
+}
+
+// 注册常驻模式下的 IPC 动作，IPC 服务需已启动
+func startResident(cfg Config, exePath string) {
+	// 只有快捷键、托盘图标、控制接口和局域网共享需要在应用退出后继续响应
+	residentKeepAlive.Store(cfg.Tray.Hotkey != "" || cfg.Tray.Icon || cfg.Control.Enabled || cfg.Install.PeerShare)
+
+	handleIPC("quit", func(msg ipcMessage) error {
+		quitResident()
+		return nil
+	})
+	// 维护操作前请求应用和启动器都退出：应用已连接 IPC 时由应用自行退出（可提示保存），否则关闭应用窗口
+	handleIPC("shutdown", func(msg ipcMessage) error {
+		residentKeepAlive.Store(false)
+		if !isAppRunning() {
+			quitResident()
+			return nil
+		}
+		if isAppConnected() {
+			sendToApp("exit")
+		} else {
+			closeAppWindow()
+		}
+		return nil
+	})
+	// 其他启动器实例转发的书籍路径，可附带 voice 选项和 -- 之后的应用参数
+	handleIPC("open", func(msg ipcMessage) error {
+		if !isAppRunning() {
+			return startPythonApp(append(launch.AppArgs(msg.Args, msg.Options["voice"]), msg.AppArgs...))
+		}
+		if len(msg.AppArgs) > 0 {
+			// 应用的命令行参数只在启动时有效
+			log.Printf("应用已在运行，忽略转发的应用参数: %q", msg.AppArgs)
+		}
+		sendToApp("activate")
+		if len(msg.Args) > 0 {
+			sendMessageToApp(ipcMessage{Verb: "open", Args: msg.Args, Options: msg.Options})
+		}
+		return nil
+	})
+	// 应用请求为某本书创建桌面快捷方式，可附带 voice 选项
+	handleIPC("create_shortcut", func(msg ipcMessage) error {
+		if len(msg.Args) == 0 {
+			return fmt.Errorf("缺少书籍路径")
+		}
+		_, err := createBookShortcut(exePath, msg.Args[0], msg.Options["voice"])
+		return err
+	})
+	// 应用打开一本书后通知启动器，用于更新跳转列表
+	handleIPC("book_opened", func(msg ipcMessage) error {
+		if !cfg.Shell.JumpList || len(msg.Args) == 0 {
+			return nil
+		}
+		return addRecentBook(exePath, msg.Args[0])
+	})
+	// 环境被手动修改后，其他启动器实例或部署工具要求下次启动执行全部检查
+	handleIPC("invalidate", func(msg ipcMessage) error {
+		return invalidateLaunchChecks()
+	})
+	go watchScreenReader()
+	if cfg.Tray.Icon {
+		startTray(exePath)
+	}
+}
+
+// 常驻消息循环，直到收到退出请求
+func runResident(cfg Config) {
+	// 快捷键注册和消息循环必须在同一个系统线程上
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	tid, _, _ := getCurrentThreadId.Call()
+	residentThreadID.Store(tid)
+	if !residentKeepAlive.Load() && !isAppRunning() {
+		return
+	}
+
+	if cfg.Tray.Hotkey != "" {
+		if err := registerGlobalHotkey(cfg.Tray.Hotkey); err != nil {
+			log.Printf("注册全局快捷键 %s 失败: %v", cfg.Tray.Hotkey, err)
+		} else {
+			defer unregisterGlobalHotkey()
+		}
+	}
+
+	log.Printf("启动器进入常驻模式")
+	var msg winMsg
+	for {
+		r, _, _ := getMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+		if int32(r) <= 0 {
+			break
+		}
+		if msg.message == uint32(WM_HOTKEY) && msg.hwnd == 0 {
+			onHotkey(cfg.Tray.HotkeyAction)
+			continue
+		}
+		translateMessage.Call(uintptr(unsafe.Pointer(&msg)))
+		dispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
+	}
+	closeTray()
+	log.Printf("启动器退出常驻模式")
+}
+
+// 应用退出后，没有需要继续响应的功能时结束常驻
+func onAppExited() {
+	if residentThreadID.Load() != 0 && !residentKeepAlive.Load() {
+		quitResident()
+	}
+}
+
+// 从任意 goroutine 请求退出常驻消息循环
+func quitResident() {
+	postThreadMessage.Call(residentThreadID.Load(), uintptr(WM_QUIT), 0, 0)
+}
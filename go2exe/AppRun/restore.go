@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+
+	"go2exe/internal/i18n"
+)
+
+// 还原卡（Deep Freeze 等）或系统还原把安装位置恢复到安装之前，而状态文件在没有保护的磁盘上时，
+// 状态文件仍记录已安装，uv、Python 或虚拟环境却已不存在。返回这些不存在的文件，没有记录或都在时返回空
+func restoredArtifacts(exeDir string, state checkState) []string {
+	var paths []string
+	if state.UVPath != "" {
+		paths = append(paths, state.UVPath)
+	}
+	if state.PythonPath != "" {
+		paths = append(paths, state.PythonPath)
+	}
+	if state.SyncHash != "" {
+		paths = append(paths, appPython(exeDir))
+	}
+	var missing []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
+// 清除状态文件中失效的检测和同步记录（安装包指纹保留），本次启动按首次安装从程序目录中的安装文件和离线安装包
+// 重新准备环境，但不显示安装向导和首次安装的提示，用户只看到进度
+func forgetRestoredEnvironment(missing []string) {
+	log.Printf("上次记录的 %s 已不存在，磁盘可能被还原，重新准备运行环境", strings.Join(missing, "、"))
+	addOutputText(i18n.T("运行环境已被磁盘还原清除，正在重新准备..."))
+	state, _ := readCheckState()
+	state.UVPath, state.PythonPath, state.PythonRequest = "", "", ""
+	state.Stamp, state.SyncHash = "", ""
+	if err := writeCheckState(state); err != nil {
+		log.Printf("保存启动检查状态失败: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"go2exe/internal/exitcode"
+	"go2exe/internal/health"
+	"go2exe/internal/runner"
+)
+
+// 退出码对应的步骤，写入结果文件，供脚本判断
+var exitSteps = map[int]string{
+	exitcode.Failed:        "launcher",
+	exitcode.Cancelled:     "cancelled",
+	exitcode.Check:         "check",
+	exitcode.Preflight:     "preflight",
+	exitcode.Verify:        "verify",
+	exitcode.Elevation:     "elevation",
+	exitcode.UVInstall:     "install_uv",
+	exitcode.PythonInstall: "install_python",
+	exitcode.Sync:          "sync",
+	exitcode.AppStart:      "start_app",
+	exitcode.Uninstall:     "uninstall",
+	exitcode.Repair:        "repair",
+	exitcode.Config:        "config",
+	exitcode.Golden:        "golden_image",
+}
+
+// 带退出码的错误
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// 给错误附上退出码，err 为 nil 时返回 nil
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// 错误对应的退出码：用户取消的为 exitcode.Cancelled，没有附上退出码的错误为 exitcode.Failed
+func exitCode(err error) int {
+	if err == nil {
+		return exitcode.OK
+	}
+	if errors.Is(err, runner.ErrCanceled) {
+		return exitcode.Cancelled
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return exitcode.Failed
+}
+
+// 写入结果文件的内容
+type launchResult struct {
+	ExitCode   int       `json:"exit_code"`
+	Step       string    `json:"step,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Plan       []string  `json:"plan,omitempty"`   // --dry-run 列出的操作
+	Config     []string  `json:"config,omitempty"` // config validate 发现的问题或 config print-effective 列出的配置
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+var (
+	resultFile string // --result-file 指定的路径，为空时不写结果文件
+	startedAt  = time.Now()
+)
+
+// 记录本次运行的结果并返回退出码。指定了 --result-file 时把结果写成 JSON
+func finish(err error) int {
+	code := exitCode(err)
+	if err != nil {
+		log.Printf("启动器退出码 %d: %v", code, err)
+		appHealth.Fail(health.StateFailed, err)
+	}
+	stopTimeline(err)
+	if resultFile == "" {
+		return code
+	}
+	result := launchResult{
+		ExitCode:   code,
+		Step:       exitSteps[code],
+		Plan:       dryRunPlan,
+		Config:     configReport,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	data, _ := json.MarshalIndent(result, "", "  ")
+	if err := os.WriteFile(resultFile, data, 0644); err != nil {
+		log.Printf("写入结果文件失败: %v", err)
+	}
+	return code
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+
+	"go2exe/internal/appupdate"
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+// 更新后连续启动失败多少次时建议回退
+const rollbackAfterFailures = 2
+
+// 备份目录中上一个版本的版本号，没有备份时返回 false
+func backupVersion(exeDir string) (string, bool) {
+	version, err := appupdate.ProjectVersion(backupDir(exeDir))
+	return version, err == nil
+}
+
+// 应用成功启动或换了版本后清零连续启动失败的次数
+func clearStartupFailures() {
+	state, _ := readCheckState()
+	if state.StartFailures == 0 {
+		return
+	}
+	state.StartFailures = 0
+	if err := writeCheckState(state); err != nil {
+		log.Printf("保存启动检查状态失败: %v", err)
+	}
+}
+
+// 记下一次启动失败（应用报告启动失败或就绪前退出）。更新后连续失败、并且有上一个版本时询问是否回退，
+// 回退成功时返回 true，调用方可以再启动一次
+func offerRollback(exeDir string, startErr error) bool {
+	state, _ := readCheckState()
+	state.StartFailures++
+	if err := writeCheckState(state); err != nil {
+		log.Printf("保存启动检查状态失败: %v", err)
+	}
+	previous, ok := backupVersion(exeDir)
+	if !ok || state.StartFailures < rollbackAfterFailures || resultFile != "" {
+		return false
+	}
+	current, _ := appupdate.ProjectVersion(filepath.Join(exeDir, "python"))
+	log.Printf("%s 已连续 %d 次启动失败，可以回退到 %s", current, state.StartFailures, previous)
+	if !ui.ConfirmBox(i18n.T("SpeakMyBook 无法启动"), i18n.T("SpeakMyBook %s 已连续 %d 次启动失败：%v\n\n"+
+		"这可能是更新引起的。是否回退到上一个版本 %s？", current, state.StartFailures, startErr, previous)) {
+		return false
+	}
+	return rollbackApp(exeDir) == nil
+}
+
+// 用备份目录中的上一个版本替换 python 目录并重新同步依赖。应用运行时需先关闭应用
+func rollbackApp(exeDir string) error {
+	previous, _ := backupVersion(exeDir)
+	current, _ := appupdate.ProjectVersion(filepath.Join(exeDir, "python"))
+	log.Printf("正在回退到 %s", previous)
+	addOutputText(i18n.T("正在回退到 SpeakMyBook %s...", previous))
+	err := appupdate.Rollback(filepath.Join(exeDir, "python"), backupDir(exeDir), projectKeep())
+	if err != nil {
+		log.Printf("回退失败: %v", err)
+		ui.ErrorBox(i18n.T("回退失败"), i18n.T("无法回退到上一个版本: %v\n\n详细信息请查看 app.log。", err))
+		return err
+	}
+	checks.fingerprint = packageFingerprint(exeDir)
+	clearStartupFailures()
+	// 记下回退掉的版本，之后检查更新时不再提示它
+	if current != "" {
+		state, _ := readCheckState()
+		state.RolledBack = current
+		if err := writeCheckState(state); err != nil {
+			log.Printf("保存启动检查状态失败: %v", err)
+		}
+	}
+	log.Printf("已回退到 %s", previous)
+	addOutputText(i18n.T("已回退到 %s", previous))
+
+	// 上一个版本的依赖按它自己的 uv.lock 重新同步
+	applyGPU(exeDir, installConfig.GPU)
+	inst := newInstaller(exeDir)
+	err = syncVenv(exeDir, inst)
+	if err != nil && !errors.Is(err, runner.ErrCanceled) {
+		ui.ErrorBox(i18n.T("回退失败"), i18n.T("同步依赖失败: %v\n\n详细信息请查看 app.log。", err))
+	}
+	return err
+}
+
+// 托盘菜单中的回退：询问后关闭应用，回退并重新启动应用
+func rollbackFromTray(exeDir string) error {
+	previous, ok := backupVersion(exeDir)
+	if !ok {
+		return nil
+	}
+	if !ui.ConfirmBox(i18n.T("回退到上一个版本"), i18n.T("将回退到 SpeakMyBook %s，需要关闭 SpeakMyBook 并重新同步依赖。\n\n是否继续？", previous)) {
+		return nil
+	}
+	if err := closeApp(); err != nil {
+		return err
+	}
+	console.Open()
+	err := rollbackApp(exeDir)
+	console.Close()
+	if errors.Is(err, runner.ErrCanceled) {
+		return err
+	}
+	return startPythonApp(nil)
+}
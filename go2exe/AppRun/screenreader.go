@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+	"time"
+	"unsafe"
+)
+
+var (
+	systemParametersInfo = user32.NewProc("SystemParametersInfoW")
+	SPI_GETSCREENREADER  = 0x0046
+)
+
+// 常驻时检查读屏软件状态变化的间隔
+const screenReaderPollInterval = 2 * time.Second
+
+// 是否有讲述人或其他读屏软件正在运行（读屏软件启动时会设置 SPI_SETSCREENREADER）
+func screenReaderActive() bool {
+	var active int32
+	r, _, err := systemParametersInfo.Call(uintptr(SPI_GETSCREENREADER), 0, uintptr(unsafe.Pointer(&active)), 0)
+	if r == 0 {
+		log.Printf("检测读屏软件失败: %v", err)
+		return false
+	}
+	return active != 0
+}
+
+// 告诉应用是否有读屏软件。有读屏软件时应用减少自己发起的朗读，避免与读屏软件同时发声；
+// 启动器自己不通过 SAPI 发声，不需要调整
+func screenReaderEnv(active bool) string {
+	if active {
+		return "SPEAKMYBOOK_SCREEN_READER=1"
+	}
+	return "SPEAKMYBOOK_SCREEN_READER=0"
+}
+
+// 常驻期间读屏软件开启或关闭时通知已连接的应用
+func watchScreenReader() {
+	active := screenReaderActive()
+	for range time.Tick(screenReaderPollInterval) {
+		now := screenReaderActive()
+		if now == active {
+			continue
+		}
+		active = now
+		log.Printf("读屏软件状态变化: %v", active)
+		if isAppConnected() {
+			state := "off"
+			if active {
+				state = "on"
+			}
+			sendToApp("screen_reader", state)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+	"go2exe/internal/models"
+	"go2exe/internal/provenance"
+)
+
+// 检查共享目录能否访问的最长等待时间。服务器关机时访问 UNC 路径可能要等几十秒，超时视为无法访问
+const seedTimeout = 5 * time.Second
+
+var (
+	seedMu      sync.Mutex
+	seedChecked = map[string]bool{} // 已检查过的共享目录及能否访问，每个目录只检查一次
+)
+
+// 配置的局域网预热目录，没有配置或无法访问时返回空
+func seedDir() string {
+	dir := installConfig.SeedDir
+	if dir == "" {
+		return ""
+	}
+	seedMu.Lock()
+	defer seedMu.Unlock()
+	ok, checked := seedChecked[dir]
+	if !checked {
+		result := make(chan error, 1)
+		go func() {
+			_, err := os.ReadDir(dir)
+			result <- err
+		}()
+		select {
+		case err := <-result:
+			ok = err == nil
+			if err != nil {
+				log.Printf("无法访问预热目录 %s，照常从网络下载: %v", dir, err)
+			}
+		case <-time.After(seedTimeout):
+			log.Printf("访问预热目录 %s 超时，照常从网络下载", dir)
+		}
+		seedChecked[dir] = ok
+	}
+	if !ok {
+		return ""
+	}
+	return dir
+}
+
+// 同步依赖使用的离线安装包目录：程序目录中的 wheels，没有时使用预热目录中包含 uv.lock 所有包的 wheels
+func offlineWheelsDir(exeDir string) string {
+	if dir := install.OfflineWheelsDir(exeDir); dir != "" {
+		return dir
+	}
+	seed := seedDir()
+	if seed == "" {
+		return ""
+	}
+	dir := install.OfflineWheelsDir(seed)
+	if dir == "" {
+		return ""
+	}
+	missing, err := install.MissingWheels(filepath.Join(exeDir, "python", "uv.lock"), dir, pythonArch())
+	if err != nil || len(missing) > 0 {
+		log.Printf("预热目录的离线安装包不完整，不使用（缺少 %d 个包）: %v", len(missing), err)
+		return ""
+	}
+	return dir
+}
+
+// 同步依赖前把预热目录中的 uv 缓存复制到本机，uv 从缓存安装已有的包，不再下载
+func seedUVCache() {
+	seed := seedDir()
+	if seed == "" {
+		return
+	}
+	src := filepath.Join(seed, install.SeedUVCacheDir)
+	if _, err := os.Stat(src); err != nil {
+		return
+	}
+	addOutputText(i18n.T("正在从预热目录复制 uv 缓存..."))
+	n, err := install.SeedCache(src, install.UVCacheDir())
+	if err != nil {
+		// 已复制的文件仍然有效，没有复制的包由 uv 下载
+		log.Printf("从 %s 复制 uv 缓存失败（已复制 %d 个文件）: %v", src, n, err)
+		return
+	}
+	log.Printf("从 %s 复制了 %d 个 uv 缓存文件", src, n)
+	provenanceRecords().Add(provenance.Record{Kind: provenance.KindUVCache, Name: "uv-cache", Source: provenance.SourceSeed, URL: src,
+		Detail: fmt.Sprintf("%d 个文件", n)})
+}
+
+// 下载模型文件前从预热目录复制本机缺少的文件，复制失败或校验不通过的文件仍然从网络下载
+func seedModels(dir string, files []models.File) {
+	seed := seedDir()
+	if seed == "" {
+		return
+	}
+	src := filepath.Join(seed, install.SeedModelsDir)
+	present := map[string]bool{}
+	for _, f := range files {
+		present[f.Name] = true
+	}
+	for _, f := range models.Missing(src, files) {
+		delete(present, f.Name)
+	}
+	for _, f := range models.Missing(dir, files) {
+		if !present[f.Name] {
+			continue
+		}
+		addOutputText(i18n.T("正在从预热目录复制 %s...", f.Path))
+		if err := models.CopyFrom(src, dir, f); err != nil {
+			log.Printf("从预热目录复制 %s 失败，改为下载: %v", f.Path, err)
+			continue
+		}
+		log.Printf("已从预热目录复制 %s", f.Path)
+		provenanceRecords().Add(provenance.Record{Kind: provenance.KindModel, Name: f.Path, Source: provenance.SourceSeed,
+			URL: f.Local(src), SHA256: f.SHA256})
+	}
+}
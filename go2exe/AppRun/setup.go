@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"go2exe/internal/envcheck"
+	"go2exe/internal/exitcode"
+	"go2exe/internal/health"
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+	"go2exe/internal/preflight"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+// 首次安装（Python、虚拟环境和临时文件）大约需要的磁盘空间（MB），另外还要保留安装时的最低剩余空间
+const installSpaceMB = 300
+
+// 环境安装选项
+type setupOptions struct {
+	allowElevate bool // 当前用户无权写入安装目录时，只把安装这一步以管理员身份执行
+	unattended   bool // 无人值守（远程控制接口调用）：不显示消息框和进度窗口
+	wizard       bool // 首次运行时显示安装向导（许可协议、安装位置和安装进度）
+	skipUV       bool // 按启动检查配置跳过 uv 检查，视为已安装
+	skipPython   bool // 按启动检查配置跳过 Python 检查，视为已安装
+	restore      bool // 磁盘被还原后重新准备环境：用户已经安装过，不再提示首次安装
+}
+
+// 检查 uv 和所需的 Python，缺少时使用随程序分发的文件安装，返回本次是否执行了安装
+func ensureEnvironment(exeDir string, inst *install.Installer, opts setupOptions) (bool, error) {
+	check := newChecker()
+
+	uvInstalled, pythonInstalled, err := detectEnvironment(check, opts)
+	if err != nil {
+		return false, withExitCode(exitcode.Check, err)
+	}
+	if uvInstalled && pythonInstalled {
+		log.Printf("uv 和 Python %s 已安装，跳过安装步骤", pythonVersion())
+		addOutputText(i18n.T("uv 和 Python %s 已安装，跳过安装步骤", pythonVersion()))
+		return false, nil
+	}
+	appHealth.Set(health.StateInstalling, "")
+	inst.Unattended = opts.unattended
+
+	if !uvInstalled && opts.wizard {
+		if !runSetupWizard(exeDir) {
+			return false, runner.ErrCanceled
+		}
+	} else if !uvInstalled && !opts.unattended && !opts.restore {
+		// 首先弹出一个简单的消息框告知用户
+		ui.MessageBox(i18n.T("环境安装"), i18n.T("即将开始安装必要的环境组件，请稍候...\n\n这个过程只在首次运行时执行，可能需要几分钟。"))
+	}
+	// 初始化控制台窗口，启动应用后关闭
+	if !opts.unattended {
+		console.Open()
+	}
+
+	// 在运行任何安装程序之前检查环境，可以提权时写入权限交给下面的提权处理
+	targets := installTargets(exeDir, !uvInstalled)
+	canElevate := opts.allowElevate && !isElevated()
+	if problems := preflight.Run(preflight.Options{
+		Runner:     cmdRunner,
+		Dirs:       append([]string{inst.TempBase()}, targets...),
+		CheckWrite: !canElevate,
+		MinFreeMB:  installSpaceMB + inst.MinFreeSpaceMB,
+		PathRoots:  targets[:2],
+		PowerShell: !uvInstalled,
+		Timeout:    checkTimeout(),
+	}); len(problems) > 0 {
+		var lines []string
+		for _, p := range problems {
+			lines = append(lines, p.String())
+			addOutputText(p.String())
+		}
+		if !opts.unattended {
+			ui.ErrorBox(i18n.T("无法开始安装"), i18n.T("安装前检查发现以下问题：")+"\n\n"+strings.Join(lines, "\n\n"))
+		}
+		return true, withExitCode(exitcode.Preflight, fmt.Errorf("安装前检查未通过: %s", strings.Join(lines, "; ")))
+	}
+
+	// 没有写入权限时以管理员身份执行安装，完成后回到普通用户继续启动应用
+	if canElevate {
+		if dir, ok := writableDirs(targets); !ok {
+			log.Printf("当前用户无权写入 %s，请求以管理员身份安装", dir)
+			addOutputText(i18n.T("当前用户无权写入 %s，需要以管理员身份安装...", dir))
+			if err := runElevatedInstall(exeDir); err != nil {
+				log.Printf("以管理员身份安装失败: %v", err)
+				addOutputText(i18n.T("以管理员身份安装失败: %v", err))
+				return true, err // 退出码由 runElevatedInstall 确定
+			}
+			log.Printf("以管理员身份安装完成")
+			addOutputText(i18n.T("以管理员身份安装完成"))
+			// 提权进程安装 uv 后修改了 PATH，本进程需要重新读取
+			envcheck.RefreshPath(childEnv)
+			return true, nil
+		}
+	}
+
+	// 如果未安装uv，则安装
+	if !uvInstalled {
+		// 内置的安装文件随程序一起签名，只校验随程序分发的 uv 目录
+		if !install.HasExternalUV(exeDir) {
+			log.Printf("未找到 uv 目录，将使用内置的安装文件")
+		} else if err := install.VerifyArtifacts(exeDir, "uv"); err != nil {
+			log.Printf("uv 安装文件校验失败: %v", err)
+			addOutputText(i18n.T("uv 安装文件校验失败: %v", err))
+			if !opts.unattended {
+				ui.ErrorBox(i18n.T("安装文件校验失败"), i18n.T("%v\n\n请重新下载完整的安装包后再试。", err))
+			}
+			return true, withExitCode(exitcode.Verify, err)
+		}
+		// 安装 uv 的同时校验 Python 安装文件，轮到安装 Python 时通常已经校验完
+		if !pythonInstalled {
+			inst.Prefetch(verifyPythonTask, verifyPython(exeDir))
+		}
+		log.Printf("正在安装uv...")
+		addOutputText(i18n.T("正在安装uv..."))
+		// 安装向导可能修改了 uv 的安装位置
+		inst.UVInstallDir = installConfig.UVDir
+		if err := inst.RunStep("安装 uv", inst.InstallUV); err != nil {
+			log.Printf("安装uv失败: %v", err)
+			addOutputText(i18n.T("安装uv失败: %v", err))
+			return true, withExitCode(exitcode.UVInstall, err)
+		}
+		log.Printf("uv安装完成")
+		addOutputText(i18n.T("uv安装完成"))
+
+		// 重新检查uv安装状态，安装脚本修改的 PATH 不会自动反映到本进程
+		if !locateUV(check, inst.UVDir) {
+			log.Printf("安装后仍无法检测到uv，请检查安装过程")
+			addOutputText(i18n.T("安装后仍无法检测到uv，请检查安装过程"))
+			return true, withExitCode(exitcode.UVInstall, fmt.Errorf("安装后仍无法检测到uv"))
+		}
+	}
+
+	// 如果未安装所需的 Python，则安装
+	if !pythonInstalled {
+		if err := inst.Await(verifyPythonTask, verifyPython(exeDir)); err != nil {
+			log.Printf("Python 安装文件校验失败: %v", err)
+			addOutputText(i18n.T("Python 安装文件校验失败: %v", err))
+			if !opts.unattended {
+				ui.ErrorBox(i18n.T("安装文件校验失败"), i18n.T("%v\n\n请重新下载完整的安装包后再试。", err))
+			}
+			return true, withExitCode(exitcode.Verify, err)
+		}
+		// 安装 Python 的同时预热镜像上各依赖的索引页，之后的 uv sync 解析依赖更快
+		inst.Prefetch("预热 PyPI 索引", inst.WarmIndex)
+		log.Printf("正在安装Python %s...", pythonVersion())
+		addOutputText(i18n.T("正在安装Python %s...", pythonVersion()))
+		if err := inst.RunStep("安装 Python", inst.InstallPython); err != nil {
+			log.Printf("安装Python %s失败: %v", pythonVersion(), err)
+			addOutputText(i18n.T("安装Python %s失败: %v", pythonVersion(), err))
+			return true, withExitCode(exitcode.PythonInstall, err)
+		}
+		log.Printf("Python %s安装完成", pythonVersion())
+		addOutputText(i18n.T("Python %s安装完成", pythonVersion()))
+	}
+	return true, nil
+}
+
+// 安装 uv 时在后台校验 Python 安装文件的预取任务
+const verifyPythonTask = "校验 Python 安装文件"
+
+// 校验 Python 安装文件，用作预取任务
+func verifyPython(exeDir string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return install.VerifyArtifacts(exeDir, pythonArtifacts())
+	}
+}
+
+// 从注册表刷新 PATH 后重新检查 uv，仍找不到时按绝对路径查找 uv.exe 并把它所在的目录加入 PATH。
+// extraDirs 为安装脚本报告的安装目录等额外的查找位置
+func locateUV(check envcheck.Checker, extraDirs ...string) bool {
+	envcheck.RefreshPath(childEnv)
+	if ok, _ := check.UVInstalled(); ok {
+		return true
+	}
+	// 配置的安装位置只通过 childEnv 传给安装脚本，不在本进程的 UV_INSTALL_DIR 中，这里明确查找
+	path, ok := envcheck.FindUV(childEnv, append(extraDirs, installConfig.UVDir)...)
+	if !ok {
+		return false
+	}
+	log.Printf("在 %s 找到 uv", path)
+	envcheck.AddToPath(childEnv, filepath.Dir(path))
+	ok, _ = check.UVInstalled()
+	return ok
+}
+
+// 用户点击进度窗口的“取消”按钮：确认后结束正在执行的命令，后续步骤不再执行
+func confirmCancel() {
+	if !ui.ConfirmBox(i18n.T("取消"), i18n.T("确定要取消吗？\n\n正在执行的步骤会被终止，下次启动时会重新执行。")) {
+		return
+	}
+	log.Printf("用户取消了操作")
+	addOutputText(i18n.T("正在取消..."))
+	cancelInstall()
+}
+
+// 随程序分发的 Python 安装包所在目录（相对于程序目录，使用 / 分隔）
+func pythonArtifacts() string {
+	if installConfig.PythonArtifacts == "" {
+		return "python/20240814"
+	}
+	return filepath.ToSlash(installConfig.PythonArtifacts)
+}
+
+// 传给 uv python install 的版本请求，版本约束无效时原样使用（检查 Python 时会报告错误）
+func pythonRequest() string {
+	request, err := envcheck.InstallRequest(installConfig.PythonVersion, pythonArch())
+	if err != nil {
+		return installConfig.PythonVersion
+	}
+	return request
+}
+
+// 应用使用的 Python 架构，配置为 auto 时为本机架构
+func pythonArch() string {
+	return envcheck.ResolveArch(installConfig.PythonArch)
+}
+
+// 应用需要的 Python 版本，用于提示信息
+func pythonVersion() string {
+	if installConfig.PythonVersion == "" {
+		return envcheck.DefaultPython
+	}
+	return installConfig.PythonVersion
+}
+
+// 使用全局执行器和配置的 Python 版本的环境检查器
+func newChecker() envcheck.Checker {
+	return envcheck.Checker{
+		Runner:  cmdRunner,
+		Timeout: checkTimeout(),
+		Python:  installConfig.PythonVersion,
+		Arch:    pythonArch(),
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"go2exe/internal/i18n"
+)
+
+// 右键菜单支持的文件类型（与卸载程序中的列表保持一致）
+var contextMenuTypes = []string{".epub", ".txt", ".pdf"}
+
+// 每种文件类型下的菜单项注册表路径（当前用户，无需管理员权限）
+func contextMenuKey(ext string) string {
+	return `Software\Classes\SystemFileAssociations\` + ext + `\shell\SpeakMyBook`
+}
+
+// 注册“用 SpeakMyBook 朗读”右键菜单，重复调用会更新程序路径
+func registerContextMenu(exePath string) error {
+	for _, ext := range contextMenuTypes {
+		key := contextMenuKey(ext)
+		if err := regSetString(HKEY_CURRENT_USER, key, "", i18n.T("用 SpeakMyBook 朗读")); err != nil {
+			return err
+		}
+		if err := regSetString(HKEY_CURRENT_USER, key, "Icon", exePath); err != nil {
+			return err
+		}
+		command := fmt.Sprintf(`"%s" "%%1"`, exePath)
+		if err := regSetString(HKEY_CURRENT_USER, key+`\command`, "", command); err != nil {
+			return err
+		}
+	}
+	notifyAssocChanged()
+	log.Printf("已注册右键菜单: %v", contextMenuTypes)
+	return nil
+}
+
+// 删除右键菜单，某个类型删除失败时仍删除其他类型
+func unregisterContextMenu() error {
+	var errs []error
+	for _, ext := range contextMenuTypes {
+		if err := regDeleteKeyTree(HKEY_CURRENT_USER, contextMenuKey(ext)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	notifyAssocChanged()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	log.Printf("已删除右键菜单")
+	return nil
+}
+
+// 按配置同步右键菜单的注册状态
+func syncContextMenu(cfg Config, exePath string) {
+	registered := false
+	for _, ext := range contextMenuTypes {
+		if cmd, err := regGetString(HKEY_CURRENT_USER, contextMenuKey(ext)+`\command`, ""); err == nil && cmd != "" {
+			registered = true
+		}
+	}
+	var err error
+	switch {
+	case cfg.Shell.ContextMenu:
+		err = registerContextMenu(exePath)
+	case registered:
+		err = unregisterContextMenu()
+	}
+	if err != nil {
+		log.Printf("更新右键菜单失败: %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+)
+
+var (
+	shGetKnownFolderPath = shell32.NewProc("SHGetKnownFolderPath")
+	coTaskMemFree        = ole32.NewProc("CoTaskMemFree")
+	FOLDERID_Desktop     = mustGUID("b4bfcc3a-db2c-424c-b029-7fe99a87c641")
+	FOLDERID_Documents   = mustGUID("fdd39ad0-238f-46af-adb4-6c85480369c7")
+	FOLDERID_SendTo      = mustGUID("8983036c-27c0-404b-8f08-102d10dcfd74")
+	FOLDERID_Programs    = mustGUID("a77f5d77-2e2b-44c3-a6a2-aba601054a51")
+	IID_IPersistFile     = mustGUID("0000010b-0000-0000-c000-000000000046")
+)
+
+// 开始菜单和桌面上启动 SpeakMyBook 的快捷方式的文件名
+const appShortcutName = "SpeakMyBook.lnk"
+
+// 获取系统已知文件夹路径（会跟随 OneDrive 等重定向）
+func knownFolderPath(id *GUID) (string, error) {
+	var p *uint16
+	r, _, _ := shGetKnownFolderPath.Call(uintptr(unsafe.Pointer(id)), 0, 0, uintptr(unsafe.Pointer(&p)))
+	if int32(r) < 0 {
+		return "", fmt.Errorf("SHGetKnownFolderPath 失败: HRESULT 0x%08X", uint32(r))
+	}
+	defer coTaskMemFree.Call(uintptr(unsafe.Pointer(p)))
+	return syscall.UTF16ToString((*[1 << 15]uint16)(unsafe.Pointer(p))[:]), nil
+}
+
+// 快捷方式参数
+type shortcut struct {
+	Path        string // .lnk 文件路径
+	Target      string // 目标程序
+	Args        string // 命令行参数
+	WorkDir     string // 起始位置
+	Icon        string // 图标文件
+	Description string // 备注
+}
+
+// PowerShell 单引号字符串转义
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// 创建快捷方式：通过 IShellLink 直接写入，COM 不可用等失败时改用 PowerShell 的 WScript.Shell
+func createShortcut(sc shortcut) error {
+	if err := os.MkdirAll(filepath.Dir(sc.Path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	err := saveShellLink(sc)
+	if err == nil {
+		log.Printf("已创建快捷方式: %s", sc.Path)
+		return nil
+	}
+	log.Printf("通过 IShellLink 创建快捷方式失败（%v），改用 PowerShell", err)
+	return createShortcutPowerShell(sc)
+}
+
+// 通过 IShellLinkW 设置快捷方式的属性，再用 IPersistFile 保存为 .lnk 文件
+func saveShellLink(sc shortcut) error {
+	// COM 对象必须在同一个 STA 线程上创建和使用
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	coInitializeEx.Call(0, uintptr(COINIT_APARTMENTTHREADED))
+	defer coUninitialize.Call()
+
+	link, err := createInstance(&CLSID_ShellLink, &IID_IShellLinkW)
+	if err != nil {
+		return err
+	}
+	defer link.release()
+	var bufs [][]uint16 // 调用期间保持字符串存活
+	str := func(v string) uintptr {
+		buf, _ := syscall.UTF16FromString(v)
+		bufs = append(bufs, buf)
+		return uintptr(unsafe.Pointer(&buf[0]))
+	}
+	for _, set := range []struct {
+		index int
+		name  string
+		value string
+	}{
+		{20, "SetPath", sc.Target},
+		{11, "SetArguments", sc.Args},
+		{9, "SetWorkingDirectory", sc.WorkDir},
+		{7, "SetDescription", sc.Description},
+	} {
+		if err := link.call(set.index, str(set.value)); err != nil {
+			return fmt.Errorf("%s 失败: %v", set.name, err)
+		}
+	}
+	if sc.Icon != "" {
+		if err := link.call(17, str(sc.Icon), 0); err != nil { // SetIconLocation
+			return fmt.Errorf("SetIconLocation 失败: %v", err)
+		}
+	}
+
+	file, err := link.queryInterface(&IID_IPersistFile)
+	if err != nil {
+		return err
+	}
+	defer file.release()
+	err = file.call(6, str(sc.Path), 1) // Save，保存后作为当前文件
+	runtime.KeepAlive(bufs)
+	if err != nil {
+		return fmt.Errorf("保存快捷方式失败: %v", err)
+	}
+	return nil
+}
+
+// 通过 PowerShell 的 WScript.Shell 创建快捷方式
+func createShortcutPowerShell(sc shortcut) error {
+	script := fmt.Sprintf(
+		"$s = (New-Object -ComObject WScript.Shell).CreateShortcut(%s); "+
+			"$s.TargetPath = %s; $s.Arguments = %s; $s.WorkingDirectory = %s; "+
+			"$s.IconLocation = %s; $s.Description = %s; $s.Save()",
+		psQuote(sc.Path), psQuote(sc.Target), psQuote(sc.Args), psQuote(sc.WorkDir),
+		psQuote(sc.Icon), psQuote(sc.Description))
+
+	output, err := cmdRunner.Output(runner.Command{
+		Name:       "powershell",
+		Args:       []string{"-NoProfile", "-NonInteractive", "-Command", script},
+		HideWindow: true,
+	})
+	if err != nil {
+		return fmt.Errorf("创建快捷方式失败: %v, 输出: %s", err, strings.TrimSpace(output))
+	}
+	log.Printf("已创建快捷方式: %s", sc.Path)
+	return nil
+}
+
+// 用户的“发送到”目录
+func sendToDir() string {
+	if dir, err := knownFolderPath(&FOLDERID_SendTo); err == nil {
+		return dir
+	}
+	return filepath.Join(os.Getenv("APPDATA"), "Microsoft", "Windows", "SendTo")
+}
+
+// 在“发送到”菜单中添加 SpeakMyBook，选中的文件路径会作为参数传给启动器
+func installSendTo(exePath string) error {
+	return createShortcut(shortcut{
+		Path:        filepath.Join(sendToDir(), "SpeakMyBook.lnk"),
+		Target:      exePath,
+		WorkDir:     filepath.Dir(exePath),
+		Icon:        exePath,
+		Description: i18n.T("用 SpeakMyBook 朗读"),
+	})
+}
+
+// 用户的开始菜单“程序”目录
+func startMenuDir() string {
+	if dir, err := knownFolderPath(&FOLDERID_Programs); err == nil {
+		return dir
+	}
+	return filepath.Join(os.Getenv("APPDATA"), "Microsoft", "Windows", "Start Menu", "Programs")
+}
+
+// 用户的桌面目录
+func desktopDir() string {
+	if dir, err := knownFolderPath(&FOLDERID_Desktop); err == nil {
+		return dir
+	}
+	return filepath.Join(os.Getenv("USERPROFILE"), "Desktop")
+}
+
+// 首次安装成功后按配置在开始菜单和桌面上添加启动 SpeakMyBook 的快捷方式，之后不用再到程序目录中找启动器。
+// 已存在时更新程序路径
+func installAppShortcuts(exePath string, cfg ShellConfig) error {
+	var dirs []string
+	if cfg.StartMenu {
+		dirs = append(dirs, startMenuDir())
+	}
+	if cfg.DesktopShortcut {
+		dirs = append(dirs, desktopDir())
+	}
+	for _, dir := range dirs {
+		err := createShortcut(shortcut{
+			Path:        filepath.Join(dir, appShortcutName),
+			Target:      exePath,
+			WorkDir:     filepath.Dir(exePath),
+			Icon:        exePath,
+			Description: i18n.T("SpeakMyBook 电子书朗读"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 删除开始菜单和桌面上的快捷方式，不存在时忽略
+func removeAppShortcuts() error {
+	var errs []error
+	for _, dir := range []string{startMenuDir(), desktopDir()} {
+		if err := os.Remove(filepath.Join(dir, appShortcutName)); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 文件名中不允许出现的字符
+var invalidFileNameChars = strings.NewReplacer(
+	`\`, "_", "/", "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_",
+)
+
+// 在桌面创建“继续收听”快捷方式，双击后通过启动器打开指定的书，voice 为空表示使用应用默认语音
+func createBookShortcut(exePath, book, voice string) (string, error) {
+	if _, err := os.Stat(book); err != nil {
+		return "", fmt.Errorf("找不到书籍文件: %v", err)
+	}
+	desktop, err := knownFolderPath(&FOLDERID_Desktop)
+	if err != nil {
+		return "", err
+	}
+	title := strings.TrimSuffix(filepath.Base(book), filepath.Ext(book))
+	args := fmt.Sprintf(`"%s"`, book)
+	if voice != "" {
+		// 选项必须写在书籍路径之前，否则启动器不会解析
+		args = fmt.Sprintf(`--voice "%s" %s`, voice, args)
+	}
+	path := filepath.Join(desktop, invalidFileNameChars.Replace(i18n.T("继续收听 %s", title))+".lnk")
+	err = createShortcut(shortcut{
+		Path:        path,
+		Target:      exePath,
+		Args:        args,
+		WorkDir:     filepath.Dir(exePath),
+		Icon:        exePath,
+		Description: i18n.T("用 SpeakMyBook 继续收听《%s》", title),
+	})
+	if err != nil {
+		return path, err
+	}
+	// 记下创建的快捷方式，卸载时据此删除
+	if err := recordBookShortcut(path); err != nil {
+		log.Printf("记录快捷方式失败: %v", err)
+	}
+	return path, nil
+}
+
+var bookShortcutsMutex sync.Mutex
+
+// 已创建的“继续收听”快捷方式列表文件
+func bookShortcutsFile() string {
+	return filepath.Join(dataDir(), "shortcuts.json")
+}
+
+// 读取已创建的“继续收听”快捷方式
+func loadBookShortcuts() []string {
+	var paths []string
+	data, err := os.ReadFile(bookShortcutsFile())
+	if err == nil {
+		json.Unmarshal(data, &paths)
+	}
+	return paths
+}
+
+// 把新建的快捷方式加入列表，同一路径只记录一次
+func recordBookShortcut(path string) error {
+	bookShortcutsMutex.Lock()
+	defer bookShortcutsMutex.Unlock()
+
+	paths := loadBookShortcuts()
+	for _, p := range paths {
+		if strings.EqualFold(p, path) {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(dataDir(), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(append(paths, path), "", "  ")
+	return os.WriteFile(bookShortcutsFile(), data, 0644)
+}
+
+// 删除列表中记录的“继续收听”快捷方式，用户已手动删除的忽略
+func removeBookShortcuts() error {
+	bookShortcutsMutex.Lock()
+	defer bookShortcutsMutex.Unlock()
+
+	var errs []error
+	for _, p := range loadBookShortcuts() {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	if err := os.Remove(bookShortcutsFile()); err != nil && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
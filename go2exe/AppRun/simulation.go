@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+	"go2exe/internal/simulate"
+)
+
+// 按场景模拟 uv、PowerShell 和网络（--simulate），用于演示和手动测试各种失败情况。
+// 状态文件、缓存和模型文件写到临时目录中，不影响真实的环境
+func applySimulation(name string) error {
+	sc, err := simulate.Load(name)
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "SpeakMyBook-simulate-")
+	if err != nil {
+		return err
+	}
+	// 启动器自己的数据和传给子进程的 LOCALAPPDATA 都改到临时目录，不修改本进程的环境变量
+	dataRoot = dir
+	childEnv.Set("LOCALAPPDATA", dir)
+	env := simulate.New(sc)
+	cmdRunner = env.Runner()
+	app.Runner = cmdRunner
+	http.DefaultTransport = env.Transport()
+	log.Printf("模拟场景 %s，数据目录 %s", sc.Name, dir)
+	addOutputText(i18n.T("模拟场景: %s", sc.Name))
+	return nil
+}
+
+// 注入故障的环境变量，格式与 --inject-fault 相同。环境变量会传给提权的安装进程和重新启动的启动器
+const faultsEnv = "SPEAKMYBOOK_FAULTS"
+
+// 在真实的命令和下载上注入故障（--inject-fault 或环境变量 SPEAKMYBOOK_FAULTS），
+// 供测试和技术支持重现重试、回退和断点续传等恢复流程。spec 为空时使用环境变量
+func applyFaults(spec string) error {
+	if spec == "" {
+		spec = os.Getenv(faultsEnv)
+	}
+	if spec == "" {
+		return nil
+	}
+	in, err := simulate.ParseFaults(spec)
+	if err != nil {
+		return err
+	}
+	childEnv.Set(faultsEnv, spec)
+	cmdRunner = in.Runner(cmdRunner)
+	app.Runner = cmdRunner
+	http.DefaultTransport = in.Transport(http.DefaultTransport)
+	var faults []string
+	for _, f := range in.Faults() {
+		faults = append(faults, f.String())
+	}
+	log.Printf("注入故障: %s", strings.Join(faults, ", "))
+	addOutputText(i18n.T("注入故障: %s", strings.Join(faults, ", ")))
+	return nil
+}
+
+// 不在帮助中列出的参数
+var hiddenFlags = map[string]bool{"inject-fault": true}
+
+// 列出参数的帮助，跳过 hiddenFlags
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+	flag.VisitAll(func(f *flag.Flag) {
+		if hiddenFlags[f.Name] {
+			return
+		}
+		name, usage := flag.UnquoteUsage(f)
+		fmt.Fprintf(out, "  -%s %s\n    \t%s\n", f.Name, name, strings.ReplaceAll(usage, "\n", "\n    \t"))
+	})
+	fmt.Fprintf(out, "  -- <应用参数>\n    \t之后的参数原样传给 Python 应用，例如 -- --book a.epub --debug\n")
+}
+
+// 录制（--record）或回放（--replay）外部命令。录下的磁带文件可以放进 testdata 中，
+// 用真实的 uv 输出做解析和错误分类的回归测试
+func applyCassette(record, replay string) error {
+	if record == "" && replay == "" {
+		return nil
+	}
+	if replay != "" {
+		cassette, err := runner.LoadCassette(replay)
+		if err != nil {
+			return err
+		}
+		cmdRunner = runner.NewReplay(cassette)
+		log.Printf("按磁带 %s 回放 %d 条命令", replay, len(cassette.Interactions))
+	}
+	if record != "" {
+		cmdRunner = &runner.Recorder{Runner: cmdRunner, Path: record}
+		log.Printf("把执行的命令录制到 %s", record)
+	}
+	app.Runner = cmdRunner
+	return nil
+}
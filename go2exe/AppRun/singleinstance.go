@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	createMutex          = kernel32.NewProc("CreateMutexW")
+	enumWindows          = user32.NewProc("EnumWindows")
+	getClassName         = user32.NewProc("GetClassNameW")
+	getWindowText        = user32.NewProc("GetWindowTextW")
+	isWindowVisible      = user32.NewProc("IsWindowVisible")
+	isIconic             = user32.NewProc("IsIconic")
+	setForegroundWindow  = user32.NewProc("SetForegroundWindow")
+	ERROR_ALREADY_EXISTS = 183
+	SW_RESTORE           = 9
+)
+
+// 单实例互斥体名称，Local\ 前缀使其只在当前登录会话内生效
+const instanceMutexName = `Local\SpeakMyBook.AppRun`
+
+// 持有到进程退出，由系统自动释放
+var instanceMutex uintptr
+
+// 创建命名互斥体，已被其他启动器持有时返回 false
+func acquireInstanceLock() bool {
+	namePtr, _ := syscall.UTF16PtrFromString(instanceMutexName)
+	h, _, err := createMutex.Call(0, 0, uintptr(unsafe.Pointer(namePtr)))
+	if h == 0 {
+		// 创建失败时不阻止启动，只记录日志
+		log.Printf("创建单实例互斥体失败: %v", err)
+		return true
+	}
+	if err == syscall.Errno(ERROR_ALREADY_EXISTS) {
+		syscall.CloseHandle(syscall.Handle(h))
+		return false
+	}
+	instanceMutex = h
+	return true
+}
+
+// 查找应用的 Tk 主窗口（类名 TkTopLevel，标题以 SpeakMyBook 开头）
+func findAppWindow() uintptr {
+	var found uintptr
+	cb := syscall.NewCallback(func(hwnd, lParam uintptr) uintptr {
+		if visible, _, _ := isWindowVisible.Call(hwnd); visible == 0 {
+			return 1
+		}
+		class := make([]uint16, 64)
+		getClassName.Call(hwnd, uintptr(unsafe.Pointer(&class[0])), uintptr(len(class)))
+		if syscall.UTF16ToString(class) != "TkTopLevel" {
+			return 1
+		}
+		title := make([]uint16, 256)
+		getWindowText.Call(hwnd, uintptr(unsafe.Pointer(&title[0])), uintptr(len(title)))
+		if strings.HasPrefix(syscall.UTF16ToString(title), "SpeakMyBook") {
+			found = hwnd
+			return 0 // 停止枚举
+		}
+		return 1
+	})
+	enumWindows.Call(cb, 0)
+	return found
+}
+
+// 把已运行的应用窗口切换到前台，找不到窗口时返回 false
+func bringAppToFront() bool {
+	hwnd := findAppWindow()
+	if hwnd == 0 {
+		return false
+	}
+	if iconic, _, _ := isIconic.Call(hwnd); iconic != 0 {
+		showWindow.Call(hwnd, uintptr(SW_RESTORE))
+	}
+	setForegroundWindow.Call(hwnd)
+	log.Printf("已将运行中的应用窗口切换到前台")
+	return true
+}
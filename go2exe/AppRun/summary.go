@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go2exe/internal/appupdate"
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+)
+
+// 首次安装的统计，安装结束后显示在向导的完成页，也可以保存下来提供给技术支持
+type setupSummary struct {
+	start      time.Time
+	sizeBefore int64 // 开始安装时 uv 缓存和模型目录的大小，用于估算下载的数据量
+}
+
+// 本次运行显示了安装向导时的安装统计，否则为 nil
+var setupStats *setupSummary
+
+// 用户点击“安装”后开始统计
+func beginSetupSummary() {
+	setupStats = &setupSummary{start: time.Now(), sizeBefore: downloadedSize()}
+}
+
+// 保存下载内容的目录的总大小：uv 缓存（依赖的安装包）和模型目录
+func downloadedSize() int64 {
+	return dirSize(install.UVCacheDir()) + dirSize(modelsDir())
+}
+
+// 目录中所有文件的大小之和，无法读取的文件不计
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// 安装报告：用时、下载的数据量、安装的版本、使用的镜像和加速方式
+func (s *setupSummary) report(exeDir string) string {
+	downloaded := max(downloadedSize()-s.sizeBefore, 0)
+	lines := []string{
+		i18n.T("SpeakMyBook 安装报告（%s）", time.Now().Format("2006-01-02 15:04")),
+		"",
+		i18n.T("安装用时：%s", time.Since(s.start).Round(time.Second)),
+		i18n.T("下载数据：%.1f MB", float64(downloaded)/(1<<20)),
+	}
+
+	check := newChecker()
+	if ok, output := check.UVInstalled(); ok {
+		lines = append(lines, i18n.T("uv：%s", output))
+	}
+	if python, err := check.FindPython(); err == nil && python != nil {
+		lines = append(lines, i18n.T("Python：%s（%s）", python.Version, python.Key))
+	}
+	if version, err := appupdate.ProjectVersion(filepath.Join(exeDir, "python")); err == nil {
+		lines = append(lines, i18n.T("SpeakMyBook：%s", version))
+	}
+
+	if dir := offlineWheelsDir(exeDir); dir != "" {
+		lines = append(lines, i18n.T("依赖来源：离线安装包目录 %s", dir))
+	} else {
+		lines = append(lines, i18n.T("PyPI 镜像：%s（%s）", pypiMirror.Name, pypiMirror.URL))
+	}
+	if gpuVariant.Name == "" {
+		lines = append(lines, i18n.T("加速方式：CPU"))
+	} else {
+		lines = append(lines, i18n.T("加速方式：CUDA %s（%s）", gpuVariant.CUDA, gpuVariant.Name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 保存安装报告时默认的位置：桌面
+func reportPath() string {
+	name := fmt.Sprintf("SpeakMyBook-安装报告-%s.txt", time.Now().Format("20060102"))
+	if desktop, err := knownFolderPath(&FOLDERID_Desktop); err == nil {
+		return filepath.Join(desktop, name)
+	}
+	return name
+}
+
+// 记录并返回安装报告
+func finishSetupSummary(exeDir string) string {
+	report := setupStats.report(exeDir)
+	setupStats = nil
+	log.Printf("安装报告:\n%s", report)
+	return report
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+
+	"go2exe/internal/timeline"
+)
+
+// 转录文件的路径，传给提权的安装进程，它把记录追加到同一个文件
+const timelineEnv = "SPEAKMYBOOK_TIMELINE"
+
+// 数据目录中保留的转录文件数量
+const keepTimelines = 10
+
+var (
+	runTimeline *timeline.Recorder // 本次启动的时间线，没有记录时为 nil
+	runSpan     *timeline.Span     // 整次启动，应用启动或启动器失败退出时结束
+)
+
+// 转录文件所在的目录
+func timelineDir() string {
+	return filepath.Join(dataDir(), "timeline")
+}
+
+// 开始记录时间线：外部命令经过记录时间的执行器，安装器记录步骤、下载和重试（见 newInstaller）。
+// path 为空时在数据目录中新建转录文件，只保留最近的 keepTimelines 个
+func startTimeline(path, name string) {
+	if path == "" {
+		path = timeline.NewPath(timelineDir(), keepTimelines)
+	}
+	rec, err := timeline.Open(path)
+	if err != nil {
+		log.Printf("无法记录时间线: %v", err)
+		return
+	}
+	runTimeline = rec
+	runSpan = rec.Begin(timeline.KindRun, name)
+	cmdRunner = timeline.Runner{Next: cmdRunner, Recorder: rec}
+	app.Runner = cmdRunner
+}
+
+// 结束记录。常驻模式下之后托盘菜单中的更新等操作不再记录，时间线只反映启动的过程
+func stopTimeline(err error) {
+	if runTimeline == nil {
+		return
+	}
+	runSpan.End(err)
+	runTimeline.Close()
+	runTimeline = nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"go2exe/internal/envbundle"
+	"go2exe/internal/exitcode"
+	"go2exe/internal/i18n"
+	"go2exe/internal/ui"
+)
+
+// 随环境一起迁移的用户数据，键为迁移文件中的名称。
+// checks.json 记录的是本机的环境指纹和虚拟环境位置，不迁移
+func transferData(exeDir string) map[string]string {
+	return map[string]string{
+		"recent.json":  recentBooksFile(),
+		configFileName: filepath.Join(exeDir, configFileName),
+	}
+}
+
+// “移到另一台电脑”向导：在旧电脑上把环境和用户数据打包成一个迁移文件，
+// 在新电脑上校验并导入这个文件
+func runTransfer(exeDir string) error {
+	switch ui.AskBox(i18n.T("移到另一台电脑"), i18n.T("把 SpeakMyBook 的运行环境、设置和最近打开的书移到另一台电脑，新电脑不需要联网。\n\n是：在这台电脑上生成迁移文件\n否：在这台（新）电脑上导入迁移文件\n取消：退出")) {
+	case ui.IDYES:
+		return transferOut(exeDir)
+	case ui.IDNO:
+		return transferIn(exeDir)
+	}
+	log.Printf("用户取消了迁移")
+	return nil
+}
+
+// 选择保存位置，生成并校验迁移文件
+func transferOut(exeDir string) error {
+	name := "SpeakMyBook-迁移-" + time.Now().Format("20060102") + ".zip"
+	initial := name
+	if desktop, err := knownFolderPath(&FOLDERID_Desktop); err == nil {
+		initial = filepath.Join(desktop, name)
+	}
+	zipPath, ok := ui.SaveFileDialog(i18n.T("保存迁移文件"), initial)
+	if !ok {
+		log.Printf("用户取消了迁移")
+		return nil
+	}
+	if err := exportEnv(exeDir, zipPath, transferData(exeDir)); err != nil {
+		return err
+	}
+	// 写入后立即按清单校验一遍，磁盘或 U 盘有问题时在旧电脑上就能发现
+	addOutputText(i18n.T("正在校验迁移文件..."))
+	m, err := envbundle.Verify(zipPath)
+	if err != nil {
+		log.Printf("迁移文件校验失败: %v", err)
+		ui.ErrorBox(i18n.T("迁移失败"), i18n.T("迁移文件校验失败，请换一个位置重新生成: %v", err))
+		return fmt.Errorf("迁移文件校验失败: %v", err)
+	}
+	log.Printf("迁移文件校验通过，共 %d 个文件", len(m.Files))
+	ui.MessageBox(i18n.T("迁移文件已生成"), i18n.T("迁移文件已保存到：\n%s\n\n在新电脑上：\n1. 复制 SpeakMyBook 程序目录和这个迁移文件\n2. 运行 AppRun.exe --transfer，选择“否”\n3. 选择这个迁移文件", zipPath))
+	return nil
+}
+
+// 选择迁移文件并导入；导入会替换虚拟环境，需要应用先退出
+func transferIn(exeDir string) error {
+	zipPath, ok := ui.OpenFileDialog(i18n.T("选择迁移文件"))
+	if !ok {
+		log.Printf("用户取消了迁移")
+		return nil
+	}
+	if err := stopRunningApp(); err != nil {
+		log.Printf("无法开始维护操作: %v", err)
+		return withExitCode(exitcode.Cancelled, err)
+	}
+	return runImportEnv(exeDir, zipPath)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"go2exe/internal/appupdate"
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+var shellExecute = shell32.NewProc("ShellExecuteW")
+
+var (
+	// 常驻模式下的托盘图标，未显示时为 nil
+	tray *ui.Tray
+	// 同一时间只执行一个托盘菜单中的操作
+	trayBusy sync.Mutex
+)
+
+// 在通知区域显示图标，菜单中显示运行环境的状态并提供常用的维护操作
+func startTray(exePath string) {
+	exeDir := filepath.Dir(exePath)
+	t, err := ui.ShowTray(trayTip(exeDir), exePath, func() []ui.TrayItem {
+		return trayMenu(exeDir)
+	})
+	if err != nil {
+		log.Printf("显示托盘图标失败: %v", err)
+		return
+	}
+	tray = t
+	log.Printf("已显示托盘图标")
+}
+
+// 移除托盘图标，进程退出前调用，否则图标会留在通知区域直到鼠标经过
+func closeTray() {
+	if tray != nil {
+		tray.Close()
+		tray = nil
+	}
+}
+
+// 运行环境的状态
+func envStatus(exeDir string) string {
+	if _, err := os.Stat(appPython(exeDir)); err != nil {
+		return i18n.T("虚拟环境缺失，请修复环境")
+	}
+	return i18n.T("运行环境正常")
+}
+
+// 鼠标悬停时的提示文字
+func trayTip(exeDir string) string {
+	return "SpeakMyBook\n" + envStatus(exeDir)
+}
+
+// 每次打开菜单时重新生成，状态总是最新的
+func trayMenu(exeDir string) []ui.TrayItem {
+	appStatus := i18n.T("应用未运行")
+	if isAppRunning() {
+		appStatus = i18n.T("应用正在运行")
+	}
+	if tray != nil {
+		tray.SetTip(trayTip(exeDir))
+	}
+	items := []ui.TrayItem{
+		{Label: envStatus(exeDir)},
+		{Label: appStatus},
+		{},
+		{Label: i18n.T("打开日志"), Action: func() { openLog(exeDir) }},
+	}
+	// 黄金镜像模式下程序目录只读，更新、修复和回退都由管理员重新准备
+	if !goldenMode {
+		items = append(items,
+			ui.TrayItem{Label: i18n.T("检查更新"), Action: trayAction(exeDir, checkForUpdates)},
+			ui.TrayItem{Label: i18n.T("修复环境"), Action: trayAction(exeDir, repairFromTray)},
+		)
+	}
+	if previous, ok := backupVersion(exeDir); ok && !goldenMode {
+		items = append(items, ui.TrayItem{Label: i18n.T("回退到上一个版本（%s）", previous), Action: trayAction(exeDir, rollbackFromTray)})
+	}
+	return append(items,
+		ui.TrayItem{Label: i18n.T("重新启动应用"), Action: trayAction(exeDir, restartApp)},
+		ui.TrayItem{},
+		ui.TrayItem{Label: i18n.T("退出"), Action: func() {
+			residentKeepAlive.Store(false)
+			quitResident()
+		}},
+	)
+}
+
+// 包装托盘菜单中的操作：已有操作在执行时提示用户，完成后更新提示文字
+func trayAction(exeDir string, fn func(exeDir string) error) func() {
+	return func() {
+		if !trayBusy.TryLock() {
+			ui.MessageBox("SpeakMyBook", i18n.T("正在执行其他操作，请等它完成后再试。"))
+			return
+		}
+		defer trayBusy.Unlock()
+		if err := fn(exeDir); err != nil && !errors.Is(err, runner.ErrCanceled) {
+			log.Printf("托盘操作失败: %v", err)
+		}
+		if tray != nil {
+			tray.SetTip(trayTip(exeDir))
+		}
+	}
+}
+
+// 用关联的程序打开文件或网址
+func shellOpen(target string) error {
+	verb, _ := syscall.UTF16PtrFromString("open")
+	file, _ := syscall.UTF16PtrFromString(target)
+	if r, _, err := shellExecute.Call(0, uintptr(unsafe.Pointer(verb)), uintptr(unsafe.Pointer(file)), 0, 0, uintptr(SW_SHOWNORMAL)); r <= 32 {
+		return err
+	}
+	return nil
+}
+
+// 用默认的文本编辑器打开启动器的日志
+func openLog(exeDir string) {
+	path := launcherLogPath(exeDir)
+	if err := shellOpen(path); err != nil {
+		log.Printf("打开日志 %s 失败: %v", path, err)
+		ui.ErrorBox(i18n.T("打开日志失败"), i18n.T("无法打开 %s: %v", path, err))
+	}
+}
+
+// 关闭本启动器启动的应用并等待它退出。用户拒绝关闭或应用没有退出时返回错误
+func closeApp() error {
+	if !isAppRunning() && findAppWindow() == 0 {
+		return nil
+	}
+	if !ui.ConfirmBox(i18n.T("关闭 SpeakMyBook"), i18n.T("SpeakMyBook 正在运行，需要先关闭它才能继续。\n\n"+
+		"请先保存正在进行的工作（例如正在导出的音频），然后点击“是”关闭 SpeakMyBook；点击“否”取消本次操作。")) {
+		return runner.ErrCanceled
+	}
+	log.Printf("请求应用退出")
+	if isAppConnected() {
+		sendToApp("exit")
+	} else {
+		closeAppWindow()
+	}
+	deadline := time.Now().Add(shutdownTimeout)
+	for time.Now().Before(deadline) {
+		if !isAppRunning() && findAppWindow() == 0 {
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	ui.ErrorBox(i18n.T("SpeakMyBook 未响应"), i18n.T("SpeakMyBook 在 %d 秒内没有退出，请手动关闭后重试。", int(shutdownTimeout/time.Second)))
+	return fmt.Errorf("应用在 %v 内没有退出", shutdownTimeout)
+}
+
+// 检查应用是否有新版本，有时询问后更新；没有时检查虚拟环境是否与 uv.lock 一致，不一致时询问后重新同步依赖
+func checkForUpdates(exeDir string) error {
+	rel, found, err := latestRelease(exeDir)
+	if err != nil {
+		log.Printf("检查应用更新失败: %v", err)
+		ui.ErrorBox(i18n.T("检查更新"), i18n.T("无法获取新版本信息: %v\n\n将继续检查已安装的依赖。", err))
+	}
+	if found {
+		return updateFromTray(exeDir, rel)
+	}
+
+	venv := filepath.Dir(filepath.Dir(appPython(exeDir)))
+	problems := install.CheckVenv(venv, filepath.Join(exeDir, "python", "uv.lock"), installConfig.PythonVersion)
+	if len(problems) == 0 {
+		ui.MessageBox(i18n.T("检查更新"), i18n.T("已安装的依赖都是最新的。"))
+		return nil
+	}
+	log.Printf("虚拟环境需要更新: %s", strings.Join(problems, "; "))
+	if !ui.ConfirmBox(i18n.T("检查更新"), i18n.T("发现以下需要更新的内容：\n\n%s\n\n是否现在更新？更新期间需要关闭 SpeakMyBook。", strings.Join(problems, "\n"))) {
+		return nil
+	}
+	if err := closeApp(); err != nil {
+		return err
+	}
+
+	console.Open()
+	inst := newInstaller(exeDir)
+	err = syncVenv(exeDir, inst)
+	console.Close()
+	if err != nil {
+		ui.ErrorBox(i18n.T("更新失败"), i18n.T("同步依赖失败: %v\n\n详细信息请查看 app.log。", err))
+		return err
+	}
+	return startPythonApp(nil)
+}
+
+// 询问后关闭应用，更新到 rel 并重新启动应用
+func updateFromTray(exeDir string, rel appupdate.Release) error {
+	if !ui.ConfirmBox(i18n.T("发现新版本"), updateMessage(exeDir, rel)+"\n\n"+i18n.T("是否现在更新？更新期间需要关闭 SpeakMyBook。")) {
+		return nil
+	}
+	if err := closeApp(); err != nil {
+		return err
+	}
+	console.Open()
+	err := applyAppUpdate(exeDir, rel)
+	console.Close()
+	if errors.Is(err, runner.ErrCanceled) {
+		return err
+	}
+	// 更新失败时 python 目录保持原样，仍然启动当前版本
+	return startPythonApp(nil)
+}
+
+// 询问后关闭应用并修复环境，完成后重新启动应用
+func repairFromTray(exeDir string) error {
+	if !ui.ConfirmBox(i18n.T("修复环境"), i18n.T("将删除并重新安装 SpeakMyBook 的虚拟环境，需要几分钟。\n\n是否继续？")) {
+		return nil
+	}
+	if err := closeApp(); err != nil {
+		return err
+	}
+	if err := runRepair(exeDir); err != nil {
+		return err
+	}
+	return startPythonApp(nil)
+}
+
+// 关闭应用后重新启动
+func restartApp(exeDir string) error {
+	if err := closeApp(); err != nil {
+		return err
+	}
+	log.Printf("重新启动应用")
+	return startPythonApp(nil)
+}
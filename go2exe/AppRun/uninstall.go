@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+// 隐藏窗口运行命令，输出实时写入日志和控制台
+func runLoggedCommand(name string, args ...string) error {
+	return cmdRunner.Stream(runner.Command{
+		Name:       name,
+		Args:       args,
+		HideWindow: true,
+		Context:    installCtx,
+		Timeout:    stepTimeout(),
+	}, ui.CommandOutput(console))
+}
+
+// 卸载启动器安装的环境：托管的 Python、.venv、缓存，确认后再删除 uv 本身
+func runUninstall(exeDir string) error {
+	if !ui.ConfirmBox(i18n.T("卸载 SpeakMyBook 环境"), i18n.T("将删除 SpeakMyBook 使用的 Python %s、虚拟环境和它的依赖在 uv 中的下载缓存。\n\n是否继续？", pythonVersion())) {
+		log.Printf("用户取消了卸载")
+		return nil
+	}
+	console.Open()
+	defer console.Close()
+
+	var failed []string
+	step := func(desc string, fn func() error) {
+		// 用户取消后跳过剩下的步骤
+		if installCtx.Err() != nil {
+			return
+		}
+		log.Printf("正在%s...", desc)
+		addOutputText(i18n.T("正在%s...", i18n.T(desc)))
+		if err := fn(); err != nil {
+			log.Printf("%s失败: %v", desc, err)
+			addOutputText(i18n.T("%s失败: %v", i18n.T(desc), err))
+			failed = append(failed, desc)
+		}
+	}
+
+	step("卸载 Python", func() error {
+		// 只卸载满足版本约束的那一个，不影响用户自己用 uv 安装的其他版本
+		python, err := newChecker().FindPython()
+		if err != nil || python == nil {
+			return err
+		}
+		return runLoggedCommand("uv", "python", "uninstall", python.Key)
+	})
+	step("删除虚拟环境", func() error {
+		return removeAllWithRetry(filepath.Join(exeDir, "python", ".venv"))
+	})
+	// uv 缓存由所有使用 uv 的项目共用，这里只清理本项目依赖的包，整个缓存在确认卸载 uv 时才清空
+	step("清理项目依赖的 uv 缓存", func() error {
+		deps, err := projectDependencies(filepath.Join(exeDir, "python"))
+		if err != nil {
+			return err
+		}
+		return runLoggedCommand("uv", append([]string{"cache", "clean"}, deps...)...)
+	})
+	// 每一项单独执行，一项失败不影响删除其他项
+	step("移除右键菜单", unregisterContextMenu)
+	step("移除文件关联", unregisterFileAssociations)
+	step("删除开始菜单和桌面快捷方式", removeAppShortcuts)
+	step("删除“继续收听”快捷方式", removeBookShortcuts)
+	step("移除“发送到”入口", func() error {
+		err := os.Remove(filepath.Join(sendToDir(), "SpeakMyBook.lnk"))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	})
+	step("删除启动器数据", func() error {
+		return removeAllWithRetry(dataDir())
+	})
+	if installCtx.Err() != nil {
+		log.Printf("卸载已取消")
+		addOutputText(i18n.T("卸载已取消"))
+		return runner.ErrCanceled
+	}
+
+	if ui.ConfirmBox(i18n.T("卸载 uv"), i18n.T("是否同时卸载 uv 并清空它的下载缓存？\n\n如果其他程序也在使用 uv，请选择“否”。")) {
+		step("清空 uv 下载缓存", func() error {
+			return runLoggedCommand("uv", "cache", "clean")
+		})
+		step("卸载 uv", removeUV)
+	}
+
+	if len(failed) > 0 {
+		addOutputText(i18n.T("卸载完成，但以下步骤失败: %s", strings.Join(failed, i18n.T("、"))))
+		time.Sleep(5 * time.Second) // 给用户时间查看错误信息
+		return fmt.Errorf("以下步骤失败: %s", strings.Join(failed, "、"))
+	}
+	addOutputText(i18n.T("卸载完成"))
+	log.Printf("卸载完成")
+	time.Sleep(2 * time.Second)
+	return nil
+}
+
+// 删除 uv 管理的目录以及 uv 可执行文件
+func removeUV() error {
+	uvPath, err := childEnv.LookPath("uv")
+	if err != nil {
+		return fmt.Errorf("找不到 uv: %v", err)
+	}
+	for _, sub := range []string{"python", "tool"} {
+		out, err := cmdRunner.Output(runner.Command{Name: uvPath, Args: []string{sub, "dir"}, HideWindow: true})
+		if err != nil {
+			continue
+		}
+		dir := strings.TrimSpace(out)
+		addOutputText(i18n.T("删除 %s", dir))
+		os.RemoveAll(dir)
+	}
+	binDir := filepath.Dir(uvPath)
+	for _, name := range []string{"uv.exe", "uvx.exe", "uvw.exe"} {
+		path := filepath.Join(binDir, name)
+		if err := removeAllWithRetry(path); err != nil {
+			return err
+		}
+		addOutputText(i18n.T("删除 %s", path))
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go2exe/internal/appupdate"
+	"go2exe/internal/i18n"
+	"go2exe/internal/install"
+	"go2exe/internal/models"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+// 获取发布清单的最长等待时间
+const updateCheckTimeout = 10 * time.Second
+
+// 应用更新设置，由配置文件设置
+var updateConfig UpdateConfig
+
+// 查询发布清单中是否有比 python 目录中更新的版本。没有配置发布清单时返回 false
+func latestRelease(exeDir string) (appupdate.Release, bool, error) {
+	if updateConfig.ManifestURL == "" {
+		return appupdate.Release{}, false, nil
+	}
+	current, err := appupdate.ProjectVersion(filepath.Join(exeDir, "python"))
+	if err != nil {
+		return appupdate.Release{}, false, fmt.Errorf("无法读取当前版本: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+	defer cancel()
+	rel, err := appupdate.Fetch(ctx, nil, updateConfig.ManifestURL)
+	if err != nil {
+		return rel, false, err
+	}
+	log.Printf("应用当前版本 %s，最新版本 %s", current, rel.Version)
+	state, _ := readCheckState()
+	found := updateAvailable(rel.Version, current, state.RolledBack)
+	if !found && appupdate.Newer(rel.Version, current) {
+		log.Printf("%s 曾因无法启动被回退，不再提示更新到 %s", state.RolledBack, rel.Version)
+	}
+	return rel, found, nil
+}
+
+// latest 是否可以作为更新提示：比当前版本新，并且比回退掉的版本 rolledBack 新（没有回退过时 rolledBack 为空）
+func updateAvailable(latest, current, rolledBack string) bool {
+	if !appupdate.Newer(latest, current) {
+		return false
+	}
+	return rolledBack == "" || appupdate.Newer(latest, rolledBack)
+}
+
+// 询问是否更新时显示的新旧版本号和更新说明
+func updateMessage(exeDir string, rel appupdate.Release) string {
+	current, _ := appupdate.ProjectVersion(filepath.Join(exeDir, "python"))
+	msg := i18n.T("SpeakMyBook 有新版本 %s（当前版本 %s）。", rel.Version, current)
+	if rel.Notes != "" {
+		msg += "\n\n" + rel.Notes
+	}
+	return msg
+}
+
+// 询问用户是否更新到 rel，同意时下载并安装。用户拒绝时返回 false
+func offerAppUpdate(exeDir string, rel appupdate.Release) (bool, error) {
+	if !ui.ConfirmBox(i18n.T("发现新版本"), updateMessage(exeDir, rel)+"\n\n"+i18n.T("是否现在更新？")) {
+		log.Printf("用户暂不更新到 %s", rel.Version)
+		return false, nil
+	}
+	return true, applyAppUpdate(exeDir, rel)
+}
+
+// 下载新版本，替换 python 目录（保留虚拟环境、日志和 Python 安装包，原来的版本移到备份目录），然后重新同步依赖。
+// 应用运行时 python 目录中的文件被占用，需先关闭应用
+func applyAppUpdate(exeDir string, rel appupdate.Release) error {
+	inst := newInstaller(exeDir)
+	err := installRelease(exeDir, inst, rel)
+	if errors.Is(err, runner.ErrCanceled) {
+		return err
+	}
+	if err != nil {
+		log.Printf("应用更新失败: %v", err)
+		ui.ErrorBox(i18n.T("更新失败"), i18n.T("无法更新到 %s: %v\n\n将继续使用当前版本。", rel.Version, err))
+		return err
+	}
+	// 应用成功启动后保存的是新版本的指纹
+	checks.fingerprint = packageFingerprint(exeDir)
+	clearStartupFailures()
+	log.Printf("已更新到 %s", rel.Version)
+	addOutputText(i18n.T("已更新到 %s", rel.Version))
+
+	// 新版本的依赖和 CUDA 变体可能不同
+	applyGPU(exeDir, installConfig.GPU)
+	inst = newInstaller(exeDir)
+	return syncVenv(exeDir, inst)
+}
+
+// 下载并安装 rel。发布清单提供了文件列表时先尝试增量更新，变化太多或增量更新失败时下载完整的压缩包
+func installRelease(exeDir string, inst *install.Installer, rel appupdate.Release) error {
+	if rel.Files != "" {
+		done, err := installDelta(exeDir, inst, rel)
+		if done || errors.Is(err, runner.ErrCanceled) {
+			return err
+		}
+		if err != nil {
+			log.Printf("增量更新失败: %v，改为下载完整的更新包", err)
+			addOutputText(i18n.T("增量更新失败，改为下载完整的更新包"))
+		}
+	}
+
+	dir := filepath.Join(dataDir(), "updates")
+	f := rel.File()
+	if err := inst.RunStep("下载应用更新", func() error {
+		return inst.DownloadFiles(dir, []models.File{f})
+	}); err != nil {
+		return err
+	}
+	log.Printf("正在安装应用 %s", rel.Version)
+	addOutputText(i18n.T("正在安装 SpeakMyBook %s...", rel.Version))
+	err := appupdate.Apply(f.Local(dir), filepath.Join(exeDir, "python"), backupDir(exeDir), projectKeep())
+	if err == nil {
+		os.Remove(f.Local(dir))
+	}
+	return err
+}
+
+// 只下载有变化的文件并安装。变化的文件超过完整压缩包的一半时不下载，返回 false，由调用方下载压缩包；
+// 出错时也返回 false，除非下载已经被取消
+func installDelta(exeDir string, inst *install.Installer, rel appupdate.Release) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+	list, err := appupdate.FetchFiles(ctx, nil, rel.Files)
+	cancel()
+	if err != nil {
+		return false, err
+	}
+	delta, err := list.Diff(filepath.Join(exeDir, "python"))
+	if err != nil {
+		return false, err
+	}
+	if !delta.Worthwhile(rel.Size) {
+		log.Printf("有 %d 个文件变化，共 %d 字节，下载完整的更新包", len(delta.Changed), delta.Size)
+		return false, nil
+	}
+	log.Printf("增量更新: %d 个文件变化，共 %d 字节", len(delta.Changed), delta.Size)
+	addOutputText(i18n.T("增量更新：%d 个文件有变化，需要下载 %.1f MB", len(delta.Changed), float64(delta.Size)/(1<<20)))
+
+	dir := filepath.Join(dataDir(), "updates", rel.Version)
+	if err := inst.RunStep("下载应用更新", func() error {
+		return inst.DownloadFiles(dir, delta.Changed)
+	}); err != nil {
+		return errors.Is(err, runner.ErrCanceled), err
+	}
+	log.Printf("正在安装应用 %s", rel.Version)
+	addOutputText(i18n.T("正在安装 SpeakMyBook %s...", rel.Version))
+	err = list.Apply(dir, filepath.Join(exeDir, "python"), backupDir(exeDir), projectKeep())
+	os.RemoveAll(dir)
+	return err == nil, err
+}
+
+// 更新和回退时从原来的 python 目录移到新目录的内容：虚拟环境、日志和 Python 安装包
+func projectKeep() []string {
+	keep := []string{".venv", "app.log"}
+	if artifacts, ok := strings.CutPrefix(pythonArtifacts(), "python/"); ok {
+		keep = append(keep, artifacts)
+	}
+	return keep
+}
+
+// 上一个版本的 python 目录，更新后无法启动时回退到它
+func backupDir(exeDir string) string {
+	return filepath.Join(exeDir, "backup", "python")
+}
+
+// 启动时检查应用更新，应用还没有运行，可以直接替换。无法获取发布清单时只记录，不影响启动
+func checkAppUpdateOnStart(exeDir string) error {
+	rel, found, err := latestRelease(exeDir)
+	if err != nil {
+		log.Printf("检查应用更新失败: %v", err)
+		return nil
+	}
+	if !found {
+		return nil
+	}
+	if resultFile != "" {
+		// 部署工具运行时不询问，由部署工具自己分发新版本
+		log.Printf("有新版本 %s，部署模式下不更新", rel.Version)
+		return nil
+	}
+	_, err = offerAppUpdate(exeDir, rel)
+	if errors.Is(err, runner.ErrCanceled) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go2exe/internal/i18n"
+	"go2exe/internal/runner"
+	"go2exe/internal/ui"
+)
+
+// 首次运行时显示的安装向导，安装结束后关闭
+var setupWizard *ui.Wizard
+
+// 配置了安装位置时通过环境变量告诉 uv 和安装脚本，提权的安装进程也会沿用
+func applyInstallDirs(cfg InstallConfig) {
+	if cfg.UVDir != "" {
+		childEnv.Set("UV_INSTALL_DIR", cfg.UVDir)
+	}
+	if cfg.PythonDir != "" {
+		childEnv.Set("UV_PYTHON_INSTALL_DIR", cfg.PythonDir)
+	}
+}
+
+// 首次运行的安装向导：确认许可协议和安装位置并保存到配置文件，之后安装进度显示在向导中。
+// 用户取消时返回 false
+func runSetupWizard(exeDir string) bool {
+	targets := installTargets(exeDir, true)
+	w := &ui.Wizard{
+		Title:     "SpeakMyBook",
+		PythonDir: targets[1],
+		UVDir:     targets[2],
+		OnCancel:  confirmCancel,
+	}
+	if !installConfig.LicenseAccepted {
+		data, err := os.ReadFile(filepath.Join(exeDir, "LICENSE"))
+		if err != nil {
+			log.Printf("读取许可协议失败: %v", err)
+		}
+		w.License = string(data)
+	}
+
+	ok, err := w.Run()
+	if err != nil {
+		log.Printf("无法显示安装向导: %v", err)
+		ui.MessageBox(i18n.T("环境安装"), i18n.T("即将开始安装必要的环境组件，请稍候...\n\n这个过程只在首次运行时执行，可能需要几分钟。"))
+		return true
+	}
+	if !ok {
+		log.Printf("用户取消了安装向导")
+		return false
+	}
+	log.Printf("安装位置: uv %s，Python %s", w.UVDir, w.PythonDir)
+
+	values := map[string]interface{}{}
+	if w.License != "" {
+		installConfig.LicenseAccepted = true
+		values["license_accepted"] = true
+	}
+	if w.UVDir != targets[2] {
+		installConfig.UVDir = w.UVDir
+		values["uv_dir"] = w.UVDir
+	}
+	if w.PythonDir != targets[1] {
+		installConfig.PythonDir = w.PythonDir
+		values["python_dir"] = w.PythonDir
+	}
+	applyInstallDirs(installConfig)
+	if len(values) > 0 {
+		if err := saveConfigValues(exeDir, "install", values); err != nil {
+			log.Printf("保存安装设置失败: %v", err)
+		}
+	}
+
+	setupWizard = w
+	console.UseView(w)
+	beginSetupSummary()
+	return true
+}
+
+// 在向导的完成页显示安装结果，安装成功时附上安装报告，等用户关闭向导。没有显示向导时直接返回
+func finishSetupWizard(exeDir string, err error) {
+	w := setupWizard
+	if w == nil {
+		return
+	}
+	setupWizard = nil
+	console.UseView(nil)
+	switch {
+	case err == nil && setupStats != nil:
+		w.FinishReport(i18n.T("安装完成"), i18n.T("运行环境已安装，SpeakMyBook 已启动。可以保存下面的安装报告，需要技术支持时提供给我们。"),
+			finishSetupSummary(exeDir), reportPath())
+	case err == nil:
+		w.Finish(i18n.T("安装完成"), i18n.T("运行环境已安装，SpeakMyBook 已启动。"))
+	case errors.Is(err, runner.ErrCanceled):
+		w.Finish(i18n.T("安装已取消"), i18n.T("安装已取消，下次启动时会重新安装。"))
+	default:
+		w.Finish(i18n.T("安装失败"), i18n.T("%v\n\n详细信息见程序目录下的 app.log。", err))
+	}
+}
+
+// 安装或启动失败后留出时间查看错误信息：显示了向导时在完成页显示错误，否则让控制台多停留一会儿
+func holdOnError(err error) {
+	if setupWizard != nil {
+		finishSetupWizard("", err)
+		return
+	}
+	if errors.Is(err, runner.ErrCanceled) {
+		return
+	}
+	time.Sleep(5 * time.Second)
+}
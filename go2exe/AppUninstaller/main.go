@@ -1,10 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 )
 
 func main() {
@@ -63,6 +65,76 @@ func main() {
 		}
 	}
 
+	// 删除“发送到”菜单中的快捷方式
+	sendToLink := filepath.Join(os.Getenv("APPDATA"), "Microsoft", "Windows", "SendTo", "SpeakMyBook.lnk")
+	fmt.Printf("执行：rm %s\n", sendToLink)
+	err = removeFile(sendToLink)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Printf("删除“发送到”快捷方式失败: %v\n", err)
+	}
+
+	// 删除开始菜单和桌面上的快捷方式（桌面被重定向到 OneDrive 等位置时需要手动删除）
+	for _, link := range []string{
+		filepath.Join(os.Getenv("APPDATA"), "Microsoft", "Windows", "Start Menu", "Programs", "SpeakMyBook.lnk"),
+		filepath.Join(os.Getenv("USERPROFILE"), "Desktop", "SpeakMyBook.lnk"),
+	} {
+		fmt.Printf("执行：rm %s\n", link)
+		err = removeFile(link)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Printf("删除快捷方式失败: %v\n", err)
+		}
+	}
+
+	// 删除启动器记录的“继续收听”快捷方式（与 AppRun 中的 bookShortcutsFile 保持一致）
+	shortcutsFile := filepath.Join(os.Getenv("LOCALAPPDATA"), "SpeakMyBook", "shortcuts.json")
+	if data, err := os.ReadFile(shortcutsFile); err == nil {
+		var links []string
+		json.Unmarshal(data, &links)
+		for _, link := range links {
+			fmt.Printf("执行：rm %s\n", link)
+			err = removeFile(link)
+			if err != nil && !os.IsNotExist(err) {
+				fmt.Printf("删除快捷方式失败: %v\n", err)
+			}
+		}
+		removeFile(shortcutsFile)
+	}
+
+	// 删除启动器注册的右键菜单（与 AppRun 中的 contextMenuTypes 保持一致）
+	for _, ext := range []string{".epub", ".txt", ".pdf"} {
+		key := `HKCU\Software\Classes\SystemFileAssociations\` + ext + `\shell\SpeakMyBook`
+		fmt.Printf("执行：reg delete \"%s\" /f\n", key)
+		err = executeCommand("reg", "delete", key, "/f")
+		if err != nil {
+			fmt.Printf("删除右键菜单失败（可能未注册）: %v\n", err)
+		}
+	}
+
+	// 删除文件关联（与 AppRun 中的 unregisterFileAssociations 保持一致），只删除 SpeakMyBook 自己写入的项
+	for _, ext := range []string{".epub", ".txt", ".pdf"} {
+		key := `HKCU\Software\Classes\` + ext
+		fmt.Printf("执行：reg delete \"%s\\OpenWithProgids\" /v SpeakMyBook.Book /f\n", key)
+		err = executeCommand("reg", "delete", key+`\OpenWithProgids`, "/v", "SpeakMyBook.Book", "/f")
+		if err != nil {
+			fmt.Printf("删除“打开方式”中的 SpeakMyBook 失败（可能未注册）: %v\n", err)
+		}
+		// 只有默认程序仍是 SpeakMyBook 时才删除默认值
+		if out, err := executeCommandAndGetOutput("reg", "query", key, "/ve"); err == nil && strings.Contains(out, "SpeakMyBook.Book") {
+			fmt.Printf("执行：reg delete \"%s\" /ve /f\n", key)
+			err = executeCommand("reg", "delete", key, "/ve", "/f")
+			if err != nil {
+				fmt.Printf("删除 %s 的默认程序失败: %v\n", ext, err)
+			}
+		}
+	}
+	for _, key := range []string{`HKCU\Software\Classes\SpeakMyBook.Book`, `HKCU\Software\Classes\Applications\SpeakMyBook.exe`} {
+		fmt.Printf("执行：reg delete \"%s\" /f\n", key)
+		err = executeCommand("reg", "delete", key, "/f")
+		if err != nil {
+			fmt.Printf("删除文件关联失败（可能未注册）: %v\n", err)
+		}
+	}
+
 	fmt.Println("完成，请按回车键退出！")
 	fmt.Scanln() // 等待用户按回车键
 }
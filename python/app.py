@@ -2,7 +2,7 @@
 """
 SpeakMyBook - 朗读我的书
 功能：
-1. 打开并阅读EPUB电子书，支持封面显示、章节列表浏览和章节内容编辑
+1. 打开并阅读EPUB、TXT和文字型PDF电子书，支持封面显示、章节列表浏览和章节内容编辑
 2. 将章节内容转换成有声书MP3，自动生成LRC字幕，添加ID3标签
 3. 支持批量转换模式和单章节转换模式
 """
@@ -25,9 +25,12 @@ import asyncio
 import edge_tts
 import sys, os
 import time
-from datetime import datetime
+from datetime import datetime, timedelta, timezone
 import queue
 import traceback
+import json
+import zlib
+import argparse
 from mutagen.mp3 import MP3
 from mutagen.id3 import ID3, APIC, TIT2, TPE1, TALB, USLT
 import aiohttp
@@ -41,6 +44,55 @@ RETRY_DELAY_SECONDS = 5                # TTS重试间隔（秒）
 TEMP_DIR = os.path.join(tempfile.gettempdir(), "epub2mp3")  # 临时目录
 LYRIC_LANG = "zho"                    # 歌词语言代码
 
+# 各朗读语言的默认 edge-tts 语音，启动器通过 SPEAKMYBOOK_SPEECH_LANG 传入系统的朗读语言（例如 zh-CN）
+SPEECH_VOICES = {
+    "zh-CN": "zh-CN-YunjianNeural",
+    "zh-TW": "zh-TW-HsiaoChenNeural",
+    "zh-HK": "zh-HK-HiuMaanNeural",
+    "en-US": "en-US-EmmaMultilingualNeural",
+    "en-GB": "en-GB-SoniaNeural",
+    "ja-JP": "ja-JP-NanamiNeural",
+    "ko-KR": "ko-KR-SunHiNeural",
+    "fr-FR": "fr-FR-DeniseNeural",
+    "de-DE": "de-DE-KatjaNeural",
+    "es-ES": "es-ES-ElviraNeural",
+}
+
+def default_voice():
+    """按启动器传入的朗读语言、区域设置或界面语言选择默认语音，都没有对应的语音时使用 DEFAULT_VOICE"""
+    for name in ("SPEAKMYBOOK_SPEECH_LANG", "SPEAKMYBOOK_LOCALE", "SPEAKMYBOOK_LANG"):
+        lang = os.getenv(name, "")
+        if not lang:
+            continue
+        for tag, voice in SPEECH_VOICES.items():
+            if tag.lower() == lang.lower():
+                return voice
+        # 没有这个地区的语音时使用同一语言的第一个，例如 en-AU 使用 en-US
+        prefix = lang.split("-")[0].lower() + "-"
+        for tag, voice in SPEECH_VOICES.items():
+            if tag.lower().startswith(prefix):
+                return voice
+    return DEFAULT_VOICE
+
+def app_timezone():
+    """启动器传入的时区：优先使用 IANA 名称（SPEAKMYBOOK_TIMEZONE），系统缺少时区数据时使用 UTC 偏移
+    （SPEAKMYBOOK_UTC_OFFSET，例如 +08:00），都没有时返回 None 表示使用本地时间"""
+    name = os.getenv("SPEAKMYBOOK_TIMEZONE")
+    if name:
+        try:
+            from zoneinfo import ZoneInfo
+            return ZoneInfo(name)
+        except Exception:
+            pass
+    offset = os.getenv("SPEAKMYBOOK_UTC_OFFSET", "")
+    if len(offset) == 6 and offset[0] in "+-" and offset[3] == ":":
+        try:
+            delta = timedelta(hours=int(offset[1:3]), minutes=int(offset[4:6]))
+        except ValueError:
+            return None
+        return timezone(-delta if offset[0] == "-" else delta)
+    return None
+
 # ===== EPUB处理模块 =====
 class EpubReader:
     """负责EPUB文件的解析和内容提取"""
@@ -381,6 +433,446 @@ def parse_epub_data(epub_filepath):
   
     return None
 
+# ===== TXT 和 PDF 处理模块 =====
+def read_text_file(path):
+    """读取 TXT 电子书，依次尝试 UTF-8（可带 BOM）、GB18030 和 UTF-16"""
+    with open(path, "rb") as f:
+        data = f.read()
+    if data.startswith((b"\xff\xfe", b"\xfe\xff")):
+        return data.decode("utf-16")
+    for encoding in ("utf-8-sig", "gb18030"):
+        try:
+            return data.decode(encoding)
+        except UnicodeDecodeError:
+            pass
+    return data.decode("gb18030", errors="replace")
+
+# 章节标题，例如“第十二章 归来”“第3回”“Chapter 5”
+CHAPTER_HEADING = re.compile(r"^\s*(第[0-9零一二三四五六七八九十百千两〇]+[章回节卷篇部集].{0,30}|chapter\s+\w+.{0,40})\s*$", re.IGNORECASE)
+PART_CHARS = 5000  # 没有章节标题时每部分的大约字数
+
+def split_text_chapters(text):
+    """按章节标题把正文拆成章节；找不到标题时按段落拆成约 PART_CHARS 字的部分"""
+    chapters = []
+    title, lines = None, []
+    for line in text.splitlines():
+        if CHAPTER_HEADING.match(line):
+            if title or "".join(lines).strip():
+                chapters.append({'title': title or "前言", 'content': "\n".join(lines).strip()})
+            title, lines = line.strip(), []
+        else:
+            lines.append(line)
+    if title or "".join(lines).strip():
+        chapters.append({'title': title or "正文", 'content': "\n".join(lines).strip()})
+    if len(chapters) > 1:
+        return chapters
+
+    # 没有章节标题：在段落处拆分
+    chapters, part = [], []
+    size = 0
+    for paragraph in text.splitlines():
+        part.append(paragraph)
+        size += len(paragraph)
+        if size >= PART_CHARS:
+            chapters.append("\n".join(part).strip())
+            part, size = [], 0
+    if "".join(part).strip():
+        chapters.append("\n".join(part).strip())
+    return [{'title': f"第 {i + 1} 部分", 'content': c} for i, c in enumerate(chapters) if c]
+
+def text_book_data(path, title, text):
+    """把 TXT 或 PDF 中的文字整理成与 parse_epub_data 相同结构的书籍数据"""
+    chapters = split_text_chapters(text)
+    if not chapters:
+        return None
+    total_words = sum(len(re.sub(r"\s", "", c['content'])) for c in chapters)
+    return {
+        'title': title or os.path.splitext(os.path.basename(path))[0],
+        'author': DEFAULT_ARTIST,
+        'publisher': "",
+        'publish_date': "",
+        'cover_data': None,
+        'chapters': chapters,
+        'total_words': total_words
+    }
+
+def parse_text_data(path):
+    """解析 TXT 电子书"""
+    return text_book_data(path, None, read_text_file(path))
+
+class PdfTextReader:
+    """从文字型 PDF 中按页面顺序提取文字。
+
+    只实现朗读需要的部分：FlateDecode 压缩的内容流和对象流、Tj/TJ 等文字操作符，
+    字体带 ToUnicode 映射时按映射转换，否则按 cp1252 解释。扫描件中没有文字，返回空字符串"""
+    WHITESPACE = b" \t\r\n\f\x00"
+    DELIMITERS = b"()<>[]{}/%"
+
+    class Ref(tuple):
+        """间接引用（对象号, 代号）"""
+
+    class Keyword(str):
+        """关键字或内容流中的操作符"""
+
+    def __init__(self, path):
+        with open(path, "rb") as f:
+            self.data = f.read()
+        self.objects = {}   # 对象号 -> 对象的原始字节（不含 stream 数据）或已解析的值
+        self.streams = {}   # 对象号 -> 流的原始数据
+        self.cmaps = {}     # 字体对象 id -> (编码字节数, 映射)
+        self._load_objects()
+
+    # ---- 词法和语法 ----
+    def _tokens(self, data):
+        """逐个返回 PDF 记号：数字、名字、字符串、数组和字典的边界、关键字"""
+        pos, n = 0, len(data)
+        while pos < n:
+            c = data[pos]
+            if c in self.WHITESPACE:
+                pos += 1
+            elif c == 0x25:  # % 注释
+                end = data.find(b"\n", pos)
+                pos = n if end < 0 else end + 1
+            elif c == 0x2F:  # /名字
+                end = pos + 1
+                while end < n and data[end] not in self.WHITESPACE and data[end] not in self.DELIMITERS:
+                    end += 1
+                name = re.sub(rb"#([0-9A-Fa-f]{2})", lambda m: bytes([int(m.group(1), 16)]), data[pos + 1:end])
+                yield ("name", name.decode("latin-1"))
+                pos = end
+            elif c == 0x28:  # (字符串)
+                value, pos = self._literal_string(data, pos + 1)
+                yield ("str", value)
+            elif data.startswith(b"<<", pos):
+                yield ("<<", None)
+                pos += 2
+            elif data.startswith(b">>", pos):
+                yield (">>", None)
+                pos += 2
+            elif c == 0x3C:  # <十六进制字符串>
+                end = data.find(b">", pos)
+                end = n if end < 0 else end
+                digits = re.sub(rb"[^0-9A-Fa-f]", b"", data[pos + 1:end])
+                if len(digits) % 2:
+                    digits += b"0"
+                yield ("str", bytes.fromhex(digits.decode("ascii")))
+                pos = end + 1
+            elif c in b"[]{}":
+                yield (chr(c), None)
+                pos += 1
+            else:
+                end = pos
+                while end < n and data[end] not in self.WHITESPACE and data[end] not in self.DELIMITERS:
+                    end += 1
+                if end == pos:  # 孤立的 ) 或 >
+                    pos += 1
+                    continue
+                word = data[pos:end].decode("latin-1")
+                pos = end
+                try:
+                    yield ("num", float(word) if "." in word else int(word))
+                except ValueError:
+                    yield ("kw", self.Keyword(word))
+                    if word == "ID":  # 内嵌图像的二进制数据，跳到 EI
+                        m = re.compile(rb"\sEI(?=[\s]|$)").search(data, pos)
+                        pos = n if not m else m.end()
+
+    @staticmethod
+    def _literal_string(data, pos):
+        """解析 ( 之后的字符串，返回内容和结束位置"""
+        out, depth, n = bytearray(), 1, len(data)
+        escapes = {ord("n"): b"\n", ord("r"): b"\r", ord("t"): b"\t", ord("b"): b"\b", ord("f"): b"\f"}
+        while pos < n:
+            c = data[pos]
+            pos += 1
+            if c == 0x5C:  # 反斜杠
+                if pos >= n:
+                    break
+                e = data[pos]
+                pos += 1
+                if e in escapes:
+                    out += escapes[e]
+                elif 0x30 <= e <= 0x37:
+                    digits = bytes([e])
+                    while len(digits) < 3 and pos < n and 0x30 <= data[pos] <= 0x37:
+                        digits += bytes([data[pos]])
+                        pos += 1
+                    out.append(int(digits, 8) & 0xFF)
+                elif e == 0x0D:  # 续行
+                    if pos < n and data[pos] == 0x0A:
+                        pos += 1
+                elif e != 0x0A:
+                    out.append(e)
+            elif c == 0x28:
+                depth += 1
+                out.append(c)
+            elif c == 0x29:
+                depth -= 1
+                if depth == 0:
+                    break
+                out.append(c)
+            else:
+                out.append(c)
+        return bytes(out), pos
+
+    def _parse(self, tokens, tok):
+        """从记号 tok 开始解析一个值；数字后面跟着 “代号 R” 时返回间接引用"""
+        kind, value = tok
+        if kind == "[":
+            items = []
+            for t in tokens:
+                if t[0] == "]":
+                    break
+                items.append(self._parse(tokens, t))
+            return self._refs(items)
+        if kind == "<<":
+            items = []
+            for t in tokens:
+                if t[0] == ">>":
+                    break
+                items.append(self._parse(tokens, t))
+            items = self._refs(items)
+            return {items[i]: items[i + 1] for i in range(0, len(items) - 1, 2) if isinstance(items[i], str)}
+        return value
+
+    def _refs(self, items):
+        """把数组或字典中连续的 “对象号 代号 R” 合并为间接引用"""
+        out = []
+        for item in items:
+            if item == "R" and isinstance(item, self.Keyword) and len(out) >= 2 \
+                    and isinstance(out[-1], int) and isinstance(out[-2], int):
+                gen, num = out.pop(), out.pop()
+                out.append(self.Ref((num, gen)))
+            else:
+                out.append(item)
+        return out
+
+    def _value(self, data):
+        """解析一段字节中的第一个值"""
+        tokens = self._tokens(data)
+        for tok in tokens:
+            return self._parse(tokens, tok)
+        return None
+
+    # ---- 对象 ----
+    def _load_objects(self):
+        """扫描文件中的所有对象（后出现的覆盖先出现的，即增量更新），展开对象流"""
+        for m in re.finditer(rb"(?<![0-9])(\d+)\s+(\d+)\s+obj\b", self.data):
+            num, start = int(m.group(1)), m.end()
+            end = self.data.find(b"endobj", start)
+            end = len(self.data) if end < 0 else end
+            body = self.data[start:end]
+            s = re.search(rb"\bstream\r?\n", body)
+            if s:
+                raw = body[s.end():]
+                raw = raw[:raw.rfind(b"endstream")] if b"endstream" in raw else raw
+                self.streams[num] = raw
+                body = body[:s.start()]
+            self.objects[num] = body
+        for num in list(self.streams):
+            obj = self.get(num)
+            if isinstance(obj, dict) and obj.get("Type") == "ObjStm":
+                self._load_object_stream(obj, self.stream_data(num, obj))
+
+    def _load_object_stream(self, obj, data):
+        """展开对象流（/Type /ObjStm）中压缩存放的对象"""
+        if not data:
+            return
+        first, count = self.resolve(obj.get("First")), self.resolve(obj.get("N"))
+        header = [int(x) for x in data[:first].split()[:2 * count]]
+        offsets = [(header[i], header[i + 1]) for i in range(0, len(header) - 1, 2)]
+        for i, (num, off) in enumerate(offsets):
+            end = offsets[i + 1][1] if i + 1 < len(offsets) else len(data) - first
+            if num not in self.streams:
+                self.objects.setdefault(num, data[first + off:first + end])
+
+    def get(self, num):
+        """按对象号取得已解析的对象"""
+        obj = self.objects.get(num)
+        if isinstance(obj, bytes):
+            obj = self._value(obj)
+            self.objects[num] = obj
+        return obj
+
+    def resolve(self, value):
+        """解开间接引用"""
+        seen = 0
+        while isinstance(value, self.Ref) and seen < 32:
+            value = self.get(value[0])
+            seen += 1
+        return value
+
+    def stream_data(self, num, obj=None):
+        """取得流解压后的数据，不支持的压缩方式返回 None"""
+        raw = self.streams.get(num)
+        if raw is None:
+            return None
+        obj = obj if obj is not None else self.get(num)
+        length = self.resolve(obj.get("Length")) if isinstance(obj, dict) else None
+        if isinstance(length, int) and 0 <= length <= len(raw):
+            raw = raw[:length]
+        filters = self.resolve(obj.get("Filter")) if isinstance(obj, dict) else None
+        filters = filters if isinstance(filters, list) else [filters] if filters else []
+        for f in filters:
+            if f != "FlateDecode":
+                return None
+            try:
+                raw = zlib.decompressobj().decompress(raw)
+            except zlib.error:
+                return None
+        return raw
+
+    # ---- 页面和文字 ----
+    def pages(self):
+        """按页面树顺序返回 (页面字典, 继承后的资源字典)"""
+        catalog = next((self.get(n) for n in list(self.objects) if self._is_type(n, "Catalog")), None)
+        if not isinstance(catalog, dict):
+            return []
+        result, stack = [], [(self.resolve(catalog.get("Pages")), None)]
+        visited = set()
+        while stack:
+            node, resources = stack.pop()
+            if not isinstance(node, dict) or id(node) in visited:
+                continue
+            visited.add(id(node))
+            resources = self.resolve(node.get("Resources")) or resources
+            kids = self.resolve(node.get("Kids"))
+            if isinstance(kids, list):
+                stack.extend((self.resolve(k), resources) for k in reversed(kids))
+            elif node.get("Type") != "Pages":
+                result.append((node, resources))
+        return result
+
+    def _is_type(self, num, name):
+        body = self.objects.get(num)
+        if isinstance(body, bytes) and b"/" + name.encode() not in body:
+            return False
+        obj = self.get(num)
+        return isinstance(obj, dict) and obj.get("Type") == name
+
+    def title(self):
+        """文档信息中的标题，没有时为 None"""
+        m = re.findall(rb"/Info\s+(\d+)\s+\d+\s+R", self.data)
+        info = self.get(int(m[-1])) if m else None
+        title = self.resolve(info.get("Title")) if isinstance(info, dict) else None
+        if not isinstance(title, bytes) or not title.strip():
+            return None
+        if title.startswith(b"\xfe\xff"):
+            return title[2:].decode("utf-16-be", errors="replace").strip() or None
+        return title.decode("cp1252", errors="replace").strip() or None
+
+    def text(self):
+        """提取所有页面的文字，页面之间空一行"""
+        texts = []
+        for page, resources in self.pages():
+            contents = page.get("Contents")
+            contents = self.resolve(contents)
+            refs = contents if isinstance(contents, list) else [page.get("Contents")]
+            data = b"\n".join(self.stream_data(r[0]) or b"" for r in refs if isinstance(r, self.Ref))
+            fonts = self.resolve((resources or {}).get("Font")) if isinstance(resources, dict) else None
+            texts.append(self._page_text(data, fonts if isinstance(fonts, dict) else {}))
+        return "\n\n".join(t.strip() for t in texts if t.strip())
+
+    def _page_text(self, data, fonts):
+        """执行内容流中与文字有关的操作符，返回页面文字"""
+        out, operands, cmap = [], [], None
+        tokens = self._tokens(data)
+        for tok in tokens:
+            if tok[0] != "kw":
+                operands.append(self._parse(tokens, tok))
+                continue
+            op = tok[1]
+            if op == "Tf" and len(operands) >= 2:
+                cmap = self._cmap(fonts.get(operands[-2]))
+            elif op in ("Tj", "'", '"') and operands:
+                if op != "Tj":
+                    out.append("\n")
+                out.append(self._decode(operands[-1], cmap))
+            elif op == "TJ" and operands and isinstance(operands[-1], list):
+                for item in operands[-1]:
+                    if isinstance(item, bytes):
+                        out.append(self._decode(item, cmap))
+                    elif isinstance(item, (int, float)) and item < -200:
+                        out.append(" ")
+            elif op == "T*" or (op in ("Td", "TD") and len(operands) >= 2 and operands[-1] != 0):
+                out.append("\n")
+            operands = []
+        text = "".join(out)
+        return re.sub(r"\n\s*\n\s*\n+", "\n\n", text)
+
+    def _decode(self, value, cmap):
+        if not isinstance(value, bytes):
+            return ""
+        if not cmap:
+            return value.decode("cp1252", errors="replace")
+        width, mapping = cmap
+        return "".join(mapping.get(value[i:i + width], "") for i in range(0, len(value), width))
+
+    def _cmap(self, font_ref):
+        """字体的 ToUnicode 映射：(编码字节数, {编码: 文字})，没有时为 None"""
+        key = font_ref if isinstance(font_ref, self.Ref) else id(font_ref)
+        if key in self.cmaps:
+            return self.cmaps[key]
+        font = self.resolve(font_ref)
+        ref = font.get("ToUnicode") if isinstance(font, dict) else None
+        data = self.stream_data(ref[0]) if isinstance(ref, self.Ref) else None
+        cmap = self._parse_cmap(data) if data else None
+        self.cmaps[key] = cmap
+        return cmap
+
+    def _parse_cmap(self, data):
+        mapping, width = {}, None
+
+        def unicode(b):
+            return b.decode("utf-16-be", errors="replace") if len(b) % 2 == 0 else b.decode("latin-1")
+
+        for m in re.finditer(rb"begincodespacerange(.*?)endcodespacerange", data, re.S):
+            for lo in re.findall(rb"<([0-9A-Fa-f]+)>\s*<[0-9A-Fa-f]+>", m.group(1)):
+                width = len(lo) // 2
+        for m in re.finditer(rb"beginbfchar(.*?)endbfchar", data, re.S):
+            for src, dst in re.findall(rb"<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]*)>", m.group(1)):
+                src = bytes.fromhex(src.decode())
+                width = width or len(src)
+                mapping[src] = unicode(bytes.fromhex(dst.decode()))
+        for m in re.finditer(rb"beginbfrange(.*?)endbfrange", data, re.S):
+            for lo, hi, dst in re.findall(rb"<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]+)>\s*(<[0-9A-Fa-f]*>|\[[^\]]*\])", m.group(1)):
+                size = len(lo) // 2
+                width = width or size
+                lo, hi = int(lo, 16), int(hi, 16)
+                if hi - lo > 0xFFFF:
+                    continue
+                if dst.startswith(b"["):
+                    for i, d in enumerate(re.findall(rb"<([0-9A-Fa-f]*)>", dst)):
+                        mapping[(lo + i).to_bytes(size, "big")] = unicode(bytes.fromhex(d.decode()))
+                else:
+                    base = bytes.fromhex(dst[1:-1].decode())
+                    start = int.from_bytes(base, "big")
+                    for i in range(hi - lo + 1):
+                        mapping[(lo + i).to_bytes(size, "big")] = unicode((start + i).to_bytes(len(base), "big"))
+        return (width or 1, mapping) if mapping else None
+
+def parse_pdf_data(path):
+    """解析 PDF 电子书，只能读取文字型 PDF"""
+    reader = PdfTextReader(path)
+    text = reader.text()
+    if not text.strip():
+        raise ValueError("没有从 PDF 中读到文字，扫描版 PDF 需要先做文字识别（OCR）")
+    return text_book_data(path, reader.title(), text)
+
+# 能打开的电子书类型
+BOOK_PARSERS = {
+    ".epub": parse_epub_data,
+    ".txt": parse_text_data,
+    ".pdf": parse_pdf_data,
+}
+
+def parse_book_data(path):
+    """按扩展名解析电子书"""
+    parser = BOOK_PARSERS.get(os.path.splitext(path)[1].lower())
+    if not parser:
+        raise ValueError(f"不支持的文件类型: {os.path.basename(path)}")
+    return parser(path)
+
 # ===== 音频转换模块 =====
 def convert_srt_to_lrc(srt_content, chars_per_line):
     """SRT->LRC并合并行"""
@@ -515,6 +1007,9 @@ class EpubToMp3App:
         self.processing = False  # 标记是否有转换任务正在进行
         self.estimated_time_var = None  # 将在create_widgets中初始化
         self.estimated_time_var_epub_tab = None  # 将在create_widgets中初始化
+        self.timezone = app_timezone()  # 日志时间使用的时区，None 为本地时间
+        # 讲述人等读屏软件是否正在运行，启动时由启动器传入，常驻的启动器在状态变化时推送 screen_reader
+        self.screen_reader = os.getenv("SPEAKMYBOOK_SCREEN_READER") == "1"
       
         # 创建临时目录
         os.makedirs(TEMP_DIR, exist_ok=True)
@@ -654,6 +1149,16 @@ class EpubToMp3App:
         )
         self.generate_ebook_button_epub_tab.pack(side=tk.LEFT, padx=(0, 5))
 
+        # 在桌面创建“继续收听”快捷方式，由启动器创建，不是由启动器启动时不显示
+        self.shortcut_button = ttk.Button(
+            top_frame,
+            text="创建桌面快捷方式",
+            command=self.create_book_shortcut,
+            state="disabled"
+        )
+        if os.getenv("SPEAKMYBOOK_IPC_PIPE"):
+            self.shortcut_button.pack(side=tk.LEFT, padx=(0, 5))
+
       
         # 主工作区 - 使用PanedWindow
         main_pane = ttk.PanedWindow(self.epub_frame, orient=tk.HORIZONTAL)
@@ -777,7 +1282,7 @@ class EpubToMp3App:
         tts_frame.pack(fill=tk.X, pady=(0, 10))
     
         ttk.Label(tts_frame, text="语音:").grid(row=0, column=0, sticky=tk.W, padx=5, pady=5)
-        self.voice_var = tk.StringVar(value=DEFAULT_VOICE)
+        self.voice_var = tk.StringVar(value=default_voice())
         voice_combo = ttk.Combobox(tts_frame, textvariable=self.voice_var, width=30)
         voice_combo['values'] = [
             "zh-CN-YunjianNeural",  # 男声
@@ -797,58 +1302,8 @@ class EpubToMp3App:
 
         # ====== 新增试听按钮（用pygame播放）======
         def tts_preview():
-            if self.processing:
-                messagebox.showwarning("处理中", "有转换任务正在进行，请等待完成。")
-                return
-            text = "腹有诗书气自华，读书万卷始通神"
-            voice = self.voice_var.get()
-            self.update_status("正在生成试听音频...")
-            self.append_log(f"试听语音: {voice}")
-            preview_mp3 = os.path.join(TEMP_DIR, "tts_preview.mp3")
-            try:
-                import asyncio
-                async def gen_preview():
-                    communicate = edge_tts.Communicate(text, voice)
-                    with open(preview_mp3, "wb") as f:
-                        async for chunk in communicate.stream():
-                            if chunk["type"] == "audio":
-                                f.write(chunk["data"])
-                loop = asyncio.new_event_loop()
-                asyncio.set_event_loop(loop)
-                loop.run_until_complete(gen_preview())
-            except Exception as e:
-                self.update_status(f"试听生成失败: {e}")
-                messagebox.showerror("试听失败", f"试听音频生成失败: {e}")
-                return
-
-            try:
-                import pygame
-            except ImportError:
-                messagebox.showerror("缺少依赖", "需要安装 pygame 库才能试听音频。\n请运行：pip install pygame")
-                return
-
-            if not os.path.exists(preview_mp3):
-                messagebox.showerror("试听失败", "试听音频文件未生成")
-                return
-
-            def play_with_pygame():
-                try:
-                    pygame.init()
-                    pygame.mixer.init()
-                    pygame.mixer.music.load(preview_mp3)
-                    pygame.mixer.music.play()
-                    self.update_status("试听播放中...")
-                    while pygame.mixer.music.get_busy():
-                        pygame.time.wait(100)
-                    pygame.mixer.music.stop()
-                except Exception as e:
-                    messagebox.showerror("试听失败", f"音频播放失败: {e}")
-                finally:
-                    pygame.quit()
-                    self.update_status("试听结束")
-
-            import threading
-            threading.Thread(target=play_with_pygame, daemon=True).start()
+            self.append_log(f"试听语音: {self.voice_var.get()}")
+            self.speak_text("腹有诗书气自华，读书万卷始通神")
         
         # ====== 试听按钮结束 ======
         preview_btn = ttk.Button(tts_frame, text="试听", command=tts_preview)
@@ -913,6 +1368,62 @@ class EpubToMp3App:
         )
         self.log_text.pack(fill=tk.BOTH, expand=True)
         self.log_text.config(state=tk.DISABLED)  # 只读
+
+    def speak_text(self, text):
+        """用当前语音朗读一段文字（试听和朗读选中文字），在后台线程播放"""
+        if self.processing:
+            messagebox.showwarning("处理中", "有转换任务正在进行，请等待完成。")
+            return
+        # 启动器检测到没有音频输出设备时，播放会静默失败
+        if os.getenv("SPEAKMYBOOK_AUDIO_OUTPUTS") == "0":
+            messagebox.showwarning("没有音频输出设备", "没有检测到可用的音频输出设备，无法试听。\n请连接扬声器或耳机，或检查声卡驱动。")
+            return
+        voice = self.voice_var.get()
+        self.update_status("正在生成试听音频...")
+        preview_mp3 = os.path.join(TEMP_DIR, "tts_preview.mp3")
+        try:
+            import asyncio
+            async def gen_preview():
+                communicate = edge_tts.Communicate(text, voice)
+                with open(preview_mp3, "wb") as f:
+                    async for chunk in communicate.stream():
+                        if chunk["type"] == "audio":
+                            f.write(chunk["data"])
+            loop = asyncio.new_event_loop()
+            asyncio.set_event_loop(loop)
+            loop.run_until_complete(gen_preview())
+        except Exception as e:
+            self.update_status(f"试听生成失败: {e}")
+            messagebox.showerror("试听失败", f"试听音频生成失败: {e}")
+            return
+
+        try:
+            import pygame
+        except ImportError:
+            messagebox.showerror("缺少依赖", "需要安装 pygame 库才能试听音频。\n请运行：pip install pygame")
+            return
+
+        if not os.path.exists(preview_mp3):
+            messagebox.showerror("试听失败", "试听音频文件未生成")
+            return
+
+        def play_with_pygame():
+            try:
+                pygame.init()
+                pygame.mixer.init()
+                pygame.mixer.music.load(preview_mp3)
+                pygame.mixer.music.play()
+                self.update_status("试听播放中...")
+                while pygame.mixer.music.get_busy():
+                    pygame.time.wait(100)
+                pygame.mixer.music.stop()
+            except Exception as e:
+                messagebox.showerror("试听失败", f"音频播放失败: {e}")
+            finally:
+                pygame.quit()
+                self.update_status("试听结束")
+
+        threading.Thread(target=play_with_pygame, daemon=True).start()
       
     def browse_directory(self, var):
         """浏览并选择目录"""
@@ -932,18 +1443,33 @@ class EpubToMp3App:
             self.cover_path_var.set(file_path)
           
     def select_epub_file(self):
-        """选择EPUB文件并解析"""
+        """选择电子书（EPUB、TXT 或 PDF）并解析"""
         file_path = filedialog.askopenfilename(
-            title="选择EPUB文件",
+            title="选择电子书",
             filetypes=[
+                ("电子书", "*.epub *.txt *.pdf"),
                 ("EPUB Files", "*.epub"),
+                ("TXT Files", "*.txt"),
+                ("PDF Files", "*.pdf"),
                 ("All Files", "*.*")
             ]
         )
       
         if not file_path:
             return
-          
+        self.open_book(file_path)
+
+    def open_book(self, file_path):
+        """打开并解析一本书，也用于命令行 --book 指定的书"""
+        # “发送到”菜单可以转发任何文件，先检查再清空当前的书
+        if not os.path.isfile(file_path):
+            messagebox.showerror("无法打开", f"找不到文件：\n{file_path}")
+            return
+        ext = os.path.splitext(file_path)[1].lower()
+        if ext not in BOOK_PARSERS:
+            supported = "、".join(e[1:].upper() for e in BOOK_PARSERS)
+            messagebox.showwarning("无法打开", f"不支持这种文件：\n{os.path.basename(file_path)}\n\n可以打开的电子书格式：{supported}")
+            return
         # 更新状态
         self.update_status(f"正在解析 {os.path.basename(file_path)}...")
         self.filepath_var.set(file_path)
@@ -954,22 +1480,37 @@ class EpubToMp3App:
         # 在后台线程中解析EPUB
         threading.Thread(target=self._parse_epub_in_thread, args=(file_path,), daemon=True).start()
       
+    def create_book_shortcut(self):
+        """请启动器在桌面为当前的书创建“继续收听”快捷方式，使用当前选择的语音"""
+        file_path = self.filepath_var.get()
+        if not file_path:
+            return
+        reply = notify_launcher("create_shortcut", os.path.abspath(file_path), options={"voice": self.voice_var.get()})
+        if reply is None:
+            messagebox.showerror("创建快捷方式失败", "无法连接启动器")
+        elif reply.get("verb") == "ok":
+            self.update_status("已在桌面创建快捷方式")
+        else:
+            messagebox.showerror("创建快捷方式失败", "\n".join(reply.get("args") or ["未知错误"]))
+
     def _parse_epub_in_thread(self, file_path):
-        """在后台线程中解析EPUB文件"""
+        """在后台线程中解析电子书"""
         try:
-            self.epub_data = parse_epub_data(file_path)
+            self.epub_data = parse_book_data(file_path)
           
             # 使用队列将结果传递回主线程
             if self.epub_data:
                 self.message_queue.put(("display_epub", None))
                 self.message_queue.put(("status", f"已加载 {os.path.basename(file_path)}"))
+                # 启动器据此更新任务栏跳转列表中最近的书
+                notify_launcher("book_opened", os.path.abspath(file_path))
             else:
                 self.message_queue.put(("status", f"解析失败: {os.path.basename(file_path)}"))
-                self.message_queue.put(("error", "无法解析选定的EPUB文件。"))
+                self.message_queue.put(("error", "无法解析选定的电子书。"))
               
         except Exception as e:
             self.message_queue.put(("status", f"解析出错: {str(e)}"))
-            self.message_queue.put(("error", f"解析EPUB文件时发生错误: {str(e)}"))
+            self.message_queue.put(("error", f"解析电子书时发生错误: {str(e)}"))
             traceback.print_exc()
           
     def process_messages(self):
@@ -990,6 +1531,8 @@ class EpubToMp3App:
                     self.append_log(data)
                 elif message_type == "batch_complete":
                     self.on_batch_complete()
+                elif message_type == "launcher":
+                    self.on_launcher_message(data)
                   
         except queue.Empty:
             pass
@@ -1004,7 +1547,7 @@ class EpubToMp3App:
     def append_log(self, message):
         """向日志文本框添加消息"""
         self.log_text.config(state=tk.NORMAL)
-        timestamp = datetime.now().strftime("%H:%M:%S")
+        timestamp = datetime.now(self.timezone).strftime("%H:%M:%S")
         self.log_text.insert(tk.END, f"[{timestamp}] {message}\n")
         self.log_text.see(tk.END)  # 滚动到最后
         self.log_text.config(state=tk.DISABLED)
@@ -1040,6 +1583,7 @@ class EpubToMp3App:
         self.chapter_save_button.config(state="disabled")
         self.convert_chapter_button.config(state="disabled")
         self.generate_ebook_button_epub_tab.config(state="disabled")
+        self.shortcut_button.config(state="disabled")
 
         # 禁用MP3标签相关控件
         self.artist_entry.config(state='disabled')
@@ -1108,6 +1652,7 @@ class EpubToMp3App:
 
             # 启用导出按钮
             self.generate_ebook_button_epub_tab.config(state="normal")
+            self.shortcut_button.config(state="normal")
 
             # 默认选择第一个章节
             if len(self.epub_data['chapters']) > 0:
@@ -1835,8 +2380,10 @@ class EpubToMp3App:
             msg = "确定要退出吗？"
         if not messagebox.askyesno("确认退出", msg):
             return
+        self.quit_app()
 
-        # 清理临时文件夹
+    def quit_app(self):
+        """清理临时文件夹后关闭主窗口"""
         try:
             if os.path.exists(TEMP_DIR):
                 shutil.rmtree(TEMP_DIR)
@@ -1845,6 +2392,64 @@ class EpubToMp3App:
 
         self.root.destroy()
 
+    def on_launcher_exit(self):
+        """启动器在更新、修复或卸载前请求应用退出。启动器已经征得用户同意，
+        只在转换任务进行中时再确认一次，用户选择不退出时启动器等待超时后提示手动关闭"""
+        if self.processing:
+            self.activate()
+            if not messagebox.askyesno("确认退出", "SpeakMyBook 需要退出以完成维护操作，但有转换任务正在进行。\n确定要中断任务并退出吗？"):
+                return
+        self.quit_app()
+
+    def on_launcher_message(self, msg):
+        """处理启动器通过 hello 连接推送的动作"""
+        verb, args, options = msg.get("verb"), msg.get("args") or [], msg.get("options") or {}
+        if verb == "activate":
+            self.activate()
+        elif verb == "read_selection":
+            self.read_selection()
+        elif verb == "open":
+            if options.get("voice"):
+                self.voice_var.set(options["voice"])
+            if args:
+                if self.processing:
+                    messagebox.showwarning("处理中", "有转换任务正在进行，完成后再打开其他书。")
+                    return
+                self.open_book(args[0])
+        elif verb == "exit":
+            self.on_launcher_exit()
+        elif verb == "screen_reader":
+            self.screen_reader = bool(args) and args[0] == "on"
+        else:
+            print(f"忽略启动器的未知动作: {msg}", file=sys.stderr)
+
+    def activate(self):
+        """把主窗口切换到前台（快捷键、再次启动或转发书籍时）"""
+        self.root.deiconify()
+        self.root.lift()
+        # 短暂置顶，避免窗口只在任务栏闪烁
+        self.root.attributes("-topmost", True)
+        self.root.after(100, self.root.attributes, "-topmost", False)
+        self.root.focus_force()
+
+    def read_selection(self):
+        """朗读章节内容中选中的文字，没有选中时朗读剪贴板中的文字。
+        读屏软件运行时只朗读选中的文字：剪贴板的内容不在读屏软件的焦点上，自动朗读它会与读屏软件同时发声"""
+        try:
+            text = self.chapter_text.get(tk.SEL_FIRST, tk.SEL_LAST)
+        except tk.TclError:
+            text = ""
+            if not self.screen_reader:
+                try:
+                    text = self.root.clipboard_get()
+                except tk.TclError:
+                    pass
+        text = text.strip()
+        if not text:
+            self.update_status("没有选中的文字")
+            return
+        self.speak_text(text)
+
     def calculate_estimated_time(self):
         """计算预计耗时，基于字数"""
         if not self.epub_data or 'total_words' not in self.epub_data:
@@ -1858,8 +2463,81 @@ class EpubToMp3App:
 
         return f"{hours:02d}:{minutes:02d}:{seconds:02d}"
 
+# ===== 命令行参数 =====
+def parse_args(argv):
+    """解析启动器传来的参数：--default-index 之后是快捷方式或命令行中 -- 之后转发的参数。
+    无法识别的参数只记录，不影响启动"""
+    parser = argparse.ArgumentParser(prog="app.pyw", add_help=False)
+    parser.add_argument("--default-index", help="启动器选择的 PyPI 镜像")
+    parser.add_argument("--book", action="append", default=[], help="启动后打开的书，可重复")
+    parser.add_argument("--voice", help="朗读使用的语音，例如 zh-CN-XiaoxiaoNeural")
+    parser.add_argument("--debug", action="store_true", help="在运行日志中显示启动参数和未捕获的异常")
+    try:
+        args, unknown = parser.parse_known_args(argv)
+    except SystemExit:
+        # 参数缺少值等错误时 argparse 会退出，这里改为忽略全部参数
+        print(f"无法解析命令行参数: {argv}", file=sys.stderr)
+        return parser.parse_args([])
+    if unknown:
+        print(f"忽略无法识别的参数: {unknown}", file=sys.stderr)
+    return args
+
+def apply_args(app, args):
+    """把命令行参数应用到界面"""
+    if args.debug:
+        app.append_log(f"启动参数: {sys.argv[1:]}")
+        app.root.report_callback_exception = lambda *exc: app.append_log("".join(traceback.format_exception(*exc)))
+    if args.voice:
+        app.voice_var.set(args.voice)
+    if args.book:
+        if len(args.book) > 1:
+            app.append_log(f"一次只能打开一本书，忽略: {args.book[1:]}")
+        app.open_book(args.book[0])
+
+# ===== 与启动器的通信 =====
+def connect_launcher(app):
+    """建立与启动器之间的 hello 连接（SPEAKMYBOOK_IPC_PIPE），在后台线程中接收启动器推送的动作，
+    交给主线程的消息队列处理。不是由启动器启动，或启动器没有开启 IPC 时忽略"""
+    pipe = os.getenv("SPEAKMYBOOK_IPC_PIPE")
+    if not pipe:
+        return
+
+    def receive():
+        try:
+            with open(pipe, "r+b", buffering=0) as f:
+                f.write((json.dumps({"verb": "hello"}) + "\n").encode("utf-8"))
+                for line in f:
+                    try:
+                        msg = json.loads(line.decode("utf-8"))
+                    except ValueError:
+                        print(f"启动器的消息格式错误: {line!r}", file=sys.stderr)
+                        continue
+                    app.message_queue.put(("launcher", msg))
+        except OSError as e:
+            print(f"与启动器的连接已断开: {e}", file=sys.stderr)
+
+    threading.Thread(target=receive, daemon=True).start()
+
+def notify_launcher(verb, *args, options=None):
+    """通过命名管道（SPEAKMYBOOK_IPC_PIPE）通知启动器，返回启动器的回复（{"verb": "ok"} 或 {"verb": "error", "args": [...]}），
+    不是由启动器启动或连接失败时返回 None"""
+    pipe = os.getenv("SPEAKMYBOOK_IPC_PIPE")
+    if not pipe:
+        return None
+    msg = {"verb": verb, "args": list(args)}
+    if options:
+        msg["options"] = options
+    try:
+        with open(pipe, "r+b", buffering=0) as f:
+            f.write((json.dumps(msg) + "\n").encode("utf-8"))
+            return json.loads(f.readline().decode("utf-8"))
+    except (OSError, ValueError):
+        return None
+
 def main():
     """主函数"""
+    args = parse_args(sys.argv[1:])
+
     # 创建并启动应用
     root = tk.Tk()
     root.attributes("-topmost", True)
@@ -1874,6 +2552,13 @@ def main():
   
     # 设置窗口关闭处理
     root.protocol("WM_DELETE_WINDOW", app.on_closing)
+
+    # 打开命令行中指定的书和语音
+    apply_args(app, args)
+
+    # 主窗口显示后告诉启动器已就绪，并接收启动器推送的动作
+    root.after(0, notify_launcher, "ready")
+    connect_launcher(app)
   
     # 启动主循环
     root.mainloop()
@@ -1882,4 +2567,8 @@ if __name__ == "__main__":
     if sys.platform == "win32" and sys.executable.endswith("pythonw.exe"):
         sys.stdout = open(os.path.join(os.getenv("TEMP"), "SpeakMyBook_stdout.log"), "w")
         sys.stderr = open(os.path.join(os.getenv("TEMP"), "SpeakMyBook_stderr.log"), "w")
-    main()
+    try:
+        main()
+    except Exception as e:
+        notify_launcher("startup_error", str(e), traceback.format_exc())
+        raise